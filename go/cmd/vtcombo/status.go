@@ -21,6 +21,7 @@ import (
 	"vitess.io/vitess/go/vt/servenv"
 	"vitess.io/vitess/go/vt/srvtopo"
 	"vitess.io/vitess/go/vt/vtgate"
+	"vitess.io/vitess/go/vt/vtgate/balancer"
 
 	_ "vitess.io/vitess/go/vt/status"
 )
@@ -46,4 +47,7 @@ func addStatusParts(vtg *vtgate.VTGate) {
 	servenv.AddStatusPart("Health Check Cache", discovery.HealthCheckTemplate, func() any {
 		return vtg.Gateway().TabletsCacheStatus()
 	})
+	servenv.AddStatusPart("Tablet Balancer", balancer.StatusTemplate, func() any {
+		return vtg.Gateway().BalancerStatus()
+	})
 }
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"time"
+
+	"vitess.io/vitess/go/timer"
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/mysqlctl"
+	"vitess.io/vitess/go/vt/servenv"
+	"vitess.io/vitess/go/vt/vtctl/grpcvtctldserver"
+
+	vtctldatapb "vitess.io/vitess/go/vt/proto/vtctldata"
+)
+
+var (
+	backupRetentionCheckInterval time.Duration
+	backupRetentionDryRun        bool
+)
+
+func init() {
+	Main.Flags().DurationVar(&backupRetentionCheckInterval, "backup-retention-check-interval", backupRetentionCheckInterval, "How often the backups of the keyspaces with a backup retention policy are pruned. Backups are never pruned if zero.")
+	Main.Flags().BoolVar(&backupRetentionDryRun, "backup-retention-dry-run", backupRetentionDryRun, "Only log the backups the backup retention policies would prune, without removing them.")
+}
+
+func initBackupRetention() {
+	if backupRetentionCheckInterval <= 0 {
+		return
+	}
+
+	server := grpcvtctldserver.NewVtctldServer(ts)
+	timer := timer.NewTimer(backupRetentionCheckInterval)
+	timer.Start(func() {
+		ctx := context.Background()
+		keyspaces, err := ts.GetKeyspaces(ctx)
+		if err != nil {
+			log.Errorf("Backup retention: failed to get keyspaces, error: %v", err)
+			return
+		}
+		for _, keyspace := range keyspaces {
+			ki, err := ts.GetKeyspace(ctx, keyspace)
+			if err != nil {
+				log.Errorf("Backup retention: failed to get keyspace %v, error: %v", keyspace, err)
+				continue
+			}
+			if mysqlctl.IsBackupRetentionPolicyEmpty(ki.BackupRetentionPolicy) {
+				continue
+			}
+			resp, err := server.PruneBackups(ctx, &vtctldatapb.PruneBackupsRequest{
+				Keyspace: keyspace,
+				DryRun:   backupRetentionDryRun,
+			})
+			if err != nil {
+				log.Errorf("Backup retention: failed to prune the backups of keyspace %v, error: %v", keyspace, err)
+				continue
+			}
+			for _, backup := range resp.Backups {
+				if backupRetentionDryRun {
+					log.Infof("Backup retention: would prune backup %v/%v", backup.Directory, backup.Name)
+				} else {
+					log.Infof("Backup retention: pruned backup %v/%v", backup.Directory, backup.Name)
+				}
+			}
+		}
+	})
+	servenv.OnClose(func() { timer.Stop() })
+}
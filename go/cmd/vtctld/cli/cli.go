@@ -71,6 +71,9 @@ func run(cmd *cobra.Command, args []string) error {
 	// Start schema manager service.
 	initSchema()
 
+	// Start pruning backups according to the keyspace retention policies.
+	initBackupRetention()
+
 	// And run the server.
 	servenv.RunDefault()
 
@@ -29,6 +29,8 @@ import (
 	"vitess.io/vitess/go/vt/mysqlctl"
 	"vitess.io/vitess/go/vt/topo/topoproto"
 
+	tabletmanagerdatapb "vitess.io/vitess/go/vt/proto/tabletmanagerdata"
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
 	vtctldatapb "vitess.io/vitess/go/vt/proto/vtctldata"
 )
 
@@ -60,6 +62,17 @@ If no replica-type tablet can be found, the backup can be taken on the primary i
 		Args:                  cobra.ExactArgs(1),
 		RunE:                  commandGetBackups,
 	}
+	// PruneBackups makes a PruneBackups gRPC call to a vtctld.
+	PruneBackups = &cobra.Command{
+		Use:   "PruneBackups [--dry-run] <keyspace|keyspace/shard>",
+		Short: "Removes the backups of the given keyspace, or of one of its shards, that the backup retention policy of the keyspace doesn't keep.",
+		Long: `Removes the backups of the given keyspace, or of one of its shards, that the backup retention policy of the keyspace doesn't keep.
+
+The removed backups are listed. With --dry-run, the backups that would be removed are listed, and nothing is removed.`,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(1),
+		RunE:                  commandPruneBackups,
+	}
 	// RemoveBackup makes a RemoveBackup gRPC call to a vtctld.
 	RemoveBackup = &cobra.Command{
 		Use:                   "RemoveBackup <keyspace/shard> <backup name>",
@@ -76,6 +89,39 @@ If no replica-type tablet can be found, the backup can be taken on the primary i
 		Args:                  cobra.ExactArgs(1),
 		RunE:                  commandRestoreFromBackup,
 	}
+	// SetKeyspaceBackupRetentionPolicy makes a SetKeyspaceBackupRetentionPolicy gRPC call to a vtctld.
+	SetKeyspaceBackupRetentionPolicy = &cobra.Command{
+		Use:   "SetKeyspaceBackupRetentionPolicy [--daily <days>] [--weekly <weeks>] [--monthly <months>] <keyspace>",
+		Short: "Sets the backup retention policy of the specified keyspace.",
+		Long: `Sets the backup retention policy of the specified keyspace.
+
+The last full backup of each of the most recent --daily days, --weekly ISO weeks and --monthly months (UTC) with a backup
+is kept. The latest full backup is always kept, and so are the incremental backups taken since the oldest full backup kept,
+along with all the backups they are based on. Setting all the values to 0 removes the policy.
+
+The backups are pruned by PruneBackups, and periodically by vtctld if it runs with --backup-retention-check-interval.
+
+To keep a week of daily backups and 6 monthly backups of the customer keyspace, you would use the following command:
+SetKeyspaceBackupRetentionPolicy --daily 7 --monthly 6 customer`,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(1),
+		RunE:                  commandSetKeyspaceBackupRetentionPolicy,
+	}
+	// VerifyBackup makes a VerifyBackup gRPC call to a vtctld.
+	VerifyBackup = &cobra.Command{
+		Use:   "VerifyBackup [--concurrency <concurrency>] <tablet_alias> <backup name>",
+		Short: "Restores the given backup of the shard of the tablet into a scratch mysqld on the tablet, and checks the restored data.",
+		Long: `Restores the given backup of the shard of the tablet into a scratch mysqld on the tablet, and checks the restored data.
+
+The restored position must match the position of the backup, and all the restored tables must be readable. The tables
+must also match the checksums recorded in the MANIFEST of the backup, if it was taken with --builtinbackup-table-checksums.
+The data of the tablet isn't touched, and it keeps serving. The verdict is written in the VERIFICATION file of the backup.
+
+The command fails if the backup didn't pass the verification.`,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(2),
+		RunE:                  commandVerifyBackup,
+	}
 )
 
 var backupOptions = struct {
@@ -199,6 +245,38 @@ func commandGetBackups(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+var pruneBackupsOptions = struct {
+	DryRun bool
+}{}
+
+func commandPruneBackups(cmd *cobra.Command, args []string) error {
+	keyspace, shard := cmd.Flags().Arg(0), ""
+	if strings.Contains(keyspace, "/") {
+		var err error
+		keyspace, shard, err = topoproto.ParseKeyspaceShard(keyspace)
+		if err != nil {
+			return err
+		}
+	}
+
+	cli.FinishedParsing(cmd)
+
+	resp, err := client.PruneBackups(commandCtx, &vtctldatapb.PruneBackupsRequest{
+		Keyspace: keyspace,
+		Shard:    shard,
+		DryRun:   pruneBackupsOptions.DryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, b := range resp.Backups {
+		fmt.Printf("%s/%s\n", b.Directory, b.Name)
+	}
+
+	return nil
+}
+
 func commandRemoveBackup(cmd *cobra.Command, args []string) error {
 	keyspace, shard, err := topoproto.ParseKeyspaceShard(cmd.Flags().Arg(0))
 	if err != nil {
@@ -278,6 +356,92 @@ func commandRestoreFromBackup(cmd *cobra.Command, args []string) error {
 	}
 }
 
+var setKeyspaceBackupRetentionPolicyOptions = struct {
+	Daily   uint32
+	Weekly  uint32
+	Monthly uint32
+}{}
+
+func commandSetKeyspaceBackupRetentionPolicy(cmd *cobra.Command, args []string) error {
+	keyspace := cmd.Flags().Arg(0)
+	cli.FinishedParsing(cmd)
+
+	resp, err := client.SetKeyspaceBackupRetentionPolicy(commandCtx, &vtctldatapb.SetKeyspaceBackupRetentionPolicyRequest{
+		Keyspace: keyspace,
+		BackupRetentionPolicy: &topodatapb.BackupRetentionPolicy{
+			Daily:   setKeyspaceBackupRetentionPolicyOptions.Daily,
+			Weekly:  setKeyspaceBackupRetentionPolicyOptions.Weekly,
+			Monthly: setKeyspaceBackupRetentionPolicyOptions.Monthly,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := cli.MarshalJSON(resp)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n", data)
+	return nil
+}
+
+var verifyBackupOptions = struct {
+	Concurrency uint64
+}{}
+
+func commandVerifyBackup(cmd *cobra.Command, args []string) error {
+	alias, err := topoproto.ParseTabletAlias(cmd.Flags().Arg(0))
+	if err != nil {
+		return err
+	}
+
+	name := cmd.Flags().Arg(1)
+
+	cli.FinishedParsing(cmd)
+
+	stream, err := client.VerifyBackup(commandCtx, &vtctldatapb.VerifyBackupRequest{
+		TabletAlias: alias,
+		BackupName:  name,
+		Concurrency: verifyBackupOptions.Concurrency,
+	})
+	if err != nil {
+		return err
+	}
+
+	var verification *tabletmanagerdatapb.BackupVerification
+	for {
+		resp, err := stream.Recv()
+		switch err {
+		case nil:
+			if resp.Event != nil {
+				fmt.Printf("%s/%s (%s): %v\n", resp.Keyspace, resp.Shard, topoproto.TabletAliasString(resp.TabletAlias), resp.Event)
+			}
+			if resp.Verification != nil {
+				verification = resp.Verification
+			}
+		case io.EOF:
+			if verification == nil {
+				return fmt.Errorf("no verdict received for backup %s", name)
+			}
+
+			data, err := cli.MarshalJSON(verification)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", data)
+			if !verification.Passed {
+				return fmt.Errorf("backup %s failed the verification", name)
+			}
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
 func init() {
 	Backup.Flags().BoolVar(&backupOptions.AllowPrimary, "allow-primary", false, "Allow the primary of a shard to be used for the backup. WARNING: If using the builtin backup engine, this will shutdown mysqld on the primary and stop writes for the duration of the backup.")
 	Backup.Flags().Uint64Var(&backupOptions.Concurrency, "concurrency", 4, "Specifies the number of compression/checksum jobs to run simultaneously.")
@@ -296,6 +460,9 @@ func init() {
 	GetBackups.Flags().BoolVarP(&getBackupsOptions.OutputJSON, "json", "j", false, "Output backup info in JSON format rather than a list of backups.")
 	Root.AddCommand(GetBackups)
 
+	PruneBackups.Flags().BoolVar(&pruneBackupsOptions.DryRun, "dry-run", false, "Only list the backups that would be removed, without removing them.")
+	Root.AddCommand(PruneBackups)
+
 	Root.AddCommand(RemoveBackup)
 
 	RestoreFromBackup.Flags().StringVarP(&restoreFromBackupOptions.BackupTimestamp, "backup-timestamp", "t", "", "Use the backup taken at, or closest before, this timestamp. Omit to use the latest backup. Timestamp format is \"YYYY-mm-DD.HHMMSS\".")
@@ -303,4 +470,12 @@ func init() {
 	RestoreFromBackup.Flags().StringVar(&restoreFromBackupOptions.RestoreToTimestamp, "restore-to-timestamp", "", "Run a point in time recovery that restores up to, and excluding, given timestamp in RFC3339 format (`2006-01-02T15:04:05Z07:00`). This will attempt to use one full backup followed by zero or more incremental backups")
 	RestoreFromBackup.Flags().BoolVar(&restoreFromBackupOptions.DryRun, "dry-run", false, "Only validate restore steps, do not actually restore data")
 	Root.AddCommand(RestoreFromBackup)
+
+	SetKeyspaceBackupRetentionPolicy.Flags().Uint32Var(&setKeyspaceBackupRetentionPolicyOptions.Daily, "daily", 0, "Number of days for which the last full backup of the day is kept.")
+	SetKeyspaceBackupRetentionPolicy.Flags().Uint32Var(&setKeyspaceBackupRetentionPolicyOptions.Weekly, "weekly", 0, "Number of ISO weeks for which the last full backup of the week is kept.")
+	SetKeyspaceBackupRetentionPolicy.Flags().Uint32Var(&setKeyspaceBackupRetentionPolicyOptions.Monthly, "monthly", 0, "Number of months for which the last full backup of the month is kept.")
+	Root.AddCommand(SetKeyspaceBackupRetentionPolicy)
+
+	VerifyBackup.Flags().Uint64Var(&verifyBackupOptions.Concurrency, "concurrency", 4, "Specifies the number of files to restore simultaneously.")
+	Root.AddCommand(VerifyBackup)
 }
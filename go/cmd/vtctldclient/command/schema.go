@@ -17,9 +17,12 @@ limitations under the License.
 package command
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -29,10 +32,12 @@ import (
 	"vitess.io/vitess/go/protoutil"
 	"vitess.io/vitess/go/vt/logutil"
 	"vitess.io/vitess/go/vt/schema"
+	"vitess.io/vitess/go/vt/schemadiff"
 	"vitess.io/vitess/go/vt/sqlparser"
 	"vitess.io/vitess/go/vt/topo/topoproto"
 	"vitess.io/vitess/go/vt/vtctl/grpcvtctldserver"
 
+	tabletmanagerdatapb "vitess.io/vitess/go/vt/proto/tabletmanagerdata"
 	vtctldatapb "vitess.io/vitess/go/vt/proto/vtctldata"
 	"vitess.io/vitess/go/vt/proto/vtrpc"
 )
@@ -59,6 +64,24 @@ For --sql, semi-colons and repeated values may be mixed, for example:
 		Args:                  cobra.ExactArgs(1),
 		RunE:                  commandApplySchema,
 	}
+	// ApplyDesiredSchema makes GetSchema and ApplySchema gRPC calls to a vtctld.
+	ApplyDesiredSchema = &cobra.Command{
+		Use:   "ApplyDesiredSchema --keyspace <keyspace> --schema-dir <dir> [--ddl-strategy <strategy>] [--migration-context <context>] [--caller-id <caller_id>] [--dry-run]",
+		Short: "Converges the schema of a keyspace to the desired schema in a directory of CREATE statements, by submitting the diffs as Online DDL migrations.",
+		Long: `Converges the schema of a keyspace to the desired schema in a directory of CREATE statements, by submitting the diffs as Online DDL migrations.
+
+The desired schema is read from the *.sql files in --schema-dir, which contain CREATE TABLE and CREATE VIEW statements.
+It is diffed against the live schema of the keyspace, as read from the primary of its first shard. The resulting plan
+of DDL statements is printed, and unless --dry-run is set, submitted via ApplySchema in an order that keeps the schema
+valid after each statement. When the statements depend on each other, --in-order-completion is added to the strategy,
+so that the migrations complete in that order.
+
+	ApplyDesiredSchema --keyspace commerce --schema-dir ./schema/commerce --dry-run
+	ApplyDesiredSchema --keyspace commerce --schema-dir ./schema/commerce --ddl-strategy "vitess --postpone-completion"`,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.NoArgs,
+		RunE:                  commandApplyDesiredSchema,
+	}
 	// GetSchema makes a GetSchema gRPC call to a vtctld.
 	GetSchema = &cobra.Command{
 		Use:                   "GetSchema [--tables TABLES ...] [--exclude-tables EXCLUDE_TABLES ...] [{--table-names-only | --table-sizes-only}] [--include-views] alias",
@@ -156,6 +179,173 @@ func commandApplySchema(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+var applyDesiredSchemaOptions = struct {
+	Keyspace         string
+	SchemaDir        string
+	DDLStrategy      string
+	MigrationContext string
+	CallerID         string
+	DryRun           bool
+}{}
+
+func commandApplyDesiredSchema(cmd *cobra.Command, args []string) error {
+	strategySetting, err := schema.ParseDDLStrategy(applyDesiredSchemaOptions.DDLStrategy)
+	if err != nil {
+		return err
+	}
+	desiredSchema, err := loadDesiredSchema(applyDesiredSchemaOptions.SchemaDir)
+	if err != nil {
+		return err
+	}
+
+	cli.FinishedParsing(cmd)
+
+	liveSchema, err := getKeyspaceSchema(commandCtx, applyDesiredSchemaOptions.Keyspace)
+	if err != nil {
+		return err
+	}
+	statements, ddlStrategy, err := planDesiredSchema(liveSchema, desiredSchema, strategySetting)
+	if err != nil {
+		return err
+	}
+	if len(statements) == 0 {
+		fmt.Printf("keyspace %s is already at the desired schema\n", applyDesiredSchemaOptions.Keyspace)
+		return nil
+	}
+
+	fmt.Printf("-- ddl strategy: %s\n", ddlStrategy)
+	for _, statement := range statements {
+		fmt.Printf("%s;\n", statement)
+	}
+	if applyDesiredSchemaOptions.DryRun {
+		return nil
+	}
+
+	var cid *vtrpc.CallerID
+	if applyDesiredSchemaOptions.CallerID != "" {
+		cid = &vtrpc.CallerID{Principal: applyDesiredSchemaOptions.CallerID}
+	}
+
+	resp, err := client.ApplySchema(commandCtx, &vtctldatapb.ApplySchemaRequest{
+		Keyspace:         applyDesiredSchemaOptions.Keyspace,
+		DdlStrategy:      ddlStrategy,
+		Sql:              statements,
+		SkipPreflight:    true,
+		MigrationContext: applyDesiredSchemaOptions.MigrationContext,
+		CallerId:         cid,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(strings.Join(resp.UuidList, "\n"))
+	return nil
+}
+
+// loadDesiredSchema reads the CREATE statements in the *.sql files of the given directory.
+func loadDesiredSchema(dir string) (*schemadiff.Schema, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .sql files found in %s", dir)
+	}
+	sort.Strings(files)
+
+	var queries []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		parts, err := sqlparser.SplitStatementToPieces(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, part := range parts {
+			if strings.TrimSpace(part) != "" {
+				queries = append(queries, part)
+			}
+		}
+	}
+	return schemadiff.NewSchemaFromQueries(queries)
+}
+
+// getKeyspaceSchema reads the schema of the keyspace from the primary of its first shard.
+func getKeyspaceSchema(ctx context.Context, keyspace string) (*schemadiff.Schema, error) {
+	shards, err := client.FindAllShardsInKeyspace(ctx, &vtctldatapb.FindAllShardsInKeyspaceRequest{
+		Keyspace: keyspace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(shards.Shards))
+	for name, shard := range shards.Shards {
+		if shard.Shard.GetPrimaryAlias() != nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no primary found in keyspace %s", keyspace)
+	}
+	sort.Strings(names)
+
+	resp, err := client.GetSchema(ctx, &vtctldatapb.GetSchemaRequest{
+		TabletAlias:     shards.Shards[names[0]].Shard.PrimaryAlias,
+		IncludeViews:    true,
+		TableSchemaOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schemaFromTableDefinitions(resp.Schema.TableDefinitions)
+}
+
+// schemaFromTableDefinitions returns the schema of the tables and views of a keyspace, without the artifact
+// tables of schema migrations and table lifecycle operations, which aren't part of a desired schema.
+func schemaFromTableDefinitions(tableDefinitions []*tabletmanagerdatapb.TableDefinition) (*schemadiff.Schema, error) {
+	queries := make([]string, 0, len(tableDefinitions))
+	for _, td := range tableDefinitions {
+		if schema.IsInternalOperationTableName(td.Name) {
+			continue
+		}
+		// The schema of views qualifies their tables with a database name placeholder
+		queries = append(queries, strings.ReplaceAll(td.Schema, "{{.DatabaseName}}.", ""))
+	}
+	return schemadiff.NewSchemaFromQueries(queries)
+}
+
+// planDesiredSchema returns the DDL statements that converge the live schema to the desired schema, in an order
+// in which they can be applied one after the other (see schemadiff.ApplyDiffsInOrder), and the strategy to submit
+// them with.
+func planDesiredSchema(liveSchema, desiredSchema *schemadiff.Schema, strategySetting *schema.DDLStrategySetting) (statements []string, ddlStrategy string, err error) {
+	schemaDiff, err := liveSchema.SchemaDiff(desiredSchema, &schemadiff.DiffHints{})
+	if err != nil {
+		return nil, "", err
+	}
+	diffs, err := schemaDiff.OrderedDiffs()
+	if err != nil {
+		return nil, "", err
+	}
+	for _, diff := range diffs {
+		statements = append(statements, diff.CanonicalStatementString())
+	}
+
+	ddlStrategy = strings.TrimSpace(fmt.Sprintf("%s %s", strategySetting.Strategy, strategySetting.Options))
+	if strategySetting.Strategy.IsDirect() {
+		// Direct DDL runs the statements one after the other
+		return statements, ddlStrategy, nil
+	}
+	if schemaDiff.HasSequentialExecutionDependencies() && strategySetting.IsAllowConcurrent() {
+		return nil, "", errors.New("--allow-concurrent is not supported: some of the migrations must run sequentially")
+	}
+	if schemaDiff.HasDependencies() && !strategySetting.IsInOrderCompletion() {
+		ddlStrategy += " --in-order-completion"
+	}
+	return statements, ddlStrategy, nil
+}
+
 var getSchemaOptions = struct {
 	Tables          []string
 	ExcludeTables   []string
@@ -302,6 +492,16 @@ func init() {
 
 	Root.AddCommand(ApplySchema)
 
+	ApplyDesiredSchema.Flags().StringVar(&applyDesiredSchemaOptions.Keyspace, "keyspace", "", "The keyspace to converge to the desired schema.")
+	ApplyDesiredSchema.MarkFlagRequired("keyspace")
+	ApplyDesiredSchema.Flags().StringVar(&applyDesiredSchemaOptions.SchemaDir, "schema-dir", "", "Path to a directory of .sql files with the CREATE TABLE and CREATE VIEW statements of the desired schema.")
+	ApplyDesiredSchema.MarkFlagRequired("schema-dir")
+	ApplyDesiredSchema.Flags().StringVar(&applyDesiredSchemaOptions.DDLStrategy, "ddl-strategy", string(schema.DDLStrategyVitess), "Online DDL strategy to submit the migrations with, compatible with @@ddl_strategy session variable.")
+	ApplyDesiredSchema.Flags().StringVar(&applyDesiredSchemaOptions.MigrationContext, "migration-context", "", "For Online DDL, optionally supply a custom unique string used as context for the migrations. By default a unique context is auto-generated by Vitess.")
+	ApplyDesiredSchema.Flags().StringVar(&applyDesiredSchemaOptions.CallerID, "caller-id", "", "Effective caller ID used for the operation and should map to an ACL name which grants this identity the necessary permissions to perform the operation (this is only necessary when strict table ACLs are used).")
+	ApplyDesiredSchema.Flags().BoolVar(&applyDesiredSchemaOptions.DryRun, "dry-run", false, "Only print the plan of DDL statements, without applying them.")
+	Root.AddCommand(ApplyDesiredSchema)
+
 	GetSchema.Flags().StringSliceVar(&getSchemaOptions.Tables, "tables", nil, "List of tables to display the schema for. Each is either an exact match, or a regular expression of the form `/regexp/`.")
 	GetSchema.Flags().StringSliceVar(&getSchemaOptions.ExcludeTables, "exclude-tables", nil, "List of tables to exclude from the result. Each is either an exact match, or a regular expression of the form `/regexp/`.")
 	GetSchema.Flags().BoolVar(&getSchemaOptions.IncludeViews, "include-views", false, "Includes views in the output in addition to base tables.")
@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/schema"
+	"vitess.io/vitess/go/vt/schemadiff"
+
+	tabletmanagerdatapb "vitess.io/vitess/go/vt/proto/tabletmanagerdata"
+)
+
+func TestLoadDesiredSchema(t *testing.T) {
+	dir := t.TempDir()
+	_, err := loadDesiredSchema(dir)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "t1.sql"), []byte("create table t1 (id int primary key);\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "v1.sql"), []byte("create view v1 as select id from t1;\ncreate table t2 (id int primary key);\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not sql"), 0o644))
+
+	desired, err := loadDesiredSchema(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"t1", "t2", "v1"}, desired.EntityNames())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.sql"), []byte("insert into t1 values (1);\n"), 0o644))
+	_, err = loadDesiredSchema(dir)
+	assert.Error(t, err)
+}
+
+func TestSchemaFromTableDefinitions(t *testing.T) {
+	live, err := schemaFromTableDefinitions([]*tabletmanagerdatapb.TableDefinition{
+		{Name: "t1", Schema: "create table t1 (id int primary key)"},
+		{Name: "_vt_HOLD_6ace8bcef73211ea87e9f875a4d24e90_20200915120410", Schema: "create table _vt_HOLD_6ace8bcef73211ea87e9f875a4d24e90_20200915120410 (id int primary key)"},
+		{Name: "v1", Schema: "create view v1 as select id from {{.DatabaseName}}.t1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"t1", "v1"}, live.EntityNames())
+
+	// The artifact table isn't in the desired schema, and isn't dropped
+	desired, err := schemadiff.NewSchemaFromSQL("create table t1 (id int primary key); create view v1 as select id from t1")
+	require.NoError(t, err)
+	strategySetting, err := schema.ParseDDLStrategy("vitess")
+	require.NoError(t, err)
+	statements, _, err := planDesiredSchema(live, desired, strategySetting)
+	require.NoError(t, err)
+	assert.Empty(t, statements)
+}
+
+func TestPlanDesiredSchema(t *testing.T) {
+	tcases := []struct {
+		name        string
+		live        string
+		desired     string
+		ddlStrategy string
+		statements  []string
+		strategy    string
+	}{
+		{
+			name:        "no changes",
+			live:        "create table t1 (id int primary key)",
+			desired:     "create table t1 (id int primary key)",
+			ddlStrategy: "vitess",
+			strategy:    "vitess",
+		},
+		{
+			name:        "independent changes",
+			live:        "create table t1 (id int primary key); create table t2 (id int primary key)",
+			desired:     "create table t1 (id int primary key, i int); create table t3 (id int primary key)",
+			ddlStrategy: "vitess --postpone-completion",
+			statements: []string{
+				"DROP TABLE `t2`",
+				"ALTER TABLE `t1` ADD COLUMN `i` int",
+				"CREATE TABLE `t3` (\n\t`id` int,\n\tPRIMARY KEY (`id`)\n)",
+			},
+			strategy: "vitess --postpone-completion",
+		},
+		{
+			name:        "dependent changes",
+			live:        "create table t1 (id int primary key)",
+			desired:     "create table t1 (id int primary key, i int); create view v1 as select id, i from t1",
+			ddlStrategy: "vitess",
+			statements: []string{
+				"ALTER TABLE `t1` ADD COLUMN `i` int",
+				"CREATE VIEW `v1` AS SELECT `id`, `i` FROM `t1`",
+			},
+			strategy: "vitess --in-order-completion",
+		},
+		{
+			name:        "dependent changes, direct",
+			live:        "create table t1 (id int primary key)",
+			desired:     "create table t1 (id int primary key, i int); create view v1 as select id, i from t1",
+			ddlStrategy: "direct",
+			statements: []string{
+				"ALTER TABLE `t1` ADD COLUMN `i` int",
+				"CREATE VIEW `v1` AS SELECT `id`, `i` FROM `t1`",
+			},
+			strategy: "direct",
+		},
+		{
+			name:        "dependent changes, allow concurrent",
+			live:        "create table t1 (id int primary key)",
+			desired:     "create table t1 (id int primary key, i int); create view v1 as select id, i from t1",
+			ddlStrategy: "vitess --allow-concurrent",
+			statements: []string{
+				"ALTER TABLE `t1` ADD COLUMN `i` int",
+				"CREATE VIEW `v1` AS SELECT `id`, `i` FROM `t1`",
+			},
+			strategy: "vitess --allow-concurrent --in-order-completion",
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			live, err := schemadiff.NewSchemaFromSQL(tcase.live)
+			require.NoError(t, err)
+			desired, err := schemadiff.NewSchemaFromSQL(tcase.desired)
+			require.NoError(t, err)
+			strategySetting, err := schema.ParseDDLStrategy(tcase.ddlStrategy)
+			require.NoError(t, err)
+
+			statements, strategy, err := planDesiredSchema(live, desired, strategySetting)
+			require.NoError(t, err)
+			assert.Equal(t, tcase.statements, statements)
+			assert.Equal(t, tcase.strategy, strategy)
+		})
+	}
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lookupvindex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"vitess.io/vitess/go/cmd/vtctldclient/cli"
+	"vitess.io/vitess/go/cmd/vtctldclient/command/vreplication/common"
+	"vitess.io/vitess/go/json2"
+	"vitess.io/vitess/go/vt/topo/topoproto"
+
+	tabletmanagerdatapb "vitess.io/vitess/go/vt/proto/tabletmanagerdata"
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+	vschemapb "vitess.io/vitess/go/vt/proto/vschema"
+	vtctldatapb "vitess.io/vitess/go/vt/proto/vtctldata"
+)
+
+// statusInterval is how often create checks on the backfill of a lookup
+// vindex that it externalizes once the backfill has completed.
+var statusInterval = 10 * time.Second
+
+var (
+	baseOptions = struct {
+		// Keyspace is the keyspace of the table the lookup vindex is on.
+		Keyspace string
+		// Name is the name of the lookup vindex.
+		Name string
+	}{}
+
+	createOptions = struct {
+		Cells                        []string
+		TabletTypes                  []topodatapb.TabletType
+		TabletTypesInPreferenceOrder bool
+		VSchema                      string
+		ContinueAfterCopyWithOwner   bool
+		AutoExternalize              bool
+	}{}
+
+	externalizeOptions = struct {
+		SkipVerify bool
+	}{}
+
+	// lookupVindex is the base command for all actions related to lookup vindexes.
+	lookupVindex = &cobra.Command{
+		Use:   "LookupVindex --name <name> --table-keyspace <keyspace> [command] [command-flags]",
+		Short: "Perform commands related to creating, backfilling, and externalizing lookup vindexes.",
+		Long: `LookupVindex commands: Create, Status, and Externalize.
+See the --help output for each command for more details.`,
+		DisableFlagsInUseLine: true,
+		Aliases:               []string{"lookupvindex"},
+		Args:                  cobra.ExactArgs(1),
+	}
+
+	// create makes a LookupVindexCreate gRPC call to a vtctld.
+	create = &cobra.Command{
+		Use:                   "create",
+		Short:                 "Create the lookup vindex in the specified keyspace and backfill it with a VReplication workflow.",
+		Example:               `vtctldclient --server localhost:15999 LookupVindex --name corder_lookup_vdx --table-keyspace customer create --vschema '{"sharded":true,"vindexes":{"corder_lookup_vdx":{"type":"consistent_lookup_unique","params":{"table":"customer.corder_lookup","from":"sku","to":"keyspace_id"},"owner":"corder"}},"tables":{"corder":{"column_vindexes":[{"column":"sku","name":"corder_lookup_vdx"}]}}}' --auto-externalize`,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Aliases:               []string{"Create"},
+		Args:                  cobra.NoArgs,
+		RunE:                  commandCreate,
+	}
+
+	// externalize makes a LookupVindexExternalize gRPC call to a vtctld.
+	externalize = &cobra.Command{
+		Use:                   "externalize",
+		Short:                 "Verify the lookup table of the lookup vindex against its owner table and externalize the vindex.",
+		Example:               `vtctldclient --server localhost:15999 LookupVindex --name corder_lookup_vdx --table-keyspace customer externalize`,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Aliases:               []string{"Externalize"},
+		Args:                  cobra.NoArgs,
+		RunE:                  commandExternalize,
+	}
+
+	// status makes a LookupVindexStatus gRPC call to a vtctld.
+	status = &cobra.Command{
+		Use:                   "status",
+		Short:                 "Show the progress of the backfill of the lookup vindex.",
+		Example:               `vtctldclient --server localhost:15999 LookupVindex --name corder_lookup_vdx --table-keyspace customer status`,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Aliases:               []string{"Status"},
+		Args:                  cobra.NoArgs,
+		RunE:                  commandStatus,
+	}
+)
+
+func commandCreate(cmd *cobra.Command, args []string) error {
+	var specs vschemapb.Keyspace
+	if err := json2.Unmarshal([]byte(createOptions.VSchema), &specs); err != nil {
+		return fmt.Errorf("invalid vschema: %w", err)
+	}
+	if _, ok := specs.Vindexes[baseOptions.Name]; !ok {
+		return fmt.Errorf("vindex %s not found in the vschema", baseOptions.Name)
+	}
+	tabletTypes := createOptions.TabletTypes
+	if !cmd.Flags().Lookup("tablet-types").Changed {
+		tabletTypes = []topodatapb.TabletType{topodatapb.TabletType_PRIMARY}
+	}
+	tsp := tabletmanagerdatapb.TabletSelectionPreference_ANY
+	if createOptions.TabletTypesInPreferenceOrder {
+		tsp = tabletmanagerdatapb.TabletSelectionPreference_INORDER
+	}
+	cli.FinishedParsing(cmd)
+
+	resp, err := common.GetClient().LookupVindexCreate(common.GetCommandCtx(), &vtctldatapb.LookupVindexCreateRequest{
+		Keyspace:                   baseOptions.Keyspace,
+		Cells:                      createOptions.Cells,
+		Vindex:                     &specs,
+		ContinueAfterCopyWithOwner: createOptions.ContinueAfterCopyWithOwner,
+		TabletTypes:                tabletTypes,
+		TabletSelectionPreference:  tsp,
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := cli.MarshalJSON(resp)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", data)
+
+	if !createOptions.AutoExternalize {
+		return nil
+	}
+	if err := waitForBackfill(common.GetCommandCtx()); err != nil {
+		return fmt.Errorf("the %s workflow continues to backfill the lookup table, externalize the vindex once it has completed: %w", resp.Workflow, err)
+	}
+	return externalizeLookupVindex(common.GetCommandCtx())
+}
+
+// waitForBackfill waits until the lookup vindex can be externalized.
+func waitForBackfill(ctx context.Context) error {
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+	for {
+		resp, err := common.GetClient().LookupVindexStatus(ctx, &vtctldatapb.LookupVindexStatusRequest{
+			Keyspace: baseOptions.Keyspace,
+			Name:     baseOptions.Name,
+		})
+		if err != nil {
+			return err
+		}
+		switch resp.State {
+		case "Copied", "Externalized":
+			return nil
+		case "Error":
+			return fmt.Errorf("the backfill failed: %s", resp.Message)
+		}
+		fmt.Printf("Backfill of the %s lookup vindex: %d of about %d rows copied (%.2f%%)\n",
+			baseOptions.Name, resp.RowsCopied, resp.RowsTotal, resp.RowsPercentage)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func commandExternalize(cmd *cobra.Command, args []string) error {
+	cli.FinishedParsing(cmd)
+
+	return externalizeLookupVindex(common.GetCommandCtx())
+}
+
+func externalizeLookupVindex(ctx context.Context) error {
+	resp, err := common.GetClient().LookupVindexExternalize(ctx, &vtctldatapb.LookupVindexExternalizeRequest{
+		Keyspace:   baseOptions.Keyspace,
+		Name:       baseOptions.Name,
+		SkipVerify: externalizeOptions.SkipVerify,
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := cli.MarshalJSON(resp)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", data)
+	return nil
+}
+
+func commandStatus(cmd *cobra.Command, args []string) error {
+	cli.FinishedParsing(cmd)
+
+	resp, err := common.GetClient().LookupVindexStatus(common.GetCommandCtx(), &vtctldatapb.LookupVindexStatusRequest{
+		Keyspace: baseOptions.Keyspace,
+		Name:     baseOptions.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := cli.MarshalJSON(resp)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", data)
+	return nil
+}
+
+func registerCommands(root *cobra.Command) {
+	lookupVindex.PersistentFlags().StringVar(&baseOptions.Name, "name", "", "The name of the lookup vindex (required).")
+	lookupVindex.MarkPersistentFlagRequired("name")
+	lookupVindex.PersistentFlags().StringVar(&baseOptions.Keyspace, "table-keyspace", "", "The keyspace of the table that the lookup vindex is on (required).")
+	lookupVindex.MarkPersistentFlagRequired("table-keyspace")
+	root.AddCommand(lookupVindex)
+
+	create.Flags().StringSliceVarP(&createOptions.Cells, "cells", "c", nil, "Cells to look in for source tablets to replicate from.")
+	create.Flags().Var((*topoproto.TabletTypeListFlag)(&createOptions.TabletTypes), "tablet-types", "Source tablet types to replicate from (e.g. PRIMARY,REPLICA,RDONLY).")
+	create.Flags().BoolVar(&createOptions.TabletTypesInPreferenceOrder, "tablet-types-in-preference-order", true, "When performing source tablet selection, look for candidates in the type order as they are listed in the tablet-types flag.")
+	create.Flags().StringVar(&createOptions.VSchema, "vschema", "", "The JSON vschema of the lookup vindex and the table it is on (required).")
+	create.MarkFlagRequired("vschema")
+	create.Flags().BoolVar(&createOptions.ContinueAfterCopyWithOwner, "continue-after-copy-with-owner", false, "Keep the workflow running after the backfill of a vindex with an owner to replicate changes.")
+	create.Flags().BoolVar(&createOptions.AutoExternalize, "auto-externalize", false, "Wait for the backfill to complete, then verify the lookup table against the owner table and externalize the vindex. The workflow keeps backfilling the lookup table if the command is interrupted.")
+	lookupVindex.AddCommand(create)
+
+	externalize.Flags().BoolVar(&externalizeOptions.SkipVerify, "skip-verify", false, "Externalize the vindex without verifying its lookup table against the owner table.")
+	lookupVindex.AddCommand(externalize)
+
+	lookupVindex.AddCommand(status)
+}
+
+func init() {
+	common.RegisterCommandHandler("LookupVindex", registerCommands)
+}
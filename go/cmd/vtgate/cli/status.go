@@ -22,6 +22,7 @@ import (
 	"vitess.io/vitess/go/vt/srvtopo"
 	_ "vitess.io/vitess/go/vt/status"
 	"vitess.io/vitess/go/vt/vtgate"
+	"vitess.io/vitess/go/vt/vtgate/balancer"
 )
 
 func addStatusParts(vtg *vtgate.VTGate) {
@@ -31,6 +32,9 @@ func addStatusParts(vtg *vtgate.VTGate) {
 	servenv.AddStatusPart("VSchema", vtgate.VSchemaTemplate, func() any {
 		return vtg.VSchemaStats()
 	})
+	servenv.AddStatusPart("Query Quotas", vtgate.QueryQuotasTemplate, func() any {
+		return vtg.QueryQuotasStatus()
+	})
 	servenv.AddStatusFuncs(srvtopo.StatusFuncs)
 	servenv.AddStatusPart("Topology Cache", srvtopo.TopoTemplate, func() any {
 		return resilientServer.CacheStatus()
@@ -41,4 +45,7 @@ func addStatusParts(vtg *vtgate.VTGate) {
 	servenv.AddStatusPart("Health Check Cache", discovery.HealthCheckTemplate, func() any {
 		return vtg.Gateway().TabletsCacheStatus()
 	})
+	servenv.AddStatusPart("Tablet Balancer", balancer.StatusTemplate, func() any {
+		return vtg.Gateway().BalancerStatus()
+	})
 }
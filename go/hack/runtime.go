@@ -19,6 +19,8 @@ limitations under the License.
 package hack
 
 import (
+	"strconv"
+	"strings"
 	"unsafe"
 )
 
@@ -52,5 +54,54 @@ func RuntimeAllocSize(size int64) int64 {
 	return int64(roundupsize(uintptr(size)))
 }
 
-//go:linkname ParseFloatPrefix strconv.parseFloatPrefix
-func ParseFloatPrefix(s string, bitSize int) (float64, int, error)
+// ParseFloatPrefix is a local stand-in for strconv.parseFloatPrefix, which
+// is not available to linkname on this toolchain.
+func ParseFloatPrefix(s string, bitSize int) (float64, int, error) {
+	n := floatPrefixLen(s)
+	if n == 0 {
+		return 0, 0, &strconv.NumError{Func: "ParseFloat", Num: s, Err: strconv.ErrSyntax}
+	}
+	f, err := strconv.ParseFloat(s[:n], bitSize)
+	return f, n, err
+}
+
+func floatPrefixLen(s string) int {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	for _, special := range []string{"infinity", "inf", "nan"} {
+		if len(s)-i >= len(special) && strings.EqualFold(s[i:i+len(special)], special) {
+			return i + len(special)
+		}
+	}
+	digits := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		k := j
+		for k < len(s) && s[k] >= '0' && s[k] <= '9' {
+			k++
+		}
+		if k > j {
+			i = k
+		}
+	}
+	return i
+}
@@ -0,0 +1,332 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package binlog
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"vitess.io/vitess/go/mysql/datetime"
+	"vitess.io/vitess/go/sqltypes"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	"vitess.io/vitess/go/vt/proto/vtrpc"
+	"vitess.io/vitess/go/vt/vterrors"
+)
+
+// maxDecimalPrecision is the maximum number of digits of a DECIMAL column.
+const maxDecimalPrecision = 65
+
+// TypeForField returns the binlog column type and metadata used to
+// encode the values of the given field in row based replication events.
+// It is the counterpart of CellValue: values encoded with CellBytes using
+// the returned type and metadata decode back to the same value.
+// Text based types, JSON included, are all sent as strings or blobs.
+func TypeForField(field *querypb.Field) (byte, uint16) {
+	switch field.Type {
+	case querypb.Type_INT8, querypb.Type_UINT8:
+		return TypeTiny, 0
+	case querypb.Type_INT16, querypb.Type_UINT16:
+		return TypeShort, 0
+	case querypb.Type_INT24, querypb.Type_UINT24:
+		return TypeInt24, 0
+	case querypb.Type_INT32, querypb.Type_UINT32:
+		return TypeLong, 0
+	case querypb.Type_INT64, querypb.Type_UINT64:
+		return TypeLongLong, 0
+	case querypb.Type_YEAR:
+		return TypeYear, 0
+	case querypb.Type_FLOAT32:
+		return TypeFloat, 4
+	case querypb.Type_FLOAT64:
+		return TypeDouble, 8
+	case querypb.Type_DECIMAL:
+		scale := int(field.Decimals)
+		// the column length of a decimal counts its sign and its decimal point
+		precision := int(field.ColumnLength)
+		if field.Flags&uint32(querypb.MySqlFlag_UNSIGNED_FLAG) == 0 {
+			precision--
+		}
+		if scale > 0 {
+			precision--
+		}
+		if scale > 30 {
+			scale = 30
+		}
+		if precision < scale || precision <= 0 || precision > maxDecimalPrecision {
+			precision = maxDecimalPrecision
+		}
+		return TypeNewDecimal, uint16(precision)<<8 | uint16(scale)
+	case querypb.Type_DATE:
+		return TypeDate, 0
+	case querypb.Type_TIME:
+		return TypeTime2, fractionalPrecision(field)
+	case querypb.Type_DATETIME:
+		return TypeDateTime2, fractionalPrecision(field)
+	case querypb.Type_TIMESTAMP:
+		return TypeTimestamp2, fractionalPrecision(field)
+	case querypb.Type_BIT:
+		bits := uint16(field.ColumnLength)
+		if bits == 0 || bits > 64 {
+			bits = 64
+		}
+		return TypeBit, (bits/8)<<8 | bits%8
+	case querypb.Type_VARCHAR, querypb.Type_CHAR, querypb.Type_VARBINARY, querypb.Type_BINARY,
+		querypb.Type_ENUM, querypb.Type_SET:
+		if field.ColumnLength > 0 && field.ColumnLength <= math.MaxUint16 {
+			return TypeVarchar, uint16(field.ColumnLength)
+		}
+		return TypeVarchar, math.MaxUint16
+	default:
+		// TEXT, BLOB, JSON, GEOMETRY and anything else we don't know better about
+		return TypeBlob, 4
+	}
+}
+
+func fractionalPrecision(field *querypb.Field) uint16 {
+	if field.Decimals > 6 {
+		return 0
+	}
+	return uint16(field.Decimals)
+}
+
+// CellBytes encodes a value in the format used by row based replication
+// events for a column of the given type and metadata.
+func CellBytes(value sqltypes.Value, typ byte, metadata uint16) ([]byte, error) {
+	raw := value.Raw()
+	switch typ {
+	case TypeTiny, TypeShort, TypeInt24, TypeLong, TypeLongLong:
+		size := map[byte]int{TypeTiny: 1, TypeShort: 2, TypeInt24: 3, TypeLong: 4, TypeLongLong: 8}[typ]
+		var val uint64
+		if value.IsSigned() || (!value.IsUnsigned() && strings.HasPrefix(string(raw), "-")) {
+			v, err := strconv.ParseInt(string(raw), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			val = uint64(v)
+		} else {
+			v, err := strconv.ParseUint(string(raw), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			val = v
+		}
+		out := make([]byte, 8)
+		binary.LittleEndian.PutUint64(out, val)
+		return out[:size], nil
+	case TypeYear:
+		year, err := strconv.ParseUint(string(raw), 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		if year == 0 {
+			return []byte{0}, nil
+		}
+		return []byte{byte(year - 1900)}, nil
+	case TypeFloat:
+		f, err := strconv.ParseFloat(string(raw), 32)
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint32(nil, math.Float32bits(float32(f))), nil
+	case TypeDouble:
+		f, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint64(nil, math.Float64bits(f)), nil
+	case TypeNewDecimal:
+		return decimalBytes(string(raw), int(metadata>>8), int(metadata&0xff))
+	case TypeDate:
+		d, ok := datetime.ParseDate(string(raw))
+		if !ok && !isZeroDate(raw) {
+			return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "invalid date value: %s", raw)
+		}
+		val := uint32(d.Year())<<9 | uint32(d.Month())<<5 | uint32(d.Day())
+		return []byte{byte(val), byte(val >> 8), byte(val >> 16)}, nil
+	case TypeDateTime2:
+		dt, _, ok := datetime.ParseDateTime(string(raw), -1)
+		if !ok && !isZeroDate(raw) {
+			return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "invalid datetime value: %s", raw)
+		}
+		ym := uint64(dt.Date.Year())*13 + uint64(dt.Date.Month())
+		ymd := ym<<5 | uint64(dt.Date.Day())
+		hms := uint64(dt.Time.Hour())<<12 | uint64(dt.Time.Minute())<<6 | uint64(dt.Time.Second())
+		packed := (ymd<<17 | hms) + 0x8000000000
+		out := []byte{byte(packed >> 32), byte(packed >> 24), byte(packed >> 16), byte(packed >> 8), byte(packed)}
+		return appendFraction(out, dt.Time.Nanosecond()/1000, metadata, false), nil
+	case TypeTimestamp2:
+		if isZeroDate(raw) {
+			return appendFraction(make([]byte, 4), 0, metadata, false), nil
+		}
+		dt, _, ok := datetime.ParseDateTime(string(raw), -1)
+		if !ok {
+			return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "invalid timestamp value: %s", raw)
+		}
+		// timestamps are decoded as UTC, see printTimestamp
+		t := dt.ToStdTime(time.UTC)
+		out := binary.BigEndian.AppendUint32(nil, uint32(t.Unix()))
+		return appendFraction(out, dt.Time.Nanosecond()/1000, metadata, false), nil
+	case TypeTime2:
+		t, _, ok := datetime.ParseTime(string(raw), -1)
+		if !ok {
+			return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "invalid time value: %s", raw)
+		}
+		hms := int64(t.Hour())<<12 | int64(t.Minute())<<6 | int64(t.Second())
+		frac := t.Nanosecond() / 1000
+		neg := t.Neg()
+		if neg {
+			// negative values store the complement of the fractional part,
+			// borrowing one second from the integral part
+			if fractionValue(frac, metadata) != 0 {
+				hms++
+			}
+			hms = -hms
+		}
+		packed := hms + 0x800000
+		out := []byte{byte(packed >> 16), byte(packed >> 8), byte(packed)}
+		return appendFraction(out, frac, metadata, neg), nil
+	case TypeBit:
+		l := int((metadata>>8)*8+metadata&0xff+7) / 8
+		if len(raw) > l {
+			return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "bit value too long: %d bytes", len(raw))
+		}
+		out := make([]byte, l)
+		copy(out[l-len(raw):], raw)
+		return out, nil
+	case TypeVarchar:
+		if metadata > 255 {
+			if len(raw) > math.MaxUint16 {
+				return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "string value too long: %d bytes", len(raw))
+			}
+			out := binary.LittleEndian.AppendUint16(nil, uint16(len(raw)))
+			return append(out, raw...), nil
+		}
+		if len(raw) > 255 {
+			return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "string value too long: %d bytes", len(raw))
+		}
+		return append([]byte{byte(len(raw))}, raw...), nil
+	case TypeBlob:
+		if metadata < 1 || metadata > 4 {
+			return nil, vterrors.Errorf(vtrpc.Code_INTERNAL, "unsupported blob metadata value %v", metadata)
+		}
+		if uint64(len(raw)) >= uint64(1)<<(8*metadata) {
+			return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "blob value too long: %d bytes", len(raw))
+		}
+		out := binary.LittleEndian.AppendUint32(nil, uint32(len(raw)))[:metadata]
+		return append(out, raw...), nil
+	default:
+		return nil, vterrors.Errorf(vtrpc.Code_INTERNAL, "unsupported type %v", typ)
+	}
+}
+
+func isZeroDate(raw []byte) bool {
+	return strings.HasPrefix(string(raw), "0000-00-00")
+}
+
+// fractionValue returns the stored fractional part of a temporal value:
+// two digits per byte, so precision 1 and 2 use one byte, and so on.
+func fractionValue(micros int, metadata uint16) int {
+	switch metadata {
+	case 1, 2:
+		return micros / 10000
+	case 3, 4:
+		return micros / 100
+	case 5, 6:
+		return micros
+	}
+	return 0
+}
+
+func appendFraction(out []byte, micros int, metadata uint16, neg bool) []byte {
+	frac := fractionValue(micros, metadata)
+	size := (int(metadata) + 1) / 2
+	if neg && frac != 0 {
+		frac = 1<<(8*size) - frac
+	}
+	for i := size - 1; i >= 0; i-- {
+		out = append(out, byte(frac>>(8*i)))
+	}
+	return out
+}
+
+// decimalBytes encodes a decimal in the binary format of MySQL, the reverse of what CellValue does
+func decimalBytes(val string, precision, scale int) ([]byte, error) {
+	neg := strings.HasPrefix(val, "-")
+	val = strings.TrimPrefix(val, "-")
+	intPart, fracPart, _ := strings.Cut(val, ".")
+	intPart = strings.TrimLeft(intPart, "0")
+
+	intg := precision - scale
+	if len(intPart) > intg {
+		return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "decimal value %s out of range for precision %d and scale %d", val, precision, scale)
+	}
+	if len(fracPart) > scale {
+		fracPart = fracPart[:scale]
+	}
+	intPart = strings.Repeat("0", intg-len(intPart)) + intPart
+	fracPart += strings.Repeat("0", scale-len(fracPart))
+
+	intg0x := intg % 9
+	frac0x := scale % 9
+
+	var out []byte
+	appendDigits := func(digits string, size int) error {
+		if size == 0 {
+			return nil
+		}
+		v, err := strconv.ParseUint(digits, 10, 32)
+		if err != nil {
+			return err
+		}
+		for i := size - 1; i >= 0; i-- {
+			out = append(out, byte(v>>(8*i)))
+		}
+		return nil
+	}
+
+	// leftover integral digits first, then full groups of 9 digits
+	if err := appendDigits(intPart[:intg0x], dig2bytes[intg0x]); err != nil {
+		return nil, err
+	}
+	for pos := intg0x; pos < intg; pos += 9 {
+		if err := appendDigits(intPart[pos:pos+9], 4); err != nil {
+			return nil, err
+		}
+	}
+	// full groups of fractional digits, then the leftover ones
+	pos := 0
+	for ; pos+9 <= scale; pos += 9 {
+		if err := appendDigits(fracPart[pos:pos+9], 4); err != nil {
+			return nil, err
+		}
+	}
+	if err := appendDigits(fracPart[pos:], dig2bytes[frac0x]); err != nil {
+		return nil, err
+	}
+
+	if neg {
+		// negative numbers are just inverted bytes
+		for i := range out {
+			out[i] ^= 0xff
+		}
+	}
+	out[0] ^= 0x80 // first bit is inverted
+	return out, nil
+}
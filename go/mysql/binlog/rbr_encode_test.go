@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package binlog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/sqltypes"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+)
+
+func TestCellBytesRoundTrip(t *testing.T) {
+	testcases := []struct {
+		field *querypb.Field
+		in    string
+		// out is the decoded value, when it differs from the input
+		out string
+	}{
+		{field: &querypb.Field{Type: querypb.Type_INT8}, in: "-2"},
+		{field: &querypb.Field{Type: querypb.Type_UINT8}, in: "250"},
+		{field: &querypb.Field{Type: querypb.Type_INT16}, in: "-12345"},
+		{field: &querypb.Field{Type: querypb.Type_INT24}, in: "-1234567"},
+		{field: &querypb.Field{Type: querypb.Type_UINT24}, in: "12345678"},
+		{field: &querypb.Field{Type: querypb.Type_INT32}, in: "-2147483648"},
+		{field: &querypb.Field{Type: querypb.Type_UINT32}, in: "4294967295"},
+		{field: &querypb.Field{Type: querypb.Type_INT64}, in: "-9223372036854775808"},
+		{field: &querypb.Field{Type: querypb.Type_UINT64}, in: "18446744073709551615"},
+		{field: &querypb.Field{Type: querypb.Type_YEAR}, in: "2023"},
+		{field: &querypb.Field{Type: querypb.Type_FLOAT32}, in: "1.5", out: "1.5E+00"},
+		{field: &querypb.Field{Type: querypb.Type_FLOAT64}, in: "-3.25", out: "-3.25E+00"},
+		{field: &querypb.Field{Type: querypb.Type_DECIMAL, ColumnLength: 13, Decimals: 2}, in: "123456789.01"},
+		{field: &querypb.Field{Type: querypb.Type_DECIMAL, ColumnLength: 12, Decimals: 2}, in: "-1234.5", out: "-1234.50"},
+		{field: &querypb.Field{Type: querypb.Type_DECIMAL, ColumnLength: 23, Decimals: 10}, in: "98765432109.0123456789"},
+		{field: &querypb.Field{Type: querypb.Type_DECIMAL, ColumnLength: 6}, in: "-12345"},
+		{field: &querypb.Field{Type: querypb.Type_DATE}, in: "2023-08-17"},
+		{field: &querypb.Field{Type: querypb.Type_DATETIME}, in: "2023-08-17 12:34:56"},
+		{field: &querypb.Field{Type: querypb.Type_DATETIME, Decimals: 3}, in: "2023-08-17 12:34:56.789"},
+		{field: &querypb.Field{Type: querypb.Type_DATETIME, Decimals: 6}, in: "0000-00-00 00:00:00.000000"},
+		{field: &querypb.Field{Type: querypb.Type_TIMESTAMP, Decimals: 2}, in: "2023-08-17 12:34:56.78"},
+		{field: &querypb.Field{Type: querypb.Type_TIMESTAMP}, in: "0000-00-00 00:00:00"},
+		{field: &querypb.Field{Type: querypb.Type_TIME}, in: "838:59:59"},
+		{field: &querypb.Field{Type: querypb.Type_TIME, Decimals: 1}, in: "-12:34:56.5"},
+		{field: &querypb.Field{Type: querypb.Type_TIME, Decimals: 6}, in: "-00:00:01.000001"},
+		{field: &querypb.Field{Type: querypb.Type_BIT, ColumnLength: 12}, in: "\x0a\xbc"},
+		{field: &querypb.Field{Type: querypb.Type_VARCHAR, ColumnLength: 40}, in: "abc"},
+		{field: &querypb.Field{Type: querypb.Type_VARBINARY, ColumnLength: 1000}, in: "a longer value"},
+		{field: &querypb.Field{Type: querypb.Type_ENUM}, in: "small"},
+		{field: &querypb.Field{Type: querypb.Type_TEXT}, in: "some text"},
+		{field: &querypb.Field{Type: querypb.Type_JSON}, in: `{"a": 1}`},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.field.Type.String()+" "+tc.in, func(t *testing.T) {
+			typ, metadata := TypeForField(tc.field)
+			data, err := CellBytes(sqltypes.MakeTrusted(tc.field.Type, []byte(tc.in)), typ, metadata)
+			require.NoError(t, err)
+
+			l, err := CellLength(data, 0, typ, metadata)
+			require.NoError(t, err)
+			assert.Equal(t, len(data), l)
+
+			value, l, err := CellValue(data, 0, typ, metadata, tc.field)
+			require.NoError(t, err)
+			assert.Equal(t, len(data), l)
+			out := tc.out
+			if out == "" {
+				out = tc.in
+			}
+			assert.Equal(t, out, value.ToString())
+		})
+	}
+}
+
+func TestCellBytesErrors(t *testing.T) {
+	_, err := CellBytes(sqltypes.NewVarChar("1234.5"), TypeNewDecimal, 3<<8|1)
+	assert.ErrorContains(t, err, "out of range")
+
+	_, err = CellBytes(sqltypes.NewVarChar("2023-13-45"), TypeDate, 0)
+	assert.ErrorContains(t, err, "invalid date value")
+}
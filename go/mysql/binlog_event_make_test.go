@@ -189,6 +189,24 @@ func TestMariadDBGTIDEVent(t *testing.T) {
 	}
 }
 
+func TestMySQL56GTIDEvent(t *testing.T) {
+	f := NewMySQL56BinlogFormat()
+	s := NewFakeBinlogStream()
+
+	sid, err := replication.ParseSID("16b1039f-22b6-11ed-b765-0a43f95f28a3")
+	require.NoError(t, err)
+	event := NewMySQL56GTIDEvent(f, s, replication.Mysql56GTID{Server: sid, Sequence: 0x123456789abcdef})
+	require.True(t, event.IsValid(), "NewMySQL56GTIDEvent().IsValid() is false")
+	require.True(t, event.IsGTID(), "NewMySQL56GTIDEvent().IsGTID() if false")
+
+	event, _, err = event.StripChecksum(f)
+	require.NoError(t, err, "StripChecksum failed: %v", err)
+
+	gtid, _, err := event.GTID(f)
+	require.NoError(t, err, "NewMySQL56GTIDEvent().GTID() returned error: %v", err)
+	assert.Equal(t, replication.Mysql56GTID{Server: sid, Sequence: 0x123456789abcdef}, gtid)
+}
+
 func TestTableMapEvent(t *testing.T) {
 	f := NewMySQL56BinlogFormat()
 	s := NewFakeBinlogStream()
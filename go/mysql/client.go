@@ -174,7 +174,9 @@ func Connect(ctx context.Context, params *ConnParams) (*Conn, error) {
 // Ping implements mysql ping command.
 func (c *Conn) Ping() error {
 	// This is a new command, need to reset the sequence.
-	c.sequence = 0
+	if err := c.resetSequence(); err != nil {
+		return sqlerror.NewSQLError(sqlerror.CRServerGone, sqlerror.SSUnknownSQLState, "%v", err)
+	}
 	data, pos := c.startEphemeralPacketWithHeader(1)
 	data[pos] = ComPing
 
@@ -300,6 +302,24 @@ func (c *Conn) clientHandshake(params *ConnParams) error {
 		return sqlerror.NewSQLError(sqlerror.CRSSLConnectionError, sqlerror.SSUnknownSQLState, "server doesn't support ClientSessionTrack but client asked for it")
 	}
 
+	// Use the compressed protocol if we asked for it and the server
+	// supports it. Otherwise we silently fall back to the regular one.
+	c.compressionAlgorithm = CompressionNone
+	switch params.Compression {
+	case CompressionZlib:
+		if capabilities&CapabilityClientCompress != 0 {
+			c.compressionAlgorithm = CompressionZlib
+		}
+	case CompressionZstd:
+		if capabilities&CapabilityClientZstdCompressionAlgorithm != 0 {
+			c.compressionAlgorithm = CompressionZstd
+			c.zstdCompressionLevel = params.ZstdCompressionLevel
+			if c.zstdCompressionLevel <= 0 {
+				c.zstdCompressionLevel = defaultZstdCompressionLevel
+			}
+		}
+	}
+
 	// Build and send our handshake response 41.
 	// Note this one will never have SSL flag on.
 	if err := c.writeHandshakeResponse41(capabilities, scrambledPassword, charset, params); err != nil {
@@ -311,6 +331,9 @@ func (c *Conn) clientHandshake(params *ConnParams) error {
 		return err
 	}
 
+	// The compressed protocol, if negotiated, starts after the OK packet.
+	c.startCompression()
+
 	// If the server didn't support DbName in its handshake, set
 	// it now. This is what the 'mysql' client does.
 	if capabilities&CapabilityClientConnectWithDB == 0 && params.DbName != "" {
@@ -544,6 +567,13 @@ func (c *Conn) writeHandshakeResponse41(capabilities uint32, scrambledPassword [
 		// CapabilityClientSessionTrack, we also support it.
 		c.Capabilities&CapabilityClientSessionTrack
 
+	switch c.compressionAlgorithm {
+	case CompressionZlib:
+		capabilityFlags |= CapabilityClientCompress
+	case CompressionZstd:
+		capabilityFlags |= CapabilityClientZstdCompressionAlgorithm
+	}
+
 	// FIXME(alainjobart) add multi statement.
 
 	length :=
@@ -569,6 +599,11 @@ func (c *Conn) writeHandshakeResponse41(capabilities uint32, scrambledPassword [
 		length++
 	}
 
+	// The zstd compression level comes last.
+	if c.compressionAlgorithm == CompressionZstd {
+		length++
+	}
+
 	data, pos := c.startEphemeralPacketWithHeader(length)
 
 	// Client capability flags.
@@ -605,6 +640,10 @@ func (c *Conn) writeHandshakeResponse41(capabilities uint32, scrambledPassword [
 	// Assume native client during response
 	pos = writeNullString(data, pos, string(c.authPluginName))
 
+	if c.compressionAlgorithm == CompressionZstd {
+		pos = writeByte(data, pos, byte(c.zstdCompressionLevel))
+	}
+
 	// Sanity-check the length.
 	if pos != len(data) {
 		return sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "writeHandshakeResponse41: only packed %v bytes, out of %v allocated", pos, len(data))
@@ -0,0 +1,325 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysql
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+	"vitess.io/vitess/go/vt/vterrors"
+)
+
+// CompressionAlgorithm is an algorithm that can be negotiated for the
+// compressed client/server protocol.
+type CompressionAlgorithm string
+
+const (
+	// CompressionNone means the connection does not use the compressed protocol.
+	CompressionNone = CompressionAlgorithm("")
+
+	// CompressionZlib is negotiated with CapabilityClientCompress.
+	CompressionZlib = CompressionAlgorithm("zlib")
+
+	// CompressionZstd is negotiated with CapabilityClientZstdCompressionAlgorithm.
+	CompressionZstd = CompressionAlgorithm("zstd")
+)
+
+const (
+	// compressedPacketHeaderSize is the size of the header of a compressed
+	// packet: 3 bytes of compressed payload length, 1 byte of sequence
+	// and 3 bytes of payload length before compression.
+	compressedPacketHeaderSize = 7
+
+	// minCompressLength is the payload size under which we send packets
+	// uncompressed. This is MIN_COMPRESS_LENGTH in MySQL.
+	minCompressLength = 50
+
+	// defaultZstdCompressionLevel is the level MySQL uses when the client
+	// does not ask for a specific one.
+	defaultZstdCompressionLevel = 3
+)
+
+// ParseCompressionAlgorithms parses a comma separated list of compression
+// algorithms, as used by the command line flags. An empty string returns
+// an empty list, meaning compression is disabled.
+func ParseCompressionAlgorithms(s string) ([]CompressionAlgorithm, error) {
+	var algorithms []CompressionAlgorithm
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch CompressionAlgorithm(name) {
+		case CompressionNone:
+		case CompressionZlib, CompressionZstd:
+			algorithms = append(algorithms, CompressionAlgorithm(name))
+		default:
+			return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "unknown compression algorithm %q, must be one of zlib, zstd", name)
+		}
+	}
+	return algorithms, nil
+}
+
+var zlibWriterPool = sync.Pool{New: func() any { return zlib.NewWriter(nil) }}
+
+var (
+	zstdEncodersMu sync.Mutex
+	zstdEncoders   = make(map[int]*zstd.Encoder)
+)
+
+// zstdEncoder returns a shared encoder for the given MySQL zstd level.
+// EncodeAll is safe for concurrent use, so one encoder per level is enough.
+func zstdEncoder(level int) (*zstd.Encoder, error) {
+	zstdEncodersMu.Lock()
+	defer zstdEncodersMu.Unlock()
+
+	if enc, ok := zstdEncoders[level]; ok {
+		return enc, nil
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	zstdEncoders[level] = enc
+	return enc, nil
+}
+
+// compressedStream implements the compressed protocol on top of the
+// plain reader and writer of a connection. Regular packets, headers
+// included, are read from and written to it as if it was the network
+// connection, and it takes care of the compressed packet framing.
+type compressedStream struct {
+	algorithm CompressionAlgorithm
+	zstdLevel int
+
+	r io.Reader
+	w io.Writer
+
+	// sequence is the sequence number of the compressed packets. It is
+	// independent from the sequence of the packets it carries, and is
+	// reset at the start of every command.
+	sequence uint8
+
+	header [compressedPacketHeaderSize]byte
+
+	// pending is what is left to return from the last packet read.
+	pending []byte
+	readBuf []byte
+	dataBuf []byte
+
+	writeBuf   []byte
+	zlibReader io.ReadCloser
+
+	// serverStats is set for server side connections, so we account
+	// for the bytes going through the compressed protocol.
+	serverStats bool
+}
+
+func newCompressedStream(r io.Reader, w io.Writer, algorithm CompressionAlgorithm, zstdLevel int) *compressedStream {
+	if zstdLevel <= 0 {
+		zstdLevel = defaultZstdCompressionLevel
+	}
+	return &compressedStream{
+		algorithm: algorithm,
+		zstdLevel: zstdLevel,
+		r:         r,
+		w:         w,
+	}
+}
+
+// Read implements io.Reader, returning the decompressed stream.
+func (cs *compressedStream) Read(p []byte) (int, error) {
+	for len(cs.pending) == 0 {
+		if err := cs.readPacket(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, cs.pending)
+	cs.pending = cs.pending[n:]
+	return n, nil
+}
+
+// readPacket reads the next compressed packet into pending.
+func (cs *compressedStream) readPacket() error {
+	// io.EOF is returned as is, so the connection can tell a client
+	// that went away from an actual error.
+	if _, err := io.ReadFull(cs.r, cs.header[:]); err != nil {
+		return err
+	}
+	length := int(uint32(cs.header[0]) | uint32(cs.header[1])<<8 | uint32(cs.header[2])<<16)
+	uncompressedLength := int(uint32(cs.header[4]) | uint32(cs.header[5])<<8 | uint32(cs.header[6])<<16)
+
+	// We follow the peer's sequence, the same way replies to a command
+	// follow the sequence of the command.
+	cs.sequence = cs.header[3] + 1
+
+	if cap(cs.readBuf) < length {
+		cs.readBuf = make([]byte, length)
+	}
+	payload := cs.readBuf[:length]
+	if _, err := io.ReadFull(cs.r, payload); err != nil {
+		return vterrors.Wrapf(err, "io.ReadFull(compressed packet body of length %v) failed", length)
+	}
+
+	if cs.serverStats {
+		compressionWireBytes.Add("in", int64(compressedPacketHeaderSize+length))
+	}
+
+	// A zero uncompressed length means the payload was sent as is.
+	if uncompressedLength == 0 {
+		cs.pending = payload
+		if cs.serverStats {
+			compressionRawBytes.Add("in", int64(length))
+		}
+		return nil
+	}
+
+	data, err := cs.decompress(payload, uncompressedLength)
+	if err != nil {
+		return err
+	}
+	if len(data) != uncompressedLength {
+		return vterrors.Errorf(vtrpcpb.Code_INTERNAL, "compressed packet decompressed to %v bytes, expected %v", len(data), uncompressedLength)
+	}
+	cs.pending = data
+	if cs.serverStats {
+		compressionRawBytes.Add("in", int64(uncompressedLength))
+	}
+	return nil
+}
+
+func (cs *compressedStream) decompress(payload []byte, uncompressedLength int) ([]byte, error) {
+	if cap(cs.dataBuf) < uncompressedLength {
+		cs.dataBuf = make([]byte, uncompressedLength)
+	}
+
+	switch cs.algorithm {
+	case CompressionZstd:
+		data, err := zstdDecoder.DecodeAll(payload, cs.dataBuf[:0])
+		if err != nil {
+			return nil, vterrors.Wrapf(err, "zstd decompression failed")
+		}
+		return data, nil
+	default:
+		var err error
+		if cs.zlibReader == nil {
+			cs.zlibReader, err = zlib.NewReader(bytes.NewReader(payload))
+		} else {
+			err = cs.zlibReader.(zlib.Resetter).Reset(bytes.NewReader(payload), nil)
+		}
+		if err != nil {
+			return nil, vterrors.Wrapf(err, "zlib decompression failed")
+		}
+		data := cs.dataBuf[:uncompressedLength]
+		if _, err := io.ReadFull(cs.zlibReader, data); err != nil {
+			return nil, vterrors.Wrapf(err, "zlib decompression failed")
+		}
+		return data, nil
+	}
+}
+
+// Write implements io.Writer. Every call sends at least one compressed
+// packet, so callers should buffer small writes.
+func (cs *compressedStream) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > MaxPacketSize {
+			chunk = chunk[:MaxPacketSize]
+		}
+		if err := cs.writePacket(chunk); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
+
+// writePacket writes one compressed packet. Small payloads, and payloads
+// that do not get smaller, are sent uncompressed.
+func (cs *compressedStream) writePacket(data []byte) error {
+	if cap(cs.writeBuf) < compressedPacketHeaderSize {
+		cs.writeBuf = make([]byte, compressedPacketHeaderSize, connBufferSize)
+	}
+
+	uncompressedLength := 0
+	buf := cs.writeBuf[:compressedPacketHeaderSize]
+	if len(data) >= minCompressLength {
+		compressed, err := cs.compress(buf, data)
+		if err != nil {
+			return err
+		}
+		if len(compressed)-compressedPacketHeaderSize < len(data) {
+			buf = compressed
+			uncompressedLength = len(data)
+		}
+	}
+	if uncompressedLength == 0 {
+		buf = append(buf[:compressedPacketHeaderSize], data...)
+	}
+	cs.writeBuf = buf
+
+	length := len(buf) - compressedPacketHeaderSize
+	buf[0] = byte(length)
+	buf[1] = byte(length >> 8)
+	buf[2] = byte(length >> 16)
+	buf[3] = cs.sequence
+	buf[4] = byte(uncompressedLength)
+	buf[5] = byte(uncompressedLength >> 8)
+	buf[6] = byte(uncompressedLength >> 16)
+
+	if n, err := cs.w.Write(buf); err != nil {
+		return vterrors.Wrapf(err, "Write(compressed packet) failed")
+	} else if n != len(buf) {
+		return vterrors.Errorf(vtrpcpb.Code_INTERNAL, "Write(compressed packet) returned a short write: %v < %v", n, len(buf))
+	}
+	cs.sequence++
+
+	if cs.serverStats {
+		compressionWireBytes.Add("out", int64(len(buf)))
+		compressionRawBytes.Add("out", int64(len(data)))
+	}
+	return nil
+}
+
+// compress appends the compressed data to dst.
+func (cs *compressedStream) compress(dst, data []byte) ([]byte, error) {
+	switch cs.algorithm {
+	case CompressionZstd:
+		enc, err := zstdEncoder(cs.zstdLevel)
+		if err != nil {
+			return nil, vterrors.Wrapf(err, "zstd compression failed")
+		}
+		return enc.EncodeAll(data, dst), nil
+	default:
+		out := bytes.NewBuffer(dst)
+		zw := zlibWriterPool.Get().(*zlib.Writer)
+		defer zlibWriterPool.Put(zw)
+		zw.Reset(out)
+		if _, err := zw.Write(data); err != nil {
+			return nil, vterrors.Wrapf(err, "zlib compression failed")
+		}
+		if err := zw.Close(); err != nil {
+			return nil, vterrors.Wrapf(err, "zlib compression failed")
+		}
+		return out.Bytes(), nil
+	}
+}
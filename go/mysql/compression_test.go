@@ -0,0 +1,265 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/test/utils"
+	"vitess.io/vitess/go/vt/vttls"
+)
+
+func TestParseCompressionAlgorithms(t *testing.T) {
+	algorithms, err := ParseCompressionAlgorithms("")
+	require.NoError(t, err)
+	assert.Empty(t, algorithms)
+
+	algorithms, err = ParseCompressionAlgorithms("zlib, ZSTD")
+	require.NoError(t, err)
+	assert.Equal(t, []CompressionAlgorithm{CompressionZlib, CompressionZstd}, algorithms)
+
+	_, err = ParseCompressionAlgorithms("zlib,lz4")
+	assert.ErrorContains(t, err, `unknown compression algorithm "lz4"`)
+}
+
+func TestCompressedStreamRoundTrip(t *testing.T) {
+	payloads := [][]byte{
+		[]byte("short"),
+		[]byte(strings.Repeat("compress me ", 1000)),
+		bytes.Repeat([]byte{0xab}, MaxPacketSize+100),
+	}
+
+	for _, algorithm := range []CompressionAlgorithm{CompressionZlib, CompressionZstd} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			var wire bytes.Buffer
+			w := newCompressedStream(nil, &wire, algorithm, 0)
+			for _, p := range payloads {
+				n, err := w.Write(p)
+				require.NoError(t, err)
+				require.Equal(t, len(p), n)
+			}
+
+			// The short payload goes as is, the others are compressed.
+			assert.EqualValues(t, 0, wire.Bytes()[4])
+			total := 0
+			for _, p := range payloads {
+				total += len(p)
+			}
+			assert.Less(t, wire.Len(), total)
+
+			r := newCompressedStream(&wire, nil, algorithm, 0)
+			for _, p := range payloads {
+				got := make([]byte, len(p))
+				_, err := io.ReadFull(r, got)
+				require.NoError(t, err)
+				assert.True(t, bytes.Equal(p, got))
+			}
+			_, err := r.Read(make([]byte, 1))
+			assert.Equal(t, io.EOF, err)
+
+			// The big payload needed two packets.
+			assert.EqualValues(t, 4, r.sequence)
+		})
+	}
+}
+
+// mysqlSelectPacket is a COM_QUERY packet, header included, used in the
+// compressed protocol examples of the MySQL documentation.
+var mysqlSelectPacket = append([]byte{0x2e, 0x00, 0x00, 0x00, ComQuery}, `select "012345678901234567890123456789012345"`...)
+
+// TestCompressedStreamMySQLFrames checks our framing against compressed
+// packets produced by MySQL, and not only against our own writer.
+func TestCompressedStreamMySQLFrames(t *testing.T) {
+	testcases := []struct {
+		name      string
+		algorithm CompressionAlgorithm
+		frame     []byte
+		expected  []byte
+	}{{
+		// The zlib example from the MySQL documentation: 0x22 bytes of
+		// payload, sequence 0, 0x32 bytes once uncompressed.
+		name:      "zlib",
+		algorithm: CompressionZlib,
+		frame: []byte{
+			0x22, 0x00, 0x00, 0x00, 0x32, 0x00, 0x00,
+			0x78, 0x9c, 0xd3, 0x63, 0x60, 0x60, 0x60, 0x2e, 0x4e, 0xcd, 0x49, 0x4d, 0x2e, 0x51, 0x50, 0x32,
+			0x30, 0x34, 0x32, 0x36, 0x31, 0x35, 0x33, 0xb7, 0xb0, 0xc4, 0xcd, 0x52, 0x02, 0x00, 0x0c, 0xd1,
+			0x0a, 0x6c,
+		},
+		expected: mysqlSelectPacket,
+	}, {
+		// The same packet compressed with libzstd at level 3, as
+		// MySQL does when zstd is negotiated.
+		name:      "zstd",
+		algorithm: CompressionZstd,
+		frame: []byte{
+			0x27, 0x00, 0x00, 0x00, 0x32, 0x00, 0x00,
+			0x28, 0xb5, 0x2f, 0xfd, 0x20, 0x32, 0xf5, 0x00, 0x00, 0xc0, 0x2e, 0x00, 0x00, 0x00, 0x03, 0x73,
+			0x65, 0x6c, 0x65, 0x63, 0x74, 0x20, 0x22, 0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38,
+			0x39, 0x22, 0x01, 0x00, 0x6b, 0x97, 0x26,
+		},
+		expected: mysqlSelectPacket,
+	}, {
+		// Payloads under 50 bytes go uncompressed, with a zero
+		// uncompressed length. This is COM_QUERY "select @@version_comment limit 1".
+		name:      "uncompressed",
+		algorithm: CompressionZlib,
+		frame: append([]byte{
+			0x25, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x21, 0x00, 0x00, 0x00, ComQuery,
+		}, "select @@version_comment limit 1"...),
+		expected: append([]byte{0x21, 0x00, 0x00, 0x00, ComQuery}, "select @@version_comment limit 1"...),
+	}}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			r := newCompressedStream(bytes.NewReader(tcase.frame), nil, tcase.algorithm, 0)
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, tcase.expected, got)
+			assert.EqualValues(t, 1, r.sequence)
+
+			// What we write must use the same sequence. Our encoders do
+			// not produce the same bytes as MySQL's, and may decide to
+			// not compress at all, but frames sent as is must match.
+			var wire bytes.Buffer
+			w := newCompressedStream(nil, &wire, tcase.algorithm, 0)
+			_, err = w.Write(tcase.expected)
+			require.NoError(t, err)
+			written := wire.Bytes()
+			assert.Equal(t, tcase.frame[3], written[3])
+			if tcase.frame[4] == 0 {
+				assert.Equal(t, tcase.frame, written)
+			}
+		})
+	}
+}
+
+func TestCompressedSequenceResetAfterFlush(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	c := newConn(server)
+	c.compressionAlgorithm = CompressionZlib
+	c.startCompression()
+
+	// Read everything the server side sends, frame by frame.
+	frames := make(chan []byte, 10)
+	go func() {
+		var header [compressedPacketHeaderSize]byte
+		for {
+			if _, err := io.ReadFull(client, header[:]); err != nil {
+				close(frames)
+				return
+			}
+			length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+			if _, err := io.CopyN(io.Discard, client, int64(length)); err != nil {
+				close(frames)
+				return
+			}
+			frames <- append([]byte(nil), header[:]...)
+		}
+	}()
+
+	// A buffered reply to a first command, followed by a new command:
+	// the buffered bytes must keep the sequence they were written with.
+	c.startWriterBuffering()
+	c.compression.sequence = 5
+	require.NoError(t, c.writeEOFPacket(0, 0))
+	require.NoError(t, c.resetSequence())
+	require.NoError(t, c.endWriterBuffering())
+	require.NoError(t, c.WriteComQuery("select 1"))
+
+	assert.EqualValues(t, 5, (<-frames)[3])
+	assert.EqualValues(t, 0, (<-frames)[3])
+}
+
+func TestCompressedConnection(t *testing.T) {
+	for _, tcase := range []struct {
+		name     string
+		server   []CompressionAlgorithm
+		client   CompressionAlgorithm
+		expected CompressionAlgorithm
+	}{{
+		name:     "zlib",
+		server:   []CompressionAlgorithm{CompressionZlib, CompressionZstd},
+		client:   CompressionZlib,
+		expected: CompressionZlib,
+	}, {
+		name:     "zstd",
+		server:   []CompressionAlgorithm{CompressionZlib, CompressionZstd},
+		client:   CompressionZstd,
+		expected: CompressionZstd,
+	}, {
+		name:     "server without compression",
+		client:   CompressionZstd,
+		expected: CompressionNone,
+	}, {
+		name:     "client without compression",
+		server:   []CompressionAlgorithm{CompressionZlib},
+		expected: CompressionNone,
+	}} {
+		t.Run(tcase.name, func(t *testing.T) {
+			th := &testHandler{}
+
+			authServer := NewAuthServerStatic("", "", 0)
+			authServer.entries["user1"] = []*AuthServerStaticEntry{{
+				Password: "password1",
+			}}
+			defer authServer.close()
+
+			l, err := NewListener("tcp", "127.0.0.1:", authServer, th, 0, 0, false, false, 0)
+			require.NoError(t, err)
+			defer l.Close()
+			l.CompressionAlgorithms = tcase.server
+			go l.Accept()
+
+			params := &ConnParams{
+				Host:        l.Addr().(*net.TCPAddr).IP.String(),
+				Port:        l.Addr().(*net.TCPAddr).Port,
+				Uname:       "user1",
+				Pass:        "password1",
+				SslMode:     vttls.Disabled,
+				Compression: tcase.client,
+			}
+			conn, err := Connect(context.Background(), params)
+			require.NoError(t, err)
+			defer conn.Close()
+			assert.Equal(t, tcase.expected, conn.CompressionAlgorithm())
+
+			// Run a few commands, to check the sequences restart properly.
+			for i := 0; i < 3; i++ {
+				result, err := conn.ExecuteFetch("select rows", 10000, true)
+				require.NoError(t, err)
+				utils.MustMatch(t, result, selectRowsResult)
+				require.NoError(t, conn.Ping())
+			}
+
+			// Send a ComQuit to avoid the error message on the server side.
+			conn.writeComQuit()
+		})
+	}
+}
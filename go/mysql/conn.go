@@ -145,6 +145,17 @@ type Conn struct {
 	// Buffered writing has a timer which flushes on inactivity.
 	bufferedWriter *bufio.Writer
 
+	// compression is set once the compressed protocol is in use.
+	// Packets are then read from and written to it instead of the
+	// underlying connection.
+	compression *compressedStream
+
+	// compressionAlgorithm and zstdCompressionLevel are what was
+	// negotiated during the handshake. Compression starts after
+	// authentication, see startCompression.
+	compressionAlgorithm CompressionAlgorithm
+	zstdCompressionLevel int
+
 	// PrepareData is the map to use a prepared statement.
 	PrepareData map[uint32]*PrepareData
 
@@ -204,6 +215,14 @@ type Conn struct {
 	// This is currently used for testing.
 	keepAliveOn bool
 
+	// openCursors is the number of prepared statements with an open cursor
+	openCursors int
+
+	// queryAttributes are the query attributes sent by the client with
+	// the command being executed. Only set when CapabilityClientQueryAttributes
+	// was negotiated.
+	queryAttributes map[string]string
+
 	// mu protects the fields below
 	mu sync.Mutex
 	// cancel keep the cancel function for the current executing query.
@@ -225,6 +244,16 @@ type PrepareData struct {
 	BindVars    map[string]*querypb.BindVariable
 	StatementID uint32
 	ParamsCount uint16
+
+	// cursor is the cursor opened by the last execution of the statement, if any
+	cursor *cursor
+}
+
+// HasCursor returns true if the statement is executed for a read only cursor,
+// whose rows are fetched by the client with COM_STMT_FETCH. Handlers should
+// stream the results of such executions rather than buffer them.
+func (pd *PrepareData) HasCursor() bool {
+	return pd.cursor != nil
 }
 
 // execResult is an enum signifying the result of executing a query
@@ -314,7 +343,7 @@ func (c *Conn) startWriterBuffering() {
 	defer c.bufMu.Unlock()
 
 	c.bufferedWriter = writersPool.Get().(*bufio.Writer)
-	c.bufferedWriter.Reset(c.conn)
+	c.bufferedWriter.Reset(c.netWriter())
 }
 
 // endWriterBuffering must be called to terminate startWriteBuffering.
@@ -358,7 +387,60 @@ func (c *Conn) getWriter() (w io.Writer, unget func()) {
 		}
 	}
 	c.bufMu.Unlock()
-	return c.conn, func() {}
+	return c.netWriter(), func() {}
+}
+
+// netWriter returns the writer for unbuffered writes. It is the
+// compression layer if the compressed protocol is in use, and the
+// network connection otherwise.
+func (c *Conn) netWriter() io.Writer {
+	if c.compression != nil {
+		return c.compression
+	}
+	return c.conn
+}
+
+// resetSequence resets the packet sequence at the start of a new
+// command. With the compressed protocol, the compressed sequence
+// restarts too, so anything still buffered from the previous command
+// is flushed first to go out with the sequence it was written with.
+func (c *Conn) resetSequence() error {
+	c.sequence = 0
+	if c.compression == nil {
+		return nil
+	}
+
+	c.bufMu.Lock()
+	defer c.bufMu.Unlock()
+	if c.bufferedWriter != nil {
+		c.stopFlushTimer()
+		if err := c.bufferedWriter.Flush(); err != nil {
+			return vterrors.Wrapf(err, "Flush() failed")
+		}
+	}
+	c.compression.sequence = 0
+	return nil
+}
+
+// startCompression switches the connection to the compressed protocol,
+// if it was negotiated during the handshake. It must be called right
+// after authentication, before any other packet is exchanged.
+func (c *Conn) startCompression() {
+	if c.compressionAlgorithm == CompressionNone {
+		return
+	}
+	cs := newCompressedStream(c.getReader(), c.conn, c.compressionAlgorithm, c.zstdCompressionLevel)
+	cs.serverStats = c.listener != nil
+	c.compression = cs
+}
+
+// CompressionAlgorithm returns the compression algorithm in use on this
+// connection, or CompressionNone.
+func (c *Conn) CompressionAlgorithm() CompressionAlgorithm {
+	if c.compression == nil {
+		return CompressionNone
+	}
+	return c.compression.algorithm
 }
 
 // startFlushTimer must be called while holding lock on bufMu.
@@ -385,8 +467,12 @@ func (c *Conn) stopFlushTimer() {
 }
 
 // getReader returns reader for connection. It can be *bufio.Reader or net.Conn
-// depending on which buffer size was passed to newServerConn.
+// depending on which buffer size was passed to newServerConn, or the
+// compression layer on top of them.
 func (c *Conn) getReader() io.Reader {
+	if c.compression != nil {
+		return c.compression
+	}
 	if c.bufferedReader != nil {
 		return c.bufferedReader
 	}
@@ -718,7 +804,9 @@ func (c *Conn) recycleWritePacket() {
 // Returns SQLError(CRServerGone) if it can't.
 func (c *Conn) writeComQuit() error {
 	// This is a new command, need to reset the sequence.
-	c.sequence = 0
+	if err := c.resetSequence(); err != nil {
+		return sqlerror.NewSQLError(sqlerror.CRServerGone, sqlerror.SSUnknownSQLState, "%v", err)
+	}
 
 	data, pos := c.startEphemeralPacketWithHeader(1)
 	data[pos] = ComQuit
@@ -738,6 +826,12 @@ func (c *Conn) ID() int64 {
 	return int64(c.ConnectionID)
 }
 
+// QueryAttributes returns the query attributes the client sent along with
+// the COM_QUERY or COM_STMT_EXECUTE being executed, or nil if there are none.
+func (c *Conn) QueryAttributes() map[string]string {
+	return c.queryAttributes
+}
+
 // Ident returns a useful identification string for error logging
 func (c *Conn) String() string {
 	return fmt.Sprintf("client %v (%s)", c.ConnectionID, c.RemoteAddr().String())
@@ -924,7 +1018,10 @@ func (c *Conn) writeEOFPacket(flags uint16, warnings uint16) error {
 // handleNextCommand is called in the server loop to process
 // incoming packets.
 func (c *Conn) handleNextCommand(handler Handler) bool {
-	c.sequence = 0
+	if err := c.resetSequence(); err != nil {
+		log.Errorf("Error flushing packets to %s: %v", c, err)
+		return false
+	}
 	data, err := c.readEphemeralPacket()
 	if err != nil {
 		// Don't log EOF errors. They cause too much spam.
@@ -941,6 +1038,11 @@ func (c *Conn) handleNextCommand(handler Handler) bool {
 		return false
 	}
 
+	if c.openCursors > 0 {
+		c.prepareCursors(data)
+	}
+	c.queryAttributes = nil
+
 	switch data[0] {
 	case ComQuit:
 		c.recycleReadPacket()
@@ -966,13 +1068,20 @@ func (c *Conn) handleNextCommand(handler Handler) bool {
 		stmtID, ok := c.parseComStmtClose(data)
 		c.recycleReadPacket()
 		if ok {
+			if prepare, ok := c.PrepareData[stmtID]; ok {
+				c.closeCursor(prepare)
+			}
 			delete(c.PrepareData, stmtID)
 		}
 	case ComStmtReset:
 		return c.handleComStmtReset(data)
+	case ComStmtFetch:
+		return c.handleComStmtFetch(handler, data)
 	case ComResetConnection:
 		c.handleComResetConnection(handler)
 		return true
+	case ComChangeUser:
+		return c.handleComChangeUser(handler, data)
 	case ComFieldList:
 		c.recycleReadPacket()
 		if !c.writeErrorAndLog(sqlerror.ERUnknownComError, sqlerror.SSNetError, "command handling not implemented yet: %v", data[0]) {
@@ -1032,6 +1141,7 @@ func (c *Conn) handleComBinlogDump(handler Handler, data []byte) (kontinue bool)
 	}
 	if err := handler.ComBinlogDump(c, logfile, binlogPos); err != nil {
 		log.Error(err.Error())
+		c.writeErrorPacketFromError(err)
 		return false
 	}
 	return kontinue
@@ -1056,6 +1166,7 @@ func (c *Conn) handleComBinlogDumpGTID(handler Handler, data []byte) (kontinue b
 	}
 	if err := handler.ComBinlogDumpGTID(c, logFile, logPos, position.GTIDSet); err != nil {
 		log.Error(err.Error())
+		c.writeErrorPacketFromError(err)
 		return false
 	}
 	return kontinue
@@ -1064,6 +1175,7 @@ func (c *Conn) handleComBinlogDumpGTID(handler Handler, data []byte) (kontinue b
 func (c *Conn) handleComResetConnection(handler Handler) {
 	// Clean up and reset the connection
 	c.recycleReadPacket()
+	c.closeCursors()
 	handler.ComResetConnection(c)
 	// Reset prepared statements
 	c.PrepareData = make(map[uint32]*PrepareData)
@@ -1073,6 +1185,92 @@ func (c *Conn) handleComResetConnection(handler Handler) {
 	}
 }
 
+// handleComChangeUser authenticates the connection as another user, through
+// the AuthServer of the listener, and then resets the connection as
+// COM_RESET_CONNECTION does. The connection is closed if the authentication
+// fails, as the session of the previous user is not usable anymore.
+func (c *Conn) handleComChangeUser(handler Handler, data []byte) bool {
+	user, schemaName, clientAuthMethod, err := c.parseComChangeUser(data)
+	c.recycleReadPacket()
+	if err != nil {
+		log.Errorf("Cannot parse COM_CHANGE_USER from %s: %v", c, err)
+		c.writeErrorPacketFromError(err)
+		return false
+	}
+
+	// The auth-response was computed with the salt of the handshake,
+	// so the authentication always starts over with a new one.
+	l := c.listener
+	negotiatedAuthMethod, err := negotiateAuthMethod(c, l.authServer, user, clientAuthMethod)
+	if err != nil {
+		for _, m := range l.authServer.AuthMethods() {
+			if m.HandleUser(c, user) {
+				negotiatedAuthMethod = m
+				break
+			}
+		}
+	}
+	if negotiatedAuthMethod == nil {
+		c.writeErrorPacket(sqlerror.ERAccessDeniedError, sqlerror.SSAccessDeniedError, "Access denied for user '%v'", user)
+		return false
+	}
+	if !l.AllowClearTextWithoutTLS.Load() && !c.TLSEnabled() && !negotiatedAuthMethod.AllowClearTextWithoutTLS() {
+		c.writeErrorPacket(sqlerror.CRServerHandshakeErr, sqlerror.SSUnknownSQLState, "Cannot use clear text authentication over non-SSL connections.")
+		return false
+	}
+
+	serverAuthPluginData, err := negotiatedAuthMethod.AuthPluginData()
+	if err != nil {
+		log.Errorf("Error generating auth switch packet for %s: %v", c, err)
+		return false
+	}
+	if err := c.writeAuthSwitchRequest(string(negotiatedAuthMethod.Name()), serverAuthPluginData); err != nil {
+		log.Errorf("Error writing auth switch packet for %s: %v", c, err)
+		return false
+	}
+	clientAuthResponse, err := c.readPacket()
+	if err != nil {
+		log.Errorf("Error reading auth switch response for %s: %v", c, err)
+		return false
+	}
+
+	userData, err := negotiatedAuthMethod.HandleAuthPluginData(c, user, serverAuthPluginData, clientAuthResponse, c.RemoteAddr())
+	if err != nil {
+		log.Warningf("Error authenticating user %s using: %s", user, negotiatedAuthMethod.Name())
+		c.writeErrorPacketFromError(err)
+		return false
+	}
+
+	if c.User != "" {
+		connCountPerUser.Add(c.User, -1)
+	}
+	if user != "" {
+		connCountPerUser.Add(user, 1)
+	}
+	c.User = user
+	c.UserData = userData
+
+	c.closeCursors()
+	handler.ComChangeUser(c)
+	c.PrepareData = make(map[uint32]*PrepareData)
+
+	c.schemaName = schemaName
+	if schemaName != "" {
+		err = handler.ComQuery(c, "use "+sqlescape.EscapeID(schemaName), func(result *sqltypes.Result) error {
+			return nil
+		})
+		if err != nil {
+			return c.writeErrorPacketFromErrorAndLog(err)
+		}
+	}
+
+	if err := c.writeOKPacket(&PacketOK{statusFlags: c.StatusFlags}); err != nil {
+		log.Errorf("Error writing ComChangeUser result to %s: %v", c, err)
+		return false
+	}
+	return true
+}
+
 func (c *Conn) handleComStmtReset(data []byte) bool {
 	stmtID, ok := c.parseComStmtReset(data)
 	c.recycleReadPacket()
@@ -1091,6 +1289,7 @@ func (c *Conn) handleComStmtReset(data []byte) bool {
 		}
 	}
 
+	c.closeCursor(prepare)
 	if prepare.BindVars != nil {
 		for k := range prepare.BindVars {
 			prepare.BindVars[k] = nil
@@ -1143,8 +1342,9 @@ func (c *Conn) handleComStmtExecute(handler Handler, data []byte) (kontinue bool
 		}
 	}()
 	queryStart := time.Now()
-	stmtID, _, err := c.parseComStmtExecute(c.PrepareData, data)
+	stmtID, cursorType, attributes, err := c.parseComStmtExecute(c.PrepareData, data)
 	c.recycleReadPacket()
+	c.queryAttributes = attributes
 
 	if stmtID != uint32(0) {
 		defer func() {
@@ -1158,10 +1358,16 @@ func (c *Conn) handleComStmtExecute(handler Handler, data []byte) (kontinue bool
 		return c.writeErrorPacketFromErrorAndLog(err)
 	}
 
+	prepare := c.PrepareData[stmtID]
+	if cursorType&CursorTypeReadOnly != 0 {
+		kontinue = c.executeCursor(handler, prepare)
+		timings.Record(queryTimingKey, queryStart)
+		return kontinue
+	}
+
 	fieldSent := false
 	// sendFinished is set if the response should just be an OK packet.
 	sendFinished := false
-	prepare := c.PrepareData[stmtID]
 	err = handler.ComStmtExecute(c, prepare, func(qr *sqltypes.Result) error {
 		if sendFinished {
 			// Failsafe: Unreachable if server is well-behaved.
@@ -1361,11 +1567,14 @@ func (c *Conn) handleComQuery(handler Handler, data []byte) (kontinue bool) {
 	}()
 
 	queryStart := time.Now()
-	query := c.parseComQuery(data)
+	query, attributes, err := c.parseComQuery(data)
 	c.recycleReadPacket()
+	if err != nil {
+		return c.writeErrorPacketFromErrorAndLog(err)
+	}
+	c.queryAttributes = attributes
 
 	var queries []string
-	var err error
 	if c.Capabilities&CapabilityClientMultiStatements != 0 {
 		queries, err = splitStatementFunction(query)
 		if err != nil {
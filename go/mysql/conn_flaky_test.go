@@ -935,6 +935,87 @@ func TestInitDbAgainstWrongDbDoesNotDropConnection(t *testing.T) {
 	require.EqualValues(t, data[0], ErrPacket) // we should see the error here
 }
 
+// changeUserHandler records the COM_CHANGE_USER calls and the queries.
+type changeUserHandler struct {
+	testRun
+	changedUsers []string
+	queries      []string
+}
+
+func (h *changeUserHandler) ComChangeUser(c *Conn) {
+	h.changedUsers = append(h.changedUsers, c.User)
+}
+
+func (h *changeUserHandler) ComQuery(c *Conn, query string, callback func(*sqltypes.Result) error) error {
+	h.queries = append(h.queries, query)
+	return nil
+}
+
+func TestComChangeUser(t *testing.T) {
+	listener, sConn, cConn := createSocketPair(t)
+	defer func() {
+		listener.Close()
+		sConn.Close()
+		cConn.Close()
+	}()
+	sConn.listener = &Listener{
+		authServer: NewAuthServerStatic("", `{"user2": {"Password": "password2", "UserData": "userData2"}}`, 0),
+	}
+	sConn.User = "user1"
+	sConn.PrepareData[1] = &PrepareData{StatementID: 1, PrepareStmt: "select 1"}
+	h := &changeUserHandler{testRun: testRun{t: t}}
+
+	changeUser := func(user, password string) bool {
+		done := make(chan bool)
+		go func() {
+			done <- sConn.handleNextCommand(h)
+		}()
+
+		payload := []byte{ComChangeUser}
+		payload = append(payload, user...)
+		payload = append(payload, 0, 0)
+		payload = append(payload, "db"...)
+		payload = append(payload, 0, 0x21, 0x00)
+		payload = append(payload, MysqlNativePassword...)
+		payload = append(payload, 0)
+		writeTestCommand(t, cConn, payload...)
+
+		// The server always asks for a new auth-response, with a new salt.
+		data, err := cConn.ReadPacket()
+		require.NoError(t, err)
+		require.EqualValues(t, AuthSwitchRequestPacket, data[0])
+		method, pos, ok := readNullString(data, 1)
+		require.True(t, ok)
+		require.EqualValues(t, MysqlNativePassword, method)
+		salt := data[pos : len(data)-1]
+
+		response := ScrambleMysqlNativePassword(salt, []byte(password))
+		buf, pos := cConn.startEphemeralPacketWithHeader(len(response))
+		copy(buf[pos:], response)
+		require.NoError(t, cConn.writeEphemeralPacket())
+
+		data, err = cConn.ReadPacket()
+		require.NoError(t, err)
+		if data[0] == ErrPacket {
+			assert.ErrorContains(t, ParseErrorPacket(data), "Access denied for user 'user2'")
+		} else {
+			require.EqualValues(t, OKPacket, data[0])
+		}
+		return <-done
+	}
+
+	require.True(t, changeUser("user2", "password2"))
+	assert.Equal(t, "user2", sConn.User)
+	assert.Equal(t, "userData2", sConn.UserData.Get().Username)
+	assert.Equal(t, []string{"user2"}, h.changedUsers)
+	assert.Equal(t, []string{"use `db`"}, h.queries)
+	assert.Empty(t, sConn.PrepareData)
+
+	// A failed authentication closes the connection.
+	require.False(t, changeUser("user2", "bad"))
+	assert.Equal(t, []string{"user2"}, h.changedUsers)
+}
+
 func TestConnectionErrorWhileWritingComQuery(t *testing.T) {
 	// Set the conn for the server connection to the simulated connection which always returns an error on writing
 	sConn := newConn(testConn{
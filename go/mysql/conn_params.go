@@ -57,6 +57,15 @@ type ConnParams struct {
 	// for informative purposes. It has no programmatic value. Returning this field is
 	// disabled by default.
 	EnableQueryInfo bool
+
+	// Compression is the compression algorithm to use for the connection,
+	// if the server supports it. It is zlib, zstd, or empty to not use
+	// the compressed protocol.
+	Compression CompressionAlgorithm `json:"compression,omitempty"`
+
+	// ZstdCompressionLevel is the level to ask the server to use when
+	// Compression is zstd. If zero, the MySQL default is used.
+	ZstdCompressionLevel int `json:"zstd_compression_level,omitempty"`
 }
 
 // EnableSSL will set the right flag on the parameters.
@@ -76,8 +76,9 @@ const (
 	// CLIENT_NO_SCHEMA 1 << 4
 	// Do not permit database.table.column. We do permit it.
 
-	// CLIENT_COMPRESS 1 << 5
-	// We do not support compression. CPU is usually our bottleneck.
+	// CapabilityClientCompress is CLIENT_COMPRESS.
+	// Use the zlib compressed protocol once the handshake is done.
+	CapabilityClientCompress = 1 << 5
 
 	// CLIENT_ODBC 1 << 6
 	// No special behavior since 3.22.
@@ -148,6 +149,20 @@ const (
 	// CapabilityClientDeprecateEOF is CLIENT_DEPRECATE_EOF
 	// Expects an OK (instead of EOF) after the resultset rows of a Text Resultset.
 	CapabilityClientDeprecateEOF = 1 << 24
+
+	// CLIENT_OPTIONAL_RESULTSET_METADATA 1 << 25
+	// Not yet supported.
+
+	// CapabilityClientZstdCompressionAlgorithm is CLIENT_ZSTD_COMPRESSION_ALGORITHM.
+	// Use the zstd compressed protocol once the handshake is done. The
+	// client sends the compression level it wants at the end of its
+	// handshake response.
+	CapabilityClientZstdCompressionAlgorithm = 1 << 26
+
+	// CapabilityClientQueryAttributes is CLIENT_QUERY_ATTRIBUTES.
+	// The client can send query attributes along with COM_QUERY
+	// and COM_STMT_EXECUTE.
+	CapabilityClientQueryAttributes = 1 << 27
 )
 
 // Status flags. They are returned by the server in a few cases.
@@ -209,6 +224,9 @@ const (
 	// ComPing is COM_PING.
 	ComPing = 0x0e
 
+	// ComChangeUser is COM_CHANGE_USER.
+	ComChangeUser = 0x11
+
 	// ComBinlogDump is COM_BINLOG_DUMP.
 	ComBinlogDump = 0x12
 
@@ -259,6 +277,18 @@ const (
 	NullValue = 0xfb
 )
 
+// Cursor type flags of COM_STMT_EXECUTE
+const (
+	// CursorTypeReadOnly opens a read only cursor, whose rows are
+	// then read with COM_STMT_FETCH.
+	CursorTypeReadOnly = 0x01
+
+	// CursorTypeParameterCountAvailable tells that the parameter count
+	// is sent even if the statement has no parameters, so query
+	// attributes can follow.
+	CursorTypeParameterCountAvailable = 0x08
+)
+
 // Auth packet types
 const (
 	// AuthMoreDataPacket is sent when server requires more data to authenticate
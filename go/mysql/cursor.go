@@ -0,0 +1,320 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysql
+
+import (
+	"errors"
+	"io"
+
+	"vitess.io/vitess/go/mysql/sqlerror"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/tb"
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/vterrors"
+
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+)
+
+// cursor is a read only cursor opened by COM_STMT_EXECUTE.
+//
+// The results are produced by the ComStmtExecute method of the handler,
+// called in its own goroutine, and handed over to the connection as the
+// client fetches rows with COM_STMT_FETCH. The handler and the connection
+// never run at the same time: the handler only runs while the connection
+// waits for its next result, so it can use the connection as usual.
+type cursor struct {
+	resume  chan bool
+	results chan cursorResult
+
+	fields  []*querypb.Field
+	pending []sqltypes.Row
+
+	// done is set once the handler returned
+	done bool
+	// err is the error the statement failed with while the cursor was materialized
+	err error
+}
+
+type cursorResult struct {
+	qr   *sqltypes.Result
+	err  error
+	done bool
+}
+
+// errCursorClosed is returned to the handler when the cursor is closed
+// before all the results were fetched.
+var errCursorClosed = errors.New("cursor closed")
+
+// openCursor starts the execution of a prepared statement for a cursor.
+func (c *Conn) openCursor(handler Handler, prepare *PrepareData) *cursor {
+	cur := &cursor{
+		resume:  make(chan bool),
+		results: make(chan cursorResult),
+	}
+	prepare.cursor = cur
+	c.openCursors++
+	go func() {
+		if !<-cur.resume {
+			cur.results <- cursorResult{done: true}
+			return
+		}
+		err := cur.execute(c, handler, prepare)
+		cur.results <- cursorResult{err: err, done: true}
+	}()
+	return cur
+}
+
+// execute runs the handler for the cursor. The handler does not run in the
+// goroutine of the connection, so its panics are caught here, and turned
+// into the error of the cursor.
+func (cur *cursor) execute(c *Conn, handler Handler, prepare *PrepareData) (err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			log.Errorf("mysql_server caught panic in cursor execution:\n%v\n%s", x, tb.Stack(4))
+			err = vterrors.Errorf(vtrpcpb.Code_INTERNAL, "cursor execution failed: %v", x)
+		}
+	}()
+	return handler.ComStmtExecute(c, prepare, func(qr *sqltypes.Result) error {
+		cur.results <- cursorResult{qr: qr}
+		if !<-cur.resume {
+			return errCursorClosed
+		}
+		return nil
+	})
+}
+
+// next runs the handler until it produces its next result.
+// It returns io.EOF once the handler is done.
+func (cur *cursor) next() (*sqltypes.Result, error) {
+	if cur.done {
+		return nil, io.EOF
+	}
+	cur.resume <- true
+	res := <-cur.results
+	if res.done {
+		cur.done = true
+		if res.err != nil {
+			return nil, res.err
+		}
+		return nil, io.EOF
+	}
+	return res.qr, nil
+}
+
+// fetch returns up to count rows of the cursor.
+func (cur *cursor) fetch(count int) ([]sqltypes.Row, error) {
+	for len(cur.pending) < count {
+		qr, err := cur.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		cur.pending = append(cur.pending, qr.Rows...)
+	}
+	if count > len(cur.pending) {
+		count = len(cur.pending)
+	}
+	rows := cur.pending[:count:count]
+	cur.pending = cur.pending[count:]
+	return rows, nil
+}
+
+// exhausted returns true once all the rows of the cursor were fetched.
+func (cur *cursor) exhausted() bool {
+	return cur.done && len(cur.pending) == 0
+}
+
+// materialize reads all the remaining results of the cursor in memory,
+// so the connection can run other commands while the cursor is open.
+// It fails once more than maxRows rows are in memory, unless maxRows is 0.
+func (cur *cursor) materialize(maxRows int) error {
+	for {
+		qr, err := cur.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		cur.pending = append(cur.pending, qr.Rows...)
+		if maxRows > 0 && len(cur.pending) > maxRows {
+			return vterrors.NewErrorf(vtrpcpb.Code_RESOURCE_EXHAUSTED, vterrors.OutOfResources, "in-memory row count of open cursor exceeded allowed limit of %d", maxRows)
+		}
+	}
+}
+
+// close stops the handler, if it is still running.
+func (cur *cursor) close() {
+	if cur.done {
+		return
+	}
+	cur.resume <- false
+	for res := range cur.results {
+		if res.done {
+			break
+		}
+		cur.resume <- false
+	}
+	cur.done = true
+	cur.pending = nil
+}
+
+// closeCursor closes the cursor of a prepared statement, if any.
+func (c *Conn) closeCursor(prepare *PrepareData) {
+	if prepare.cursor != nil {
+		prepare.cursor.close()
+		prepare.cursor = nil
+		c.openCursors--
+	}
+}
+
+// closeCursors closes all the cursors of the connection.
+func (c *Conn) closeCursors() {
+	if c.openCursors == 0 {
+		return
+	}
+	for _, prepare := range c.PrepareData {
+		c.closeCursor(prepare)
+	}
+}
+
+// prepareCursors gets the open cursors ready for the given command.
+//
+// The cursors are served by the handler, which can only be used for one
+// command at a time. Before a command that needs it, the remaining results
+// of the open cursors are read in memory: like in MySQL, the cursors stay
+// open, and the client can go on fetching their rows. A cursor whose
+// statement failed, or that has more rows than the listener allows in
+// memory, returns the error to the client on its next fetch.
+// The cursor of a statement that is executed again is closed instead.
+func (c *Conn) prepareCursors(data []byte) {
+	switch data[0] {
+	case ComStmtFetch, ComStmtClose, ComStmtReset, ComStmtSendLongData, ComPing, ComQuit:
+		return
+	case ComStmtExecute:
+		if stmtID, _, ok := readUint32(data, 1); ok {
+			if prepare, ok := c.PrepareData[stmtID]; ok {
+				c.closeCursor(prepare)
+			}
+		}
+	}
+	maxRows := 0
+	if c.listener != nil {
+		maxRows = c.listener.MaxCursorMemoryRows
+	}
+	for _, prepare := range c.PrepareData {
+		cur := prepare.cursor
+		if cur == nil || cur.done {
+			continue
+		}
+		if err := cur.materialize(maxRows); err != nil {
+			cur.close()
+			cur.err = err
+		}
+	}
+}
+
+// executeCursor executes a prepared statement for a read only cursor. The
+// response only has the fields of the result, and its rows are sent on
+// COM_STMT_FETCH. A statement without a result set gets the usual OK packet,
+// and no cursor.
+func (c *Conn) executeCursor(handler Handler, prepare *PrepareData) (kontinue bool) {
+	cur := c.openCursor(handler, prepare)
+	qr, err := cur.next()
+	if err == io.EOF {
+		// This is just a failsafe. Should never happen.
+		err = sqlerror.NewSQLErrorFromError(errors.New("unexpected: query ended without no results and no error"))
+	}
+	if err != nil {
+		c.closeCursor(prepare)
+		return c.writeErrorPacketFromErrorAndLog(err)
+	}
+
+	if len(qr.Fields) == 0 {
+		c.closeCursor(prepare)
+		ok := PacketOK{
+			affectedRows:     qr.RowsAffected,
+			lastInsertID:     qr.InsertID,
+			statusFlags:      c.StatusFlags,
+			warnings:         0,
+			info:             "",
+			sessionStateData: qr.SessionStateChanges,
+		}
+		if err := c.writeOKPacket(&ok); err != nil {
+			return false
+		}
+		return true
+	}
+
+	cur.fields = qr.Fields
+	cur.pending = qr.Rows
+	if err := c.writeCursorFields(qr); err != nil {
+		return false
+	}
+	return true
+}
+
+// handleComStmtFetch sends the next rows of the cursor of a prepared statement.
+func (c *Conn) handleComStmtFetch(handler Handler, data []byte) (kontinue bool) {
+	c.startWriterBuffering()
+	defer func() {
+		if err := c.endWriterBuffering(); err != nil {
+			log.Errorf("conn %v: flush() failed: %v", c.ID(), err)
+			kontinue = false
+		}
+	}()
+
+	stmtID, count, ok := c.parseComStmtFetch(data)
+	c.recycleReadPacket()
+	if !ok {
+		return c.writeErrorPacketFromErrorAndLog(sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "error parsing COM_STMT_FETCH packet"))
+	}
+	prepare, ok := c.PrepareData[stmtID]
+	if !ok || prepare.cursor == nil {
+		return c.writeErrorPacketFromErrorAndLog(sqlerror.NewSQLError(sqlerror.ERStmtHasNoOpenCursor, sqlerror.SSUnknownSQLState, "The statement (%d) has no open cursor.", stmtID))
+	}
+
+	cur := prepare.cursor
+	if cur.err != nil {
+		c.closeCursor(prepare)
+		return c.writeErrorPacketFromErrorAndLog(sqlerror.NewSQLErrorFromError(cur.err))
+	}
+	rows, err := cur.fetch(int(count))
+	if err != nil {
+		c.closeCursor(prepare)
+		return c.writeErrorPacketFromErrorAndLog(err)
+	}
+	if err := c.writeBinaryRows(&sqltypes.Result{Fields: cur.fields, Rows: rows}); err != nil {
+		log.Errorf("Error writing result to %s: %v", c, err)
+		return false
+	}
+
+	// Like MySQL, the cursor is closed once the client got all of its rows.
+	flags := c.StatusFlags | ServerStatusCursorExists
+	if cur.exhausted() {
+		flags |= ServerStatusLastRowSent
+		c.closeCursor(prepare)
+	}
+	if err := c.writeEndResultPacket(flags, 0, 0, handler.WarningCount(c)); err != nil {
+		log.Errorf("Error writing result to %s: %v", c, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysql
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/mysql/replication"
+	"vitess.io/vitess/go/mysql/sqlerror"
+	"vitess.io/vitess/go/sqltypes"
+
+	querypb "vitess.io/vitess/go/vt/proto/query"
+)
+
+// cursorHandler streams its results to ComStmtExecute, and records
+// how many of them were produced.
+type cursorHandler struct {
+	UnimplementedHandler
+	results   []*sqltypes.Result
+	err       error
+	produced  int
+	hasCursor bool
+	panics    bool
+}
+
+func (h *cursorHandler) ComQuery(c *Conn, query string, callback func(*sqltypes.Result) error) error {
+	return callback(&sqltypes.Result{RowsAffected: 1})
+}
+
+func (h *cursorHandler) ComPrepare(c *Conn, query string, bindVars map[string]*querypb.BindVariable) ([]*querypb.Field, error) {
+	return nil, nil
+}
+
+func (h *cursorHandler) ComStmtExecute(c *Conn, prepare *PrepareData, callback func(*sqltypes.Result) error) error {
+	h.hasCursor = prepare.HasCursor()
+	for _, qr := range h.results {
+		h.produced++
+		if err := callback(qr); err != nil {
+			return err
+		}
+	}
+	if h.panics {
+		panic("handler failed")
+	}
+	return h.err
+}
+
+func (h *cursorHandler) ComRegisterReplica(c *Conn, replicaHost string, replicaPort uint16, replicaUser string, replicaPassword string) error {
+	return nil
+}
+
+func (h *cursorHandler) ComBinlogDump(c *Conn, logFile string, binlogPos uint32) error {
+	return nil
+}
+
+func (h *cursorHandler) ComBinlogDumpGTID(c *Conn, logFile string, logPos uint64, gtidSet replication.GTIDSet) error {
+	return nil
+}
+
+func (h *cursorHandler) WarningCount(c *Conn) uint16 {
+	return 0
+}
+
+func writeTestCommand(t *testing.T, c *Conn, payload ...byte) {
+	c.sequence = 0
+	data, pos := c.startEphemeralPacketWithHeader(len(payload))
+	copy(data[pos:], payload)
+	require.NoError(t, c.writeEphemeralPacket())
+}
+
+func writeTestStmtExecute(t *testing.T, c *Conn, stmtID uint32, cursorType byte) {
+	payload := []byte{ComStmtExecute, 0, 0, 0, 0, cursorType, 1, 0, 0, 0}
+	binary.LittleEndian.PutUint32(payload[1:], stmtID)
+	writeTestCommand(t, c, payload...)
+}
+
+func writeTestStmtFetch(t *testing.T, c *Conn, stmtID, count uint32) {
+	payload := make([]byte, 9)
+	payload[0] = ComStmtFetch
+	binary.LittleEndian.PutUint32(payload[1:], stmtID)
+	binary.LittleEndian.PutUint32(payload[5:], count)
+	writeTestCommand(t, c, payload...)
+}
+
+// readTestFetch reads the rows sent on COM_STMT_FETCH, with their single
+// BIGINT column, and the status flags of the EOF packet that ends them.
+func readTestFetch(t *testing.T, c *Conn) ([]int64, uint16) {
+	var ids []int64
+	for {
+		data, err := c.ReadPacket()
+		require.NoError(t, err)
+		if c.isEOFPacket(data) {
+			_, flags, err := parseEOFPacket(data)
+			require.NoError(t, err)
+			return ids, flags
+		}
+		require.EqualValues(t, 0, data[0], "not a binary row: %v", data)
+		ids = append(ids, int64(binary.LittleEndian.Uint64(data[len(data)-8:])))
+	}
+}
+
+func TestCursorFetch(t *testing.T) {
+	listener, sConn, cConn := createSocketPair(t)
+	defer func() {
+		listener.Close()
+		sConn.Close()
+		cConn.Close()
+	}()
+
+	fields := []*querypb.Field{{Name: "id", Type: querypb.Type_INT64}}
+	row := func(id int64) sqltypes.Row { return sqltypes.Row{sqltypes.NewInt64(id)} }
+	h := &cursorHandler{results: []*sqltypes.Result{
+		{Fields: fields},
+		{Rows: []sqltypes.Row{row(1), row(2)}},
+		{Rows: []sqltypes.Row{row(3)}},
+		{Rows: []sqltypes.Row{row(4), row(5)}},
+	}}
+	sConn.PrepareData[1] = &PrepareData{StatementID: 1, PrepareStmt: "select id from t"}
+	sConn.PrepareData[2] = &PrepareData{StatementID: 2, PrepareStmt: "select id from t"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for sConn.handleNextCommand(h) {
+		}
+	}()
+
+	// The response to the execution only has the fields, and the rows are not read yet.
+	writeTestStmtExecute(t, cConn, 1, CursorTypeReadOnly)
+	data, err := cConn.ReadPacket()
+	require.NoError(t, err)
+	assert.EqualValues(t, []byte{1}, data)
+	field := &querypb.Field{}
+	require.NoError(t, cConn.readColumnDefinition(field, 0))
+	assert.Equal(t, "id", field.Name)
+	data, err = cConn.ReadPacket()
+	require.NoError(t, err)
+	require.True(t, cConn.isEOFPacket(data))
+	_, flags, err := parseEOFPacket(data)
+	require.NoError(t, err)
+	assert.NotZero(t, flags&ServerStatusCursorExists)
+
+	// The rows are read from the handler as the client fetches them.
+	writeTestStmtFetch(t, cConn, 1, 3)
+	ids, flags := readTestFetch(t, cConn)
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+	assert.NotZero(t, flags&ServerStatusCursorExists)
+	assert.Zero(t, flags&ServerStatusLastRowSent)
+	assert.True(t, h.hasCursor)
+	assert.Equal(t, 3, h.produced)
+
+	// Another command reads the rest of the cursor in memory first, and the client can go on fetching.
+	writeTestCommand(t, cConn, append([]byte{ComQuery}, "set @a = 1"...)...)
+	_, _, err = cConn.readComQueryResponse()
+	require.NoError(t, err)
+	assert.Equal(t, 4, h.produced)
+
+	writeTestStmtFetch(t, cConn, 1, 10)
+	ids, flags = readTestFetch(t, cConn)
+	assert.Equal(t, []int64{4, 5}, ids)
+	assert.NotZero(t, flags&ServerStatusLastRowSent)
+
+	// The cursor is closed once all of its rows were sent.
+	writeTestStmtFetch(t, cConn, 1, 10)
+	data, err = cConn.ReadPacket()
+	require.NoError(t, err)
+	require.True(t, isErrorPacket(data))
+	var sqlErr *sqlerror.SQLError
+	require.ErrorAs(t, ParseErrorPacket(data), &sqlErr)
+	assert.Equal(t, sqlerror.ERStmtHasNoOpenCursor, sqlErr.Number())
+
+	// Closing a statement stops its handler.
+	h.produced = 0
+	writeTestStmtExecute(t, cConn, 2, CursorTypeReadOnly)
+	for i := 0; i < 3; i++ {
+		_, err = cConn.ReadPacket()
+		require.NoError(t, err)
+	}
+	writeTestCommand(t, cConn, ComStmtClose, 2, 0, 0, 0)
+	writeTestCommand(t, cConn, ComQuit)
+	<-done
+	assert.Equal(t, 1, h.produced)
+	assert.Zero(t, sConn.openCursors)
+}
+
+func TestCursorWithoutResultSet(t *testing.T) {
+	listener, sConn, cConn := createSocketPair(t)
+	defer func() {
+		listener.Close()
+		sConn.Close()
+		cConn.Close()
+	}()
+
+	h := &cursorHandler{results: []*sqltypes.Result{{RowsAffected: 2}}}
+	sConn.PrepareData[1] = &PrepareData{StatementID: 1, PrepareStmt: "update t set a = 1"}
+
+	// A statement without a result set gets an OK packet, and no cursor.
+	go sConn.handleNextCommand(h)
+	writeTestStmtExecute(t, cConn, 1, CursorTypeReadOnly)
+	_, ok, err := cConn.readComQueryResponse()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, ok.affectedRows)
+
+	// Errors are sent as usual.
+	h.results = nil
+	h.err = sqlerror.NewSQLError(sqlerror.ERNoSuchTable, sqlerror.SSUnknownTable, "table t not found")
+	go sConn.handleNextCommand(h)
+	writeTestStmtExecute(t, cConn, 1, CursorTypeReadOnly)
+	_, _, err = cConn.readComQueryResponse()
+	assert.ErrorContains(t, err, "table t not found")
+
+	// So are the errors of a statement that fails after its fields were sent.
+	h.results = []*sqltypes.Result{{Fields: []*querypb.Field{{Name: "id", Type: querypb.Type_INT64}}}}
+	h.err = errors.New("stream failed")
+	go sConn.handleNextCommand(h)
+	writeTestStmtExecute(t, cConn, 1, CursorTypeReadOnly)
+	for i := 0; i < 3; i++ {
+		_, err = cConn.ReadPacket()
+		require.NoError(t, err)
+	}
+	go sConn.handleNextCommand(h)
+	writeTestStmtFetch(t, cConn, 1, 10)
+	data, err := cConn.ReadPacket()
+	require.NoError(t, err)
+	require.True(t, isErrorPacket(data))
+	assert.ErrorContains(t, ParseErrorPacket(data), "stream failed")
+	assert.Zero(t, sConn.openCursors)
+}
+
+func TestCursorHandlerPanic(t *testing.T) {
+	listener, sConn, cConn := createSocketPair(t)
+	defer func() {
+		listener.Close()
+		sConn.Close()
+		cConn.Close()
+	}()
+
+	h := &cursorHandler{
+		results: []*sqltypes.Result{{Fields: []*querypb.Field{{Name: "id", Type: querypb.Type_INT64}}}},
+		panics:  true,
+	}
+	sConn.PrepareData[1] = &PrepareData{StatementID: 1, PrepareStmt: "select id from t"}
+
+	// The panic of the handler is the error of the cursor.
+	go sConn.handleNextCommand(h)
+	writeTestStmtExecute(t, cConn, 1, CursorTypeReadOnly)
+	for i := 0; i < 3; i++ {
+		_, err := cConn.ReadPacket()
+		require.NoError(t, err)
+	}
+	go sConn.handleNextCommand(h)
+	writeTestStmtFetch(t, cConn, 1, 10)
+	data, err := cConn.ReadPacket()
+	require.NoError(t, err)
+	require.True(t, isErrorPacket(data))
+	assert.ErrorContains(t, ParseErrorPacket(data), "handler failed")
+	assert.Zero(t, sConn.openCursors)
+}
+
+func TestCursorMaxMemoryRows(t *testing.T) {
+	listener, sConn, cConn := createSocketPair(t)
+	defer func() {
+		listener.Close()
+		sConn.Close()
+		cConn.Close()
+	}()
+	sConn.listener = &Listener{MaxCursorMemoryRows: 2}
+
+	fields := []*querypb.Field{{Name: "id", Type: querypb.Type_INT64}}
+	row := func(id int64) sqltypes.Row { return sqltypes.Row{sqltypes.NewInt64(id)} }
+	h := &cursorHandler{results: []*sqltypes.Result{
+		{Fields: fields},
+		{Rows: []sqltypes.Row{row(1)}},
+		{Rows: []sqltypes.Row{row(2), row(3)}},
+		{Rows: []sqltypes.Row{row(4)}},
+		{Rows: []sqltypes.Row{row(5)}},
+	}}
+	sConn.PrepareData[1] = &PrepareData{StatementID: 1, PrepareStmt: "select id from t"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for sConn.handleNextCommand(h) {
+		}
+	}()
+
+	writeTestStmtExecute(t, cConn, 1, CursorTypeReadOnly)
+	for i := 0; i < 3; i++ {
+		_, err := cConn.ReadPacket()
+		require.NoError(t, err)
+	}
+	writeTestStmtFetch(t, cConn, 1, 1)
+	ids, _ := readTestFetch(t, cConn)
+	assert.Equal(t, []int64{1}, ids)
+
+	// The rest of the cursor does not fit in memory, so the cursor fails
+	// and its handler is stopped, but the other command runs.
+	writeTestCommand(t, cConn, append([]byte{ComQuery}, "set @a = 1"...)...)
+	_, _, err := cConn.readComQueryResponse()
+	require.NoError(t, err)
+	assert.Equal(t, 4, h.produced)
+
+	writeTestStmtFetch(t, cConn, 1, 10)
+	data, err := cConn.ReadPacket()
+	require.NoError(t, err)
+	require.True(t, isErrorPacket(data))
+	err = ParseErrorPacket(data)
+	assert.ErrorContains(t, err, "in-memory row count of open cursor exceeded allowed limit of 2")
+	sqlErr, ok := err.(*sqlerror.SQLError)
+	require.True(t, ok)
+	assert.Equal(t, sqlerror.EROutOfResources, sqlErr.Number())
+	assert.Equal(t, sqlerror.SSUnknownSQLState, sqlErr.SQLState())
+
+	writeTestCommand(t, cConn, ComQuit)
+	<-done
+	assert.Zero(t, sConn.openCursors)
+}
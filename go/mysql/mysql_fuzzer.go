@@ -294,7 +294,7 @@ func (th *fuzzTestHandler) WarningCount(c *Conn) uint16 {
 }
 
 func (c *Conn) writeFuzzedPacket(packet []byte) {
-	c.sequence = 0
+	_ = c.resetSequence()
 	data, pos := c.startEphemeralPacketWithHeader(len(packet) + 1)
 	copy(data[pos:], packet)
 	_ = c.writeEphemeralPacket()
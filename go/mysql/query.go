@@ -42,7 +42,9 @@ import (
 // Returns SQLError(CRServerGone) if it can't.
 func (c *Conn) WriteComQuery(query string) error {
 	// This is a new command, need to reset the sequence.
-	c.sequence = 0
+	if err := c.resetSequence(); err != nil {
+		return sqlerror.NewSQLError(sqlerror.CRServerGone, sqlerror.SSUnknownSQLState, "%v", err)
+	}
 
 	data, pos := c.startEphemeralPacketWithHeader(len(query) + 1)
 	data[pos] = ComQuery
@@ -58,6 +60,10 @@ func (c *Conn) WriteComQuery(query string) error {
 // Client -> Server.
 // Returns SQLError(CRServerGone) if it can't.
 func (c *Conn) writeComInitDB(db string) error {
+	// This is a new command, need to reset the sequence.
+	if err := c.resetSequence(); err != nil {
+		return sqlerror.NewSQLError(sqlerror.CRServerGone, sqlerror.SSUnknownSQLState, "%v", err)
+	}
 	data, pos := c.startEphemeralPacketWithHeader(len(db) + 1)
 	data[pos] = ComInitDB
 	pos++
@@ -531,8 +537,118 @@ func (c *Conn) readComQueryResponse() (int, *PacketOK, error) {
 // Server side methods.
 //
 
-func (c *Conn) parseComQuery(data []byte) string {
-	return string(data[1:])
+func (c *Conn) parseComQuery(data []byte) (string, map[string]string, error) {
+	if c.Capabilities&CapabilityClientQueryAttributes == 0 {
+		return string(data[1:]), nil, nil
+	}
+	payload := data[1:]
+
+	paramsCount, pos, ok := readLenEncInt(payload, 0)
+	if !ok {
+		return "", nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading parameter count failed")
+	}
+	// parameter set count, always 1.
+	_, pos, ok = readLenEncInt(payload, pos)
+	if !ok {
+		return "", nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading parameter set count failed")
+	}
+	if paramsCount == 0 {
+		return string(payload[pos:]), nil, nil
+	}
+	if paramsCount > uint64(len(payload)) {
+		return "", nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "invalid parameter count %d", paramsCount)
+	}
+
+	bitMap, pos, ok := readBytes(payload, pos, (int(paramsCount)+7)/8)
+	if !ok {
+		return "", nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading NULL-bitmap failed")
+	}
+	// new params bound flag, always 1.
+	_, pos, ok = readByte(payload, pos)
+	if !ok {
+		return "", nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading new params bound flag failed")
+	}
+
+	types := make([]querypb.Type, paramsCount)
+	names := make([]string, paramsCount)
+	for i := range types {
+		var err error
+		types[i], names[i], pos, err = parseQueryAttributeType(payload, pos)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	attributes := make(map[string]string, paramsCount)
+	for i, typ := range types {
+		if (bitMap[i/8] & (1 << uint(i%8))) > 0 {
+			continue
+		}
+		var val sqltypes.Value
+		val, pos, ok = c.parseStmtArgs(payload, typ, pos)
+		if !ok {
+			return "", nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "decoding query attribute %s failed", names[i])
+		}
+		attributes[names[i]] = val.ToString()
+	}
+
+	return string(payload[pos:]), attributes, nil
+}
+
+// parseQueryAttributeType reads the type and the name of a query attribute.
+func parseQueryAttributeType(payload []byte, pos int) (querypb.Type, string, int, error) {
+	mysqlType, pos, ok := readByte(payload, pos)
+	if !ok {
+		return 0, "", 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading parameter type failed")
+	}
+	flags, pos, ok := readByte(payload, pos)
+	if !ok {
+		return 0, "", 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading parameter flags failed")
+	}
+	valType, err := sqltypes.MySQLToType(int64(mysqlType), int64(flags))
+	if err != nil {
+		return 0, "", 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "MySQLToType(%v,%v) failed: %v", mysqlType, flags, err)
+	}
+	name, pos, ok := readLenEncString(payload, pos)
+	if !ok {
+		return 0, "", 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading parameter name failed")
+	}
+	return valType, name, pos, nil
+}
+
+// parseComChangeUser returns the user, the schema and the auth method of a
+// COM_CHANGE_USER. The auth-response is skipped, and so are the character set
+// and the connection attributes.
+func (c *Conn) parseComChangeUser(data []byte) (string, string, AuthMethodDescription, error) {
+	user, pos, ok := readNullString(data, 1)
+	if !ok {
+		return "", "", "", sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading user failed")
+	}
+	authResponseLen, pos, ok := readByte(data, pos)
+	if !ok {
+		return "", "", "", sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading auth-response length failed")
+	}
+	_, pos, ok = readBytes(data, pos, int(authResponseLen))
+	if !ok {
+		return "", "", "", sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading auth-response failed")
+	}
+	schemaName, pos, ok := readNullString(data, pos)
+	if !ok {
+		return "", "", "", sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading schema failed")
+	}
+
+	// The character set and the auth method are only sent by 4.1 clients.
+	authMethod := MysqlNativePassword
+	if _, pos, ok = readUint16(data, pos); ok && pos < len(data) {
+		authMethodStr, _, ok := readNullString(data, pos)
+		if !ok {
+			return "", "", "", sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading auth method failed")
+		}
+		if authMethodStr != "" {
+			authMethod = AuthMethodDescription(authMethodStr)
+		}
+	}
+	return user, schemaName, authMethod, nil
 }
 
 func (c *Conn) parseComSetOption(data []byte) (uint16, bool) {
@@ -544,7 +660,7 @@ func (c *Conn) parseComPrepare(data []byte) string {
 	return string(data[1:])
 }
 
-func (c *Conn) parseComStmtExecute(prepareData map[uint32]*PrepareData, data []byte) (uint32, byte, error) {
+func (c *Conn) parseComStmtExecute(prepareData map[uint32]*PrepareData, data []byte) (uint32, byte, map[string]string, error) {
 	pos := 0
 	payload := data[1:]
 	bitMap := make([]byte, 0)
@@ -552,57 +668,95 @@ func (c *Conn) parseComStmtExecute(prepareData map[uint32]*PrepareData, data []b
 	// statement ID
 	stmtID, pos, ok := readUint32(payload, 0)
 	if !ok {
-		return 0, 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading statement ID failed")
+		return 0, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading statement ID failed")
 	}
 	prepare, ok := prepareData[stmtID]
 	if !ok {
-		return 0, 0, sqlerror.NewSQLError(sqlerror.CRCommandsOutOfSync, sqlerror.SSUnknownSQLState, "statement ID is not found from record")
+		return 0, 0, nil, sqlerror.NewSQLError(sqlerror.CRCommandsOutOfSync, sqlerror.SSUnknownSQLState, "statement ID is not found from record")
 	}
 
 	// cursor type flags
 	cursorType, pos, ok := readByte(payload, pos)
 	if !ok {
-		return stmtID, 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading cursor type flags failed")
+		return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading cursor type flags failed")
 	}
 
 	// iteration count
 	iterCount, pos, ok := readUint32(payload, pos)
 	if !ok {
-		return stmtID, 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading iteration count failed")
+		return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading iteration count failed")
 	}
 	if iterCount != uint32(1) {
-		return stmtID, 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "iteration count is not equal to 1")
+		return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "iteration count is not equal to 1")
+	}
+
+	// With query attributes, the parameter count is sent and covers the
+	// statement parameters followed by the query attributes.
+	queryAttributes := c.Capabilities&CapabilityClientQueryAttributes != 0
+	paramsCount := int(prepare.ParamsCount)
+	if queryAttributes && (paramsCount > 0 || cursorType&CursorTypeParameterCountAvailable != 0) {
+		var count uint64
+		count, pos, ok = readLenEncInt(payload, pos)
+		if !ok {
+			return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading parameter count failed")
+		}
+		if count < uint64(prepare.ParamsCount) || count > uint64(len(payload)) {
+			return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "invalid parameter count %d", count)
+		}
+		paramsCount = int(count)
 	}
 
-	if prepare.ParamsCount > 0 {
-		bitMap, pos, ok = readBytes(payload, pos, (int(prepare.ParamsCount)+7)/8)
+	if paramsCount > 0 {
+		bitMap, pos, ok = readBytes(payload, pos, (paramsCount+7)/8)
 		if !ok {
-			return stmtID, 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading NULL-bitmap failed")
+			return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading NULL-bitmap failed")
 		}
 	}
 
+	var attributeTypes []querypb.Type
+	var attributeNames []string
 	newParamsBoundFlag, pos, ok := readByte(payload, pos)
 	if ok && newParamsBoundFlag == 0x01 {
 		var mysqlType, flags byte
 		for i := uint16(0); i < prepare.ParamsCount; i++ {
 			mysqlType, pos, ok = readByte(payload, pos)
 			if !ok {
-				return stmtID, 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading parameter type failed")
+				return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading parameter type failed")
 			}
 
 			flags, pos, ok = readByte(payload, pos)
 			if !ok {
-				return stmtID, 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading parameter flags failed")
+				return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading parameter flags failed")
 			}
 
 			// convert MySQL type to internal type.
 			valType, err := sqltypes.MySQLToType(int64(mysqlType), int64(flags))
 			if err != nil {
-				return stmtID, 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "MySQLToType(%v,%v) failed: %v", mysqlType, flags, err)
+				return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "MySQLToType(%v,%v) failed: %v", mysqlType, flags, err)
 			}
 
 			prepare.ParamsType[i] = int32(valType)
+
+			// statement parameters have a name too, usually empty.
+			if queryAttributes {
+				_, pos, ok = readLenEncString(payload, pos)
+				if !ok {
+					return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "reading parameter name failed")
+				}
+			}
 		}
+
+		for i := int(prepare.ParamsCount); i < paramsCount; i++ {
+			valType, name, next, err := parseQueryAttributeType(payload, pos)
+			if err != nil {
+				return stmtID, 0, nil, err
+			}
+			attributeTypes = append(attributeTypes, valType)
+			attributeNames = append(attributeNames, name)
+			pos = next
+		}
+	} else if paramsCount > int(prepare.ParamsCount) {
+		return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "query attributes sent without their types")
 	}
 
 	for i := 0; i < len(prepare.ParamsType); i++ {
@@ -620,13 +774,30 @@ func (c *Conn) parseComStmtExecute(prepareData map[uint32]*PrepareData, data []b
 			val, pos, ok = c.parseStmtArgs(payload, querypb.Type(prepare.ParamsType[i]), pos)
 		}
 		if !ok {
-			return stmtID, 0, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "decoding parameter value failed: %v", prepare.ParamsType[i])
+			return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "decoding parameter value failed: %v", prepare.ParamsType[i])
 		}
 
 		prepare.BindVars[parameterID] = sqltypes.ValueBindVariable(val)
 	}
 
-	return stmtID, cursorType, nil
+	if len(attributeTypes) == 0 {
+		return stmtID, cursorType, nil, nil
+	}
+	attributes := make(map[string]string, len(attributeTypes))
+	for j, typ := range attributeTypes {
+		i := int(prepare.ParamsCount) + j
+		if (bitMap[i/8] & (1 << uint(i%8))) > 0 {
+			continue
+		}
+		var val sqltypes.Value
+		val, pos, ok = c.parseStmtArgs(payload, typ, pos)
+		if !ok {
+			return stmtID, 0, nil, sqlerror.NewSQLError(sqlerror.CRMalformedPacket, sqlerror.SSUnknownSQLState, "decoding query attribute %s failed", attributeNames[j])
+		}
+		attributes[attributeNames[j]] = val.ToString()
+	}
+
+	return stmtID, cursorType, attributes, nil
 }
 
 func (c *Conn) parseStmtArgs(data []byte, typ querypb.Type, pos int) (sqltypes.Value, int, bool) {
@@ -885,6 +1056,15 @@ func (c *Conn) parseComStmtReset(data []byte) (uint32, bool) {
 	return val, ok
 }
 
+func (c *Conn) parseComStmtFetch(data []byte) (uint32, uint32, bool) {
+	stmtID, pos, ok := readUint32(data, 1)
+	if !ok {
+		return 0, 0, false
+	}
+	count, _, ok := readUint32(data, pos)
+	return stmtID, count, ok
+}
+
 func (c *Conn) parseComInitDB(data []byte) string {
 	return string(data[1:])
 }
@@ -1011,6 +1191,11 @@ func (c *Conn) writeEndResult(more bool, affectedRows, lastInsertID uint64, warn
 	if more {
 		flags |= ServerMoreResultsExists
 	}
+	return c.writeEndResultPacket(flags, affectedRows, lastInsertID, warnings)
+}
+
+// writeEndResultPacket sends the EOF, or OK packet, that ends a Result, with the given status flags.
+func (c *Conn) writeEndResultPacket(flags uint16, affectedRows, lastInsertID uint64, warnings uint16) error {
 	if c.Capabilities&CapabilityClientDeprecateEOF == 0 {
 		if err := c.writeEOFPacket(flags, warnings); err != nil {
 			return err
@@ -1030,6 +1215,22 @@ func (c *Conn) writeEndResult(more bool, affectedRows, lastInsertID uint64, warn
 	return nil
 }
 
+// writeCursorFields writes the fields of a Result, in response to the
+// COM_STMT_EXECUTE that opened a cursor. Unlike writeFields, the fields
+// are always followed by an EOF, or OK packet, telling the client that the
+// rows are to be fetched with COM_STMT_FETCH.
+func (c *Conn) writeCursorFields(result *sqltypes.Result) error {
+	if err := c.sendColumnCount(uint64(len(result.Fields))); err != nil {
+		return err
+	}
+	for _, field := range result.Fields {
+		if err := c.writeColumnDefinition(field); err != nil {
+			return err
+		}
+	}
+	return c.writeEndResultPacket(c.StatusFlags|ServerStatusCursorExists, 0, 0, 0)
+}
+
 // PacketComStmtPrepareOK contains the COM_STMT_PREPARE_OK packet details
 type PacketComStmtPrepareOK struct {
 	status       uint8
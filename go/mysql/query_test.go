@@ -248,7 +248,7 @@ func TestComStmtExecute(t *testing.T) {
 	// This is simulated packets for `select * from test_table where id = ?`
 	data := []byte{23, 18, 0, 0, 0, 128, 1, 0, 0, 0, 0, 1, 1, 128, 1}
 
-	stmtID, _, err := sConn.parseComStmtExecute(cConn.PrepareData, data)
+	stmtID, _, _, err := sConn.parseComStmtExecute(cConn.PrepareData, data)
 	require.NoError(t, err, "parseComStmtExeute failed: %v", err)
 	require.Equal(t, uint32(18), stmtID, "Parsed incorrect values")
 
@@ -292,7 +292,7 @@ func TestComStmtExecuteUpdStmt(t *testing.T) {
 		0x35, 0x36, 0x37, 0x38, 0x0c, 0xe9, 0x9f, 0xa9, 0xe5, 0x86, 0xac, 0xe7, 0x9c, 0x9f, 0xe8, 0xb5,
 		0x9e, 0x03, 0x66, 0x6f, 0x6f, 0x07, 0x66, 0x6f, 0x6f, 0x2c, 0x62, 0x61, 0x72}
 
-	stmtID, _, err := sConn.parseComStmtExecute(prepareDataMap, data[4:]) // first 4 are header
+	stmtID, _, _, err := sConn.parseComStmtExecute(prepareDataMap, data[4:]) // first 4 are header
 	require.NoError(t, err)
 	require.EqualValues(t, 1, stmtID)
 
@@ -331,6 +331,98 @@ func TestComStmtExecuteUpdStmt(t *testing.T) {
 	assert.EqualValues(t, querypb.Type_CHAR, prepData.ParamsType[28], "got: %s", querypb.Type(prepData.ParamsType[28]))
 }
 
+func TestComQueryAttributes(t *testing.T) {
+	listener, sConn, cConn := createSocketPair(t)
+	defer func() {
+		listener.Close()
+		sConn.Close()
+		cConn.Close()
+	}()
+
+	// Without the capability the whole payload is the query.
+	query, attributes, err := sConn.parseComQuery(append([]byte{ComQuery}, "select 1"...))
+	require.NoError(t, err)
+	assert.Equal(t, "select 1", query)
+	assert.Nil(t, attributes)
+
+	sConn.Capabilities |= CapabilityClientQueryAttributes
+
+	// Two attributes, the second one NULL.
+	data := []byte{ComQuery, 2, 1, 0x02, 1}
+	data = append(data, 0xfe, 0x00, 11)
+	data = append(data, "vt_priority"...)
+	data = append(data, 0x08, 0x00, 7)
+	data = append(data, "nothing"...)
+	data = append(data, 2, '1', '0')
+	data = append(data, "select 1"...)
+	query, attributes, err = sConn.parseComQuery(data)
+	require.NoError(t, err)
+	assert.Equal(t, "select 1", query)
+	assert.Equal(t, map[string]string{"vt_priority": "10"}, attributes)
+
+	// No attributes.
+	query, attributes, err = sConn.parseComQuery(append([]byte{ComQuery, 0, 1}, "select 1"...))
+	require.NoError(t, err)
+	assert.Equal(t, "select 1", query)
+	assert.Nil(t, attributes)
+
+	// Truncated attributes.
+	_, _, err = sConn.parseComQuery([]byte{ComQuery, 1, 1, 0x00, 1, 0xfe, 0x00, 11})
+	assert.ErrorContains(t, err, "reading parameter name failed")
+}
+
+func TestComStmtExecuteAttributes(t *testing.T) {
+	listener, sConn, cConn := createSocketPair(t)
+	defer func() {
+		listener.Close()
+		sConn.Close()
+		cConn.Close()
+	}()
+	sConn.Capabilities |= CapabilityClientQueryAttributes
+
+	prepareData := map[uint32]*PrepareData{
+		1: {
+			StatementID: 1,
+			ParamsCount: 1,
+			ParamsType:  make([]int32, 1),
+			BindVars:    map[string]*querypb.BindVariable{},
+		},
+		2: {
+			StatementID: 2,
+			BindVars:    map[string]*querypb.BindVariable{},
+		},
+	}
+
+	// One statement parameter followed by one query attribute.
+	data := []byte{ComStmtExecute, 1, 0, 0, 0, 0x00, 1, 0, 0, 0, 2, 0x00, 1}
+	data = append(data, 0x08, 0x00, 0)
+	data = append(data, 0xfe, 0x00, 16)
+	data = append(data, "vt_workload_name"...)
+	data = append(data, 5, 0, 0, 0, 0, 0, 0, 0)
+	data = append(data, 9)
+	data = append(data, "reporting"...)
+	stmtID, _, attributes, err := sConn.parseComStmtExecute(prepareData, data)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, stmtID)
+	assert.Equal(t, map[string]string{"vt_workload_name": "reporting"}, attributes)
+	assert.Equal(t, sqltypes.Int64BindVariable(5), prepareData[1].BindVars["v1"])
+
+	// A statement without parameters needs the parameter count flag.
+	data = []byte{ComStmtExecute, 2, 0, 0, 0, CursorTypeParameterCountAvailable, 1, 0, 0, 0, 1, 0x00, 1}
+	data = append(data, 0xfe, 0x00, 11)
+	data = append(data, "vt_priority"...)
+	data = append(data, 2, '1', '0')
+	_, cursorType, attributes, err := sConn.parseComStmtExecute(prepareData, data)
+	require.NoError(t, err)
+	assert.EqualValues(t, CursorTypeParameterCountAvailable, cursorType)
+	assert.Equal(t, map[string]string{"vt_priority": "10"}, attributes)
+
+	// Query attributes need their types.
+	data = []byte{ComStmtExecute, 2, 0, 0, 0, CursorTypeParameterCountAvailable, 1, 0, 0, 0, 1, 0x00, 0}
+	_, _, _, err = sConn.parseComStmtExecute(prepareData, data)
+	assert.ErrorContains(t, err, "query attributes sent without their types")
+}
+
 func TestComStmtClose(t *testing.T) {
 	listener, sConn, cConn := createSocketPair(t)
 	defer func() {
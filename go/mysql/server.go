@@ -68,7 +68,11 @@ var (
 
 	connCountByTLSVer = stats.NewGaugesWithSingleLabel("MysqlServerConnCountByTLSVer", "Active MySQL server connections by TLS version", "tls")
 	connCountPerUser  = stats.NewGaugesWithSingleLabel("MysqlServerConnCountPerUser", "Active MySQL server connections per user", "count")
-	_                 = stats.NewGaugeFunc("MysqlServerConnCountUnauthenticated", "Active MySQL server connections that haven't authenticated yet", func() int64 {
+
+	connCountByCompression = stats.NewGaugesWithSingleLabel("MysqlServerConnCountByCompression", "Active MySQL server connections using the compressed protocol, by algorithm", "algorithm")
+	compressionWireBytes   = stats.NewCountersWithSingleLabel("MysqlServerCompressedBytes", "Bytes received and sent on the wire by compressed MySQL server connections", "direction")
+	compressionRawBytes    = stats.NewCountersWithSingleLabel("MysqlServerCompressionRawBytes", "Protocol bytes carried by compressed MySQL server connections, before compression", "direction")
+	_                      = stats.NewGaugeFunc("MysqlServerConnCountUnauthenticated", "Active MySQL server connections that haven't authenticated yet", func() int64 {
 		totalUsers := int64(0)
 		for _, v := range connCountPerUser.Counts() {
 			totalUsers += v
@@ -133,6 +137,12 @@ type Handler interface {
 	WarningCount(c *Conn) uint16
 
 	ComResetConnection(c *Conn)
+
+	// ComChangeUser is called when the connection authenticated as
+	// another user with COM_CHANGE_USER. c.User and c.UserData are the
+	// ones of the new user. The state of the previous user's session
+	// must not be kept.
+	ComChangeUser(c *Conn)
 }
 
 // UnimplementedHandler implemnts all of the optional callbacks so as to satisy
@@ -145,6 +155,7 @@ func (UnimplementedHandler) NewConnection(*Conn)      {}
 func (UnimplementedHandler) ConnectionReady(*Conn)    {}
 func (UnimplementedHandler) ConnectionClosed(*Conn)   {}
 func (UnimplementedHandler) ComResetConnection(*Conn) {}
+func (UnimplementedHandler) ComChangeUser(*Conn)      {}
 
 // Listener is the MySQL server protocol listener.
 type Listener struct {
@@ -206,6 +217,16 @@ type Listener struct {
 	// RequireSecureTransport configures the server to reject connections from insecure clients
 	RequireSecureTransport bool
 
+	// CompressionAlgorithms are the compression algorithms we advertise
+	// to clients. If a client supports both, zlib is used, like MySQL
+	// does. If empty, the compressed protocol is never used.
+	CompressionAlgorithms []CompressionAlgorithm
+
+	// MaxCursorMemoryRows is the maximum number of rows of an open cursor
+	// that are read in memory when the connection runs another command.
+	// The cursor fails if it has more. Zero means no limit.
+	MaxCursorMemoryRows int
+
 	// PreHandleFunc is called for each incoming connection, immediately after
 	// accepting a new connection. By default it's no-op. Useful for custom
 	// connection inspection or TLS termination. The returned connection is
@@ -370,12 +391,13 @@ func (l *Listener) handle(conn net.Conn, connectionID uint32, acceptTime time.Ti
 	// Tell the handler about the connection coming and going.
 	l.handler.NewConnection(c)
 	defer l.handler.ConnectionClosed(c)
+	defer c.closeCursors()
 
 	// Adjust the count of open connections
 	defer connCount.Add(-1)
 
 	// First build and send the server handshake packet.
-	serverAuthPluginData, err := c.writeHandshakeV10(l.ServerVersion, l.authServer, l.TLSConfig.Load() != nil)
+	serverAuthPluginData, err := c.writeHandshakeV10(l.ServerVersion, l.authServer, l.TLSConfig.Load() != nil, l.CompressionAlgorithms)
 	if err != nil {
 		if err != io.EOF {
 			log.Errorf("Cannot send HandshakeV10 packet to %s: %v", c, err)
@@ -498,8 +520,13 @@ func (l *Listener) handle(conn net.Conn, connectionID uint32, acceptTime time.Ti
 
 	if c.User != "" {
 		connCountPerUser.Add(c.User, 1)
-		defer connCountPerUser.Add(c.User, -1)
 	}
+	// The user can be changed with COM_CHANGE_USER.
+	defer func() {
+		if c.User != "" {
+			connCountPerUser.Add(c.User, -1)
+		}
+	}()
 
 	// Set initial db name.
 	if c.schemaName != "" {
@@ -518,6 +545,13 @@ func (l *Listener) handle(conn net.Conn, connectionID uint32, acceptTime time.Ti
 		return
 	}
 
+	// The compressed protocol, if negotiated, starts after the OK packet.
+	c.startCompression()
+	if algorithm := c.CompressionAlgorithm(); algorithm != CompressionNone {
+		connCountByCompression.Add(string(algorithm), 1)
+		defer connCountByCompression.Add(string(algorithm), -1)
+	}
+
 	// Record how long we took to establish the connection
 	timings.Record(connectTimingKey, acceptTime)
 
@@ -556,7 +590,7 @@ func (l *Listener) Shutdown() {
 
 // writeHandshakeV10 writes the Initial Handshake Packet, server side.
 // It returns the salt data.
-func (c *Conn) writeHandshakeV10(serverVersion string, authServer AuthServer, enableTLS bool) ([]byte, error) {
+func (c *Conn) writeHandshakeV10(serverVersion string, authServer AuthServer, enableTLS bool, compressionAlgorithms []CompressionAlgorithm) ([]byte, error) {
 	capabilities := CapabilityClientLongPassword |
 		CapabilityClientFoundRows |
 		CapabilityClientLongFlag |
@@ -569,10 +603,19 @@ func (c *Conn) writeHandshakeV10(serverVersion string, authServer AuthServer, en
 		CapabilityClientPluginAuth |
 		CapabilityClientPluginAuthLenencClientData |
 		CapabilityClientDeprecateEOF |
-		CapabilityClientConnAttr
+		CapabilityClientConnAttr |
+		CapabilityClientQueryAttributes
 	if enableTLS {
 		capabilities |= CapabilityClientSSL
 	}
+	for _, algorithm := range compressionAlgorithms {
+		switch algorithm {
+		case CompressionZlib:
+			capabilities |= CapabilityClientCompress
+		case CompressionZstd:
+			capabilities |= CapabilityClientZstdCompressionAlgorithm
+		}
+	}
 
 	// Grab the default auth method. This can only be either
 	// mysql_native_password or caching_sha2_password. Both
@@ -690,7 +733,7 @@ func (l *Listener) parseClientHandshakePacket(c *Conn, firstTime bool, data []by
 	// later in the protocol. If we re-received the handshake packet
 	// after SSL negotiation, do not overwrite capabilities.
 	if firstTime {
-		c.Capabilities = clientFlags & (CapabilityClientDeprecateEOF | CapabilityClientFoundRows)
+		c.Capabilities = clientFlags & (CapabilityClientDeprecateEOF | CapabilityClientFoundRows | CapabilityClientQueryAttributes)
 	}
 
 	// set connection capability for executing multi statements
@@ -790,14 +833,41 @@ func (l *Listener) parseClientHandshakePacket(c *Conn, firstTime bool, data []by
 
 	// Decode connection attributes send by the client
 	if clientFlags&CapabilityClientConnAttr != 0 {
-		if _, _, err := parseConnAttrs(data, pos); err != nil {
+		_, next, err := parseConnAttrs(data, pos)
+		if err != nil {
 			log.Warningf("Decode connection attributes send by the client: %v", err)
+			next = len(data)
+		}
+		pos = next
+	}
+
+	// Pick the compression algorithm. The zstd level, if any, is
+	// the last field of the packet.
+	c.compressionAlgorithm = CompressionNone
+	switch {
+	case clientFlags&CapabilityClientCompress != 0 && l.allowsCompression(CompressionZlib):
+		c.compressionAlgorithm = CompressionZlib
+	case clientFlags&CapabilityClientZstdCompressionAlgorithm != 0 && l.allowsCompression(CompressionZstd):
+		c.compressionAlgorithm = CompressionZstd
+		if level, _, ok := readByte(data, pos); ok {
+			c.zstdCompressionLevel = int(level)
 		}
 	}
 
 	return username, AuthMethodDescription(authMethod), authResponse, nil
 }
 
+// allowsCompression returns true if the given algorithm was advertised
+// to clients.
+func (l *Listener) allowsCompression(algorithm CompressionAlgorithm) bool {
+	for _, a := range l.CompressionAlgorithms {
+		if a == algorithm {
+			return true
+		}
+	}
+	return false
+}
+
 func parseConnAttrs(data []byte, pos int) (map[string]string, int, error) {
 	var attrLen uint64
 
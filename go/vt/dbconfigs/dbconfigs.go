@@ -89,6 +89,7 @@ type DBConfigs struct {
 	ConnectTimeoutMilliseconds int           `json:"connectTimeoutMilliseconds,omitempty"`
 	DBName                     string        `json:"dbName,omitempty"`
 	EnableQueryInfo            bool          `json:"enableQueryInfo,omitempty"`
+	Compression                string        `json:"compression,omitempty"`
 
 	App          UserConfig `json:"app,omitempty"`
 	Dba          UserConfig `json:"dba,omitempty"`
@@ -145,6 +146,7 @@ func registerBaseFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&GlobalDBConfigs.ServerName, "db_server_name", "", "server name of the DB we are connecting to.")
 	fs.IntVar(&GlobalDBConfigs.ConnectTimeoutMilliseconds, "db_connect_timeout_ms", 0, "connection timeout to mysqld in milliseconds (0 for no timeout)")
 	fs.BoolVar(&GlobalDBConfigs.EnableQueryInfo, "db_conn_query_info", false, "enable parsing and processing of QUERY_OK info fields")
+	fs.StringVar(&GlobalDBConfigs.Compression, "db_compression", "", "Compression algorithm for the MySQL protocol, used if mysqld supports it. Options: zlib, zstd. Empty disables compression.")
 }
 
 // The flags will change the global singleton
@@ -311,6 +313,38 @@ func (dbcfgs *DBConfigs) Clone() *DBConfigs {
 	return &result
 }
 
+// CloneWithSocket returns a clone of the DBConfig, whose connection
+// parameters connect to the given socket file rather than to the
+// configured host or socket.
+func (dbcfgs *DBConfigs) CloneWithSocket(socketFile string) *DBConfigs {
+	result := dbcfgs.Clone()
+	for _, userKey := range All {
+		if userKey == ExternalRepl {
+			continue
+		}
+		_, cp := result.getParams(userKey, result)
+		cp.Host = ""
+		cp.Port = 0
+		cp.UnixSocket = socketFile
+	}
+	return result
+}
+
+// compressionAlgorithm returns the algorithm set with --db_compression, which names at most one algorithm.
+func (dbcfgs *DBConfigs) compressionAlgorithm() (mysql.CompressionAlgorithm, error) {
+	algorithms, err := mysql.ParseCompressionAlgorithms(dbcfgs.Compression)
+	if err != nil {
+		return mysql.CompressionNone, err
+	}
+	switch len(algorithms) {
+	case 0:
+		return mysql.CompressionNone, nil
+	case 1:
+		return algorithms[0], nil
+	}
+	return mysql.CompressionNone, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "only one compression algorithm can be used, got %q", dbcfgs.Compression)
+}
+
 // InitWithSocket will initialize all the necessary connection parameters.
 // Precedence is as follows: if UserConfig settings are set,
 // they supersede all other settings.
@@ -319,6 +353,10 @@ func (dbcfgs *DBConfigs) Clone() *DBConfigs {
 // If no per-user parameters are supplied, then the defaultSocketFile
 // is used to initialize the per-user conn params.
 func (dbcfgs *DBConfigs) InitWithSocket(defaultSocketFile string) {
+	compression, err := dbcfgs.compressionAlgorithm()
+	if err != nil {
+		log.Exitf("Invalid --db_compression: %v", err)
+	}
 	for _, userKey := range All {
 		uc, cp := dbcfgs.getParams(userKey, dbcfgs)
 		// TODO @rafael: For ExternalRepl we need to respect the provided host / port
@@ -350,6 +388,7 @@ func (dbcfgs *DBConfigs) InitWithSocket(defaultSocketFile string) {
 		}
 		cp.ConnectTimeoutMs = uint64(dbcfgs.ConnectTimeoutMilliseconds)
 		cp.EnableQueryInfo = dbcfgs.EnableQueryInfo
+		cp.Compression = compression
 
 		cp.Uname = uc.User
 		cp.Pass = uc.Password
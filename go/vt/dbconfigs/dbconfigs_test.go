@@ -187,6 +187,32 @@ func TestInit(t *testing.T) {
 	assert.Equal(t, want, dbConfigs.dbaParams)
 }
 
+func TestCompressionAlgorithm(t *testing.T) {
+	tcases := []struct {
+		compression string
+		algorithm   mysql.CompressionAlgorithm
+		err         string
+	}{
+		{compression: "", algorithm: mysql.CompressionNone},
+		{compression: "zlib", algorithm: mysql.CompressionZlib},
+		{compression: " ZSTD ", algorithm: mysql.CompressionZstd},
+		{compression: "zstdd", err: `unknown compression algorithm "zstdd"`},
+		{compression: "zlib,zstd", err: "only one compression algorithm can be used"},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.compression, func(t *testing.T) {
+			dbcfgs := DBConfigs{Compression: tcase.compression}
+			algorithm, err := dbcfgs.compressionAlgorithm()
+			if tcase.err != "" {
+				assert.ErrorContains(t, err, tcase.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tcase.algorithm, algorithm)
+		})
+	}
+}
+
 func TestUseTCP(t *testing.T) {
 	dbConfigs := DBConfigs{
 		Host:   "a",
@@ -221,6 +247,40 @@ func TestUseTCP(t *testing.T) {
 	assert.Equal(t, want, dbConfigs.dbaParams)
 }
 
+func TestCloneWithSocket(t *testing.T) {
+	dbConfigs := DBConfigs{
+		Host: "a",
+		Port: 1,
+		App: UserConfig{
+			User:   "app",
+			UseTCP: true,
+		},
+		Dba: UserConfig{
+			User: "dba",
+		},
+		Charset: "utf8",
+	}
+	dbConfigs.InitWithSocket("default")
+	dbConfigs.externalReplParams = mysql.ConnParams{Host: "external", Port: 2}
+
+	clone := dbConfigs.CloneWithSocket("scratch.sock")
+	assert.Equal(t, mysql.ConnParams{
+		Uname:      "app",
+		UnixSocket: "scratch.sock",
+		Charset:    "utf8",
+	}, clone.appParams)
+	assert.Equal(t, mysql.ConnParams{
+		Uname:      "dba",
+		UnixSocket: "scratch.sock",
+		Charset:    "utf8",
+	}, clone.dbaParams)
+	// The external replication user doesn't connect to the local mysqld.
+	assert.Equal(t, dbConfigs.externalReplParams, clone.externalReplParams)
+	// The original is untouched.
+	assert.Equal(t, "a", dbConfigs.appParams.Host)
+	assert.Equal(t, "a", dbConfigs.dbaParams.Host)
+}
+
 func TestAccessors(t *testing.T) {
 	dbc := &DBConfigs{
 		appParams:      mysql.ConnParams{},
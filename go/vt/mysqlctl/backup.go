@@ -29,6 +29,7 @@ import (
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 
+	"vitess.io/vitess/go/mysql/replication"
 	"vitess.io/vitess/go/vt/log"
 	"vitess.io/vitess/go/vt/mysqlctl/backupstats"
 	"vitess.io/vitess/go/vt/mysqlctl/backupstorage"
@@ -301,6 +302,16 @@ func removeExistingFiles(cnf *Mycnf) error {
 	return nil
 }
 
+// findBackup returns the handle of the backup with the given name.
+func findBackup(bhs []backupstorage.BackupHandle, backupDir string, name string) (backupstorage.BackupHandle, error) {
+	for _, bh := range bhs {
+		if bh.Name() == name {
+			return bh, nil
+		}
+	}
+	return nil, vterrors.Errorf(vtrpc.Code_NOT_FOUND, "backup %v not found in directory %v", name, backupDir)
+}
+
 // ShouldRestore checks whether a database with tables already exists
 // and returns whether a restore action should be performed
 func ShouldRestore(ctx context.Context, params RestoreParams) (bool, error) {
@@ -389,6 +400,13 @@ func Restore(ctx context.Context, params RestoreParams) (*BackupManifest, error)
 	if err != nil {
 		return nil, vterrors.Wrap(err, "ListBackups failed")
 	}
+	if params.BackupName != "" {
+		bh, err := findBackup(bhs, backupDir, params.BackupName)
+		if err != nil {
+			return nil, err
+		}
+		bhs = []backupstorage.BackupHandle{bh}
+	}
 
 	if len(bhs) == 0 {
 		// There are no backups (not even broken/incomplete ones).
@@ -479,6 +497,11 @@ func Restore(ctx context.Context, params RestoreParams) (*BackupManifest, error)
 			params.Logger.Infof("Restore: applied incremental backup: %v", manifest.Position)
 		}
 		params.Logger.Infof("Restore: done applying incremental backups")
+		pos, err := params.Mysqld.PrimaryPosition()
+		if err != nil {
+			return nil, vterrors.Wrap(err, "failed to get the restored position")
+		}
+		params.Logger.Infof("Restore: point in time recovery reached position %v", replication.EncodePosition(pos))
 	}
 
 	params.Logger.Infof("Restore: removing state file")
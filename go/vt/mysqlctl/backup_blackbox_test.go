@@ -19,6 +19,7 @@ package mysqlctl_test
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"path"
@@ -580,6 +581,135 @@ func TestExecuteRestoreWithTimedOutContext(t *testing.T) {
 	}
 }
 
+func TestExecuteBackupAndRestoreEncrypted(t *testing.T) {
+	ctx := utils.LeakCheckContext(t)
+
+	// Set up local backup directory
+	id := fmt.Sprintf("%d", time.Now().UnixNano())
+	backupRoot := fmt.Sprintf("testdata/builtinbackup_test_%s", id)
+	filebackupstorage.FileBackupStorageRoot = backupRoot
+	require.NoError(t, createBackupDir(backupRoot, "innodb", "log", "datadir"))
+	dataDir := path.Join(backupRoot, "datadir")
+	require.NoError(t, createBackupDir(dataDir, "test1"))
+	require.NoError(t, createBackupFiles(path.Join(dataDir, "test1"), 2, "ibd"))
+	defer os.RemoveAll(backupRoot)
+
+	needIt, err := needInnoDBRedoLogSubdir()
+	require.NoError(t, err)
+	if needIt {
+		fpath := path.Join("log", mysql.DynamicRedoLogSubdir)
+		if err := createBackupDir(backupRoot, fpath); err != nil {
+			require.Failf(t, err.Error(), "failed to create directory: %s", fpath)
+		}
+	}
+
+	// Encrypt the backup with the keys of a keyfile.
+	keyfile := path.Join(t.TempDir(), "keyfile")
+	require.NoError(t, os.WriteFile(keyfile, []byte("key1 "+base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))+"\n"), 0600))
+	defer func(provider, keyfile string) {
+		mysqlctl.BackupEncryptionKeyProvider, mysqlctl.BackupEncryptionKeyfile = provider, keyfile
+	}(mysqlctl.BackupEncryptionKeyProvider, mysqlctl.BackupEncryptionKeyfile)
+	mysqlctl.BackupEncryptionKeyProvider = mysqlctl.KeyfileKeyProvider
+	mysqlctl.BackupEncryptionKeyfile = keyfile
+
+	// Set up topo
+	keyspace, shard := "mykeyspace", "-80"
+	ts := memorytopo.NewServer(ctx, "cell1")
+	defer ts.Close()
+
+	require.NoError(t, ts.CreateKeyspace(ctx, keyspace, &topodata.Keyspace{}))
+	require.NoError(t, ts.CreateShard(ctx, keyspace, shard))
+
+	tablet := topo.NewTablet(100, "cell1", "mykeyspace-00-80-0100")
+	tablet.Keyspace = keyspace
+	tablet.Shard = shard
+
+	require.NoError(t, ts.CreateTablet(ctx, tablet))
+
+	_, err = ts.UpdateShardFields(ctx, keyspace, shard, func(si *topo.ShardInfo) error {
+		si.PrimaryAlias = &topodata.TabletAlias{Uid: 100, Cell: "cell1"}
+
+		now := time.Now()
+		si.PrimaryTermStartTime = &vttime.Time{Seconds: int64(now.Second()), Nanoseconds: int32(now.Nanosecond())}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	be := &mysqlctl.BuiltinBackupEngine{}
+	bh := filebackupstorage.NewBackupHandle(nil, "", "", false)
+	fakedb := fakesqldb.New(t)
+	defer fakedb.Close()
+	mysqld := mysqlctl.NewFakeMysqlDaemon(fakedb)
+	defer mysqld.Close()
+	mysqld.ExpectedExecuteSuperQueryList = []string{"STOP SLAVE", "START SLAVE"}
+
+	cnf := &mysqlctl.Mycnf{
+		InnodbDataHomeDir:     path.Join(backupRoot, "innodb"),
+		InnodbLogGroupHomeDir: path.Join(backupRoot, "log"),
+		DataDir:               path.Join(backupRoot, "datadir"),
+		BinLogPath:            path.Join(backupRoot, "binlog"),
+		RelayLogPath:          path.Join(backupRoot, "relaylog"),
+		RelayLogIndexPath:     path.Join(backupRoot, "relaylogindex"),
+		RelayLogInfoPath:      path.Join(backupRoot, "relayloginfo"),
+	}
+	ok, err := be.ExecuteBackup(ctx, mysqlctl.BackupParams{
+		Logger:       logutil.NewConsoleLogger(),
+		Mysqld:       mysqld,
+		Cnf:          cnf,
+		Stats:        backupstats.NewFakeStats(),
+		Concurrency:  2,
+		HookExtraEnv: map[string]string{},
+		TopoServer:   ts,
+		Keyspace:     keyspace,
+		Shard:        shard,
+	}, bh)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// The key is recorded in the MANIFEST, and the files are encrypted.
+	manifest, err := os.ReadFile(path.Join(backupRoot, "MANIFEST"))
+	require.NoError(t, err)
+	assert.Contains(t, string(manifest), `"KeyProvider": "keyfile"`)
+	assert.Contains(t, string(manifest), `"KeyID": "key1"`)
+	for i := 0; i < 2; i++ {
+		data, err := os.ReadFile(path.Join(backupRoot, fmt.Sprintf("%d", i)))
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "hello, world!")
+	}
+
+	restoreParams := mysqlctl.RestoreParams{
+		Cnf:          cnf,
+		Logger:       logutil.NewConsoleLogger(),
+		Mysqld:       mysqld,
+		Concurrency:  2,
+		HookExtraEnv: map[string]string{},
+		DbName:       "test",
+		Keyspace:     "test",
+		Shard:        "-",
+		StartTime:    time.Now(),
+		Stats:        backupstats.NewFakeStats(),
+	}
+
+	// The restore fails if the key isn't in the keyfile anymore.
+	require.NoError(t, os.WriteFile(keyfile, []byte("key2 "+base64.StdEncoding.EncodeToString([]byte("abcdef0123456789abcdef0123456789"))+"\n"), 0600))
+	bh = filebackupstorage.NewBackupHandle(nil, "", "", true)
+	_, err = be.ExecuteRestore(ctx, restoreParams, bh)
+	assert.ErrorContains(t, err, `key "key1" is not in the backup encryption keyfile`)
+
+	// The restore decrypts the files once the key is back.
+	require.NoError(t, os.WriteFile(keyfile, []byte("key2 "+base64.StdEncoding.EncodeToString([]byte("abcdef0123456789abcdef0123456789"))+"\nkey1 "+base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))+"\n"), 0600))
+	require.NoError(t, os.RemoveAll(path.Join(dataDir, "test1")))
+	bm, err := be.ExecuteRestore(ctx, restoreParams, bh)
+	require.NoError(t, err)
+	assert.NotNil(t, bm)
+	for i := 0; i < 2; i++ {
+		data, err := os.ReadFile(path.Join(dataDir, "test1", fmt.Sprintf("%d.ibd", i)))
+		require.NoError(t, err)
+		assert.Equal(t, "hello, world!", string(data))
+	}
+}
+
 // needInnoDBRedoLogSubdir indicates whether we need to create a redo log subdirectory.
 // Starting with MySQL 8.0.30, the InnoDB redo logs are stored in a subdirectory of the
 // <innodb_log_group_home_dir> (<datadir>/. by default) called "#innodb_redo". See:
@@ -119,6 +119,8 @@ type RestoreParams struct {
 	// StartTime: if non-zero, look for a backup that was taken at or before this time
 	// Otherwise, find the most recent backup
 	StartTime time.Time
+	// BackupName: if set, restore this full backup, rather than looking for one by time.
+	BackupName string
 	// RestoreToPos hints that a point in time recovery is requested, to recover up to the specific given pos.
 	// When empty, the restore is a normal from full backup
 	RestoreToPos replication.Position
@@ -144,6 +146,7 @@ func (p *RestoreParams) Copy() RestoreParams {
 		Keyspace:            p.Keyspace,
 		Shard:               p.Shard,
 		StartTime:           p.StartTime,
+		BackupName:          p.BackupName,
 		RestoreToPos:        p.RestoreToPos,
 		RestoreToTimestamp:  p.RestoreToTimestamp,
 		DryRun:              p.DryRun,
@@ -309,6 +312,12 @@ type BackupManifest struct {
 
 	// IncrementalDetails is nil for non-incremental backups
 	IncrementalDetails *IncrementalBackupDetails
+
+	// TableChecksums are the CHECKSUM TABLE values of all the tables at the
+	// backup position, by "<database>.<table>". They are only recorded by the
+	// builtin engine with --builtinbackup-table-checksums, for VerifyBackup to
+	// compare the restored tables against.
+	TableChecksums map[string]uint64 `json:",omitempty"`
 }
 
 func (m *BackupManifest) HashKey() string {
@@ -0,0 +1,160 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"vitess.io/vitess/go/vt/logutil"
+	"vitess.io/vitess/go/vt/mysqlctl/backupstorage"
+
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+)
+
+// IsBackupRetentionPolicyEmpty returns true if the policy doesn't keep any
+// backup bucket, in which case backups are never pruned.
+func IsBackupRetentionPolicyEmpty(policy *topodatapb.BackupRetentionPolicy) bool {
+	return policy.GetDaily() == 0 && policy.GetWeekly() == 0 && policy.GetMonthly() == 0
+}
+
+// retainedBackup is a complete backup considered by BackupsToPrune.
+type retainedBackup struct {
+	handle     backupstorage.BackupHandle
+	manifest   *BackupManifest
+	backupTime time.Time
+	keep       bool
+}
+
+// BackupsToPrune returns the backups of a shard that the retention policy
+// doesn't keep. bhs are the backups of the shard, as returned by
+// BackupStorage.ListBackups.
+//
+// Full backups are bucketed by UTC day, ISO week and month, and the last full
+// backup of each of the most recent buckets is kept, as many buckets as the
+// policy allows. The latest full backup is always kept. Incremental backups
+// taken since the oldest full backup kept are kept, and so are all the backups
+// a kept incremental backup depends on. Backups whose MANIFEST can't be read
+// may be in progress, and are never pruned.
+func BackupsToPrune(ctx context.Context, logger logutil.Logger, policy *topodatapb.BackupRetentionPolicy, bhs []backupstorage.BackupHandle) []backupstorage.BackupHandle {
+	if IsBackupRetentionPolicyEmpty(policy) {
+		return nil
+	}
+
+	backups := make([]*retainedBackup, 0, len(bhs))
+	for _, bh := range bhs {
+		manifest, err := GetBackupManifest(ctx, bh)
+		if err != nil {
+			logger.Warningf("Not pruning backup %v/%v, which is possibly incomplete: can't read MANIFEST: %v", bh.Directory(), bh.Name(), err)
+			continue
+		}
+		backupTime, err := ParseRFC3339(manifest.BackupTime)
+		if err != nil {
+			logger.Warningf("Not pruning backup %v/%v with invalid time %v: %v", bh.Directory(), bh.Name(), manifest.BackupTime, err)
+			continue
+		}
+		backups = append(backups, &retainedBackup{handle: bh, manifest: manifest, backupTime: backupTime.UTC()})
+	}
+
+	// Full backups, newest first.
+	buckets := []struct {
+		count uint32
+		key   func(t time.Time) string
+		last  string
+	}{
+		{count: policy.GetDaily(), key: func(t time.Time) string { return t.Format("2006-01-02") }},
+		{count: policy.GetWeekly(), key: func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{count: policy.GetMonthly(), key: func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	var oldestKeptFull *retainedBackup
+	for i := len(backups) - 1; i >= 0; i-- {
+		backup := backups[i]
+		if backup.manifest.Incremental {
+			continue
+		}
+		if oldestKeptFull == nil {
+			// The latest full backup.
+			backup.keep = true
+		}
+		for j := range buckets {
+			bucket := &buckets[j]
+			if bucket.count == 0 {
+				continue
+			}
+			if key := bucket.key(backup.backupTime); key != bucket.last {
+				bucket.last = key
+				bucket.count--
+				backup.keep = true
+			}
+		}
+		if backup.keep {
+			oldestKeptFull = backup
+		}
+	}
+	if oldestKeptFull == nil {
+		// No full backup at all: there is nothing incremental backups could be
+		// restored on top of, but we'd rather not guess.
+		return nil
+	}
+
+	// Incremental backups, newest first, so that the backups they depend on
+	// are considered after them.
+	for i := len(backups) - 1; i >= 0; i-- {
+		backup := backups[i]
+		if !backup.manifest.Incremental {
+			continue
+		}
+		if !backup.keep && backup.backupTime.Before(oldestKeptFull.backupTime) {
+			continue
+		}
+		backup.keep = true
+		if base := incrementalBackupBase(backups, i); base != nil {
+			base.keep = true
+		}
+	}
+
+	var prune []backupstorage.BackupHandle
+	for _, backup := range backups {
+		if !backup.keep {
+			prune = append(prune, backup.handle)
+		}
+	}
+	return prune
+}
+
+// incrementalBackupBase returns the backup the incremental backups[i] was
+// taken on top of: the backup named in its manifest, or else the latest
+// previous backup that covers its starting position.
+func incrementalBackupBase(backups []*retainedBackup, i int) *retainedBackup {
+	manifest := backups[i].manifest
+	for j := i - 1; j >= 0; j-- {
+		if manifest.FromBackup != "" {
+			if backups[j].handle.Name() == manifest.FromBackup {
+				return backups[j]
+			}
+			continue
+		}
+		if !manifest.FromPosition.IsZero() && backups[j].manifest.Position.AtLeast(manifest.FromPosition) {
+			return backups[j]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/mysql/replication"
+	"vitess.io/vitess/go/vt/logutil"
+	"vitess.io/vitess/go/vt/mysqlctl/backupstorage"
+
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+)
+
+type retentionTestBackup struct {
+	name         string
+	backupTime   string
+	incremental  bool
+	fromBackup   string
+	fromPosition string
+	position     string
+	noManifest   bool
+}
+
+func retentionTestBackupHandles(t *testing.T, backups []retentionTestBackup) []backupstorage.BackupHandle {
+	pos := func(gtids string) replication.Position {
+		if gtids == "" {
+			return replication.Position{}
+		}
+		return replication.MustParsePosition(replication.Mysql56FlavorID, "16b1039f-22b6-11ed-b765-0a43f95f28a3:"+gtids)
+	}
+	bhs := make([]backupstorage.BackupHandle, 0, len(backups))
+	for _, backup := range backups {
+		manifestBytes, err := json.Marshal(BackupManifest{
+			BackupMethod: "fake",
+			BackupTime:   backup.backupTime,
+			Incremental:  backup.incremental,
+			FromBackup:   backup.fromBackup,
+			FromPosition: pos(backup.fromPosition),
+			Position:     pos(backup.position),
+		})
+		require.NoError(t, err)
+		noManifest := backup.noManifest
+		bhs = append(bhs, &FakeBackupHandle{
+			Dir:   "test/-",
+			NameV: backup.name,
+			ReadFileReturnF: func(context.Context, string) (io.ReadCloser, error) {
+				if noManifest {
+					return nil, errors.New("no such file")
+				}
+				return io.NopCloser(bytes.NewBuffer(manifestBytes)), nil
+			},
+		})
+	}
+	return bhs
+}
+
+func TestBackupsToPrune(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *topodatapb.BackupRetentionPolicy
+		backups  []retentionTestBackup
+		expected []string
+	}{
+		{
+			name: "no policy",
+			backups: []retentionTestBackup{
+				{name: "f1", backupTime: "2023-08-01T10:00:00Z", position: "1-100"},
+				{name: "f2", backupTime: "2023-08-02T10:00:00Z", position: "1-200"},
+			},
+		},
+		{
+			name:   "empty policy",
+			policy: &topodatapb.BackupRetentionPolicy{},
+			backups: []retentionTestBackup{
+				{name: "f1", backupTime: "2023-08-01T10:00:00Z", position: "1-100"},
+				{name: "f2", backupTime: "2023-08-02T10:00:00Z", position: "1-200"},
+			},
+		},
+		{
+			name:   "daily",
+			policy: &topodatapb.BackupRetentionPolicy{Daily: 2},
+			backups: []retentionTestBackup{
+				{name: "f1", backupTime: "2023-08-01T10:00:00Z", position: "1-100"},
+				{name: "f2", backupTime: "2023-08-01T22:00:00Z", position: "1-200"},
+				{name: "f3", backupTime: "2023-08-02T10:00:00Z", position: "1-300"},
+				{name: "f4", backupTime: "2023-08-02T22:00:00Z", position: "1-400"},
+				{name: "f5", backupTime: "2023-08-03T10:00:00Z", position: "1-500"},
+			},
+			expected: []string{"f1", "f2", "f3"},
+		},
+		{
+			name:   "daily and weekly",
+			policy: &topodatapb.BackupRetentionPolicy{Daily: 1, Weekly: 3},
+			backups: []retentionTestBackup{
+				{name: "f1", backupTime: "2023-07-10T10:00:00Z", position: "1-100"},
+				{name: "f2", backupTime: "2023-07-17T10:00:00Z", position: "1-200"},
+				{name: "f3", backupTime: "2023-07-20T10:00:00Z", position: "1-300"},
+				{name: "f4", backupTime: "2023-07-25T10:00:00Z", position: "1-400"},
+				{name: "f5", backupTime: "2023-08-01T10:00:00Z", position: "1-500"},
+				{name: "f6", backupTime: "2023-08-02T10:00:00Z", position: "1-600"},
+			},
+			expected: []string{"f1", "f2", "f5"},
+		},
+		{
+			name:   "monthly",
+			policy: &topodatapb.BackupRetentionPolicy{Monthly: 2},
+			backups: []retentionTestBackup{
+				{name: "f1", backupTime: "2023-06-30T10:00:00Z", position: "1-100"},
+				{name: "f2", backupTime: "2023-07-01T10:00:00Z", position: "1-200"},
+				{name: "f3", backupTime: "2023-07-31T10:00:00Z", position: "1-300"},
+				{name: "f4", backupTime: "2023-08-01T10:00:00Z", position: "1-400"},
+			},
+			expected: []string{"f1", "f2"},
+		},
+		{
+			name:   "incremental backups since the oldest full backup kept",
+			policy: &topodatapb.BackupRetentionPolicy{Daily: 1},
+			backups: []retentionTestBackup{
+				{name: "f1", backupTime: "2023-07-31T10:00:00Z", position: "1-100"},
+				{name: "i1", backupTime: "2023-07-31T12:00:00Z", incremental: true, fromPosition: "1-100", position: "1-150"},
+				{name: "f2", backupTime: "2023-08-01T10:00:00Z", position: "1-200"},
+				{name: "i2", backupTime: "2023-08-01T12:00:00Z", incremental: true, fromPosition: "1-200", position: "1-250"},
+				{name: "i3", backupTime: "2023-08-01T14:00:00Z", incremental: true, fromPosition: "1-250", position: "1-300"},
+			},
+			expected: []string{"f1", "i1"},
+		},
+		{
+			name:   "backups kept incremental backups depend on",
+			policy: &topodatapb.BackupRetentionPolicy{Daily: 1},
+			backups: []retentionTestBackup{
+				{name: "f1", backupTime: "2023-07-30T10:00:00Z", position: "1-50"},
+				{name: "f2", backupTime: "2023-07-31T10:00:00Z", position: "1-100"},
+				{name: "i1", backupTime: "2023-08-01T08:00:00Z", incremental: true, fromPosition: "1-100", position: "1-150"},
+				{name: "f3", backupTime: "2023-08-01T09:00:00Z", position: "1-120"},
+				{name: "i2", backupTime: "2023-08-01T10:00:00Z", incremental: true, fromPosition: "1-150", position: "1-200"},
+			},
+			expected: []string{"f1"},
+		},
+		{
+			name:   "incremental backup based on a named backup",
+			policy: &topodatapb.BackupRetentionPolicy{Daily: 1},
+			backups: []retentionTestBackup{
+				{name: "f1", backupTime: "2023-07-30T10:00:00Z", position: "1-50"},
+				{name: "f2", backupTime: "2023-07-31T10:00:00Z", position: "1-100"},
+				{name: "f3", backupTime: "2023-08-01T09:00:00Z", position: "1-120"},
+				{name: "i1", backupTime: "2023-08-01T10:00:00Z", incremental: true, fromBackup: "f2", fromPosition: "1-100", position: "1-200"},
+			},
+			expected: []string{"f1"},
+		},
+		{
+			name:   "incomplete backups",
+			policy: &topodatapb.BackupRetentionPolicy{Daily: 1},
+			backups: []retentionTestBackup{
+				{name: "f1", backupTime: "2023-07-30T10:00:00Z", noManifest: true},
+				{name: "f2", backupTime: "2023-07-31T10:00:00Z", position: "1-100"},
+				{name: "f3", backupTime: "2023-08-01T10:00:00Z", position: "1-200"},
+				{name: "f4", backupTime: "2023-08-02T10:00:00Z", noManifest: true},
+			},
+			expected: []string{"f2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bhs := retentionTestBackupHandles(t, tt.backups)
+			prune := BackupsToPrune(context.Background(), logutil.NewMemoryLogger(), tt.policy, bhs)
+			var names []string
+			for _, bh := range prune {
+				names = append(names, bh.Name())
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
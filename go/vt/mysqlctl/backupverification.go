@@ -0,0 +1,297 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"vitess.io/vitess/go/mysql/replication"
+	"vitess.io/vitess/go/sqlescape"
+	"vitess.io/vitess/go/vt/dbconfigs"
+	"vitess.io/vitess/go/vt/logutil"
+	"vitess.io/vitess/go/vt/mysqlctl/backupstats"
+	"vitess.io/vitess/go/vt/mysqlctl/backupstorage"
+	"vitess.io/vitess/go/vt/proto/vtrpc"
+	"vitess.io/vitess/go/vt/vterrors"
+)
+
+// backupVerificationFileName is the VERIFICATION file name within a backup.
+const backupVerificationFileName = "VERIFICATION"
+
+// BackupVerification is the verdict of a backup verification. It is stored
+// in the VERIFICATION file of the backup.
+type BackupVerification struct {
+	// Passed is true if the backup was restored, and the restored data
+	// matches the MANIFEST of the backup.
+	Passed bool
+
+	// VerificationTime is when the backup was verified, in UTC time
+	// (RFC 3339 format).
+	VerificationTime string
+
+	// Position is the replication position of the restored backup.
+	Position replication.Position
+
+	// TableChecksums are the CHECKSUM TABLE values of the restored tables,
+	// by "<database>.<table>".
+	TableChecksums map[string]uint64
+
+	// Failures lists why the verification didn't pass.
+	Failures []string
+}
+
+func (v *BackupVerification) failf(format string, args ...any) {
+	v.Failures = append(v.Failures, fmt.Sprintf(format, args...))
+}
+
+// VerifyBackupParams is the struct that holds all params passed to VerifyBackup.
+type VerifyBackupParams struct {
+	// Cnf and Mysqld are the scratch mysqld the backup is restored into.
+	// Its existing data is deleted.
+	Cnf    *Mycnf
+	Mysqld MysqlDaemon
+	Logger logutil.Logger
+	// Concurrency is the number of files restored in parallel.
+	Concurrency int
+	// Extra env variables for pre-restore and post-restore transform hooks
+	HookExtraEnv map[string]string
+	// Keyspace and Shard are used to infer the directory where backups are stored
+	Keyspace string
+	Shard    string
+	// BackupName is the name of the backup to verify.
+	BackupName string
+	// Stats let's restore engines report detailed restore timings.
+	Stats backupstats.Stats
+}
+
+// VerifyBackup restores a backup into a scratch mysqld, checks the restored
+// data against the MANIFEST of the backup, and records the verdict in the
+// VERIFICATION file of the backup:
+// - the restored mysqld must be at the position of the backup.
+// - all the restored tables must be readable by CHECKSUM TABLE, and their
+// checksums must match the ones recorded in the MANIFEST, if any.
+//
+// A backup that can't be restored fails the verification. The scratch mysqld
+// is left running, for the caller to shut it down and remove it.
+func VerifyBackup(ctx context.Context, params VerifyBackupParams) (*BackupVerification, error) {
+	bs, err := backupstorage.GetBackupStorage()
+	if err != nil {
+		return nil, err
+	}
+	defer bs.Close()
+
+	backupDir := GetBackupDir(params.Keyspace, params.Shard)
+	bhs, err := bs.ListBackups(ctx, backupDir)
+	if err != nil {
+		return nil, vterrors.Wrap(err, "ListBackups failed")
+	}
+	bh, err := findBackup(bhs, backupDir, params.BackupName)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &BackupVerification{}
+	if err := verifyBackup(ctx, params, bh, verification); err != nil {
+		return nil, err
+	}
+	verification.Passed = len(verification.Failures) == 0
+	verification.VerificationTime = FormatRFC3339(time.Now().UTC())
+	if verification.Passed {
+		params.Logger.Infof("VerifyBackup: backup %v passed the verification", params.BackupName)
+	} else {
+		params.Logger.Errorf("VerifyBackup: backup %v failed the verification: %v", params.BackupName, verification.Failures)
+	}
+
+	if err := writeBackupVerification(ctx, bs, backupDir, params.BackupName, verification); err != nil {
+		return verification, vterrors.Wrapf(err, "can't write the %v file", backupVerificationFileName)
+	}
+	return verification, nil
+}
+
+// verifyBackup restores the backup and checks it, recording the failures in
+// the verification. It only returns an error if the verification can't go on.
+func verifyBackup(ctx context.Context, params VerifyBackupParams, bh backupstorage.BackupHandle, verification *BackupVerification) error {
+	manifest, err := GetBackupManifest(ctx, bh)
+	if err != nil {
+		verification.failf("can't read the MANIFEST: %v", err)
+		return nil
+	}
+
+	restoreParams := RestoreParams{
+		Cnf:                 params.Cnf,
+		Mysqld:              params.Mysqld,
+		Logger:              params.Logger,
+		Concurrency:         params.Concurrency,
+		HookExtraEnv:        params.HookExtraEnv,
+		DeleteBeforeRestore: true,
+		Keyspace:            params.Keyspace,
+		Shard:               params.Shard,
+		Stats:               params.Stats,
+	}
+	if manifest.Incremental {
+		// An incremental backup is restored along with the full backup
+		// and the incremental backups it is based on.
+		restoreParams.RestoreToPos = manifest.Position
+	} else {
+		restoreParams.BackupName = bh.Name()
+	}
+	params.Logger.Infof("VerifyBackup: restoring backup %v", bh.Name())
+	if _, err := Restore(ctx, restoreParams); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		verification.failf("can't restore the backup: %v", err)
+		return nil
+	}
+
+	params.Logger.Infof("VerifyBackup: checking the restored position")
+	verification.Position, err = params.Mysqld.PrimaryPosition()
+	if err != nil {
+		return vterrors.Wrap(err, "can't get the restored position")
+	}
+	if !verification.Position.Equal(manifest.Position) {
+		verification.failf("the restored position %v doesn't match the position %v of the MANIFEST", verification.Position, manifest.Position)
+	}
+
+	params.Logger.Infof("VerifyBackup: checksumming the restored tables")
+	verification.TableChecksums, err = getTableChecksums(ctx, params.Mysqld)
+	if err != nil {
+		verification.failf("can't checksum the restored tables: %v", err)
+		return nil
+	}
+	if manifest.TableChecksums == nil {
+		params.Logger.Infof("VerifyBackup: the MANIFEST has no table checksums to compare the restored tables against")
+		return nil
+	}
+	for _, table := range sortedTableNames(manifest.TableChecksums) {
+		checksum, ok := verification.TableChecksums[table]
+		switch {
+		case !ok:
+			verification.failf("table %v is missing", table)
+		case checksum != manifest.TableChecksums[table]:
+			verification.failf("the checksum of table %v is %v, the MANIFEST has %v", table, checksum, manifest.TableChecksums[table])
+		}
+	}
+	for _, table := range sortedTableNames(verification.TableChecksums) {
+		if _, ok := manifest.TableChecksums[table]; !ok {
+			verification.failf("table %v isn't in the MANIFEST", table)
+		}
+	}
+	return nil
+}
+
+// getTableChecksums returns the CHECKSUM TABLE values of all the tables of
+// mysqld, by "<database>.<table>".
+func getTableChecksums(ctx context.Context, mysqld MysqlDaemon) (map[string]uint64, error) {
+	qr, err := mysqld.FetchSuperQuery(ctx, "SELECT table_schema, table_name FROM information_schema.tables WHERE table_type = 'BASE TABLE' AND table_schema NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')")
+	if err != nil {
+		return nil, err
+	}
+	checksums := make(map[string]uint64, len(qr.Rows))
+	for _, row := range qr.Rows {
+		table := sqlescape.EscapeID(row[0].ToString()) + "." + sqlescape.EscapeID(row[1].ToString())
+		cqr, err := mysqld.FetchSuperQuery(ctx, "CHECKSUM TABLE "+table)
+		if err != nil {
+			return nil, vterrors.Wrapf(err, "can't checksum table %v", table)
+		}
+		// CHECKSUM TABLE returns a NULL checksum for the tables it can't read.
+		if len(cqr.Rows) != 1 || len(cqr.Rows[0]) != 2 || cqr.Rows[0][1].IsNull() {
+			return nil, fmt.Errorf("can't checksum table %v", table)
+		}
+		checksum, err := cqr.Rows[0][1].ToUint64()
+		if err != nil {
+			return nil, vterrors.Wrapf(err, "invalid checksum of table %v", table)
+		}
+		checksums[row[0].ToString()+"."+row[1].ToString()] = checksum
+	}
+	return checksums, nil
+}
+
+func sortedTableNames(checksums map[string]uint64) []string {
+	tables := make([]string, 0, len(checksums))
+	for table := range checksums {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// writeBackupVerification adds the VERIFICATION file to an existing backup.
+func writeBackupVerification(ctx context.Context, bs backupstorage.BackupStorage, dir, name string, verification *BackupVerification) error {
+	data, err := json.MarshalIndent(verification, "", "  ")
+	if err != nil {
+		return err
+	}
+	bh, err := bs.StartBackup(ctx, dir, name)
+	if err != nil {
+		return err
+	}
+	// The backup isn't aborted on failure, as that would remove it.
+	wc, err := bh.AddFile(ctx, backupVerificationFileName, int64(len(data)))
+	if err != nil {
+		return err
+	}
+	if _, err := wc.Write(data); err != nil {
+		wc.Close()
+		return err
+	}
+	if err := wc.Close(); err != nil {
+		return err
+	}
+	return bh.EndBackup(ctx)
+}
+
+// CreateScratchMysqldAndMycnf returns a Mysqld and a Mycnf for a scratch
+// mysql instance, in a new directory of the tablet directory of cnf and
+// listening on a free port, to restore backups without touching the data of
+// the tablet. The scratch instance connects with the users of dbcfgs. The
+// caller removes the directory, Mycnf.TabletDir(), once done.
+func CreateScratchMysqldAndMycnf(cnf *Mycnf, dbcfgs *dbconfigs.DBConfigs) (*Mysqld, *Mycnf, error) {
+	if socketFile != "" {
+		return nil, nil, vterrors.Errorf(vtrpc.Code_FAILED_PRECONDITION, "a scratch mysqld can't be started through mysqlctld")
+	}
+	dir, err := os.MkdirTemp(cnf.TabletDir(), "scratch_")
+	if err != nil {
+		return nil, nil, err
+	}
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	scratchCnf := newMycnfInDir(dir, cnf.ServerID, port)
+	if err := scratchCnf.RandomizeMysqlServerID(); err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, fmt.Errorf("couldn't generate random MySQL server_id: %v", err)
+	}
+	mysqld := NewMysqld(dbcfgs.CloneWithSocket(scratchCnf.SocketFile))
+	if err := mysqld.InitConfig(scratchCnf); err != nil {
+		mysqld.Close()
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	return mysqld, scratchCnf, nil
+}
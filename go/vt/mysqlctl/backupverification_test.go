@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/mysqlctl/backupstorage"
+	"vitess.io/vitess/go/vt/vterrors"
+
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+)
+
+type closableBuffer struct {
+	bytes.Buffer
+}
+
+func (b *closableBuffer) Close() error {
+	return nil
+}
+
+const testBackupName = "2023-08-01.120000.zone1-0000000100"
+
+func tableChecksumsResults(checksums map[string]uint64) map[string]*sqltypes.Result {
+	tables := sqltypes.MakeTestResult(sqltypes.MakeTestFields("table_schema|table_name", "varchar|varchar"))
+	results := map[string]*sqltypes.Result{
+		"SELECT table_schema, table_name FROM information_schema.tables WHERE table_type = 'BASE TABLE' AND table_schema NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')": tables,
+	}
+	for _, table := range sortedTableNames(checksums) {
+		db, name, _ := strings.Cut(table, ".")
+		tables.Rows = append(tables.Rows, []sqltypes.Value{sqltypes.NewVarChar(db), sqltypes.NewVarChar(name)})
+		results["CHECKSUM TABLE `"+db+"`.`"+name+"`"] = sqltypes.MakeTestResult(
+			sqltypes.MakeTestFields("Table|Checksum", "varchar|uint64"),
+			table+"|"+sqltypes.NewUint64(checksums[table]).ToString(),
+		)
+	}
+	return results
+}
+
+func TestVerifyBackup(t *testing.T) {
+	tests := []struct {
+		name             string
+		backupName       string
+		manifest         map[string]uint64
+		restored         map[string]uint64
+		restoreErr       error
+		expectedErr      string
+		expectedFailures []string
+	}{
+		{
+			name:       "passed",
+			backupName: testBackupName,
+			manifest:   map[string]uint64{"vt_test.t1": 1, "vt_test.t2": 2},
+			restored:   map[string]uint64{"vt_test.t1": 1, "vt_test.t2": 2},
+		},
+		{
+			name:       "passed without checksums in the MANIFEST",
+			backupName: testBackupName,
+			restored:   map[string]uint64{"vt_test.t1": 1},
+		},
+		{
+			name:       "tables don't match",
+			backupName: testBackupName,
+			manifest:   map[string]uint64{"vt_test.t1": 1, "vt_test.t2": 2, "vt_test.t3": 3},
+			restored:   map[string]uint64{"vt_test.t1": 1, "vt_test.t2": 20, "vt_test.t4": 4},
+			expectedFailures: []string{
+				"the checksum of table vt_test.t2 is 20, the MANIFEST has 2",
+				"table vt_test.t3 is missing",
+				"table vt_test.t4 isn't in the MANIFEST",
+			},
+		},
+		{
+			name:             "restore fails",
+			backupName:       testBackupName,
+			restoreErr:       errors.New("corrupted file"),
+			expectedFailures: []string{"can't restore the backup: corrupted file"},
+		},
+		{
+			name:        "no such backup",
+			backupName:  "2023-08-02.120000.zone1-0000000100",
+			expectedErr: "backup 2023-08-02.120000.zone1-0000000100 not found in directory test/-",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := createFakeBackupRestoreEnv(t)
+
+			manifest := BackupManifest{
+				BackupTime:     FormatRFC3339(time.Now().Add(-1 * time.Hour)),
+				BackupMethod:   "fake",
+				Keyspace:       "test",
+				Shard:          "-",
+				MySQLVersion:   "8.0.32",
+				TableChecksums: tt.manifest,
+			}
+			manifestBytes, err := json.Marshal(manifest)
+			require.NoError(t, err)
+			env.backupEngine.ExecuteRestoreReturn = FakeBackupEngineExecuteRestoreReturn{&manifest, tt.restoreErr}
+			env.backupStorage.ListBackupsReturn = FakeBackupStorageListBackupsReturn{
+				BackupHandles: []backupstorage.BackupHandle{
+					&FakeBackupHandle{
+						NameV: testBackupName,
+						ReadFileReturnF: func(context.Context, string) (io.ReadCloser, error) {
+							return io.NopCloser(bytes.NewBuffer(manifestBytes)), nil
+						},
+					},
+				},
+			}
+			var verificationFile closableBuffer
+			bh := &FakeBackupHandle{AddFileReturn: FakeBackupHandleAddFileReturn{WriteCloser: &verificationFile}}
+			env.backupStorage.StartBackupReturn = FakeBackupStorageStartBackupReturn{bh, nil}
+			env.mysqld.FetchSuperQueryMap = tableChecksumsResults(tt.restored)
+
+			verification, err := VerifyBackup(env.ctx, VerifyBackupParams{
+				Cnf:          env.restoreParams.Cnf,
+				Mysqld:       env.mysqld,
+				Logger:       env.logger,
+				Concurrency:  1,
+				HookExtraEnv: map[string]string{},
+				Keyspace:     "test",
+				Shard:        "-",
+				BackupName:   tt.backupName,
+				Stats:        env.stats,
+			})
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				assert.Equal(t, vtrpcpb.Code_NOT_FOUND, vterrors.Code(err))
+				assert.Empty(t, bh.AddFileCalls)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.expectedFailures) == 0, verification.Passed)
+			assert.Equal(t, tt.expectedFailures, verification.Failures)
+
+			// The verdict is added to the backup.
+			require.Len(t, env.backupStorage.StartBackupCalls, 1)
+			assert.Equal(t, "test/-", env.backupStorage.StartBackupCalls[0].Dir)
+			assert.Equal(t, testBackupName, env.backupStorage.StartBackupCalls[0].Name)
+			require.Len(t, bh.AddFileCalls, 1)
+			assert.Equal(t, backupVerificationFileName, bh.AddFileCalls[0].Filename)
+			assert.Len(t, bh.EndBackupCalls, 1)
+			assert.Empty(t, bh.AbortBackupCalls)
+			var written BackupVerification
+			require.NoError(t, json.Unmarshal(verificationFile.Bytes(), &written))
+			assert.Equal(t, *verification, written)
+		})
+	}
+}
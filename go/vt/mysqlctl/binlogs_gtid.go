@@ -17,12 +17,18 @@ limitations under the License.
 package mysqlctl
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"os"
 	"sort"
 	"strings"
 	"time"
 
+	"vitess.io/vitess/go/mysql"
 	"vitess.io/vitess/go/mysql/replication"
 	"vitess.io/vitess/go/vt/proto/vtrpc"
 	"vitess.io/vitess/go/vt/vterrors"
@@ -398,3 +404,85 @@ func FindPITRToTimePath(restoreToTime time.Time, manifests [](*BackupManifest))
 	}
 	return shortestPath, nil
 }
+
+// binlogFileMagic is the header of a binary log file.
+var binlogFileMagic = []byte{0xfe, 'b', 'i', 'n'}
+
+// BinlogFileTransactions describes the transactions of a binary log file that precede a point in time.
+type BinlogFileTransactions struct {
+	// GTIDs are the GTIDs of the transactions.
+	GTIDs replication.GTIDSet
+	// LastGTID is the GTID of the last transaction, if any.
+	LastGTID replication.GTID
+	// LastTimestamp is the timestamp of the last transaction, if any.
+	LastTimestamp time.Time
+	// ReachedTimestamp is true if the binary log file has a transaction at or after the point in time.
+	// Neither the rest of the file nor the binary logs that follow it should be applied then.
+	ReachedTimestamp bool
+}
+
+// ReadBinlogFileTransactionsBefore reads a MySQL binary log file, and returns the transactions that precede the first
+// transaction timestamped at or after the given time. Transactions are timestamped by the header of their GTID event,
+// like `mysqlbinlog --stop-datetime` does, but the timestamps are compared in UTC rather than in the local time zone.
+func ReadBinlogFileTransactionsBefore(binlogFile string, timestamp time.Time) (*BinlogFileTransactions, error) {
+	f, err := os.Open(binlogFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	magic := make([]byte, len(binlogFileMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, vterrors.Wrapf(err, "reading binary log file %v", binlogFile)
+	}
+	if !bytes.Equal(magic, binlogFileMagic) {
+		return nil, vterrors.Errorf(vtrpc.Code_FAILED_PRECONDITION, "%v is not a binary log file, or it is encrypted", binlogFile)
+	}
+
+	txs := &BinlogFileTransactions{GTIDs: replication.Mysql56GTIDSet{}}
+	var format mysql.BinlogFormat
+	header := make([]byte, 19)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return txs, nil
+			}
+			return nil, vterrors.Wrapf(err, "reading event header in binary log file %v", binlogFile)
+		}
+		size := binary.LittleEndian.Uint32(header[9:13])
+		if size < uint32(len(header)) {
+			return nil, vterrors.Errorf(vtrpc.Code_FAILED_PRECONDITION, "invalid event size %v in binary log file %v", size, binlogFile)
+		}
+		buf := make([]byte, size)
+		copy(buf, header)
+		if _, err := io.ReadFull(r, buf[len(header):]); err != nil {
+			return nil, vterrors.Wrapf(err, "reading event in binary log file %v", binlogFile)
+		}
+		ev := mysql.NewMysql56BinlogEvent(buf)
+		if ev.IsFormatDescription() {
+			if format, err = ev.Format(); err != nil {
+				return nil, vterrors.Wrapf(err, "parsing format description event in binary log file %v", binlogFile)
+			}
+			continue
+		}
+		if format.IsZero() || !ev.IsGTID() {
+			continue
+		}
+		if ev, _, err = ev.StripChecksum(format); err != nil {
+			return nil, vterrors.Wrapf(err, "stripping checksum in binary log file %v", binlogFile)
+		}
+		gtid, _, err := ev.GTID(format)
+		if err != nil {
+			return nil, vterrors.Wrapf(err, "parsing GTID event in binary log file %v", binlogFile)
+		}
+		eventTime := time.Unix(int64(ev.Timestamp()), 0).UTC()
+		if !eventTime.Before(timestamp) {
+			txs.ReachedTimestamp = true
+			return txs, nil
+		}
+		txs.GTIDs = txs.GTIDs.AddGTID(gtid)
+		txs.LastGTID = gtid
+		txs.LastTimestamp = eventTime
+	}
+}
@@ -23,12 +23,15 @@ package mysqlctl
 import (
 	"context"
 	"fmt"
+	"os"
+	"path"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"vitess.io/vitess/go/mysql"
 	"vitess.io/vitess/go/mysql/replication"
 )
 
@@ -917,3 +920,91 @@ func TestFindPITRToTimePath(t *testing.T) {
 		}
 	})
 }
+
+func TestReadBinlogFileTransactionsBefore(t *testing.T) {
+	sid, err := replication.ParseSID("16b1039f-22b6-11ed-b765-0a43f95f28a3")
+	require.NoError(t, err)
+	gtid := func(sequence int64) replication.GTID {
+		return replication.Mysql56GTID{Server: sid, Sequence: sequence}
+	}
+	base := time.Date(2023, time.August, 1, 10, 0, 0, 0, time.UTC)
+
+	// A binary log with a transaction every minute, in which the third one is timestamped before the second one.
+	f := mysql.NewMySQL56BinlogFormat()
+	s := mysql.NewFakeBinlogStream()
+	binlog := append([]byte{}, binlogFileMagic...)
+	binlog = append(binlog, mysql.NewFormatDescriptionEvent(f, s).Bytes()...)
+	for i, minutes := range []int{0, 2, 1, 3} {
+		s.Timestamp = uint32(base.Add(time.Duration(minutes) * time.Minute).Unix())
+		binlog = append(binlog, mysql.NewMySQL56GTIDEvent(f, s, gtid(int64(i+1)).(replication.Mysql56GTID)).Bytes()...)
+		binlog = append(binlog, mysql.NewQueryEvent(f, s, mysql.Query{SQL: "BEGIN"}).Bytes()...)
+		binlog = append(binlog, mysql.NewXIDEvent(f, s).Bytes()...)
+	}
+	binlogFile := path.Join(t.TempDir(), "vt-bin.000001")
+	require.NoError(t, os.WriteFile(binlogFile, binlog, 0600))
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		expected  *BinlogFileTransactions
+	}{
+		{
+			name:      "before the first transaction",
+			timestamp: base,
+			expected: &BinlogFileTransactions{
+				GTIDs:            replication.Mysql56GTIDSet{},
+				ReachedTimestamp: true,
+			},
+		},
+		{
+			name:      "stops at the first transaction after the timestamp",
+			timestamp: base.Add(90 * time.Second),
+			expected: &BinlogFileTransactions{
+				GTIDs:            replication.Mysql56GTIDSet{}.AddGTID(gtid(1)),
+				LastGTID:         gtid(1),
+				LastTimestamp:    base,
+				ReachedTimestamp: true,
+			},
+		},
+		{
+			name:      "excludes the transactions at the timestamp",
+			timestamp: base.Add(3 * time.Minute),
+			expected: &BinlogFileTransactions{
+				GTIDs:            replication.Mysql56GTIDSet{}.AddGTID(gtid(1)).AddGTID(gtid(2)).AddGTID(gtid(3)),
+				LastGTID:         gtid(3),
+				LastTimestamp:    base.Add(time.Minute),
+				ReachedTimestamp: true,
+			},
+		},
+		{
+			name:      "after the last transaction",
+			timestamp: base.Add(time.Hour),
+			expected: &BinlogFileTransactions{
+				GTIDs:         replication.Mysql56GTIDSet{}.AddGTID(gtid(1)).AddGTID(gtid(2)).AddGTID(gtid(3)).AddGTID(gtid(4)),
+				LastGTID:      gtid(4),
+				LastTimestamp: base.Add(3 * time.Minute),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txs, err := ReadBinlogFileTransactionsBefore(binlogFile, tt.timestamp)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, txs)
+		})
+	}
+
+	t.Run("not a binary log", func(t *testing.T) {
+		notBinlogFile := path.Join(t.TempDir(), "MANIFEST")
+		require.NoError(t, os.WriteFile(notBinlogFile, []byte(`{"BackupMethod": "builtin"}`), 0600))
+		_, err := ReadBinlogFileTransactionsBefore(notBinlogFile, base)
+		assert.ErrorContains(t, err, "is not a binary log file")
+	})
+
+	t.Run("truncated binary log", func(t *testing.T) {
+		truncatedBinlogFile := path.Join(t.TempDir(), "vt-bin.000002")
+		require.NoError(t, os.WriteFile(truncatedBinlogFile, binlog[:len(binlog)-5], 0600))
+		_, err := ReadBinlogFileTransactionsBefore(truncatedBinlogFile, base.Add(time.Hour))
+		assert.ErrorContains(t, err, "reading event in binary log file")
+	})
+}
@@ -80,6 +80,10 @@ var (
 	// engines during backups.  The backupstorage may be a physical file,
 	// network, or something else.
 	builtinBackupStorageWriteBufferSize = 2 * 1024 * 1024 /* 2 MiB */
+
+	// builtinBackupTableChecksums records the CHECKSUM TABLE of all the tables
+	// in the MANIFEST of full backups.
+	builtinBackupTableChecksums bool
 )
 
 // BuiltinBackupEngine encapsulates the logic of the builtin engine
@@ -123,6 +127,15 @@ type builtinBackupManifest struct {
 	// ExternalDecompressor will be used. If neither are set, the restore will
 	// abort.
 	ExternalDecompressor string
+
+	// Encryption is set if the backup files were encrypted. It records the
+	// wrapped data key they were encrypted with, and the key it was
+	// wrapped with.
+	Encryption *BackupEncryption `json:",omitempty"`
+
+	// dataKey is the data key of an encrypted backup, once unwrapped
+	// on restore.
+	dataKey []byte
 }
 
 // FileEntry is one file to backup
@@ -157,6 +170,7 @@ func registerBuiltinBackupEngineFlags(fs *pflag.FlagSet) {
 	fs.DurationVar(&builtinBackupProgress, "builtinbackup_progress", builtinBackupProgress, "how often to send progress updates when backing up large files.")
 	fs.UintVar(&builtinBackupFileReadBufferSize, "builtinbackup-file-read-buffer-size", builtinBackupFileReadBufferSize, "read files using an IO buffer of this many bytes. Golang defaults are used when set to 0.")
 	fs.UintVar(&builtinBackupFileWriteBufferSize, "builtinbackup-file-write-buffer-size", builtinBackupFileWriteBufferSize, "write files using an IO buffer of this many bytes. Golang defaults are used when set to 0.")
+	fs.BoolVar(&builtinBackupTableChecksums, "builtinbackup-table-checksums", builtinBackupTableChecksums, "record the CHECKSUM TABLE of all the tables in the MANIFEST of full backups, for VerifyBackup to compare the restored tables against. Checksumming reads all the tables while replication is stopped.")
 }
 
 // fullPath returns the full path of the entry, based on its type
@@ -363,7 +377,7 @@ func (be *BuiltinBackupEngine) executeIncrementalBackup(ctx context.Context, par
 	// incrementalBackupFromGTID is the "previous GTIDs" of the first binlog file we back up.
 	// It is a fact that incrementalBackupFromGTID is earlier or equal to params.IncrementalFromPos.
 	// In the backup manifest file, we document incrementalBackupFromGTID, not the user's requested position.
-	if err := be.backupFiles(ctx, params, bh, incrementalBackupToPosition, gtidPurged, incrementalBackupFromPosition, fromBackupName, binaryLogsToBackup, serverUUID, mysqlVersion, incrDetails); err != nil {
+	if err := be.backupFiles(ctx, params, bh, incrementalBackupToPosition, gtidPurged, incrementalBackupFromPosition, fromBackupName, binaryLogsToBackup, serverUUID, mysqlVersion, incrDetails, nil); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -458,6 +472,16 @@ func (be *BuiltinBackupEngine) executeFullBackup(ctx context.Context, params Bac
 		return false, vterrors.Wrap(err, "can't get MySQL version")
 	}
 
+	// Replication is stopped, or the primary is read-only: the checksums are
+	// the ones of the backup position.
+	var tableChecksums map[string]uint64
+	if builtinBackupTableChecksums {
+		params.Logger.Infof("computing table checksums")
+		if tableChecksums, err = getTableChecksums(ctx, params.Mysqld); err != nil {
+			return false, vterrors.Wrap(err, "can't compute table checksums")
+		}
+	}
+
 	// check if we need to set innodb_fast_shutdown=0 for a backup safe for upgrades
 	if params.UpgradeSafe {
 		if _, err := params.Mysqld.FetchSuperQuery(ctx, "SET GLOBAL innodb_fast_shutdown=0"); err != nil {
@@ -474,7 +498,7 @@ func (be *BuiltinBackupEngine) executeFullBackup(ctx context.Context, params Bac
 	}
 
 	// Backup everything, capture the error.
-	backupErr := be.backupFiles(ctx, params, bh, replicationPosition, gtidPurgedPosition, replication.Position{}, "", nil, serverUUID, mysqlVersion, nil)
+	backupErr := be.backupFiles(ctx, params, bh, replicationPosition, gtidPurgedPosition, replication.Position{}, "", nil, serverUUID, mysqlVersion, nil, tableChecksums)
 	usable := backupErr == nil
 
 	// Try to restart mysqld, use background context in case we timed out the original context
@@ -562,6 +586,7 @@ func (be *BuiltinBackupEngine) backupFiles(
 	serverUUID string,
 	mysqlVersion string,
 	incrDetails *IncrementalBackupDetails,
+	tableChecksums map[string]uint64,
 ) (finalErr error) {
 	// Get the files to backup.
 	// We don't care about totalSize because we add each file separately.
@@ -577,6 +602,12 @@ func (be *BuiltinBackupEngine) backupFiles(
 	}
 	params.Logger.Infof("found %v files to backup", len(fes))
 
+	// Generate the data key the files are encrypted with, if necessary.
+	encryption, dataKey, err := newBackupEncryption(ctx)
+	if err != nil {
+		return vterrors.Wrap(err, "can't set up backup encryption")
+	}
+
 	// Backup with the provided concurrency.
 	sema := semaphore.NewWeighted(int64(params.Concurrency))
 	wg := sync.WaitGroup{}
@@ -612,7 +643,7 @@ func (be *BuiltinBackupEngine) backupFiles(
 
 			// Backup the individual file.
 			name := fmt.Sprintf("%v", i)
-			bh.RecordError(be.backupFile(ctx, params, bh, fe, name))
+			bh.RecordError(be.backupFile(ctx, params, bh, fe, name, dataKey))
 		}(i)
 	}
 
@@ -661,6 +692,7 @@ func (be *BuiltinBackupEngine) backupFiles(
 			MySQLVersion:       mysqlVersion,
 			UpgradeSafe:        params.UpgradeSafe,
 			IncrementalDetails: incrDetails,
+			TableChecksums:     tableChecksums,
 		},
 
 		// Builtin-specific fields
@@ -668,6 +700,7 @@ func (be *BuiltinBackupEngine) backupFiles(
 		SkipCompress:         !backupStorageCompress,
 		CompressionEngine:    CompressionEngineName,
 		ExternalDecompressor: ManifestExternalDecompressorCmd,
+		Encryption:           encryption,
 	}
 	data, err := json.MarshalIndent(bm, "", "  ")
 	if err != nil {
@@ -763,8 +796,9 @@ func (bp *backupPipe) ReportProgress(period time.Duration, logger logutil.Logger
 	}
 }
 
-// backupFile backs up an individual file.
-func (be *BuiltinBackupEngine) backupFile(ctx context.Context, params BackupParams, bh backupstorage.BackupHandle, fe *FileEntry, name string) (finalErr error) {
+// backupFile backs up an individual file. It is encrypted with the data key,
+// if not nil.
+func (be *BuiltinBackupEngine) backupFile(ctx context.Context, params BackupParams, bh backupstorage.BackupHandle, fe *FileEntry, name string, dataKey []byte) (finalErr error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	// Open the source file for reading.
@@ -823,6 +857,24 @@ func (be *BuiltinBackupEngine) backupFile(ctx context.Context, params BackupPara
 		var reader io.Reader = br
 		var writer io.Writer = bw
 
+		// Create the encryption pipe, if necessary. It is closed after the
+		// compressor, to encrypt everything the compressor flushes.
+		if dataKey != nil {
+			encryptor, err := newEncryptingWriter(writer, dataKey)
+			if err != nil {
+				return vterrors.Wrap(err, "can't create encryptor")
+			}
+			writer = encryptor
+
+			defer func() {
+				if cerr := encryptor.Close(); cerr != nil {
+					cerr = vterrors.Wrapf(cerr, "failed to close encryptor %v", name)
+					params.Logger.Error(cerr)
+					createAndCopyErr = errors.Join(createAndCopyErr, cerr)
+				}
+			}()
+		}
+
 		// Create the gzip compression pipe, if necessary.
 		if backupStorageCompress {
 			var compressor io.WriteCloser
@@ -916,18 +968,40 @@ func (be *BuiltinBackupEngine) executeRestoreIncrementalBackup(ctx context.Conte
 		if err != nil {
 			return vterrors.Wrap(err, "failed to restore file")
 		}
+		defer os.Remove(binlogFile)
 		req := &mysqlctlpb.ApplyBinlogFileRequest{
-			BinlogFileName:        binlogFile,
-			BinlogRestoreDatetime: protoutil.TimeToProto(params.RestoreToTimestamp),
+			BinlogFileName: binlogFile,
 		}
 		if params.RestoreToPos.GTIDSet != nil {
 			req.BinlogRestorePosition = params.RestoreToPos.GTIDSet.String()
 		}
+		var reachedTimestamp bool
+		if !params.RestoreToTimestamp.IsZero() {
+			// Rather than relying on `mysqlbinlog --stop-datetime`, we find the exact transactions to apply,
+			// so that we know which GTID the restore reaches.
+			txs, err := ReadBinlogFileTransactionsBefore(binlogFile, params.RestoreToTimestamp)
+			if err != nil {
+				return vterrors.Wrapf(err, "failed to read binlog file %v", binlogFile)
+			}
+			if txs.LastGTID == nil {
+				if txs.ReachedTimestamp {
+					params.Logger.Infof("Skipping binlog file %v, which has no transaction before %v", binlogFile, FormatRFC3339(params.RestoreToTimestamp))
+					break
+				}
+				continue
+			}
+			params.Logger.Infof("Applying binlog file %v up to transaction %v at %v", binlogFile, txs.LastGTID, FormatRFC3339(txs.LastTimestamp))
+			req.BinlogRestorePosition = txs.GTIDs.String()
+			reachedTimestamp = txs.ReachedTimestamp
+		}
 		if err := mysqld.ApplyBinlogFile(ctx, req); err != nil {
 			return vterrors.Wrapf(err, "failed to apply binlog file %v", binlogFile)
 		}
-		defer os.Remove(binlogFile)
 		params.Logger.Infof("Applied binlog file: %v", binlogFile)
+		if reachedTimestamp {
+			// The rest of the binlog files only have transactions that follow the restore point.
+			break
+		}
 	}
 	if err != nil {
 		// don't delete the file here because that is how we detect an interrupted restore
@@ -948,6 +1022,16 @@ func (be *BuiltinBackupEngine) ExecuteRestore(ctx context.Context, params Restor
 		return nil, err
 	}
 
+	// Unwrap the data key of an encrypted backup before starting to restore,
+	// which checks that the key it was wrapped with is available.
+	if bm.Encryption != nil {
+		dataKey, err := bm.Encryption.dataKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		bm.dataKey = dataKey
+	}
+
 	// mark restore as in progress
 	if err := createStateFile(params.Cnf); err != nil {
 		return nil, err
@@ -1079,6 +1163,14 @@ func (be *BuiltinBackupEngine) restoreFile(ctx context.Context, params RestorePa
 
 	bufferedDest := bufio.NewWriterSize(timedDest, int(builtinBackupFileWriteBufferSize))
 
+	// Create the decrypter if needed, before the uncompresser.
+	if bm.Encryption != nil {
+		reader, err = newDecryptingReader(reader, bm.dataKey)
+		if err != nil {
+			return vterrors.Wrap(err, "can't create decrypter")
+		}
+	}
+
 	// Create the uncompresser if needed.
 	if !bm.SkipCompress {
 		var decompressor io.ReadCloser
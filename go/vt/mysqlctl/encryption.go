@@ -0,0 +1,461 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+
+	"vitess.io/vitess/go/vt/servenv"
+	"vitess.io/vitess/go/vt/vterrors"
+
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+)
+
+// The files of an encrypted builtin backup are encrypted with a data key
+// that is generated for each backup. The data key is wrapped by a key
+// provider, like a local keyfile or a KMS, and stored in the MANIFEST.
+//
+// Each file is encrypted with AES-256-GCM in segments of 64KiB. The file
+// starts with a random nonce prefix, and the nonce of each segment is made
+// of this prefix, the number of the segment and a flag set for the last
+// segment, so that segments can't be reordered, dropped or truncated
+// without the decryption failing.
+
+const (
+	// KeyfileKeyProvider is the key provider that wraps the data keys
+	// with the keys of a local file.
+	KeyfileKeyProvider = "keyfile"
+
+	backupEncryptionAlgorithm = "aes-256-gcm-64k"
+
+	encryptionKeySize         = 32
+	encryptionSegmentSize     = 64 * 1024
+	encryptionNoncePrefixSize = 7
+)
+
+var (
+	// BackupEncryptionKeyProvider is the name of the key provider that wraps
+	// the data keys of the builtin backups. Backups aren't encrypted if empty.
+	BackupEncryptionKeyProvider string
+	// BackupEncryptionKeyfile is the file the keyfile key provider reads
+	// its keys from.
+	BackupEncryptionKeyfile string
+
+	errEncryptedFileTruncated = errors.New("encrypted backup file is truncated")
+	errEncryptedFileCorrupted = errors.New("cannot decrypt backup file, it is corrupted, truncated or was encrypted with another key")
+
+	backupKeyProvidersMu sync.Mutex
+	backupKeyProviders   = map[string]BackupKeyProviderFactory{
+		KeyfileKeyProvider: newKeyfileKeyProvider,
+	}
+)
+
+func init() {
+	for _, cmd := range []string{"vtbackup", "vtcombo", "vttablet", "vttestserver"} {
+		servenv.OnParseFor(cmd, registerBackupEncryptionFlags)
+	}
+}
+
+func registerBackupEncryptionFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&BackupEncryptionKeyProvider, "backup-encryption-key-provider", BackupEncryptionKeyProvider, "if set, the builtin backups are encrypted with a data key wrapped by this key provider, e.g. 'keyfile'.")
+	fs.StringVar(&BackupEncryptionKeyfile, "backup-encryption-keyfile", BackupEncryptionKeyfile, "file of the keys of the 'keyfile' backup encryption key provider, with one '<key id> <base64 encoded 32 bytes key>' per line. The first key wraps the data keys of new backups.")
+}
+
+// BackupKeyProvider wraps and unwraps the data keys of encrypted backups,
+// e.g. with the keys of a KMS.
+type BackupKeyProvider interface {
+	// WrapKey encrypts a data key. It returns the ID of the key it was
+	// wrapped with, which is recorded in the MANIFEST of the backup.
+	WrapKey(ctx context.Context, dataKey []byte) (keyID string, wrappedKey []byte, err error)
+
+	// UnwrapKey decrypts a data key wrapped with the given key.
+	UnwrapKey(ctx context.Context, keyID string, wrappedKey []byte) ([]byte, error)
+}
+
+// BackupKeyProviderFactory creates a BackupKeyProvider.
+type BackupKeyProviderFactory func() (BackupKeyProvider, error)
+
+// RegisterBackupKeyProvider makes a BackupKeyProvider available under the
+// given name, for the --backup-encryption-key-provider flag.
+func RegisterBackupKeyProvider(name string, factory BackupKeyProviderFactory) {
+	backupKeyProvidersMu.Lock()
+	defer backupKeyProvidersMu.Unlock()
+	if _, ok := backupKeyProviders[name]; ok {
+		panic(fmt.Sprintf("backup key provider %s is already registered", name))
+	}
+	backupKeyProviders[name] = factory
+}
+
+func getBackupKeyProvider(name string) (BackupKeyProvider, error) {
+	backupKeyProvidersMu.Lock()
+	factory, ok := backupKeyProviders[name]
+	backupKeyProvidersMu.Unlock()
+	if !ok {
+		return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "unknown backup encryption key provider %q", name)
+	}
+	return factory()
+}
+
+// BackupEncryption describes how the files of a builtin backup are
+// encrypted. It is recorded in the MANIFEST.
+type BackupEncryption struct {
+	// Algorithm is the cipher the files are encrypted with.
+	Algorithm string
+
+	// KeyProvider is the name of the key provider that wrapped the data key.
+	KeyProvider string
+
+	// KeyID is the ID of the key that wrapped the data key.
+	KeyID string
+
+	// WrappedKey is the data key the files are encrypted with, wrapped by
+	// the key provider.
+	WrappedKey []byte
+}
+
+// newBackupEncryption generates the data key of a new backup, and wraps it
+// with the configured key provider. It returns nil if the backups aren't
+// encrypted.
+func newBackupEncryption(ctx context.Context) (*BackupEncryption, []byte, error) {
+	if BackupEncryptionKeyProvider == "" {
+		return nil, nil, nil
+	}
+	provider, err := getBackupKeyProvider(BackupEncryptionKeyProvider)
+	if err != nil {
+		return nil, nil, err
+	}
+	dataKey := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+	keyID, wrappedKey, err := provider.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, nil, vterrors.Wrapf(err, "cannot wrap the data key with key provider %q", BackupEncryptionKeyProvider)
+	}
+	return &BackupEncryption{
+		Algorithm:   backupEncryptionAlgorithm,
+		KeyProvider: BackupEncryptionKeyProvider,
+		KeyID:       keyID,
+		WrappedKey:  wrappedKey,
+	}, dataKey, nil
+}
+
+// dataKey unwraps the data key of the backup with the key provider and
+// the key recorded in the MANIFEST.
+func (be *BackupEncryption) dataKey(ctx context.Context) ([]byte, error) {
+	if be.Algorithm != backupEncryptionAlgorithm {
+		return nil, vterrors.Errorf(vtrpcpb.Code_FAILED_PRECONDITION, "unsupported backup encryption algorithm %q", be.Algorithm)
+	}
+	provider, err := getBackupKeyProvider(be.KeyProvider)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := provider.UnwrapKey(ctx, be.KeyID, be.WrappedKey)
+	if err != nil {
+		return nil, vterrors.Wrapf(err, "cannot unwrap the data key with key %q of key provider %q", be.KeyID, be.KeyProvider)
+	}
+	if len(dataKey) != encryptionKeySize {
+		return nil, vterrors.Errorf(vtrpcpb.Code_FAILED_PRECONDITION, "invalid data key size %d", len(dataKey))
+	}
+	return dataKey, nil
+}
+
+func newBackupAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// setSegmentNonce sets the number of the segment and the last segment flag
+// in the nonce, after the prefix.
+func setSegmentNonce(nonce []byte, segment uint32, last bool) {
+	binary.BigEndian.PutUint32(nonce[encryptionNoncePrefixSize:], segment)
+	nonce[len(nonce)-1] = 0
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+}
+
+type encryptingWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	nonce   []byte
+	segment uint32
+	buf     []byte
+	sealed  []byte
+	closed  bool
+}
+
+// newEncryptingWriter returns a writer that encrypts what is written to it
+// with the data key, and writes it to w. It must be closed to write the
+// last segment, which doesn't close w.
+func newEncryptingWriter(w io.Writer, dataKey []byte) (io.WriteCloser, error) {
+	aead, err := newBackupAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce[:encryptionNoncePrefixSize]); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(nonce[:encryptionNoncePrefixSize]); err != nil {
+		return nil, err
+	}
+	return &encryptingWriter{
+		w:      w,
+		aead:   aead,
+		nonce:  nonce,
+		buf:    make([]byte, 0, encryptionSegmentSize),
+		sealed: make([]byte, 0, encryptionSegmentSize+aead.Overhead()),
+	}, nil
+}
+
+// Write is part of the io.Writer interface. A full segment is only sealed
+// once more data comes, as it's not known before if it's the last one.
+func (ew *encryptingWriter) Write(p []byte) (int, error) {
+	if ew.closed {
+		return 0, io.ErrClosedPipe
+	}
+	written := 0
+	for len(p) > 0 {
+		if len(ew.buf) == encryptionSegmentSize {
+			if err := ew.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := min(encryptionSegmentSize-len(ew.buf), len(p))
+		ew.buf = append(ew.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close is part of the io.Closer interface.
+func (ew *encryptingWriter) Close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+	return ew.seal(true)
+}
+
+func (ew *encryptingWriter) seal(last bool) error {
+	if ew.segment == math.MaxUint32 {
+		return vterrors.Errorf(vtrpcpb.Code_OUT_OF_RANGE, "backup file is too large to be encrypted")
+	}
+	setSegmentNonce(ew.nonce, ew.segment, last)
+	ew.sealed = ew.aead.Seal(ew.sealed[:0], ew.nonce, ew.buf, nil)
+	if _, err := ew.w.Write(ew.sealed); err != nil {
+		return err
+	}
+	ew.buf = ew.buf[:0]
+	ew.segment++
+	return nil
+}
+
+type decryptingReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	nonce   []byte
+	segment uint32
+	sealed  []byte
+	plain   []byte
+	unread  []byte
+	started bool
+	done    bool
+}
+
+// newDecryptingReader returns a reader that decrypts what it reads from r
+// with the data key. It fails if the data was tampered with or truncated.
+func newDecryptingReader(r io.Reader, dataKey []byte) (io.Reader, error) {
+	aead, err := newBackupAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptingReader{
+		r:      bufio.NewReader(r),
+		aead:   aead,
+		nonce:  make([]byte, aead.NonceSize()),
+		sealed: make([]byte, encryptionSegmentSize+aead.Overhead()),
+		plain:  make([]byte, 0, encryptionSegmentSize),
+	}, nil
+}
+
+// Read is part of the io.Reader interface.
+func (dr *decryptingReader) Read(p []byte) (int, error) {
+	for len(dr.unread) == 0 {
+		if dr.done {
+			return 0, io.EOF
+		}
+		if err := dr.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, dr.unread)
+	dr.unread = dr.unread[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next segment.
+func (dr *decryptingReader) open() error {
+	if !dr.started {
+		if _, err := io.ReadFull(dr.r, dr.nonce[:encryptionNoncePrefixSize]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return errEncryptedFileTruncated
+			}
+			return err
+		}
+		dr.started = true
+	}
+
+	n, err := io.ReadFull(dr.r, dr.sealed)
+	last := false
+	switch err {
+	case nil:
+		// A full segment is the last one if nothing follows it.
+		if _, err := dr.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+	if n < dr.aead.Overhead() {
+		return errEncryptedFileTruncated
+	}
+
+	setSegmentNonce(dr.nonce, dr.segment, last)
+	dr.plain, err = dr.aead.Open(dr.plain[:0], dr.nonce, dr.sealed[:n], nil)
+	if err != nil {
+		// This is also the error of a file truncated after a full segment,
+		// as the segment wasn't sealed as the last one.
+		return errEncryptedFileCorrupted
+	}
+	if dr.segment == math.MaxUint32 && !last {
+		return errEncryptedFileCorrupted
+	}
+	dr.unread = dr.plain
+	dr.segment++
+	dr.done = last
+	return nil
+}
+
+// keyfileKeyProvider wraps the data keys with AES-256-GCM and the keys of
+// a local file, so that the keys can be rotated: new data keys are wrapped
+// with the first key, and the other ones can still unwrap older backups.
+type keyfileKeyProvider struct {
+	keys    map[string][]byte
+	primary string
+}
+
+func newKeyfileKeyProvider() (BackupKeyProvider, error) {
+	if BackupEncryptionKeyfile == "" {
+		return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "--backup-encryption-keyfile must be set to use the %q backup encryption key provider", KeyfileKeyProvider)
+	}
+	data, err := os.ReadFile(BackupEncryptionKeyfile)
+	if err != nil {
+		return nil, err
+	}
+	return parseKeyfile(string(data))
+}
+
+// parseKeyfile parses the lines of a keyfile, which are made of a key ID
+// and of a base64 encoded key. Empty lines and lines starting with # are
+// ignored.
+func parseKeyfile(data string) (*keyfileKeyProvider, error) {
+	kp := &keyfileKeyProvider{keys: make(map[string][]byte)}
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "invalid line %d of the backup encryption keyfile, expected '<key id> <base64 encoded key>'", i+1)
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(key) != encryptionKeySize {
+			return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "invalid key %q in the backup encryption keyfile, expected %d base64 encoded bytes", fields[0], encryptionKeySize)
+		}
+		if _, ok := kp.keys[fields[0]]; ok {
+			return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "duplicate key %q in the backup encryption keyfile", fields[0])
+		}
+		kp.keys[fields[0]] = key
+		if kp.primary == "" {
+			kp.primary = fields[0]
+		}
+	}
+	if kp.primary == "" {
+		return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "no key in the backup encryption keyfile")
+	}
+	return kp, nil
+}
+
+// WrapKey is part of the BackupKeyProvider interface.
+func (kp *keyfileKeyProvider) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	aead, err := newBackupAEAD(kp.keys[kp.primary])
+	if err != nil {
+		return "", nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return kp.primary, aead.Seal(nonce, nonce, dataKey, []byte(kp.primary)), nil
+}
+
+// UnwrapKey is part of the BackupKeyProvider interface.
+func (kp *keyfileKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrappedKey []byte) ([]byte, error) {
+	key, ok := kp.keys[keyID]
+	if !ok {
+		return nil, vterrors.Errorf(vtrpcpb.Code_NOT_FOUND, "key %q is not in the backup encryption keyfile", keyID)
+	}
+	aead, err := newBackupAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrappedKey) < aead.NonceSize() {
+		return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "wrapped key is too short")
+	}
+	nonce, sealed := wrappedKey[:aead.NonceSize()], wrappedKey[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, []byte(keyID))
+	if err != nil {
+		return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "cannot unwrap the data key with key %q, the key doesn't match", keyID)
+	}
+	return dataKey, nil
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encrypt(t *testing.T, key, data []byte, chunk int) []byte {
+	var buf bytes.Buffer
+	w, err := newEncryptingWriter(&buf, key)
+	require.NoError(t, err)
+	for p := data; len(p) > 0; {
+		n := min(chunk, len(p))
+		_, err := w.Write(p[:n])
+		require.NoError(t, err)
+		p = p[n:]
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func decrypt(key, data []byte) ([]byte, error) {
+	r, err := newDecryptingReader(bytes.NewReader(data), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	key := make([]byte, encryptionKeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	for _, size := range []int{0, 1, encryptionSegmentSize - 1, encryptionSegmentSize, encryptionSegmentSize + 1, 3*encryptionSegmentSize + 17} {
+		for _, chunk := range []int{1000, encryptionSegmentSize, 5 * encryptionSegmentSize} {
+			t.Run(fmt.Sprintf("%d/%d", size, chunk), func(t *testing.T) {
+				data := make([]byte, size)
+				_, err := rand.Read(data)
+				require.NoError(t, err)
+
+				encrypted := encrypt(t, key, data, chunk)
+				segments := max(1, (size+encryptionSegmentSize-1)/encryptionSegmentSize)
+				assert.Equal(t, encryptionNoncePrefixSize+size+segments*16, len(encrypted))
+				decrypted, err := decrypt(key, encrypted)
+				require.NoError(t, err)
+				assert.Equal(t, data, decrypted)
+			})
+		}
+	}
+}
+
+func TestEncryptionTampering(t *testing.T) {
+	key := make([]byte, encryptionKeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	otherKey := make([]byte, encryptionKeySize)
+	_, err = rand.Read(otherKey)
+	require.NoError(t, err)
+
+	data := make([]byte, 2*encryptionSegmentSize)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+	encrypted := encrypt(t, key, data, len(data))
+	segment := encryptionSegmentSize + 16
+
+	flipped := bytes.Clone(encrypted)
+	flipped[100] ^= 1
+
+	// The first segment alone is a full segment, which wasn't sealed as the last one.
+	truncatedAfterSegment := encrypted[:encryptionNoncePrefixSize+segment]
+
+	// The segments are swapped.
+	swapped := bytes.Clone(encrypted[:encryptionNoncePrefixSize])
+	swapped = append(swapped, encrypted[encryptionNoncePrefixSize+segment:encryptionNoncePrefixSize+2*segment]...)
+	swapped = append(swapped, encrypted[encryptionNoncePrefixSize:encryptionNoncePrefixSize+segment]...)
+	swapped = append(swapped, encrypted[encryptionNoncePrefixSize+2*segment:]...)
+
+	tests := []struct {
+		name string
+		key  []byte
+		data []byte
+		err  error
+	}{
+		{name: "flipped bit", key: key, data: flipped, err: errEncryptedFileCorrupted},
+		{name: "truncated in the middle of a segment", key: key, data: encrypted[:len(encrypted)-100], err: errEncryptedFileCorrupted},
+		{name: "truncated after a segment", key: key, data: truncatedAfterSegment, err: errEncryptedFileCorrupted},
+		{name: "truncated last segment", key: key, data: encrypted[:len(encrypted)-segment+10], err: errEncryptedFileTruncated},
+		{name: "truncated nonce", key: key, data: encrypted[:3], err: errEncryptedFileTruncated},
+		{name: "swapped segments", key: key, data: swapped, err: errEncryptedFileCorrupted},
+		{name: "other key", key: otherKey, data: encrypted, err: errEncryptedFileCorrupted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decrypt(tt.key, tt.data)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func newTestKeyfile(t *testing.T, ids ...string) string {
+	var buf bytes.Buffer
+	buf.WriteString("# backup encryption keys\n\n")
+	for _, id := range ids {
+		key := make([]byte, encryptionKeySize)
+		// The keys are derived from their ID, so that a key keeps its value
+		// across keyfiles.
+		copy(key, id)
+		fmt.Fprintf(&buf, "%s %s\n", id, base64.StdEncoding.EncodeToString(key))
+	}
+	keyfile := path.Join(t.TempDir(), "keyfile")
+	require.NoError(t, os.WriteFile(keyfile, buf.Bytes(), 0600))
+	return keyfile
+}
+
+func TestParseKeyfile(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, encryptionKeySize))
+	tests := []struct {
+		data string
+		err  string
+	}{{
+		data: "k1 " + key + "\n  k2\t" + key + "  \n",
+	}, {
+		data: "# no key\n",
+		err:  "no key in the backup encryption keyfile",
+	}, {
+		data: "k1 " + key + "\nk2\n",
+		err:  "invalid line 2 of the backup encryption keyfile, expected '<key id> <base64 encoded key>'",
+	}, {
+		data: "k1 " + base64.StdEncoding.EncodeToString([]byte("short")),
+		err:  `invalid key "k1" in the backup encryption keyfile, expected 32 base64 encoded bytes`,
+	}, {
+		data: "k1 not-base64",
+		err:  `invalid key "k1" in the backup encryption keyfile, expected 32 base64 encoded bytes`,
+	}, {
+		data: "k1 " + key + "\nk1 " + key,
+		err:  `duplicate key "k1" in the backup encryption keyfile`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			kp, err := parseKeyfile(tt.data)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "k1", kp.primary)
+			assert.Len(t, kp.keys, 2)
+		})
+	}
+}
+
+func TestBackupEncryptionKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	defer func(provider, keyfile string) {
+		BackupEncryptionKeyProvider, BackupEncryptionKeyfile = provider, keyfile
+	}(BackupEncryptionKeyProvider, BackupEncryptionKeyfile)
+
+	// Backups aren't encrypted by default.
+	encryption, dataKey, err := newBackupEncryption(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, encryption)
+	assert.Nil(t, dataKey)
+
+	BackupEncryptionKeyProvider = "kms"
+	_, _, err = newBackupEncryption(ctx)
+	assert.EqualError(t, err, `unknown backup encryption key provider "kms"`)
+
+	BackupEncryptionKeyProvider = KeyfileKeyProvider
+	_, _, err = newBackupEncryption(ctx)
+	assert.EqualError(t, err, `--backup-encryption-keyfile must be set to use the "keyfile" backup encryption key provider`)
+
+	BackupEncryptionKeyfile = newTestKeyfile(t, "2023-01")
+	encryption, dataKey, err = newBackupEncryption(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, backupEncryptionAlgorithm, encryption.Algorithm)
+	assert.Equal(t, KeyfileKeyProvider, encryption.KeyProvider)
+	assert.Equal(t, "2023-01", encryption.KeyID)
+	assert.NotContains(t, string(encryption.WrappedKey), string(dataKey))
+
+	// The rotated key can still unwrap the data keys of the older backups.
+	BackupEncryptionKeyfile = newTestKeyfile(t, "2023-02", "2023-01")
+	unwrapped, err := encryption.dataKey(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+	newEncryption, _, err := newBackupEncryption(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "2023-02", newEncryption.KeyID)
+
+	// But not once it is removed from the keyfile.
+	BackupEncryptionKeyfile = newTestKeyfile(t, "2023-02")
+	_, err = encryption.dataKey(ctx)
+	assert.EqualError(t, err, `cannot unwrap the data key with key "2023-01" of key provider "keyfile": key "2023-01" is not in the backup encryption keyfile`)
+
+	// The ID of the key is authenticated with the wrapped key.
+	encryption.KeyID = "2023-02"
+	_, err = encryption.dataKey(ctx)
+	assert.EqualError(t, err, `cannot unwrap the data key with key "2023-02" of key provider "keyfile": cannot unwrap the data key with key "2023-02", the key doesn't match`)
+}
@@ -190,9 +190,10 @@ func (fbs *FileBackupStorage) StartBackup(ctx context.Context, dir, name string)
 		return nil, err
 	}
 
-	// Create the subdirectory for this named backup.
+	// Create the subdirectory for this named backup. It already exists
+	// when files are added to an existing backup, like VerifyBackup does.
 	p = path.Join(p, name)
-	if err := os.Mkdir(p, os.ModePerm); err != nil {
+	if err := os.Mkdir(p, os.ModePerm); err != nil && !os.IsExist(err) {
 		return nil, err
 	}
 
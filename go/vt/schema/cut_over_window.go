@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var cutOverWindowRegexp = regexp.MustCompile(`^\s*(\d{1,2}):(\d{2})\s*-\s*(\d{1,2}):(\d{2})(?:\s+(\S+))?\s*$`)
+
+// CutOverWindow is a daily time window, e.g. "02:00-04:00 UTC", in which a migration may cut over.
+// A window whose end is before its start, e.g. "23:00-01:00", spans midnight.
+type CutOverWindow struct {
+	// Start and End are offsets from midnight
+	Start    time.Duration
+	End      time.Duration
+	Location *time.Location
+}
+
+// ParseCutOverWindow parses a window of the form "HH:MM-HH:MM [location]". The location is an IANA
+// time zone name, e.g. "America/New_York", and defaults to UTC.
+func ParseCutOverWindow(s string) (*CutOverWindow, error) {
+	submatch := cutOverWindowRegexp.FindStringSubmatch(s)
+	if len(submatch) == 0 {
+		return nil, fmt.Errorf("invalid cut-over window %q, expected HH:MM-HH:MM [location]", s)
+	}
+	start, err := parseTimeOfDay(submatch[1], submatch[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cut-over window %q: %v", s, err)
+	}
+	end, err := parseTimeOfDay(submatch[3], submatch[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cut-over window %q: %v", s, err)
+	}
+	if start == end {
+		return nil, fmt.Errorf("invalid cut-over window %q: empty window", s)
+	}
+	location := time.UTC
+	if name := submatch[5]; name != "" {
+		if location, err = time.LoadLocation(name); err != nil {
+			return nil, fmt.Errorf("invalid cut-over window %q: %v", s, err)
+		}
+	}
+	return &CutOverWindow{Start: start, End: end, Location: location}, nil
+}
+
+func parseTimeOfDay(hours, minutes string) (time.Duration, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(hours+":"+minutes, "%d:%d", &h, &m); err != nil {
+		return 0, err
+	}
+	if h > 24 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time of day %s:%s", hours, minutes)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// windowStart returns the start of the window on the day of the given midnight
+func (w *CutOverWindow) windowStart(midnight time.Time) time.Time {
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), 0, 0, 0, 0, w.Location).Add(w.Start)
+}
+
+// windowEnd returns the end of the window that starts on the day of the given midnight
+func (w *CutOverWindow) windowEnd(midnight time.Time) time.Time {
+	end := time.Date(midnight.Year(), midnight.Month(), midnight.Day(), 0, 0, 0, 0, w.Location).Add(w.End)
+	if w.End < w.Start {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// NextStart returns the start of the window that t is in, or of the next window if t is outside of any window.
+func (w *CutOverWindow) NextStart(t time.Time) time.Time {
+	t = t.In(w.Location)
+	// A window that spans midnight may have started on the day before
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.Location).AddDate(0, 0, -1)
+	for !t.Before(w.windowEnd(day)) {
+		day = day.AddDate(0, 0, 1)
+	}
+	// The first window that ends after t either contains t, or is the next one
+	return w.windowStart(day)
+}
+
+// Contains returns true when t is in the window.
+func (w *CutOverWindow) Contains(t time.Time) bool {
+	return !t.Before(w.NextStart(t))
+}
+
+// String returns the window in the form it is parsed from
+func (w *CutOverWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d %s",
+		int(w.Start.Hours()), int(w.Start.Minutes())%60,
+		int(w.End.Hours()), int(w.End.Minutes())%60,
+		w.Location.String(),
+	)
+}
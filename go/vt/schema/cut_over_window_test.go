@@ -0,0 +1,165 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCutOverWindow(t *testing.T) {
+	tt := []struct {
+		s      string
+		start  time.Duration
+		end    time.Duration
+		str    string
+		expect bool
+	}{
+		{
+			s:      "02:00-04:00 UTC",
+			start:  2 * time.Hour,
+			end:    4 * time.Hour,
+			str:    "02:00-04:00 UTC",
+			expect: true,
+		},
+		{
+			s:      "2:30-4:15",
+			start:  2*time.Hour + 30*time.Minute,
+			end:    4*time.Hour + 15*time.Minute,
+			str:    "02:30-04:15 UTC",
+			expect: true,
+		},
+		{
+			s:      "23:00 - 01:00 Asia/Tokyo",
+			start:  23 * time.Hour,
+			end:    time.Hour,
+			str:    "23:00-01:00 Asia/Tokyo",
+			expect: true,
+		},
+		{
+			s:      "22:00-24:00",
+			start:  22 * time.Hour,
+			end:    24 * time.Hour,
+			str:    "22:00-24:00 UTC",
+			expect: true,
+		},
+		{
+			s: "02:00",
+		},
+		{
+			s: "02:00-02:00",
+		},
+		{
+			s: "25:00-02:00",
+		},
+		{
+			s: "02:60-03:00",
+		},
+		{
+			s: "02:00-03:00 Not/AZone",
+		},
+	}
+	for _, ts := range tt {
+		t.Run(ts.s, func(t *testing.T) {
+			w, err := ParseCutOverWindow(ts.s)
+			if !ts.expect {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, ts.start, w.Start)
+			assert.Equal(t, ts.end, w.End)
+			assert.Equal(t, ts.str, w.String())
+		})
+	}
+}
+
+func TestCutOverWindowNextStart(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return tm
+	}
+	tt := []struct {
+		window    string
+		t         string
+		nextStart string
+		contains  bool
+	}{
+		{
+			window:    "02:00-04:00 UTC",
+			t:         "2023-08-01T01:59:59Z",
+			nextStart: "2023-08-01T02:00:00Z",
+		},
+		{
+			window:    "02:00-04:00 UTC",
+			t:         "2023-08-01T02:00:00Z",
+			nextStart: "2023-08-01T02:00:00Z",
+			contains:  true,
+		},
+		{
+			window:    "02:00-04:00 UTC",
+			t:         "2023-08-01T03:59:59Z",
+			nextStart: "2023-08-01T02:00:00Z",
+			contains:  true,
+		},
+		{
+			window:    "02:00-04:00 UTC",
+			t:         "2023-08-01T04:00:00Z",
+			nextStart: "2023-08-02T02:00:00Z",
+		},
+		{
+			window:    "23:00-01:00 UTC",
+			t:         "2023-08-01T00:30:00Z",
+			nextStart: "2023-07-31T23:00:00Z",
+			contains:  true,
+		},
+		{
+			window:    "23:00-01:00 UTC",
+			t:         "2023-08-01T12:00:00Z",
+			nextStart: "2023-08-01T23:00:00Z",
+		},
+		{
+			window:    "23:00-01:00 UTC",
+			t:         "2023-08-01T23:30:00Z",
+			nextStart: "2023-08-01T23:00:00Z",
+			contains:  true,
+		},
+		{
+			window:    "02:00-04:00 America/New_York",
+			t:         "2023-08-01T05:00:00Z",
+			nextStart: "2023-08-01T06:00:00Z",
+		},
+		{
+			window:    "02:00-04:00 America/New_York",
+			t:         "2023-08-01T07:00:00Z",
+			nextStart: "2023-08-01T06:00:00Z",
+			contains:  true,
+		},
+	}
+	for _, ts := range tt {
+		t.Run(ts.window+" "+ts.t, func(t *testing.T) {
+			w, err := ParseCutOverWindow(ts.window)
+			require.NoError(t, err)
+			assert.True(t, at(ts.nextStart).Equal(w.NextStart(at(ts.t))), "next start: %v", w.NextStart(at(ts.t)))
+			assert.Equal(t, ts.contains, w.Contains(at(ts.t)))
+		})
+	}
+}
@@ -28,6 +28,7 @@ import (
 var (
 	strategyParserRegexp       = regexp.MustCompile(`^([\S]+)\s+(.*)$`)
 	cutOverThresholdFlagRegexp = regexp.MustCompile(fmt.Sprintf(`^[-]{1,2}%s=(.*?)$`, cutOverThresholdFlag))
+	cutOverWindowFlagRegexp    = regexp.MustCompile(fmt.Sprintf(`^[-]{1,2}%s=(.*?)$`, cutOverWindowFlag))
 )
 
 const (
@@ -43,6 +44,7 @@ const (
 	preferInstantDDL       = "prefer-instant-ddl"
 	fastRangeRotationFlag  = "fast-range-rotation"
 	cutOverThresholdFlag   = "cut-over-threshold"
+	cutOverWindowFlag      = "cut-over-window"
 	vreplicationTestSuite  = "vreplication-test-suite"
 	allowForeignKeysFlag   = "unsafe-allow-foreign-keys"
 	analyzeTableFlag       = "analyze-table"
@@ -110,6 +112,9 @@ func ParseDDLStrategy(strategyVariable string) (*DDLStrategySetting, error) {
 	if _, err := setting.CutOverThreshold(); err != nil {
 		return nil, err
 	}
+	if _, err := setting.CutOverWindow(); err != nil {
+		return nil, err
+	}
 	return setting, nil
 }
 
@@ -212,6 +217,33 @@ func (setting *DDLStrategySetting) CutOverThreshold() (d time.Duration, err erro
 	return d, err
 }
 
+// isCutOverWindowFlag returns true when given option denotes a `--cut-over-window=[...]` flag
+func isCutOverWindowFlag(opt string) (string, bool) {
+	submatch := cutOverWindowFlagRegexp.FindStringSubmatch(opt)
+	if len(submatch) == 0 {
+		return "", false
+	}
+	return submatch[1], true
+}
+
+// CutOverWindow returns the window specified in '--cut-over-window=...', or nil if unspecified
+func (setting *DDLStrategySetting) CutOverWindow() (w *CutOverWindow, err error) {
+	opts, _ := shlex.Split(setting.Options)
+	for _, opt := range opts {
+		if val, isCutOverWindow := isCutOverWindowFlag(opt); isCutOverWindow {
+			// value is possibly quoted
+			if s, err := strconv.Unquote(val); err == nil {
+				val = s
+			}
+			w = nil
+			if val != "" {
+				w, err = ParseCutOverWindow(val)
+			}
+		}
+	}
+	return w, err
+}
+
 // IsVreplicationTestSuite checks if strategy options include --vreplicatoin-test-suite
 func (setting *DDLStrategySetting) IsVreplicationTestSuite() bool {
 	return setting.hasFlag(vreplicationTestSuite)
@@ -235,6 +267,9 @@ func (setting *DDLStrategySetting) RuntimeOptions() []string {
 		if _, ok := isCutOverThresholdFlag(opt); ok {
 			continue
 		}
+		if _, ok := isCutOverWindowFlag(opt); ok {
+			continue
+		}
 		switch {
 		case isFlag(opt, declarativeFlag):
 		case isFlag(opt, skipTopoFlag):
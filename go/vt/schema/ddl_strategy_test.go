@@ -118,6 +118,7 @@ func TestParseDDLStrategy(t *testing.T) {
 		allowForeignKeys     bool
 		analyzeTable         bool
 		cutOverThreshold     time.Duration
+		cutOverWindow        string
 		runtimeOptions       string
 		err                  error
 	}{
@@ -239,6 +240,15 @@ func TestParseDDLStrategy(t *testing.T) {
 			runtimeOptions:   "",
 			cutOverThreshold: 5 * time.Minute,
 		},
+		{
+			strategyVariable: `vitess --cut-over-window="02:00-04:00 UTC" --postpone-completion`,
+			strategy:         DDLStrategyVitess,
+			options:          `--cut-over-window="02:00-04:00 UTC" --postpone-completion`,
+			runtimeOptions:   "",
+			cutOverWindow:    "02:00-04:00 UTC",
+
+			isPostponeCompletion: true,
+		},
 		{
 			strategyVariable: "vitess --analyze-table",
 			strategy:         DDLStrategyVitess,
@@ -265,6 +275,13 @@ func TestParseDDLStrategy(t *testing.T) {
 			cutOverThreshold, err := setting.CutOverThreshold()
 			assert.NoError(t, err)
 			assert.Equal(t, ts.cutOverThreshold, cutOverThreshold)
+			cutOverWindow, err := setting.CutOverWindow()
+			assert.NoError(t, err)
+			if ts.cutOverWindow == "" {
+				assert.Nil(t, cutOverWindow)
+			} else {
+				assert.Equal(t, ts.cutOverWindow, cutOverWindow.String())
+			}
 
 			runtimeOptions := strings.Join(setting.RuntimeOptions(), " ")
 			assert.Equal(t, ts.runtimeOptions, runtimeOptions)
@@ -282,4 +299,8 @@ func TestParseDDLStrategy(t *testing.T) {
 		_, err := ParseDDLStrategy("online --cut-over-threshold=3")
 		assert.Error(t, err)
 	}
+	{
+		_, err := ParseDDLStrategy("online --cut-over-window=02:00")
+		assert.Error(t, err)
+	}
 }
@@ -0,0 +1,24 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+CREATE TABLE IF NOT EXISTS message_acks
+(
+    `table_name`     varbinary(128) NOT NULL,
+    `id`             varbinary(255) NOT NULL,
+    `consumer_group` varbinary(128) NOT NULL,
+    `time_acked`     bigint         NOT NULL,
+    PRIMARY KEY (`table_name`, `id`, `consumer_group`)
+) ENGINE = InnoDB
@@ -2055,6 +2055,11 @@ func ContainsAggregation(e SQLNode) bool {
 			// so we don't need to worry about aggregation in the original
 			return false, nil
 		case AggrFunc:
+			if GetOverClause(node) != nil {
+				// an aggregate function with an OVER clause is a window function,
+				// and does not group the rows of the query
+				return true, nil
+			}
 			hasAggregates = true
 			return false, io.EOF
 		}
@@ -2064,6 +2069,45 @@ func ContainsAggregation(e SQLNode) bool {
 	return hasAggregates
 }
 
+// GetOverClause returns the OVER clause of a window function,
+// or nil if the node is not used as a window function
+func GetOverClause(node SQLNode) *OverClause {
+	switch node := node.(type) {
+	case *ArgumentLessWindowExpr:
+		return node.OverClause
+	case *FirstOrLastValueExpr:
+		return node.OverClause
+	case *NtileExpr:
+		return node.OverClause
+	case *NTHValueExpr:
+		return node.OverClause
+	case *LagLeadExpr:
+		return node.OverClause
+	case *Sum:
+		return node.OverClause
+	case *Avg:
+		return node.OverClause
+	}
+	return nil
+}
+
+// ContainsWindowFunction returns true if the expression uses a window function.
+// Subqueries are not inspected, since they are evaluated on their own.
+func ContainsWindowFunction(e SQLNode) bool {
+	found := false
+	_ = Walk(func(node SQLNode) (kontinue bool, err error) {
+		if _, isSubq := node.(*Subquery); isSubq {
+			return false, nil
+		}
+		if GetOverClause(node) != nil {
+			found = true
+			return false, io.EOF
+		}
+		return true, nil
+	}, e)
+	return found
+}
+
 // GetFirstSelect gets the first select statement
 func GetFirstSelect(selStmt SelectStatement) *Select {
 	if selStmt == nil {
@@ -2541,3 +2585,21 @@ func IsLiteral(expr Expr) bool {
 		return false
 	}
 }
+
+// IsSelfReferencing returns true if the definition of the common table expression
+// reads from the CTE itself, which is only allowed for recursive CTEs.
+func (node *CommonTableExpr) IsSelfReferencing() bool {
+	found := false
+	_ = Walk(func(n SQLNode) (bool, error) {
+		tbl, ok := n.(*AliasedTableExpr)
+		if !ok {
+			return true, nil
+		}
+		if name, isTableName := tbl.Expr.(TableName); isTableName && name.Qualifier.IsEmpty() && name.Name.String() == node.ID.String() {
+			found = true
+			return false, nil
+		}
+		return true, nil
+	}, node.Subquery)
+	return found
+}
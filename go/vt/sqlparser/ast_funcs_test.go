@@ -172,3 +172,61 @@ func TestColumns_Indexes(t *testing.T) {
 		})
 	}
 }
+
+func TestCommonTableExprIsSelfReferencing(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{{
+		query: "with recursive x as (select 1 as id union all select id + 1 from x where id < 5) select id from x",
+		want:  true,
+	}, {
+		query: "with recursive x as (select id from t union all select t.id from t join x on t.parent = x.id) select id from x",
+		want:  true,
+	}, {
+		query: "with recursive x as (select id from t where t.x = 1) select id from x",
+		want:  false,
+	}, {
+		query: "with recursive x as (select id from ks.x) select id from x",
+		want:  false,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			stmt, err := Parse(tt.query)
+			require.NoError(t, err)
+			cte := stmt.(*Select).With.CTEs[0]
+			assert.Equal(t, tt.want, cte.IsSelfReferencing())
+		})
+	}
+}
+
+func TestContainsWindowFunction(t *testing.T) {
+	tests := []struct {
+		expr        string
+		window      bool
+		aggregation bool
+	}{{
+		expr:   "row_number() over (partition by a order by b)",
+		window: true,
+	}, {
+		expr:   "sum(a) over (order by b) + 1",
+		window: true,
+	}, {
+		expr:        "sum(a)",
+		aggregation: true,
+	}, {
+		expr:        "sum(a) over () + count(b)",
+		window:      true,
+		aggregation: true,
+	}, {
+		expr: "a + (select row_number() over () from t)",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseExpr(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.window, ContainsWindowFunction(expr))
+			assert.Equal(t, tt.aggregation, ContainsAggregation(expr))
+		})
+	}
+}
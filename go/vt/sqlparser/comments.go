@@ -56,6 +56,15 @@ const (
 	// DirectivePriority specifies the priority of a workload. It should be an integer between 0 and MaxPriorityValue,
 	// where 0 is the highest priority, and MaxPriorityValue is the lowest one.
 	DirectivePriority = "PRIORITY"
+	// DirectiveWorkloadClass specifies the vttablet workload class of a query, e.g. oltp or batch. The class decides
+	// the reserved slots and the priority the query is admitted to the vttablet connection pools with.
+	DirectiveWorkloadClass = "WORKLOAD_CLASS"
+	// DirectiveResultCacheTTL caches the result of a read-only query in vtgate for the given duration, e.g. 10s.
+	DirectiveResultCacheTTL = "RESULT_CACHE_TTL"
+	// DirectiveConsumerGroup names the consumer group a STREAM of a message table with consumer groups is a member of.
+	DirectiveConsumerGroup = "CONSUMER_GROUP"
+	// DirectiveQuotaTag names the tenant of a query for the query quotas of the VSchema whose key is "tag".
+	DirectiveQuotaTag = "QUOTA_TAG"
 
 	// MaxPriorityValue specifies the maximum value allowed for the priority query directive. Valid priority values are
 	// between zero and MaxPriorityValue.
@@ -465,3 +474,18 @@ func GetWorkloadNameFromStatement(statement Statement) string {
 
 	return workloadName
 }
+
+// GetWorkloadClassFromStatement gets the workload class from the provided Statement, using DirectiveWorkloadClass
+func GetWorkloadClassFromStatement(statement Statement) string {
+	commentedStatement, ok := statement.(Commented)
+	// This would mean that the statement lacks comments, so we can't obtain the workload class from it. Hence default
+	// to empty workload class
+	if !ok {
+		return ""
+	}
+
+	directives := commentedStatement.GetParsedComments().Directives()
+	workloadClass, _ := directives.GetString(DirectiveWorkloadClass, "")
+
+	return strings.ToLower(workloadClass)
+}
@@ -561,3 +561,35 @@ func TestGetPriorityFromStatement(t *testing.T) {
 		})
 	}
 }
+
+func TestGetWorkloadClassFromStatement(t *testing.T) {
+	testCases := []struct {
+		query                 string
+		expectedWorkloadClass string
+	}{
+		{
+			query:                 "select * from a_table",
+			expectedWorkloadClass: "",
+		},
+		{
+			query:                 "select /*vt+ PRIORITY=33 */ * from another_table",
+			expectedWorkloadClass: "",
+		},
+		{
+			query:                 "select /*vt+ WORKLOAD_CLASS=batch */ * from another_table",
+			expectedWorkloadClass: "batch",
+		},
+		{
+			query:                 "update /*vt+ WORKLOAD_CLASS=OLTP */ a_table set a = 1",
+			expectedWorkloadClass: "oltp",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.query, func(t *testing.T) {
+			stmt, err := Parse(testCase.query)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedWorkloadClass, GetWorkloadClassFromStatement(stmt))
+		})
+	}
+}
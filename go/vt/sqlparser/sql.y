@@ -703,11 +703,11 @@ load_statement:
 with_clause:
   WITH with_list
   {
-	$$ = &With{ctes: $2, Recursive: false}
+	$$ = &With{CTEs: $2, Recursive: false}
   }
 | WITH RECURSIVE with_list
   {
-	$$ = &With{ctes: $3, Recursive: true}
+	$$ = &With{CTEs: $3, Recursive: true}
   }
 
 with_clause_opt:
@@ -5938,10 +5938,18 @@ UTC_DATE func_paren_opt
   {
     $$ = &Sum{Distinct:$3, Arg:$4}
   }
+| SUM openb distinct_opt expression closeb over_clause
+  {
+    $$ = &Sum{Distinct:$3, Arg:$4, OverClause: $6}
+  }
 | AVG openb distinct_opt expression closeb
   {
     $$ = &Avg{Distinct:$3, Arg:$4}
   }
+| AVG openb distinct_opt expression closeb over_clause
+  {
+    $$ = &Avg{Distinct:$3, Arg:$4, OverClause: $6}
+  }
 | BIT_AND openb expression closeb
   {
     $$ = &BitAnd{Arg:$3}
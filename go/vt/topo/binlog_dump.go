@@ -0,0 +1,158 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package topo
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"vitess.io/vitess/go/vt/vterrors"
+
+	binlogdatapb "vitess.io/vitess/go/vt/proto/binlogdata"
+)
+
+// The vtgates serve the changes of a keyspace with the MySQL binlog
+// replication protocol. The binlog files of a stream are numbered, and
+// the VGTID every file starts at is stored in the global topo, under
+// BinlogDumpPath/<keyspace>/<stream>/<sequence number>, so a client can
+// resume the stream from any vtgate. The last time a vtgate served a stream
+// is stored under BinlogDumpPath/<keyspace>/<stream>/active, so the streams
+// no client comes back to can be purged.
+
+const binlogDumpActiveFile = "active"
+
+func binlogDumpStreamPath(keyspace, stream string) string {
+	return path.Join(BinlogDumpPath, keyspace, stream)
+}
+
+func binlogDumpPositionPath(keyspace, stream string, seq uint64) string {
+	return path.Join(binlogDumpStreamPath(keyspace, stream), strconv.FormatUint(seq, 10))
+}
+
+// CreateBinlogDumpPosition stores the VGTID a binlog file of a stream starts at.
+// It returns a NodeExists error if the file was already created.
+func (ts *Server) CreateBinlogDumpPosition(ctx context.Context, keyspace, stream string, seq uint64, vgtid *binlogdatapb.VGtid) error {
+	data, err := proto.Marshal(vgtid)
+	if err != nil {
+		return err
+	}
+	_, err = ts.globalCell.Create(ctx, binlogDumpPositionPath(keyspace, stream, seq), data)
+	return err
+}
+
+// GetBinlogDumpPosition returns the VGTID a binlog file of a stream starts at.
+// It returns a NoNode error if the file is unknown, or was purged.
+func (ts *Server) GetBinlogDumpPosition(ctx context.Context, keyspace, stream string, seq uint64) (*binlogdatapb.VGtid, error) {
+	data, _, err := ts.globalCell.Get(ctx, binlogDumpPositionPath(keyspace, stream, seq))
+	if err != nil {
+		return nil, err
+	}
+	vgtid := &binlogdatapb.VGtid{}
+	if err := proto.Unmarshal(data, vgtid); err != nil {
+		return nil, vterrors.Wrapf(err, "bad binlog dump position data: %q", data)
+	}
+	return vgtid, nil
+}
+
+// GetBinlogDumpFiles returns the sorted sequence numbers of the binlog files of a stream.
+func (ts *Server) GetBinlogDumpFiles(ctx context.Context, keyspace, stream string) ([]uint64, error) {
+	entries, err := ts.globalCell.ListDir(ctx, binlogDumpStreamPath(keyspace, stream), false)
+	switch {
+	case IsErrType(err, NoNode):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	var files []uint64
+	for _, entry := range entries {
+		seq, err := strconv.ParseUint(entry.Name, 10, 64)
+		if err != nil {
+			continue
+		}
+		files = append(files, seq)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i] < files[j] })
+	return files, nil
+}
+
+// GetBinlogDumpStreams returns the streams of a keyspace.
+func (ts *Server) GetBinlogDumpStreams(ctx context.Context, keyspace string) ([]string, error) {
+	entries, err := ts.globalCell.ListDir(ctx, path.Join(BinlogDumpPath, keyspace), false)
+	switch {
+	case IsErrType(err, NoNode):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	streams := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		streams = append(streams, entry.Name)
+	}
+	return streams, nil
+}
+
+// UpdateBinlogDumpActiveTime stores the last time a stream was served.
+func (ts *Server) UpdateBinlogDumpActiveTime(ctx context.Context, keyspace, stream string, active time.Time) error {
+	data, err := active.UTC().MarshalText()
+	if err != nil {
+		return err
+	}
+	_, err = ts.globalCell.Update(ctx, path.Join(binlogDumpStreamPath(keyspace, stream), binlogDumpActiveFile), data, nil)
+	return err
+}
+
+// GetBinlogDumpActiveTime returns the last time a stream was served.
+// It returns a NoNode error if the stream never stored it.
+func (ts *Server) GetBinlogDumpActiveTime(ctx context.Context, keyspace, stream string) (time.Time, error) {
+	data, _, err := ts.globalCell.Get(ctx, path.Join(binlogDumpStreamPath(keyspace, stream), binlogDumpActiveFile))
+	if err != nil {
+		return time.Time{}, err
+	}
+	var active time.Time
+	if err := active.UnmarshalText(data); err != nil {
+		return time.Time{}, vterrors.Wrapf(err, "bad binlog dump active time data: %q", data)
+	}
+	return active, nil
+}
+
+// DeleteBinlogDumpStream purges all the binlog files of a stream.
+func (ts *Server) DeleteBinlogDumpStream(ctx context.Context, keyspace, stream string) error {
+	entries, err := ts.globalCell.ListDir(ctx, binlogDumpStreamPath(keyspace, stream), false)
+	switch {
+	case IsErrType(err, NoNode):
+		return nil
+	case err != nil:
+		return err
+	}
+	for _, entry := range entries {
+		err := ts.globalCell.Delete(ctx, path.Join(binlogDumpStreamPath(keyspace, stream), entry.Name), nil)
+		if err != nil && !IsErrType(err, NoNode) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteBinlogDumpPosition purges a binlog file of a stream.
+func (ts *Server) DeleteBinlogDumpPosition(ctx context.Context, keyspace, stream string, seq uint64) error {
+	return ts.globalCell.Delete(ctx, binlogDumpPositionPath(keyspace, stream, seq), nil)
+}
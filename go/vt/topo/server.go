@@ -89,6 +89,7 @@ const (
 	ShardsPath            = "shards"
 	TabletsPath           = "tablets"
 	MetadataPath          = "metadata"
+	BinlogDumpPath        = "binlog_dump"
 	ExternalClusterVitess = "vitess"
 )
 
@@ -61,6 +61,7 @@ import (
 	"vitess.io/vitess/go/vt/vtctl/workflow"
 	"vitess.io/vitess/go/vt/vterrors"
 	"vitess.io/vitess/go/vt/vtgate/vindexes"
+	"vitess.io/vitess/go/vt/vttablet/tabletserver/throttle/base"
 	"vitess.io/vitess/go/vt/vttablet/tmclient"
 
 	logutilpb "vitess.io/vitess/go/vt/proto/logutil"
@@ -1793,6 +1794,13 @@ func (s *VtctldServer) UpdateThrottlerConfig(ctx context.Context, req *vtctldata
 	if req.CheckAsCheckSelf && req.CheckAsCheckShard {
 		return nil, fmt.Errorf("--check-as-check-self and --check-as-check-shard are mutually exclusive")
 	}
+	if req.MetricName != "" {
+		if err := base.ValidateMetricName(req.MetricName); err != nil {
+			return nil, err
+		}
+	} else if req.RemoveMetric {
+		return nil, fmt.Errorf("--remove-metric requires --metric-name")
+	}
 
 	update := func(throttlerConfig *topodatapb.ThrottlerConfig) *topodatapb.ThrottlerConfig {
 		if throttlerConfig == nil {
@@ -1801,14 +1809,46 @@ func (s *VtctldServer) UpdateThrottlerConfig(ctx context.Context, req *vtctldata
 		if throttlerConfig.ThrottledApps == nil {
 			throttlerConfig.ThrottledApps = make(map[string]*topodatapb.ThrottledAppRule)
 		}
-		if req.CustomQuerySet {
-			// custom query provided
-			throttlerConfig.CustomQuery = req.CustomQuery
-			throttlerConfig.Threshold = req.Threshold // allowed to be zero/negative because who knows what kind of custom query this is
-		} else {
-			// no custom query, throttler works by querying replication lag. We only allow positive values
-			if req.Threshold > 0 {
-				throttlerConfig.Threshold = req.Threshold
+		switch {
+		case req.MetricName == "":
+			if req.CustomQuerySet {
+				// custom query provided
+				throttlerConfig.CustomQuery = req.CustomQuery
+				throttlerConfig.Threshold = req.Threshold // allowed to be zero/negative because who knows what kind of custom query this is
+			} else {
+				// no custom query, throttler works by querying replication lag. We only allow positive values
+				if req.Threshold > 0 {
+					throttlerConfig.Threshold = req.Threshold
+				}
+			}
+		case req.RemoveMetric:
+			delete(throttlerConfig.Metrics, req.MetricName)
+		default:
+			if throttlerConfig.Metrics == nil {
+				throttlerConfig.Metrics = make(map[string]*topodatapb.ThrottlerMetric)
+			}
+			metric, ok := throttlerConfig.Metrics[req.MetricName]
+			if !ok {
+				metric = &topodatapb.ThrottlerMetric{}
+				throttlerConfig.Metrics[req.MetricName] = metric
+			}
+			// same as the default metric: a custom query sets any threshold, otherwise only positive values
+			if req.CustomQuerySet {
+				metric.Query = req.CustomQuery
+				metric.Threshold = req.Threshold
+			} else if req.Threshold > 0 {
+				metric.Threshold = req.Threshold
+			}
+		}
+		if req.AppName != "" {
+			if len(req.AppCheckedMetrics) == 0 {
+				// the app checks the default metric
+				delete(throttlerConfig.AppCheckedMetrics, req.AppName)
+			} else {
+				if throttlerConfig.AppCheckedMetrics == nil {
+					throttlerConfig.AppCheckedMetrics = make(map[string]*topodatapb.ThrottlerMetricNames)
+				}
+				throttlerConfig.AppCheckedMetrics[req.AppName] = &topodatapb.ThrottlerMetricNames{Names: req.AppCheckedMetrics}
 			}
 		}
 		if req.Enable {
@@ -1841,6 +1881,9 @@ func (s *VtctldServer) UpdateThrottlerConfig(ctx context.Context, req *vtctldata
 	}
 
 	ki.ThrottlerConfig = update(ki.ThrottlerConfig)
+	if err := validateThrottlerMetrics(ki.ThrottlerConfig); err != nil {
+		return nil, err
+	}
 
 	err = s.ts.UpdateKeyspace(ctx, ki)
 	if err != nil {
@@ -1852,6 +1895,32 @@ func (s *VtctldServer) UpdateThrottlerConfig(ctx context.Context, req *vtctldata
 	return &vtctldatapb.UpdateThrottlerConfigResponse{}, err
 }
 
+// validateThrottlerMetrics validates the named metrics of a throttler config, and the metrics checked by its apps.
+func validateThrottlerMetrics(throttlerConfig *topodatapb.ThrottlerConfig) error {
+	for name, metric := range throttlerConfig.Metrics {
+		if metric.Query != "" {
+			continue
+		}
+		if !base.MetricName(name).IsWellKnown() {
+			return fmt.Errorf("metric %s is not a well known metric and requires a --custom-query", name)
+		}
+		if metric.Threshold <= 0 {
+			return fmt.Errorf("metric %s requires a positive --threshold", name)
+		}
+	}
+	for appName, metricNames := range throttlerConfig.AppCheckedMetrics {
+		for _, name := range metricNames.Names {
+			if name == base.DefaultMetricName.String() {
+				continue
+			}
+			if _, ok := throttlerConfig.Metrics[name]; !ok {
+				return fmt.Errorf("app %s checks metric %s, which is not defined", appName, name)
+			}
+		}
+	}
+	return nil
+}
+
 // GetSrvVSchema is part of the vtctlservicepb.VtctldServer interface.
 func (s *VtctldServer) GetSrvVSchema(ctx context.Context, req *vtctldatapb.GetSrvVSchemaRequest) (resp *vtctldatapb.GetSrvVSchemaResponse, err error) {
 	span, ctx := trace.NewSpan(ctx, "VtctldServer.GetSrvVSchema")
@@ -2509,6 +2578,50 @@ func (s *VtctldServer) LaunchSchemaMigration(ctx context.Context, req *vtctldata
 	return resp, nil
 }
 
+// LookupVindexCreate is part of the vtctlservicepb.VtctldServer interface.
+func (s *VtctldServer) LookupVindexCreate(ctx context.Context, req *vtctldatapb.LookupVindexCreateRequest) (resp *vtctldatapb.LookupVindexCreateResponse, err error) {
+	span, ctx := trace.NewSpan(ctx, "VtctldServer.LookupVindexCreate")
+	defer span.Finish()
+
+	defer panicHandler(&err)
+
+	span.Annotate("keyspace", req.Keyspace)
+	span.Annotate("cells", req.Cells)
+	span.Annotate("tablet_types", req.TabletTypes)
+
+	resp, err = s.ws.LookupVindexCreate(ctx, req)
+	return resp, err
+}
+
+// LookupVindexExternalize is part of the vtctlservicepb.VtctldServer interface.
+func (s *VtctldServer) LookupVindexExternalize(ctx context.Context, req *vtctldatapb.LookupVindexExternalizeRequest) (resp *vtctldatapb.LookupVindexExternalizeResponse, err error) {
+	span, ctx := trace.NewSpan(ctx, "VtctldServer.LookupVindexExternalize")
+	defer span.Finish()
+
+	defer panicHandler(&err)
+
+	span.Annotate("keyspace", req.Keyspace)
+	span.Annotate("name", req.Name)
+	span.Annotate("skip_verify", req.SkipVerify)
+
+	resp, err = s.ws.LookupVindexExternalize(ctx, req)
+	return resp, err
+}
+
+// LookupVindexStatus is part of the vtctlservicepb.VtctldServer interface.
+func (s *VtctldServer) LookupVindexStatus(ctx context.Context, req *vtctldatapb.LookupVindexStatusRequest) (resp *vtctldatapb.LookupVindexStatusResponse, err error) {
+	span, ctx := trace.NewSpan(ctx, "VtctldServer.LookupVindexStatus")
+	defer span.Finish()
+
+	defer panicHandler(&err)
+
+	span.Annotate("keyspace", req.Keyspace)
+	span.Annotate("name", req.Name)
+
+	resp, err = s.ws.LookupVindexStatus(ctx, req)
+	return resp, err
+}
+
 // MoveTablesCreate is part of the vtctlservicepb.VtctldServer interface.
 func (s *VtctldServer) MoveTablesCreate(ctx context.Context, req *vtctldatapb.MoveTablesCreateRequest) (resp *vtctldatapb.WorkflowStatusResponse, err error) {
 	span, ctx := trace.NewSpan(ctx, "VtctldServer.MoveTablesCreate")
@@ -2633,6 +2746,71 @@ func (s *VtctldServer) PlannedReparentShard(ctx context.Context, req *vtctldatap
 	return resp, err
 }
 
+// PruneBackups is part of the vtctlservicepb.VtctldServer interface.
+func (s *VtctldServer) PruneBackups(ctx context.Context, req *vtctldatapb.PruneBackupsRequest) (resp *vtctldatapb.PruneBackupsResponse, err error) {
+	span, ctx := trace.NewSpan(ctx, "VtctldServer.PruneBackups")
+	defer span.Finish()
+
+	defer panicHandler(&err)
+
+	span.Annotate("keyspace", req.Keyspace)
+	span.Annotate("shard", req.Shard)
+	span.Annotate("dry_run", req.DryRun)
+
+	ki, err := s.ts.GetKeyspace(ctx, req.Keyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := ki.BackupRetentionPolicy
+	if mysqlctl.IsBackupRetentionPolicyEmpty(policy) {
+		err = vterrors.Errorf(vtrpcpb.Code_FAILED_PRECONDITION, "keyspace %v has no backup retention policy", req.Keyspace)
+		return nil, err
+	}
+
+	shards := []string{req.Shard}
+	if req.Shard == "" {
+		shards, err = s.ts.GetShardNames(ctx, req.Keyspace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bs, err := backupstorage.GetBackupStorage()
+	if err != nil {
+		return nil, err
+	}
+	defer bs.Close()
+
+	logger := logutil.NewConsoleLogger()
+	resp = &vtctldatapb.PruneBackupsResponse{}
+
+	for _, shard := range shards {
+		bucket := filepath.Join(req.Keyspace, shard)
+
+		bhs, err := bs.ListBackups(ctx, bucket)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, bh := range mysqlctl.BackupsToPrune(ctx, logger, policy, bhs) {
+			if !req.DryRun {
+				if err = bs.RemoveBackup(ctx, bucket, bh.Name()); err != nil {
+					return nil, err
+				}
+			}
+
+			bi := mysqlctlproto.BackupHandleToProto(bh)
+			bi.Keyspace = req.Keyspace
+			bi.Shard = shard
+
+			resp.Backups = append(resp.Backups, bi)
+		}
+	}
+
+	return resp, nil
+}
+
 // RebuildKeyspaceGraph is part of the vtctlservicepb.VtctldServer interface.
 func (s *VtctldServer) RebuildKeyspaceGraph(ctx context.Context, req *vtctldatapb.RebuildKeyspaceGraphRequest) (resp *vtctldatapb.RebuildKeyspaceGraphResponse, err error) {
 	span, ctx := trace.NewSpan(ctx, "VtctldServer.RebuildKeyspaceGraph")
@@ -3150,6 +3328,47 @@ func (s *VtctldServer) RunHealthCheck(ctx context.Context, req *vtctldatapb.RunH
 	return &vtctldatapb.RunHealthCheckResponse{}, nil
 }
 
+// SetKeyspaceBackupRetentionPolicy is part of the vtctlservicepb.VtctldServer interface.
+func (s *VtctldServer) SetKeyspaceBackupRetentionPolicy(ctx context.Context, req *vtctldatapb.SetKeyspaceBackupRetentionPolicyRequest) (resp *vtctldatapb.SetKeyspaceBackupRetentionPolicyResponse, err error) {
+	span, ctx := trace.NewSpan(ctx, "VtctldServer.SetKeyspaceBackupRetentionPolicy")
+	defer span.Finish()
+
+	defer panicHandler(&err)
+
+	span.Annotate("keyspace", req.Keyspace)
+	span.Annotate("daily", req.BackupRetentionPolicy.GetDaily())
+	span.Annotate("weekly", req.BackupRetentionPolicy.GetWeekly())
+	span.Annotate("monthly", req.BackupRetentionPolicy.GetMonthly())
+
+	ctx, unlock, lockErr := s.ts.LockKeyspace(ctx, req.Keyspace, "SetKeyspaceBackupRetentionPolicy")
+	if lockErr != nil {
+		err = lockErr
+		return nil, err
+	}
+
+	defer unlock(&err)
+
+	ki, err := s.ts.GetKeyspace(ctx, req.Keyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if mysqlctl.IsBackupRetentionPolicyEmpty(req.BackupRetentionPolicy) {
+		ki.BackupRetentionPolicy = nil
+	} else {
+		ki.BackupRetentionPolicy = req.BackupRetentionPolicy
+	}
+
+	err = s.ts.UpdateKeyspace(ctx, ki)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vtctldatapb.SetKeyspaceBackupRetentionPolicyResponse{
+		Keyspace: ki.Keyspace,
+	}, nil
+}
+
 // SetKeyspaceDurabilityPolicy is part of the vtctlservicepb.VtctldServer interface.
 func (s *VtctldServer) SetKeyspaceDurabilityPolicy(ctx context.Context, req *vtctldatapb.SetKeyspaceDurabilityPolicyRequest) (resp *vtctldatapb.SetKeyspaceDurabilityPolicyResponse, err error) {
 	span, ctx := trace.NewSpan(ctx, "VtctldServer.SetKeyspaceDurabilityPolicy")
@@ -4688,6 +4907,62 @@ func (s *VtctldServer) ValidateVSchema(ctx context.Context, req *vtctldatapb.Val
 	return resp, err
 }
 
+// VerifyBackup is part of the vtctlservicepb.VtctldServer interface.
+func (s *VtctldServer) VerifyBackup(req *vtctldatapb.VerifyBackupRequest, stream vtctlservicepb.Vtctld_VerifyBackupServer) (err error) {
+	span, ctx := trace.NewSpan(stream.Context(), "VtctldServer.VerifyBackup")
+	defer span.Finish()
+
+	defer panicHandler(&err)
+
+	span.Annotate("tablet_alias", topoproto.TabletAliasString(req.TabletAlias))
+	span.Annotate("backup_name", req.BackupName)
+	span.Annotate("concurrency", req.Concurrency)
+
+	ti, err := s.ts.GetTablet(ctx, req.TabletAlias)
+	if err != nil {
+		return err
+	}
+
+	span.Annotate("keyspace", ti.Keyspace)
+	span.Annotate("shard", ti.Shard)
+
+	r := &tabletmanagerdatapb.VerifyBackupRequest{
+		BackupName:  req.BackupName,
+		Concurrency: int64(req.Concurrency),
+	}
+	verifyStream, err := s.tmc.VerifyBackup(ctx, ti.Tablet, r)
+	if err != nil {
+		return err
+	}
+
+	logger := logutil.NewConsoleLogger()
+
+	for {
+		var tmResp *tabletmanagerdatapb.VerifyBackupResponse
+		tmResp, err = verifyStream.Recv()
+		switch err {
+		case nil:
+			if tmResp.Event != nil {
+				logutil.LogEvent(logger, tmResp.Event)
+			}
+			resp := &vtctldatapb.VerifyBackupResponse{
+				TabletAlias:  req.TabletAlias,
+				Keyspace:     ti.Keyspace,
+				Shard:        ti.Shard,
+				Event:        tmResp.Event,
+				Verification: tmResp.Verification,
+			}
+			if err = stream.Send(resp); err != nil {
+				logger.Errorf("failed to send stream response %+v: %v", resp, err)
+			}
+		case io.EOF:
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
 // WorkflowDelete is part of the vtctlservicepb.VtctldServer interface.
 func (s *VtctldServer) WorkflowDelete(ctx context.Context, req *vtctldatapb.WorkflowDeleteRequest) (resp *vtctldatapb.WorkflowDeleteResponse, err error) {
 	span, ctx := trace.NewSpan(ctx, "VtctldServer.WorkflowDelete")
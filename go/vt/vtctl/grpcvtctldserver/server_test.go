@@ -7479,6 +7479,121 @@ func TestPlannedReparentShard(t *testing.T) {
 	}
 }
 
+func TestPruneBackups(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ts := memorytopo.NewServer(ctx)
+	testutil.AddKeyspace(ctx, t, ts, &vtctldatapb.Keyspace{
+		Name: "testkeyspace",
+		Keyspace: &topodatapb.Keyspace{
+			BackupRetentionPolicy: &topodatapb.BackupRetentionPolicy{Daily: 1},
+		},
+	})
+	testutil.AddKeyspace(ctx, t, ts, &vtctldatapb.Keyspace{
+		Name:     "nopolicy",
+		Keyspace: &topodatapb.Keyspace{},
+	})
+	testutil.AddShards(ctx, t, ts, &vtctldatapb.Shard{
+		Keyspace: "testkeyspace",
+		Name:     "-80",
+	}, &vtctldatapb.Shard{
+		Keyspace: "testkeyspace",
+		Name:     "80-",
+	})
+	vtctld := testutil.NewVtctldServerWithTabletManagerClient(t, ts, nil, func(ts *topo.Server) vtctlservicepb.VtctldServer {
+		return NewVtctldServer(ts)
+	})
+
+	setup := func() {
+		testutil.BackupStorage.Backups = map[string][]string{
+			"testkeyspace/-80": {"backup1", "backup2", "backup3"},
+			"testkeyspace/80-": {"backup1", "backup2"},
+		}
+		testutil.BackupStorage.Manifests = map[string][]byte{
+			"testkeyspace/-80/backup1": []byte(`{"BackupTime": "2023-07-31T10:00:00Z"}`),
+			"testkeyspace/-80/backup2": []byte(`{"BackupTime": "2023-08-01T10:00:00Z"}`),
+			"testkeyspace/80-/backup1": []byte(`{"BackupTime": "2023-07-31T10:00:00Z"}`),
+			"testkeyspace/80-/backup2": []byte(`{"BackupTime": "2023-08-01T10:00:00Z"}`),
+		}
+	}
+	defer func() { testutil.BackupStorage.Manifests = nil }()
+
+	t.Run("ok", func(t *testing.T) {
+		setup()
+		resp, err := vtctld.PruneBackups(ctx, &vtctldatapb.PruneBackupsRequest{
+			Keyspace: "testkeyspace",
+		})
+		require.NoError(t, err)
+
+		expected := &vtctldatapb.PruneBackupsResponse{
+			Backups: []*mysqlctlpb.BackupInfo{
+				{
+					Directory: "testkeyspace/-80",
+					Name:      "backup1",
+					Keyspace:  "testkeyspace",
+					Shard:     "-80",
+				},
+				{
+					Directory: "testkeyspace/80-",
+					Name:      "backup1",
+					Keyspace:  "testkeyspace",
+					Shard:     "80-",
+				},
+			},
+		}
+		utils.MustMatch(t, expected, resp)
+		// backup3 has no MANIFEST, and may be in progress.
+		utils.MustMatch(t, map[string][]string{
+			"testkeyspace/-80": {"backup2", "backup3"},
+			"testkeyspace/80-": {"backup2"},
+		}, testutil.BackupStorage.Backups)
+	})
+
+	t.Run("shard", func(t *testing.T) {
+		setup()
+		resp, err := vtctld.PruneBackups(ctx, &vtctldatapb.PruneBackupsRequest{
+			Keyspace: "testkeyspace",
+			Shard:    "80-",
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Backups, 1)
+		assert.Equal(t, "testkeyspace/80-", resp.Backups[0].Directory)
+		assert.Len(t, testutil.BackupStorage.Backups["testkeyspace/-80"], 3)
+		assert.Len(t, testutil.BackupStorage.Backups["testkeyspace/80-"], 1)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		setup()
+		resp, err := vtctld.PruneBackups(ctx, &vtctldatapb.PruneBackupsRequest{
+			Keyspace: "testkeyspace",
+			DryRun:   true,
+		})
+		require.NoError(t, err)
+		assert.Len(t, resp.Backups, 2)
+		assert.Len(t, testutil.BackupStorage.Backups["testkeyspace/-80"], 3)
+		assert.Len(t, testutil.BackupStorage.Backups["testkeyspace/80-"], 2)
+	})
+
+	t.Run("no retention policy", func(t *testing.T) {
+		setup()
+		_, err := vtctld.PruneBackups(ctx, &vtctldatapb.PruneBackupsRequest{
+			Keyspace: "nopolicy",
+		})
+		assert.EqualError(t, err, "keyspace nopolicy has no backup retention policy")
+	})
+
+	t.Run("listbackups error", func(t *testing.T) {
+		setup()
+		testutil.BackupStorage.ListBackupsError = assert.AnError
+		defer func() { testutil.BackupStorage.ListBackupsError = nil }()
+
+		_, err := vtctld.PruneBackups(ctx, &vtctldatapb.PruneBackupsRequest{
+			Keyspace: "testkeyspace",
+		})
+		assert.Error(t, err)
+	})
+}
+
 func TestRebuildKeyspaceGraph(t *testing.T) {
 	t.Parallel()
 
@@ -9824,6 +9939,95 @@ func TestRunHealthCheck(t *testing.T) {
 	}
 }
 
+func TestSetKeyspaceBackupRetentionPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		keyspaces   []*vtctldatapb.Keyspace
+		req         *vtctldatapb.SetKeyspaceBackupRetentionPolicyRequest
+		expected    *vtctldatapb.SetKeyspaceBackupRetentionPolicyResponse
+		expectedErr string
+	}{
+		{
+			name: "ok",
+			keyspaces: []*vtctldatapb.Keyspace{
+				{
+					Name:     "ks1",
+					Keyspace: &topodatapb.Keyspace{},
+				},
+			},
+			req: &vtctldatapb.SetKeyspaceBackupRetentionPolicyRequest{
+				Keyspace: "ks1",
+				BackupRetentionPolicy: &topodatapb.BackupRetentionPolicy{
+					Daily:   7,
+					Monthly: 6,
+				},
+			},
+			expected: &vtctldatapb.SetKeyspaceBackupRetentionPolicyResponse{
+				Keyspace: &topodatapb.Keyspace{
+					BackupRetentionPolicy: &topodatapb.BackupRetentionPolicy{
+						Daily:   7,
+						Monthly: 6,
+					},
+				},
+			},
+		},
+		{
+			name: "empty policy removes the policy",
+			keyspaces: []*vtctldatapb.Keyspace{
+				{
+					Name: "ks1",
+					Keyspace: &topodatapb.Keyspace{
+						BackupRetentionPolicy: &topodatapb.BackupRetentionPolicy{
+							Weekly: 4,
+						},
+					},
+				},
+			},
+			req: &vtctldatapb.SetKeyspaceBackupRetentionPolicyRequest{
+				Keyspace:              "ks1",
+				BackupRetentionPolicy: &topodatapb.BackupRetentionPolicy{},
+			},
+			expected: &vtctldatapb.SetKeyspaceBackupRetentionPolicyResponse{
+				Keyspace: &topodatapb.Keyspace{},
+			},
+		},
+		{
+			name: "keyspace not found",
+			req: &vtctldatapb.SetKeyspaceBackupRetentionPolicyRequest{
+				Keyspace: "ks1",
+			},
+			expectedErr: "node doesn't exist: keyspaces/ks1",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			ts := memorytopo.NewServer(ctx, "zone1")
+			testutil.AddKeyspaces(ctx, t, ts, tt.keyspaces...)
+
+			vtctld := testutil.NewVtctldServerWithTabletManagerClient(t, ts, nil, func(ts *topo.Server) vtctlservicepb.VtctldServer {
+				return NewVtctldServer(ts)
+			})
+			resp, err := vtctld.SetKeyspaceBackupRetentionPolicy(ctx, tt.req)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			utils.MustMatch(t, tt.expected, resp)
+		})
+	}
+}
+
 func TestSetKeyspaceDurabilityPolicy(t *testing.T) {
 	t.Parallel()
 
@@ -12149,6 +12353,148 @@ func TestUpdateCellsAlias(t *testing.T) {
 	}
 }
 
+func TestUpdateThrottlerConfig(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ts := memorytopo.NewServer(ctx, "zone1")
+	testutil.AddKeyspace(ctx, t, ts, &vtctldatapb.Keyspace{
+		Name:     "ks",
+		Keyspace: &topodatapb.Keyspace{},
+	})
+	vtctld := testutil.NewVtctldServerWithTabletManagerClient(t, ts, nil, func(ts *topo.Server) vtctlservicepb.VtctldServer {
+		return NewVtctldServer(ts)
+	})
+
+	tests := []struct {
+		name        string
+		req         *vtctldatapb.UpdateThrottlerConfigRequest
+		expected    *topodatapb.ThrottlerConfig
+		expectedErr string
+	}{
+		{
+			name: "default metric",
+			req:  &vtctldatapb.UpdateThrottlerConfigRequest{Enable: true, Threshold: 2},
+			expected: &topodatapb.ThrottlerConfig{
+				Enabled:       true,
+				Threshold:     2,
+				ThrottledApps: map[string]*topodatapb.ThrottledAppRule{},
+			},
+		},
+		{
+			name: "well known metric",
+			req:  &vtctldatapb.UpdateThrottlerConfigRequest{MetricName: "threads_running", Threshold: 100},
+			expected: &topodatapb.ThrottlerConfig{
+				Enabled:       true,
+				Threshold:     2,
+				ThrottledApps: map[string]*topodatapb.ThrottledAppRule{},
+				Metrics: map[string]*topodatapb.ThrottlerMetric{
+					"threads_running": {Threshold: 100},
+				},
+			},
+		},
+		{
+			name:        "well known metric without threshold",
+			req:         &vtctldatapb.UpdateThrottlerConfigRequest{MetricName: "history_list_length"},
+			expectedErr: "metric history_list_length requires a positive --threshold",
+		},
+		{
+			name:        "custom metric without query",
+			req:         &vtctldatapb.UpdateThrottlerConfigRequest{MetricName: "queue_size", Threshold: 10},
+			expectedErr: "metric queue_size is not a well known metric and requires a --custom-query",
+		},
+		{
+			name:        "reserved metric name",
+			req:         &vtctldatapb.UpdateThrottlerConfigRequest{MetricName: "default", Threshold: 10},
+			expectedErr: `metric name "default" is reserved`,
+		},
+		{
+			name: "custom metric",
+			req:  &vtctldatapb.UpdateThrottlerConfigRequest{MetricName: "queue_size", CustomQuery: "select count(*) from queue", CustomQuerySet: true, Threshold: 10},
+			expected: &topodatapb.ThrottlerConfig{
+				Enabled:       true,
+				Threshold:     2,
+				ThrottledApps: map[string]*topodatapb.ThrottledAppRule{},
+				Metrics: map[string]*topodatapb.ThrottlerMetric{
+					"threads_running": {Threshold: 100},
+					"queue_size":      {Query: "select count(*) from queue", Threshold: 10},
+				},
+			},
+		},
+		{
+			name:        "app checks undefined metric",
+			req:         &vtctldatapb.UpdateThrottlerConfigRequest{AppName: "online-ddl", AppCheckedMetrics: []string{"lag"}},
+			expectedErr: "app online-ddl checks metric lag, which is not defined",
+		},
+		{
+			name: "app checked metrics",
+			req:  &vtctldatapb.UpdateThrottlerConfigRequest{AppName: "online-ddl", AppCheckedMetrics: []string{"default", "threads_running"}},
+			expected: &topodatapb.ThrottlerConfig{
+				Enabled:       true,
+				Threshold:     2,
+				ThrottledApps: map[string]*topodatapb.ThrottledAppRule{},
+				Metrics: map[string]*topodatapb.ThrottlerMetric{
+					"threads_running": {Threshold: 100},
+					"queue_size":      {Query: "select count(*) from queue", Threshold: 10},
+				},
+				AppCheckedMetrics: map[string]*topodatapb.ThrottlerMetricNames{
+					"online-ddl": {Names: []string{"default", "threads_running"}},
+				},
+			},
+		},
+		{
+			name:        "remove checked metric",
+			req:         &vtctldatapb.UpdateThrottlerConfigRequest{MetricName: "threads_running", RemoveMetric: true},
+			expectedErr: "app online-ddl checks metric threads_running, which is not defined",
+		},
+		{
+			name: "remove metric",
+			req:  &vtctldatapb.UpdateThrottlerConfigRequest{MetricName: "queue_size", RemoveMetric: true},
+			expected: &topodatapb.ThrottlerConfig{
+				Enabled:       true,
+				Threshold:     2,
+				ThrottledApps: map[string]*topodatapb.ThrottledAppRule{},
+				Metrics: map[string]*topodatapb.ThrottlerMetric{
+					"threads_running": {Threshold: 100},
+				},
+				AppCheckedMetrics: map[string]*topodatapb.ThrottlerMetricNames{
+					"online-ddl": {Names: []string{"default", "threads_running"}},
+				},
+			},
+		},
+		{
+			name: "reset app checked metrics",
+			req:  &vtctldatapb.UpdateThrottlerConfigRequest{AppName: "online-ddl"},
+			expected: &topodatapb.ThrottlerConfig{
+				Enabled:       true,
+				Threshold:     2,
+				ThrottledApps: map[string]*topodatapb.ThrottledAppRule{},
+				Metrics: map[string]*topodatapb.ThrottlerMetric{
+					"threads_running": {Threshold: 100},
+				},
+				AppCheckedMetrics: map[string]*topodatapb.ThrottlerMetricNames{},
+			},
+		},
+	}
+
+	// The requests apply in order, each to the throttler config left by the previous ones
+	for _, tt := range tests {
+		tt.req.Keyspace = "ks"
+		_, err := vtctld.UpdateThrottlerConfig(ctx, tt.req)
+		if tt.expectedErr != "" {
+			assert.EqualError(t, err, tt.expectedErr, tt.name)
+			continue
+		}
+		require.NoError(t, err, tt.name)
+
+		ki, err := ts.GetKeyspace(ctx, "ks")
+		require.NoError(t, err, tt.name)
+		utils.MustMatch(t, tt.expected, ki.ThrottlerConfig, tt.name)
+	}
+}
+
 func TestValidate(t *testing.T) {
 	t.Parallel()
 
@@ -13266,6 +13612,134 @@ func TestValidateShard(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyBackup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	verification := &tabletmanagerdatapb.BackupVerification{
+		Passed:   false,
+		Failures: []string{"table vt_ks.t1 is missing"},
+	}
+	tests := []struct {
+		name      string
+		tmc       *testutil.TabletManagerClient
+		req       *vtctldatapb.VerifyBackupRequest
+		expected  []*vtctldatapb.VerifyBackupResponse
+		shouldErr bool
+	}{
+		{
+			name: "ok",
+			tmc: &testutil.TabletManagerClient{
+				VerifyBackupResults: map[string]struct {
+					Events       []*logutilpb.Event
+					Verification *tabletmanagerdatapb.BackupVerification
+					Error        error
+				}{
+					"zone1-0000000100": {
+						Events:       []*logutilpb.Event{{Value: "restoring"}, {Value: "checksumming"}},
+						Verification: verification,
+					},
+				},
+			},
+			req: &vtctldatapb.VerifyBackupRequest{
+				TabletAlias: &topodatapb.TabletAlias{Cell: "zone1", Uid: 100},
+				BackupName:  "2023-08-01.120000.zone1-0000000100",
+			},
+			expected: []*vtctldatapb.VerifyBackupResponse{
+				{
+					TabletAlias: &topodatapb.TabletAlias{Cell: "zone1", Uid: 100},
+					Keyspace:    "ks",
+					Shard:       "-",
+					Event:       &logutilpb.Event{Value: "restoring"},
+				},
+				{
+					TabletAlias: &topodatapb.TabletAlias{Cell: "zone1", Uid: 100},
+					Keyspace:    "ks",
+					Shard:       "-",
+					Event:       &logutilpb.Event{Value: "checksumming"},
+				},
+				{
+					TabletAlias:  &topodatapb.TabletAlias{Cell: "zone1", Uid: 100},
+					Keyspace:     "ks",
+					Shard:        "-",
+					Verification: verification,
+				},
+			},
+		},
+		{
+			name: "verification error",
+			tmc: &testutil.TabletManagerClient{
+				VerifyBackupResults: map[string]struct {
+					Events       []*logutilpb.Event
+					Verification *tabletmanagerdatapb.BackupVerification
+					Error        error
+				}{
+					"zone1-0000000100": {
+						Events: []*logutilpb.Event{{Value: "restoring"}},
+						Error:  assert.AnError,
+					},
+				},
+			},
+			req: &vtctldatapb.VerifyBackupRequest{
+				TabletAlias: &topodatapb.TabletAlias{Cell: "zone1", Uid: 100},
+				BackupName:  "2023-08-01.120000.zone1-0000000100",
+			},
+			expected: []*vtctldatapb.VerifyBackupResponse{
+				{
+					TabletAlias: &topodatapb.TabletAlias{Cell: "zone1", Uid: 100},
+					Keyspace:    "ks",
+					Shard:       "-",
+					Event:       &logutilpb.Event{Value: "restoring"},
+				},
+			},
+			shouldErr: true,
+		},
+		{
+			name: "no such tablet",
+			tmc:  &testutil.TabletManagerClient{},
+			req: &vtctldatapb.VerifyBackupRequest{
+				TabletAlias: &topodatapb.TabletAlias{Cell: "zone404", Uid: 404},
+				BackupName:  "2023-08-01.120000.zone1-0000000100",
+			},
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := memorytopo.NewServer(ctx, "zone1")
+			testutil.AddTablets(ctx, t, ts, nil, &topodatapb.Tablet{
+				Alias:    &topodatapb.TabletAlias{Cell: "zone1", Uid: 100},
+				Keyspace: "ks",
+				Shard:    "-",
+				Type:     topodatapb.TabletType_REPLICA,
+			})
+			vtctld := testutil.NewVtctldServerWithTabletManagerClient(t, ts, tt.tmc, func(ts *topo.Server) vtctlservicepb.VtctldServer {
+				return NewVtctldServer(ts)
+			})
+			client := localvtctldclient.New(vtctld)
+			stream, err := client.VerifyBackup(ctx, tt.req)
+			require.NoError(t, err)
+
+			var responses []*vtctldatapb.VerifyBackupResponse
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF {
+					assert.False(t, tt.shouldErr, "expected the stream to end with an error")
+					break
+				}
+				if err != nil {
+					assert.True(t, tt.shouldErr, "unexpected error: %v", err)
+					break
+				}
+				responses = append(responses, resp)
+			}
+			utils.MustMatch(t, tt.expected, responses)
+		})
+	}
+}
+
 func TestMain(m *testing.M) {
 	_flag.ParseFlagsForTest()
 	os.Exit(m.Run())
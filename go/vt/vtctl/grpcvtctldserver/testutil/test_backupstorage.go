@@ -17,8 +17,11 @@ limitations under the License.
 package testutil
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"path"
 	"sort"
 
 	"vitess.io/vitess/go/vt/mysqlctl/backupstorage"
@@ -30,6 +33,9 @@ type backupStorage struct {
 	// Backups is a mapping of directory to list of backup names stored in that
 	// directory.
 	Backups map[string][]string
+	// Manifests is a mapping of "<directory>/<name>" to the MANIFEST file of
+	// the backup. Reading the MANIFEST of other backups fails.
+	Manifests map[string][]byte
 	// ListBackupsError is returned from ListBackups when it is non-nil.
 	ListBackupsError error
 }
@@ -45,7 +51,7 @@ func (bs *backupStorage) ListBackups(ctx context.Context, dir string) ([]backups
 	for k, v := range bs.Backups {
 		if k == dir {
 			for _, name := range v {
-				handles = append(handles, &backupHandle{directory: k, name: name})
+				handles = append(handles, &backupHandle{directory: k, name: name, manifest: bs.Manifests[path.Join(k, name)]})
 			}
 		}
 	}
@@ -87,11 +93,22 @@ type backupHandle struct {
 
 	directory string
 	name      string
+	manifest  []byte
 }
 
 func (bh *backupHandle) Directory() string { return bh.directory }
 func (bh *backupHandle) Name() string      { return bh.name }
 
+// ReadFile is part of the backupstorage.BackupHandle interface. Only the
+// MANIFEST file can be read.
+func (bh *backupHandle) ReadFile(ctx context.Context, filename string) (io.ReadCloser, error) {
+	if filename != "MANIFEST" || bh.manifest == nil {
+		return nil, fmt.Errorf("no file %s in backup %s/%s", filename, bh.directory, bh.name)
+	}
+
+	return io.NopCloser(bytes.NewReader(bh.manifest)), nil
+}
+
 // handlesByName implements the sort interface for backup handles by Name().
 type handlesByName []backupstorage.BackupHandle
 
@@ -19,6 +19,7 @@ package testutil
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"sync"
@@ -347,6 +348,13 @@ type TabletManagerClient struct {
 		Result *querypb.QueryResult
 		Error  error
 	}
+	// keyed by tablet alias. The verification is sent after the events, and
+	// the error after the verification.
+	VerifyBackupResults map[string]struct {
+		Events       []*logutilpb.Event
+		Verification *tabletmanagerdatapb.BackupVerification
+		Error        error
+	}
 	// keyed by tablet alias.
 	WaitForPositionDelays map[string]time.Duration
 	// keyed by tablet alias. injects a sleep to the end of the function
@@ -1393,6 +1401,41 @@ func (fake *TabletManagerClient) VReplicationExec(ctx context.Context, tablet *t
 	return nil, assert.AnError
 }
 
+type verifyBackupStream struct {
+	responses []*tabletmanagerdatapb.VerifyBackupResponse
+	err       error
+}
+
+func (stream *verifyBackupStream) Recv() (*tabletmanagerdatapb.VerifyBackupResponse, error) {
+	if len(stream.responses) == 0 {
+		if stream.err != nil {
+			return nil, stream.err
+		}
+		return nil, io.EOF
+	}
+	resp := stream.responses[0]
+	stream.responses = stream.responses[1:]
+	return resp, nil
+}
+
+// VerifyBackup is part of the tmclient.TabletManagerClient interface.
+func (fake *TabletManagerClient) VerifyBackup(ctx context.Context, tablet *topodatapb.Tablet, req *tabletmanagerdatapb.VerifyBackupRequest) (tmclient.VerifyBackupStream, error) {
+	key := topoproto.TabletAliasString(tablet.Alias)
+	testdata, ok := fake.VerifyBackupResults[key]
+	if !ok {
+		return nil, fmt.Errorf("no VerifyBackup fake result set for %s", key)
+	}
+
+	stream := &verifyBackupStream{err: testdata.Error}
+	for _, event := range testdata.Events {
+		stream.responses = append(stream.responses, &tabletmanagerdatapb.VerifyBackupResponse{Event: event})
+	}
+	if testdata.Verification != nil {
+		stream.responses = append(stream.responses, &tabletmanagerdatapb.VerifyBackupResponse{Verification: testdata.Verification})
+	}
+	return stream, nil
+}
+
 // CheckThrottler is part of the tmclient.TabletManagerCLient interface.
 func (fake *TabletManagerClient) CheckThrottler(ctx context.Context, tablet *topodatapb.Tablet, req *tabletmanagerdatapb.CheckThrottlerRequest) (*tabletmanagerdatapb.CheckThrottlerResponse, error) {
 	if fake.CheckThrottlerResults == nil {
@@ -0,0 +1,740 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+
+	"vitess.io/vitess/go/protoutil"
+	"vitess.io/vitess/go/sqlescape"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/trace"
+	"vitess.io/vitess/go/vt/schema"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/topo"
+	"vitess.io/vitess/go/vt/topo/topoproto"
+	"vitess.io/vitess/go/vt/vtctl/schematools"
+	"vitess.io/vitess/go/vt/vterrors"
+	"vitess.io/vitess/go/vt/vtgate/vindexes"
+	"vitess.io/vitess/go/vt/vttablet/tmclient"
+
+	binlogdatapb "vitess.io/vitess/go/vt/proto/binlogdata"
+	tabletmanagerdatapb "vitess.io/vitess/go/vt/proto/tabletmanagerdata"
+	vschemapb "vitess.io/vitess/go/vt/proto/vschema"
+	vtctldatapb "vitess.io/vitess/go/vt/proto/vtctldata"
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+)
+
+// The states of a lookup vindex reported by LookupVindexStatus.
+const (
+	lookupVindexCopying      = "Copying"
+	lookupVindexCopied       = "Copied"
+	lookupVindexError        = "Error"
+	lookupVindexExternalized = "Externalized"
+)
+
+const (
+	sqlLookupVindexStreams = "select vr.id, vr.state, vr.message, vr.rows_copied," +
+		" (select count(*) from _vt.copy_state cs where cs.vrepl_id = vr.id) as tables_copying," +
+		" (select ifnull(unix_timestamp(min(vl.created_at)), 0) from _vt.vreplication_log vl where vl.vrepl_id = vr.id) as time_started," +
+		" vr.source" +
+		" from _vt.vreplication vr where vr.db_name = %s and vr.workflow = %s"
+	sqlLookupVindexTableRows = "select table_rows from information_schema.tables where table_schema = %s and table_name = %s"
+)
+
+// LookupVindexCreate is part of the vtctlservicepb.VtctldServer interface.
+// It adds a write only lookup vindex to the vschema and creates the workflow
+// that backfills its lookup table.
+func (s *Server) LookupVindexCreate(ctx context.Context, req *vtctldatapb.LookupVindexCreateRequest) (*vtctldatapb.LookupVindexCreateResponse, error) {
+	span, ctx := trace.NewSpan(ctx, "workflow.Server.LookupVindexCreate")
+	defer span.Finish()
+
+	span.Annotate("keyspace", req.Keyspace)
+	span.Annotate("cells", req.Cells)
+	span.Annotate("tablet_types", req.TabletTypes)
+
+	ms, sourceVSchema, targetVSchema, err := PrepareCreateLookup(ctx, s.ts, s.tmc, req.Keyspace, req.Vindex, req.ContinueAfterCopyWithOwner)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ts.SaveVSchema(ctx, ms.TargetKeyspace, targetVSchema); err != nil {
+		return nil, err
+	}
+	ms.Cell = strings.Join(req.Cells, ",")
+	ms.TabletTypes = topoproto.MakeStringTypeCSV(req.TabletTypes)
+	ms.TabletSelectionPreference = req.TabletSelectionPreference
+	if err := Materialize(ctx, s.ts, s.tmc, ms); err != nil {
+		return nil, err
+	}
+	if err := s.ts.SaveVSchema(ctx, req.Keyspace, sourceVSchema); err != nil {
+		return nil, err
+	}
+	if err := s.ts.RebuildSrvVSchema(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	return &vtctldatapb.LookupVindexCreateResponse{Workflow: ms.Workflow}, nil
+}
+
+// LookupVindexStatus is part of the vtctlservicepb.VtctldServer interface.
+// It reports the progress of the backfill of a lookup vindex, which is Copied
+// once the vindex can be externalized.
+func (s *Server) LookupVindexStatus(ctx context.Context, req *vtctldatapb.LookupVindexStatusRequest) (*vtctldatapb.LookupVindexStatusResponse, error) {
+	span, ctx := trace.NewSpan(ctx, "workflow.Server.LookupVindexStatus")
+	defer span.Finish()
+
+	span.Annotate("keyspace", req.Keyspace)
+	span.Annotate("name", req.Name)
+
+	sourceVSchema, vindex, targetKeyspace, targetTableName, err := s.getLookupVindex(ctx, req.Keyspace, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	resp := &vtctldatapb.LookupVindexStatusResponse{Workflow: targetTableName + "_vdx"}
+	targetShards, err := s.ts.GetServingShards(ctx, targetKeyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu            sync.Mutex
+		streams       int
+		copying       bool
+		errorMessages []string
+		timeStarted   int64
+	)
+	err = forAllShards(targetShards, func(targetShard *topo.ShardInfo) error {
+		targetPrimary, err := s.ts.GetTablet(ctx, targetShard.PrimaryAlias)
+		if err != nil {
+			return err
+		}
+		query := fmt.Sprintf(sqlLookupVindexStreams, encodeString(targetPrimary.DbName()), encodeString(resp.Workflow))
+		p3qr, err := s.tmc.ExecuteFetchAsDba(ctx, targetPrimary.Tablet, true, &tabletmanagerdatapb.ExecuteFetchAsDbaRequest{
+			Query:   []byte(query),
+			MaxRows: 1000,
+		})
+		if err != nil {
+			return err
+		}
+		qr := sqltypes.Proto3ToResult(p3qr)
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, row := range qr.Rows {
+			id, err := row[0].ToCastInt64()
+			if err != nil {
+				return err
+			}
+			rowsCopied, err := row[3].ToCastInt64()
+			if err != nil {
+				return err
+			}
+			tablesCopying, err := row[4].ToCastInt64()
+			if err != nil {
+				return err
+			}
+			started, err := row[5].ToCastInt64()
+			if err != nil {
+				return err
+			}
+			var bls binlogdatapb.BinlogSource
+			sourceBytes, err := row[6].ToBytes()
+			if err != nil {
+				return err
+			}
+			if err := prototext.Unmarshal(sourceBytes, &bls); err != nil {
+				return err
+			}
+			state := binlogdatapb.VReplicationWorkflowState(binlogdatapb.VReplicationWorkflowState_value[row[1].ToString()])
+			streams++
+			resp.RowsCopied += rowsCopied
+			if tablesCopying > 0 || !lookupVindexStreamCopied(vindex.Owner != "", &bls, state, row[2].ToString()) {
+				copying = true
+			}
+			if state == binlogdatapb.VReplicationWorkflowState_Error {
+				errorMessages = append(errorMessages, fmt.Sprintf("stream %d on %s/%s: %s", id, targetShard.Keyspace(), targetShard.ShardName(), row[2].ToString()))
+			}
+			if started > 0 && (timeStarted == 0 || started < timeStarted) {
+				timeStarted = started
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case vindex.Params["write_only"] != "true":
+		resp.State = lookupVindexExternalized
+	case streams == 0:
+		return nil, vterrors.Errorf(vtrpcpb.Code_NOT_FOUND, "workflow %s not found in keyspace %s", resp.Workflow, targetKeyspace)
+	case len(errorMessages) > 0:
+		sort.Strings(errorMessages)
+		resp.State = lookupVindexError
+		resp.Message = strings.Join(errorMessages, "; ")
+	case copying:
+		resp.State = lookupVindexCopying
+	default:
+		resp.State = lookupVindexCopied
+	}
+
+	if ownerTable := lookupVindexOwnerTable(sourceVSchema, req.Name); ownerTable != "" {
+		if resp.RowsTotal, err = s.getTableRows(ctx, req.Keyspace, ownerTable); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case resp.State == lookupVindexCopied || resp.State == lookupVindexExternalized:
+		resp.RowsPercentage = 100
+	case resp.RowsTotal > 0:
+		resp.RowsPercentage = min(100, float32(resp.RowsCopied)*100/float32(resp.RowsTotal))
+	}
+	if resp.State == lookupVindexCopying && timeStarted > 0 && resp.RowsCopied > 0 && resp.RowsTotal > resp.RowsCopied {
+		now := time.Now()
+		elapsed := now.Sub(time.Unix(timeStarted, 0))
+		if elapsed > 0 {
+			remaining := time.Duration(float64(elapsed) * float64(resp.RowsTotal-resp.RowsCopied) / float64(resp.RowsCopied))
+			resp.EstimatedCompletion = protoutil.TimeToProto(now.Add(remaining))
+		}
+	}
+	return resp, nil
+}
+
+// getTableRows returns the estimated number of rows of a table in a keyspace,
+// from the table statistics of its primaries.
+func (s *Server) getTableRows(ctx context.Context, keyspace, table string) (int64, error) {
+	shards, err := s.ts.GetServingShards(ctx, keyspace)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		mu   sync.Mutex
+		rows int64
+	)
+	err = forAllShards(shards, func(shard *topo.ShardInfo) error {
+		primary, err := s.ts.GetTablet(ctx, shard.PrimaryAlias)
+		if err != nil {
+			return err
+		}
+		query := fmt.Sprintf(sqlLookupVindexTableRows, encodeString(primary.DbName()), encodeString(table))
+		p3qr, err := s.tmc.ExecuteFetchAsDba(ctx, primary.Tablet, true, &tabletmanagerdatapb.ExecuteFetchAsDbaRequest{
+			Query:   []byte(query),
+			MaxRows: 1,
+		})
+		if err != nil {
+			return err
+		}
+		qr := sqltypes.Proto3ToResult(p3qr)
+		if len(qr.Rows) == 0 {
+			return nil
+		}
+		n, err := qr.Rows[0][0].ToCastInt64()
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		rows += n
+		return nil
+	})
+	return rows, err
+}
+
+// LookupVindexExternalize is part of the vtctlservicepb.VtctldServer
+// interface. It externalizes a lookup vindex that's finished backfilling or
+// has caught up, once its lookup table has been verified against the owner
+// table unless SkipVerify is set.
+func (s *Server) LookupVindexExternalize(ctx context.Context, req *vtctldatapb.LookupVindexExternalizeRequest) (*vtctldatapb.LookupVindexExternalizeResponse, error) {
+	span, ctx := trace.NewSpan(ctx, "workflow.Server.LookupVindexExternalize")
+	defer span.Finish()
+
+	span.Annotate("keyspace", req.Keyspace)
+	span.Annotate("name", req.Name)
+	span.Annotate("skip_verify", req.SkipVerify)
+
+	sourceVSchema, sourceVindex, targetKeyspace, targetTableName, err := s.getLookupVindex(ctx, req.Keyspace, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	workflow := targetTableName + "_vdx"
+	targetShards, err := s.ts.GetServingShards(ctx, targetKeyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	err = forAllShards(targetShards, func(targetShard *topo.ShardInfo) error {
+		targetPrimary, err := s.ts.GetTablet(ctx, targetShard.PrimaryAlias)
+		if err != nil {
+			return err
+		}
+		p3qr, err := s.tmc.VReplicationExec(ctx, targetPrimary.Tablet, fmt.Sprintf("select id, state, message, source from _vt.vreplication where workflow=%s and db_name=%s", encodeString(workflow), encodeString(targetPrimary.DbName())))
+		if err != nil {
+			return err
+		}
+		qr := sqltypes.Proto3ToResult(p3qr)
+		for _, row := range qr.Rows {
+			id, err := row[0].ToCastInt64()
+			if err != nil {
+				return err
+			}
+			state := binlogdatapb.VReplicationWorkflowState(binlogdatapb.VReplicationWorkflowState_value[row[1].ToString()])
+			message := row[2].ToString()
+			var bls binlogdatapb.BinlogSource
+			sourceBytes, err := row[3].ToBytes()
+			if err != nil {
+				return err
+			}
+			if err := prototext.Unmarshal(sourceBytes, &bls); err != nil {
+				return err
+			}
+			if lookupVindexStreamCopied(sourceVindex.Owner != "", &bls, state, message) {
+				continue
+			}
+			if sourceVindex.Owner == "" || !bls.StopAfterCopy {
+				// If there's no owner or we've requested that the workflow NOT be stopped
+				// after the copy phase completes, then all streams need to be running.
+				return fmt.Errorf("stream %d for %v.%v is not in Running state: %v", id, targetShard.Keyspace(), targetShard.ShardName(), state)
+			}
+			// If there is an owner, all streams need to be stopped after copy.
+			return fmt.Errorf("stream %d for %v.%v is not in Stopped after copy state: %v, %v", id, targetShard.Keyspace(), targetShard.ShardName(), state, message)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &vtctldatapb.LookupVindexExternalizeResponse{}
+	if !req.SkipVerify {
+		verifier, err := s.newLookupVindexVerifier(ctx, req.Keyspace, sourceVSchema, req.Name, targetKeyspace, targetTableName)
+		if err != nil {
+			return nil, err
+		}
+		if resp.RowsVerified, err = verifier.verify(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if sourceVindex.Owner != "" {
+		// If there is an owner, we have to delete the streams.
+		err := forAllShards(targetShards, func(targetShard *topo.ShardInfo) error {
+			targetPrimary, err := s.ts.GetTablet(ctx, targetShard.PrimaryAlias)
+			if err != nil {
+				return err
+			}
+			query := fmt.Sprintf("delete from _vt.vreplication where db_name=%s and workflow=%s", encodeString(targetPrimary.DbName()), encodeString(workflow))
+			_, err = s.tmc.VReplicationExec(ctx, targetPrimary.Tablet, query)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.WorkflowDeleted = true
+	}
+
+	// Remove the write_only param and save the source vschema.
+	delete(sourceVindex.Params, "write_only")
+	if err := s.ts.SaveVSchema(ctx, req.Keyspace, sourceVSchema); err != nil {
+		return nil, err
+	}
+	if err := s.ts.RebuildSrvVSchema(ctx, nil); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// lookupVindexStreamCopied returns true if a stream of the workflow of a
+// lookup vindex is in the state the vindex can be externalized in: stopped
+// after the copy if the vindex has an owner and the stream stops after the
+// copy, and running otherwise.
+func lookupVindexStreamCopied(hasOwner bool, bls *binlogdatapb.BinlogSource, state binlogdatapb.VReplicationWorkflowState, message string) bool {
+	if !hasOwner || !bls.StopAfterCopy {
+		return state == binlogdatapb.VReplicationWorkflowState_Running
+	}
+	return state == binlogdatapb.VReplicationWorkflowState_Stopped && strings.Contains(message, "Stopped after copy")
+}
+
+// getLookupVindex returns the vschema of the keyspace of a lookup vindex, the
+// vindex, and the keyspace and name of its lookup table.
+func (s *Server) getLookupVindex(ctx context.Context, keyspace, name string) (*vschemapb.Keyspace, *vschemapb.Vindex, string, string, error) {
+	vschema, err := s.ts.GetVSchema(ctx, keyspace)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+	vindex := vschema.Vindexes[name]
+	if vindex == nil {
+		return nil, nil, "", "", vterrors.Errorf(vtrpcpb.Code_NOT_FOUND, "vindex %s.%s not found in vschema", keyspace, name)
+	}
+	targetKeyspace, targetTableName, err := sqlparser.ParseTable(vindex.Params["table"])
+	if err != nil || targetKeyspace == "" {
+		return nil, nil, "", "", vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "vindex table name must be in the form <keyspace>.<table>. Got: %v", vindex.Params["table"])
+	}
+	return vschema, vindex, targetKeyspace, targetTableName, nil
+}
+
+// lookupVindexOwnerTable returns the owner table of a lookup vindex or, for a
+// vindex without an owner, the first table that uses it.
+func lookupVindexOwnerTable(vschema *vschemapb.Keyspace, name string) string {
+	if owner := vschema.Vindexes[name].GetOwner(); owner != "" {
+		return owner
+	}
+	tables := make([]string, 0, len(vschema.Tables))
+	for table := range vschema.Tables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		for _, colVindex := range vschema.Tables[table].ColumnVindexes {
+			if colVindex.Name == name {
+				return table
+			}
+		}
+	}
+	return ""
+}
+
+// PrepareCreateLookup performs the preparatory steps for creating a lookup
+// vindex. It returns the settings of the workflow that backfills the lookup
+// table, and the source and target vschemas updated with the vindex and the
+// lookup table.
+func PrepareCreateLookup(ctx context.Context, ts *topo.Server, tmc tmclient.TabletManagerClient, keyspace string, specs *vschemapb.Keyspace, continueAfterCopyWithOwner bool) (ms *vtctldatapb.MaterializeSettings, sourceVSchema, targetVSchema *vschemapb.Keyspace, err error) {
+	// Important variables are pulled out here.
+	var (
+		// lookup vindex info
+		vindexName        string
+		vindex            *vschemapb.Vindex
+		targetKeyspace    string
+		targetTableName   string
+		vindexFromCols    []string
+		vindexToCol       string
+		vindexIgnoreNulls bool
+
+		// source table info
+		sourceTableName string
+		// sourceTable is the supplied table info
+		sourceTable *vschemapb.Table
+		// sourceVSchemaTable is the table info present in the vschema
+		sourceVSchemaTable *vschemapb.Table
+		// sourceVindexColumns are computed from the input sourceTable
+		sourceVindexColumns []string
+
+		// target table info
+		createDDL        string
+		materializeQuery string
+	)
+
+	// Validate input vindex
+	if len(specs.Vindexes) != 1 {
+		return nil, nil, nil, fmt.Errorf("only one vindex must be specified in the specs: %v", specs.Vindexes)
+	}
+	for name, vi := range specs.Vindexes {
+		vindexName = name
+		vindex = vi
+	}
+	if !strings.Contains(vindex.Type, "lookup") {
+		return nil, nil, nil, fmt.Errorf("vindex %s is not a lookup type", vindex.Type)
+	}
+
+	targetKeyspace, targetTableName, err = sqlparser.ParseTable(vindex.Params["table"])
+	if err != nil || targetKeyspace == "" {
+		return nil, nil, nil, fmt.Errorf("vindex table name must be in the form <keyspace>.<table>. Got: %v", vindex.Params["table"])
+	}
+
+	vindexFromCols = strings.Split(vindex.Params["from"], ",")
+	if strings.Contains(vindex.Type, "unique") {
+		if len(vindexFromCols) != 1 {
+			return nil, nil, nil, fmt.Errorf("unique vindex 'from' should have only one column: %v", vindex)
+		}
+	} else {
+		if len(vindexFromCols) < 2 {
+			return nil, nil, nil, fmt.Errorf("non-unique vindex 'from' should have more than one column: %v", vindex)
+		}
+	}
+	vindexToCol = vindex.Params["to"]
+	// Make the vindex write_only. If one exists already in the vschema,
+	// it will need to match this vindex exactly, including the write_only setting.
+	vindex.Params["write_only"] = "true"
+	// See if we can create the vindex without errors.
+	if _, err := vindexes.CreateVindex(vindex.Type, vindexName, vindex.Params); err != nil {
+		return nil, nil, nil, err
+	}
+	if ignoreNullsStr, ok := vindex.Params["ignore_nulls"]; ok {
+		// This mirrors the behavior of vindexes.boolFromMap().
+		switch ignoreNullsStr {
+		case "true":
+			vindexIgnoreNulls = true
+		case "false":
+			vindexIgnoreNulls = false
+		default:
+			return nil, nil, nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "ignore_nulls value must be 'true' or 'false': '%s'",
+				ignoreNullsStr)
+		}
+	}
+
+	// Validate input table
+	if len(specs.Tables) != 1 {
+		return nil, nil, nil, fmt.Errorf("exactly one table must be specified in the specs: %v", specs.Tables)
+	}
+	// Loop executes once.
+	for k, ti := range specs.Tables {
+		if len(ti.ColumnVindexes) != 1 {
+			return nil, nil, nil, fmt.Errorf("exactly one ColumnVindex must be specified for the table: %v", specs.Tables)
+		}
+		sourceTableName = k
+		sourceTable = ti
+	}
+
+	// Validate input table and vindex consistency
+	if sourceTable.ColumnVindexes[0].Name != vindexName {
+		return nil, nil, nil, fmt.Errorf("ColumnVindex name must match vindex name: %s vs %s", sourceTable.ColumnVindexes[0].Name, vindexName)
+	}
+	if vindex.Owner != "" && vindex.Owner != sourceTableName {
+		return nil, nil, nil, fmt.Errorf("vindex owner must match table name: %v vs %v", vindex.Owner, sourceTableName)
+	}
+	if len(sourceTable.ColumnVindexes[0].Columns) != 0 {
+		sourceVindexColumns = sourceTable.ColumnVindexes[0].Columns
+	} else {
+		if sourceTable.ColumnVindexes[0].Column == "" {
+			return nil, nil, nil, fmt.Errorf("at least one column must be specified in ColumnVindexes: %v", sourceTable.ColumnVindexes)
+		}
+		sourceVindexColumns = []string{sourceTable.ColumnVindexes[0].Column}
+	}
+	if len(sourceVindexColumns) != len(vindexFromCols) {
+		return nil, nil, nil, fmt.Errorf("length of table columns differes from length of vindex columns: %v vs %v", sourceVindexColumns, vindexFromCols)
+	}
+
+	// Validate against source vschema
+	sourceVSchema, err = ts.GetVSchema(ctx, keyspace)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if sourceVSchema.Vindexes == nil {
+		sourceVSchema.Vindexes = make(map[string]*vschemapb.Vindex)
+	}
+	// If source and target keyspaces are same, Make vschemas point to the same object.
+	if keyspace == targetKeyspace {
+		targetVSchema = sourceVSchema
+	} else {
+		targetVSchema, err = ts.GetVSchema(ctx, targetKeyspace)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if targetVSchema.Vindexes == nil {
+		targetVSchema.Vindexes = make(map[string]*vschemapb.Vindex)
+	}
+	if targetVSchema.Tables == nil {
+		targetVSchema.Tables = make(map[string]*vschemapb.Table)
+	}
+	if existing, ok := sourceVSchema.Vindexes[vindexName]; ok {
+		if !proto.Equal(existing, vindex) {
+			return nil, nil, nil, fmt.Errorf("a conflicting vindex named %s already exists in the source vschema", vindexName)
+		}
+	}
+	sourceVSchemaTable = sourceVSchema.Tables[sourceTableName]
+	if sourceVSchemaTable == nil {
+		if !schema.IsInternalOperationTableName(sourceTableName) {
+			return nil, nil, nil, fmt.Errorf("source table %s not found in vschema", sourceTableName)
+		}
+	}
+	for _, colVindex := range sourceVSchemaTable.ColumnVindexes {
+		// For a conflict, the vindex name and column should match.
+		if colVindex.Name != vindexName {
+			continue
+		}
+		colName := colVindex.Column
+		if len(colVindex.Columns) != 0 {
+			colName = colVindex.Columns[0]
+		}
+		if colName == sourceVindexColumns[0] {
+			return nil, nil, nil, fmt.Errorf("ColumnVindex for table %v already exists: %v, please remove it and try again", sourceTableName, colName)
+		}
+	}
+
+	// Validate against source schema
+	sourceShards, err := ts.GetServingShards(ctx, keyspace)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	onesource := sourceShards[0]
+	if onesource.PrimaryAlias == nil {
+		return nil, nil, nil, fmt.Errorf("source shard has no primary: %v", onesource.ShardName())
+	}
+	req := &tabletmanagerdatapb.GetSchemaRequest{Tables: []string{sourceTableName}}
+	tableSchema, err := schematools.GetSchema(ctx, ts, tmc, onesource.PrimaryAlias, req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(tableSchema.TableDefinitions) != 1 {
+		return nil, nil, nil, fmt.Errorf("unexpected number of tables returned from schema: %v", tableSchema.TableDefinitions)
+	}
+
+	// Generate "create table" statement
+	lines := strings.Split(tableSchema.TableDefinitions[0].Schema, "\n")
+	if len(lines) < 3 {
+		// Unreachable
+		return nil, nil, nil, fmt.Errorf("schema looks incorrect: %s, expecting at least four lines", tableSchema.TableDefinitions[0].Schema)
+	}
+	var modified []string
+	modified = append(modified, strings.Replace(lines[0], sourceTableName, targetTableName, 1))
+	for i := range sourceVindexColumns {
+		line, err := generateColDef(lines, sourceVindexColumns[i], vindexFromCols[i])
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		modified = append(modified, line)
+	}
+
+	if vindex.Params["data_type"] == "" || strings.EqualFold(vindex.Type, "consistent_lookup_unique") || strings.EqualFold(vindex.Type, "consistent_lookup") {
+		modified = append(modified, fmt.Sprintf("  %s varbinary(128),", sqlescape.EscapeID(vindexToCol)))
+	} else {
+		modified = append(modified, fmt.Sprintf("  %s %s,", sqlescape.EscapeID(vindexToCol), sqlescape.EscapeID(vindex.Params["data_type"])))
+	}
+	buf := sqlparser.NewTrackedBuffer(nil)
+	fmt.Fprintf(buf, "  PRIMARY KEY (")
+	prefix := ""
+	for _, col := range vindexFromCols {
+		fmt.Fprintf(buf, "%s%s", prefix, sqlescape.EscapeID(col))
+		prefix = ", "
+	}
+	fmt.Fprintf(buf, ")")
+	modified = append(modified, buf.String())
+	modified = append(modified, ")")
+	createDDL = strings.Join(modified, "\n")
+
+	// Generate vreplication query
+	buf = sqlparser.NewTrackedBuffer(nil)
+	buf.Myprintf("select ")
+	for i := range vindexFromCols {
+		buf.Myprintf("%s as %s, ", sqlparser.String(sqlparser.NewIdentifierCI(sourceVindexColumns[i])), sqlparser.String(sqlparser.NewIdentifierCI(vindexFromCols[i])))
+	}
+	if strings.EqualFold(vindexToCol, "keyspace_id") || strings.EqualFold(vindex.Type, "consistent_lookup_unique") || strings.EqualFold(vindex.Type, "consistent_lookup") {
+		buf.Myprintf("keyspace_id() as %s ", sqlparser.String(sqlparser.NewIdentifierCI(vindexToCol)))
+	} else {
+		buf.Myprintf("%s as %s ", sqlparser.String(sqlparser.NewIdentifierCI(vindexToCol)), sqlparser.String(sqlparser.NewIdentifierCI(vindexToCol)))
+	}
+	buf.Myprintf("from %s", sqlparser.String(sqlparser.NewIdentifierCS(sourceTableName)))
+	if vindexIgnoreNulls {
+		buf.Myprintf(" where ")
+		lastValIdx := len(vindexFromCols) - 1
+		for i := range vindexFromCols {
+			buf.Myprintf("%s is not null", sqlparser.String(sqlparser.NewIdentifierCI(vindexFromCols[i])))
+			if i != lastValIdx {
+				buf.Myprintf(" and ")
+			}
+		}
+	}
+	if vindex.Owner != "" {
+		// Only backfill
+		buf.Myprintf(" group by ")
+		for i := range vindexFromCols {
+			buf.Myprintf("%s, ", sqlparser.String(sqlparser.NewIdentifierCI(vindexFromCols[i])))
+		}
+		buf.Myprintf("%s", sqlparser.String(sqlparser.NewIdentifierCI(vindexToCol)))
+	}
+	materializeQuery = buf.String()
+
+	// Update targetVSchema
+	var targetTable *vschemapb.Table
+	if targetVSchema.Sharded {
+		// Choose a primary vindex type for target table based on source specs
+		var targetVindexType string
+		var targetVindex *vschemapb.Vindex
+		for _, field := range tableSchema.TableDefinitions[0].Fields {
+			if sourceVindexColumns[0] == field.Name {
+				targetVindexType, err = vindexes.ChooseVindexForType(field.Type)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				targetVindex = &vschemapb.Vindex{
+					Type: targetVindexType,
+				}
+				break
+			}
+		}
+		if targetVindex == nil {
+			// Unreachable. We validated column names when generating the DDL.
+			return nil, nil, nil, fmt.Errorf("column %s not found in schema %v", sourceVindexColumns[0], tableSchema.TableDefinitions[0])
+		}
+		if existing, ok := targetVSchema.Vindexes[targetVindexType]; ok {
+			if !proto.Equal(existing, targetVindex) {
+				return nil, nil, nil, fmt.Errorf("a conflicting vindex named %v already exists in the target vschema", targetVindexType)
+			}
+		} else {
+			targetVSchema.Vindexes[targetVindexType] = targetVindex
+		}
+
+		targetTable = &vschemapb.Table{
+			ColumnVindexes: []*vschemapb.ColumnVindex{{
+				Column: vindexFromCols[0],
+				Name:   targetVindexType,
+			}},
+		}
+	} else {
+		targetTable = &vschemapb.Table{}
+	}
+	if existing, ok := targetVSchema.Tables[targetTableName]; ok {
+		if !proto.Equal(existing, targetTable) {
+			return nil, nil, nil, fmt.Errorf("a conflicting table named %v already exists in the target vschema", targetTableName)
+		}
+	} else {
+		targetVSchema.Tables[targetTableName] = targetTable
+	}
+
+	ms = &vtctldatapb.MaterializeSettings{
+		Workflow:              targetTableName + "_vdx",
+		MaterializationIntent: vtctldatapb.MaterializationIntent_CREATELOOKUPINDEX,
+		SourceKeyspace:        keyspace,
+		TargetKeyspace:        targetKeyspace,
+		StopAfterCopy:         vindex.Owner != "" && !continueAfterCopyWithOwner,
+		TableSettings: []*vtctldatapb.TableMaterializeSettings{{
+			TargetTable:      targetTableName,
+			SourceExpression: materializeQuery,
+			CreateDdl:        createDDL,
+		}},
+	}
+
+	// Update sourceVSchema
+	sourceVSchema.Vindexes[vindexName] = vindex
+	sourceVSchemaTable.ColumnVindexes = append(sourceVSchemaTable.ColumnVindexes, sourceTable.ColumnVindexes[0])
+
+	return ms, sourceVSchema, targetVSchema, nil
+}
+
+func generateColDef(lines []string, sourceVindexCol, vindexFromCol string) (string, error) {
+	source := sqlescape.EscapeID(sourceVindexCol)
+	target := sqlescape.EscapeID(vindexFromCol)
+
+	for _, line := range lines[1:] {
+		if strings.Contains(line, source) {
+			line = strings.Replace(line, source, target, 1)
+			line = strings.Replace(line, " AUTO_INCREMENT", "", 1)
+			line = strings.Replace(line, " DEFAULT NULL", "", 1)
+			return line, nil
+		}
+	}
+	return "", fmt.Errorf("column %s not found in schema %v", sourceVindexCol, lines)
+}
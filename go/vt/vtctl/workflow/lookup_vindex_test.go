@@ -0,0 +1,318 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/key"
+	"vitess.io/vitess/go/vt/vtgate/vindexes"
+
+	binlogdatapb "vitess.io/vitess/go/vt/proto/binlogdata"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	tabletmanagerdatapb "vitess.io/vitess/go/vt/proto/tabletmanagerdata"
+	vschemapb "vitess.io/vitess/go/vt/proto/vschema"
+	vtctldatapb "vitess.io/vitess/go/vt/proto/vtctldata"
+)
+
+// newTestLookupVindexEnv returns an env with a sourceks keyspace that has a
+// t1 table with a write only lookup vindex on its c1 column, backfilled into
+// the lkp table of the targetks keyspace with the targetShards.
+func newTestLookupVindexEnv(t *testing.T, ctx context.Context, targetShards ...string) *testMaterializerEnv {
+	ms := &vtctldatapb.MaterializeSettings{
+		SourceKeyspace: "sourceks",
+		TargetKeyspace: "targetks",
+	}
+	if len(targetShards) == 0 {
+		targetShards = []string{"0"}
+	}
+	env := newTestMaterializerEnv(t, ctx, ms, []string{"0"}, targetShards)
+	err := env.topoServ.SaveVSchema(ctx, "sourceks", &vschemapb.Keyspace{
+		Sharded: true,
+		Vindexes: map[string]*vschemapb.Vindex{
+			"hash": {Type: "hash"},
+			"v": {
+				Type: "lookup_unique",
+				Params: map[string]string{
+					"table":      "targetks.lkp",
+					"from":       "c1",
+					"to":         "keyspace_id",
+					"write_only": "true",
+				},
+				Owner: "t1",
+			},
+		},
+		Tables: map[string]*vschemapb.Table{
+			"t1": {
+				ColumnVindexes: []*vschemapb.ColumnVindex{
+					{Column: "id", Name: "hash"},
+					{Column: "c1", Name: "v"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	env.tmc.schema["sourceks.t1"] = &tabletmanagerdatapb.SchemaDefinition{
+		TableDefinitions: []*tabletmanagerdatapb.TableDefinition{{
+			Name:              "t1",
+			PrimaryKeyColumns: []string{"id"},
+		}},
+	}
+	env.tmc.schema["targetks.lkp"] = &tabletmanagerdatapb.SchemaDefinition{
+		TableDefinitions: []*tabletmanagerdatapb.TableDefinition{{
+			Name:              "lkp",
+			PrimaryKeyColumns: []string{"c1"},
+		}},
+	}
+	return env
+}
+
+func TestLookupVindexStatus(t *testing.T) {
+	bls, err := prototext.Marshal(&binlogdatapb.BinlogSource{Keyspace: "sourceks", Shard: "0", StopAfterCopy: true})
+	require.NoError(t, err)
+	started := time.Now().Add(-time.Minute).Unix()
+
+	testcases := []struct {
+		name           string
+		stream         string
+		state          string
+		rowsPercentage float32
+	}{
+		{
+			name:           "copying",
+			stream:         fmt.Sprintf("1|Running||50|1|%d|%s", started, bls),
+			state:          lookupVindexCopying,
+			rowsPercentage: 25,
+		},
+		{
+			name:           "copied, not stopped yet",
+			stream:         fmt.Sprintf("1|Running||50|0|%d|%s", started, bls),
+			state:          lookupVindexCopying,
+			rowsPercentage: 25,
+		},
+		{
+			name:           "stopped after copy",
+			stream:         fmt.Sprintf("1|Stopped|Stopped after copy|50|0|%d|%s", started, bls),
+			state:          lookupVindexCopied,
+			rowsPercentage: 100,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			env := newTestLookupVindexEnv(t, ctx)
+			defer env.close()
+
+			env.tmc.expectVRQuery(200, fmt.Sprintf(sqlLookupVindexStreams, "'vt_targetks'", "'lkp_vdx'"), sqltypes.MakeTestResult(
+				sqltypes.MakeTestFields("id|state|message|rows_copied|tables_copying|time_started|source", "int64|varchar|varchar|int64|int64|int64|blob"),
+				tc.stream,
+			))
+			env.tmc.expectVRQuery(100, fmt.Sprintf(sqlLookupVindexTableRows, "'vt_sourceks'", "'t1'"), sqltypes.MakeTestResult(
+				sqltypes.MakeTestFields("table_rows", "int64"),
+				"200",
+			))
+
+			resp, err := env.ws.LookupVindexStatus(ctx, &vtctldatapb.LookupVindexStatusRequest{
+				Keyspace: "sourceks",
+				Name:     "v",
+			})
+			require.NoError(t, err)
+			require.Equal(t, "lkp_vdx", resp.Workflow)
+			require.Equal(t, tc.state, resp.State)
+			require.EqualValues(t, 50, resp.RowsCopied)
+			require.EqualValues(t, 200, resp.RowsTotal)
+			require.EqualValues(t, tc.rowsPercentage, resp.RowsPercentage)
+			if tc.state == lookupVindexCopying {
+				require.NotNil(t, resp.EstimatedCompletion)
+				require.Greater(t, resp.EstimatedCompletion.Seconds, time.Now().Unix())
+			}
+		})
+	}
+}
+
+func TestLookupVindexExternalize(t *testing.T) {
+	hash, err := vindexes.CreateVindex("hash", "hash", nil)
+	require.NoError(t, err)
+	ksid := func(id int64) []byte {
+		destinations, err := vindexes.Map(context.Background(), hash, nil, [][]sqltypes.Value{{sqltypes.NewInt64(id)}})
+		require.NoError(t, err)
+		return destinations[0].(key.DestinationKeyspaceID)
+	}
+	bls, err := prototext.Marshal(&binlogdatapb.BinlogSource{Keyspace: "sourceks", Shard: "0", StopAfterCopy: true})
+	require.NoError(t, err)
+	streams := sqltypes.MakeTestResult(
+		sqltypes.MakeTestFields("id|state|message|source", "int64|varchar|varchar|blob"),
+		fmt.Sprintf("1|Stopped|Stopped after copy|%s", bls),
+	)
+	ownerRows := &sqltypes.Result{
+		Fields: sqltypes.MakeTestFields("c1|id", "varchar|int64"),
+		Rows: [][]sqltypes.Value{
+			{sqltypes.NULL, sqltypes.NewInt64(3)},
+			{sqltypes.NewVarChar("a"), sqltypes.NewInt64(1)},
+			{sqltypes.NewVarChar("b"), sqltypes.NewInt64(2)},
+		},
+	}
+
+	testcases := []struct {
+		name         string
+		ignoreNulls  bool
+		lookupRows   [][]sqltypes.Value
+		rowsVerified int64
+		wantErr      string
+	}{
+		{
+			name: "consistent",
+			lookupRows: [][]sqltypes.Value{
+				{sqltypes.NULL, sqltypes.MakeTrusted(querypb.Type_VARBINARY, ksid(3))},
+				{sqltypes.NewVarChar("a"), sqltypes.MakeTrusted(querypb.Type_VARBINARY, ksid(1))},
+				{sqltypes.NewVarChar("b"), sqltypes.MakeTrusted(querypb.Type_VARBINARY, ksid(2))},
+			},
+			rowsVerified: 6,
+		},
+		{
+			name:        "consistent, ignoring nulls",
+			ignoreNulls: true,
+			lookupRows: [][]sqltypes.Value{
+				{sqltypes.NewVarChar("a"), sqltypes.MakeTrusted(querypb.Type_VARBINARY, ksid(1))},
+				{sqltypes.NewVarChar("b"), sqltypes.MakeTrusted(querypb.Type_VARBINARY, ksid(2))},
+			},
+			rowsVerified: 5,
+		},
+		{
+			name: "empty values don't match nulls",
+			lookupRows: [][]sqltypes.Value{
+				{sqltypes.NewVarChar(""), sqltypes.MakeTrusted(querypb.Type_VARBINARY, ksid(3))},
+				{sqltypes.NewVarChar("a"), sqltypes.MakeTrusted(querypb.Type_VARBINARY, ksid(1))},
+				{sqltypes.NewVarChar("b"), sqltypes.MakeTrusted(querypb.Type_VARBINARY, ksid(2))},
+			},
+			wantErr: "the targetks.lkp lookup table does not match the sourceks.t1 table: 1 rows are missing and 1 rows don't match any row",
+		},
+		{
+			name:        "missing and extra rows",
+			ignoreNulls: true,
+			lookupRows: [][]sqltypes.Value{
+				{sqltypes.NewVarChar("a"), sqltypes.MakeTrusted(querypb.Type_VARBINARY, ksid(1))},
+				{sqltypes.NewVarChar("b"), sqltypes.MakeTrusted(querypb.Type_VARBINARY, ksid(1))},
+			},
+			wantErr: "the targetks.lkp lookup table does not match the sourceks.t1 table: 1 rows are missing and 1 rows don't match any row",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			env := newTestLookupVindexEnv(t, ctx)
+			defer env.close()
+			if tc.ignoreNulls {
+				vschema, err := env.topoServ.GetVSchema(ctx, "sourceks")
+				require.NoError(t, err)
+				vschema.Vindexes["v"].Params["ignore_nulls"] = "true"
+				require.NoError(t, env.topoServ.SaveVSchema(ctx, "sourceks", vschema))
+			}
+
+			env.tmc.expectVRQuery(200, "select id, state, message, source from _vt.vreplication where workflow='lkp_vdx' and db_name='vt_targetks'", streams)
+			env.tmc.expectVRQuery(100, "select c1, id from t1 order by c1, id limit 10000", ownerRows)
+			env.tmc.expectVRQuery(200, "select c1, keyspace_id from lkp order by c1 limit 10000", &sqltypes.Result{
+				Fields: sqltypes.MakeTestFields("c1|keyspace_id", "varchar|varbinary"),
+				Rows:   tc.lookupRows,
+			})
+			if tc.wantErr == "" {
+				env.tmc.expectVRQuery(200, "delete from _vt.vreplication where db_name='vt_targetks' and workflow='lkp_vdx'", &sqltypes.Result{})
+			}
+
+			resp, err := env.ws.LookupVindexExternalize(ctx, &vtctldatapb.LookupVindexExternalizeRequest{
+				Keyspace: "sourceks",
+				Name:     "v",
+			})
+			vschema, verr := env.topoServ.GetVSchema(ctx, "sourceks")
+			require.NoError(t, verr)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				require.Equal(t, "true", vschema.Vindexes["v"].Params["write_only"])
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, resp.WorkflowDeleted)
+			require.EqualValues(t, tc.rowsVerified, resp.RowsVerified)
+			require.NotContains(t, vschema.Vindexes["v"].Params, "write_only")
+		})
+	}
+}
+
+func TestLookupVindexExternalizeBatches(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	env := newTestLookupVindexEnv(t, ctx, "-80", "80-")
+	defer env.close()
+	defer func(batchSize int) {
+		lookupVindexVerifyBatchSize = batchSize
+	}(lookupVindexVerifyBatchSize)
+	lookupVindexVerifyBatchSize = 2
+
+	// The lookup vindex maps to the id column of t1, rather than to keyspace
+	// ids, and c1 has the same value for two rows.
+	vschema, err := env.topoServ.GetVSchema(ctx, "sourceks")
+	require.NoError(t, err)
+	vschema.Vindexes["v"].Type = "lookup"
+	vschema.Vindexes["v"].Params["to"] = "id"
+	require.NoError(t, env.topoServ.SaveVSchema(ctx, "sourceks", vschema))
+	env.tmc.schema["targetks.lkp"].TableDefinitions[0].PrimaryKeyColumns = []string{"c1", "id"}
+
+	bls, err := prototext.Marshal(&binlogdatapb.BinlogSource{Keyspace: "sourceks", Shard: "0", StopAfterCopy: true})
+	require.NoError(t, err)
+	streams := sqltypes.MakeTestResult(
+		sqltypes.MakeTestFields("id|state|message|source", "int64|varchar|varchar|blob"),
+		fmt.Sprintf("1|Stopped|Stopped after copy|%s", bls),
+	)
+	fields := sqltypes.MakeTestFields("c1|id", "varchar|int64")
+	// The rows of the lookup table are merged from both of its shards.
+	for _, tabletID := range []int{200, 210} {
+		env.tmc.expectVRQuery(tabletID, "select id, state, message, source from _vt.vreplication where workflow='lkp_vdx' and db_name='vt_targetks'", streams)
+	}
+	env.tmc.expectVRQuery(100, "select c1, id from t1 order by c1, id limit 2", sqltypes.MakeTestResult(fields, "null|3", "a|1"))
+	env.tmc.expectVRQuery(100, "select c1, id from t1 where c1 > 'a' or (c1 <=> 'a' and id > 1) order by c1, id limit 2", sqltypes.MakeTestResult(fields, "a|2"))
+	env.tmc.expectVRQuery(200, "select c1, id from lkp order by c1, id limit 2", sqltypes.MakeTestResult(fields, "null|3", "a|2"))
+	env.tmc.expectVRQuery(200, "select c1, id from lkp where c1 > 'a' or (c1 <=> 'a' and id > 2) order by c1, id limit 2", &sqltypes.Result{Fields: fields})
+	env.tmc.expectVRQuery(210, "select c1, id from lkp order by c1, id limit 2", sqltypes.MakeTestResult(fields, "a|1"))
+	for _, tabletID := range []int{200, 210} {
+		env.tmc.expectVRQuery(tabletID, "delete from _vt.vreplication where db_name='vt_targetks' and workflow='lkp_vdx'", &sqltypes.Result{})
+	}
+
+	resp, err := env.ws.LookupVindexExternalize(ctx, &vtctldatapb.LookupVindexExternalizeRequest{
+		Keyspace: "sourceks",
+		Name:     "v",
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 6, resp.RowsVerified)
+}
+
+func TestScanTableQuery(t *testing.T) {
+	require.Equal(t, "select c1, id from t1 order by c1, id limit 10000",
+		scanTableQuery("t1", []string{"c1", "id"}, []string{"c1", "id"}, nil))
+	require.Equal(t, "select c1, c2, keyspace_id from lkp where c1 > 'a' or (c1 <=> 'a' and c2 > 1) order by c1, c2 limit 10000",
+		scanTableQuery("lkp", []string{"c1", "c2", "keyspace_id"}, []string{"c1", "c2"}, []sqltypes.Value{sqltypes.NewVarChar("a"), sqltypes.NewInt64(1)}))
+	require.Equal(t, "select c1, id from t1 where c1 is not null or (c1 <=> null and id > 3) order by c1, id limit 10000",
+		scanTableQuery("t1", []string{"c1", "id"}, []string{"c1", "id"}, []sqltypes.Value{sqltypes.NULL, sqltypes.NewInt64(3)}))
+}
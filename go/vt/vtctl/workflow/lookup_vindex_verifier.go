@@ -0,0 +1,522 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"vitess.io/vitess/go/mysql/collations"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/key"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/topo"
+	"vitess.io/vitess/go/vt/vtctl/schematools"
+	"vitess.io/vitess/go/vt/vterrors"
+	"vitess.io/vitess/go/vt/vtgate/evalengine"
+	"vitess.io/vitess/go/vt/vtgate/vindexes"
+
+	tabletmanagerdatapb "vitess.io/vitess/go/vt/proto/tabletmanagerdata"
+	vschemapb "vitess.io/vitess/go/vt/proto/vschema"
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+)
+
+// lookupVindexVerifyBatchSize is the number of rows read by each query when
+// verifying a lookup table.
+var lookupVindexVerifyBatchSize = 10000
+
+// lookupVindexVerifier verifies that a lookup table has exactly one row for
+// each distinct entry of the rows of its owner table, the way the workflow
+// that backfills it copies them.
+type lookupVindexVerifier struct {
+	s *Server
+
+	sourceKeyspace string
+	targetKeyspace string
+	ownerTable     string
+	lookupTable    string
+
+	// ownerCols are the columns of the owner table that the vindex is on,
+	// and fromCols the matching columns of the lookup table.
+	ownerCols []string
+	fromCols  []string
+	toCol     string
+	// ignoreNulls is true if the rows of the owner table with a NULL value
+	// in any of the ownerCols aren't backfilled.
+	ignoreNulls bool
+
+	// primaryVindex maps the primaryCols of the rows of the owner table to
+	// their keyspace ids, when the lookup table maps to keyspace ids.
+	// Otherwise the to column is copied from the owner table.
+	primaryVindex vindexes.Vindex
+	primaryCols   []string
+}
+
+func (s *Server) newLookupVindexVerifier(ctx context.Context, sourceKeyspace string, sourceVSchema *vschemapb.Keyspace, name, targetKeyspace, lookupTable string) (*lookupVindexVerifier, error) {
+	vindex := sourceVSchema.Vindexes[name]
+	ownerTable := lookupVindexOwnerTable(sourceVSchema, name)
+	if ownerTable == "" || sourceVSchema.Tables[ownerTable] == nil {
+		return nil, vterrors.Errorf(vtrpcpb.Code_FAILED_PRECONDITION, "cannot verify the %s.%s vindex: it is not used by any table", sourceKeyspace, name)
+	}
+	v := &lookupVindexVerifier{
+		s:              s,
+		sourceKeyspace: sourceKeyspace,
+		targetKeyspace: targetKeyspace,
+		ownerTable:     ownerTable,
+		lookupTable:    lookupTable,
+		toCol:          vindex.Params["to"],
+		// This mirrors the ignore_nulls check of PrepareCreateLookup.
+		ignoreNulls: vindex.Params["ignore_nulls"] == "true",
+	}
+	for _, col := range strings.Split(vindex.Params["from"], ",") {
+		v.fromCols = append(v.fromCols, strings.TrimSpace(col))
+	}
+	colVindexes := sourceVSchema.Tables[ownerTable].ColumnVindexes
+	for _, colVindex := range colVindexes {
+		if colVindex.Name == name {
+			v.ownerCols = columnVindexColumns(colVindex)
+			break
+		}
+	}
+	if len(v.ownerCols) != len(v.fromCols) {
+		return nil, vterrors.Errorf(vtrpcpb.Code_FAILED_PRECONDITION, "cannot verify the %s.%s vindex: the %s table has %d columns for it, and the lookup table %d",
+			sourceKeyspace, name, ownerTable, len(v.ownerCols), len(v.fromCols))
+	}
+
+	// This mirrors the select of the backfill in PrepareCreateLookup.
+	if strings.EqualFold(v.toCol, "keyspace_id") || strings.EqualFold(vindex.Type, "consistent_lookup_unique") || strings.EqualFold(vindex.Type, "consistent_lookup") {
+		if len(colVindexes) == 0 || colVindexes[0].Name == name {
+			return nil, vterrors.Errorf(vtrpcpb.Code_FAILED_PRECONDITION, "cannot verify the %s.%s vindex: the %s table has no primary vindex", sourceKeyspace, name, ownerTable)
+		}
+		primary := sourceVSchema.Vindexes[colVindexes[0].Name]
+		if primary == nil {
+			return nil, vterrors.Errorf(vtrpcpb.Code_FAILED_PRECONDITION, "cannot verify the %s.%s vindex: vindex %s not found in vschema", sourceKeyspace, name, colVindexes[0].Name)
+		}
+		primaryVindex, err := vindexes.CreateVindex(primary.Type, colVindexes[0].Name, primary.Params)
+		if err != nil {
+			return nil, err
+		}
+		if primaryVindex.NeedsVCursor() {
+			return nil, vterrors.Errorf(vtrpcpb.Code_FAILED_PRECONDITION, "cannot verify the %s.%s vindex: the primary vindex %s of the %s table needs to query vtgate", sourceKeyspace, name, colVindexes[0].Name, ownerTable)
+		}
+		v.primaryVindex = primaryVindex
+		v.primaryCols = columnVindexColumns(colVindexes[0])
+	}
+	return v, nil
+}
+
+func columnVindexColumns(colVindex *vschemapb.ColumnVindex) []string {
+	if len(colVindex.Columns) > 0 {
+		return colVindex.Columns
+	}
+	return []string{colVindex.Column}
+}
+
+// verify compares the lookup table to the owner table and returns the number
+// of rows of both that were read. It fails if rows are missing from the
+// lookup table or if it has rows that don't match any row of the owner table.
+//
+// Both tables are read ordered by the columns of the vindex, and merged
+// across their shards, so that only the rows of one value of the vindex
+// columns are held in memory at a time.
+func (v *lookupVindexVerifier) verify(ctx context.Context) (int64, error) {
+	cols := append([]string{}, v.ownerCols...)
+	if v.primaryVindex != nil {
+		cols = append(cols, v.primaryCols...)
+	} else {
+		cols = append(cols, v.toCol)
+	}
+	owner, err := v.newTableScan(ctx, v.sourceKeyspace, v.ownerTable, v.ownerCols, cols)
+	if err != nil {
+		return 0, err
+	}
+	lookup, err := v.newTableScan(ctx, v.targetKeyspace, v.lookupTable, v.fromCols, append(append([]string{}, v.fromCols...), v.toCol))
+	if err != nil {
+		return 0, err
+	}
+
+	var verified, missing, extra int64
+	for {
+		ownerRow, err := owner.peek(ctx)
+		if err != nil {
+			return 0, err
+		}
+		lookupRow, err := lookup.peek(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if ownerRow == nil && lookupRow == nil {
+			break
+		}
+		// The values of the vindex columns are compared with the collations
+		// of the owner table, that the lookup table was created with.
+		collationIDs := owner.collationIDs
+		if collationIDs == nil {
+			collationIDs = lookup.collationIDs
+		}
+		key := ownerRow
+		if key == nil {
+			key = lookupRow
+		} else if lookupRow != nil {
+			cmp, err := compareLookupValues(lookupRow, ownerRow, collationIDs)
+			if err != nil {
+				return 0, err
+			}
+			if cmp < 0 {
+				key = lookupRow
+			}
+		}
+		key = key[:len(v.fromCols)]
+
+		// Collect the entries that the lookup table should have for the
+		// values of the vindex columns.
+		expected := make(map[string]bool)
+		for ownerRow != nil {
+			cmp, err := compareLookupValues(ownerRow, key, collationIDs)
+			if err != nil {
+				return 0, err
+			}
+			if cmp != 0 {
+				break
+			}
+			verified++
+			if err := v.addExpectedEntry(ctx, expected, ownerRow); err != nil {
+				return 0, err
+			}
+			owner.next()
+			if ownerRow, err = owner.peek(ctx); err != nil {
+				return 0, err
+			}
+		}
+		// Match the rows of the lookup table with the expected entries.
+		for lookupRow != nil {
+			cmp, err := compareLookupValues(lookupRow, key, collationIDs)
+			if err != nil {
+				return 0, err
+			}
+			if cmp != 0 {
+				break
+			}
+			verified++
+			entry := lookupEntryKey(lookupRow)
+			if expected[entry] {
+				delete(expected, entry)
+			} else {
+				extra++
+			}
+			lookup.next()
+			if lookupRow, err = lookup.peek(ctx); err != nil {
+				return 0, err
+			}
+		}
+		missing += int64(len(expected))
+	}
+
+	if missing > 0 || extra > 0 {
+		return 0, vterrors.Errorf(vtrpcpb.Code_FAILED_PRECONDITION, "the %s.%s lookup table does not match the %s.%s table: %d rows are missing and %d rows don't match any row",
+			v.targetKeyspace, v.lookupTable, v.sourceKeyspace, v.ownerTable, missing, extra)
+	}
+	return verified, nil
+}
+
+// addExpectedEntry adds the entry of the lookup table for a row of the owner
+// table to expected, unless the row isn't backfilled.
+func (v *lookupVindexVerifier) addExpectedEntry(ctx context.Context, expected map[string]bool, row []sqltypes.Value) error {
+	entry := make([]sqltypes.Value, 0, len(v.ownerCols)+1)
+	for _, val := range row[:len(v.ownerCols)] {
+		if val.IsNull() && v.ignoreNulls {
+			return nil
+		}
+		entry = append(entry, val)
+	}
+	to, err := v.toValue(ctx, row[len(v.ownerCols):])
+	if err != nil {
+		return err
+	}
+	expected[lookupEntryKey(append(entry, to))] = true
+	return nil
+}
+
+// toValue returns the value of the to column of the lookup table for the
+// values of the primary vindex columns, or of the to column, of a row of the
+// owner table.
+func (v *lookupVindexVerifier) toValue(ctx context.Context, values []sqltypes.Value) (sqltypes.Value, error) {
+	if v.primaryVindex == nil {
+		return values[0], nil
+	}
+	destinations, err := vindexes.Map(ctx, v.primaryVindex, nil, [][]sqltypes.Value{values})
+	if err != nil {
+		return sqltypes.NULL, err
+	}
+	ksid, ok := destinations[0].(key.DestinationKeyspaceID)
+	if !ok {
+		return sqltypes.NULL, vterrors.Errorf(vtrpcpb.Code_FAILED_PRECONDITION, "cannot map %v of the %s.%s table to a keyspace id", values, v.sourceKeyspace, v.ownerTable)
+	}
+	return sqltypes.MakeTrusted(sqltypes.VarBinary, ksid), nil
+}
+
+// tableScan reads the rows of a table from the primaries of all the shards of
+// a keyspace, ordered by the values of its first columns.
+type tableScan struct {
+	cursors []*shardCursor
+	// current is the cursor of the row returned by peek.
+	current *shardCursor
+	// collationIDs are the collations of the ordered columns, known once a
+	// batch of rows has been read.
+	collationIDs []collations.ID
+}
+
+// shardCursor reads the rows of a table from the primary of a shard, in
+// batches ordered by the ordered columns followed by the primary key.
+type shardCursor struct {
+	scan    *tableScan
+	v       *lookupVindexVerifier
+	primary *topo.TabletInfo
+	table   string
+	// selectCols are the columns returned by peek, followed by the columns
+	// of the primary key that aren't among them.
+	selectCols []string
+	numCols    int
+	numOrdered int
+	orderCols  []string
+	// orderIndexes are the indexes of the orderCols in selectCols.
+	orderIndexes []int
+
+	rows [][]sqltypes.Value
+	// last has the values of the orderCols of the last row read.
+	last []sqltypes.Value
+	done bool
+}
+
+// newTableScan returns a scan of the cols of a table, that start with the
+// orderedCols the rows are ordered by.
+func (v *lookupVindexVerifier) newTableScan(ctx context.Context, keyspace, table string, orderedCols, cols []string) (*tableScan, error) {
+	shards, err := v.s.ts.GetServingShards(ctx, keyspace)
+	if err != nil {
+		return nil, err
+	}
+	for _, shard := range shards {
+		if shard.PrimaryAlias == nil {
+			return nil, fmt.Errorf("shard has no primary: %v/%v", shard.Keyspace(), shard.ShardName())
+		}
+	}
+	tableSchema, err := schematools.GetSchema(ctx, v.s.ts, v.s.tmc, shards[0].PrimaryAlias, &tabletmanagerdatapb.GetSchemaRequest{Tables: []string{table}})
+	if err != nil {
+		return nil, err
+	}
+	if len(tableSchema.TableDefinitions) != 1 {
+		return nil, vterrors.Errorf(vtrpcpb.Code_NOT_FOUND, "table %s.%s not found", keyspace, table)
+	}
+	pkCols := tableSchema.TableDefinitions[0].PrimaryKeyColumns
+	if len(pkCols) == 0 {
+		return nil, vterrors.Errorf(vtrpcpb.Code_FAILED_PRECONDITION, "table %s.%s has no primary key", keyspace, table)
+	}
+
+	// The rows are also ordered by the primary key, so that each batch can
+	// start after the last row of the previous one.
+	selectCols := append([]string{}, cols...)
+	orderCols := append([]string{}, orderedCols...)
+	for _, pkCol := range pkCols {
+		if !slices.Contains(orderCols, pkCol) {
+			orderCols = append(orderCols, pkCol)
+		}
+	}
+	orderIndexes := make([]int, 0, len(orderCols))
+	for _, col := range orderCols {
+		i := slices.Index(selectCols, col)
+		if i < 0 {
+			i = len(selectCols)
+			selectCols = append(selectCols, col)
+		}
+		orderIndexes = append(orderIndexes, i)
+	}
+
+	scan := &tableScan{}
+	for _, shard := range shards {
+		primary, err := v.s.ts.GetTablet(ctx, shard.PrimaryAlias)
+		if err != nil {
+			return nil, err
+		}
+		scan.cursors = append(scan.cursors, &shardCursor{
+			scan:         scan,
+			v:            v,
+			primary:      primary,
+			table:        table,
+			selectCols:   selectCols,
+			numCols:      len(cols),
+			numOrdered:   len(orderedCols),
+			orderCols:    orderCols,
+			orderIndexes: orderIndexes,
+		})
+	}
+	return scan, nil
+}
+
+// peek returns the next row of the scan, the lowest next row of its shards,
+// without consuming it, or nil once all the rows have been read.
+func (scan *tableScan) peek(ctx context.Context) ([]sqltypes.Value, error) {
+	var row []sqltypes.Value
+	scan.current = nil
+	for _, cursor := range scan.cursors {
+		next, err := cursor.peek(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			continue
+		}
+		if row != nil {
+			cmp, err := compareLookupValues(next, row, scan.collationIDs)
+			if err != nil {
+				return nil, err
+			}
+			if cmp >= 0 {
+				continue
+			}
+		}
+		row = next
+		scan.current = cursor
+	}
+	return row, nil
+}
+
+// next consumes the row returned by peek.
+func (scan *tableScan) next() {
+	if scan.current != nil {
+		scan.current.rows = scan.current.rows[1:]
+		scan.current = nil
+	}
+}
+
+// peek returns the next row of the shard, reading the next batch of rows if
+// needed, or nil once all the rows have been read.
+func (c *shardCursor) peek(ctx context.Context) ([]sqltypes.Value, error) {
+	if len(c.rows) > 0 {
+		return c.rows[0][:c.numCols], nil
+	}
+	if c.done {
+		return nil, nil
+	}
+	query := scanTableQuery(c.table, c.selectCols, c.orderCols, c.last)
+	p3qr, err := c.v.s.tmc.ExecuteFetchAsDba(ctx, c.primary.Tablet, true, &tabletmanagerdatapb.ExecuteFetchAsDbaRequest{
+		Query:   []byte(query),
+		DbName:  c.primary.DbName(),
+		MaxRows: uint64(lookupVindexVerifyBatchSize),
+	})
+	if err != nil {
+		return nil, err
+	}
+	qr := sqltypes.Proto3ToResult(p3qr)
+	c.done = len(qr.Rows) < lookupVindexVerifyBatchSize
+	if len(qr.Rows) == 0 {
+		return nil, nil
+	}
+	if c.scan.collationIDs == nil {
+		for _, field := range qr.Fields[:c.numOrdered] {
+			collationID := collations.ID(field.Charset)
+			if collationID == collations.Unknown {
+				collationID = collations.DefaultCollationForType(field.Type)
+			}
+			c.scan.collationIDs = append(c.scan.collationIDs, collationID)
+		}
+	}
+	last := qr.Rows[len(qr.Rows)-1]
+	c.last = make([]sqltypes.Value, 0, len(c.orderIndexes))
+	for _, i := range c.orderIndexes {
+		c.last = append(c.last, last[i])
+	}
+	c.rows = qr.Rows
+	return c.rows[0][:c.numCols], nil
+}
+
+// scanTableQuery returns the query that reads the next batch of rows of a
+// table ordered by orderCols, after the row with the last values of the
+// orderCols if they are set. NULL values come first, as MySQL orders them.
+func scanTableQuery(table string, cols, orderCols []string, last []sqltypes.Value) string {
+	buf := sqlparser.NewTrackedBuffer(nil)
+	buf.Myprintf("select ")
+	for i, col := range cols {
+		if i > 0 {
+			buf.Myprintf(", ")
+		}
+		buf.Myprintf("%v", sqlparser.NewIdentifierCI(col))
+	}
+	buf.Myprintf(" from %v", sqlparser.NewIdentifierCS(table))
+	if last != nil {
+		// The rows after the last one have the same values for the first
+		// orderCols, and a greater value for the next one.
+		buf.Myprintf(" where ")
+		for i := range last {
+			if i > 0 {
+				buf.Myprintf(" or (")
+			}
+			for j := 0; j < i; j++ {
+				buf.Myprintf("%v <=> ", sqlparser.NewIdentifierCI(orderCols[j]))
+				last[j].EncodeSQLStringBuilder(buf.Builder)
+				buf.Myprintf(" and ")
+			}
+			if last[i].IsNull() {
+				buf.Myprintf("%v is not null", sqlparser.NewIdentifierCI(orderCols[i]))
+			} else {
+				buf.Myprintf("%v > ", sqlparser.NewIdentifierCI(orderCols[i]))
+				last[i].EncodeSQLStringBuilder(buf.Builder)
+			}
+			if i > 0 {
+				buf.Myprintf(")")
+			}
+		}
+	}
+	buf.Myprintf(" order by ")
+	for i, col := range orderCols {
+		if i > 0 {
+			buf.Myprintf(", ")
+		}
+		buf.Myprintf("%v", sqlparser.NewIdentifierCI(col))
+	}
+	buf.Myprintf(" limit %d", lookupVindexVerifyBatchSize)
+	return buf.String()
+}
+
+// compareLookupValues compares the first values of two rows, one for each of
+// the collations. NULL values are the lowest, the way MySQL orders them.
+func compareLookupValues(a, b []sqltypes.Value, collationIDs []collations.ID) (int, error) {
+	for i, collationID := range collationIDs {
+		cmp, err := evalengine.NullsafeCompare(a[i], b[i], collationID)
+		if err != nil || cmp != 0 {
+			return cmp, err
+		}
+	}
+	return 0, nil
+}
+
+// lookupEntryKey returns a key that identifies the values of the columns of
+// an entry of a lookup table. Only the bytes of the values are compared, but
+// NULL values don't match empty ones.
+func lookupEntryKey(values []sqltypes.Value) string {
+	var b strings.Builder
+	for _, val := range values {
+		if val.IsNull() {
+			b.WriteString("N")
+			continue
+		}
+		fmt.Fprintf(&b, "%d:", len(val.Raw()))
+		b.Write(val.Raw())
+	}
+	return b.String()
+}
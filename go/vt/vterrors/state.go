@@ -73,6 +73,7 @@ const (
 
 	// resource exhausted
 	NetPacketTooLarge
+	CTEMaxRecursionDepth
 
 	// cancelled
 	QueryInterrupted
@@ -116,6 +117,9 @@ const (
 	CharacterSetMismatch
 	WrongParametersToNativeFct
 
+	// resource exhausted
+	OutOfResources
+
 	// No state should be added below NumOfStates
 	NumOfStates
 )
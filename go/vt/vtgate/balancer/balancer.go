@@ -0,0 +1,409 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package balancer picks the tablet a vtgate sends a query to, among the
+healthy tablets of the target.
+
+The flow balancer spreads the queries so that every tablet of a target gets
+the same share of the traffic, wherever the vtgates and the tablets live. It
+assumes the vtgates are evenly spread over the cells they run in, so each of
+these cells sends the same share of the traffic.
+
+Each cell serves its own traffic with its own tablets as long as they can
+take it, i.e. while its share of the traffic is lower than its share of the
+tablets. The traffic of the cells that don't have enough tablets overflows
+to the cells that have more tablets than traffic, in proportion to how much
+more traffic they can take.
+
+For example, with vtgates in cells a and b, and 1 tablet in a, 3 in b and 2
+in c, each tablet should get 1/6 of the traffic. The vtgates of cell a send
+1/2 of the traffic, 1/3 of it goes to the tablet in a and 2/3 overflows. The
+vtgates of b send 1/2 of the traffic, which is b's share of the tablets, so
+it all goes to the tablets of b and b can't take more. Cell c sends no
+traffic, so the whole overflow of a goes to the tablets of c.
+
+The shares can be weighted by the health stats of the tablets, so that the
+ones lagging or serving more queries than the other tablets of the target get
+less traffic.
+*/
+package balancer
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"vitess.io/vitess/go/vt/discovery"
+	"vitess.io/vitess/go/vt/topo/topoproto"
+
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+)
+
+// statusInterval is how often the status of a target is saved. It is not
+// saved on every query, as the status page doesn't need it that fresh.
+const statusInterval = time.Second
+
+// TabletBalancer orders the healthy tablets of a target for a query.
+type TabletBalancer interface {
+	// ShuffleTablets reorders the tablets so that the first one is the one
+	// the query is sent to, and the next ones are the ones it is retried on.
+	ShuffleTablets(target *querypb.Target, tablets []*discovery.TabletHealth)
+
+	// Status returns the last shares of the tablets of each target,
+	// for the status page.
+	Status() []*TargetStatus
+}
+
+// Weighting is the health stat the shares of the tablets are weighted by.
+type Weighting string
+
+const (
+	// WeightingNone gives the same weight to all the tablets.
+	WeightingNone = Weighting("none")
+	// WeightingReplicationLag gives less traffic to the tablets lagging
+	// more than the other tablets of the target.
+	WeightingReplicationLag = Weighting("replication_lag")
+	// WeightingQPS gives less traffic to the tablets serving more queries
+	// than the other tablets of the target.
+	WeightingQPS = Weighting("qps")
+)
+
+// Config is the configuration of a TabletBalancer.
+type Config struct {
+	// LocalCell is the cell of this vtgate.
+	LocalCell string
+	// VTGateCells are the cells vtgates run in. If empty, vtgates are
+	// assumed to run in the cells that have tablets.
+	VTGateCells []string
+	// Weighting is the health stat the shares of the tablets are weighted by.
+	Weighting Weighting
+}
+
+// Factory creates a TabletBalancer.
+type Factory func(cfg Config) TabletBalancer
+
+var factories = map[string]Factory{
+	"flow": newFlowBalancer,
+}
+
+// Register makes a TabletBalancer available under the given name.
+func Register(name string, factory Factory) {
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("tablet balancer %s is already registered", name))
+	}
+	factories[name] = factory
+}
+
+// New creates the TabletBalancer registered under the given name.
+func New(name string, cfg Config) (TabletBalancer, error) {
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tablet balancer %s", name)
+	}
+	switch cfg.Weighting {
+	case "":
+		cfg.Weighting = WeightingNone
+	case WeightingNone, WeightingReplicationLag, WeightingQPS:
+	default:
+		return nil, fmt.Errorf("unknown tablet balancer weighting %s", cfg.Weighting)
+	}
+	return factory(cfg), nil
+}
+
+// TargetStatus is the share of the traffic of a target each tablet gets.
+type TargetStatus struct {
+	Target  *querypb.Target
+	Tablets []*TabletStatus
+}
+
+// TabletStatus is the share of the traffic of a target a tablet gets.
+type TabletStatus struct {
+	Alias string
+	Cell  string
+	// Share is the percentage of the traffic of this vtgate the tablet gets.
+	Share float64
+}
+
+// FormattedShare returns the share as a percentage.
+func (ts *TabletStatus) FormattedShare() string {
+	return fmt.Sprintf("%.1f%%", ts.Share)
+}
+
+type flowBalancer struct {
+	localCell   string
+	vtgateCells []string
+	weighting   Weighting
+
+	// targets is read on every query without any lock. It is copied
+	// under mu to add the targets seen for the first time.
+	targets atomic.Pointer[map[targetKey]*targetStatus]
+	mu      sync.Mutex
+}
+
+type targetKey struct {
+	keyspace   string
+	shard      string
+	tabletType topodatapb.TabletType
+}
+
+// targetStatus is the last status saved for a target, and when it was saved.
+type targetStatus struct {
+	savedAt atomic.Int64
+	status  atomic.Pointer[TargetStatus]
+}
+
+func newFlowBalancer(cfg Config) TabletBalancer {
+	b := &flowBalancer{
+		localCell:   cfg.LocalCell,
+		vtgateCells: cfg.VTGateCells,
+		weighting:   cfg.Weighting,
+	}
+	b.targets.Store(&map[targetKey]*targetStatus{})
+	return b
+}
+
+// ShuffleTablets is part of the TabletBalancer interface. It orders the
+// tablets at random, with each one coming first with a probability equal
+// to its share.
+func (b *flowBalancer) ShuffleTablets(target *querypb.Target, tablets []*discovery.TabletHealth) {
+	shares := b.shares(tablets)
+	b.saveStatus(target, tablets, shares)
+
+	// A weighted random permutation: the keys are drawn so that the
+	// tablet with the highest one is picked with a probability equal
+	// to its share. The tablets without any share come last.
+	keys := make([]float64, len(tablets))
+	for i, share := range shares {
+		if share > 0 {
+			keys[i] = math.Pow(rand.Float64(), 1/share)
+		} else {
+			keys[i] = -rand.Float64()
+		}
+	}
+	sort.Sort(&byKey{tablets: tablets, keys: keys})
+}
+
+// shares returns the share of the traffic of this vtgate each tablet gets,
+// which add up to 1.
+func (b *flowBalancer) shares(tablets []*discovery.TabletHealth) []float64 {
+	shares := make([]float64, len(tablets))
+	if len(tablets) == 0 {
+		return shares
+	}
+
+	tabletsPerCell := make(map[string]int)
+	for _, th := range tablets {
+		tabletsPerCell[th.Tablet.Alias.Cell]++
+	}
+
+	vtgateCells := make(map[string]bool)
+	for _, cell := range b.vtgateCells {
+		vtgateCells[cell] = true
+	}
+	if len(vtgateCells) == 0 {
+		for cell := range tabletsPerCell {
+			vtgateCells[cell] = true
+		}
+	}
+	vtgateCells[b.localCell] = true
+
+	// The share of the traffic each cell sends, and the share of the
+	// traffic the tablets of each cell should get.
+	demand := 1 / float64(len(vtgateCells))
+	capacity := func(cell string) float64 {
+		return float64(tabletsPerCell[cell]) / float64(len(tablets))
+	}
+	underflow := func(cell string) float64 {
+		under := capacity(cell)
+		if vtgateCells[cell] {
+			under -= demand
+		}
+		return math.Max(under, 0)
+	}
+
+	// The part of the local traffic served by the local tablets,
+	// and how much more traffic the other cells can take.
+	local := math.Min(capacity(b.localCell)/demand, 1)
+	var totalUnderflow float64
+	for cell := range tabletsPerCell {
+		if cell != b.localCell {
+			totalUnderflow += underflow(cell)
+		}
+	}
+
+	for i, th := range tablets {
+		cell := th.Tablet.Alias.Cell
+		switch {
+		case cell == b.localCell:
+			shares[i] = local / float64(tabletsPerCell[cell])
+		case totalUnderflow > 0:
+			shares[i] = (1 - local) * underflow(cell) / totalUnderflow / float64(tabletsPerCell[cell])
+		default:
+			// Only rounding errors can get us here.
+			shares[i] = (1 - local) / float64(len(tablets)-tabletsPerCell[b.localCell])
+		}
+	}
+
+	b.weigh(tablets, shares)
+	return shares
+}
+
+// weigh weighs the shares of the tablets by their health stats, relative to
+// the average of the tablets of the target, and normalizes them again.
+func (b *flowBalancer) weigh(tablets []*discovery.TabletHealth, shares []float64) {
+	var stat func(th *discovery.TabletHealth) float64
+	switch b.weighting {
+	case WeightingReplicationLag:
+		stat = func(th *discovery.TabletHealth) float64 { return float64(th.Stats.GetReplicationLagSeconds()) }
+	case WeightingQPS:
+		stat = func(th *discovery.TabletHealth) float64 { return th.Stats.GetQps() }
+	default:
+		return
+	}
+
+	var avg float64
+	for _, th := range tablets {
+		avg += stat(th)
+	}
+	avg /= float64(len(tablets))
+	if avg == 0 {
+		return
+	}
+
+	var total float64
+	for i, th := range tablets {
+		shares[i] /= 1 + stat(th)/avg
+		total += shares[i]
+	}
+	if total == 0 {
+		return
+	}
+	for i := range shares {
+		shares[i] /= total
+	}
+}
+
+// saveStatus saves the shares of the tablets of the target, unless they
+// were saved less than statusInterval ago.
+func (b *flowBalancer) saveStatus(target *querypb.Target, tablets []*discovery.TabletHealth, shares []float64) {
+	ts := b.targetStatus(target)
+	now := time.Now().UnixNano()
+	savedAt := ts.savedAt.Load()
+	if savedAt != 0 && now-savedAt < int64(statusInterval) {
+		return
+	}
+	if !ts.savedAt.CompareAndSwap(savedAt, now) {
+		// Another query is saving it.
+		return
+	}
+
+	status := &TargetStatus{Target: target}
+	for i, th := range tablets {
+		status.Tablets = append(status.Tablets, &TabletStatus{
+			Alias: topoproto.TabletAliasString(th.Tablet.Alias),
+			Cell:  th.Tablet.Alias.Cell,
+			Share: shares[i] * 100,
+		})
+	}
+	ts.status.Store(status)
+}
+
+// targetStatus returns the status of the target, and adds it if this is
+// the first time the target is seen.
+func (b *flowBalancer) targetStatus(target *querypb.Target) *targetStatus {
+	key := targetKey{keyspace: target.Keyspace, shard: target.Shard, tabletType: target.TabletType}
+	if ts, ok := (*b.targets.Load())[key]; ok {
+		return ts
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	targets := *b.targets.Load()
+	if ts, ok := targets[key]; ok {
+		return ts
+	}
+	newTargets := make(map[targetKey]*targetStatus, len(targets)+1)
+	for k, ts := range targets {
+		newTargets[k] = ts
+	}
+	ts := &targetStatus{}
+	newTargets[key] = ts
+	b.targets.Store(&newTargets)
+	return ts
+}
+
+// Status is part of the TabletBalancer interface.
+func (b *flowBalancer) Status() []*TargetStatus {
+	targets := *b.targets.Load()
+	status := make([]*TargetStatus, 0, len(targets))
+	for _, ts := range targets {
+		if s := ts.status.Load(); s != nil {
+			status = append(status, s)
+		}
+	}
+	sort.Slice(status, func(i, j int) bool {
+		ti, tj := status[i].Target, status[j].Target
+		if ti.Keyspace != tj.Keyspace {
+			return ti.Keyspace < tj.Keyspace
+		}
+		if ti.Shard != tj.Shard {
+			return ti.Shard < tj.Shard
+		}
+		return ti.TabletType < tj.TabletType
+	})
+	return status
+}
+
+type byKey struct {
+	tablets []*discovery.TabletHealth
+	keys    []float64
+}
+
+func (s *byKey) Len() int           { return len(s.tablets) }
+func (s *byKey) Less(i, j int) bool { return s.keys[i] > s.keys[j] }
+func (s *byKey) Swap(i, j int) {
+	s.tablets[i], s.tablets[j] = s.tablets[j], s.tablets[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+// StatusTemplate is the display part to use to show the shares of the
+// tablets picked by the balancer.
+const StatusTemplate = `
+<table>
+  <tr>
+    <th>Target</th>
+    <th>Tablet</th>
+    <th>Cell</th>
+    <th>Share</th>
+  </tr>
+  {{range $i, $target := .}}{{range $j, $tablet := $target.Tablets}}
+  <tr>
+    <td>{{$target.Target.Keyspace}}/{{$target.Target.Shard}} ({{$target.Target.TabletType}})</td>
+    <td>{{$tablet.Alias}}</td>
+    <td>{{$tablet.Cell}}</td>
+    <td>{{$tablet.FormattedShare}}</td>
+  </tr>
+  {{end}}{{else}}
+  <tr><td colspan="4">The tablet balancer isn't enabled or hasn't picked any tablet yet.</td></tr>
+  {{end}}
+</table>
+`
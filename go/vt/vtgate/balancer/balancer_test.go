@@ -0,0 +1,187 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package balancer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/discovery"
+
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+)
+
+var target = &querypb.Target{Keyspace: "ks", Shard: "-80", TabletType: topodatapb.TabletType_REPLICA}
+
+func createTablets(cells ...string) []*discovery.TabletHealth {
+	var tablets []*discovery.TabletHealth
+	for i, cell := range cells {
+		tablets = append(tablets, &discovery.TabletHealth{
+			Tablet: &topodatapb.Tablet{
+				Alias: &topodatapb.TabletAlias{Cell: cell, Uid: uint32(100 + i)},
+			},
+			Target:  target,
+			Serving: true,
+			Stats:   &querypb.RealtimeStats{},
+		})
+	}
+	return tablets
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("unknown", Config{})
+	assert.EqualError(t, err, "unknown tablet balancer unknown")
+
+	_, err = New("flow", Config{Weighting: "cpu"})
+	assert.EqualError(t, err, "unknown tablet balancer weighting cpu")
+
+	b, err := New("flow", Config{LocalCell: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, WeightingNone, b.(*flowBalancer).weighting)
+
+	assert.Panics(t, func() { Register("flow", newFlowBalancer) })
+}
+
+func TestShares(t *testing.T) {
+	tests := []struct {
+		name        string
+		localCell   string
+		vtgateCells []string
+		cells       []string
+		shares      []float64
+	}{{
+		name:        "local cell takes all its traffic",
+		localCell:   "b",
+		vtgateCells: []string{"a", "b"},
+		cells:       []string{"a", "b", "b", "b", "c", "c"},
+		shares:      []float64{0, 1. / 3, 1. / 3, 1. / 3, 0, 0},
+	}, {
+		name:        "local cell overflows",
+		localCell:   "a",
+		vtgateCells: []string{"a", "b"},
+		cells:       []string{"a", "b", "b", "b", "c", "c"},
+		// b can take 1/2-1/2=0 more and c 1/3 more, so the overflow all
+		// goes to c.
+		shares: []float64{1. / 3, 0, 0, 0, 1. / 3, 1. / 3},
+	}, {
+		name:      "vtgates in the cells with tablets",
+		localCell: "a",
+		cells:     []string{"a", "b", "b", "b"},
+		shares:    []float64{1. / 2, 1. / 6, 1. / 6, 1. / 6},
+	}, {
+		name:        "no local tablet",
+		localCell:   "a",
+		vtgateCells: []string{"a", "b"},
+		cells:       []string{"b", "c"},
+		shares:      []float64{0, 1},
+	}, {
+		name:        "evenly spread",
+		localCell:   "a",
+		vtgateCells: []string{"a", "b"},
+		cells:       []string{"a", "a", "b", "b"},
+		shares:      []float64{1. / 2, 1. / 2, 0, 0},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newFlowBalancer(Config{LocalCell: tt.localCell, VTGateCells: tt.vtgateCells, Weighting: WeightingNone}).(*flowBalancer)
+			shares := b.shares(createTablets(tt.cells...))
+			require.Len(t, shares, len(tt.shares))
+			var total float64
+			for i := range shares {
+				assert.InDelta(t, tt.shares[i], shares[i], 1e-9, "tablet %d", i)
+				total += shares[i]
+			}
+			assert.InDelta(t, 1, total, 1e-9)
+		})
+	}
+}
+
+func TestSharesWeighting(t *testing.T) {
+	tablets := createTablets("a", "a")
+	tablets[0].Stats.ReplicationLagSeconds = 30
+	tablets[0].Stats.Qps = 100
+	tablets[1].Stats.Qps = 100
+
+	// The average lag is 15s, so the weights are 1/3 and 1.
+	b := newFlowBalancer(Config{LocalCell: "a", Weighting: WeightingReplicationLag}).(*flowBalancer)
+	shares := b.shares(tablets)
+	assert.InDelta(t, 0.25, shares[0], 1e-9)
+	assert.InDelta(t, 0.75, shares[1], 1e-9)
+
+	// The same QPS gives the same shares.
+	b = newFlowBalancer(Config{LocalCell: "a", Weighting: WeightingQPS}).(*flowBalancer)
+	shares = b.shares(tablets)
+	assert.InDelta(t, 0.5, shares[0], 1e-9)
+	assert.InDelta(t, 0.5, shares[1], 1e-9)
+}
+
+func TestShuffleTablets(t *testing.T) {
+	b := newFlowBalancer(Config{LocalCell: "a", VTGateCells: []string{"a", "b"}})
+
+	picked := make(map[string]int)
+	const n = 30000
+	for i := 0; i < n; i++ {
+		tablets := createTablets("a", "b", "b", "b", "c", "c")
+		b.ShuffleTablets(target, tablets)
+		require.Len(t, tablets, 6)
+		picked[tablets[0].Tablet.Alias.Cell]++
+		// The tablets of b don't get any traffic, so they come last.
+		for _, th := range tablets[3:] {
+			assert.Equal(t, "b", th.Tablet.Alias.Cell)
+		}
+	}
+	assert.InDelta(t, n/3, picked["a"], n/30)
+	assert.InDelta(t, 2*n/3, picked["c"], n/30)
+
+	status := b.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, target, status[0].Target)
+	require.Len(t, status[0].Tablets, 6)
+	var total float64
+	for _, ts := range status[0].Tablets {
+		total += ts.Share
+	}
+	assert.InDelta(t, 100, total, 1e-6)
+	assert.Equal(t, fmt.Sprintf("%.1f%%", status[0].Tablets[0].Share), status[0].Tablets[0].FormattedShare())
+}
+
+func TestStatusSampling(t *testing.T) {
+	b := newFlowBalancer(Config{LocalCell: "a"}).(*flowBalancer)
+	assert.Empty(t, b.Status())
+
+	// The status of a target is saved on its first query, and not again
+	// until statusInterval has passed.
+	b.ShuffleTablets(target, createTablets("a", "a"))
+	b.ShuffleTablets(target, createTablets("a", "a", "a"))
+	other := &querypb.Target{Keyspace: "ks", Shard: "80-", TabletType: topodatapb.TabletType_REPLICA}
+	b.ShuffleTablets(other, createTablets("a"))
+
+	status := b.Status()
+	require.Len(t, status, 2)
+	assert.Equal(t, target, status[0].Target)
+	assert.Len(t, status[0].Tablets, 2)
+	assert.Equal(t, other, status[1].Target)
+	assert.Len(t, status[1].Tablets, 1)
+
+	b.targetStatus(target).savedAt.Add(-int64(statusInterval))
+	b.ShuffleTablets(target, createTablets("a", "a", "a"))
+	assert.Len(t, b.Status()[0].Tablets, 3)
+}
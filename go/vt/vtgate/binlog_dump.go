@@ -0,0 +1,448 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"vitess.io/vitess/go/mysql"
+	"vitess.io/vitess/go/mysql/binlog"
+	"vitess.io/vitess/go/mysql/sqlerror"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/servenv"
+	"vitess.io/vitess/go/vt/topo"
+	"vitess.io/vitess/go/vt/vterrors"
+
+	binlogdatapb "vitess.io/vitess/go/vt/proto/binlogdata"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+)
+
+// binlogDumpFilePrefix starts the names of the binlog files served by the vtgate.
+// A name is made of the prefix, the id of the stream and a sequence number, like
+// vt-0123456789abcdef.000042, whatever the number of shards of the keyspace.
+// The VGTID every file starts at is stored in the global topo, so the file name
+// a client saves is all it needs to resume the stream, on any vtgate.
+const binlogDumpFilePrefix = "vt-"
+
+// binlogDumpServerID is the server id found in the events served by the vtgate
+const binlogDumpServerID = 1
+
+var binlogDumpFileNameRegexp = regexp.MustCompile(`^vt-([0-9a-f]{16})\.([0-9]{6,})$`)
+
+// binlogDumpFileName returns the name of a binlog file of a stream
+func binlogDumpFileName(stream string, seq uint64) string {
+	return fmt.Sprintf("%s%s.%06d", binlogDumpFilePrefix, stream, seq)
+}
+
+// parseBinlogDumpFileName returns the stream and the sequence number of one of our binlog files
+func parseBinlogDumpFileName(name string) (string, uint64, bool) {
+	match := binlogDumpFileNameRegexp.FindStringSubmatch(name)
+	if match == nil {
+		return "", 0, false
+	}
+	seq, err := strconv.ParseUint(match[2], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return match[1], seq, true
+}
+
+// binlogDump translates the events of a VStream into the row based
+// binlog events a MySQL server would send to its replicas.
+type binlogDump struct {
+	ctx      context.Context
+	ts       *topo.Server
+	keyspace string
+	format   mysql.BinlogFormat
+	stream   *mysql.FakeBinlogStream
+	vgtid    *binlogdatapb.VGtid
+
+	// id and seq name the current binlog file. The first file of a stream,
+	// with the sequence number 0, is the only one without a stored position:
+	// it is sent before we know the position of the shards.
+	id  string
+	seq uint64
+	// fileVgtid is the VGTID the current file starts at, and rotated is when we moved to it
+	fileVgtid *binlogdatapb.VGtid
+	rotated   time.Time
+
+	// rotateInterval is the minimum time between two rotations, and
+	// retainedFiles the number of files of the stream we keep the position of
+	rotateInterval time.Duration
+	retainedFiles  int
+	// streamTTL is how long the positions of a stream no client uses are kept,
+	// and active when we last stored that the stream is in use
+	streamTTL time.Duration
+	active    time.Time
+
+	inTransaction bool
+
+	// tables are the tables we got a FIELD event for, by name
+	tables      map[string]*binlogDumpTable
+	nextTableID uint64
+
+	send func(mysql.BinlogEvent) error
+}
+
+// binlogDumpTable is what we know about a table of the stream
+type binlogDumpTable struct {
+	id       uint64
+	fields   []*querypb.Field
+	tableMap *mysql.TableMap
+}
+
+func newBinlogDump(ctx context.Context, ts *topo.Server, keyspace string, rotateInterval time.Duration, retainedFiles int, streamTTL time.Duration, send func(mysql.BinlogEvent) error) *binlogDump {
+	format := mysql.NewMySQL56BinlogFormat()
+	format.ServerVersion = servenv.MySQLServerVersion()
+	return &binlogDump{
+		ctx:      ctx,
+		ts:       ts,
+		keyspace: keyspace,
+		format:   format,
+		stream: &mysql.FakeBinlogStream{
+			ServerID: binlogDumpServerID,
+		},
+		rotateInterval: rotateInterval,
+		retainedFiles:  retainedFiles,
+		streamTTL:      streamTTL,
+		tables:         make(map[string]*binlogDumpTable),
+		nextTableID:    1,
+		send:           send,
+	}
+}
+
+// position returns the VGTID to start streaming from, for a client that asked for the given binlog file.
+// Without a binlog file of ours, a new stream starts at the current position of all the shards of the keyspace.
+func (bd *binlogDump) position(logFile string) (*binlogdatapb.VGtid, error) {
+	bd.vgtid = &binlogdatapb.VGtid{
+		ShardGtids: []*binlogdatapb.ShardGtid{{
+			Keyspace: bd.keyspace,
+			Gtid:     "current",
+		}},
+	}
+	id, seq, ok := parseBinlogDumpFileName(logFile)
+	if err := bd.purgeExpiredStreams(id); err != nil {
+		return nil, err
+	}
+	if !ok {
+		bd.id = fmt.Sprintf("%016x", rand.Uint64())
+		return bd.vgtid, nil
+	}
+
+	files, err := bd.ts.GetBinlogDumpFiles(bd.ctx, bd.keyspace, id)
+	if err != nil {
+		return nil, err
+	}
+	bd.id, bd.seq = id, seq
+	if seq > 0 {
+		bd.vgtid, err = bd.ts.GetBinlogDumpPosition(bd.ctx, bd.keyspace, id, seq)
+		if topo.IsErrType(err, topo.NoNode) {
+			return nil, sqlerror.NewSQLError(sqlerror.ERMasterFatalReadingBinlog, sqlerror.SSUnknownSQLState, "Could not find first log file name in binary log index file: %s", logFile)
+		}
+		if err != nil {
+			return nil, err
+		}
+		bd.fileVgtid = bd.vgtid
+		bd.rotated = time.Now()
+		if err := bd.touch(); err != nil {
+			return nil, err
+		}
+	}
+
+	// The client has everything up to the file it resumes from: the files before
+	// are not needed anymore, and the files after were sent by a previous
+	// connection, and will be sent again from a different position.
+	for _, file := range files {
+		if file == seq {
+			continue
+		}
+		if err := bd.ts.DeleteBinlogDumpPosition(bd.ctx, bd.keyspace, id, file); err != nil && !topo.IsErrType(err, topo.NoNode) {
+			return nil, err
+		}
+	}
+	return bd.vgtid, nil
+}
+
+// purgeExpiredStreams purges the positions of the streams of the keyspace no client was served
+// for longer than the stream TTL, such as the streams of the clients that went away for good.
+func (bd *binlogDump) purgeExpiredStreams(except string) error {
+	if bd.streamTTL <= 0 {
+		return nil
+	}
+	streams, err := bd.ts.GetBinlogDumpStreams(bd.ctx, bd.keyspace)
+	if err != nil {
+		return err
+	}
+	for _, stream := range streams {
+		if stream == except {
+			continue
+		}
+		active, err := bd.ts.GetBinlogDumpActiveTime(bd.ctx, bd.keyspace, stream)
+		if err != nil && !topo.IsErrType(err, topo.NoNode) {
+			return err
+		}
+		// A stream without an active time was never served by a vtgate with a stream TTL.
+		if err == nil && time.Since(active) < bd.streamTTL {
+			continue
+		}
+		if err := bd.ts.DeleteBinlogDumpStream(bd.ctx, bd.keyspace, stream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// touch stores that the stream is in use, so it is not purged.
+func (bd *binlogDump) touch() error {
+	if bd.streamTTL <= 0 {
+		return nil
+	}
+	now := time.Now()
+	if err := bd.ts.UpdateBinlogDumpActiveTime(bd.ctx, bd.keyspace, bd.id, now); err != nil {
+		return err
+	}
+	bd.active = now
+	return nil
+}
+
+// maybeTouch stores that a stream with stored positions is in use, a few times per stream TTL.
+func (bd *binlogDump) maybeTouch() error {
+	if bd.seq == 0 || time.Since(bd.active) < bd.streamTTL/4 {
+		return nil
+	}
+	return bd.touch()
+}
+
+// start sends the events a MySQL server sends at the beginning of a binlog dump:
+// a fake rotation to the requested file, followed by the format description of the file.
+func (bd *binlogDump) start() error {
+	name := binlogDumpFileName(bd.id, bd.seq)
+	if err := bd.emit(mysql.NewFakeRotateEvent(bd.format, bd.stream, name), false); err != nil {
+		return err
+	}
+	bd.stream.LogPosition = 4
+	return bd.emit(mysql.NewFormatDescriptionEvent(bd.format, bd.stream), true)
+}
+
+// maybeRotate moves the stream to a new file that starts at the current VGTID,
+// if the position moved since the start of the current file, and the current
+// file is old enough. The first file of a stream is left as soon as possible,
+// so the client gets a position it can resume from.
+func (bd *binlogDump) maybeRotate() error {
+	if bd.inTransaction || bd.vgtid == nil || proto.Equal(bd.vgtid, bd.fileVgtid) {
+		return nil
+	}
+	if bd.fileVgtid != nil && time.Since(bd.rotated) < bd.rotateInterval {
+		return nil
+	}
+	return bd.rotate()
+}
+
+// rotate moves the stream to a new file that starts at the current VGTID.
+// The position of the file is stored before the client hears about it.
+func (bd *binlogDump) rotate() error {
+	seq := bd.seq + 1
+	// The stream is marked as in use before it has positions, so it is never purged as unused.
+	if err := bd.touch(); err != nil {
+		return err
+	}
+	err := bd.ts.CreateBinlogDumpPosition(bd.ctx, bd.keyspace, bd.id, seq, bd.vgtid)
+	if topo.IsErrType(err, topo.NodeExists) {
+		// Another connection is serving the same stream, from the same file.
+		return vterrors.Errorf(vtrpcpb.Code_ABORTED, "binlog file %s was created by another connection", binlogDumpFileName(bd.id, seq))
+	}
+	if err != nil {
+		return err
+	}
+	if bd.retainedFiles > 0 && seq > uint64(bd.retainedFiles) {
+		err := bd.ts.DeleteBinlogDumpPosition(bd.ctx, bd.keyspace, bd.id, seq-uint64(bd.retainedFiles))
+		if err != nil && !topo.IsErrType(err, topo.NoNode) {
+			return err
+		}
+	}
+	bd.seq = seq
+	bd.fileVgtid = bd.vgtid
+	bd.rotated = time.Now()
+
+	if err := bd.emit(mysql.NewRotateEvent(bd.format, bd.stream, 4, binlogDumpFileName(bd.id, bd.seq)), true); err != nil {
+		return err
+	}
+	bd.stream.LogPosition = 4
+	return bd.emit(mysql.NewFormatDescriptionEvent(bd.format, bd.stream), true)
+}
+
+// emit sends an event, after setting its log position to the position of the next event
+func (bd *binlogDump) emit(ev mysql.BinlogEvent, advance bool) error {
+	if advance {
+		buf := ev.Bytes()
+		bd.stream.LogPosition += uint32(len(buf))
+		binary.LittleEndian.PutUint32(buf[13:17], bd.stream.LogPosition)
+		checksum := crc32.ChecksumIEEE(buf[:len(buf)-4])
+		binary.LittleEndian.PutUint32(buf[len(buf)-4:], checksum)
+	}
+	return bd.send(ev)
+}
+
+// handle is the callback of the VStream
+func (bd *binlogDump) handle(events []*binlogdatapb.VEvent) error {
+	for _, ev := range events {
+		if ev.Timestamp > 0 {
+			bd.stream.Timestamp = uint32(ev.Timestamp)
+		}
+		var err error
+		switch ev.Type {
+		case binlogdatapb.VEventType_VGTID:
+			bd.vgtid = ev.Vgtid
+		case binlogdatapb.VEventType_FIELD:
+			bd.addTable(ev.FieldEvent)
+		case binlogdatapb.VEventType_BEGIN:
+			bd.inTransaction = true
+			err = bd.emit(mysql.NewQueryEvent(bd.format, bd.stream, mysql.Query{Database: bd.keyspace, SQL: "BEGIN"}), true)
+		case binlogdatapb.VEventType_ROW:
+			err = bd.rows(ev.RowEvent)
+		case binlogdatapb.VEventType_COMMIT:
+			bd.inTransaction = false
+			if err = bd.emit(mysql.NewXIDEvent(bd.format, bd.stream), true); err == nil {
+				err = bd.maybeRotate()
+			}
+		case binlogdatapb.VEventType_DDL:
+			if err = bd.emit(mysql.NewQueryEvent(bd.format, bd.stream, mysql.Query{Database: bd.keyspace, SQL: ev.Statement}), true); err == nil {
+				err = bd.maybeRotate()
+			}
+		case binlogdatapb.VEventType_HEARTBEAT:
+			if err = bd.emit(mysql.NewHeartbeatEvent(bd.format, bd.stream), false); err == nil {
+				err = bd.maybeRotate()
+			}
+			if err == nil {
+				err = bd.maybeTouch()
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addTable records the fields of a table, and builds the table map sent along with its rows
+func (bd *binlogDump) addTable(fe *binlogdatapb.FieldEvent) {
+	_, name, _ := strings.Cut(fe.TableName, ".")
+	tm := &mysql.TableMap{
+		Database:  bd.keyspace,
+		Name:      name,
+		Types:     make([]byte, len(fe.Fields)),
+		CanBeNull: mysql.NewServerBitmap(len(fe.Fields)),
+		Metadata:  make([]uint16, len(fe.Fields)),
+	}
+	for i, field := range fe.Fields {
+		tm.Types[i], tm.Metadata[i] = binlog.TypeForField(field)
+		tm.CanBeNull.Set(i, true)
+	}
+	bd.tables[fe.TableName] = &binlogDumpTable{
+		id:       bd.nextTableID,
+		fields:   fe.Fields,
+		tableMap: tm,
+	}
+	bd.nextTableID++
+}
+
+// rows sends the table map of a table, followed by an event for each row change.
+// Like MySQL, every row change has all the columns of the table before and after the change.
+func (bd *binlogDump) rows(re *binlogdatapb.RowEvent) error {
+	table, ok := bd.tables[re.TableName]
+	if !ok {
+		return vterrors.Errorf(vtrpcpb.Code_INTERNAL, "row event for table %s without a field event", re.TableName)
+	}
+	if err := bd.emit(mysql.NewTableMapEvent(bd.format, bd.stream, table.id, table.tableMap), true); err != nil {
+		return err
+	}
+
+	for i, change := range re.RowChanges {
+		count := len(table.fields)
+		rows := mysql.Rows{}
+		if i == len(re.RowChanges)-1 {
+			rows.Flags = 0x0001 // STMT_END_F
+		}
+		row := mysql.Row{}
+		var err error
+		// the bitmaps an event does not use must stay empty, as they count in its length
+		if change.Before != nil {
+			rows.IdentifyColumns = mysql.NewServerBitmap(count)
+			for c := 0; c < count; c++ {
+				rows.IdentifyColumns.Set(c, true)
+			}
+			row.NullIdentifyColumns, row.Identify, err = bd.encodeRow(table, change.Before)
+			if err != nil {
+				return err
+			}
+		}
+		if change.After != nil {
+			rows.DataColumns = mysql.NewServerBitmap(count)
+			for c := 0; c < count; c++ {
+				rows.DataColumns.Set(c, true)
+			}
+			row.NullColumns, row.Data, err = bd.encodeRow(table, change.After)
+			if err != nil {
+				return err
+			}
+		}
+		rows.Rows = []mysql.Row{row}
+
+		var ev mysql.BinlogEvent
+		switch {
+		case change.Before == nil:
+			ev = mysql.NewWriteRowsEvent(bd.format, bd.stream, table.id, rows)
+		case change.After == nil:
+			ev = mysql.NewDeleteRowsEvent(bd.format, bd.stream, table.id, rows)
+		default:
+			ev = mysql.NewUpdateRowsEvent(bd.format, bd.stream, table.id, rows)
+		}
+		if err := bd.emit(ev, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bd *binlogDump) encodeRow(table *binlogDumpTable, row *querypb.Row) (mysql.Bitmap, []byte, error) {
+	values := sqltypes.MakeRowTrusted(table.fields, row)
+	nulls := mysql.NewServerBitmap(len(values))
+	var data []byte
+	for c, value := range values {
+		if value.IsNull() {
+			nulls.Set(c, true)
+			continue
+		}
+		cell, err := binlog.CellBytes(value, table.tableMap.Types[c], table.tableMap.Metadata[c])
+		if err != nil {
+			return mysql.Bitmap{}, nil, vterrors.Wrapf(err, "column %s of table %s", table.fields[c].Name, table.tableMap.Name)
+		}
+		data = append(data, cell...)
+	}
+	return nulls, data, nil
+}
@@ -0,0 +1,365 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"vitess.io/vitess/go/mysql"
+	"vitess.io/vitess/go/mysql/replication"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/test/utils"
+	"vitess.io/vitess/go/vt/topo/memorytopo"
+
+	binlogdatapb "vitess.io/vitess/go/vt/proto/binlogdata"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+)
+
+func TestBinlogDumpFileName(t *testing.T) {
+	name := binlogDumpFileName("0123456789abcdef", 42)
+	assert.Equal(t, "vt-0123456789abcdef.000042", name)
+	id, seq, ok := parseBinlogDumpFileName(name)
+	require.True(t, ok)
+	assert.Equal(t, "0123456789abcdef", id)
+	assert.EqualValues(t, 42, seq)
+
+	id, seq, ok = parseBinlogDumpFileName("vt-0123456789abcdef.1234567")
+	require.True(t, ok)
+	assert.Equal(t, "0123456789abcdef", id)
+	assert.EqualValues(t, 1234567, seq)
+
+	for _, name := range []string{"binlog.000001", "vt-0123.000001", "vt-0123456789abcdef.42", "vt-0123456789abcdef.000042/../1"} {
+		_, _, ok := parseBinlogDumpFileName(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestBinlogDumpPosition(t *testing.T) {
+	ctx := utils.LeakCheckContext(t)
+	ts := memorytopo.NewServer(ctx, "cell")
+	defer ts.Close()
+
+	// without one of our files, a new stream starts from the current position
+	bd := newBinlogDump(ctx, ts, "ks", 0, 0, 0, nil)
+	vgtid, err := bd.position("binlog.000001")
+	require.NoError(t, err)
+	assert.Equal(t, "current", vgtid.ShardGtids[0].Gtid)
+	assert.Empty(t, vgtid.ShardGtids[0].Shard)
+	assert.Len(t, bd.id, 16)
+	assert.Zero(t, bd.seq)
+
+	// the file names do not depend on the number of shards
+	var shards []*binlogdatapb.ShardGtid
+	for i := 0; i < 256; i++ {
+		shards = append(shards, &binlogdatapb.ShardGtid{
+			Keyspace: "ks",
+			Shard:    fmt.Sprintf("%02x-%02x", i, i+1),
+			Gtid:     fmt.Sprintf("MySQL56/0d4fd8c6-3c8b-11ee-a5c7-0242ac1100%02x:1-%d,1a2b3c4d-3c8b-11ee-a5c7-0242ac1100%02x:1-7", i, 1000+i, i),
+		})
+	}
+	want := &binlogdatapb.VGtid{ShardGtids: shards}
+	var files []string
+	bd.send = func(ev mysql.BinlogEvent) error {
+		if ev.IsRotate() {
+			file, _, err := ev.NextLogFile(mysql.NewMySQL56BinlogFormat())
+			require.NoError(t, err)
+			files = append(files, file)
+		}
+		return nil
+	}
+	require.NoError(t, bd.handle([]*binlogdatapb.VEvent{
+		{Type: binlogdatapb.VEventType_VGTID, Vgtid: want},
+		{Type: binlogdatapb.VEventType_HEARTBEAT},
+	}))
+	require.Len(t, files, 1)
+	assert.Equal(t, binlogDumpFileName(bd.id, 1), files[0])
+	assert.Len(t, files[0], len("vt-0123456789abcdef.000001"))
+
+	// a client resumes from the file, on any vtgate
+	resumed := newBinlogDump(ctx, ts, "ks", 0, 0, 0, nil)
+	got, err := resumed.position(files[0])
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(want, got))
+	assert.Equal(t, bd.id, resumed.id)
+	assert.EqualValues(t, 1, resumed.seq)
+
+	// the files of a keyspace cannot be used for another one, and purged files are gone
+	_, err = newBinlogDump(ctx, ts, "other", 0, 0, 0, nil).position(files[0])
+	assert.ErrorContains(t, err, "Could not find first log file name in binary log index file")
+	_, err = newBinlogDump(ctx, ts, "ks", 0, 0, 0, nil).position(binlogDumpFileName(bd.id, 2))
+	assert.ErrorContains(t, err, "Could not find first log file name in binary log index file")
+}
+
+func TestBinlogDumpRetention(t *testing.T) {
+	ctx := utils.LeakCheckContext(t)
+	ts := memorytopo.NewServer(ctx, "cell")
+	defer ts.Close()
+
+	position := func(i int) *binlogdatapb.VGtid {
+		return &binlogdatapb.VGtid{ShardGtids: []*binlogdatapb.ShardGtid{{Keyspace: "ks", Shard: "-80", Gtid: fmt.Sprintf("MySQL56/0d4fd8c6-3c8b-11ee-a5c7-0242ac110002:1-%d", i)}}}
+	}
+	commit := func(bd *binlogDump, i int) error {
+		return bd.handle([]*binlogdatapb.VEvent{
+			{Type: binlogdatapb.VEventType_BEGIN},
+			{Type: binlogdatapb.VEventType_VGTID, Vgtid: position(i)},
+			{Type: binlogdatapb.VEventType_COMMIT},
+		})
+	}
+	send := func(mysql.BinlogEvent) error { return nil }
+
+	bd := newBinlogDump(ctx, ts, "ks", 0, 3, 0, send)
+	_, err := bd.position("")
+	require.NoError(t, err)
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, commit(bd, i))
+	}
+	files, err := ts.GetBinlogDumpFiles(ctx, "ks", bd.id)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{3, 4, 5}, files)
+
+	// when the client resumes, the files it does not need anymore are purged,
+	// and the stream goes on from the file it resumed from
+	resumed := newBinlogDump(ctx, ts, "ks", 0, 3, 0, send)
+	vgtid, err := resumed.position(binlogDumpFileName(bd.id, 4))
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(position(4), vgtid))
+	files, err = ts.GetBinlogDumpFiles(ctx, "ks", bd.id)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{4}, files)
+
+	require.NoError(t, commit(resumed, 5))
+	got, err := ts.GetBinlogDumpPosition(ctx, "ks", bd.id, 5)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(position(5), got))
+
+	// the old connection cannot go on with the same stream
+	bd.seq = 4
+	assert.ErrorContains(t, commit(bd, 6), "was created by another connection")
+
+	// without a newer position, there is nothing to rotate to
+	require.NoError(t, resumed.handle([]*binlogdatapb.VEvent{{Type: binlogdatapb.VEventType_HEARTBEAT}}))
+	assert.EqualValues(t, 5, resumed.seq)
+
+	// and files are not rotated more often than asked for
+	resumed.rotateInterval = time.Hour
+	require.NoError(t, commit(resumed, 6))
+	assert.EqualValues(t, 5, resumed.seq)
+}
+
+func TestBinlogDumpStreamTTL(t *testing.T) {
+	ctx := utils.LeakCheckContext(t)
+	ts := memorytopo.NewServer(ctx, "cell")
+	defer ts.Close()
+
+	vgtid := &binlogdatapb.VGtid{ShardGtids: []*binlogdatapb.ShardGtid{{Keyspace: "ks", Shard: "-80", Gtid: "MySQL56/0d4fd8c6-3c8b-11ee-a5c7-0242ac110002:1-5"}}}
+	send := func(mysql.BinlogEvent) error { return nil }
+	stream := func() *binlogDump {
+		bd := newBinlogDump(ctx, ts, "ks", 0, 0, time.Hour, send)
+		_, err := bd.position("")
+		require.NoError(t, err)
+		require.NoError(t, bd.handle([]*binlogdatapb.VEvent{
+			{Type: binlogdatapb.VEventType_VGTID, Vgtid: vgtid},
+			{Type: binlogdatapb.VEventType_HEARTBEAT},
+		}))
+		return bd
+	}
+	abandoned := stream()
+	active := stream()
+
+	// the abandoned stream was last served more than a TTL ago
+	require.NoError(t, ts.UpdateBinlogDumpActiveTime(ctx, "ks", abandoned.id, time.Now().Add(-2*time.Hour)))
+	// and a stream of a vtgate without a TTL has no active time
+	require.NoError(t, ts.CreateBinlogDumpPosition(ctx, "ks", "0123456789abcdef", 1, vgtid))
+
+	// a new connection purges the streams no client was served for longer than the TTL
+	stream()
+	streams, err := ts.GetBinlogDumpStreams(ctx, "ks")
+	require.NoError(t, err)
+	assert.Len(t, streams, 2)
+	assert.Contains(t, streams, active.id)
+	assert.NotContains(t, streams, abandoned.id)
+	_, err = newBinlogDump(ctx, ts, "ks", 0, 0, time.Hour, send).position(binlogDumpFileName(abandoned.id, 1))
+	assert.ErrorContains(t, err, "Could not find first log file name in binary log index file")
+
+	// the active stream stays, and a resumed stream is marked as in use
+	before, err := ts.GetBinlogDumpActiveTime(ctx, "ks", active.id)
+	require.NoError(t, err)
+	_, err = newBinlogDump(ctx, ts, "ks", 0, 0, time.Hour, send).position(binlogDumpFileName(active.id, 1))
+	require.NoError(t, err)
+	after, err := ts.GetBinlogDumpActiveTime(ctx, "ks", active.id)
+	require.NoError(t, err)
+	assert.False(t, after.Before(before))
+}
+
+func TestBinlogDumpAllowed(t *testing.T) {
+	defer func(users string) { mysqlBinlogDumpUsers = users }(mysqlBinlogDumpUsers)
+
+	mysqlBinlogDumpUsers = ""
+	assert.False(t, binlogDumpAllowed("user1"))
+	assert.False(t, binlogDumpAllowed(""))
+
+	mysqlBinlogDumpUsers = "user1, user2"
+	assert.True(t, binlogDumpAllowed("user1"))
+	assert.True(t, binlogDumpAllowed("user2"))
+	assert.False(t, binlogDumpAllowed("user3"))
+	assert.False(t, binlogDumpAllowed(""))
+}
+
+func TestBinlogDumpGTIDSet(t *testing.T) {
+	gtidSet, err := replication.ParseMysql56GTIDSet("0d4fd8c6-3c8b-11ee-a5c7-0242ac110002:1-42")
+	require.NoError(t, err)
+	err = (&vtgateHandler{}).ComBinlogDumpGTID(nil, "", 4, gtidSet)
+	assert.ErrorContains(t, err, "resuming a binlog dump from the GTID set 0d4fd8c6-3c8b-11ee-a5c7-0242ac110002:1-42")
+}
+
+func TestBinlogDumpEvents(t *testing.T) {
+	ctx := utils.LeakCheckContext(t)
+	ts := memorytopo.NewServer(ctx, "cell")
+	defer ts.Close()
+
+	var events []mysql.BinlogEvent
+	start := &binlogdatapb.VGtid{ShardGtids: []*binlogdatapb.ShardGtid{{Keyspace: "ks", Shard: "-80", Gtid: "MySQL56/0d4fd8c6-3c8b-11ee-a5c7-0242ac110002:1-41"}}}
+	require.NoError(t, ts.CreateBinlogDumpPosition(ctx, "ks", "0123456789abcdef", 1, start))
+	bd := newBinlogDump(ctx, ts, "ks", 0, 0, 0, func(ev mysql.BinlogEvent) error {
+		events = append(events, ev)
+		return nil
+	})
+	vgtid, err := bd.position("vt-0123456789abcdef.000001")
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(start, vgtid))
+	require.NoError(t, bd.start())
+
+	fields := []*querypb.Field{
+		{Name: "id", Type: querypb.Type_INT64},
+		{Name: "name", Type: querypb.Type_VARCHAR, ColumnLength: 64},
+	}
+	row := func(values ...sqltypes.Value) *querypb.Row {
+		return sqltypes.RowToProto3(values)
+	}
+	next := &binlogdatapb.VGtid{ShardGtids: []*binlogdatapb.ShardGtid{{Keyspace: "ks", Shard: "-80", Gtid: "MySQL56/0d4fd8c6-3c8b-11ee-a5c7-0242ac110002:1-42"}}}
+	err = bd.handle([]*binlogdatapb.VEvent{
+		{Type: binlogdatapb.VEventType_FIELD, FieldEvent: &binlogdatapb.FieldEvent{TableName: "ks.t1", Fields: fields}},
+		{Type: binlogdatapb.VEventType_BEGIN, Timestamp: 1692270000},
+		{Type: binlogdatapb.VEventType_ROW, RowEvent: &binlogdatapb.RowEvent{
+			TableName: "ks.t1",
+			RowChanges: []*binlogdatapb.RowChange{
+				{After: row(sqltypes.NewInt64(1), sqltypes.NewVarChar("a"))},
+				{Before: row(sqltypes.NewInt64(2), sqltypes.NewVarChar("b")), After: row(sqltypes.NewInt64(2), sqltypes.NULL)},
+				{Before: row(sqltypes.NewInt64(3), sqltypes.NewVarChar("c"))},
+			},
+		}},
+		{Type: binlogdatapb.VEventType_VGTID, Vgtid: next},
+		{Type: binlogdatapb.VEventType_COMMIT},
+	})
+	require.NoError(t, err)
+
+	var kinds []string
+	var format mysql.BinlogFormat
+	var tableMap *mysql.TableMap
+	var files []string
+	var values []string
+	position := uint32(4)
+	for _, ev := range events {
+		require.True(t, ev.IsValid())
+		if ev.IsRotate() {
+			// the fake rotation comes before the format description, and reading the file name strips the checksum itself
+			file, _, err := ev.NextLogFile(mysql.NewMySQL56BinlogFormat())
+			require.NoError(t, err)
+			files = append(files, file)
+		}
+		if ev.IsFormatDescription() {
+			format, err = ev.Format()
+			require.NoError(t, err)
+		}
+		data := ev.Bytes()
+		assert.Equal(t, crc32.ChecksumIEEE(data[:len(data)-4]), binary.LittleEndian.Uint32(data[len(data)-4:]))
+		ev, _, err = ev.StripChecksum(format)
+		require.NoError(t, err)
+
+		if !ev.IsRotate() || len(kinds) > 0 {
+			// all events but the fake rotation at the start give the position of the next one
+			if ev.IsFormatDescription() {
+				position = 4
+			}
+			position += uint32(len(events[len(kinds)].Bytes()))
+			assert.Equal(t, position, ev.NextPosition())
+		}
+
+		switch {
+		case ev.IsRotate():
+			kinds = append(kinds, "rotate")
+		case ev.IsFormatDescription():
+			kinds = append(kinds, "format")
+		case ev.IsQuery():
+			q, err := ev.Query(format)
+			require.NoError(t, err)
+			kinds = append(kinds, q.SQL)
+			assert.Equal(t, "ks", q.Database)
+		case ev.IsTableMap():
+			kinds = append(kinds, "table_map")
+			tableMap, err = ev.TableMap(format)
+			require.NoError(t, err)
+			assert.Equal(t, "ks", tableMap.Database)
+			assert.Equal(t, "t1", tableMap.Name)
+		case ev.IsWriteRows(), ev.IsUpdateRows(), ev.IsDeleteRows():
+			switch {
+			case ev.IsWriteRows():
+				kinds = append(kinds, "write")
+			case ev.IsUpdateRows():
+				kinds = append(kinds, "update")
+			default:
+				kinds = append(kinds, "delete")
+			}
+			rows, err := ev.Rows(format, tableMap)
+			require.NoError(t, err)
+			if !ev.IsWriteRows() {
+				v, err := rows.StringIdentifiesForTests(tableMap, 0)
+				require.NoError(t, err)
+				values = append(values, strings.Join(v, ","))
+			}
+			if !ev.IsDeleteRows() {
+				v, err := rows.StringValuesForTests(tableMap, 0)
+				require.NoError(t, err)
+				values = append(values, strings.Join(v, ","))
+			}
+		case ev.IsXID():
+			kinds = append(kinds, "xid")
+		}
+	}
+
+	assert.Equal(t, []string{
+		"rotate", "format",
+		"BEGIN", "table_map", "write", "update", "delete", "xid",
+		"rotate", "format",
+	}, kinds)
+	assert.Equal(t, []string{"1,a", "2,b", "2,NULL", "3,c"}, values)
+
+	// the client resumes from the file of the last rotation, which starts at the position after the transaction
+	assert.Equal(t, []string{"vt-0123456789abcdef.000001", "vt-0123456789abcdef.000002"}, files)
+	resume, err := ts.GetBinlogDumpPosition(ctx, "ks", "0123456789abcdef", 2)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(next, resume))
+}
@@ -42,6 +42,7 @@ import (
 )
 
 var testMaxMemoryRows = 100
+var testCTEMaxRecursionDepth = 10
 var testIgnoreMaxMemoryRows = false
 
 var _ VCursor = (*noopVCursor)(nil)
@@ -143,7 +144,7 @@ func (t *noopVCursor) VStream(ctx context.Context, rss []*srvtopo.ResolvedShard,
 	panic("implement me")
 }
 
-func (t *noopVCursor) MessageStream(ctx context.Context, rss []*srvtopo.ResolvedShard, tableName string, callback func(*sqltypes.Result) error) error {
+func (t *noopVCursor) MessageStream(ctx context.Context, rss []*srvtopo.ResolvedShard, tableName, consumerGroup string, callback func(*sqltypes.Result) error) error {
 	panic("implement me")
 }
 
@@ -289,6 +290,10 @@ func (t *noopVCursor) SetPriority(string) {
 	panic("implement me")
 }
 
+func (t *noopVCursor) SetWorkloadClass(string) {
+	panic("implement me")
+}
+
 func (t *noopVCursor) SetConsolidator(querypb.ExecuteOptions_Consolidator) {
 	panic("implement me")
 }
@@ -305,6 +310,10 @@ func (t *noopVCursor) ExceedsMaxMemoryRows(numRows int) bool {
 	return !testIgnoreMaxMemoryRows && numRows > testMaxMemoryRows
 }
 
+func (t *noopVCursor) CTEMaxRecursionDepth() int {
+	return testCTEMaxRecursionDepth
+}
+
 func (t *noopVCursor) GetKeyspace() string {
 	return ""
 }
@@ -549,7 +558,7 @@ func (f *loggingVCursor) ResolveDestinations(ctx context.Context, keyspace strin
 				}
 			}
 			shards = f.shards
-		case key.DestinationKeyRange:
+		case key.DestinationKeyRange, key.DestinationKeyRanges:
 			shards = f.shardForKsid
 		case key.DestinationKeyspaceID:
 			if f.shardForKsid == nil || f.curShardForKsid >= len(f.shardForKsid) {
@@ -729,6 +738,10 @@ func (f *loggingVCursor) SetPriority(string) {
 	panic("implement me")
 }
 
+func (f *loggingVCursor) SetWorkloadClass(string) {
+	panic("implement me")
+}
+
 func (f *loggingVCursor) FindRoutedTable(tbl sqlparser.TableName) (*vindexes.Table, error) {
 	f.log = append(f.log, fmt.Sprintf("FindTable(%s)", sqlparser.String(tbl)))
 	return f.tableRoutes.tbl, nil
@@ -39,6 +39,10 @@ type MStream struct {
 	// TableName specifies the table on which stream will be executed.
 	TableName string
 
+	// ConsumerGroup is the consumer group the stream is a member of,
+	// if the table has consumer groups.
+	ConsumerGroup string
+
 	noTxNeeded
 
 	noInputs
@@ -70,7 +74,7 @@ func (m *MStream) TryStreamExecute(ctx context.Context, vcursor VCursor, bindVar
 	if err != nil {
 		return err
 	}
-	return vcursor.MessageStream(ctx, rss, m.TableName, callback)
+	return vcursor.MessageStream(ctx, rss, m.TableName, m.ConsumerGroup, callback)
 }
 
 // GetFields implements the Primitive interface
@@ -79,11 +83,15 @@ func (m *MStream) GetFields(ctx context.Context, vcursor VCursor, bindVars map[s
 }
 
 func (m *MStream) description() PrimitiveDescription {
+	other := map[string]any{"Table": m.TableName}
+	if m.ConsumerGroup != "" {
+		other["ConsumerGroup"] = m.ConsumerGroup
+	}
 	return PrimitiveDescription{
 		OperatorType:      "MStream",
 		Keyspace:          m.Keyspace,
 		TargetDestination: m.TargetDestination,
 
-		Other: map[string]any{"Table": m.TableName},
+		Other: other,
 	}
 }
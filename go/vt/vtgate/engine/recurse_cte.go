@@ -0,0 +1,413 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"vitess.io/vitess/go/mysql/collations"
+	"vitess.io/vitess/go/sqltypes"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+	"vitess.io/vitess/go/vt/vterrors"
+	"vitess.io/vitess/go/vt/vtgate/evalengine"
+)
+
+var _ Primitive = (*RecurseCTE)(nil)
+
+// RecurseCTE evaluates a recursive common table expression at the vtgate level.
+// The Seed primitive produces the first working set. Every iteration then joins
+// the working set with the rows of the Term primitive, filters and projects the
+// joined rows, and the result becomes the next working set.
+// This goes on until the working set is empty.
+type RecurseCTE struct {
+	// Seed is the non-recursive part of the CTE
+	Seed Primitive
+	// Term reads the tables the recursive part of the CTE joins with the CTE.
+	// It is executed once per iteration, and is nil when the recursive part
+	// only reads from the CTE.
+	Term Primitive `json:",omitempty"`
+
+	// JoinCol is the offset of the column of the working set that is compared
+	// to the TermJoinCol column of the Term rows, or -1 for a cross join.
+	// The distinct values of JoinCol are sent to Term in the ValuesVar list
+	// bind variable, so that it only returns the rows that can be joined.
+	JoinCol     int
+	TermJoinCol int
+	ValuesVar   string `json:",omitempty"`
+
+	// Predicate and Exprs are evaluated over a row of the working set followed
+	// by the Term row it is joined with. Predicate filters the joined rows, and
+	// Exprs produce the rows of the next working set.
+	Predicate evalengine.Expr   `json:",omitempty"`
+	Exprs     []evalengine.Expr `json:",omitempty"`
+
+	// Collation is used to compare the join columns and to produce text values
+	Collation collations.ID
+
+	// Columns are the names of the CTE columns
+	Columns []string `json:",omitempty"`
+
+	// Distinct is set when the recursive part is added using UNION DISTINCT.
+	// Rows that have been seen before are then dropped, and are not used
+	// for the next iteration.
+	Distinct  bool
+	CheckCols []CheckCol `json:",omitempty"`
+}
+
+// RouteType returns a description of the query routing type used by the primitive
+func (r *RecurseCTE) RouteType() string {
+	return "RecurseCTE"
+}
+
+// GetKeyspaceName specifies the Keyspace that this primitive routes to.
+func (r *RecurseCTE) GetKeyspaceName() string {
+	if r.Term == nil || r.Seed.GetKeyspaceName() == r.Term.GetKeyspaceName() {
+		return r.Seed.GetKeyspaceName()
+	}
+	return r.Seed.GetKeyspaceName() + "_" + r.Term.GetKeyspaceName()
+}
+
+// GetTableName specifies the table that this primitive routes to.
+func (r *RecurseCTE) GetTableName() string {
+	return r.Seed.GetTableName()
+}
+
+// TryExecute implements the Primitive interface
+func (r *RecurseCTE) TryExecute(ctx context.Context, vcursor VCursor, bindVars map[string]*querypb.BindVariable, wantfields bool) (*sqltypes.Result, error) {
+	seed, err := vcursor.ExecutePrimitive(ctx, r.Seed, bindVars, wantfields)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &sqltypes.Result{Fields: r.renameFields(seed.Fields)}
+	err = r.recurse(ctx, vcursor, bindVars, seed.Rows, func(rows []sqltypes.Row) error {
+		result.Rows = append(result.Rows, rows...)
+		if vcursor.ExceedsMaxMemoryRows(len(result.Rows)) {
+			return fmt.Errorf("in-memory row count exceeded allowed limit of %d", vcursor.MaxMemoryRows())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TryStreamExecute implements the Primitive interface
+func (r *RecurseCTE) TryStreamExecute(ctx context.Context, vcursor VCursor, bindVars map[string]*querypb.BindVariable, wantfields bool, callback func(*sqltypes.Result) error) error {
+	// the whole seed is needed to start the recursion, so there is no point in streaming it
+	seed, err := vcursor.ExecutePrimitive(ctx, r.Seed, bindVars, wantfields)
+	if err != nil {
+		return err
+	}
+	if wantfields {
+		if err := callback(&sqltypes.Result{Fields: r.renameFields(seed.Fields)}); err != nil {
+			return err
+		}
+	}
+	return r.recurse(ctx, vcursor, bindVars, seed.Rows, func(rows []sqltypes.Row) error {
+		return callback(&sqltypes.Result{Rows: rows})
+	})
+}
+
+// recurse produces the seed rows, and then runs the recursive term until the working set is empty.
+// Every batch of new rows is handed to the output function.
+func (r *RecurseCTE) recurse(
+	ctx context.Context,
+	vcursor VCursor,
+	bindVars map[string]*querypb.BindVariable,
+	seed []sqltypes.Row,
+	output func([]sqltypes.Row) error,
+) error {
+	var pt *probeTable
+	if r.Distinct {
+		pt = newProbeTable(r.CheckCols)
+	}
+
+	working, err := r.unseen(pt, seed)
+	if err != nil {
+		return err
+	}
+	if err := output(working); err != nil {
+		return err
+	}
+
+	env := evalengine.NewExpressionEnv(ctx, bindVars, vcursor)
+	maxDepth := vcursor.CTEMaxRecursionDepth()
+	for depth := 1; len(working) > 0; depth++ {
+		if depth > maxDepth {
+			return vterrors.NewErrorf(vtrpcpb.Code_RESOURCE_EXHAUSTED, vterrors.CTEMaxRecursionDepth,
+				"Recursive query aborted after %d iterations. Try increasing --cte-max-recursion-depth to a larger value.", depth)
+		}
+
+		joined, err := r.join(ctx, vcursor, bindVars, working)
+		if err != nil {
+			return err
+		}
+		next, err := r.project(env, joined)
+		if err != nil {
+			return err
+		}
+		next, err = r.unseen(pt, next)
+		if err != nil {
+			return err
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		if err := output(next); err != nil {
+			return err
+		}
+		working = next
+	}
+	return nil
+}
+
+// join executes Term for the current working set, and returns every row of the
+// working set followed by the columns of each Term row it joins with
+func (r *RecurseCTE) join(ctx context.Context, vcursor VCursor, bindVars map[string]*querypb.BindVariable, working []sqltypes.Row) ([]sqltypes.Row, error) {
+	if r.Term == nil {
+		return working, nil
+	}
+
+	if r.JoinCol < 0 {
+		res, err := vcursor.ExecutePrimitive(ctx, r.Term, bindVars, false)
+		if err != nil {
+			return nil, err
+		}
+		var out []sqltypes.Row
+		for _, row := range working {
+			for _, termRow := range res.Rows {
+				out = append(out, joinRecurseRows(row, termRow))
+			}
+		}
+		return out, nil
+	}
+
+	// NULL never matches anything, and each value only needs to be sent once
+	values := &querypb.BindVariable{Type: querypb.Type_TUPLE}
+	seen := map[string]bool{}
+	for _, row := range working {
+		val := row[r.JoinCol]
+		if val.IsNull() {
+			continue
+		}
+		key := val.ToString()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		values.Values = append(values.Values, sqltypes.ValueToProto(val))
+	}
+	if len(values.Values) == 0 {
+		return nil, nil
+	}
+
+	res, err := vcursor.ExecutePrimitive(ctx, r.Term, combineVars(bindVars, map[string]*querypb.BindVariable{r.ValuesVar: values}), false)
+	if err != nil {
+		return nil, err
+	}
+
+	comparisonType := r.comparisonType(working, res.Rows)
+	probe := map[evalengine.HashCode][]sqltypes.Row{}
+	for _, termRow := range res.Rows {
+		val := termRow[r.TermJoinCol]
+		if val.IsNull() {
+			continue
+		}
+		hashcode, err := evalengine.NullsafeHashcode(val, r.Collation, comparisonType)
+		if err != nil {
+			return nil, err
+		}
+		probe[hashcode] = append(probe[hashcode], termRow)
+	}
+
+	var out []sqltypes.Row
+	for _, row := range working {
+		val := row[r.JoinCol]
+		if val.IsNull() {
+			continue
+		}
+		hashcode, err := evalengine.NullsafeHashcode(val, r.Collation, comparisonType)
+		if err != nil {
+			return nil, err
+		}
+		for _, termRow := range probe[hashcode] {
+			// hash codes can give false positives, so we need to check with a real comparison as well
+			cmp, err := evalengine.NullsafeCompare(val, termRow[r.TermJoinCol], r.Collation)
+			if err != nil {
+				return nil, err
+			}
+			if cmp == 0 {
+				out = append(out, joinRecurseRows(row, termRow))
+			}
+		}
+	}
+	return out, nil
+}
+
+// comparisonType returns the type used to hash the join columns. Like
+// NullsafeCompare, the values are compared as numbers if any of them is one.
+func (r *RecurseCTE) comparisonType(working, termRows []sqltypes.Row) sqltypes.Type {
+	for _, row := range working {
+		if sqltypes.IsNumber(row[r.JoinCol].Type()) {
+			return sqltypes.Decimal
+		}
+	}
+	for _, row := range termRows {
+		if sqltypes.IsNumber(row[r.TermJoinCol].Type()) {
+			return sqltypes.Decimal
+		}
+	}
+	return sqltypes.VarChar
+}
+
+func joinRecurseRows(row, termRow sqltypes.Row) sqltypes.Row {
+	out := make(sqltypes.Row, 0, len(row)+len(termRow))
+	out = append(out, row...)
+	return append(out, termRow...)
+}
+
+// project filters the joined rows and produces the rows of the next working set
+func (r *RecurseCTE) project(env *evalengine.ExpressionEnv, rows []sqltypes.Row) ([]sqltypes.Row, error) {
+	var out []sqltypes.Row
+	for _, row := range rows {
+		env.Row = row
+		if r.Predicate != nil {
+			res, err := env.Evaluate(r.Predicate)
+			if err != nil {
+				return nil, err
+			}
+			if !res.ToBoolean() {
+				continue
+			}
+		}
+		newRow := make(sqltypes.Row, 0, len(r.Exprs))
+		for _, expr := range r.Exprs {
+			res, err := env.Evaluate(expr)
+			if err != nil {
+				return nil, err
+			}
+			newRow = append(newRow, res.Value(r.Collation))
+		}
+		out = append(out, newRow)
+	}
+	return out, nil
+}
+
+// unseen drops the rows that have already been produced when the CTE uses UNION DISTINCT
+func (r *RecurseCTE) unseen(pt *probeTable, rows []sqltypes.Row) ([]sqltypes.Row, error) {
+	if pt == nil {
+		return rows, nil
+	}
+	var out []sqltypes.Row
+	for _, row := range rows {
+		exists, err := pt.exists(row)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			out = append(out, row)
+		}
+	}
+	return out, nil
+}
+
+// renameFields uses the column names of the CTE for the fields produced by the seed
+func (r *RecurseCTE) renameFields(fields []*querypb.Field) []*querypb.Field {
+	if fields == nil {
+		return nil
+	}
+	out := make([]*querypb.Field, len(fields))
+	for i, f := range fields {
+		if i >= len(r.Columns) {
+			out[i] = f
+			continue
+		}
+		field := f.CloneVT()
+		field.Name = r.Columns[i]
+		out[i] = field
+	}
+	return out
+}
+
+// GetFields implements the Primitive interface
+func (r *RecurseCTE) GetFields(ctx context.Context, vcursor VCursor, bindVars map[string]*querypb.BindVariable) (*sqltypes.Result, error) {
+	res, err := r.Seed.GetFields(ctx, vcursor, bindVars)
+	if err != nil {
+		return nil, err
+	}
+	return &sqltypes.Result{Fields: r.renameFields(res.Fields)}, nil
+}
+
+// NeedsTransaction implements the Primitive interface
+func (r *RecurseCTE) NeedsTransaction() bool {
+	return r.Seed.NeedsTransaction() || (r.Term != nil && r.Term.NeedsTransaction())
+}
+
+// Inputs implements the Primitive interface
+func (r *RecurseCTE) Inputs() ([]Primitive, []map[string]any) {
+	if r.Term == nil {
+		return []Primitive{r.Seed}, []map[string]any{{
+			inputName: "Seed",
+		}}
+	}
+	return []Primitive{r.Seed, r.Term}, []map[string]any{{
+		inputName: "Seed",
+	}, {
+		inputName: "Term",
+	}}
+}
+
+func (r *RecurseCTE) description() PrimitiveDescription {
+	other := map[string]any{
+		"Columns": strings.Join(r.Columns, ", "),
+	}
+	if r.Term != nil && r.JoinCol >= 0 {
+		// the offsets are the ones of the joined rows, as in Predicate and Expressions
+		other["JoinPredicate"] = fmt.Sprintf("[COLUMN %d] = [COLUMN %d]", r.JoinCol, len(r.Columns)+r.TermJoinCol)
+		other["ValuesVar"] = r.ValuesVar
+	}
+	if r.Predicate != nil {
+		other["Predicate"] = evalengine.FormatExpr(r.Predicate)
+	}
+	var exprs []string
+	for _, expr := range r.Exprs {
+		exprs = append(exprs, evalengine.FormatExpr(expr))
+	}
+	other["Expressions"] = exprs
+	if r.Distinct {
+		var colls []string
+		for _, checkCol := range r.CheckCols {
+			colls = append(colls, checkCol.String())
+		}
+		other["Collations"] = colls
+	}
+
+	variant := "UnionAll"
+	if r.Distinct {
+		variant = "Union"
+	}
+	return PrimitiveDescription{
+		OperatorType: "RecurseCTE",
+		Variant:      variant,
+		Other:        other,
+	}
+}
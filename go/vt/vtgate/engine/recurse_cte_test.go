@@ -0,0 +1,255 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/mysql/collations"
+	"vitess.io/vitess/go/sqltypes"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vterrors"
+	"vitess.io/vitess/go/vt/vtgate/evalengine"
+)
+
+// recurseExprs translates expressions over the columns of the joined rows of a RecurseCTE
+func recurseExprs(t *testing.T, columns string, exprs ...string) []evalengine.Expr {
+	t.Helper()
+	var fields evalengine.FieldResolver
+	for _, name := range strings.Split(columns, "|") {
+		fields = append(fields, &querypb.Field{Name: name})
+	}
+
+	var out []evalengine.Expr
+	for _, expr := range exprs {
+		ast, err := sqlparser.ParseExpr(expr)
+		require.NoError(t, err)
+		eexpr, err := evalengine.Translate(ast, &evalengine.Config{
+			ResolveColumn: fields.Column,
+			Collation:     collations.Default(),
+		})
+		require.NoError(t, err)
+		out = append(out, eexpr)
+	}
+	return out
+}
+
+func TestRecurseCTEExecute(t *testing.T) {
+	fields := sqltypes.MakeTestFields("id|name", "int64|varchar")
+	seed := &fakePrimitive{
+		results: []*sqltypes.Result{
+			sqltypes.MakeTestResult(fields, "1|a"),
+		},
+	}
+	// select id, manager_id, name from employee where manager_id in ::t_id
+	termFields := sqltypes.MakeTestFields("id|manager_id|name", "int64|int64|varchar")
+	term := &fakePrimitive{
+		results: []*sqltypes.Result{
+			sqltypes.MakeTestResult(termFields, "2|1|b", "3|1|c"),
+			sqltypes.MakeTestResult(termFields, "4|2|d", "5|2|e", "6|3|f"),
+			sqltypes.MakeTestResult(termFields),
+		},
+	}
+	bv := map[string]*querypb.BindVariable{
+		"a": sqltypes.Int64BindVariable(10),
+	}
+
+	rcte := &RecurseCTE{
+		Seed:        seed,
+		Term:        term,
+		JoinCol:     0,
+		TermJoinCol: 1,
+		ValuesVar:   "t_id",
+		Exprs:       recurseExprs(t, "id|name|e_id|e_manager_id|e_name", "e_id", "e_name"),
+		Collation:   collations.Default(),
+		Columns:     []string{"node", "label"},
+	}
+	r, err := rcte.TryExecute(context.Background(), &noopVCursor{}, bv, true)
+	require.NoError(t, err)
+	seed.ExpectLog(t, []string{
+		`Execute a: type:INT64 value:"10" true`,
+	})
+	// the term runs once per level, with all the values of the working set
+	term.ExpectLog(t, []string{
+		`Execute a: type:INT64 value:"10" t_id: type:TUPLE values:{type:INT64 value:"1"} false`,
+		`Execute a: type:INT64 value:"10" t_id: type:TUPLE values:{type:INT64 value:"2"} values:{type:INT64 value:"3"} false`,
+		`Execute a: type:INT64 value:"10" t_id: type:TUPLE values:{type:INT64 value:"4"} values:{type:INT64 value:"5"} values:{type:INT64 value:"6"} false`,
+	})
+	expectResult(t, "rcte.Execute", r, sqltypes.MakeTestResult(
+		sqltypes.MakeTestFields("node|label", "int64|varchar"),
+		"1|a",
+		"2|b",
+		"3|c",
+		"4|d",
+		"5|e",
+		"6|f",
+	))
+
+	// the streaming version sends every level as soon as it is produced
+	seed.rewind()
+	term.rewind()
+	var results []*sqltypes.Result
+	err = rcte.TryStreamExecute(context.Background(), &noopVCursor{}, bv, true, func(qr *sqltypes.Result) error {
+		results = append(results, qr)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	require.Equal(t, "node", results[0].Fields[0].Name)
+	require.Equal(t, "[[INT64(4) VARCHAR(\"d\")] [INT64(5) VARCHAR(\"e\")] [INT64(6) VARCHAR(\"f\")]]", fmt.Sprintf("%v", results[3].Rows))
+}
+
+func TestRecurseCTEWithoutTerm(t *testing.T) {
+	fields := sqltypes.MakeTestFields("n", "int64")
+	seed := &fakePrimitive{
+		results: []*sqltypes.Result{
+			sqltypes.MakeTestResult(fields, "1"),
+		},
+	}
+
+	// select n + 1 from t where n < 5
+	rcte := &RecurseCTE{
+		Seed:      seed,
+		JoinCol:   -1,
+		Predicate: recurseExprs(t, "n", "n < 5")[0],
+		Exprs:     recurseExprs(t, "n", "n + 1"),
+		Collation: collations.Default(),
+		Columns:   []string{"n"},
+	}
+	r, err := rcte.TryExecute(context.Background(), &noopVCursor{}, nil, true)
+	require.NoError(t, err)
+	expectResult(t, "rcte.Execute", r, sqltypes.MakeTestResult(fields, "1", "2", "3", "4", "5"))
+}
+
+func TestRecurseCTEDistinct(t *testing.T) {
+	fields := sqltypes.MakeTestFields("id", "int64")
+	seed := &fakePrimitive{
+		results: []*sqltypes.Result{
+			sqltypes.MakeTestResult(fields, "1"),
+		},
+	}
+	// 1 -> 2 -> 1 is a cycle, which only terminates because the rows are deduplicated
+	termFields := sqltypes.MakeTestFields("src|dst", "int64|int64")
+	term := &fakePrimitive{
+		results: []*sqltypes.Result{
+			sqltypes.MakeTestResult(termFields, "1|2"),
+			sqltypes.MakeTestResult(termFields, "2|1"),
+		},
+	}
+
+	rcte := &RecurseCTE{
+		Seed:        seed,
+		Term:        term,
+		JoinCol:     0,
+		TermJoinCol: 0,
+		ValuesVar:   "t_id",
+		Exprs:       recurseExprs(t, "id|src|dst", "dst"),
+		Collation:   collations.Default(),
+		Columns:     []string{"id"},
+		Distinct:    true,
+		CheckCols: []CheckCol{{
+			Col:       0,
+			Type:      sqltypes.Int64,
+			Collation: collations.CollationBinaryID,
+		}},
+	}
+	r, err := rcte.TryExecute(context.Background(), &noopVCursor{}, nil, true)
+	require.NoError(t, err)
+	expectResult(t, "rcte.Execute", r, sqltypes.MakeTestResult(fields, "1", "2"))
+}
+
+func TestRecurseCTEJoinFilter(t *testing.T) {
+	fields := sqltypes.MakeTestFields("id|lvl", "int64|int64")
+	seed := &fakePrimitive{
+		results: []*sqltypes.Result{
+			sqltypes.MakeTestResult(fields, "1|0", "2|0"),
+		},
+	}
+	// the join values are compared as numbers, and NULL never matches
+	termFields := sqltypes.MakeTestFields("parent|child", "varchar|int64")
+	term := &fakePrimitive{
+		results: []*sqltypes.Result{
+			sqltypes.MakeTestResult(termFields, "1|10", "2|20", "1|11", "null|30"),
+			sqltypes.MakeTestResult(termFields),
+		},
+	}
+
+	// the rows of the second level are dropped by the filter on the CTE column
+	rcte := &RecurseCTE{
+		Seed:        seed,
+		Term:        term,
+		JoinCol:     0,
+		TermJoinCol: 0,
+		ValuesVar:   "t_id",
+		Predicate:   recurseExprs(t, "id|lvl|parent|child", "child != 20")[0],
+		Exprs:       recurseExprs(t, "id|lvl|parent|child", "child", "lvl + 1"),
+		Collation:   collations.Default(),
+		Columns:     []string{"id", "lvl"},
+	}
+	r, err := rcte.TryExecute(context.Background(), &noopVCursor{}, nil, true)
+	require.NoError(t, err)
+	require.Equal(t, "[[INT64(1) INT64(0)] [INT64(2) INT64(0)] [INT64(10) INT64(1)] [INT64(11) INT64(1)]]", fmt.Sprintf("%v", r.Rows))
+}
+
+func TestRecurseCTEMaxRecursionDepth(t *testing.T) {
+	fields := sqltypes.MakeTestFields("id", "int64")
+	seed := &fakePrimitive{
+		results: []*sqltypes.Result{
+			sqltypes.MakeTestResult(fields, "0"),
+		},
+	}
+
+	// the recursive part never stops producing rows
+	rcte := &RecurseCTE{
+		Seed:      seed,
+		JoinCol:   -1,
+		Exprs:     recurseExprs(t, "id", "id + 1"),
+		Collation: collations.Default(),
+		Columns:   []string{"id"},
+	}
+	_, err := rcte.TryExecute(context.Background(), &noopVCursor{}, nil, true)
+	require.EqualError(t, err, fmt.Sprintf("Recursive query aborted after %d iterations. Try increasing --cte-max-recursion-depth to a larger value.", testCTEMaxRecursionDepth+1))
+	require.Equal(t, vterrors.CTEMaxRecursionDepth, vterrors.ErrState(err))
+}
+
+func TestRecurseCTEMaxMemoryRows(t *testing.T) {
+	save := testMaxMemoryRows
+	testMaxMemoryRows = 2
+	defer func() { testMaxMemoryRows = save }()
+
+	fields := sqltypes.MakeTestFields("id", "int64")
+	seed := &fakePrimitive{
+		results: []*sqltypes.Result{
+			sqltypes.MakeTestResult(fields, "1", "2"),
+		},
+	}
+
+	rcte := &RecurseCTE{
+		Seed:      seed,
+		JoinCol:   -1,
+		Exprs:     recurseExprs(t, "id", "id + 2"),
+		Collation: collations.Default(),
+	}
+	_, err := rcte.TryExecute(context.Background(), &noopVCursor{}, nil, true)
+	require.EqualError(t, err, "in-memory row count exceeded allowed limit of 2")
+}
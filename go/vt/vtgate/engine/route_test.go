@@ -715,6 +715,51 @@ func TestSelectLike(t *testing.T) {
 
 }
 
+func TestSelectRange(t *testing.T) {
+	vindex, _ := vindexes.CreateVindex("time_range", "", map[string]string{
+		"bucket": "month",
+		"ranges": "2023-01:-80,2023-07:80-",
+	})
+	sel := NewRoute(
+		Range,
+		&vindexes.Keyspace{
+			Name:    "ks",
+			Sharded: true,
+		},
+		"dummy_select",
+		"dummy_select_field",
+	)
+	sel.Vindex = vindex.(vindexes.SingleColumn)
+	sel.Values = []evalengine.Expr{
+		evalengine.NewLiteralString([]byte("2023-03-01"), collations.SystemCollation),
+		evalengine.NewLiteralString([]byte("2023-05-01"), collations.SystemCollation),
+	}
+	vc := &loggingVCursor{
+		shards:       []string{"-20", "20-80", "80-"},
+		shardForKsid: []string{"-20"},
+		results:      []*sqltypes.Result{defaultSelectResult},
+	}
+	result, err := sel.TryExecute(context.Background(), vc, map[string]*querypb.BindVariable{}, false)
+	require.NoError(t, err)
+	vc.ExpectLog(t, []string{
+		`ResolveDestinations ks [] Destinations:DestinationKeyRanges(0000000063fe9580-00000000644f0101)`,
+		`ExecuteMultiShard ks.-20: dummy_select {} false false`,
+	})
+	expectResult(t, "sel.Execute", result, defaultSelectResult)
+
+	// A range spanning the ranges of the vindex.
+	sel.Values[1] = evalengine.NewLiteralString([]byte("2023-09-30"), collations.SystemCollation)
+	vc.Rewind()
+	vc.shardForKsid = []string{"-20", "20-80", "80-"}
+	result, err = wrapStreamExecute(sel, vc, map[string]*querypb.BindVariable{}, false)
+	require.NoError(t, err)
+	vc.ExpectLog(t, []string{
+		`ResolveDestinations ks [] Destinations:DestinationKeyRanges(0000000063fe9580-80,8000000000649f6c80-800000000065176501)`,
+		`StreamExecuteMulti dummy_select ks.-20: {} ks.20-80: {} ks.80-: {} `,
+	})
+	expectResult(t, "sel.StreamExecute", result, defaultSelectResult)
+}
+
 func TestSelectNext(t *testing.T) {
 	sel := NewRoute(
 		Next,
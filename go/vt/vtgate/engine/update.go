@@ -68,7 +68,7 @@ func (upd *Update) TryExecute(ctx context.Context, vcursor VCursor, bindVars map
 	switch upd.Opcode {
 	case Unsharded:
 		return upd.execUnsharded(ctx, upd, vcursor, bindVars, rss)
-	case Equal, EqualUnique, IN, Scatter, ByDestination, SubShard, MultiEqual:
+	case Equal, EqualUnique, IN, Scatter, ByDestination, SubShard, MultiEqual, Range:
 		return upd.execMultiDestination(ctx, upd, vcursor, bindVars, rss, upd.updateVindexEntries)
 	default:
 		// Unreachable.
@@ -131,7 +131,7 @@ func (upd *Update) updateVindexEntries(ctx context.Context, vcursor VCursor, bin
 		for _, colVindex := range upd.Vindexes {
 			// Skip this vindex if no rows are being changed
 			updColValues, ok := upd.ChangedVindexValues[colVindex.Name]
-			if !ok {
+			if !ok || colVindex.IsPartialVindex() {
 				continue
 			}
 
@@ -0,0 +1,473 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"vitess.io/vitess/go/mysql/collations"
+	"vitess.io/vitess/go/sqltypes"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	"vitess.io/vitess/go/vt/vterrors"
+	"vitess.io/vitess/go/vt/vtgate/engine/opcode"
+	"vitess.io/vitess/go/vt/vtgate/evalengine"
+)
+
+var _ Primitive = (*Window)(nil)
+
+// Window is a primitive that computes window functions at the vtgate level.
+// It expects the underlying primitive to feed results sorted by the
+// PartitionBy columns, followed by the OrderBy columns of the window.
+// The result of every window function is added as a new column at the
+// end of the input rows.
+type Window struct {
+	// PartitionBy are the columns of the PARTITION BY clause of the window.
+	// Consecutive rows with the same values for these columns belong to the same partition.
+	PartitionBy []OrderByParams `json:",omitempty"`
+
+	// OrderBy are the columns of the ORDER BY clause of the window.
+	// Rows with the same values for these columns are peers.
+	OrderBy []OrderByParams `json:",omitempty"`
+
+	// Funcs are the window functions to compute, in the order they are added to the rows.
+	Funcs []*WindowFunc
+
+	// Input is the primitive that will feed into this Primitive.
+	Input Primitive
+}
+
+// WindowOpcode is the window function to compute
+type WindowOpcode int
+
+// These constants list the supported window functions.
+const (
+	WindowRowNumber = WindowOpcode(iota)
+	WindowRank
+	WindowDenseRank
+	WindowLag
+	WindowLead
+	WindowFirstValue
+	WindowSum
+	WindowAvg
+)
+
+var windowName = map[WindowOpcode]string{
+	WindowRowNumber:  "row_number",
+	WindowRank:       "rank",
+	WindowDenseRank:  "dense_rank",
+	WindowLag:        "lag",
+	WindowLead:       "lead",
+	WindowFirstValue: "first_value",
+	WindowSum:        "sum",
+	WindowAvg:        "avg",
+}
+
+func (code WindowOpcode) String() string {
+	return windowName[code]
+}
+
+// MarshalJSON serializes the WindowOpcode as a JSON string.
+// It's used for testing and diagnostics.
+func (code WindowOpcode) MarshalJSON() ([]byte, error) {
+	return ([]byte)(fmt.Sprintf("\"%s\"", code.String())), nil
+}
+
+// WindowFunc specifies a window function to compute
+type WindowFunc struct {
+	Opcode WindowOpcode
+	// Col is the input column the function reads from, or -1 for the ranking functions
+	Col int
+	// Offset is the number of rows LAG and LEAD look back or ahead
+	Offset int
+	// Default is the value returned by LAG and LEAD when the row they look at does
+	// not exist. It is evaluated against the current row. If nil, NULL is returned.
+	Default evalengine.Expr
+	// Rows is set when the frame of a running aggregation is made of rows instead of
+	// the default range, which means that the peers of the current row are not included.
+	Rows bool
+	// Alias is the name of the column produced
+	Alias string
+}
+
+func (wf *WindowFunc) String() string {
+	var args []string
+	if wf.Col >= 0 {
+		args = append(args, fmt.Sprintf("%d", wf.Col))
+	}
+	if wf.Opcode == WindowLag || wf.Opcode == WindowLead {
+		args = append(args, fmt.Sprintf("%d", wf.Offset))
+		if wf.Default != nil {
+			args = append(args, evalengine.FormatExpr(wf.Default))
+		}
+	}
+	out := fmt.Sprintf("%s(%s)", wf.Opcode.String(), strings.Join(args, ", "))
+	if wf.Rows {
+		out += " rows"
+	}
+	if wf.Alias != "" {
+		out += " AS " + wf.Alias
+	}
+	return out
+}
+
+func (wf *WindowFunc) typ(fields []*querypb.Field) querypb.Type {
+	switch wf.Opcode {
+	case WindowRowNumber, WindowRank, WindowDenseRank:
+		return sqltypes.Uint64
+	case WindowSum:
+		return opcode.AggregateSum.Type(fields[wf.Col].Type)
+	case WindowAvg:
+		if sqltypes.IsFloat(fields[wf.Col].Type) {
+			return sqltypes.Float64
+		}
+		return sqltypes.Decimal
+	default:
+		return fields[wf.Col].Type
+	}
+}
+
+// RouteType returns a description of the query routing type used by the primitive
+func (w *Window) RouteType() string {
+	return w.Input.RouteType()
+}
+
+// GetKeyspaceName specifies the Keyspace that this primitive routes to.
+func (w *Window) GetKeyspaceName() string {
+	return w.Input.GetKeyspaceName()
+}
+
+// GetTableName specifies the table that this primitive routes to.
+func (w *Window) GetTableName() string {
+	return w.Input.GetTableName()
+}
+
+// TryExecute is a Primitive function.
+func (w *Window) TryExecute(ctx context.Context, vcursor VCursor, bindVars map[string]*querypb.BindVariable, _ bool) (*sqltypes.Result, error) {
+	// we need the input fields types to correctly calculate the output types
+	qr, err := vcursor.ExecutePrimitive(ctx, w.Input, bindVars, true)
+	if err != nil {
+		return nil, err
+	}
+	if vcursor.ExceedsMaxMemoryRows(len(qr.Rows)) {
+		return nil, fmt.Errorf("in-memory row count exceeded allowed limit of %d", vcursor.MaxMemoryRows())
+	}
+
+	env := evalengine.NewExpressionEnv(ctx, bindVars, vcursor)
+	result := &sqltypes.Result{Fields: w.fields(qr.Fields)}
+	err = w.partitions(qr.Rows, func(partition []sqltypes.Row) error {
+		rows, err := w.computePartition(env, vcursor.ConnCollation(), qr.Fields, partition)
+		if err != nil {
+			return err
+		}
+		result.Rows = append(result.Rows, rows...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TryStreamExecute is a Primitive function.
+func (w *Window) TryStreamExecute(ctx context.Context, vcursor VCursor, bindVars map[string]*querypb.BindVariable, _ bool, callback func(*sqltypes.Result) error) error {
+	env := evalengine.NewExpressionEnv(ctx, bindVars, vcursor)
+	var fields []*querypb.Field
+	var current []sqltypes.Row
+
+	flush := func() error {
+		if len(current) == 0 {
+			return nil
+		}
+		rows, err := w.computePartition(env, vcursor.ConnCollation(), fields, current)
+		if err != nil {
+			return err
+		}
+		current = nil
+		return callback(&sqltypes.Result{Rows: rows})
+	}
+
+	visitor := func(qr *sqltypes.Result) error {
+		if fields == nil && len(qr.Fields) != 0 {
+			fields = qr.Fields
+			if err := callback(&sqltypes.Result{Fields: w.fields(fields)}); err != nil {
+				return err
+			}
+		}
+		for _, row := range qr.Rows {
+			if len(current) > 0 {
+				same, err := w.samePartition(current[0], row)
+				if err != nil {
+					return err
+				}
+				if !same {
+					// a partition is only complete when we see the first row of the next one
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+			}
+			current = append(current, row)
+			// the rows of a partition are buffered until it is complete
+			if vcursor.ExceedsMaxMemoryRows(len(current)) {
+				return fmt.Errorf("in-memory row count exceeded allowed limit of %d", vcursor.MaxMemoryRows())
+			}
+		}
+		return nil
+	}
+
+	// we need the input fields types to correctly calculate the output types
+	if err := vcursor.StreamExecutePrimitive(ctx, w.Input, bindVars, true, visitor); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// GetFields is a Primitive function.
+func (w *Window) GetFields(ctx context.Context, vcursor VCursor, bindVars map[string]*querypb.BindVariable) (*sqltypes.Result, error) {
+	qr, err := w.Input.GetFields(ctx, vcursor, bindVars)
+	if err != nil {
+		return nil, err
+	}
+	return &sqltypes.Result{Fields: w.fields(qr.Fields)}, nil
+}
+
+func (w *Window) fields(input []*querypb.Field) []*querypb.Field {
+	if input == nil {
+		return nil
+	}
+	fields := make([]*querypb.Field, 0, len(input)+len(w.Funcs))
+	fields = append(fields, input...)
+	for _, wf := range w.Funcs {
+		fields = append(fields, &querypb.Field{
+			Name: wf.Alias,
+			Type: wf.typ(input),
+		})
+	}
+	return fields
+}
+
+// partitions splits the sorted rows into partitions, and hands them one by one to the given function
+func (w *Window) partitions(rows []sqltypes.Row, f func([]sqltypes.Row) error) error {
+	start := 0
+	for i := 1; i <= len(rows); i++ {
+		if i < len(rows) {
+			same, err := w.samePartition(rows[start], rows[i])
+			if err != nil {
+				return err
+			}
+			if same {
+				continue
+			}
+		}
+		if err := f(rows[start:i]); err != nil {
+			return err
+		}
+		start = i
+	}
+	return nil
+}
+
+func (w *Window) samePartition(a, b sqltypes.Row) (bool, error) {
+	return sameValues(w.PartitionBy, a, b)
+}
+
+func (w *Window) peers(a, b sqltypes.Row) (bool, error) {
+	return sameValues(w.OrderBy, a, b)
+}
+
+func sameValues(params []OrderByParams, a, b sqltypes.Row) (bool, error) {
+	for _, c := range extractSlices(params) {
+		cmp, err := c.compare(a, b)
+		if err != nil {
+			return false, err
+		}
+		if cmp != 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// computePartition computes all the window functions for the rows of a single partition
+func (w *Window) computePartition(env *evalengine.ExpressionEnv, coll collations.ID, fields []*querypb.Field, partition []sqltypes.Row) ([]sqltypes.Row, error) {
+	// peerEnd[i] is the index after the last peer of row i
+	peerEnd := make([]int, len(partition))
+	peerStart := make([]int, len(partition))
+	start := 0
+	for i := 1; i <= len(partition); i++ {
+		if i < len(partition) {
+			same, err := w.peers(partition[start], partition[i])
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				continue
+			}
+		}
+		for j := start; j < i; j++ {
+			peerStart[j] = start
+			peerEnd[j] = i
+		}
+		start = i
+	}
+
+	out := make([]sqltypes.Row, len(partition))
+	for i, row := range partition {
+		newRow := make(sqltypes.Row, len(row), len(row)+len(w.Funcs))
+		copy(newRow, row)
+		out[i] = newRow
+	}
+
+	for _, wf := range w.Funcs {
+		var err error
+		switch wf.Opcode {
+		case WindowRowNumber:
+			for i := range partition {
+				out[i] = append(out[i], sqltypes.NewUint64(uint64(i+1)))
+			}
+		case WindowRank:
+			for i := range partition {
+				out[i] = append(out[i], sqltypes.NewUint64(uint64(peerStart[i]+1)))
+			}
+		case WindowDenseRank:
+			rank := uint64(0)
+			for i := range partition {
+				if i == peerStart[i] {
+					rank++
+				}
+				out[i] = append(out[i], sqltypes.NewUint64(rank))
+			}
+		case WindowLag, WindowLead:
+			err = w.lagLead(env, coll, wf, partition, out)
+		case WindowFirstValue:
+			for i := range partition {
+				out[i] = append(out[i], partition[0][wf.Col])
+			}
+		case WindowSum, WindowAvg:
+			err = w.runningAggregate(wf, fields, partition, peerEnd, out)
+		default:
+			err = vterrors.VT13001(fmt.Sprintf("unexpected window function: %s", wf.Opcode.String()))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (w *Window) lagLead(env *evalengine.ExpressionEnv, coll collations.ID, wf *WindowFunc, partition []sqltypes.Row, out []sqltypes.Row) error {
+	for i, row := range partition {
+		j := i - wf.Offset
+		if wf.Opcode == WindowLead {
+			j = i + wf.Offset
+		}
+		if j >= 0 && j < len(partition) {
+			out[i] = append(out[i], partition[j][wf.Col])
+			continue
+		}
+		if wf.Default == nil {
+			out[i] = append(out[i], sqltypes.NULL)
+			continue
+		}
+		env.Row = row
+		res, err := env.Evaluate(wf.Default)
+		if err != nil {
+			return err
+		}
+		out[i] = append(out[i], res.Value(coll))
+	}
+	return nil
+}
+
+// runningAggregate computes SUM and AVG over the frame of every row. When the window has
+// an ORDER BY, the default frame goes from the start of the partition to the last peer of
+// the current row. Without an ORDER BY all rows are peers, so the whole partition is used.
+func (w *Window) runningAggregate(wf *WindowFunc, fields []*querypb.Field, partition []sqltypes.Row, peerEnd []int, out []sqltypes.Row) error {
+	sum := evalengine.NewAggregationSum(fields[wf.Col].Type)
+	count := int64(0)
+	added := 0
+
+	for i := range partition {
+		end := peerEnd[i]
+		if wf.Rows {
+			end = i + 1
+		}
+		for ; added < end; added++ {
+			v := partition[added][wf.Col]
+			if v.IsNull() {
+				continue
+			}
+			if err := sum.Add(v); err != nil {
+				return err
+			}
+			count++
+		}
+
+		result := sum.Result()
+		if wf.Opcode == WindowAvg && count > 0 {
+			var err error
+			result, err = evalengine.Divide(result, sqltypes.NewInt64(count))
+			if err != nil {
+				return err
+			}
+		} else if wf.Opcode == WindowAvg {
+			result = sqltypes.NULL
+		}
+		out[i] = append(out[i], result)
+	}
+	return nil
+}
+
+// Inputs returns the Primitive input for this window
+func (w *Window) Inputs() ([]Primitive, []map[string]any) {
+	return []Primitive{w.Input}, nil
+}
+
+// NeedsTransaction implements the Primitive interface
+func (w *Window) NeedsTransaction() bool {
+	return w.Input.NeedsTransaction()
+}
+
+func (w *Window) description() PrimitiveDescription {
+	other := map[string]any{}
+	if len(w.PartitionBy) > 0 {
+		other["PartitionBy"] = orderByParamsString(w.PartitionBy)
+	}
+	if len(w.OrderBy) > 0 {
+		other["OrderBy"] = orderByParamsString(w.OrderBy)
+	}
+	var funcs []string
+	for _, wf := range w.Funcs {
+		funcs = append(funcs, wf.String())
+	}
+	other["Functions"] = strings.Join(funcs, ", ")
+
+	return PrimitiveDescription{
+		OperatorType: "Window",
+		Other:        other,
+	}
+}
+
+func orderByParamsString(params []OrderByParams) string {
+	var out []string
+	for _, p := range params {
+		out = append(out, p.String())
+	}
+	return strings.Join(out, ", ")
+}
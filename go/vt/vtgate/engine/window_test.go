@@ -0,0 +1,185 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/mysql/collations"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/vtgate/evalengine"
+)
+
+func TestWindowRanking(t *testing.T) {
+	fields := sqltypes.MakeTestFields("subject|score", "varchar|int64")
+	fp := &fakePrimitive{
+		results: []*sqltypes.Result{sqltypes.MakeTestResult(fields,
+			"a|10",
+			"a|20",
+			"a|20",
+			"a|30",
+			"b|5",
+		)},
+	}
+
+	w := &Window{
+		PartitionBy: []OrderByParams{{Col: 0, WeightStringCol: -1, CollationID: collations.CollationUtf8mb4ID}},
+		OrderBy:     []OrderByParams{{Col: 1, WeightStringCol: -1}},
+		Funcs: []*WindowFunc{
+			{Opcode: WindowRowNumber, Col: -1, Alias: "rn"},
+			{Opcode: WindowRank, Col: -1, Alias: "r"},
+			{Opcode: WindowDenseRank, Col: -1, Alias: "dr"},
+		},
+		Input: fp,
+	}
+
+	expected := sqltypes.MakeTestResult(
+		sqltypes.MakeTestFields("subject|score|rn|r|dr", "varchar|int64|uint64|uint64|uint64"),
+		"a|10|1|1|1",
+		"a|20|2|2|2",
+		"a|20|3|2|2",
+		"a|30|4|4|3",
+		"b|5|1|1|1",
+	)
+
+	r, err := w.TryExecute(context.Background(), &noopVCursor{}, nil, true)
+	require.NoError(t, err)
+	expectResult(t, "w.Execute", r, expected)
+
+	// streaming sends every partition as soon as the next one starts
+	fp.rewind()
+	results := &sqltypes.Result{}
+	var batches int
+	err = w.TryStreamExecute(context.Background(), &noopVCursor{}, nil, true, func(qr *sqltypes.Result) error {
+		if qr.Fields != nil {
+			results.Fields = qr.Fields
+		}
+		if len(qr.Rows) > 0 {
+			batches++
+		}
+		results.Rows = append(results.Rows, qr.Rows...)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, batches)
+	expectResult(t, "w.StreamExecute", results, expected)
+}
+
+func TestWindowLagLeadFirstValue(t *testing.T) {
+	fields := sqltypes.MakeTestFields("id|val", "int64|int64")
+	fp := &fakePrimitive{
+		results: []*sqltypes.Result{sqltypes.MakeTestResult(fields,
+			"1|10",
+			"2|20",
+			"3|30",
+		)},
+	}
+
+	w := &Window{
+		OrderBy: []OrderByParams{{Col: 0, WeightStringCol: -1}},
+		Funcs: []*WindowFunc{
+			{Opcode: WindowLag, Col: 1, Offset: 1, Alias: "prev"},
+			{Opcode: WindowLead, Col: 1, Offset: 2, Default: evalengine.NewLiteralInt(0), Alias: "next"},
+			{Opcode: WindowFirstValue, Col: 1, Alias: "first"},
+		},
+		Input: fp,
+	}
+
+	r, err := w.TryExecute(context.Background(), &noopVCursor{}, nil, true)
+	require.NoError(t, err)
+	expectResult(t, "w.Execute", r, sqltypes.MakeTestResult(
+		sqltypes.MakeTestFields("id|val|prev|next|first", "int64|int64|int64|int64|int64"),
+		"1|10|null|30|10",
+		"2|20|10|0|10",
+		"3|30|20|0|10",
+	))
+}
+
+func TestWindowSumAvg(t *testing.T) {
+	fields := sqltypes.MakeTestFields("grp|id|val", "int64|int64|int64")
+	fp := &fakePrimitive{
+		results: []*sqltypes.Result{sqltypes.MakeTestResult(fields,
+			"1|1|10",
+			"1|2|20",
+			"1|2|30",
+			"2|1|null",
+		)},
+	}
+
+	w := &Window{
+		PartitionBy: []OrderByParams{{Col: 0, WeightStringCol: -1}},
+		OrderBy:     []OrderByParams{{Col: 1, WeightStringCol: -1}},
+		Funcs: []*WindowFunc{
+			// the default frame includes all the peers of the current row
+			{Opcode: WindowSum, Col: 2, Alias: "running"},
+			{Opcode: WindowSum, Col: 2, Rows: true, Alias: "running_rows"},
+			{Opcode: WindowAvg, Col: 2, Alias: "average"},
+		},
+		Input: fp,
+	}
+
+	r, err := w.TryExecute(context.Background(), &noopVCursor{}, nil, true)
+	require.NoError(t, err)
+	expectResult(t, "w.Execute", r, sqltypes.MakeTestResult(
+		sqltypes.MakeTestFields("grp|id|val|running|running_rows|average", "int64|int64|int64|decimal|decimal|decimal"),
+		"1|1|10|10|10|10.0000",
+		"1|2|20|60|30|20.0000",
+		"1|2|30|60|60|20.0000",
+		"2|1|null|null|null|null",
+	))
+}
+
+func TestWindowMaxMemoryRows(t *testing.T) {
+	save := testMaxMemoryRows
+	testMaxMemoryRows = 2
+	defer func() { testMaxMemoryRows = save }()
+
+	fields := sqltypes.MakeTestFields("subject|score", "varchar|int64")
+	fp := &fakePrimitive{
+		results: []*sqltypes.Result{sqltypes.MakeTestResult(fields,
+			"a|10",
+			"b|20",
+			"b|30",
+			"b|40",
+		)},
+	}
+
+	w := &Window{
+		PartitionBy: []OrderByParams{{Col: 0, WeightStringCol: -1, CollationID: collations.CollationUtf8mb4ID}},
+		OrderBy:     []OrderByParams{{Col: 1, WeightStringCol: -1}},
+		Funcs: []*WindowFunc{
+			{Opcode: WindowRowNumber, Col: -1, Alias: "rn"},
+		},
+		Input: fp,
+	}
+
+	_, err := w.TryExecute(context.Background(), &noopVCursor{}, nil, true)
+	require.EqualError(t, err, "in-memory row count exceeded allowed limit of 2")
+
+	// when streaming, only the rows of the current partition are kept in memory
+	fp.rewind()
+	var rows int
+	err = w.TryStreamExecute(context.Background(), &noopVCursor{}, nil, true, func(qr *sqltypes.Result) error {
+		rows += len(qr.Rows)
+		return nil
+	})
+	require.EqualError(t, err, "in-memory row count exceeded allowed limit of 2")
+	require.Equal(t, 1, rows)
+}
@@ -108,6 +108,11 @@ type Executor struct {
 	plans *PlanCache
 	epoch atomic.Uint32
 
+	// resultCache, if enabled, caches the results of read-only queries.
+	resultCache *resultCache
+	// queryQuotas limits the queries of the tenants of the keyspaces with query quotas.
+	queryQuotas *queryQuotas
+
 	normalize       bool
 	warnShardedOnly bool
 
@@ -162,6 +167,10 @@ func NewExecutor(
 		allowScatter:    !noScatter,
 		pv:              pv,
 		plans:           plans,
+		queryQuotas:     newQueryQuotas(),
+	}
+	if resultCacheSize > 0 {
+		e.resultCache = newResultCache(resultCacheSize, resultCacheMaxStaleness)
 	}
 
 	vschemaacl.Init()
@@ -208,6 +217,7 @@ func (e *Executor) Execute(ctx context.Context, mysqlCtx vtgateservice.MySQLConn
 	defer span.Finish()
 
 	logStats := logstats.NewLogStats(ctx, method, sql, safeSession.GetSessionUUID(), bindVars)
+	logStats.QueryAttributes = safeSession.GetQueryAttributes()
 	stmtType, result, err := e.execute(ctx, mysqlCtx, safeSession, sql, bindVars, logStats)
 	logStats.Error = err
 	if result == nil {
@@ -267,6 +277,7 @@ func (e *Executor) StreamExecute(
 	defer span.Finish()
 
 	logStats := logstats.NewLogStats(ctx, method, sql, safeSession.GetSessionUUID(), bindVars)
+	logStats.QueryAttributes = safeSession.GetQueryAttributes()
 	srr := &streaminResultReceiver{callback: callback}
 	var err error
 
@@ -956,19 +967,70 @@ func (e *Executor) showVitessReplicationStatus(ctx context.Context, filter *sqlp
 }
 
 // MessageStream is part of the vtgate service API. This is a V2 level API that's sent
-// to the Resolver.
-func (e *Executor) MessageStream(ctx context.Context, keyspace string, shard string, keyRange *topodatapb.KeyRange, name string, callback func(*sqltypes.Result) error) error {
+// to the Resolver. If the table has consumer groups, the stream is a member of
+// consumerGroup.
+func (e *Executor) MessageStream(ctx context.Context, keyspace string, shard string, keyRange *topodatapb.KeyRange, name, consumerGroup string, callback func(*sqltypes.Result) error) error {
 	err := e.resolver.MessageStream(
 		ctx,
 		keyspace,
 		shard,
 		keyRange,
 		name,
+		consumerGroup,
 		callback,
 	)
 	return formatError(err)
 }
 
+// MessageAck is part of the vtgate service API. It acks the messages on the
+// shards their ids map to with the primary vindex of the table. If the table
+// has consumer groups, the messages are only acked for consumerGroup.
+// It returns the number of messages successfully acked.
+func (e *Executor) MessageAck(ctx context.Context, keyspace, name, consumerGroup string, ids []*querypb.Value) (int64, error) {
+	count, err := e.messageAck(ctx, keyspace, name, consumerGroup, ids)
+	return count, formatError(err)
+}
+
+func (e *Executor) messageAck(ctx context.Context, keyspace, name, consumerGroup string, ids []*querypb.Value) (int64, error) {
+	vschema := e.VSchema()
+	table, err := vschema.FindTable(keyspace, name)
+	if err != nil {
+		return 0, err
+	}
+
+	destinations := make([]key.Destination, len(ids))
+	if table.Keyspace.Sharded {
+		// The id of a message table must be its primary vindex.
+		mapper, ok := table.ColumnVindexes[0].Vindex.(vindexes.SingleColumn)
+		if !ok {
+			return 0, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "the primary vindex of message table %s is not a single column vindex", name)
+		}
+		safeSession := NewSafeSession(&vtgatepb.Session{TargetString: table.Keyspace.Name})
+		logStats := logstats.NewLogStats(ctx, "MessageAck", "", "", nil)
+		vcursor, err := newVCursorImpl(safeSession, sqlparser.MarginComments{}, e, logStats, e.vm, vschema, e.resolver.resolver, e.serv, e.warnShardedOnly, e.pv)
+		if err != nil {
+			return 0, err
+		}
+		values := make([]sqltypes.Value, 0, len(ids))
+		for _, id := range ids {
+			values = append(values, sqltypes.ProtoToValue(id))
+		}
+		if destinations, err = mapper.Map(ctx, vcursor, values); err != nil {
+			return 0, err
+		}
+	} else {
+		for i := range destinations {
+			destinations[i] = key.DestinationAnyShard{}
+		}
+	}
+
+	rss, rssValues, err := e.resolver.resolver.ResolveDestinations(ctx, table.Keyspace.Name, topodatapb.TabletType_PRIMARY, ids, destinations)
+	if err != nil {
+		return 0, err
+	}
+	return e.scatterConn.MessageAck(ctx, rss, rssValues, name, consumerGroup)
+}
+
 // VSchema returns the VSchema.
 func (e *Executor) VSchema() *vindexes.VSchema {
 	e.mu.Lock()
@@ -985,6 +1047,9 @@ func (e *Executor) SaveVSchema(vschema *vindexes.VSchema, stats *VSchemaStats) {
 	}
 	e.vschemaStats = stats
 	e.ClearPlans()
+	if e.resultCache != nil {
+		e.resultCache.flush("VSchema")
+	}
 
 	if vschemaCounters != nil {
 		vschemaCounters.Add("Reload", 1)
@@ -1041,6 +1106,7 @@ func (e *Executor) getPlan(
 		return nil, err
 	}
 	vcursor.SetPriority(priority)
+	vcursor.SetWorkloadClass(sqlparser.GetWorkloadClassFromStatement(stmt))
 
 	setVarComment, err := prepareSetVarComment(vcursor, stmt)
 	if err != nil {
@@ -1225,6 +1291,11 @@ func (e *Executor) updateQueryCounts(planType, keyspace, tableName string, shard
 	}
 }
 
+// QueryQuotasStatus returns the status of the query quotas of the VSchema.
+func (e *Executor) QueryQuotasStatus() []*QueryQuotaStatus {
+	return e.queryQuotas.status(e.VSchema())
+}
+
 // VSchemaStats returns the loaded vschema stats.
 func (e *Executor) VSchemaStats() *VSchemaStats {
 	e.mu.Lock()
@@ -1276,6 +1347,7 @@ func isValidPayloadSize(query string) bool {
 // Prepare executes a prepare statements.
 func (e *Executor) Prepare(ctx context.Context, method string, safeSession *SafeSession, sql string, bindVars map[string]*querypb.BindVariable) (fld []*querypb.Field, err error) {
 	logStats := logstats.NewLogStats(ctx, method, sql, safeSession.GetSessionUUID(), bindVars)
+	logStats.QueryAttributes = safeSession.GetQueryAttributes()
 	fld, err = e.prepare(ctx, safeSession, sql, bindVars, logStats)
 	logStats.Error = err
 
@@ -1329,7 +1401,7 @@ func (e *Executor) handlePrepare(ctx context.Context, safeSession *SafeSession,
 	query, comments := sqlparser.SplitMarginComments(sql)
 	vcursor, _ := newVCursorImpl(safeSession, comments, e, logStats, e.vm, e.VSchema(), e.resolver.resolver, e.serv, e.warnShardedOnly, e.pv)
 
-	stmt, reservedVars, err := parseAndValidateQuery(query)
+	stmt, reservedVars, err := parseAndValidateQuery(query, safeSession.GetQueryAttributes())
 	if err != nil {
 		return nil, err
 	}
@@ -1364,11 +1436,14 @@ func (e *Executor) handlePrepare(ctx context.Context, safeSession *SafeSession,
 	return qr.Fields, err
 }
 
-func parseAndValidateQuery(query string) (sqlparser.Statement, *sqlparser.ReservedVars, error) {
+func parseAndValidateQuery(query string, attributes map[string]string) (sqlparser.Statement, *sqlparser.ReservedVars, error) {
 	stmt, reserved, err := sqlparser.Parse2(query)
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := applyQueryAttributes(stmt, attributes); err != nil {
+		return nil, nil, err
+	}
 	if !sqlparser.IgnoreMaxPayloadSizeDirective(stmt) && !isValidPayloadSize(query) {
 		return nil, nil, vterrors.NewErrorf(vtrpcpb.Code_RESOURCE_EXHAUSTED, vterrors.NetPacketTooLarge, "query payload size above threshold")
 	}
@@ -1391,8 +1466,8 @@ func (e *Executor) ExecuteLock(ctx context.Context, rs *srvtopo.ResolvedShard, q
 }
 
 // ExecuteMessageStream implements the IExecutor interface
-func (e *Executor) ExecuteMessageStream(ctx context.Context, rss []*srvtopo.ResolvedShard, tableName string, callback func(reply *sqltypes.Result) error) error {
-	return e.scatterConn.MessageStream(ctx, rss, tableName, callback)
+func (e *Executor) ExecuteMessageStream(ctx context.Context, rss []*srvtopo.ResolvedShard, tableName, consumerGroup string, callback func(reply *sqltypes.Result) error) error {
+	return e.scatterConn.MessageStream(ctx, rss, tableName, consumerGroup, callback)
 }
 
 // ExecuteVStream implements the IExecutor interface
@@ -1500,7 +1575,7 @@ func (e *Executor) ReleaseLock(ctx context.Context, session *SafeSession) error
 
 // planPrepareStmt implements the IExecutor interface
 func (e *Executor) planPrepareStmt(ctx context.Context, vcursor *vcursorImpl, query string) (*engine.Plan, sqlparser.Statement, error) {
-	stmt, reservedVars, err := parseAndValidateQuery(query)
+	stmt, reservedVars, err := parseAndValidateQuery(query, vcursor.safeSession.GetQueryAttributes())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1532,4 +1607,7 @@ func (e *Executor) Close() {
 	}
 	topo.Close()
 	e.plans.Close()
+	if e.resultCache != nil {
+		e.resultCache.store.Close()
+	}
 }
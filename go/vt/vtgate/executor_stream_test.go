@@ -94,6 +94,29 @@ func TestStreamSQLSharded(t *testing.T) {
 	}
 }
 
+func TestMessageConsumerGroup(t *testing.T) {
+	executor, sbc1, sbc2, _, ctx := createExecutorEnv(t)
+
+	// The stream is a member of the consumer group named by its directive.
+	_, err := executorStreamMessages(executor, "stream /*vt+ CONSUMER_GROUP=billing */ * from sharded_user_msgs")
+	require.NoError(t, err)
+	require.Equal(t, "billing", sbc1.MessageConsumerGroup)
+	require.Equal(t, "billing", sbc2.MessageConsumerGroup)
+
+	// The messages are acked for the group, on the shards of their ids.
+	ids := []*querypb.Value{sqltypes.ValueToProto(sqltypes.NewInt64(1)), sqltypes.ValueToProto(sqltypes.NewInt64(3))}
+	count, err := executor.MessageAck(ctx, KsTestSharded, "sharded_user_msgs", "billing", ids)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, count)
+	utils.MustMatch(t, ids[:1], sbc1.MessageIDs)
+	utils.MustMatch(t, ids[1:], sbc2.MessageIDs)
+	require.Equal(t, "billing", sbc1.MessageConsumerGroup)
+	require.Equal(t, "billing", sbc2.MessageConsumerGroup)
+
+	_, err = executor.MessageAck(ctx, KsTestSharded, "unknown", "billing", ids)
+	require.ErrorContains(t, err, "table unknown not found")
+}
+
 func executorStreamMessages(executor *Executor, sql string) (qr *sqltypes.Result, err error) {
 	results := make(chan *sqltypes.Result, 100)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
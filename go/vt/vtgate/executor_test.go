@@ -1699,7 +1699,7 @@ func getPlanCached(t *testing.T, ctx context.Context, e *Executor, vcursor *vcur
 			Options: &querypb.ExecuteOptions{SkipQueryPlanCache: skipQueryPlanCache}},
 	}
 
-	stmt, reservedVars, err := parseAndValidateQuery(sql)
+	stmt, reservedVars, err := parseAndValidateQuery(sql, nil)
 	require.NoError(t, err)
 	plan, err := e.getPlan(context.Background(), vcursor, sql, stmt, comments, bindVars, reservedVars /* normalize */, e.normalize, logStats)
 	require.NoError(t, err)
@@ -1884,6 +1884,28 @@ func TestGetPlanPriority(t *testing.T) {
 
 }
 
+func TestGetPlanWorkloadClass(t *testing.T) {
+	r, _, _, _, ctx := createExecutorEnv(t)
+	session := NewSafeSession(&vtgatepb.Session{TargetString: "@unknown", Options: &querypb.ExecuteOptions{}})
+	vCursor, err := newVCursorImpl(session, makeComments(""), r, nil, r.vm, r.VSchema(), r.resolver.resolver, nil, false, pv)
+	require.NoError(t, err)
+
+	getPlan := func(sql string) {
+		stmt, err := sqlparser.Parse(sql)
+		require.NoError(t, err)
+		logStats := logstats.NewLogStats(ctx, "Test", "", "", nil)
+		_, err = r.getPlan(context.Background(), vCursor, sql, stmt, makeComments(""), map[string]*querypb.BindVariable{}, nil, true, logStats)
+		require.NoError(t, err)
+	}
+
+	getPlan("select /*vt+ WORKLOAD_CLASS=Batch */ * from music_user_map")
+	assert.Equal(t, "batch", vCursor.safeSession.Options.WorkloadClass)
+
+	// The workload class only applies to the query it is set on.
+	getPlan("select * from music_user_map")
+	assert.Empty(t, vCursor.safeSession.Options.WorkloadClass)
+}
+
 func TestPassthroughDDL(t *testing.T) {
 	executor, sbc1, sbc2, _, ctx := createExecutorEnv(t)
 	session := &vtgatepb.Session{
@@ -57,6 +57,8 @@ type LogStats struct {
 	SessionUUID    string
 	CachedPlan     bool
 	ActiveKeyspace string // ActiveKeyspace is the selected keyspace `use ks`
+	// QueryAttributes are the query attributes sent by the MySQL client.
+	QueryAttributes map[string]string
 }
 
 // NewLogStats constructs a new LogStats with supplied Method and ctx
@@ -154,7 +156,7 @@ func (stats *LogStats) Logf(w io.Writer, params url.Values) error {
 	case streamlog.QueryLogFormatText:
 		fmtString = "%v\t%v\t%v\t'%v'\t'%v'\t%v\t%v\t%.6f\t%.6f\t%.6f\t%.6f\t%v\t%q\t%v\t%v\t%v\t%q\t%q\t%q\t%v\t%v\t%q\n"
 	case streamlog.QueryLogFormatJSON:
-		fmtString = "{\"Method\": %q, \"RemoteAddr\": %q, \"Username\": %q, \"ImmediateCaller\": %q, \"Effective Caller\": %q, \"Start\": \"%v\", \"End\": \"%v\", \"TotalTime\": %.6f, \"PlanTime\": %v, \"ExecuteTime\": %v, \"CommitTime\": %v, \"StmtType\": %q, \"SQL\": %q, \"BindVars\": %v, \"ShardQueries\": %v, \"RowsAffected\": %v, \"Error\": %q, \"TabletType\": %q, \"SessionUUID\": %q, \"Cached Plan\": %v, \"TablesUsed\": %v, \"ActiveKeyspace\": %q}\n"
+		fmtString = "{\"Method\": %q, \"RemoteAddr\": %q, \"Username\": %q, \"ImmediateCaller\": %q, \"Effective Caller\": %q, \"Start\": \"%v\", \"End\": \"%v\", \"TotalTime\": %.6f, \"PlanTime\": %v, \"ExecuteTime\": %v, \"CommitTime\": %v, \"StmtType\": %q, \"SQL\": %q, \"BindVars\": %v, \"ShardQueries\": %v, \"RowsAffected\": %v, \"Error\": %q, \"TabletType\": %q, \"SessionUUID\": %q, \"Cached Plan\": %v, \"TablesUsed\": %v, \"ActiveKeyspace\": %q, \"QueryAttributes\": %v}\n"
 	}
 
 	tables := stats.TablesUsed
@@ -165,9 +167,7 @@ func (stats *LogStats) Logf(w io.Writer, params url.Values) error {
 	if marshalErr != nil {
 		return marshalErr
 	}
-	_, err := fmt.Fprintf(
-		w,
-		fmtString,
+	args := []any{
 		stats.Method,
 		remoteAddr,
 		username,
@@ -190,7 +190,23 @@ func (stats *LogStats) Logf(w io.Writer, params url.Values) error {
 		stats.CachedPlan,
 		string(tablesUsed),
 		stats.ActiveKeyspace,
-	)
+	}
+	if streamlog.GetQueryLogFormat() == streamlog.QueryLogFormatJSON {
+		queryAttributes := "\"[REDACTED]\""
+		if !streamlog.GetRedactDebugUIQueries() {
+			attributes := stats.QueryAttributes
+			if attributes == nil {
+				attributes = map[string]string{}
+			}
+			formatted, marshalErr := json.Marshal(attributes)
+			if marshalErr != nil {
+				return marshalErr
+			}
+			queryAttributes = string(formatted)
+		}
+		args = append(args, queryAttributes)
+	}
+	_, err := fmt.Fprintf(w, fmtString, args...)
 
 	return err
 }
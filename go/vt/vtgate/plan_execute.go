@@ -80,7 +80,7 @@ func (e *Executor) newExecute(
 	query, comments := sqlparser.SplitMarginComments(sql)
 
 	// 2: Parse and Validate query
-	stmt, reservedVars, err := parseAndValidateQuery(query)
+	stmt, reservedVars, err := parseAndValidateQuery(query, safeSession.GetQueryAttributes())
 	if err != nil {
 		return err
 	}
@@ -143,6 +143,14 @@ func (e *Executor) newExecute(
 			logStats.Error = err
 			return err
 		}
+		if e.resultCache != nil {
+			vcursor.resultCacheTTL = e.resultCache.ttl(stmt, plan, vs)
+		}
+		releaseQuotas, err := e.queryQuotas.acquire(ctx, stmt, plan, vs, bindVars)
+		if err != nil {
+			logStats.Error = err
+			return err
+		}
 
 		// 5: Execute the plan and retry if needed
 		if plan.Instructions.NeedsTransaction() {
@@ -153,6 +161,7 @@ func (e *Executor) newExecute(
 		} else {
 			err = execPlan(ctx, plan, vcursor, bindVars, execStart)
 		}
+		releaseQuotas()
 
 		if err == nil || safeSession.InTransaction() {
 			return err
@@ -278,7 +287,9 @@ func (e *Executor) executePlan(
 ) (*sqltypes.Result, error) {
 
 	// 4: Execute!
-	qr, err := vcursor.ExecutePrimitive(ctx, plan.Instructions, bindVars, true)
+	qr, err := e.executeWithResultCache(ctx, safeSession, plan, vcursor, bindVars, func() (*sqltypes.Result, error) {
+		return vcursor.ExecutePrimitive(ctx, plan.Instructions, bindVars, true)
+	})
 
 	// 5: Log and add statistics
 	e.setLogStats(logStats, plan, vcursor, execStart, err, qr)
@@ -34,10 +34,6 @@ func gen4DeleteStmtPlanner(
 	reservedVars *sqlparser.ReservedVars,
 	vschema plancontext.VSchema,
 ) (*planResult, error) {
-	if deleteStmt.With != nil {
-		return nil, vterrors.VT12001("WITH expression in DELETE statement")
-	}
-
 	var err error
 	if len(deleteStmt.TableExprs) == 1 && len(deleteStmt.Targets) == 1 {
 		deleteStmt, err = rewriteSingleTbl(deleteStmt)
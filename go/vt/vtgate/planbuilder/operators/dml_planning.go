@@ -63,6 +63,10 @@ func buildChangedVindexesValues(update *sqlparser.Update, table *vindexes.Table,
 	changedVindexes := make(map[string]*engine.VindexValues)
 	buf, offset := initialQuery(ksidCols, table)
 	for i, vindex := range table.ColumnVindexes {
+		if vindex.IsPartialVindex() {
+			// The full column vindex covers the columns of its partial ones.
+			continue
+		}
 		vindexValueMap := make(map[string]evalengine.Expr)
 		first := true
 		for _, vcol := range vindex.Columns {
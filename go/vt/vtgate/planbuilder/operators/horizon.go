@@ -103,7 +103,8 @@ func (h *Horizon) AddPredicate(ctx *plancontext.PlanningContext, expr sqlparser.
 	}
 
 	newExpr := semantics.RewriteDerivedTableExpression(expr, tableInfo)
-	if sqlparser.ContainsAggregation(newExpr) {
+	// window functions are computed after the WHERE clause, like aggregations
+	if sqlparser.ContainsAggregation(newExpr) || sqlparser.ContainsWindowFunction(newExpr) {
 		return &Filter{Source: h, Predicates: []sqlparser.Expr{expr}}, nil
 	}
 	h.Source, err = h.Source.AddPredicate(ctx, newExpr)
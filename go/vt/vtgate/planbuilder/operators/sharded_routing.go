@@ -21,6 +21,7 @@ import (
 
 	"vitess.io/vitess/go/mysql/collations"
 	"vitess.io/vitess/go/slice"
+	"vitess.io/vitess/go/sqltypes"
 	"vitess.io/vitess/go/vt/sqlparser"
 	"vitess.io/vitess/go/vt/vterrors"
 	"vitess.io/vitess/go/vt/vtgate/engine"
@@ -228,6 +229,10 @@ func (tr *ShardedRouting) searchForNewVindexes(ctx *plancontext.PlanningContext,
 	case *sqlparser.IsExpr:
 		found := tr.planIsExpr(ctx, node)
 		newVindexFound = newVindexFound || found
+
+	case *sqlparser.BetweenExpr:
+		found := tr.planBetweenExpr(ctx, node)
+		newVindexFound = newVindexFound || found
 	}
 
 	return nil, newVindexFound, nil
@@ -244,7 +249,9 @@ func (tr *ShardedRouting) planComparison(ctx *plancontext.PlanningContext, cmp *
 	case sqlparser.LikeOp:
 		found := tr.planLikeOp(ctx, cmp)
 		return nil, found, nil
-
+	case sqlparser.LessThanOp, sqlparser.LessEqualOp, sqlparser.GreaterThanOp, sqlparser.GreaterEqualOp:
+		found := tr.planRangeCmpOp(ctx, cmp)
+		return nil, found, nil
 	}
 	return nil, false, nil
 }
@@ -323,6 +330,87 @@ func (tr *ShardedRouting) planLikeOp(ctx *plancontext.PlanningContext, node *sql
 	return tr.haveMatchingVindex(ctx, node, vdValue, column, val, selectEqual, vdx)
 }
 
+func (tr *ShardedRouting) planBetweenExpr(ctx *plancontext.PlanningContext, node *sqlparser.BetweenExpr) bool {
+	if !node.IsBetween {
+		return false
+	}
+	column, ok := node.Left.(*sqlparser.ColName)
+	if !ok {
+		return false
+	}
+	return tr.planRange(ctx, node, column, node.From, node.To)
+}
+
+// planRangeCmpOp plans the `<`, `<=`, `>` and `>=` comparisons of a column as
+// ranges that are unbounded on one side. The bounds of `<` and `>` are
+// included, which only widens the range.
+func (tr *ShardedRouting) planRangeCmpOp(ctx *plancontext.PlanningContext, node *sqlparser.ComparisonExpr) bool {
+	// upperBound is true if the other side is the upper bound of the column
+	upperBound := node.Operator == sqlparser.LessThanOp || node.Operator == sqlparser.LessEqualOp
+	column, ok := node.Left.(*sqlparser.ColName)
+	other := node.Right
+	if !ok {
+		column, ok = node.Right.(*sqlparser.ColName)
+		if !ok {
+			// either the LHS or RHS have to be a column to be useful for the vindex
+			return false
+		}
+		other = node.Left
+		upperBound = !upperBound
+	}
+
+	if upperBound {
+		return tr.planRange(ctx, node, column, &sqlparser.NullVal{}, other)
+	}
+	return tr.planRange(ctx, node, column, other, &sqlparser.NullVal{})
+}
+
+// planRange adds the vindex options of the vindexes of the column that can
+// map the range from `from` to `to`. A NULL bound leaves the range unbounded
+// on that side.
+func (tr *ShardedRouting) planRange(ctx *plancontext.PlanningContext, node sqlparser.Expr, column *sqlparser.ColName, fromExpr, toExpr sqlparser.Expr) bool {
+	from := makeEvalEngineExpr(ctx, fromExpr)
+	to := makeEvalEngineExpr(ctx, toExpr)
+	if from == nil || to == nil {
+		return false
+	}
+
+	newVindexFound := false
+	for _, v := range tr.VindexPreds {
+		if !ctx.SemTable.DirectDeps(column).IsSolvedBy(v.TableID) {
+			continue
+		}
+		// Only vindexes that can map a range of values to key ranges can be used.
+		rangeMapper, ok := v.ColVindex.Vindex.(vindexes.RangeMapper)
+		if !ok || !column.Name.Equal(v.ColVindex.Columns[0]) {
+			continue
+		}
+		// The binary vindex compares the bounds byte by byte, which is only
+		// how MySQL compares the values of binary columns. Other columns
+		// have collations, so their ranges can't be mapped to key ranges.
+		if _, isBinary := rangeMapper.(*vindexes.Binary); isBinary && !isBinaryColumn(ctx, column) {
+			continue
+		}
+		v.Options = append(v.Options, &VindexOption{
+			Values:      []evalengine.Expr{from, to},
+			ValueExprs:  []sqlparser.Expr{fromExpr, toExpr},
+			Predicates:  []sqlparser.Expr{node},
+			OpCode:      engine.Range,
+			FoundVindex: rangeMapper,
+			Cost:        costFor(v.ColVindex, engine.Range),
+			Ready:       true,
+		})
+		newVindexFound = true
+	}
+	return newVindexFound
+}
+
+// isBinaryColumn returns true if the column is known to be binary or varbinary.
+func isBinaryColumn(ctx *plancontext.PlanningContext, column *sqlparser.ColName) bool {
+	typ, _, found := ctx.SemTable.TypeForExpr(column)
+	return found && (typ == sqltypes.Binary || typ == sqltypes.VarBinary)
+}
+
 func (tr *ShardedRouting) Cost() int {
 	switch tr.RouteOpCode {
 	case engine.EqualUnique:
@@ -333,6 +421,8 @@ func (tr *ShardedRouting) Cost() int {
 		return 10
 	case engine.MultiEqual:
 		return 10
+	case engine.Range:
+		return 15
 	case engine.Scatter:
 		return 20
 	default:
@@ -599,7 +689,7 @@ func tryMergeJoinShardedRouting(
 		// can merge via join predicates instead.
 		fallthrough
 
-	case engine.Scatter, engine.IN, engine.None:
+	case engine.Scatter, engine.IN, engine.Range, engine.None:
 		if len(joinPredicates) == 0 {
 			// If we are doing two Scatters, we have to make sure that the
 			// joins are on the correct vindex to allow them to be merged
@@ -0,0 +1,838 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package planbuilder
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"vitess.io/vitess/go/mysql/collations"
+	"vitess.io/vitess/go/sqltypes"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vterrors"
+	"vitess.io/vitess/go/vt/vtgate/engine"
+	"vitess.io/vitess/go/vt/vtgate/evalengine"
+	"vitess.io/vitess/go/vt/vtgate/planbuilder/operators"
+	"vitess.io/vitess/go/vt/vtgate/planbuilder/plancontext"
+	"vitess.io/vitess/go/vt/vtgate/vindexes"
+)
+
+// gen4RecursiveCTEPlanner plans a SELECT that uses a recursive common table expression.
+// If all the tables used live in the same unsharded keyspace, the whole query is sent to it.
+// Otherwise, the anchor and the recursive part of the CTE are planned as separate queries,
+// and the recursion is driven by the vtgate. The returned plan is nil when the query does
+// not use a recursive CTE.
+func gen4RecursiveCTEPlanner(
+	plannerVersion querypb.ExecuteOptions_PlannerVersion,
+	sel *sqlparser.Select,
+	reservedVars *sqlparser.ReservedVars,
+	vschema plancontext.VSchema,
+) (*planResult, error) {
+	if sel.With == nil || !sel.With.Recursive {
+		return nil, nil
+	}
+	var rcte *sqlparser.CommonTableExpr
+	var others []*sqlparser.CommonTableExpr
+	for _, cte := range sel.With.CTEs {
+		if !cte.IsSelfReferencing() {
+			others = append(others, cte)
+			continue
+		}
+		if rcte != nil {
+			return nil, vterrors.VT12001("multiple recursive common table expressions in the same query")
+		}
+		rcte = cte
+	}
+	if rcte == nil {
+		return nil, nil
+	}
+
+	ks, tableNames, err := recursiveCTEUnshardedKeyspace(sel, vschema)
+	if err != nil {
+		return nil, err
+	}
+	if ks != nil {
+		return recursiveCTEUnshardedShortcut(sel, ks, tableNames, reservedVars, vschema, plannerVersion)
+	}
+
+	rc := &recursiveCTE{
+		cte:            rcte,
+		others:         others,
+		reservedVars:   reservedVars,
+		vschema:        vschema,
+		plannerVersion: plannerVersion,
+	}
+	return rc.plan(sel)
+}
+
+// recursiveCTEUnshardedKeyspace returns the keyspace of the query if all the tables it uses
+// belong to the same unsharded keyspace.
+func recursiveCTEUnshardedKeyspace(sel *sqlparser.Select, vschema plancontext.VSchema) (*vindexes.Keyspace, []sqlparser.TableName, error) {
+	cteNames := map[string]any{}
+	for _, cte := range sel.With.CTEs {
+		cteNames[cte.ID.String()] = nil
+	}
+
+	var ks *vindexes.Keyspace
+	tableNameMap := map[string]sqlparser.TableName{}
+	sharded := false
+	err := sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		aliasedTbl, ok := node.(*sqlparser.AliasedTableExpr)
+		if !ok {
+			return true, nil
+		}
+		tbl, ok := aliasedTbl.Expr.(sqlparser.TableName)
+		if !ok {
+			return true, nil
+		}
+		if tbl.Qualifier.IsEmpty() {
+			if _, isCTE := cteNames[tbl.Name.String()]; isCTE || tbl.Name.String() == "dual" {
+				return true, nil
+			}
+		}
+		vtbl, _, _, dest, err := vschema.FindTable(tbl)
+		if err != nil {
+			return false, err
+		}
+		if dest != nil || vtbl.Keyspace == nil || vtbl.Keyspace.Sharded || (ks != nil && ks.Name != vtbl.Keyspace.Name) {
+			sharded = true
+			return false, nil
+		}
+		ks = vtbl.Keyspace
+		tableNameMap[vtbl.Name.String()] = sqlparser.TableName{Name: vtbl.Name}
+		return true, nil
+	}, sel)
+	if err != nil || sharded || ks == nil {
+		return nil, nil, err
+	}
+
+	var tableNames []sqlparser.TableName
+	for _, tbl := range tableNameMap {
+		tableNames = append(tableNames, tbl)
+	}
+	return ks, tableNames, nil
+}
+
+func recursiveCTEUnshardedShortcut(
+	sel *sqlparser.Select,
+	ks *vindexes.Keyspace,
+	tableNames []sqlparser.TableName,
+	reservedVars *sqlparser.ReservedVars,
+	vschema plancontext.VSchema,
+	plannerVersion querypb.ExecuteOptions_PlannerVersion,
+) (*planResult, error) {
+	sqlparser.SafeRewrite(sel, nil, func(cursor *sqlparser.Cursor) bool {
+		switch node := cursor.Node().(type) {
+		case sqlparser.SelectExpr:
+			removeKeyspaceFromSelectExpr(node)
+		case sqlparser.TableName:
+			cursor.Replace(sqlparser.TableName{
+				Name: node.Name,
+			})
+		}
+		return true
+	})
+	sortTableNames(tableNames)
+
+	plan := &route{
+		eroute: &engine.Route{
+			RoutingParameters: &engine.RoutingParameters{
+				Opcode:   engine.Unsharded,
+				Keyspace: ks,
+			},
+			TableName: strings.Join(escapedTableNames(tableNames), ", "),
+		},
+		Select: sel,
+	}
+	ctx := &plancontext.PlanningContext{
+		ReservedVars:   reservedVars,
+		VSchema:        vschema,
+		PlannerVersion: plannerVersion,
+	}
+	if err := plan.Wireup(ctx); err != nil {
+		return nil, err
+	}
+	lp := pushCommentDirectivesOnPlan(plan, sel)
+	return newPlanResult(lp.Primitive(), operators.QualifiedTableNames(ks, tableNames)...), nil
+}
+
+func sortTableNames(tableNames []sqlparser.TableName) {
+	sort.Slice(tableNames, func(i, j int) bool {
+		return tableNames[i].Name.String() < tableNames[j].Name.String()
+	})
+}
+
+// recursiveCTE holds the state needed to plan a recursive CTE at the vtgate level
+type recursiveCTE struct {
+	cte            *sqlparser.CommonTableExpr
+	others         []*sqlparser.CommonTableExpr
+	columns        []string
+	reservedVars   *sqlparser.ReservedVars
+	vschema        plancontext.VSchema
+	plannerVersion querypb.ExecuteOptions_PlannerVersion
+}
+
+func (rc *recursiveCTE) plan(sel *sqlparser.Select) (*planResult, error) {
+	body, ok := rc.cte.Subquery.Select.(*sqlparser.Union)
+	if !ok {
+		return nil, vterrors.VT12001("recursive common table expression without a UNION")
+	}
+	if len(body.OrderBy) > 0 || body.Limit != nil {
+		return nil, vterrors.VT12001("ORDER BY / LIMIT in a recursive common table expression")
+	}
+	term, ok := body.Right.(*sqlparser.Select)
+	if !ok {
+		return nil, vterrors.VT12001("recursive part of a common table expression that is not a simple SELECT")
+	}
+	anchor := body.Left
+	anchorCheck := &sqlparser.CommonTableExpr{ID: rc.cte.ID, Subquery: &sqlparser.Subquery{Select: anchor}}
+	if anchorCheck.IsSelfReferencing() {
+		return nil, vterrors.VT12001("recursive reference in the anchor of a recursive common table expression")
+	}
+	if len(term.GroupBy) > 0 || term.Having != nil || len(term.OrderBy) > 0 || term.Limit != nil || term.Distinct ||
+		sqlparser.ContainsAggregation(term.SelectExprs) {
+		return nil, vterrors.VT12001("aggregation, DISTINCT, ORDER BY or LIMIT in the recursive part of a common table expression")
+	}
+
+	if err := rc.setColumns(anchor); err != nil {
+		return nil, err
+	}
+
+	part, err := rc.splitTerm(term)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rc.others) > 0 {
+		anchor.SetWith(&sqlparser.With{CTEs: rc.others})
+		if part.query != nil {
+			part.query.With = sqlparser.CloneRefOfWith(&sqlparser.With{CTEs: rc.others})
+		}
+	}
+
+	seed, err := gen4SelectStmtPlanner(sqlparser.String(anchor), rc.plannerVersion, anchor, rc.reservedVars, rc.vschema)
+	if err != nil {
+		return nil, err
+	}
+	tablesUsed := seed.tables
+
+	eRecurse := &engine.RecurseCTE{
+		Seed:        seed.primitive,
+		JoinCol:     part.joinCol,
+		TermJoinCol: part.termJoinCol,
+		ValuesVar:   part.valuesVar,
+		Predicate:   part.predicate,
+		Exprs:       part.exprs,
+		Collation:   rc.vschema.ConnCollation(),
+		Columns:     rc.columns,
+		Distinct:    body.Distinct,
+	}
+	if part.query != nil {
+		recursive, err := gen4SelectStmtPlanner(sqlparser.String(part.query), rc.plannerVersion, part.query, rc.reservedVars, rc.vschema)
+		if err != nil {
+			return nil, err
+		}
+		eRecurse.Term = recursive.primitive
+		tablesUsed = append(tablesUsed, recursive.tables...)
+	}
+	if body.Distinct {
+		for i := range rc.columns {
+			eRecurse.CheckCols = append(eRecurse.CheckCols, engine.CheckCol{
+				Col:       i,
+				Type:      sqltypes.Unknown,
+				Collation: rc.vschema.ConnCollation(),
+			})
+		}
+	}
+
+	prim, err := rc.planOuterQuery(sel, eRecurse)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(tablesUsed)
+	return newPlanResult(prim, slices.Compact(tablesUsed)...), nil
+}
+
+// setColumns decides on the column names of the CTE
+func (rc *recursiveCTE) setColumns(anchor sqlparser.SelectStatement) error {
+	if len(rc.cte.Columns) > 0 {
+		if len(rc.cte.Columns) != anchor.GetColumnCount() {
+			return vterrors.NewErrorf(vtrpcpb.Code_INVALID_ARGUMENT, vterrors.WrongNumberOfColumnsInSelect,
+				"In definition of view, derived table or common table expression, SELECT list and column names list have different column counts")
+		}
+		for _, col := range rc.cte.Columns {
+			rc.columns = append(rc.columns, col.String())
+		}
+		return nil
+	}
+	for _, expr := range anchor.GetColumns() {
+		ae, ok := expr.(*sqlparser.AliasedExpr)
+		if !ok {
+			return vterrors.VT12001("'*' in the anchor of a recursive common table expression without a column list")
+		}
+		rc.columns = append(rc.columns, ae.ColumnName())
+	}
+	return nil
+}
+
+func (rc *recursiveCTE) columnOffset(name sqlparser.IdentifierCI) int {
+	for i, col := range rc.columns {
+		if name.EqualString(col) {
+			return i
+		}
+	}
+	return -1
+}
+
+// recursivePart is the recursive part of a CTE, split between the query sent to the
+// tablets once per iteration and the expressions the vtgate evaluates over the rows
+// of the working set joined with the rows of that query
+type recursivePart struct {
+	// query reads the tables joined with the CTE. It is nil when the CTE is the only table.
+	query *sqlparser.Select
+
+	joinCol, termJoinCol int
+	valuesVar            string
+
+	predicate evalengine.Expr
+	exprs     []evalengine.Expr
+}
+
+// splitTerm removes the reference to the CTE from the recursive part of the query.
+// The first equality between a column of the CTE and an expression over the other
+// tables becomes the join condition: the query reads the rows matching any value of
+// the working set through a list bind variable. The expressions that only use the
+// other tables are pushed to the query, and everything else is evaluated by the
+// vtgate, over a row of the working set followed by the columns of the query.
+func (rc *recursiveCTE) splitTerm(term *sqlparser.Select) (*recursivePart, error) {
+	var alias sqlparser.IdentifierCS
+	var predicates []sqlparser.Expr
+	found := 0
+
+	var removeCTE func(te sqlparser.TableExpr) (sqlparser.TableExpr, error)
+	removeCTE = func(te sqlparser.TableExpr) (sqlparser.TableExpr, error) {
+		switch te := te.(type) {
+		case *sqlparser.AliasedTableExpr:
+			tbl, ok := te.Expr.(sqlparser.TableName)
+			if !ok || !tbl.Qualifier.IsEmpty() || tbl.Name.String() != rc.cte.ID.String() {
+				return te, nil
+			}
+			found++
+			alias = te.As
+			if alias.IsEmpty() {
+				alias = tbl.Name
+			}
+			return nil, nil
+		case *sqlparser.ParenTableExpr:
+			var exprs sqlparser.TableExprs
+			for _, expr := range te.Exprs {
+				newExpr, err := removeCTE(expr)
+				if err != nil {
+					return nil, err
+				}
+				if newExpr != nil {
+					exprs = append(exprs, newExpr)
+				}
+			}
+			if len(exprs) == 0 {
+				return nil, nil
+			}
+			te.Exprs = exprs
+			return te, nil
+		case *sqlparser.JoinTableExpr:
+			before := found
+			lhs, err := removeCTE(te.LeftExpr)
+			if err != nil {
+				return nil, err
+			}
+			rhs, err := removeCTE(te.RightExpr)
+			if err != nil {
+				return nil, err
+			}
+			if lhs != nil && rhs != nil {
+				te.LeftExpr, te.RightExpr = lhs, rhs
+				return te, nil
+			}
+			if found > before && (te.Join != sqlparser.NormalJoinType || te.Condition.Using != nil) {
+				return nil, vterrors.VT12001("outer join or USING with the recursive reference of a common table expression")
+			}
+			if te.Condition.On != nil {
+				predicates = append(predicates, te.Condition.On)
+			}
+			if lhs != nil {
+				return lhs, nil
+			}
+			return rhs, nil
+		}
+		return te, nil
+	}
+
+	var from sqlparser.TableExprs
+	for _, te := range term.From {
+		newTE, err := removeCTE(te)
+		if err != nil {
+			return nil, err
+		}
+		if newTE != nil {
+			from = append(from, newTE)
+		}
+	}
+	if found != 1 {
+		return nil, vterrors.VT12001("recursive common table expression that is not referenced exactly once in the FROM clause of its recursive part")
+	}
+	rest := &sqlparser.Select{SelectExprs: term.SelectExprs, From: from, Where: term.Where}
+	if (&sqlparser.CommonTableExpr{ID: rc.cte.ID, Subquery: &sqlparser.Subquery{Select: rest}}).IsSelfReferencing() {
+		return nil, vterrors.VT12001("recursive reference in a subquery of a recursive common table expression")
+	}
+
+	tr := &termResolver{
+		rc:           rc,
+		alias:        alias,
+		onlyCTE:      len(from) == 0,
+		otherColumns: rc.knownColumns(from),
+	}
+	if !tr.onlyCTE {
+		// the join conditions left in the query must not use the CTE
+		if hasRefs, err := tr.hasCTERefs(from); err != nil || hasRefs {
+			if err == nil {
+				err = vterrors.VT12001("reference to a recursive common table expression in a join condition it is not part of")
+			}
+			return nil, err
+		}
+	}
+
+	if term.Where != nil {
+		predicates = sqlparser.SplitAndExpression(predicates, term.Where.Expr)
+	}
+	part := &recursivePart{joinCol: -1, termJoinCol: -1}
+	var queryPreds, vtgatePreds []sqlparser.Expr
+	var joinExpr sqlparser.Expr
+	for _, pred := range predicates {
+		hasRefs, err := tr.hasCTERefs(pred)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !hasRefs && !tr.onlyCTE:
+			queryPreds = append(queryPreds, pred)
+		case joinExpr == nil && !tr.onlyCTE:
+			col, expr, err := tr.joinPredicate(pred)
+			if err != nil {
+				return nil, err
+			}
+			if expr == nil {
+				vtgatePreds = append(vtgatePreds, pred)
+				continue
+			}
+			part.joinCol = col
+			joinExpr = expr
+		default:
+			vtgatePreds = append(vtgatePreds, pred)
+		}
+	}
+
+	selectExprs := term.SelectExprs
+	if !tr.onlyCTE {
+		term.From = from
+		term.SelectExprs = nil
+		term.Where = nil
+		for _, pred := range queryPreds {
+			term.AddWhere(pred)
+		}
+		tr.query = term
+		part.query = term
+	}
+
+	if joinExpr != nil {
+		part.termJoinCol = tr.push(joinExpr)
+		part.valuesVar = rc.reservedVars.ReserveVariable(alias.String() + "_" + rc.columns[part.joinCol])
+		term.AddWhere(&sqlparser.ComparisonExpr{
+			Operator: sqlparser.InOp,
+			Left:     sqlparser.CloneExpr(joinExpr),
+			Right:    sqlparser.NewListArg(part.valuesVar),
+		})
+	}
+
+	cfg := &evalengine.Config{
+		ResolveColumn: tr.resolve,
+		Collation:     rc.vschema.ConnCollation(),
+	}
+	for _, expr := range selectExprs {
+		ae, ok := expr.(*sqlparser.AliasedExpr)
+		if !ok {
+			return nil, vterrors.VT12001(sqlparser.String(expr) + " in the recursive part of a common table expression")
+		}
+		hasRefs, err := tr.hasCTERefs(ae.Expr)
+		if err != nil {
+			return nil, err
+		}
+		if !hasRefs && !tr.onlyCTE {
+			offset := len(rc.columns) + tr.push(ae.Expr)
+			part.exprs = append(part.exprs, evalengine.NewColumn(offset, sqltypes.Unknown, collations.Unknown))
+			continue
+		}
+		eexpr, err := evalengine.Translate(ae.Expr, cfg)
+		if err != nil {
+			return nil, err
+		}
+		part.exprs = append(part.exprs, eexpr)
+	}
+	if len(part.exprs) != len(rc.columns) {
+		return nil, vterrors.NewErrorf(vtrpcpb.Code_INVALID_ARGUMENT, vterrors.WrongNumberOfColumnsInSelect, "The used SELECT statements have a different number of columns")
+	}
+
+	if len(vtgatePreds) > 0 {
+		var err error
+		part.predicate, err = evalengine.Translate(sqlparser.AndExpressions(vtgatePreds...), cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if part.query != nil && len(part.query.SelectExprs) == 0 {
+		tr.push(sqlparser.NewIntLiteral("1"))
+	}
+	return part, nil
+}
+
+// knownColumns returns the names of the columns the vschema knows about for the given tables
+func (rc *recursiveCTE) knownColumns(from sqlparser.TableExprs) map[string]bool {
+	columns := map[string]bool{}
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Subquery:
+			return false, nil
+		case *sqlparser.AliasedTableExpr:
+			tbl, ok := node.Expr.(sqlparser.TableName)
+			if !ok {
+				return false, nil
+			}
+			vtbl, _, _, _, err := rc.vschema.FindTable(tbl)
+			if err != nil || vtbl == nil {
+				// the planner reports unknown tables when planning the query
+				return false, nil
+			}
+			for _, col := range vtbl.Columns {
+				columns[col.Name.Lowered()] = true
+			}
+			return false, nil
+		}
+		return true, nil
+	}, from)
+	return columns
+}
+
+// termResolver decides which columns of the recursive part of a CTE are read from the CTE
+type termResolver struct {
+	rc    *recursiveCTE
+	alias sqlparser.IdentifierCS
+	// onlyCTE is set when the CTE is the only table of the recursive part
+	onlyCTE bool
+	// otherColumns are the known columns of the other tables
+	otherColumns map[string]bool
+	// query is the query reading the other tables, its SELECT expressions are
+	// appended to the columns of the working set
+	query *sqlparser.Select
+}
+
+// cteColumn returns the offset of the CTE column the given column refers to, or -1.
+// Unqualified columns are resolved against the columns of the CTE, and are ambiguous
+// if one of the other tables has a column with the same name.
+func (tr *termResolver) cteColumn(col *sqlparser.ColName) (int, error) {
+	if !col.Qualifier.IsEmpty() {
+		if !col.Qualifier.Qualifier.IsEmpty() || col.Qualifier.Name.String() != tr.alias.String() {
+			return -1, nil
+		}
+		offset := tr.rc.columnOffset(col.Name)
+		if offset < 0 {
+			return -1, vterrors.VT03019(sqlparser.String(col))
+		}
+		return offset, nil
+	}
+	offset := tr.rc.columnOffset(col.Name)
+	if offset < 0 {
+		if tr.onlyCTE {
+			return -1, vterrors.VT03019(sqlparser.String(col))
+		}
+		return -1, nil
+	}
+	if tr.otherColumns[col.Name.Lowered()] {
+		return -1, vterrors.VT03021(sqlparser.String(col))
+	}
+	return offset, nil
+}
+
+// hasCTERefs returns true if the given node reads a column of the CTE
+func (tr *termResolver) hasCTERefs(node sqlparser.SQLNode) (bool, error) {
+	found := false
+	err := sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Subquery:
+			// the columns of a subquery belong to its own tables, unless they are
+			// qualified with the name of the CTE
+			return false, sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+				col, ok := node.(*sqlparser.ColName)
+				if ok && col.Qualifier.Qualifier.IsEmpty() && col.Qualifier.Name.String() == tr.alias.String() {
+					return false, vterrors.VT12001("reference to a recursive common table expression in a subquery of its recursive part")
+				}
+				return true, nil
+			}, node)
+		case *sqlparser.ColName:
+			offset, err := tr.cteColumn(node)
+			if err != nil {
+				return false, err
+			}
+			found = found || offset >= 0
+		}
+		return true, nil
+	}, node)
+	return found, err
+}
+
+// joinPredicate returns the CTE column and the expression of an equality that can be
+// used to join the working set with the other tables, or a nil expression
+func (tr *termResolver) joinPredicate(pred sqlparser.Expr) (int, sqlparser.Expr, error) {
+	cmp, ok := pred.(*sqlparser.ComparisonExpr)
+	if !ok || cmp.Operator != sqlparser.EqualOp {
+		return -1, nil, nil
+	}
+	for _, sides := range [][2]sqlparser.Expr{{cmp.Left, cmp.Right}, {cmp.Right, cmp.Left}} {
+		col, ok := sides[0].(*sqlparser.ColName)
+		if !ok {
+			continue
+		}
+		offset, err := tr.cteColumn(col)
+		if err != nil {
+			return -1, nil, err
+		}
+		if offset < 0 {
+			continue
+		}
+		hasRefs, err := tr.hasCTERefs(sides[1])
+		if err != nil {
+			return -1, nil, err
+		}
+		if !hasRefs && containsColumn(sides[1]) {
+			return offset, sides[1], nil
+		}
+	}
+	return -1, nil, nil
+}
+
+func containsColumn(expr sqlparser.Expr) bool {
+	found := false
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		_, isCol := node.(*sqlparser.ColName)
+		found = found || isCol
+		return !found, nil
+	}, expr)
+	return found
+}
+
+// push adds the expression to the SELECT expressions of the query, and returns its offset
+func (tr *termResolver) push(expr sqlparser.Expr) int {
+	for i, selectExpr := range tr.query.SelectExprs {
+		if ae, ok := selectExpr.(*sqlparser.AliasedExpr); ok && sqlparser.Equals.Expr(ae.Expr, expr) {
+			return i
+		}
+	}
+	tr.query.SelectExprs = append(tr.query.SelectExprs, &sqlparser.AliasedExpr{Expr: sqlparser.CloneExpr(expr)})
+	return len(tr.query.SelectExprs) - 1
+}
+
+// resolve is used by the evalengine: the columns of the CTE are the first columns of the
+// joined rows, and the columns of the other tables are read from the query
+func (tr *termResolver) resolve(col *sqlparser.ColName) (int, error) {
+	offset, err := tr.cteColumn(col)
+	if err != nil || offset >= 0 {
+		return offset, err
+	}
+	return len(tr.rc.columns) + tr.push(col), nil
+}
+
+// planOuterQuery adds the filtering, projection, ordering and limit of the query
+// that reads from the recursive CTE on top of it
+func (rc *recursiveCTE) planOuterQuery(sel *sqlparser.Select, input engine.Primitive) (engine.Primitive, error) {
+	alias, err := rc.outerAlias(sel)
+	if err != nil {
+		return nil, err
+	}
+	if len(sel.GroupBy) > 0 || sel.Having != nil || sel.Distinct || sel.Into != nil || sel.Lock != sqlparser.NoLock ||
+		sqlparser.ContainsAggregation(sel.SelectExprs) {
+		return nil, vterrors.VT12001("aggregation, DISTINCT, INTO or locking on top of a recursive common table expression")
+	}
+
+	collation := rc.vschema.ConnCollation()
+	cfg := &evalengine.Config{
+		ResolveColumn: func(col *sqlparser.ColName) (int, error) {
+			if !col.Qualifier.IsEmpty() && (!col.Qualifier.Qualifier.IsEmpty() || col.Qualifier.Name.String() != alias.String()) {
+				return 0, vterrors.VT03019(sqlparser.String(col))
+			}
+			offset := rc.columnOffset(col.Name)
+			if offset < 0 {
+				return 0, vterrors.VT03019(sqlparser.String(col))
+			}
+			return offset, nil
+		},
+		Collation: collation,
+	}
+
+	if sel.Where != nil {
+		predicate, err := evalengine.Translate(sel.Where.Expr, cfg)
+		if err != nil {
+			return nil, err
+		}
+		input = &engine.Filter{
+			Predicate:    predicate,
+			ASTPredicate: sel.Where.Expr,
+			Input:        input,
+		}
+	}
+
+	proj := &engine.Projection{Input: input}
+	identity := true
+	addColumn := func(name string, expr evalengine.Expr) {
+		offset := len(proj.Cols)
+		if col, ok := expr.(*evalengine.Column); !ok || col.Offset != offset || offset >= len(rc.columns) || rc.columns[offset] != name {
+			identity = false
+		}
+		proj.Cols = append(proj.Cols, name)
+		proj.Exprs = append(proj.Exprs, expr)
+	}
+	var selectExprs []sqlparser.Expr
+	for _, expr := range sel.SelectExprs {
+		switch expr := expr.(type) {
+		case *sqlparser.StarExpr:
+			if !expr.TableName.IsEmpty() && (!expr.TableName.Qualifier.IsEmpty() || expr.TableName.Name.String() != alias.String()) {
+				return nil, vterrors.VT05004(sqlparser.String(expr.TableName))
+			}
+			for i, col := range rc.columns {
+				addColumn(col, evalengine.NewColumn(i, sqltypes.Unknown, collations.Unknown))
+				selectExprs = append(selectExprs, sqlparser.NewColName(col))
+			}
+		case *sqlparser.AliasedExpr:
+			eexpr, err := evalengine.Translate(expr.Expr, cfg)
+			if err != nil {
+				return nil, err
+			}
+			addColumn(expr.ColumnName(), eexpr)
+			selectExprs = append(selectExprs, expr.Expr)
+		default:
+			return nil, vterrors.VT12001(sqlparser.String(expr) + " on top of a recursive common table expression")
+		}
+	}
+	columnCount := len(proj.Cols)
+
+	var orderBy []engine.OrderByParams
+	for _, order := range sel.OrderBy {
+		offset, err := rc.orderByOffset(order.Expr, sel.SelectExprs, selectExprs, columnCount)
+		if err != nil {
+			return nil, err
+		}
+		if offset < 0 {
+			eexpr, err := evalengine.Translate(order.Expr, cfg)
+			if err != nil {
+				return nil, err
+			}
+			offset = len(proj.Cols)
+			identity = false
+			proj.Cols = append(proj.Cols, sqlparser.String(order.Expr))
+			proj.Exprs = append(proj.Exprs, eexpr)
+		}
+		orderBy = append(orderBy, engine.OrderByParams{
+			Col:             offset,
+			WeightStringCol: -1,
+			Desc:            order.Direction == sqlparser.DescOrder,
+			CollationID:     collation,
+		})
+	}
+
+	if !identity || len(proj.Cols) != len(rc.columns) {
+		input = proj
+	}
+	if len(orderBy) > 0 {
+		ms := &engine.MemorySort{
+			OrderBy: orderBy,
+			Input:   input,
+		}
+		if len(proj.Cols) > columnCount {
+			ms.TruncateColumnCount = columnCount
+		}
+		input = ms
+	}
+
+	if sel.Limit != nil {
+		lp, err := createLimit(&primitiveWrapper{prim: input}, sel.Limit)
+		if err != nil {
+			return nil, err
+		}
+		input = lp.Primitive()
+	}
+	return input, nil
+}
+
+// outerAlias makes sure the query on top of the recursive CTE only reads from it,
+// and returns the name it is known by
+func (rc *recursiveCTE) outerAlias(sel *sqlparser.Select) (sqlparser.IdentifierCS, error) {
+	if len(sel.From) == 1 {
+		if aliasedTbl, ok := sel.From[0].(*sqlparser.AliasedTableExpr); ok {
+			tbl, ok := aliasedTbl.Expr.(sqlparser.TableName)
+			if ok && tbl.Qualifier.IsEmpty() && tbl.Name.String() == rc.cte.ID.String() {
+				if aliasedTbl.As.IsEmpty() {
+					return tbl.Name, nil
+				}
+				return aliasedTbl.As, nil
+			}
+		}
+	}
+	return sqlparser.IdentifierCS{}, vterrors.VT12001("reading from anything else than the recursive common table expression in the same SELECT")
+}
+
+// orderByOffset finds the offset of an ORDER BY expression in the projection, or returns -1
+// if it needs to be added as a hidden column
+func (rc *recursiveCTE) orderByOffset(expr sqlparser.Expr, selectExprs sqlparser.SelectExprs, exprs []sqlparser.Expr, columnCount int) (int, error) {
+	if lit, ok := expr.(*sqlparser.Literal); ok && lit.Type == sqlparser.IntVal {
+		num, err := strconv.Atoi(lit.Val)
+		if err != nil || num < 1 || num > columnCount {
+			return 0, vterrors.VT03014(num, "order clause")
+		}
+		return num - 1, nil
+	}
+	if col, ok := expr.(*sqlparser.ColName); ok && col.Qualifier.IsEmpty() {
+		// ORDER BY can refer to the aliases of the SELECT expressions
+		offset := 0
+		for _, selExpr := range selectExprs {
+			if ae, ok := selExpr.(*sqlparser.AliasedExpr); ok && !ae.As.IsEmpty() && col.Name.Equal(ae.As) {
+				return offset, nil
+			}
+			if _, ok := selExpr.(*sqlparser.StarExpr); ok {
+				offset += len(rc.columns)
+			} else {
+				offset++
+			}
+		}
+	}
+	for i, selExpr := range exprs {
+		if sqlparser.Equals.Expr(selExpr, expr) {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
@@ -38,25 +38,23 @@ func gen4SelectStmtPlanner(
 	reservedVars *sqlparser.ReservedVars,
 	vschema plancontext.VSchema,
 ) (*planResult, error) {
-	switch node := stmt.(type) {
-	case *sqlparser.Select:
-		if node.With != nil {
-			return nil, vterrors.VT12001("WITH expression in SELECT statement")
+	sel, isSel := stmt.(*sqlparser.Select)
+	if isSel {
+		// recursive CTEs are evaluated at the vtgate level, unless the whole query can go to a single unsharded keyspace
+		p, err := gen4RecursiveCTEPlanner(plannerVersion, sel, reservedVars, vschema)
+		if err != nil {
+			return nil, err
 		}
-	case *sqlparser.Union:
-		if node.With != nil {
-			return nil, vterrors.VT12001("WITH expression in UNION statement")
+		if p != nil {
+			return p, nil
 		}
-	}
 
-	sel, isSel := stmt.(*sqlparser.Select)
-	if isSel {
 		// handle dual table for processing at vtgate.
-		p, err := handleDualSelects(sel, vschema)
+		dualPlan, err := handleDualSelects(sel, vschema)
 		if err != nil {
 			return nil, err
 		}
-		if p != nil {
+		if dualPlan != nil {
 			used := "dual"
 			keyspace, ksErr := vschema.DefaultKeyspace()
 			if ksErr == nil {
@@ -64,7 +62,7 @@ func gen4SelectStmtPlanner(
 				// no need to fail this if we can't find the default keyspace
 				used = keyspace.Name + ".dual"
 			}
-			return newPlanResult(p, used), nil
+			return newPlanResult(dualPlan, used), nil
 		}
 
 		if sel.SQLCalcFoundRows && sel.Limit != nil {
@@ -78,11 +76,22 @@ func gen4SelectStmtPlanner(
 		return newBuildSelectPlan(selStatement, reservedVars, vschema, plannerVersion)
 	}
 
+	// window functions that the shards cannot compute are evaluated at the vtgate level
+	if isSel && selectUsesWindowFunctions(sel) && !windowCanBePushed(sel, vschema) {
+		return gen4WindowPlanner(plannerVersion, sel, reservedVars, vschema)
+	}
+	// the planning rewrites the query, so this is checked beforehand
+	nestedWindowsPushable := nestedWindowFunctionsCanBePushed(stmt, vschema)
+
 	plan, tablesUsed, err := getPlan(stmt)
 	if err != nil {
 		return nil, err
 	}
 
+	if !nestedWindowsPushable && !isSingleShardPlan(plan.Primitive()) {
+		return nil, vterrors.VT12001("window functions in a derived table, UNION or subquery that span multiple shards")
+	}
+
 	if shouldRetryAfterPredicateRewriting(plan) {
 		// by transforming the predicates to CNF, the planner will sometimes find better plans
 		plan2, tablesUsed := gen4PredicateRewrite(stmt, getPlan)
@@ -36,9 +36,11 @@ func buildStreamPlan(stmt *sqlparser.Stream, vschema plancontext.VSchema) (*plan
 	if dest == nil {
 		dest = key.DestinationExactKeyRange{}
 	}
+	consumerGroup, _ := stmt.Comments.Directives().GetString(sqlparser.DirectiveConsumerGroup, "")
 	return newPlanResult(&engine.MStream{
 		Keyspace:          table.Keyspace,
 		TargetDestination: dest,
 		TableName:         table.Name.CompliantName(),
+		ConsumerGroup:     consumerGroup,
 	}), nil
 }
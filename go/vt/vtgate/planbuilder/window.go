@@ -0,0 +1,513 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package planbuilder
+
+import (
+	"io"
+	"strconv"
+
+	"vitess.io/vitess/go/vt/key"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vterrors"
+	"vitess.io/vitess/go/vt/vtgate/engine"
+	"vitess.io/vitess/go/vt/vtgate/evalengine"
+	"vitess.io/vitess/go/vt/vtgate/planbuilder/plancontext"
+	"vitess.io/vitess/go/vt/vtgate/vindexes"
+)
+
+// selectUsesWindowFunctions returns true if the SELECT expressions or the ORDER BY of the query use window functions
+func selectUsesWindowFunctions(sel *sqlparser.Select) bool {
+	return sqlparser.ContainsWindowFunction(sel.SelectExprs) || sqlparser.ContainsWindowFunction(sel.OrderBy)
+}
+
+// windowCanBePushed returns true if the shards compute the window functions of the query
+// correctly. That is the case when the whole query goes to a single shard, or when it reads
+// from a single sharded table and every window is partitioned by the columns of its primary
+// vindex, so that no partition spans more than one shard. This is decided before planning,
+// so that the query is only planned once.
+func windowCanBePushed(sel *sqlparser.Select, vschema plancontext.VSchema) bool {
+	if singleUnshardedKeyspace(sel.From, vschema) {
+		return true
+	}
+
+	if len(sel.From) != 1 {
+		return false
+	}
+	aliasedTbl, ok := sel.From[0].(*sqlparser.AliasedTableExpr)
+	if !ok {
+		return false
+	}
+	tbl, ok := aliasedTbl.Expr.(sqlparser.TableName)
+	if !ok {
+		return false
+	}
+	vtbl, _, _, dest, err := vschema.FindTable(tbl)
+	if err != nil || vtbl == nil {
+		return false
+	}
+	if _, isShard := dest.(key.DestinationShard); isShard || vtbl.Type == vindexes.TypeReference {
+		return true
+	}
+	if len(vtbl.ColumnVindexes) == 0 {
+		return false
+	}
+	alias := tbl.Name
+	if !aliasedTbl.As.IsEmpty() {
+		alias = aliasedTbl.As
+	}
+	primary := vtbl.ColumnVindexes[0]
+	if primary.IsUnique() && sel.Where != nil && selectsSingleRow(sel.Where.Expr, primary.Columns, alias) {
+		return true
+	}
+
+	aligned := true
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if _, isSubq := node.(*sqlparser.Subquery); isSubq {
+			return false, nil
+		}
+		over := sqlparser.GetOverClause(node)
+		if over == nil {
+			return true, nil
+		}
+		if over.WindowSpec == nil {
+			aligned = false
+			return false, nil
+		}
+		for _, vindexCol := range primary.Columns {
+			if !partitionedBy(over.WindowSpec.PartitionClause, vindexCol, alias) {
+				aligned = false
+				return false, nil
+			}
+		}
+		return true, nil
+	}, sel.SelectExprs, sel.OrderBy)
+	return aligned
+}
+
+// singleUnshardedKeyspace returns true if all the tables read by the FROM clause belong to
+// the same unsharded keyspace. Subqueries of the join conditions are not inspected.
+func singleUnshardedKeyspace(from sqlparser.TableExprs, vschema plancontext.VSchema) bool {
+	var ks string
+	unsharded := true
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Subquery:
+			return false, nil
+		case *sqlparser.AliasedTableExpr:
+			tbl, ok := node.Expr.(sqlparser.TableName)
+			if !ok {
+				return true, nil
+			}
+			if tbl.Qualifier.IsEmpty() && tbl.Name.String() == "dual" {
+				return false, nil
+			}
+			vtbl, _, _, dest, err := vschema.FindTable(tbl)
+			if err != nil || vtbl == nil || dest != nil || vtbl.Keyspace == nil || vtbl.Keyspace.Sharded ||
+				(ks != "" && ks != vtbl.Keyspace.Name) {
+				unsharded = false
+				return false, io.EOF
+			}
+			ks = vtbl.Keyspace.Name
+			return false, nil
+		}
+		return true, nil
+	}, from)
+	return unsharded
+}
+
+// selectsSingleRow returns true if the predicate compares every given column of the table
+// to a value, which makes the query go to a single shard when they are the columns of a
+// unique vindex
+func selectsSingleRow(where sqlparser.Expr, columns []sqlparser.IdentifierCI, alias sqlparser.IdentifierCS) bool {
+	predicates := sqlparser.SplitAndExpression(nil, where)
+	for _, col := range columns {
+		found := false
+		for _, pred := range predicates {
+			cmp, ok := pred.(*sqlparser.ComparisonExpr)
+			if !ok || cmp.Operator != sqlparser.EqualOp {
+				continue
+			}
+			for _, sides := range [][2]sqlparser.Expr{{cmp.Left, cmp.Right}, {cmp.Right, cmp.Left}} {
+				colName, ok := sides[0].(*sqlparser.ColName)
+				if !ok || !partitionedBy(sqlparser.Exprs{colName}, col, alias) {
+					continue
+				}
+				switch sides[1].(type) {
+				case *sqlparser.Literal, *sqlparser.Argument:
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// nestedWindowFunctionsCanBePushed returns false when a derived table, UNION or subquery
+// of the statement uses window functions that the shards cannot compute on their own. The
+// top level SELECT is left out, since the vtgate can evaluate its window functions.
+func nestedWindowFunctionsCanBePushed(stmt sqlparser.SelectStatement, vschema plancontext.VSchema) bool {
+	top, _ := stmt.(*sqlparser.Select)
+	pushable := true
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		sel, ok := node.(*sqlparser.Select)
+		if !ok || sel == top || !selectUsesWindowFunctions(sel) {
+			return true, nil
+		}
+		if !windowCanBePushed(sel, vschema) {
+			pushable = false
+			return false, io.EOF
+		}
+		return true, nil
+	}, stmt)
+	return pushable
+}
+
+// isSingleShardPlan returns true if the plan sends the whole query to a single shard
+func isSingleShardPlan(prim engine.Primitive) bool {
+	if limit, ok := prim.(*engine.Limit); ok {
+		prim = limit.Input
+	}
+	route, ok := prim.(*engine.Route)
+	return ok && route.Opcode.IsSingleShard()
+}
+
+func partitionedBy(partition sqlparser.Exprs, col sqlparser.IdentifierCI, alias sqlparser.IdentifierCS) bool {
+	for _, expr := range partition {
+		colName, ok := expr.(*sqlparser.ColName)
+		if !ok || !colName.Name.Equal(col) {
+			continue
+		}
+		if colName.Qualifier.IsEmpty() || (colName.Qualifier.Qualifier.IsEmpty() && colName.Qualifier.Name.String() == alias.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// gen4WindowPlanner plans a SELECT using window functions that cannot be pushed down to the shards.
+// The rows needed to compute the window functions are fetched by an inner query sorted by the window
+// partition and order, and the window functions are evaluated at the vtgate level.
+func gen4WindowPlanner(
+	plannerVersion querypb.ExecuteOptions_PlannerVersion,
+	sel *sqlparser.Select,
+	reservedVars *sqlparser.ReservedVars,
+	vschema plancontext.VSchema,
+) (*planResult, error) {
+	if len(sel.GroupBy) > 0 || sel.Having != nil || sel.Distinct || sel.Into != nil || sel.Lock != sqlparser.NoLock ||
+		sel.SQLCalcFoundRows || sqlparser.ContainsAggregation(sel.SelectExprs) {
+		return nil, vterrors.VT12001("aggregation, DISTINCT, INTO or locking with window functions that span multiple shards")
+	}
+	if len(sel.Windows) > 0 {
+		return nil, vterrors.VT12001("named windows with window functions that span multiple shards")
+	}
+
+	wp := &windowPlanner{
+		inner: &sqlparser.Select{
+			Comments: sel.Comments,
+			With:     sel.With,
+			From:     sel.From,
+			Where:    sel.Where,
+		},
+		window: &engine.Window{},
+	}
+	collation := vschema.ConnCollation()
+	wp.cfg = &evalengine.Config{
+		ResolveColumn: func(col *sqlparser.ColName) (int, error) {
+			return wp.addInner(col), nil
+		},
+		Collation: collation,
+	}
+
+	// every SELECT expression is either a column of the inner query, or a window function
+	type output struct {
+		innerOffset int
+		funcOffset  int
+	}
+	var outputs []output
+	for _, selExpr := range sel.SelectExprs {
+		ae, ok := selExpr.(*sqlparser.AliasedExpr)
+		if !ok {
+			return nil, vterrors.VT12001(sqlparser.String(selExpr) + " with window functions that span multiple shards")
+		}
+		if !sqlparser.ContainsWindowFunction(ae.Expr) {
+			outputs = append(outputs, output{innerOffset: wp.addInnerExpr(ae), funcOffset: -1})
+			continue
+		}
+		if err := wp.addFunc(ae); err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, output{innerOffset: -1, funcOffset: len(wp.window.Funcs) - 1})
+	}
+
+	// the ORDER BY of the query can use the SELECT expressions, or columns that we fetch in the inner query
+	type ordering struct {
+		output      int
+		innerOffset int
+		desc        bool
+	}
+	var orderings []ordering
+	for _, order := range sel.OrderBy {
+		idx, err := windowOrderByOutput(order.Expr, sel.SelectExprs)
+		if err != nil {
+			return nil, err
+		}
+		o := ordering{output: idx, innerOffset: -1, desc: order.Direction == sqlparser.DescOrder}
+		if idx < 0 {
+			if sqlparser.ContainsWindowFunction(order.Expr) {
+				return nil, vterrors.VT12001("ORDER BY window function that is not in the SELECT list")
+			}
+			o.innerOffset = wp.addInner(order.Expr)
+		}
+		orderings = append(orderings, o)
+	}
+
+	// the inner query must produce the partitions one after the other, each of them sorted by the window order
+	if wp.spec != nil {
+		for _, expr := range wp.spec.PartitionClause {
+			wp.window.PartitionBy = append(wp.window.PartitionBy, engine.OrderByParams{
+				Col:             wp.addInner(expr),
+				WeightStringCol: -1,
+				CollationID:     collation,
+			})
+			wp.inner.OrderBy = append(wp.inner.OrderBy, &sqlparser.Order{Expr: sqlparser.CloneExpr(expr), Direction: sqlparser.AscOrder})
+		}
+		for _, order := range wp.spec.OrderClause {
+			wp.window.OrderBy = append(wp.window.OrderBy, engine.OrderByParams{
+				Col:             wp.addInner(order.Expr),
+				WeightStringCol: -1,
+				Desc:            order.Direction == sqlparser.DescOrder,
+				CollationID:     collation,
+			})
+			wp.inner.OrderBy = append(wp.inner.OrderBy, sqlparser.CloneRefOfOrder(order))
+		}
+	}
+
+	innerPlan, err := gen4SelectStmtPlanner(sqlparser.String(wp.inner), plannerVersion, wp.inner, reservedVars, vschema)
+	if err != nil {
+		return nil, err
+	}
+	wp.window.Input = innerPlan.primitive
+	var prim engine.Primitive = wp.window
+
+	innerCount := len(wp.inner.SelectExprs)
+	offsetOf := func(o output) int {
+		if o.innerOffset >= 0 {
+			return o.innerOffset
+		}
+		return innerCount + o.funcOffset
+	}
+
+	if len(orderings) > 0 {
+		ms := &engine.MemorySort{Input: prim}
+		for _, o := range orderings {
+			col := o.innerOffset
+			if o.output >= 0 {
+				col = offsetOf(outputs[o.output])
+			}
+			ms.OrderBy = append(ms.OrderBy, engine.OrderByParams{
+				Col:             col,
+				WeightStringCol: -1,
+				Desc:            o.desc,
+				CollationID:     collation,
+			})
+		}
+		prim = ms
+	}
+
+	proj := &engine.SimpleProjection{Input: prim}
+	identity := len(outputs) == innerCount+len(wp.window.Funcs)
+	for i, o := range outputs {
+		offset := offsetOf(o)
+		identity = identity && offset == i
+		proj.Cols = append(proj.Cols, offset)
+	}
+	if !identity {
+		prim = proj
+	}
+
+	if sel.Limit != nil {
+		lp, err := createLimit(&primitiveWrapper{prim: prim}, sel.Limit)
+		if err != nil {
+			return nil, err
+		}
+		prim = lp.Primitive()
+	}
+	return newPlanResult(prim, innerPlan.tables...), nil
+}
+
+// windowPlanner holds the state needed to plan window functions at the vtgate level
+type windowPlanner struct {
+	inner  *sqlparser.Select
+	window *engine.Window
+	// spec is the window used by all the window functions of the query
+	spec *sqlparser.WindowSpecification
+	cfg  *evalengine.Config
+}
+
+// addInnerExpr adds an expression to the SELECT list of the inner query, unless it is already there
+func (wp *windowPlanner) addInnerExpr(ae *sqlparser.AliasedExpr) int {
+	for i, selExpr := range wp.inner.SelectExprs {
+		if existing, ok := selExpr.(*sqlparser.AliasedExpr); ok && sqlparser.Equals.Expr(existing.Expr, ae.Expr) {
+			return i
+		}
+	}
+	wp.inner.SelectExprs = append(wp.inner.SelectExprs, sqlparser.CloneRefOfAliasedExpr(ae))
+	return len(wp.inner.SelectExprs) - 1
+}
+
+func (wp *windowPlanner) addInner(expr sqlparser.Expr) int {
+	return wp.addInnerExpr(&sqlparser.AliasedExpr{Expr: expr})
+}
+
+// addFunc adds the window function of a SELECT expression to the Window primitive
+func (wp *windowPlanner) addFunc(ae *sqlparser.AliasedExpr) error {
+	over := sqlparser.GetOverClause(ae.Expr)
+	if over == nil {
+		return vterrors.VT12001("window function inside of an expression, when it spans multiple shards: " + sqlparser.String(ae.Expr))
+	}
+	if err := wp.setSpec(over); err != nil {
+		return err
+	}
+
+	wf := &engine.WindowFunc{Col: -1, Alias: ae.ColumnName()}
+	switch expr := ae.Expr.(type) {
+	case *sqlparser.ArgumentLessWindowExpr:
+		switch expr.Type {
+		case sqlparser.RowNumberExprType:
+			wf.Opcode = engine.WindowRowNumber
+		case sqlparser.RankExprType:
+			wf.Opcode = engine.WindowRank
+		case sqlparser.DenseRankExprType:
+			wf.Opcode = engine.WindowDenseRank
+		default:
+			return wp.unsupportedFunc(ae.Expr)
+		}
+	case *sqlparser.LagLeadExpr:
+		if expr.NullTreatmentClause != nil && expr.NullTreatmentClause.Type == sqlparser.IgnoreNullsType {
+			return wp.unsupportedFunc(ae.Expr)
+		}
+		wf.Opcode = engine.WindowLag
+		if expr.Type == sqlparser.LeadExprType {
+			wf.Opcode = engine.WindowLead
+		}
+		wf.Col = wp.addInner(expr.Expr)
+		wf.Offset = 1
+		if expr.N != nil {
+			lit, ok := expr.N.(*sqlparser.Literal)
+			if !ok || lit.Type != sqlparser.IntVal {
+				return vterrors.VT12001("LAG / LEAD with an offset that is not an integer literal, when it spans multiple shards")
+			}
+			n, err := strconv.Atoi(lit.Val)
+			if err != nil {
+				return err
+			}
+			wf.Offset = n
+		}
+		if expr.Default != nil {
+			def, err := evalengine.Translate(expr.Default, wp.cfg)
+			if err != nil {
+				return err
+			}
+			wf.Default = def
+		}
+	case *sqlparser.FirstOrLastValueExpr:
+		if expr.Type != sqlparser.FirstValueExprType ||
+			(expr.NullTreatmentClause != nil && expr.NullTreatmentClause.Type == sqlparser.IgnoreNullsType) {
+			return wp.unsupportedFunc(ae.Expr)
+		}
+		wf.Opcode = engine.WindowFirstValue
+		wf.Col = wp.addInner(expr.Expr)
+	case *sqlparser.Sum:
+		if expr.Distinct {
+			return wp.unsupportedFunc(ae.Expr)
+		}
+		wf.Opcode = engine.WindowSum
+		wf.Col = wp.addInner(expr.Arg)
+	case *sqlparser.Avg:
+		if expr.Distinct {
+			return wp.unsupportedFunc(ae.Expr)
+		}
+		wf.Opcode = engine.WindowAvg
+		wf.Col = wp.addInner(expr.Arg)
+	default:
+		return wp.unsupportedFunc(ae.Expr)
+	}
+
+	if frame := over.WindowSpec.FrameClause; frame != nil {
+		wf.Rows = frame.Unit == sqlparser.FrameRowsType
+	}
+	wp.window.Funcs = append(wp.window.Funcs, wf)
+	return nil
+}
+
+func (wp *windowPlanner) unsupportedFunc(expr sqlparser.Expr) error {
+	return vterrors.VT12001("window function that spans multiple shards: " + sqlparser.String(expr))
+}
+
+// setSpec checks that the window of a function can be evaluated at the vtgate level,
+// and that it is the same as the window of the other functions of the query
+func (wp *windowPlanner) setSpec(over *sqlparser.OverClause) error {
+	spec := over.WindowSpec
+	if !over.WindowName.IsEmpty() || spec == nil || !spec.Name.IsEmpty() {
+		return vterrors.VT12001("named windows with window functions that span multiple shards")
+	}
+	if frame := spec.FrameClause; frame != nil {
+		if frame.Start == nil || frame.Start.Type != sqlparser.UnboundedPrecedingType ||
+			(frame.End != nil && frame.End.Type != sqlparser.CurrentRowType) {
+			return vterrors.VT12001("window frame other than UNBOUNDED PRECEDING to CURRENT ROW, when it spans multiple shards")
+		}
+	}
+	if wp.spec == nil {
+		wp.spec = spec
+		return nil
+	}
+	// the frame only matters for the running aggregations, and is handled by every function on its own
+	current, other := *wp.spec, *spec
+	current.FrameClause, other.FrameClause = nil, nil
+	if !sqlparser.Equals.RefOfWindowSpecification(&current, &other) {
+		return vterrors.VT12001("window functions with different windows, when they span multiple shards")
+	}
+	return nil
+}
+
+// windowOrderByOutput returns the index of the SELECT expression an ORDER BY expression refers to, or -1
+func windowOrderByOutput(expr sqlparser.Expr, selectExprs sqlparser.SelectExprs) (int, error) {
+	if lit, ok := expr.(*sqlparser.Literal); ok && lit.Type == sqlparser.IntVal {
+		num, err := strconv.Atoi(lit.Val)
+		if err != nil || num < 1 || num > len(selectExprs) {
+			return 0, vterrors.VT03014(num, "order clause")
+		}
+		return num - 1, nil
+	}
+	if col, ok := expr.(*sqlparser.ColName); ok && col.Qualifier.IsEmpty() {
+		for i, selExpr := range selectExprs {
+			if ae, ok := selExpr.(*sqlparser.AliasedExpr); ok && !ae.As.IsEmpty() && col.Name.Equal(ae.As) {
+				return i, nil
+			}
+		}
+	}
+	for i, selExpr := range selectExprs {
+		if ae, ok := selExpr.(*sqlparser.AliasedExpr); ok && sqlparser.Equals.Expr(ae.Expr, expr) {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
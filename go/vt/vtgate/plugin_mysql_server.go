@@ -41,6 +41,7 @@ import (
 	"vitess.io/vitess/go/vt/callerid"
 	"vitess.io/vitess/go/vt/callinfo"
 	"vitess.io/vitess/go/vt/log"
+	binlogdatapb "vitess.io/vitess/go/vt/proto/binlogdata"
 	querypb "vitess.io/vitess/go/vt/proto/query"
 	vtgatepb "vitess.io/vitess/go/vt/proto/vtgate"
 	"vitess.io/vitess/go/vt/servenv"
@@ -71,6 +72,12 @@ var (
 	mysqlQueryTimeout             time.Duration
 	mysqlSlowConnectWarnThreshold time.Duration
 	mysqlConnBufferPooling        bool
+	mysqlServerCompression        string
+
+	mysqlBinlogDumpUsers          string
+	mysqlBinlogDumpRotateInterval = 5 * time.Second
+	mysqlBinlogDumpRetainedFiles  = 720
+	mysqlBinlogDumpStreamTTL      = 7 * 24 * time.Hour
 
 	mysqlDefaultWorkloadName = "OLTP"
 	mysqlDefaultWorkload     int32
@@ -97,6 +104,11 @@ func registerPluginFlags(fs *pflag.FlagSet) {
 	fs.DurationVar(&mysqlQueryTimeout, "mysql_server_query_timeout", mysqlQueryTimeout, "mysql query timeout")
 	fs.BoolVar(&mysqlConnBufferPooling, "mysql-server-pool-conn-read-buffers", mysqlConnBufferPooling, "If set, the server will pool incoming connection read buffers")
 	fs.DurationVar(&mysqlKeepAlivePeriod, "mysql-server-keepalive-period", mysqlKeepAlivePeriod, "TCP period between keep-alives")
+	fs.StringVar(&mysqlServerCompression, "mysql_server_compression", mysqlServerCompression, "Comma separated list of compression algorithms the MySQL server offers to clients. Options: zlib, zstd. Empty disables the compressed protocol.")
+	fs.StringVar(&mysqlBinlogDumpUsers, "mysql_server_binlog_dump_users", mysqlBinlogDumpUsers, "Comma separated list of users allowed to stream the changes of all the tables of a keyspace with the binlog replication protocol. Empty disables the binlog dump commands.")
+	fs.DurationVar(&mysqlBinlogDumpRotateInterval, "mysql_server_binlog_dump_rotate_interval", mysqlBinlogDumpRotateInterval, "Minimum time between two rotations of the binlog files served with the binlog replication protocol. A client resuming a stream gets the events of its current file again.")
+	fs.IntVar(&mysqlBinlogDumpRetainedFiles, "mysql_server_binlog_dump_retained_files", mysqlBinlogDumpRetainedFiles, "Number of binlog files of a stream served with the binlog replication protocol a client can resume from. The position of every file is stored in the global topo.")
+	fs.DurationVar(&mysqlBinlogDumpStreamTTL, "mysql_server_binlog_dump_stream_ttl", mysqlBinlogDumpStreamTTL, "Time after which the positions of a binlog dump stream no client was served are purged from the global topo. Zero keeps them forever.")
 	fs.StringVar(&mysqlDefaultWorkloadName, "mysql_default_workload", mysqlDefaultWorkloadName, "Default session workload (OLTP, OLAP, DBA)")
 }
 
@@ -143,6 +155,15 @@ func (vh *vtgateHandler) ComResetConnection(c *mysql.Conn) {
 	}
 }
 
+// ComChangeUser closes the session of the previous user, as ComResetConnection
+// does, and drops it so that the new user starts with a new one. The immediate
+// caller ID is built from c.User and c.UserData for every query, so table ACLs
+// apply to the new user from then on.
+func (vh *vtgateHandler) ComChangeUser(c *mysql.Conn) {
+	vh.ComResetConnection(c)
+	c.ClientData = nil
+}
+
 func (vh *vtgateHandler) ConnectionClosed(c *mysql.Conn) {
 	// Rollback if there is an ongoing transaction. Ignore error.
 	defer func() {
@@ -216,6 +237,7 @@ func (vh *vtgateHandler) ComQuery(c *mysql.Conn, query string, callback func(*sq
 	defer span.Finish()
 
 	ctx = callinfo.MysqlCallInfo(ctx, c)
+	ctx = withQueryAttributes(ctx, c.QueryAttributes())
 
 	// Fill in the ImmediateCallerID with the UserData returned by
 	// the AuthServer plugin for that user. If nothing was
@@ -322,6 +344,7 @@ func (vh *vtgateHandler) ComStmtExecute(c *mysql.Conn, prepare *mysql.PrepareDat
 	}
 
 	ctx = callinfo.MysqlCallInfo(ctx, c)
+	ctx = withQueryAttributes(ctx, c.QueryAttributes())
 
 	// Fill in the ImmediateCallerID with the UserData returned by
 	// the AuthServer plugin for that user. If nothing was
@@ -345,7 +368,8 @@ func (vh *vtgateHandler) ComStmtExecute(c *mysql.Conn, prepare *mysql.PrepareDat
 		}
 	}()
 
-	if session.Options.Workload == querypb.ExecuteOptions_OLAP {
+	// The rows of a cursor are fetched by the client as it goes, so they are streamed too.
+	if session.Options.Workload == querypb.ExecuteOptions_OLAP || prepare.HasCursor() {
 		_, err := vh.vtg.StreamExecute(ctx, vh, session, prepare.PrepareStmt, prepare.BindVars, callback)
 		if err != nil {
 			return sqlerror.NewSQLErrorFromError(err)
@@ -373,12 +397,78 @@ func (vh *vtgateHandler) ComRegisterReplica(c *mysql.Conn, replicaHost string, r
 
 // ComBinlogDump is part of the mysql.Handler interface.
 func (vh *vtgateHandler) ComBinlogDump(c *mysql.Conn, logFile string, binlogPos uint32) error {
-	return vterrors.VT12001("ComBinlogDump for the VTGate handler")
+	return vh.binlogDump(c, logFile)
 }
 
 // ComBinlogDumpGTID is part of the mysql.Handler interface.
+// The GTIDs of the shards of a keyspace cannot be told apart in a single GTID set,
+// so the position to resume from can only be given with a binlog file name.
 func (vh *vtgateHandler) ComBinlogDumpGTID(c *mysql.Conn, logFile string, logPos uint64, gtidSet replication.GTIDSet) error {
-	return vterrors.VT12001("ComBinlogDumpGTID for the VTGate handler")
+	if gtidSet != nil && gtidSet.String() != "" {
+		return vterrors.VT12001(fmt.Sprintf("resuming a binlog dump from the GTID set %s: use the binlog file name and position instead", gtidSet.String()))
+	}
+	return vh.binlogDump(c, logFile)
+}
+
+// binlogDump serves the changes of the keyspace selected by the connection as a stream of
+// row based binlog events, by translating the events of a VStream over all of its shards.
+func (vh *vtgateHandler) binlogDump(c *mysql.Conn, logFile string) error {
+	if !binlogDumpAllowed(c.User) {
+		return sqlerror.NewSQLError(sqlerror.ERSpecifiedAccessDenied, sqlerror.SSClientError, "Access denied; user %s is not allowed to stream binlog events, see --mysql_server_binlog_dump_users", c.User)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.UpdateCancelCtx(cancel)
+	defer cancel()
+
+	ctx = callinfo.MysqlCallInfo(ctx, c)
+	im := c.UserData.Get()
+	ef := callerid.NewEffectiveCallerID(
+		c.User,                  /* principal: who */
+		c.RemoteAddr().String(), /* component: running client process */
+		"VTGate MySQL Connector" /* subcomponent: part of the client */)
+	ctx = callerid.NewContext(ctx, ef, im)
+
+	session := vh.session(c)
+	keyspace, tabletType, _, err := vh.vtg.executor.ParseDestinationTarget(session.TargetString)
+	if err != nil {
+		return err
+	}
+	if keyspace == "" {
+		return vterrors.VT09005()
+	}
+
+	ts, err := vh.vtg.executor.serv.GetTopoServer()
+	if err != nil {
+		return err
+	}
+	bd := newBinlogDump(ctx, ts, keyspace, mysqlBinlogDumpRotateInterval, mysqlBinlogDumpRetainedFiles, mysqlBinlogDumpStreamTTL, func(ev mysql.BinlogEvent) error {
+		return c.WriteBinlogEvent(ev, false)
+	})
+	vgtid, err := bd.position(logFile)
+	if err != nil {
+		return err
+	}
+	if err := bd.start(); err != nil {
+		return err
+	}
+	filter := &binlogdatapb.Filter{
+		Rules: []*binlogdatapb.Rule{{
+			Match: "/.*",
+		}},
+	}
+	return vh.vtg.VStream(ctx, tabletType, vgtid, filter, &vtgatepb.VStreamFlags{}, bd.handle)
+}
+
+// binlogDumpAllowed returns true if the user is in --mysql_server_binlog_dump_users.
+// A binlog dump streams every table of the keyspace, so it is only allowed to the users listed there.
+func binlogDumpAllowed(user string) bool {
+	for _, allowed := range strings.Split(mysqlBinlogDumpUsers, ",") {
+		if allowed = strings.TrimSpace(allowed); allowed != "" && allowed == user {
+			return true
+		}
+	}
+	return false
 }
 
 // KillConnection closes an open connection by connection ID.
@@ -506,8 +596,12 @@ func initMySQLProtocol(vtgate *VTGate) *mysqlServer {
 		log.Exitf("-mysql_tcp_version must be one of [tcp, tcp4, tcp6]")
 	}
 
+	compressionAlgorithms, err := mysql.ParseCompressionAlgorithms(mysqlServerCompression)
+	if err != nil {
+		log.Exitf("-mysql_server_compression: %v", err)
+	}
+
 	// Create a Listener.
-	var err error
 	srv := &mysqlServer{}
 	srv.vtgateHandle = newVtgateHandler(vtgate)
 	if mysqlServerPort >= 0 {
@@ -535,6 +629,8 @@ func initMySQLProtocol(vtgate *VTGate) *mysqlServer {
 			_ = initTLSConfig(context.Background(), srv, mysqlSslCert, mysqlSslKey, mysqlSslCa, mysqlSslCrl, mysqlSslServerCA, mysqlServerRequireSecureTransport, tlsVersion)
 		}
 		srv.tcpListener.AllowClearTextWithoutTLS.Store(mysqlAllowClearTextWithoutTLS)
+		srv.tcpListener.CompressionAlgorithms = compressionAlgorithms
+		srv.tcpListener.MaxCursorMemoryRows = maxMemoryRows
 		// Check for the connection threshold
 		if mysqlSlowConnectWarnThreshold != 0 {
 			log.Infof("setting mysql slow connection threshold to %v", mysqlSlowConnectWarnThreshold)
@@ -554,6 +650,8 @@ func initMySQLProtocol(vtgate *VTGate) *mysqlServer {
 			log.Exitf("mysql.NewListener failed: %v", err)
 			return nil
 		}
+		srv.unixListener.CompressionAlgorithms = compressionAlgorithms
+		srv.unixListener.MaxCursorMemoryRows = maxMemoryRows
 		// Listen for unix socket
 		go srv.unixListener.Accept()
 	}
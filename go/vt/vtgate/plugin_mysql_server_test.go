@@ -304,6 +304,24 @@ func testInitTLSConfig(t *testing.T, serverCA bool) {
 	}
 }
 
+func TestComChangeUser(t *testing.T) {
+	executor, _, _, _, _ := createExecutorEnv(t)
+	vh := newVtgateHandler(&VTGate{executor: executor})
+
+	mysqlConn := mysql.GetTestConn()
+	session := vh.session(mysqlConn)
+	session.TargetString = "TestExecutor"
+	session.UserDefinedVariables = map[string]*querypb.BindVariable{"a": sqltypes.Int64BindVariable(1)}
+
+	// The new user gets a new session.
+	vh.ComChangeUser(mysqlConn)
+	newSession := vh.session(mysqlConn)
+	assert.NotSame(t, session, newSession)
+	assert.Empty(t, newSession.TargetString)
+	assert.Empty(t, newSession.UserDefinedVariables)
+	assert.NotEqual(t, session.SessionUUID, newSession.SessionUUID)
+}
+
 // TestKillMethods test the mysql plugin for kill method calls.
 func TestKillMethods(t *testing.T) {
 	executor, _, _, _, _ := createExecutorEnv(t)
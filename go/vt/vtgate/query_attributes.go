@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vterrors"
+
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+)
+
+// queryAttributeDirectivePrefix is the prefix of the query attributes
+// that are used as comment directives, e.g. vt_priority=10 is
+// equivalent to /*vt+ PRIORITY=10 */.
+const queryAttributeDirectivePrefix = "vt_"
+
+// queryAttributeDirectives are the comment directives that can be set
+// with a query attribute.
+var queryAttributeDirectives = map[string]bool{
+	sqlparser.DirectiveMultiShardAutocommit:    true,
+	sqlparser.DirectiveSkipQueryPlanCache:      true,
+	sqlparser.DirectiveQueryTimeout:            true,
+	sqlparser.DirectiveScatterErrorsAsWarnings: true,
+	sqlparser.DirectiveIgnoreMaxPayloadSize:    true,
+	sqlparser.DirectiveIgnoreMaxMemoryRows:     true,
+	sqlparser.DirectiveAllowScatter:            true,
+	sqlparser.DirectiveAllowHashJoin:           true,
+	sqlparser.DirectiveQueryPlanner:            true,
+	sqlparser.DirectiveConsolidator:            true,
+	sqlparser.DirectiveWorkloadName:            true,
+	sqlparser.DirectivePriority:                true,
+	sqlparser.DirectiveWorkloadClass:           true,
+	sqlparser.DirectiveResultCacheTTL:          true,
+	sqlparser.DirectiveQuotaTag:                true,
+}
+
+// queryAttributeValue is what a query attribute used as a directive can
+// hold, so that it can't end the comment it is written into.
+var queryAttributeValue = regexp.MustCompile(`^[A-Za-z0-9_.\-]*$`)
+
+type queryAttributesKey struct{}
+
+// withQueryAttributes returns a context carrying the query attributes
+// sent by a MySQL client.
+func withQueryAttributes(ctx context.Context, attributes map[string]string) context.Context {
+	if len(attributes) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryAttributesKey{}, attributes)
+}
+
+// queryAttributesFromContext returns the query attributes stored by
+// withQueryAttributes, or nil.
+func queryAttributesFromContext(ctx context.Context) map[string]string {
+	attributes, _ := ctx.Value(queryAttributesKey{}).(map[string]string)
+	return attributes
+}
+
+// queryAttributesDirective builds the comment directive equivalent to the
+// query attributes, or returns an empty string if none of them is one.
+func queryAttributesDirective(attributes map[string]string) (string, error) {
+	var directives []string
+	for name, value := range attributes {
+		if len(name) <= len(queryAttributeDirectivePrefix) || !strings.EqualFold(name[:len(queryAttributeDirectivePrefix)], queryAttributeDirectivePrefix) {
+			continue
+		}
+		directive := strings.ToUpper(name[len(queryAttributeDirectivePrefix):])
+		if !queryAttributeDirectives[directive] {
+			continue
+		}
+		if !queryAttributeValue.MatchString(value) {
+			return "", vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "invalid value for query attribute %s: %q", name, value)
+		}
+		if value != "" {
+			directive += "=" + value
+		}
+		directives = append(directives, directive)
+	}
+	if len(directives) == 0 {
+		return "", nil
+	}
+	sort.Strings(directives)
+	return "/*vt+ " + strings.Join(directives, " ") + " */", nil
+}
+
+// applyQueryAttributes adds the directives carried by the query attributes
+// to the statement. They are added before the comments of the query, so a
+// directive written in the query wins.
+func applyQueryAttributes(stmt sqlparser.Statement, attributes map[string]string) error {
+	if len(attributes) == 0 {
+		return nil
+	}
+	directive, err := queryAttributesDirective(attributes)
+	if err != nil || directive == "" {
+		return err
+	}
+	commented, ok := stmt.(sqlparser.Commented)
+	if !ok {
+		return nil
+	}
+	commented.SetComments(commented.GetParsedComments().Prepend(directive))
+	return nil
+}
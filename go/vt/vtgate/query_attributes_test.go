@@ -0,0 +1,111 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	vtgatepb "vitess.io/vitess/go/vt/proto/vtgate"
+)
+
+func TestQueryAttributesDirective(t *testing.T) {
+	tests := []struct {
+		attributes map[string]string
+		directive  string
+		err        string
+	}{{
+		attributes: nil,
+	}, {
+		attributes: map[string]string{"trace": "abc", "vt_unknown": "1", "vt_": "1"},
+	}, {
+		attributes: map[string]string{"vt_priority": "10", "VT_Workload_Name": "reporting", "trace": "abc"},
+		directive:  "/*vt+ PRIORITY=10 WORKLOAD_NAME=reporting */",
+	}, {
+		attributes: map[string]string{"vt_allow_scatter": ""},
+		directive:  "/*vt+ ALLOW_SCATTER */",
+	}, {
+		attributes: map[string]string{"vt_workload_name": "a */ drop table t; /*"},
+		err:        "invalid value for query attribute vt_workload_name",
+	}}
+	for _, tt := range tests {
+		directive, err := queryAttributesDirective(tt.attributes)
+		if tt.err != "" {
+			assert.ErrorContains(t, err, tt.err)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tt.directive, directive)
+	}
+}
+
+func TestApplyQueryAttributes(t *testing.T) {
+	stmt, err := sqlparser.Parse("select /*vt+ PRIORITY=20 */ id from t")
+	require.NoError(t, err)
+
+	err = applyQueryAttributes(stmt, map[string]string{"vt_priority": "10", "vt_query_timeout_ms": "100"})
+	require.NoError(t, err)
+
+	// The directive written in the query wins.
+	directives := stmt.(sqlparser.Commented).GetParsedComments().Directives()
+	priority, _ := directives.GetString(sqlparser.DirectivePriority, "")
+	assert.Equal(t, "20", priority)
+	timeout, _ := directives.GetString(sqlparser.DirectiveQueryTimeout, "")
+	assert.Equal(t, "100", timeout)
+}
+
+func TestExecutorQueryAttributes(t *testing.T) {
+	executor, sbc1, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	attributes := map[string]string{"vt_priority": "33", "vt_workload_name": "reporting", "trace": "abc"}
+	session := NewSafeSession(&vtgatepb.Session{TargetString: "@primary", Options: &querypb.ExecuteOptions{}})
+	session.SetQueryAttributes(attributes)
+
+	_, err := executor.Execute(ctx, nil, "TestExecute", session, "select id from user where id = 1", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "33", session.Options.Priority)
+	assert.Equal(t, "reporting", session.Options.WorkloadName)
+	require.Len(t, sbc1.Queries, 1)
+	assert.Equal(t, "select /*vt+ PRIORITY=33 WORKLOAD_NAME=reporting */ id from `user` where id = 1", sbc1.Queries[0].Sql)
+
+	logStats := getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.Equal(t, attributes, logStats.QueryAttributes)
+
+	// The same query without the attributes gets a plan of its own.
+	sbc1.Queries = nil
+	session = NewSafeSession(&vtgatepb.Session{TargetString: "@primary", Options: &querypb.ExecuteOptions{}})
+	_, err = executor.Execute(ctx, nil, "TestExecute", session, "select id from user where id = 1", nil)
+	require.NoError(t, err)
+	require.Len(t, sbc1.Queries, 1)
+	assert.Equal(t, "select id from `user` where id = 1", sbc1.Queries[0].Sql)
+	assert.Empty(t, session.Options.Priority)
+
+	// The attributes are carried by the context of the MySQL handler.
+	ctx = withQueryAttributes(context.Background(), attributes)
+	assert.Equal(t, attributes, queryAttributesFromContext(ctx))
+	assert.Nil(t, queryAttributesFromContext(context.Background()))
+}
@@ -0,0 +1,505 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"vitess.io/vitess/go/ratelimiter"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/stats"
+	"vitess.io/vitess/go/vt/callerid"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vterrors"
+	"vitess.io/vitess/go/vt/vtgate/engine"
+	"vitess.io/vitess/go/vt/vtgate/vindexes"
+
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+)
+
+const (
+	// queryQuotaPollInterval is how often a query queued behind the QPS
+	// limit of its tenant checks whether it can run.
+	queryQuotaPollInterval = 10 * time.Millisecond
+	// queryQuotaSweepSize is the number of tenants a quota tracks before
+	// it forgets the idle ones.
+	queryQuotaSweepSize = 1000
+	// queryQuotaIdleTime is how long a tenant is idle before it is forgotten.
+	queryQuotaIdleTime = time.Minute
+)
+
+var (
+	queryQuotaRejections = stats.NewCountersWithMultiLabels("QueryQuotaRejections", "Queries rejected because their tenant exceeded a query quota", []string{"Keyspace", "Quota"})
+	queryQuotaQueued     = stats.NewCountersWithMultiLabels("QueryQuotaQueued", "Queries that waited for a query quota of their tenant", []string{"Keyspace", "Quota"})
+)
+
+// QueryQuotasTemplate is the HTML template to display QueryQuotaStatus.
+const QueryQuotasTemplate = `
+<style>
+  table {
+    border-collapse: collapse;
+  }
+  td, th {
+    border: 1px solid #999;
+    padding: 0.2rem;
+  }
+</style>
+<table>
+  <tr>
+    <th>Keyspace</th>
+    <th>Quota</th>
+    <th>Key</th>
+    <th>Max QPS</th>
+    <th>Max Concurrency</th>
+    <th>Queue Timeout</th>
+    <th>Tenants</th>
+    <th>In Flight</th>
+    <th>Queued</th>
+    <th>Rejected</th>
+  </tr>
+{{range .}}  <tr>
+    <td>{{.Keyspace}}</td>
+    <td>{{.Name}}</td>
+    <td>{{.Key}}</td>
+    <td>{{if .MaxQPS}}{{.MaxQPS}}{{else}}-{{end}}</td>
+    <td>{{if .MaxConcurrency}}{{.MaxConcurrency}}{{else}}-{{end}}</td>
+    <td>{{.QueueTimeout}}</td>
+    <td>{{.Tenants}}</td>
+    <td>{{.InFlight}}</td>
+    <td>{{.Queued}}</td>
+    <td>{{.Rejected}}</td>
+  </tr>{{end}}
+</table>
+`
+
+type (
+	// queryQuotas enforces the query quotas of the keyspaces in the VSchema.
+	// Each quota has a limiter, which tracks the queries of each tenant.
+	queryQuotas struct {
+		mu sync.Mutex
+		// limiters is keyed by keyspace.quota.
+		limiters map[string]*quotaLimiter
+	}
+
+	quotaLimiter struct {
+		keyspace string
+		quota    vindexes.QueryQuota
+
+		queued   atomic.Int64
+		rejected atomic.Int64
+
+		// mu protects tenants and lastSweep.
+		mu        sync.Mutex
+		tenants   map[string]*tenantQuota
+		lastSweep time.Time
+	}
+
+	tenantQuota struct {
+		// rate is nil if the quota has no max QPS.
+		rate *ratelimiter.RateLimiter
+		// slots is nil if the quota has no max concurrency.
+		slots chan struct{}
+		// inFlight counts the queries of the tenant that run or wait for the quota.
+		inFlight atomic.Int64
+		lastUsed atomic.Int64
+	}
+
+	// QueryQuotaStatus is the status of a query quota, for the status page.
+	QueryQuotaStatus struct {
+		Keyspace       string
+		Name           string
+		Key            string
+		MaxQPS         int64
+		MaxConcurrency int64
+		QueueTimeout   time.Duration
+		Tenants        int
+		InFlight       int64
+		Queued         int64
+		Rejected       int64
+	}
+)
+
+func newQueryQuotas() *queryQuotas {
+	return &queryQuotas{limiters: make(map[string]*quotaLimiter)}
+}
+
+// limiter returns the limiter of the quota, and replaces it if the
+// definition of the quota changed in the VSchema.
+func (qq *queryQuotas) limiter(keyspace string, quota *vindexes.QueryQuota) *quotaLimiter {
+	key := keyspace + "." + quota.Name
+	qq.mu.Lock()
+	defer qq.mu.Unlock()
+	if ql, ok := qq.limiters[key]; ok && sameQueryQuota(&ql.quota, quota) {
+		return ql
+	}
+	ql := &quotaLimiter{
+		keyspace: keyspace,
+		quota:    *quota,
+		tenants:  make(map[string]*tenantQuota),
+	}
+	qq.limiters[key] = ql
+	return ql
+}
+
+// acquire waits until the tenants of the query are within the quotas of
+// the keyspaces it uses, and returns the function that releases the query
+// from them once it ran. It fails if a tenant exceeds a quota and its
+// queue timeout expires.
+func (qq *queryQuotas) acquire(ctx context.Context, stmt sqlparser.Statement, plan *engine.Plan, vschema *vindexes.VSchema, bindVars map[string]*querypb.BindVariable) (func(), error) {
+	var releases []func()
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	for _, keyspace := range planKeyspaces(plan) {
+		ks, ok := vschema.Keyspaces[keyspace]
+		if !ok {
+			continue
+		}
+		for _, quota := range ks.QueryQuotas {
+			tenant := quotaTenant(ctx, quota, stmt, bindVars)
+			if tenant == "" {
+				continue
+			}
+			r, err := qq.limiter(keyspace, quota).acquire(ctx, tenant)
+			if err != nil {
+				release()
+				return nil, err
+			}
+			releases = append(releases, r)
+		}
+	}
+	return release, nil
+}
+
+// status returns the status of the quotas that are still in the VSchema.
+func (qq *queryQuotas) status(vschema *vindexes.VSchema) []*QueryQuotaStatus {
+	qq.mu.Lock()
+	defer qq.mu.Unlock()
+	var statuses []*QueryQuotaStatus
+	for key, ql := range qq.limiters {
+		if !quotaInVSchema(vschema, ql) {
+			delete(qq.limiters, key)
+			continue
+		}
+		statuses = append(statuses, ql.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Keyspace != statuses[j].Keyspace {
+			return statuses[i].Keyspace < statuses[j].Keyspace
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+func quotaInVSchema(vschema *vindexes.VSchema, ql *quotaLimiter) bool {
+	if vschema == nil {
+		return false
+	}
+	ks, ok := vschema.Keyspaces[ql.keyspace]
+	if !ok {
+		return false
+	}
+	for _, quota := range ks.QueryQuotas {
+		if sameQueryQuota(quota, &ql.quota) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameQueryQuota(a, b *vindexes.QueryQuota) bool {
+	return a.Name == b.Name && a.Key == b.Key && a.Column.Equal(b.Column) &&
+		a.MaxQPS == b.MaxQPS && a.MaxConcurrency == b.MaxConcurrency && a.QueueTimeout == b.QueueTimeout
+}
+
+// planKeyspaces returns the keyspaces of the tables used by the plan.
+func planKeyspaces(plan *engine.Plan) []string {
+	var keyspaces []string
+	for _, table := range plan.TablesUsed {
+		keyspace, _, ok := strings.Cut(table, ".")
+		if !ok {
+			continue
+		}
+		found := false
+		for _, ks := range keyspaces {
+			if ks == keyspace {
+				found = true
+				break
+			}
+		}
+		if !found {
+			keyspaces = append(keyspaces, keyspace)
+		}
+	}
+	return keyspaces
+}
+
+// quotaTenant returns the tenant of the query for the quota, or an empty
+// string if the query has none and isn't limited by the quota.
+func quotaTenant(ctx context.Context, quota *vindexes.QueryQuota, stmt sqlparser.Statement, bindVars map[string]*querypb.BindVariable) string {
+	switch quota.Key {
+	case vindexes.QueryQuotaKeyCallerID:
+		if principal := callerid.EffectiveCallerIDFromContext(ctx).GetPrincipal(); principal != "" {
+			return principal
+		}
+		return callerid.ImmediateCallerIDFromContext(ctx).GetUsername()
+	case vindexes.QueryQuotaKeyTag:
+		commented, ok := stmt.(sqlparser.Commented)
+		if !ok {
+			return ""
+		}
+		tag, _ := commented.GetParsedComments().Directives().GetString(sqlparser.DirectiveQuotaTag, "")
+		return tag
+	case vindexes.QueryQuotaKeyColumn:
+		return columnTenant(quota.Column, stmt, bindVars)
+	}
+	return ""
+}
+
+// columnTenant returns the value the query gives to the column: in an
+// equality of its WHERE clause, or in the rows it inserts if they all
+// have the same.
+func columnTenant(column sqlparser.IdentifierCI, stmt sqlparser.Statement, bindVars map[string]*querypb.BindVariable) string {
+	var where *sqlparser.Where
+	switch stmt := stmt.(type) {
+	case *sqlparser.Select:
+		where = stmt.Where
+	case *sqlparser.Update:
+		where = stmt.Where
+	case *sqlparser.Delete:
+		where = stmt.Where
+	case *sqlparser.Insert:
+		return insertTenant(column, stmt, bindVars)
+	}
+	if where == nil {
+		return ""
+	}
+	for _, expr := range sqlparser.SplitAndExpression(nil, where.Expr) {
+		cmp, ok := expr.(*sqlparser.ComparisonExpr)
+		if !ok || cmp.Operator != sqlparser.EqualOp {
+			continue
+		}
+		value := cmp.Right
+		col, ok := cmp.Left.(*sqlparser.ColName)
+		if !ok {
+			value = cmp.Left
+			col, ok = cmp.Right.(*sqlparser.ColName)
+		}
+		if !ok || !col.Name.Equal(column) {
+			continue
+		}
+		if tenant := exprTenant(value, bindVars); tenant != "" {
+			return tenant
+		}
+	}
+	return ""
+}
+
+func insertTenant(column sqlparser.IdentifierCI, ins *sqlparser.Insert, bindVars map[string]*querypb.BindVariable) string {
+	rows, ok := ins.Rows.(sqlparser.Values)
+	if !ok {
+		return ""
+	}
+	idx := ins.Columns.FindColumn(column)
+	if idx < 0 {
+		return ""
+	}
+	var tenant string
+	for _, row := range rows {
+		if idx >= len(row) {
+			return ""
+		}
+		value := exprTenant(row[idx], bindVars)
+		if value == "" || (tenant != "" && value != tenant) {
+			return ""
+		}
+		tenant = value
+	}
+	return tenant
+}
+
+func exprTenant(expr sqlparser.Expr, bindVars map[string]*querypb.BindVariable) string {
+	var value sqltypes.Value
+	var err error
+	switch expr := expr.(type) {
+	case *sqlparser.Literal:
+		value, err = sqlparser.LiteralToValue(expr)
+	case *sqlparser.Argument:
+		bv, ok := bindVars[expr.Name]
+		if !ok {
+			return ""
+		}
+		value, err = sqltypes.BindVariableToValue(bv)
+	default:
+		return ""
+	}
+	if err != nil || value.IsNull() {
+		return ""
+	}
+	return value.ToString()
+}
+
+// acquire waits until the tenant is within the quota, for up to the
+// queue timeout of the quota.
+func (ql *quotaLimiter) acquire(ctx context.Context, tenant string) (func(), error) {
+	tq := ql.tenant(tenant)
+
+	var deadline time.Time
+	wait := func() error {
+		if deadline.IsZero() {
+			if ql.quota.QueueTimeout <= 0 {
+				return ql.reject(tenant)
+			}
+			ql.queued.Add(1)
+			queryQuotaQueued.Add([]string{ql.keyspace, ql.quota.Name}, 1)
+			deadline = time.Now().Add(ql.quota.QueueTimeout)
+		}
+		return nil
+	}
+
+	if tq.rate != nil {
+		for !tq.rate.Allow() {
+			if err := wait(); err != nil {
+				tq.inFlight.Add(-1)
+				return nil, err
+			}
+			if err := ql.sleep(ctx, tenant, deadline, queryQuotaPollInterval); err != nil {
+				tq.inFlight.Add(-1)
+				return nil, err
+			}
+		}
+	}
+	if tq.slots != nil {
+		select {
+		case tq.slots <- struct{}{}:
+		default:
+			if err := wait(); err != nil {
+				tq.inFlight.Add(-1)
+				return nil, err
+			}
+			timer := time.NewTimer(time.Until(deadline))
+			defer timer.Stop()
+			select {
+			case tq.slots <- struct{}{}:
+			case <-timer.C:
+				tq.inFlight.Add(-1)
+				return nil, ql.reject(tenant)
+			case <-ctx.Done():
+				tq.inFlight.Add(-1)
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	return func() {
+		if tq.slots != nil {
+			<-tq.slots
+		}
+		tq.inFlight.Add(-1)
+		tq.lastUsed.Store(time.Now().UnixNano())
+	}, nil
+}
+
+// sleep waits for the interval, and fails if the deadline or the context
+// expire first.
+func (ql *quotaLimiter) sleep(ctx context.Context, tenant string, deadline time.Time, interval time.Duration) error {
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return ql.reject(tenant)
+	}
+	if interval > remaining {
+		interval = remaining
+	}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (ql *quotaLimiter) reject(tenant string) error {
+	ql.rejected.Add(1)
+	queryQuotaRejections.Add([]string{ql.keyspace, ql.quota.Name}, 1)
+	return vterrors.Errorf(vtrpcpb.Code_RESOURCE_EXHAUSTED, "query quota %s of keyspace %s exceeded by tenant %s", ql.quota.Name, ql.keyspace, tenant)
+}
+
+// tenant returns the state of the tenant in the quota with one more query in
+// flight, counted under the lock so that the state can't be forgotten in
+// between. It forgets the idle tenants once there are many of them.
+func (ql *quotaLimiter) tenant(tenant string) *tenantQuota {
+	ql.mu.Lock()
+	defer ql.mu.Unlock()
+	if tq, ok := ql.tenants[tenant]; ok {
+		tq.inFlight.Add(1)
+		return tq
+	}
+	now := time.Now()
+	if len(ql.tenants) >= queryQuotaSweepSize && now.Sub(ql.lastSweep) > queryQuotaIdleTime {
+		ql.lastSweep = now
+		for name, tq := range ql.tenants {
+			if tq.inFlight.Load() == 0 && now.Sub(time.Unix(0, tq.lastUsed.Load())) > queryQuotaIdleTime {
+				delete(ql.tenants, name)
+			}
+		}
+	}
+	tq := &tenantQuota{}
+	if ql.quota.MaxQPS > 0 {
+		tq.rate = ratelimiter.NewRateLimiter(int(ql.quota.MaxQPS), time.Second)
+	}
+	if ql.quota.MaxConcurrency > 0 {
+		tq.slots = make(chan struct{}, ql.quota.MaxConcurrency)
+	}
+	tq.lastUsed.Store(now.UnixNano())
+	tq.inFlight.Add(1)
+	ql.tenants[tenant] = tq
+	return tq
+}
+
+func (ql *quotaLimiter) status() *QueryQuotaStatus {
+	ql.mu.Lock()
+	defer ql.mu.Unlock()
+	status := &QueryQuotaStatus{
+		Keyspace:       ql.keyspace,
+		Name:           ql.quota.Name,
+		Key:            ql.quota.Key,
+		MaxQPS:         ql.quota.MaxQPS,
+		MaxConcurrency: ql.quota.MaxConcurrency,
+		QueueTimeout:   ql.quota.QueueTimeout,
+		Tenants:        len(ql.tenants),
+		Queued:         ql.queued.Load(),
+		Rejected:       ql.rejected.Load(),
+	}
+	if ql.quota.Key == vindexes.QueryQuotaKeyColumn {
+		status.Key += " " + ql.quota.Column.String()
+	}
+	for _, tq := range ql.tenants {
+		status.InFlight += tq.inFlight.Load()
+	}
+	return status
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/callerid"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vterrors"
+	"vitess.io/vitess/go/vt/vtgate/engine"
+	"vitess.io/vitess/go/vt/vtgate/vindexes"
+
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	vschemapb "vitess.io/vitess/go/vt/proto/vschema"
+	vtgatepb "vitess.io/vitess/go/vt/proto/vtgate"
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+)
+
+func TestQueryQuotaTenant(t *testing.T) {
+	column := &vindexes.QueryQuota{Key: vindexes.QueryQuotaKeyColumn, Column: sqlparser.NewIdentifierCI("tenant_id")}
+	tag := &vindexes.QueryQuota{Key: vindexes.QueryQuotaKeyTag}
+	bindVars := map[string]*querypb.BindVariable{
+		"tenant": sqltypes.Int64BindVariable(7),
+	}
+
+	tests := []struct {
+		quota  *vindexes.QueryQuota
+		query  string
+		tenant string
+	}{{
+		quota:  column,
+		query:  "select * from t1 where a = 1 and tenant_id = 42",
+		tenant: "42",
+	}, {
+		quota:  column,
+		query:  "select * from t1 where 'acme' = t1.TENANT_ID",
+		tenant: "acme",
+	}, {
+		quota:  column,
+		query:  "update t1 set a = 1 where tenant_id = :tenant",
+		tenant: "7",
+	}, {
+		quota:  column,
+		query:  "delete from t1 where tenant_id = 3",
+		tenant: "3",
+	}, {
+		quota: column,
+		query: "select * from t1 where tenant_id = 1 or tenant_id = 2",
+	}, {
+		quota: column,
+		query: "select * from t1 where tenant_id in (1, 2)",
+	}, {
+		quota: column,
+		query: "select * from t1",
+	}, {
+		quota:  column,
+		query:  "insert into t1(id, tenant_id) values (1, 5), (2, 5)",
+		tenant: "5",
+	}, {
+		quota: column,
+		query: "insert into t1(id, tenant_id) values (1, 5), (2, 6)",
+	}, {
+		quota: column,
+		query: "insert into t1(id) values (1)",
+	}, {
+		quota:  tag,
+		query:  "select /*vt+ QUOTA_TAG=reports */ * from t1",
+		tenant: "reports",
+	}, {
+		quota: tag,
+		query: "select * from t1",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			stmt, err := sqlparser.Parse(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.tenant, quotaTenant(context.Background(), tt.quota, stmt, bindVars))
+		})
+	}
+
+	callerID := &vindexes.QueryQuota{Key: vindexes.QueryQuotaKeyCallerID}
+	stmt, err := sqlparser.Parse("select * from t1")
+	require.NoError(t, err)
+	ctx := callerid.NewContext(context.Background(), nil, callerid.NewImmediateCallerID("app"))
+	assert.Equal(t, "app", quotaTenant(ctx, callerID, stmt, nil))
+	ctx = callerid.NewContext(ctx, callerid.NewEffectiveCallerID("user1", "", ""), callerid.NewImmediateCallerID("app"))
+	assert.Equal(t, "user1", quotaTenant(ctx, callerID, stmt, nil))
+}
+
+func TestQueryQuotasAcquire(t *testing.T) {
+	vschema := vindexes.BuildVSchema(&vschemapb.SrvVSchema{
+		Keyspaces: map[string]*vschemapb.Keyspace{
+			"ks": {
+				QueryQuotas: []*vschemapb.QueryQuota{{
+					Name:           "concurrency",
+					Key:            "tag",
+					MaxConcurrency: 1,
+				}, {
+					Name:         "qps",
+					Key:          "column",
+					Column:       "tenant_id",
+					MaxQps:       1,
+					QueueTimeout: "10ms",
+				}},
+			},
+		},
+	})
+	require.NoError(t, vschema.Keyspaces["ks"].Error)
+
+	qq := newQueryQuotas()
+	acquire := func(query string) (func(), error) {
+		stmt, err := sqlparser.Parse(query)
+		require.NoError(t, err)
+		return qq.acquire(context.Background(), stmt, &engine.Plan{TablesUsed: []string{"ks.t1"}}, vschema, nil)
+	}
+
+	// a second concurrent query of the tenant is rejected right away
+	release, err := acquire("select /*vt+ QUOTA_TAG=a */ * from t1")
+	require.NoError(t, err)
+	_, err = acquire("select /*vt+ QUOTA_TAG=a */ * from t1")
+	require.EqualError(t, err, "query quota concurrency of keyspace ks exceeded by tenant a")
+	assert.Equal(t, vtrpcpb.Code_RESOURCE_EXHAUSTED, vterrors.Code(err))
+	// other tenants aren't limited
+	releaseB, err := acquire("select /*vt+ QUOTA_TAG=b */ * from t1")
+	require.NoError(t, err)
+	releaseB()
+	release()
+	release, err = acquire("select /*vt+ QUOTA_TAG=a */ * from t1")
+	require.NoError(t, err)
+	release()
+
+	// a query over the QPS of its tenant waits for the queue timeout before it is rejected
+	release, err = acquire("select * from t1 where tenant_id = 1")
+	require.NoError(t, err)
+	release()
+	start := time.Now()
+	_, err = acquire("select * from t1 where tenant_id = 1")
+	require.EqualError(t, err, "query quota qps of keyspace ks exceeded by tenant 1")
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+
+	// queries of other keyspaces aren't limited
+	stmt, err := sqlparser.Parse("select /*vt+ QUOTA_TAG=a */ * from t1")
+	require.NoError(t, err)
+	release, err = qq.acquire(context.Background(), stmt, &engine.Plan{TablesUsed: []string{"other.t1"}}, vschema, nil)
+	require.NoError(t, err)
+	release()
+
+	statuses := qq.status(vschema)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "concurrency", statuses[0].Name)
+	assert.EqualValues(t, 2, statuses[0].Tenants)
+	assert.EqualValues(t, 1, statuses[0].Rejected)
+	assert.EqualValues(t, 0, statuses[0].InFlight)
+	assert.Equal(t, "qps", statuses[1].Name)
+	assert.Equal(t, "column tenant_id", statuses[1].Key)
+	assert.EqualValues(t, 1, statuses[1].Queued)
+	assert.EqualValues(t, 1, statuses[1].Rejected)
+
+	// the limiters of the quotas that are no longer in the VSchema are dropped
+	assert.Empty(t, qq.status(vindexes.BuildVSchema(&vschemapb.SrvVSchema{})))
+}
+
+func TestQueryQuotaQueueConcurrency(t *testing.T) {
+	qq := newQueryQuotas()
+	ql := qq.limiter("ks", &vindexes.QueryQuota{
+		Name:           "q",
+		Key:            vindexes.QueryQuotaKeyTag,
+		MaxConcurrency: 1,
+		QueueTimeout:   time.Minute,
+	})
+
+	release, err := ql.acquire(context.Background(), "a")
+	require.NoError(t, err)
+	acquired := make(chan error)
+	go func() {
+		release, err := ql.acquire(context.Background(), "a")
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	release()
+	require.NoError(t, <-acquired)
+
+	// a cancelled query stops waiting
+	release, err = ql.acquire(context.Background(), "a")
+	require.NoError(t, err)
+	defer release()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ql.acquire(ctx, "a")
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestExecutorQueryQuotas(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	executor.normalize = true
+	executor.VSchema().Keyspaces[KsTestUnsharded].QueryQuotas = []*vindexes.QueryQuota{{
+		Name:   "tenants",
+		Key:    vindexes.QueryQuotaKeyColumn,
+		Column: sqlparser.NewIdentifierCI("tenant_id"),
+		MaxQPS: 1,
+	}}
+	session := &vtgatepb.Session{TargetString: "@primary"}
+
+	_, err := executorExec(ctx, executor, session, "select id from main1 where tenant_id = 5", nil)
+	require.NoError(t, err)
+	_, err = executorExec(ctx, executor, session, "select id from main1 where tenant_id = 6", nil)
+	require.NoError(t, err)
+	_, err = executorExec(ctx, executor, session, "select id from main1 where tenant_id = 5", nil)
+	require.EqualError(t, err, "query quota tenants of keyspace TestUnsharded exceeded by tenant 5")
+}
+
+func TestQueryQuotaSweep(t *testing.T) {
+	qq := newQueryQuotas()
+	ql := qq.limiter("ks", &vindexes.QueryQuota{
+		Name:           "q",
+		Key:            vindexes.QueryQuotaKeyTag,
+		MaxConcurrency: 1,
+	})
+	for i := 0; i < queryQuotaSweepSize; i++ {
+		release, err := ql.acquire(context.Background(), fmt.Sprintf("t%d", i))
+		require.NoError(t, err)
+		release()
+	}
+	idle := time.Now().Add(-2 * queryQuotaIdleTime).UnixNano()
+	for _, tq := range ql.tenants {
+		tq.lastUsed.Store(idle)
+	}
+	ql.lastSweep = time.Time{}
+
+	// the idle tenants are forgotten once a new tenant comes, but not the
+	// ones with queries in flight
+	release, err := ql.acquire(context.Background(), "t0")
+	require.NoError(t, err)
+	defer release()
+	releaseNew, err := ql.acquire(context.Background(), "new")
+	require.NoError(t, err)
+	defer releaseNew()
+	require.Len(t, ql.tenants, 2)
+	require.Contains(t, ql.tenants, "t0")
+	require.Contains(t, ql.tenants, "new")
+
+	// the tenant is still limited by the state it acquired
+	_, err = ql.acquire(context.Background(), "t0")
+	require.EqualError(t, err, "query quota q of keyspace ks exceeded by tenant t0")
+}
@@ -46,8 +46,9 @@ func NewResolver(resolver *srvtopo.Resolver, serv srvtopo.Server, cell string, s
 	}
 }
 
-// MessageStream streams messages.
-func (res *Resolver) MessageStream(ctx context.Context, keyspace string, shard string, keyRange *topodatapb.KeyRange, name string, callback func(*sqltypes.Result) error) error {
+// MessageStream streams messages, as a member of consumerGroup if the
+// table has consumer groups.
+func (res *Resolver) MessageStream(ctx context.Context, keyspace string, shard string, keyRange *topodatapb.KeyRange, name, consumerGroup string, callback func(*sqltypes.Result) error) error {
 	var destination key.Destination
 	if shard != "" {
 		// If we pass in a shard, resolve the keyspace/shard
@@ -63,7 +64,7 @@ func (res *Resolver) MessageStream(ctx context.Context, keyspace string, shard s
 	if err != nil {
 		return err
 	}
-	return res.scatterConn.MessageStream(ctx, rss, name, callback)
+	return res.scatterConn.MessageStream(ctx, rss, name, consumerGroup, callback)
 }
 
 // GetGatewayCacheStatus returns a displayable version of the Gateway cache.
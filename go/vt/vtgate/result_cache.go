@@ -0,0 +1,376 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"vitess.io/vitess/go/cache/theine"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/stats"
+	"vitess.io/vitess/go/vt/callerid"
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/servenv"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/engine"
+	"vitess.io/vitess/go/vt/vtgate/vindexes"
+	"vitess.io/vitess/go/vt/vthash"
+
+	binlogdatapb "vitess.io/vitess/go/vt/proto/binlogdata"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+	vtgatepb "vitess.io/vitess/go/vt/proto/vtgate"
+)
+
+var (
+	// resultCacheSize is the size of the result cache in bytes. The cache is disabled if it is 0.
+	resultCacheSize int64
+	// resultCacheMaxStaleness is the longest time a result is served from the cache,
+	// whatever the TTL asked for by the query or the VSchema.
+	resultCacheMaxStaleness = 1 * time.Minute
+	// resultCacheInvalidationKeyspaces are the keyspaces whose row events invalidate the cache.
+	resultCacheInvalidationKeyspaces []string
+	// resultCacheRetryDelay is the time to wait before restarting a broken invalidation stream.
+	resultCacheRetryDelay = 5 * time.Second
+
+	resultCacheHits          = stats.NewCounter("ResultCacheHits", "Result cache hits")
+	resultCacheMisses        = stats.NewCounter("ResultCacheMisses", "Result cache misses")
+	resultCacheInvalidations = stats.NewCountersWithSingleLabel("ResultCacheInvalidations", "Result cache invalidations, by source", "Source")
+)
+
+func init() {
+	servenv.OnParseFor("vtgate", func(fs *pflag.FlagSet) {
+		fs.Int64Var(&resultCacheSize, "result-cache-size", resultCacheSize, "Size in bytes of the cache of the results of read-only queries that ask for it with the RESULT_CACHE_TTL directive, or read tables with a result_cache_ttl in the VSchema. The cache is disabled if 0")
+		fs.DurationVar(&resultCacheMaxStaleness, "result-cache-max-staleness", resultCacheMaxStaleness, "The longest time a result is served from the result cache, whatever the TTL asked for by the query or the VSchema")
+		fs.StringSliceVar(&resultCacheInvalidationKeyspaces, "result-cache-invalidation-keyspaces", resultCacheInvalidationKeyspaces, "The keyspaces whose row events, streamed from their primaries, invalidate the result cache. Otherwise the results are only invalidated by their TTL and by schema changes")
+	})
+}
+
+// nonDeterministicFunctions are the functions that make a query return a
+// different result each time it runs.
+var nonDeterministicFunctions = map[string]bool{
+	"rand":           true,
+	"uuid":           true,
+	"uuid_short":     true,
+	"sysdate":        true,
+	"connection_id":  true,
+	"current_user":   true,
+	"user":           true,
+	"session_user":   true,
+	"system_user":    true,
+	"get_lock":       true,
+	"release_lock":   true,
+	"is_free_lock":   true,
+	"is_used_lock":   true,
+	"sleep":          true,
+	"benchmark":      true,
+	"unix_timestamp": true,
+	"random_bytes":   true,
+}
+
+type (
+	// resultCache caches the results of read-only queries. Its entries
+	// expire after their TTL, and when one of the tables they read changes:
+	// each table has a generation that is increased when it is invalidated,
+	// and an entry is only valid while the generations of its tables are
+	// the ones they had when the query started. All the entries are flushed
+	// when the VSchema changes, as it can change the results of the queries.
+	resultCache struct {
+		store        *theine.Store[theine.HashKey256, *cachedResult]
+		maxStaleness time.Duration
+		// epoch is increased to flush the cache, like the epoch of the plan cache.
+		epoch atomic.Uint32
+
+		// mu protects generations.
+		mu sync.Mutex
+		// generations is keyed by keyspace for the generation of a whole
+		// keyspace, and by keyspace.table for the generation of a table.
+		generations map[string]uint64
+	}
+
+	cachedResult struct {
+		result      *sqltypes.Result
+		tables      []string
+		generations []uint64
+		expires     time.Time
+	}
+)
+
+func newResultCache(size int64, maxStaleness time.Duration) *resultCache {
+	return &resultCache{
+		store:        theine.NewStore[theine.HashKey256, *cachedResult](size, false),
+		maxStaleness: maxStaleness,
+		generations:  make(map[string]uint64),
+	}
+}
+
+// CachedSize is part of the theine cacheval interface.
+func (cr *cachedResult) CachedSize(alloc bool) int64 {
+	size := cr.result.CachedSize(true)
+	for _, table := range cr.tables {
+		size += int64(len(table)) + 16
+	}
+	size += int64(8*cap(cr.generations)) + 64
+	return size
+}
+
+// ttl returns how long the result of the query can be cached, or 0 if it
+// can't. The RESULT_CACHE_TTL directive wins over the result_cache_ttl of
+// the tables in the VSchema, which only apply if all the tables the query
+// reads have one.
+func (rc *resultCache) ttl(stmt sqlparser.Statement, plan *engine.Plan, vschema *vindexes.VSchema) time.Duration {
+	if plan.Type != sqlparser.StmtSelect || len(plan.TablesUsed) == 0 || !resultCacheable(stmt) {
+		return 0
+	}
+
+	var ttl time.Duration
+	if commented, ok := stmt.(sqlparser.Commented); ok {
+		if value, ok := commented.GetParsedComments().Directives().GetString(sqlparser.DirectiveResultCacheTTL, ""); ok {
+			var err error
+			if ttl, err = time.ParseDuration(value); err != nil {
+				return 0
+			}
+			return min(ttl, rc.maxStaleness)
+		}
+	}
+
+	for _, name := range plan.TablesUsed {
+		keyspace, table, _ := strings.Cut(name, ".")
+		ks := vschema.Keyspaces[keyspace]
+		if ks == nil || ks.Tables[table] == nil || ks.Tables[table].ResultCacheTTL <= 0 {
+			return 0
+		}
+		if tableTTL := ks.Tables[table].ResultCacheTTL; ttl == 0 || tableTTL < ttl {
+			ttl = tableTTL
+		}
+	}
+	return min(ttl, rc.maxStaleness)
+}
+
+// resultCacheable returns false for the statements that lock rows or
+// that call functions returning a different result each time.
+func resultCacheable(stmt sqlparser.Statement) bool {
+	cacheable := true
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Select:
+			if node.Lock != sqlparser.NoLock || node.Into != nil {
+				cacheable = false
+			}
+		case *sqlparser.FuncExpr:
+			if nonDeterministicFunctions[node.Name.Lowered()] {
+				cacheable = false
+			}
+		case *sqlparser.CurTimeFuncExpr, *sqlparser.LockingFunc:
+			cacheable = false
+		}
+		return cacheable, nil
+	}, stmt)
+	return cacheable
+}
+
+// key returns the key of the result of the query. It depends on everything
+// that can change the result: the query and its bind variables, the target,
+// the collation and the system variables of the session, and the caller,
+// whose table ACLs apply.
+func (rc *resultCache) key(ctx context.Context, vcursor *vcursorImpl, plan *engine.Plan, bindVars map[string]*querypb.BindVariable) (theine.HashKey256, error) {
+	hasher := vthash.New256()
+	write := func(s string) {
+		_, _ = hasher.WriteString(strconv.Itoa(len(s)))
+		_, _ = hasher.WriteString(":")
+		_, _ = hasher.WriteString(s)
+	}
+
+	write(plan.Original)
+	write(vcursor.safeSession.TargetString)
+	write(vcursor.TabletType().String())
+	write(strconv.Itoa(int(vcursor.collation)))
+	write(callerid.GetPrincipal(callerid.EffectiveCallerIDFromContext(ctx)))
+	write(callerid.GetUsername(callerid.ImmediateCallerIDFromContext(ctx)))
+
+	var sysvars []string
+	vcursor.safeSession.GetSystemVariables(func(k, v string) {
+		sysvars = append(sysvars, k+"="+v)
+	})
+	sort.Strings(sysvars)
+	for _, sysvar := range sysvars {
+		write(sysvar)
+	}
+
+	names := make([]string, 0, len(bindVars))
+	for name := range bindVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := bindVars[name].MarshalVT()
+		if err != nil {
+			return theine.HashKey256{}, err
+		}
+		write(name)
+		write(string(value))
+	}
+
+	var key theine.HashKey256
+	hasher.Sum(key[:0])
+	return key, nil
+}
+
+// generationsOf returns the current generations of the tables.
+func (rc *resultCache) generationsOf(tables []string) []uint64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	generations := make([]uint64, 0, len(tables))
+	for _, table := range tables {
+		keyspace, _, _ := strings.Cut(table, ".")
+		generations = append(generations, rc.generations[keyspace]+rc.generations[table])
+	}
+	return generations
+}
+
+// get returns the cached result of the query, if it is still valid.
+func (rc *resultCache) get(key theine.HashKey256) (*sqltypes.Result, bool) {
+	cached, ok := rc.store.Get(key, rc.epoch.Load())
+	if !ok {
+		resultCacheMisses.Add(1)
+		return nil, false
+	}
+	if time.Now().After(cached.expires) || !equalGenerations(cached.generations, rc.generationsOf(cached.tables)) {
+		rc.store.Delete(key)
+		resultCacheMisses.Add(1)
+		return nil, false
+	}
+	resultCacheHits.Add(1)
+	return cached.result.Copy(), true
+}
+
+// set caches the result of a query, with the epoch of the cache and the
+// generations its tables had when it started.
+func (rc *resultCache) set(key theine.HashKey256, result *sqltypes.Result, tables []string, epoch uint32, generations []uint64, ttl time.Duration) {
+	rc.store.Set(key, &cachedResult{
+		result:      result.Copy(),
+		tables:      tables,
+		generations: generations,
+		expires:     time.Now().Add(ttl),
+	}, 0, epoch)
+}
+
+func equalGenerations(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// invalidateTables invalidates the results read from the tables of the keyspace.
+func (rc *resultCache) invalidateTables(source, keyspace string, tables []string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for _, table := range tables {
+		rc.generations[keyspace+"."+table]++
+	}
+	resultCacheInvalidations.Add(source, int64(len(tables)))
+}
+
+// invalidateKeyspace invalidates all the results read from the keyspace.
+func (rc *resultCache) invalidateKeyspace(source, keyspace string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.generations[keyspace]++
+	resultCacheInvalidations.Add(source, 1)
+}
+
+// flush invalidates all the cached results.
+func (rc *resultCache) flush(source string) {
+	rc.epoch.Add(1)
+	resultCacheInvalidations.Add(source, 1)
+}
+
+// watchRowEvents invalidates the results read from the tables of the
+// keyspace when their rows change, until the context is done. The row
+// events are streamed from the primaries. Since some events may be missed
+// while the stream is restarted, the whole keyspace is then invalidated.
+func (rc *resultCache) watchRowEvents(ctx context.Context, vsm *vstreamManager, keyspace string) {
+	vgtid := &binlogdatapb.VGtid{ShardGtids: []*binlogdatapb.ShardGtid{{Keyspace: keyspace, Gtid: "current"}}}
+	filter := &binlogdatapb.Filter{Rules: []*binlogdatapb.Rule{{Match: "/.*"}}}
+	for {
+		err := vsm.VStream(ctx, topodatapb.TabletType_PRIMARY, vgtid.CloneVT(), filter, &vtgatepb.VStreamFlags{}, func(events []*binlogdatapb.VEvent) error {
+			rc.invalidateEvents(keyspace, events)
+			return nil
+		})
+		rc.invalidateKeyspace("VStream", keyspace)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(resultCacheRetryDelay):
+		}
+		log.Warningf("result cache invalidation stream of keyspace %s stopped, restarting it: %v", keyspace, err)
+	}
+}
+
+// invalidateEvents invalidates the results read from the tables changed by the events.
+func (rc *resultCache) invalidateEvents(keyspace string, events []*binlogdatapb.VEvent) {
+	var tables []string
+	for _, event := range events {
+		switch event.Type {
+		case binlogdatapb.VEventType_ROW:
+			tables = append(tables, event.RowEvent.TableName)
+		case binlogdatapb.VEventType_DDL:
+			rc.invalidateKeyspace("VStream", keyspace)
+		}
+	}
+	if len(tables) > 0 {
+		rc.invalidateTables("VStream", keyspace, tables)
+	}
+}
+
+// executeWithResultCache executes the plan, or returns its cached result
+// if the query asked for it and it is still valid.
+func (e *Executor) executeWithResultCache(ctx context.Context, safeSession *SafeSession, plan *engine.Plan, vcursor *vcursorImpl, bindVars map[string]*querypb.BindVariable, execute func() (*sqltypes.Result, error)) (*sqltypes.Result, error) {
+	if e.resultCache == nil || vcursor.resultCacheTTL <= 0 || safeSession.InTransaction() || safeSession.InReservedConn() {
+		return execute()
+	}
+	key, err := e.resultCache.key(ctx, vcursor, plan, bindVars)
+	if err != nil {
+		return execute()
+	}
+	if result, ok := e.resultCache.get(key); ok {
+		return result, nil
+	}
+
+	epoch := e.resultCache.epoch.Load()
+	generations := e.resultCache.generationsOf(plan.TablesUsed)
+	result, err := execute()
+	if err == nil {
+		e.resultCache.set(key, result, plan.TablesUsed, epoch, generations, vcursor.resultCacheTTL)
+	}
+	return result, err
+}
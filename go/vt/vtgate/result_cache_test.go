@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/engine"
+	"vitess.io/vitess/go/vt/vtgate/vindexes"
+
+	binlogdatapb "vitess.io/vitess/go/vt/proto/binlogdata"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	vschemapb "vitess.io/vitess/go/vt/proto/vschema"
+	vtgatepb "vitess.io/vitess/go/vt/proto/vtgate"
+)
+
+func TestResultCacheTTL(t *testing.T) {
+	vschema := vindexes.BuildVSchema(&vschemapb.SrvVSchema{
+		Keyspaces: map[string]*vschemapb.Keyspace{
+			"ks": {
+				Tables: map[string]*vschemapb.Table{
+					"t1": {ResultCacheTtl: "10s"},
+					"t2": {ResultCacheTtl: "5s"},
+					"t3": {},
+				},
+			},
+		},
+	})
+	require.NoError(t, vschema.Keyspaces["ks"].Error)
+
+	rc := newResultCache(1024*1024, 30*time.Second)
+	defer rc.store.Close()
+
+	tests := []struct {
+		query  string
+		tables []string
+		ttl    time.Duration
+	}{{
+		query:  "select * from t1",
+		tables: []string{"ks.t1"},
+		ttl:    10 * time.Second,
+	}, {
+		query:  "select * from t1 join t2",
+		tables: []string{"ks.t1", "ks.t2"},
+		ttl:    5 * time.Second,
+	}, {
+		query:  "select * from t1 join t3",
+		tables: []string{"ks.t1", "ks.t3"},
+	}, {
+		query:  "select /*vt+ RESULT_CACHE_TTL=20s */ * from t3",
+		tables: []string{"ks.t3"},
+		ttl:    20 * time.Second,
+	}, {
+		query:  "select /*vt+ RESULT_CACHE_TTL=0 */ * from t1",
+		tables: []string{"ks.t1"},
+	}, {
+		// the max staleness wins
+		query:  "select /*vt+ RESULT_CACHE_TTL=1h */ * from t1",
+		tables: []string{"ks.t1"},
+		ttl:    30 * time.Second,
+	}, {
+		query:  "select /*vt+ RESULT_CACHE_TTL=soon */ * from t1",
+		tables: []string{"ks.t1"},
+	}, {
+		query:  "select * from t1 for update",
+		tables: []string{"ks.t1"},
+	}, {
+		query:  "select now(), id from t1",
+		tables: []string{"ks.t1"},
+	}, {
+		query:  "select rand() from t1",
+		tables: []string{"ks.t1"},
+	}, {
+		query:  "select get_lock('a', 1) from t1",
+		tables: []string{"ks.t1"},
+	}, {
+		query:  "update t1 set a = 1",
+		tables: []string{"ks.t1"},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			stmt, err := sqlparser.Parse(tt.query)
+			require.NoError(t, err)
+			plan := &engine.Plan{Type: sqlparser.ASTToStatementType(stmt), TablesUsed: tt.tables}
+			assert.Equal(t, tt.ttl, rc.ttl(stmt, plan, vschema))
+		})
+	}
+}
+
+func TestResultCacheInvalidateEvents(t *testing.T) {
+	rc := newResultCache(1024*1024, time.Minute)
+	defer rc.store.Close()
+
+	generations := rc.generationsOf([]string{"ks.t1", "ks.t2", "other.t1"})
+	rc.invalidateEvents("ks", []*binlogdatapb.VEvent{{
+		Type:     binlogdatapb.VEventType_ROW,
+		RowEvent: &binlogdatapb.RowEvent{TableName: "t1"},
+	}, {
+		Type: binlogdatapb.VEventType_COMMIT,
+	}})
+	newGenerations := rc.generationsOf([]string{"ks.t1", "ks.t2", "other.t1"})
+	assert.NotEqual(t, generations[0], newGenerations[0])
+	assert.Equal(t, generations[1:], newGenerations[1:])
+
+	rc.invalidateEvents("ks", []*binlogdatapb.VEvent{{Type: binlogdatapb.VEventType_DDL}})
+	generations = newGenerations
+	newGenerations = rc.generationsOf([]string{"ks.t1", "ks.t2", "other.t1"})
+	assert.NotEqual(t, generations[:2], newGenerations[:2])
+	assert.Equal(t, generations[2], newGenerations[2])
+}
+
+func TestExecutorResultCache(t *testing.T) {
+	executor, sbc1, _, _, ctx := createExecutorEnv(t)
+	executor.resultCache = newResultCache(1024*1024, time.Minute)
+
+	query := "select /*vt+ RESULT_CACHE_TTL=10s */ id from user where id = 1"
+	session := NewSafeSession(&vtgatepb.Session{TargetString: "@primary", Autocommit: true})
+	for i := 0; i < 3; i++ {
+		_, err := executor.Execute(ctx, nil, "TestExecute", session, query, nil)
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, sbc1.ExecCount.Load())
+
+	// Other bind variables are another result.
+	_, err := executor.Execute(ctx, nil, "TestExecute", session, "select /*vt+ RESULT_CACHE_TTL=10s */ id from user where id = :id", map[string]*querypb.BindVariable{
+		"id": {Type: querypb.Type_INT64, Value: []byte("1")},
+	})
+	require.NoError(t, err)
+	_, err = executor.Execute(ctx, nil, "TestExecute", session, "select /*vt+ RESULT_CACHE_TTL=10s */ id from user where id = :id", map[string]*querypb.BindVariable{
+		"id": {Type: querypb.Type_INT64, Value: []byte("2")},
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, sbc1.ExecCount.Load())
+
+	// A change of the table invalidates the result.
+	executor.resultCache.invalidateTables("test", KsTestSharded, []string{"user"})
+	_, err = executor.Execute(ctx, nil, "TestExecute", session, query, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, sbc1.ExecCount.Load())
+
+	// So does a change of the VSchema.
+	executor.SaveVSchema(executor.VSchema(), executor.VSchemaStats())
+	_, err = executor.Execute(ctx, nil, "TestExecute", session, query, nil)
+	require.NoError(t, err)
+	_, err = executor.Execute(ctx, nil, "TestExecute", session, query, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, sbc1.ExecCount.Load())
+
+	// Transactions don't use the cache.
+	_, err = executor.Execute(ctx, nil, "TestExecute", session, "begin", nil)
+	require.NoError(t, err)
+	_, err = executor.Execute(ctx, nil, "TestExecute", session, query, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, sbc1.ExecCount.Load())
+	_, err = executor.Execute(ctx, nil, "TestExecute", session, "rollback", nil)
+	require.NoError(t, err)
+
+	// Queries without the directive aren't cached.
+	for i := 0; i < 2; i++ {
+		_, err = executor.Execute(ctx, nil, "TestExecute", session, "select id from user where id = 1", nil)
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 8, sbc1.ExecCount.Load())
+}
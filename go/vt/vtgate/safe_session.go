@@ -66,6 +66,10 @@ type (
 
 		logging *executeLogger
 
+		// queryAttributes are the query attributes the MySQL client sent
+		// with the query being executed.
+		queryAttributes map[string]string
+
 		*vtgatepb.Session
 	}
 
@@ -216,6 +220,20 @@ func (session *SafeSession) GetQueryTimeout() int64 {
 	return session.QueryTimeout
 }
 
+// SetQueryAttributes sets the query attributes of the query being executed.
+func (session *SafeSession) SetQueryAttributes(attributes map[string]string) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.queryAttributes = attributes
+}
+
+// GetQueryAttributes returns the query attributes of the query being executed.
+func (session *SafeSession) GetQueryAttributes() map[string]string {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.queryAttributes
+}
+
 // SavePoints returns the save points of the session. It's safe to use concurrently
 func (session *SafeSession) SavePoints() []string {
 	session.mu.Lock()
@@ -493,59 +493,114 @@ func (tt *timeTracker) Record(target *querypb.Target) time.Time {
 	return last
 }
 
+// messageStreamResult is a result of the message stream of a shard,
+// handed over to ScatterConn.MessageStream to be sent.
+type messageStreamResult struct {
+	qr   *sqltypes.Result
+	sent chan error
+}
+
 // MessageStream streams messages from the specified shards.
-// Note we guarantee the callback will not be called concurrently
-// by multiple go routines, through processOneStreamingResult.
-func (stc *ScatterConn) MessageStream(ctx context.Context, rss []*srvtopo.ResolvedShard, name string, callback func(*sqltypes.Result) error) error {
+// The shards hand their results over to a single loop calling
+// the callback, one at a time and in turn: a shard can't send
+// again before the shards that were already waiting to send, so
+// that the stream is balanced across shards instead of favoring
+// the fastest ones. If the table has consumer groups, the stream
+// is a member of consumerGroup.
+func (stc *ScatterConn) MessageStream(ctx context.Context, rss []*srvtopo.ResolvedShard, name, consumerGroup string, callback func(*sqltypes.Result) error) error {
 	// The cancelable context is used for handling errors
 	// from individual streams.
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	// mu is used to merge multiple callback calls into one.
-	var mu sync.Mutex
-	fieldSent := false
+	// results is unbuffered: its waiting senders are queued in order.
+	results := make(chan *messageStreamResult)
+	done := make(chan struct{})
+	var allErrors *concurrency.AllErrorRecorder
 	lastErrors := newTimeTracker()
-	allErrors := stc.multiGo("MessageStream", rss, func(rs *srvtopo.ResolvedShard, i int) error {
-		// This loop handles the case where a reparent happens, which can cause
-		// an individual stream to end. If we don't succeed on the retries for
-		// messageStreamGracePeriod, we abort and return an error.
-		for {
-			err := rs.Gateway.MessageStream(ctx, rs.Target, name, func(qr *sqltypes.Result) error {
-				lastErrors.Reset(rs.Target)
-				return stc.processOneStreamingResult(&mu, &fieldSent, qr, callback)
-			})
-			// nil and EOF are equivalent. UNAVAILABLE can be returned by vttablet if it's demoted
-			// from primary to replica. For any of these conditions, we have to retry.
-			if err != nil && err != io.EOF && vterrors.Code(err) != vtrpcpb.Code_UNAVAILABLE {
-				cancel()
-				return err
-			}
+	go func() {
+		defer close(done)
+		allErrors = stc.multiGo("MessageStream", rss, func(rs *srvtopo.ResolvedShard, i int) error {
+			// This loop handles the case where a reparent happens, which can cause
+			// an individual stream to end. If we don't succeed on the retries for
+			// messageStreamGracePeriod, we abort and return an error.
+			for {
+				err := rs.Gateway.MessageStream(ctx, rs.Target, name, consumerGroup, func(qr *sqltypes.Result) error {
+					lastErrors.Reset(rs.Target)
+					result := &messageStreamResult{qr: qr, sent: make(chan error, 1)}
+					select {
+					case results <- result:
+					case <-ctx.Done():
+						return io.EOF
+					}
+					return <-result.sent
+				})
+				// nil and EOF are equivalent. UNAVAILABLE can be returned by vttablet if it's demoted
+				// from primary to replica. For any of these conditions, we have to retry.
+				if err != nil && err != io.EOF && vterrors.Code(err) != vtrpcpb.Code_UNAVAILABLE {
+					cancel()
+					return err
+				}
 
-			// There was no error. We have to see if we need to retry.
-			// If context was canceled, likely due to client disconnect,
-			// return normally without retrying.
-			select {
-			case <-ctx.Done():
-				return nil
-			default:
-			}
-			firstErrorTimeStamp := lastErrors.Record(rs.Target)
-			if time.Since(firstErrorTimeStamp) >= messageStreamGracePeriod {
-				// Cancel all streams and return an error.
-				cancel()
-				return vterrors.Errorf(vtrpcpb.Code_DEADLINE_EXCEEDED, "message stream from %v has repeatedly failed for longer than %v", rs.Target, messageStreamGracePeriod)
+				// There was no error. We have to see if we need to retry.
+				// If context was canceled, likely due to client disconnect,
+				// return normally without retrying.
+				select {
+				case <-ctx.Done():
+					return nil
+				default:
+				}
+				firstErrorTimeStamp := lastErrors.Record(rs.Target)
+				if time.Since(firstErrorTimeStamp) >= messageStreamGracePeriod {
+					// Cancel all streams and return an error.
+					cancel()
+					return vterrors.Errorf(vtrpcpb.Code_DEADLINE_EXCEEDED, "message stream from %v has repeatedly failed for longer than %v", rs.Target, messageStreamGracePeriod)
+				}
+
+				// It's not been too long since our last good send. Wait and retry.
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(messageStreamGracePeriod / 5):
+				}
 			}
+		})
+	}()
 
-			// It's not been too long since our last good send. Wait and retry.
-			select {
-			case <-ctx.Done():
-				return nil
-			case <-time.After(messageStreamGracePeriod / 5):
+	// Only this loop calls processOneStreamingResult, so mu is never contended.
+	var mu sync.Mutex
+	fieldSent := false
+	for {
+		select {
+		case result := <-results:
+			if ctx.Err() != nil {
+				// The streams are ending: don't send any more messages.
+				result.sent <- io.EOF
+				continue
 			}
+			result.sent <- stc.processOneStreamingResult(&mu, &fieldSent, result.qr, callback)
+		case <-done:
+			return allErrors.AggrError(vterrors.Aggregate)
+		}
+	}
+}
+
+// MessageAck acks messages across multiple shards, for consumerGroup
+// if the table has consumer groups.
+func (stc *ScatterConn) MessageAck(ctx context.Context, rss []*srvtopo.ResolvedShard, values [][]*querypb.Value, name, consumerGroup string) (int64, error) {
+	var mu sync.Mutex
+	var totalCount int64
+	allErrors := stc.multiGo("MessageAck", rss, func(rs *srvtopo.ResolvedShard, i int) error {
+		count, err := rs.Gateway.MessageAck(ctx, rs.Target, name, consumerGroup, values[i])
+		if err != nil {
+			return err
 		}
+		mu.Lock()
+		defer mu.Unlock()
+		totalCount += count
+		return nil
 	})
-	return allErrors.AggrError(vterrors.Aggregate)
+	return totalCount, allErrors.AggrError(vterrors.Aggregate)
 }
 
 // Close closes the underlying Gateway.
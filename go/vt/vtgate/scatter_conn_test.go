@@ -17,7 +17,9 @@ limitations under the License.
 package vtgate
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"vitess.io/vitess/go/mysql/sqlerror"
 	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
@@ -410,6 +412,49 @@ func TestReservedConnFail(t *testing.T) {
 	assert.NotEqual(t, oldAlias, session.Session.ShardSessions[0].TabletAlias, "tablet alias should have changed as this is a different tablet")
 }
 
+func TestMessageStreamBalancesShards(t *testing.T) {
+	ctx := utils.LeakCheckContext(t)
+
+	keyspace := "TestMessageStreamBalancesShards"
+	createSandbox(keyspace)
+	hc := discovery.NewFakeHealthCheck(nil)
+	sc := newTestScatterConn(ctx, hc, newSandboxForCells(ctx, []string{"aa"}), "aa")
+	var rss []*srvtopo.ResolvedShard
+	for _, shard := range []string{"0", "1"} {
+		sbc := hc.AddTestTablet("aa", shard, 1, keyspace, shard, topodatapb.TabletType_PRIMARY, true, 1, nil)
+		fields := sqltypes.MakeTestFields("shard", "varchar")
+		results := []*sqltypes.Result{{Fields: fields}}
+		for i := 0; i < 20; i++ {
+			results = append(results, &sqltypes.Result{Rows: sqltypes.MakeTestResult(fields, shard).Rows})
+		}
+		sbc.SetResults(results)
+		rss = append(rss, &srvtopo.ResolvedShard{
+			Target:  &querypb.Target{Keyspace: keyspace, Shard: shard, TabletType: topodatapb.TabletType_PRIMARY},
+			Gateway: sbc,
+		})
+	}
+
+	// The shards stream messages faster than the client consumes them:
+	// the client must get messages from both shards in turn.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	received := map[string]int{}
+	total := 0
+	err := sc.MessageStream(ctx, rss, "msg", "", func(qr *sqltypes.Result) error {
+		for _, row := range qr.Rows {
+			received[row[0].ToString()]++
+			total++
+		}
+		if total == 20 {
+			cancel()
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"0": 10, "1": 10}, received)
+}
+
 func TestIsConnClosed(t *testing.T) {
 	var testCases = []struct {
 		name      string
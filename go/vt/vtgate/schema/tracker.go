@@ -46,6 +46,8 @@ type (
 		views  *viewMap
 		ctx    context.Context
 		signal func() // a function that we'll call whenever we have new schema data
+		// tablesChanged is called with the tables and views whose schema changed.
+		tablesChanged func(keyspace string, tables []string)
 
 		// map of keyspace currently tracked
 		tracked      map[keyspaceStr]*updateController
@@ -243,6 +245,7 @@ func (t *Tracker) Views(ks string) map[string]sqlparser.SelectStatement {
 }
 
 func (t *Tracker) updateSchema(th *discovery.TabletHealth) bool {
+	t.notifyTablesChanged(th)
 	success := true
 	if th.Stats.TableSchemaChanged != nil {
 		success = t.updatedTableSchema(th)
@@ -387,6 +390,29 @@ func (t *Tracker) RegisterSignalReceiver(f func()) {
 	t.signal = f
 }
 
+// RegisterTablesChangedReceiver allows a function to register to be called
+// with the tables and views whose schema changed, before the new schema is loaded.
+func (t *Tracker) RegisterTablesChangedReceiver(f func(keyspace string, tables []string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tablesChanged = f
+}
+
+func (t *Tracker) notifyTablesChanged(th *discovery.TabletHealth) {
+	t.mu.Lock()
+	tablesChanged := t.tablesChanged
+	t.mu.Unlock()
+	if tablesChanged == nil {
+		return
+	}
+	var tables []string
+	tables = append(tables, th.Stats.TableSchemaChanged...)
+	tables = append(tables, th.Stats.ViewSchemaChanged...)
+	if len(tables) > 0 {
+		tablesChanged(th.Target.Keyspace, tables)
+	}
+}
+
 // AddNewKeyspace adds keyspace to the tracker.
 func (t *Tracker) AddNewKeyspace(conn queryservice.QueryService, target *querypb.Target) error {
 	updateController := t.newUpdateController()
@@ -0,0 +1,199 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package semantics
+
+import (
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vterrors"
+)
+
+// cteRewriter replaces references to non-recursive common table expressions
+// with derived tables. After the rewrite, the rest of the analysis and the
+// planner only ever see derived tables, which they already know how to merge
+// into routes or evaluate at the vtgate level.
+type cteRewriter struct {
+	// scopes holds the CTEs visible at the current point of the walk, innermost last
+	scopes []map[string]*sqlparser.CommonTableExpr
+	// owners holds the nodes that opened each scope, so we know when to close it
+	owners []sqlparser.SQLNode
+	err    error
+}
+
+// rewriteCTEs expands all common table expressions in the statement into derived tables.
+func rewriteCTEs(statement sqlparser.Statement) error {
+	r := &cteRewriter{}
+	_ = sqlparser.Rewrite(statement, r.down, r.up)
+	return r.err
+}
+
+func (r *cteRewriter) down(cursor *sqlparser.Cursor) bool {
+	if r.err != nil {
+		return false
+	}
+	switch node := cursor.Node().(type) {
+	case *sqlparser.Select:
+		node.With = r.openScope(node, node.With)
+	case *sqlparser.Union:
+		node.With = r.openScope(node, node.With)
+	case *sqlparser.Update:
+		node.With = r.openScope(node, node.With)
+	case *sqlparser.Delete:
+		if err := r.checkDeleteTargets(node); err != nil {
+			r.err = err
+			return false
+		}
+		node.With = r.openScope(node, node.With)
+	case *sqlparser.AliasedTableExpr:
+		if r.expandTableExpr(node) {
+			// the CTE definition has already been expanded, and
+			// visiting it again could pick up its own name
+			return false
+		}
+	}
+	return r.err == nil
+}
+
+func (r *cteRewriter) up(cursor *sqlparser.Cursor) bool {
+	if len(r.owners) > 0 && r.owners[len(r.owners)-1] == cursor.Node() {
+		r.scopes = r.scopes[:len(r.scopes)-1]
+		r.owners = r.owners[:len(r.owners)-1]
+	}
+	return r.err == nil
+}
+
+// openScope makes the CTEs of the given WITH clause visible to the owning statement.
+// Every CTE is expanded using only the CTEs defined before it. The returned value
+// is what should be left in the WITH position of the owner - nil, since all
+// references have been replaced by derived tables.
+func (r *cteRewriter) openScope(owner sqlparser.SQLNode, with *sqlparser.With) *sqlparser.With {
+	if with == nil {
+		return nil
+	}
+	for _, cte := range with.CTEs {
+		if with.Recursive && cte.IsSelfReferencing() {
+			// recursive CTEs are planned separately when they are used at the top level of a query
+			r.err = vterrors.VT12001("recursive common table expression in this position")
+			return with
+		}
+	}
+
+	scope := map[string]*sqlparser.CommonTableExpr{}
+	r.scopes = append(r.scopes, scope)
+	r.owners = append(r.owners, owner)
+	for _, cte := range with.CTEs {
+		name := cte.ID.String()
+		if _, exists := scope[name]; exists {
+			r.err = vterrors.VT03013(name)
+			return with
+		}
+		_ = sqlparser.Rewrite(cte.Subquery, r.down, r.up)
+		if r.err != nil {
+			return with
+		}
+		scope[name] = cte
+	}
+	return nil
+}
+
+// expandTableExpr replaces a table name that refers to a CTE with the CTE definition
+func (r *cteRewriter) expandTableExpr(node *sqlparser.AliasedTableExpr) bool {
+	tbl, ok := node.Expr.(sqlparser.TableName)
+	if !ok {
+		return false
+	}
+	cte := r.findCTE(tbl)
+	if cte == nil {
+		return false
+	}
+
+	node.Expr = &sqlparser.DerivedTable{Select: cloneCTEBody(cte.Subquery.Select)}
+	if node.As.IsEmpty() {
+		node.As = cte.ID
+	}
+	if len(node.Columns) == 0 {
+		node.Columns = sqlparser.CloneColumns(cte.Columns)
+	}
+	return true
+}
+
+// cloneCTEBody creates a copy of the CTE definition that shares no nodes with it.
+// ColNames are not copied by the generated clone methods, but the semantic
+// analysis binds them by pointer, so every use of the CTE needs its own.
+func cloneCTEBody(sel sqlparser.SelectStatement) sqlparser.SelectStatement {
+	clone := sqlparser.CloneSelectStatement(sel)
+	_ = sqlparser.Rewrite(clone, func(cursor *sqlparser.Cursor) bool {
+		if col, ok := cursor.Node().(*sqlparser.ColName); ok {
+			newCol := *col
+			cursor.Replace(&newCol)
+		}
+		return true
+	}, nil)
+	return clone
+}
+
+// checkDeleteTargets makes sure we are not trying to delete from a CTE, which is not updatable
+func (r *cteRewriter) checkDeleteTargets(del *sqlparser.Delete) error {
+	if del.With == nil {
+		return nil
+	}
+	r.openScopeForLookup(del.With)
+	defer r.closeScopeForLookup()
+
+	targets := del.Targets
+	if len(targets) == 0 {
+		for _, te := range del.TableExprs {
+			if aliasedTbl, ok := te.(*sqlparser.AliasedTableExpr); ok {
+				if tbl, ok := aliasedTbl.Expr.(sqlparser.TableName); ok && aliasedTbl.As.IsEmpty() {
+					targets = append(targets, tbl)
+				}
+			}
+		}
+	}
+	for _, target := range targets {
+		if r.findCTE(target) != nil {
+			return vterrors.VT03004(target.Name.String())
+		}
+	}
+	return nil
+}
+
+// openScopeForLookup makes the CTE names of a WITH clause visible without expanding them
+func (r *cteRewriter) openScopeForLookup(with *sqlparser.With) {
+	scope := map[string]*sqlparser.CommonTableExpr{}
+	for _, cte := range with.CTEs {
+		scope[cte.ID.String()] = cte
+	}
+	r.scopes = append(r.scopes, scope)
+}
+
+func (r *cteRewriter) closeScopeForLookup() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *cteRewriter) findCTE(tbl sqlparser.TableName) *sqlparser.CommonTableExpr {
+	if !tbl.Qualifier.IsEmpty() {
+		// a qualified table name always refers to a real table
+		return nil
+	}
+	name := tbl.Name.String()
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if cte, found := r.scopes[i][name]; found {
+			return cte
+		}
+	}
+	return nil
+}
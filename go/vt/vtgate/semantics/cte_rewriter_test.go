@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package semantics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+func TestRewriteCTEs(t *testing.T) {
+	tcases := []struct {
+		sql    string
+		expSQL string
+		expErr string
+	}{{
+		sql:    "with x as (select id from t1) select id from x",
+		expSQL: "select id from (select id from t1) as x",
+	}, {
+		sql:    "with x as (select id from t1) select x.id from x as y",
+		expSQL: "select x.id from (select id from t1) as y",
+	}, {
+		sql:    "with x(a, b) as (select id, col from t1) select a from x",
+		expSQL: "select a from (select id, col from t1) as x(a, b)",
+	}, {
+		sql:    "with x as (select id from t1), y as (select id from x) select id from y",
+		expSQL: "select id from (select id from (select id from t1) as x) as y",
+	}, {
+		// a CTE is not visible inside its own definition unless it is recursive
+		sql:    "with t1 as (select id from t1) select id from t1",
+		expSQL: "select id from (select id from t1) as t1",
+	}, {
+		// qualified table names always refer to real tables
+		sql:    "with t1 as (select id from t2) select id from db.t1",
+		expSQL: "select id from db.t1",
+	}, {
+		// an inner WITH shadows the outer one
+		sql:    "with x as (select id from t1) select id from x where id in (with x as (select id from t2) select id from x)",
+		expSQL: "select id from (select id from t1) as x where id in (select id from (select id from t2) as x)",
+	}, {
+		sql:    "with x as (select id from t1) select id from x union select id from x",
+		expSQL: "select id from (select id from t1) as x union select id from (select id from t1) as x",
+	}, {
+		sql:    "with x as (select id from t1) update t2 set col = 1 where id in (select id from x)",
+		expSQL: "update t2 set col = 1 where id in (select id from (select id from t1) as x)",
+	}, {
+		sql:    "with x as (select id from t1) delete from t2 where id in (select id from x)",
+		expSQL: "delete from t2 where id in (select id from (select id from t1) as x)",
+	}, {
+		sql:    "with x as (select id from t1) delete from x",
+		expErr: "VT03004: the target table x of the DELETE is not updatable",
+	}, {
+		sql:    "with x as (select id from t1), x as (select id from t2) select id from x",
+		expErr: "VT03013: not unique table/alias: 'x'",
+	}, {
+		sql:    "with recursive x as (select 1 as id union all select id + 1 from x where id < 5) select id from x",
+		expErr: "VT12001: unsupported: recursive common table expression in this position",
+	}, {
+		// WITH RECURSIVE only matters for the CTEs that read from themselves
+		sql:    "with recursive x as (select id from t1) select id from x",
+		expSQL: "select id from (select id from t1) as x",
+	}}
+	for _, tcase := range tcases {
+		t.Run(tcase.sql, func(t *testing.T) {
+			ast, err := sqlparser.Parse(tcase.sql)
+			require.NoError(t, err)
+			err = rewriteCTEs(ast)
+			if tcase.expErr == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tcase.expSQL, sqlparser.String(ast))
+			} else {
+				require.EqualError(t, err, tcase.expErr)
+			}
+		})
+	}
+}
+
+func TestRewriteCTEsDoesNotShareColumns(t *testing.T) {
+	ast, err := sqlparser.Parse("with x as (select id from t1) select x1.id from x as x1 join x as x2")
+	require.NoError(t, err)
+	require.NoError(t, rewriteCTEs(ast))
+
+	var cols []*sqlparser.ColName
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if col, ok := node.(*sqlparser.ColName); ok && col.Qualifier.IsEmpty() {
+			cols = append(cols, col)
+		}
+		return true, nil
+	}, ast)
+	require.Len(t, cols, 2)
+	assert.NotSame(t, cols[0], cols[1])
+}
@@ -36,6 +36,7 @@ import (
 	"vitess.io/vitess/go/vt/topo"
 	"vitess.io/vitess/go/vt/topo/topoproto"
 	"vitess.io/vitess/go/vt/vterrors"
+	"vitess.io/vitess/go/vt/vtgate/balancer"
 	"vitess.io/vitess/go/vt/vtgate/buffer"
 	"vitess.io/vitess/go/vt/vttablet/queryservice"
 
@@ -53,6 +54,17 @@ var (
 	initialTabletTimeout = 30 * time.Second
 	// retryCount is the number of times a query will be retried on error
 	retryCount = 2
+
+	// tabletBalancer is the balancer picking the tablet a query is sent to.
+	// The default, cell, prefers the tablets of the local cell.
+	tabletBalancer = "cell"
+	// balancerVTGateCells are the cells vtgates run in, for the balancer.
+	balancerVTGateCells []string
+	// balancerKeyspaces are the keyspaces the balancer is used for. If empty,
+	// it is used for all of them.
+	balancerKeyspaces []string
+	// balancerWeighting is the health stat the balancer weighs the tablets by.
+	balancerWeighting = string(balancer.WeightingNone)
 )
 
 func init() {
@@ -62,6 +74,10 @@ func init() {
 		fs.MarkDeprecated("buffer_implementation", "The 'healthcheck' buffer implementation has been removed in v18 and this option will be removed in v19")
 		fs.DurationVar(&initialTabletTimeout, "gateway_initial_tablet_timeout", 30*time.Second, "At startup, the tabletGateway will wait up to this duration to get at least one tablet per keyspace/shard/tablet type")
 		fs.IntVar(&retryCount, "retry-count", 2, "retry count")
+		fs.StringVar(&tabletBalancer, "tablet-balancer", tabletBalancer, "The balancer picking the tablet a query is sent to. Allowed values: cell (prefer the tablets of the local cell), flow (give all the tablets of a target the same load, across cells)")
+		fs.StringSliceVar(&balancerVTGateCells, "balancer-vtgate-cells", balancerVTGateCells, "The cells vtgates run in, used by the flow tablet balancer. If empty, vtgates are assumed to run in the cells that have tablets")
+		fs.StringSliceVar(&balancerKeyspaces, "balancer-keyspaces", balancerKeyspaces, "The keyspaces the tablet balancer is used for. If empty, it is used for all keyspaces")
+		fs.StringVar(&balancerWeighting, "balancer-weighting", balancerWeighting, "The health stat the flow tablet balancer weighs the tablets by. Allowed values: none, replication_lag, qps")
 	})
 }
 
@@ -84,6 +100,11 @@ type TabletGateway struct {
 
 	// buffer, if enabled, buffers requests during a detected PRIMARY failover.
 	buffer *buffer.Buffer
+
+	// balancer, if set, picks the tablet a query is sent to for the
+	// keyspaces in balancerKeyspaces.
+	balancer          balancer.TabletBalancer
+	balancerKeyspaces map[string]bool
 }
 
 func createHealthCheck(ctx context.Context, retryDelay, timeout time.Duration, ts *topo.Server, cell, cellsToWatch string) discovery.HealthCheck {
@@ -112,10 +133,32 @@ func NewTabletGateway(ctx context.Context, hc discovery.HealthCheck, serv srvtop
 		statusAggregators: make(map[string]*TabletStatusAggregator),
 	}
 	gw.setupBuffering(ctx)
+	gw.setupBalancer()
 	gw.QueryService = queryservice.Wrap(nil, gw.withRetry)
 	return gw
 }
 
+func (gw *TabletGateway) setupBalancer() {
+	if tabletBalancer == "cell" {
+		return
+	}
+	var err error
+	gw.balancer, err = balancer.New(tabletBalancer, balancer.Config{
+		LocalCell:   gw.localCell,
+		VTGateCells: balancerVTGateCells,
+		Weighting:   balancer.Weighting(balancerWeighting),
+	})
+	if err != nil {
+		log.Exitf("Unable to create the tablet balancer: %v", err)
+	}
+	if len(balancerKeyspaces) > 0 {
+		gw.balancerKeyspaces = make(map[string]bool)
+		for _, keyspace := range balancerKeyspaces {
+			gw.balancerKeyspaces[keyspace] = true
+		}
+	}
+}
+
 func (gw *TabletGateway) setupBuffering(ctx context.Context) {
 	cfg := buffer.NewConfigFromFlags()
 	if !cfg.Enabled {
@@ -306,7 +349,11 @@ func (gw *TabletGateway) withRetry(ctx context.Context, target *querypb.Target,
 			break
 		}
 
-		gw.shuffleTablets(gw.localCell, tablets)
+		if gw.useBalancer(target.Keyspace) {
+			gw.balancer.ShuffleTablets(target, tablets)
+		} else {
+			gw.shuffleTablets(gw.localCell, tablets)
+		}
 
 		var th *discovery.TabletHealth
 		// skip tablets we tried before
@@ -376,6 +423,12 @@ func (gw *TabletGateway) getStatsAggregator(target *querypb.Target) *TabletStatu
 	return aggr
 }
 
+// useBalancer returns true if the tablet balancer picks the tablets
+// of the keyspace.
+func (gw *TabletGateway) useBalancer(keyspace string) bool {
+	return gw.balancer != nil && (gw.balancerKeyspaces == nil || gw.balancerKeyspaces[keyspace])
+}
+
 func (gw *TabletGateway) shuffleTablets(cell string, tablets []*discovery.TabletHealth) {
 	sameCell, diffCell, sameCellMax := 0, 0, -1
 	length := len(tablets)
@@ -428,6 +481,15 @@ func (gw *TabletGateway) TabletsCacheStatus() discovery.TabletsCacheStatusList {
 	return gw.hc.CacheStatus()
 }
 
+// BalancerStatus returns the shares of the tablets picked by the tablet
+// balancer, or nil if it isn't enabled.
+func (gw *TabletGateway) BalancerStatus() []*balancer.TargetStatus {
+	if gw.balancer == nil {
+		return nil
+	}
+	return gw.balancer.Status()
+}
+
 func (gw *TabletGateway) updateDefaultConnCollation(tablet *topodatapb.Tablet) {
 	if atomic.CompareAndSwapUint32(&gw.defaultConnCollation, 0, tablet.DefaultConnCollation) {
 		return
@@ -152,6 +152,44 @@ func TestTabletGatewayShuffleTablets(t *testing.T) {
 	}
 }
 
+func TestTabletGatewayBalancer(t *testing.T) {
+	ctx := utils.LeakCheckContext(t)
+
+	defer func(balancer string, keyspaces []string) {
+		tabletBalancer, balancerKeyspaces = balancer, keyspaces
+	}(tabletBalancer, balancerKeyspaces)
+	tabletBalancer = "flow"
+	balancerKeyspaces = []string{"ks1"}
+
+	keyspace := "ks1"
+	shard := "0"
+	tabletType := topodatapb.TabletType_REPLICA
+	host := "1.1.1.1"
+	port := int32(1001)
+	target := &querypb.Target{Keyspace: keyspace, Shard: shard, TabletType: tabletType}
+	hc := discovery.NewFakeHealthCheck(nil)
+	ts := &fakeTopoServer{}
+	tg := NewTabletGateway(ctx, hc, ts, "cell")
+	defer tg.Close(ctx)
+
+	assert.True(t, tg.useBalancer("ks1"))
+	assert.False(t, tg.useBalancer("ks2"))
+	assert.Empty(t, tg.BalancerStatus())
+
+	hc.AddTestTablet("cell", host, port, keyspace, shard, tabletType, true, 10, nil)
+	hc.AddTestTablet("cell", host, port+1, keyspace, shard, tabletType, true, 10, nil)
+	_, err := tg.Execute(ctx, target, "query", nil, 0, 0, nil)
+	require.NoError(t, err)
+
+	status := tg.BalancerStatus()
+	require.Len(t, status, 1)
+	assert.Equal(t, keyspace, status[0].Target.Keyspace)
+	require.Len(t, status[0].Tablets, 2)
+	for _, ts := range status[0].Tablets {
+		assert.InDelta(t, 50, ts.Share, 1e-6)
+	}
+}
+
 func TestTabletGatewayReplicaTransactionError(t *testing.T) {
 	ctx := utils.LeakCheckContext(t)
 
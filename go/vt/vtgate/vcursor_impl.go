@@ -69,7 +69,7 @@ type iExecute interface {
 	StreamExecuteMulti(ctx context.Context, primitive engine.Primitive, query string, rss []*srvtopo.ResolvedShard, vars []map[string]*querypb.BindVariable, session *SafeSession, autocommit bool, callback func(reply *sqltypes.Result) error) []error
 	ExecuteLock(ctx context.Context, rs *srvtopo.ResolvedShard, query *querypb.BoundQuery, session *SafeSession, lockFuncType sqlparser.LockingFuncType) (*sqltypes.Result, error)
 	Commit(ctx context.Context, safeSession *SafeSession) error
-	ExecuteMessageStream(ctx context.Context, rss []*srvtopo.ResolvedShard, name string, callback func(*sqltypes.Result) error) error
+	ExecuteMessageStream(ctx context.Context, rss []*srvtopo.ResolvedShard, name, consumerGroup string, callback func(*sqltypes.Result) error) error
 	ExecuteVStream(ctx context.Context, rss []*srvtopo.ResolvedShard, filter *binlogdatapb.Filter, gtid string, callback func(evs []*binlogdatapb.VEvent) error) error
 	ReleaseLock(ctx context.Context, session *SafeSession) error
 
@@ -113,6 +113,9 @@ type vcursorImpl struct {
 
 	warnings []*querypb.QueryWarning // any warnings that are accumulated during the planning phase are stored here
 	pv       plancontext.PlannerVersion
+
+	// resultCacheTTL is how long the result of the query can be cached, if the result cache is enabled.
+	resultCacheTTL time.Duration
 }
 
 // newVcursorImpl creates a vcursorImpl. Before creating this object, you have to separate out any marginComments that came with
@@ -199,6 +202,11 @@ func (vc *vcursorImpl) MaxMemoryRows() int {
 	return maxMemoryRows
 }
 
+// CTEMaxRecursionDepth returns the cteMaxRecursionDepth flag value.
+func (vc *vcursorImpl) CTEMaxRecursionDepth() int {
+	return cteMaxRecursionDepth
+}
+
 // ExceedsMaxMemoryRows returns a boolean indicating whether the maxMemoryRows value has been exceeded.
 // Returns false if the max memory rows override directive is set to true.
 func (vc *vcursorImpl) ExceedsMaxMemoryRows(numRows int) bool {
@@ -872,6 +880,15 @@ func (vc *vcursorImpl) SetPriority(priority string) {
 
 }
 
+// SetWorkloadClass implements the SessionActions interface
+func (vc *vcursorImpl) SetWorkloadClass(workloadClass string) {
+	if workloadClass != "" {
+		vc.safeSession.GetOrCreateOptions().WorkloadClass = workloadClass
+	} else if vc.safeSession.Options != nil && vc.safeSession.Options.WorkloadClass != "" {
+		vc.safeSession.Options.WorkloadClass = ""
+	}
+}
+
 // SetConsolidator implements the SessionActions interface
 func (vc *vcursorImpl) SetConsolidator(consolidator querypb.ExecuteOptions_Consolidator) {
 	// Avoid creating session Options when they do not yet exist and the
@@ -1074,6 +1091,12 @@ func (vc *vcursorImpl) keyForPlan(ctx context.Context, query string, buf io.Stri
 			_, _ = buf.WriteString(vc.destination.String())
 		}
 	}
+	// The directives set with query attributes are not always part of the
+	// query, which isn't normalized for every statement.
+	if directive, _ := queryAttributesDirective(vc.safeSession.GetQueryAttributes()); directive != "" {
+		_, _ = buf.WriteString("+Attributes:")
+		_, _ = buf.WriteString(directive)
+	}
 	_, _ = buf.WriteString("+Query:")
 	_, _ = buf.WriteString(query)
 }
@@ -1120,9 +1143,9 @@ func (vc *vcursorImpl) ExecuteVSchema(ctx context.Context, keyspace string, vsch
 
 }
 
-func (vc *vcursorImpl) MessageStream(ctx context.Context, rss []*srvtopo.ResolvedShard, tableName string, callback func(*sqltypes.Result) error) error {
+func (vc *vcursorImpl) MessageStream(ctx context.Context, rss []*srvtopo.ResolvedShard, tableName, consumerGroup string, callback func(*sqltypes.Result) error) error {
 	atomic.AddUint64(&vc.logStats.ShardQueries, uint64(len(rss)))
-	return vc.executor.ExecuteMessageStream(ctx, rss, tableName, callback)
+	return vc.executor.ExecuteMessageStream(ctx, rss, tableName, consumerGroup, callback)
 }
 
 func (vc *vcursorImpl) VStream(ctx context.Context, rss []*srvtopo.ResolvedShard, filter *binlogdatapb.Filter, gtid string, callback func(evs []*binlogdatapb.VEvent) error) error {
@@ -23,12 +23,15 @@ import (
 
 	"vitess.io/vitess/go/sqltypes"
 	"vitess.io/vitess/go/vt/key"
+
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
 )
 
 var (
 	_ SingleColumn    = (*Binary)(nil)
 	_ Reversible      = (*Binary)(nil)
 	_ Hashing         = (*Binary)(nil)
+	_ RangeMapper     = (*Binary)(nil)
 	_ ParamValidating = (*Binary)(nil)
 )
 
@@ -92,6 +95,26 @@ func (vind *Binary) Map(ctx context.Context, vcursor VCursor, ids []sqltypes.Val
 	return out, nil
 }
 
+// MapRange maps the ids from start to end to the key range of their
+// keyspace ids. Only quoted bounds are compared byte by byte like the
+// keyspace ids, so other bounds leave the range unbounded on their side.
+// The planner only uses it for binary and varbinary columns, since the
+// ranges of other columns depend on their collation.
+func (vind *Binary) MapRange(ctx context.Context, vcursor VCursor, start, end sqltypes.Value) (key.Destination, error) {
+	keyRange := &topodatapb.KeyRange{}
+	if start.IsQuoted() {
+		keyRange.Start = start.Raw()
+	}
+	if end.IsQuoted() {
+		if bytes.Compare(end.Raw(), keyRange.Start) < 0 {
+			return key.DestinationNone{}, nil
+		}
+		// The smallest keyspace id after end.
+		keyRange.End = append(bytes.Clone(end.Raw()), 0)
+	}
+	return key.DestinationKeyRange{KeyRange: keyRange}, nil
+}
+
 func (vind *Binary) Hash(id sqltypes.Value) ([]byte, error) {
 	return id.ToBytes()
 }
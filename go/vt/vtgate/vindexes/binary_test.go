@@ -24,10 +24,13 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"vitess.io/vitess/go/sqltypes"
 	"vitess.io/vitess/go/vt/key"
+
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
 )
 
 var binOnlyVindex SingleColumn
@@ -113,6 +116,46 @@ func TestBinaryMap(t *testing.T) {
 	}
 }
 
+func TestBinaryMapRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end sqltypes.Value
+		want       key.Destination
+	}{{
+		name:  "bounded",
+		start: sqltypes.NewVarBinary("abc"),
+		end:   sqltypes.NewVarBinary("abd"),
+		want: key.DestinationKeyRange{KeyRange: &topodatapb.KeyRange{
+			Start: []byte("abc"),
+			End:   []byte("abd\x00"),
+		}},
+	}, {
+		name:  "unbounded",
+		start: sqltypes.NULL,
+		end:   sqltypes.NULL,
+		want:  key.DestinationKeyRange{KeyRange: &topodatapb.KeyRange{}},
+	}, {
+		name:  "numeric bound",
+		start: sqltypes.NewInt64(10),
+		end:   sqltypes.NewVarChar("abd"),
+		want: key.DestinationKeyRange{KeyRange: &topodatapb.KeyRange{
+			End: []byte("abd\x00"),
+		}},
+	}, {
+		name:  "empty",
+		start: sqltypes.NewVarBinary("abd"),
+		end:   sqltypes.NewVarBinary("abc"),
+		want:  key.DestinationNone{},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := binOnlyVindex.(RangeMapper).MapRange(context.Background(), nil, tt.start, tt.end)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestBinaryVerify(t *testing.T) {
 	hexValStr := "8a1e"
 	hexValStrSQL := fmt.Sprintf("x'%s'", hexValStr)
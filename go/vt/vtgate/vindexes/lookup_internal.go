@@ -24,7 +24,9 @@ import (
 	"strconv"
 	"strings"
 
+	"vitess.io/vitess/go/mysql/collations"
 	"vitess.io/vitess/go/vt/vterrors"
+	"vitess.io/vitess/go/vt/vtgate/evalengine"
 
 	"vitess.io/vitess/go/sqltypes"
 
@@ -205,6 +207,174 @@ func (lkp *lookupInternal) Lookup(ctx context.Context, vcursor VCursor, ids []sq
 	return results, nil
 }
 
+// LookupMultiCol performs a lookup for the values of all, or of a prefix, of
+// the from columns of each row. The rows that have values for the same number
+// of from columns are looked up by a single query. The rows of each result
+// have the to column.
+func (lkp *lookupInternal) LookupMultiCol(ctx context.Context, vcursor VCursor, rowsColValues [][]sqltypes.Value, co vtgatepb.CommitOrder) ([]*sqltypes.Result, error) {
+	if vcursor == nil {
+		return nil, fmt.Errorf("cannot perform lookup: no vcursor provided")
+	}
+	if lkp.Autocommit {
+		co = vtgatepb.CommitOrder_AUTOCOMMIT
+	}
+	// rowsByCount has the indexes of the rows by their number of values.
+	rowsByCount := make([][]int, len(lkp.FromColumns)+1)
+	for i, colValues := range rowsColValues {
+		if len(colValues) == 0 || len(colValues) > len(lkp.FromColumns) {
+			return nil, vterrors.Errorf(vtrpcpb.Code_INTERNAL, "[BUG] wrong number of column values were passed: maximum allowed %d, got %d", len(lkp.FromColumns), len(colValues))
+		}
+		rowsByCount[len(colValues)] = append(rowsByCount[len(colValues)], i)
+	}
+	inTxDml := vcursor.InTransactionAndIsDML()
+	results := make([]*sqltypes.Result, len(rowsColValues))
+	for count, rows := range rowsByCount {
+		if len(rows) == 0 {
+			continue
+		}
+		batch := make([][]sqltypes.Value, 0, len(rows))
+		for _, i := range rows {
+			batch = append(batch, rowsColValues[i])
+			results[i] = &sqltypes.Result{}
+		}
+		result, err := vcursor.Execute(ctx, "VindexLookup", lkp.multiColSelect(count, len(batch), inTxDml), lkp.multiColBindVars(batch, nil), false /* rollbackOnError */, co)
+		if err != nil {
+			return nil, fmt.Errorf("lookup.Map: %v", err)
+		}
+		// The rows are matched like MySQL matched them, so that values that
+		// only differ by their case still match with a case insensitive
+		// collation.
+		for _, row := range result.Rows {
+			for _, i := range rows {
+				match, err := valuesMatch(row[1:1+count], rowsColValues[i], vcursor.ConnCollation())
+				if err != nil {
+					return nil, fmt.Errorf("lookup.Map: %v", err)
+				}
+				if match {
+					results[i].Rows = append(results[i].Rows, []sqltypes.Value{row[0]})
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// VerifyMultiCol returns true for the rows of values of all the from columns
+// that map to values. All the rows are verified by a single query.
+func (lkp *lookupInternal) VerifyMultiCol(ctx context.Context, vcursor VCursor, rowsColValues [][]sqltypes.Value, values []sqltypes.Value) ([]bool, error) {
+	co := vtgatepb.CommitOrder_NORMAL
+	if lkp.Autocommit {
+		co = vtgatepb.CommitOrder_AUTOCOMMIT
+	}
+	out := make([]bool, len(rowsColValues))
+	if len(rowsColValues) == 0 {
+		return out, nil
+	}
+	for _, colValues := range rowsColValues {
+		if len(colValues) != len(lkp.FromColumns) {
+			return nil, fmt.Errorf("lookup.Verify: column vindex count does not match the columns in the lookup: %d vs %v", len(colValues), lkp.FromColumns)
+		}
+	}
+	result, err := vcursor.Execute(ctx, "VindexVerify", lkp.multiColVerify(len(rowsColValues)), lkp.multiColBindVars(rowsColValues, values), false /* rollbackOnError */, co)
+	if err != nil {
+		return nil, fmt.Errorf("lookup.Verify: %v", err)
+	}
+	for _, row := range result.Rows {
+		for i, colValues := range rowsColValues {
+			if out[i] {
+				continue
+			}
+			match, err := valuesMatch(row, append(append([]sqltypes.Value{}, colValues...), values[i]), vcursor.ConnCollation())
+			if err != nil {
+				return nil, fmt.Errorf("lookup.Verify: %v", err)
+			}
+			out[i] = match
+		}
+	}
+	return out, nil
+}
+
+// multiColSelect returns the query that selects the to column, followed by
+// the first count from columns, for rows rows of values of these columns.
+func (lkp *lookupInternal) multiColSelect(count, rows int, inTxDml bool) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "select %s, %s from %s where ", lkp.To, strings.Join(lkp.FromColumns[:count], ", "), lkp.Table)
+	writeColumnsInPredicate(&buf, lkp.FromColumns[:count], rows)
+	if inTxDml && lkp.ReadLock != readLockNone {
+		lockExpr, ok := readLockExprs[lkp.ReadLock]
+		if !ok {
+			lockExpr = readLockExprs[readLockDefault]
+		}
+		buf.WriteString(" " + lockExpr)
+	}
+	return buf.String()
+}
+
+// multiColVerify returns the query that selects the from columns and the to
+// column of the rows rows of values of these columns that exist.
+func (lkp *lookupInternal) multiColVerify(rows int) string {
+	columns := append(append([]string{}, lkp.FromColumns...), lkp.To)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "select %s from %s where ", strings.Join(columns, ", "), lkp.Table)
+	writeColumnsInPredicate(&buf, columns, rows)
+	return buf.String()
+}
+
+// multiColBindVars returns the bind variables of the rows of values of the
+// from columns, and of the to values if they're set.
+func (lkp *lookupInternal) multiColBindVars(rowsColValues [][]sqltypes.Value, values []sqltypes.Value) map[string]*querypb.BindVariable {
+	bindVars := make(map[string]*querypb.BindVariable, len(rowsColValues)*(len(lkp.FromColumns)+1))
+	for rowIdx, colValues := range rowsColValues {
+		for colIdx, colValue := range colValues {
+			bindVars[fmt.Sprintf("%s_%d", lkp.FromColumns[colIdx], rowIdx)] = sqltypes.ValueBindVariable(colValue)
+		}
+		if values != nil {
+			bindVars[fmt.Sprintf("%s_%d", lkp.To, rowIdx)] = sqltypes.ValueBindVariable(values[rowIdx])
+		}
+	}
+	return bindVars
+}
+
+// writeColumnsInPredicate writes the predicate that the columns are in rows
+// rows of bind variables, named after the columns and the index of the row.
+func writeColumnsInPredicate(buf *bytes.Buffer, columns []string, rows int) {
+	writeTuple := func(name func(column string) string) {
+		if len(columns) > 1 {
+			buf.WriteString("(")
+		}
+		for colIdx, column := range columns {
+			if colIdx != 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(name(column))
+		}
+		if len(columns) > 1 {
+			buf.WriteString(")")
+		}
+	}
+	writeTuple(func(column string) string { return column })
+	buf.WriteString(" in (")
+	for rowIdx := 0; rowIdx < rows; rowIdx++ {
+		if rowIdx != 0 {
+			buf.WriteString(", ")
+		}
+		writeTuple(func(column string) string { return fmt.Sprintf(":%s_%d", column, rowIdx) })
+	}
+	buf.WriteString(")")
+}
+
+// valuesMatch returns true if the values of a row of a lookup table are equal
+// to the values that were looked up.
+func valuesMatch(row, values []sqltypes.Value, collation collations.ID) (bool, error) {
+	for i, value := range values {
+		cmp, err := evalengine.NullsafeCompare(row[i], value, collation)
+		if err != nil || cmp != 0 {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
 // Verify returns true if ids map to values.
 func (lkp *lookupInternal) Verify(ctx context.Context, vcursor VCursor, ids, values []sqltypes.Value) ([]bool, error) {
 	co := vtgatepb.CommitOrder_NORMAL
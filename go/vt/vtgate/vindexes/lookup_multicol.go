@@ -0,0 +1,383 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vindexes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/key"
+
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+	vtgatepb "vitess.io/vitess/go/vt/proto/vtgate"
+)
+
+var (
+	_ MultiColumn     = (*LookupMultiCol)(nil)
+	_ Lookup          = (*LookupMultiCol)(nil)
+	_ LookupBackfill  = (*LookupMultiCol)(nil)
+	_ ParamValidating = (*LookupMultiCol)(nil)
+	_ MultiColumn     = (*LookupMultiColUnique)(nil)
+	_ Lookup          = (*LookupMultiColUnique)(nil)
+	_ LookupBackfill  = (*LookupMultiColUnique)(nil)
+	_ ParamValidating = (*LookupMultiColUnique)(nil)
+)
+
+func init() {
+	Register("lookup_multicol", newLookupMultiCol)
+	Register("lookup_multicol_unique", newLookupMultiColUnique)
+}
+
+// LookupMultiCol defines a vindex that uses a lookup table keyed on multiple
+// from columns, like (tenant_id, email). Unlike the lookup vindex, which maps
+// the values of the first from column only, it maps the values of all the
+// from columns or of a prefix of them.
+// It's NonUnique and a Lookup.
+type LookupMultiCol struct {
+	name          string
+	writeOnly     bool
+	noVerify      bool
+	lkp           lookupInternal
+	unknownParams []string
+}
+
+// newLookupMultiCol creates a LookupMultiCol vindex.
+// The supplied map has the following required fields:
+//
+//	table: name of the backing table. It can be qualified by the keyspace.
+//	from: list of columns in the table that have the 'from' values of the lookup vindex.
+//	to: The 'to' column name of the table.
+//
+// The following fields are optional:
+//
+//	autocommit: setting this to "true" will cause inserts to upsert and deletes to be ignored.
+//	write_only: in this mode, Map functions return the full keyrange causing a full scatter.
+//	no_verify: in this mode, Verify will always succeed.
+func newLookupMultiCol(name string, m map[string]string) (Vindex, error) {
+	lookup := &LookupMultiCol{
+		name:          name,
+		unknownParams: FindUnknownParams(m, lookupParams),
+	}
+
+	cc, err := parseCommonConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	lookup.writeOnly, err = boolFromMap(m, lookupParamWriteOnly)
+	if err != nil {
+		return nil, err
+	}
+	lookup.noVerify, err = boolFromMap(m, lookupParamNoVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	// if autocommit is on for non-unique lookup, upsert should also be on.
+	upsert := cc.autocommit || cc.multiShardAutocommit
+	if err := lookup.lkp.Init(m, cc.autocommit, upsert, cc.multiShardAutocommit); err != nil {
+		return nil, err
+	}
+	return lookup, nil
+}
+
+// String returns the name of the vindex.
+func (lm *LookupMultiCol) String() string {
+	return lm.name
+}
+
+// Cost returns the cost of this vindex as 20.
+func (lm *LookupMultiCol) Cost() int {
+	return 20
+}
+
+// IsUnique returns false since the Vindex is non unique.
+func (lm *LookupMultiCol) IsUnique() bool {
+	return false
+}
+
+// NeedsVCursor satisfies the Vindex interface.
+func (lm *LookupMultiCol) NeedsVCursor() bool {
+	return true
+}
+
+// PartialVindex returns true since a prefix of the from columns can be mapped.
+func (lm *LookupMultiCol) PartialVindex() bool {
+	return true
+}
+
+// Map can map the values of all, or of a prefix, of the from columns to
+// key.Destination objects.
+func (lm *LookupMultiCol) Map(ctx context.Context, vcursor VCursor, rowsColValues [][]sqltypes.Value) ([]key.Destination, error) {
+	if lm.writeOnly {
+		return fullKeyRanges(len(rowsColValues)), nil
+	}
+	// if ignore_nulls is set and the query is about a single row with null values, then fallback to all shards
+	if len(rowsColValues) == 1 && hasNull(rowsColValues[0]) && lm.lkp.IgnoreNulls {
+		return fullKeyRanges(1), nil
+	}
+
+	results, err := lm.lkp.LookupMultiCol(ctx, vcursor, rowsColValues, vtgatepb.CommitOrder_NORMAL)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]key.Destination, 0, len(results))
+	for _, result := range results {
+		destination, err := keyspaceIDsDestination(result)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, destination)
+	}
+	return out, nil
+}
+
+// Verify returns true if the values of all the from columns map to ksids.
+func (lm *LookupMultiCol) Verify(ctx context.Context, vcursor VCursor, rowsColValues [][]sqltypes.Value, ksids [][]byte) ([]bool, error) {
+	if lm.writeOnly || lm.noVerify {
+		return allTrue(len(rowsColValues)), nil
+	}
+	return lm.lkp.VerifyMultiCol(ctx, vcursor, rowsColValues, ksidsToValues(ksids))
+}
+
+// Create reserves the ids by inserting them into the vindex table.
+func (lm *LookupMultiCol) Create(ctx context.Context, vcursor VCursor, rowsColValues [][]sqltypes.Value, ksids [][]byte, ignoreMode bool) error {
+	return lm.lkp.Create(ctx, vcursor, rowsColValues, ksidsToValues(ksids), ignoreMode)
+}
+
+// Delete deletes the entries from the vindex table.
+func (lm *LookupMultiCol) Delete(ctx context.Context, vcursor VCursor, rowsColValues [][]sqltypes.Value, ksid []byte) error {
+	return lm.lkp.Delete(ctx, vcursor, rowsColValues, sqltypes.MakeTrusted(sqltypes.VarBinary, ksid), vtgatepb.CommitOrder_NORMAL)
+}
+
+// Update updates the entry in the vindex table.
+func (lm *LookupMultiCol) Update(ctx context.Context, vcursor VCursor, oldValues []sqltypes.Value, ksid []byte, newValues []sqltypes.Value) error {
+	return lm.lkp.Update(ctx, vcursor, oldValues, ksid, sqltypes.MakeTrusted(sqltypes.VarBinary, ksid), newValues)
+}
+
+// IsBackfilling implements the LookupBackfill interface
+func (lm *LookupMultiCol) IsBackfilling() bool {
+	return lm.writeOnly
+}
+
+// MarshalJSON returns a JSON representation of LookupMultiCol.
+func (lm *LookupMultiCol) MarshalJSON() ([]byte, error) {
+	return json.Marshal(lm.lkp)
+}
+
+// UnknownParams implements the ParamValidating interface.
+func (lm *LookupMultiCol) UnknownParams() []string {
+	return lm.unknownParams
+}
+
+//====================================================================
+
+// LookupMultiColUnique defines a vindex that uses a lookup table keyed on
+// multiple from columns. The table is expected to define the from columns as
+// a unique key. The values of all the from columns map to a single keyspace
+// id, while the values of a prefix of them can map to several.
+// It's Unique and a Lookup.
+type LookupMultiColUnique struct {
+	name          string
+	writeOnly     bool
+	noVerify      bool
+	lkp           lookupInternal
+	unknownParams []string
+}
+
+// newLookupMultiColUnique creates a LookupMultiColUnique vindex.
+// The supplied map has the following required fields:
+//
+//	table: name of the backing table. It can be qualified by the keyspace.
+//	from: list of columns in the table that have the 'from' values of the lookup vindex.
+//	to: The 'to' column name of the table.
+//
+// The following fields are optional:
+//
+//	autocommit: setting this to "true" will cause deletes to be ignored.
+//	write_only: in this mode, Map functions return the full keyrange causing a full scatter.
+//	no_verify: in this mode, Verify will always succeed.
+func newLookupMultiColUnique(name string, m map[string]string) (Vindex, error) {
+	lu := &LookupMultiColUnique{
+		name:          name,
+		unknownParams: FindUnknownParams(m, lookupParams),
+	}
+
+	cc, err := parseCommonConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	lu.writeOnly, err = boolFromMap(m, lookupParamWriteOnly)
+	if err != nil {
+		return nil, err
+	}
+	lu.noVerify, err = boolFromMap(m, lookupParamNoVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't allow upserts for unique vindexes.
+	if err := lu.lkp.Init(m, cc.autocommit, false /* upsert */, cc.multiShardAutocommit); err != nil {
+		return nil, err
+	}
+	return lu, nil
+}
+
+// String returns the name of the vindex.
+func (lu *LookupMultiColUnique) String() string {
+	return lu.name
+}
+
+// Cost returns the cost of this vindex as 10.
+func (lu *LookupMultiColUnique) Cost() int {
+	return 10
+}
+
+// IsUnique returns true since the Vindex is unique.
+func (lu *LookupMultiColUnique) IsUnique() bool {
+	return true
+}
+
+// NeedsVCursor satisfies the Vindex interface.
+func (lu *LookupMultiColUnique) NeedsVCursor() bool {
+	return true
+}
+
+// PartialVindex returns true since a prefix of the from columns can be mapped.
+func (lu *LookupMultiColUnique) PartialVindex() bool {
+	return true
+}
+
+// Map can map the values of all, or of a prefix, of the from columns to
+// key.Destination objects.
+func (lu *LookupMultiColUnique) Map(ctx context.Context, vcursor VCursor, rowsColValues [][]sqltypes.Value) ([]key.Destination, error) {
+	if lu.writeOnly {
+		return fullKeyRanges(len(rowsColValues)), nil
+	}
+	results, err := lu.lkp.LookupMultiCol(ctx, vcursor, rowsColValues, vtgatepb.CommitOrder_NORMAL)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]key.Destination, 0, len(results))
+	for i, result := range results {
+		if len(rowsColValues[i]) < len(lu.lkp.FromColumns) {
+			// A prefix of the from columns isn't unique.
+			destination, err := keyspaceIDsDestination(result)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, destination)
+			continue
+		}
+		switch len(result.Rows) {
+		case 0:
+			out = append(out, key.DestinationNone{})
+		case 1:
+			rowBytes, err := result.Rows[0][0].ToBytes()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, key.DestinationKeyspaceID(rowBytes))
+		default:
+			return nil, fmt.Errorf("Lookup.Map: unexpected multiple results from vindex %s: %v", lu.lkp.Table, rowsColValues[i])
+		}
+	}
+	return out, nil
+}
+
+// Verify returns true if the values of all the from columns map to ksids.
+func (lu *LookupMultiColUnique) Verify(ctx context.Context, vcursor VCursor, rowsColValues [][]sqltypes.Value, ksids [][]byte) ([]bool, error) {
+	if lu.writeOnly || lu.noVerify {
+		return allTrue(len(rowsColValues)), nil
+	}
+	return lu.lkp.VerifyMultiCol(ctx, vcursor, rowsColValues, ksidsToValues(ksids))
+}
+
+// Create reserves the ids by inserting them into the vindex table.
+func (lu *LookupMultiColUnique) Create(ctx context.Context, vcursor VCursor, rowsColValues [][]sqltypes.Value, ksids [][]byte, ignoreMode bool) error {
+	return lu.lkp.Create(ctx, vcursor, rowsColValues, ksidsToValues(ksids), ignoreMode)
+}
+
+// Delete deletes the entries from the vindex table.
+func (lu *LookupMultiColUnique) Delete(ctx context.Context, vcursor VCursor, rowsColValues [][]sqltypes.Value, ksid []byte) error {
+	return lu.lkp.Delete(ctx, vcursor, rowsColValues, sqltypes.MakeTrusted(sqltypes.VarBinary, ksid), vtgatepb.CommitOrder_NORMAL)
+}
+
+// Update updates the entry in the vindex table.
+func (lu *LookupMultiColUnique) Update(ctx context.Context, vcursor VCursor, oldValues []sqltypes.Value, ksid []byte, newValues []sqltypes.Value) error {
+	return lu.lkp.Update(ctx, vcursor, oldValues, ksid, sqltypes.MakeTrusted(sqltypes.VarBinary, ksid), newValues)
+}
+
+// IsBackfilling implements the LookupBackfill interface
+func (lu *LookupMultiColUnique) IsBackfilling() bool {
+	return lu.writeOnly
+}
+
+// MarshalJSON returns a JSON representation of LookupMultiColUnique.
+func (lu *LookupMultiColUnique) MarshalJSON() ([]byte, error) {
+	return json.Marshal(lu.lkp)
+}
+
+// UnknownParams implements the ParamValidating interface.
+func (lu *LookupMultiColUnique) UnknownParams() []string {
+	return lu.unknownParams
+}
+
+//====================================================================
+
+// keyspaceIDsDestination returns the destination of the keyspace ids of the
+// rows of a lookup result.
+func keyspaceIDsDestination(result *sqltypes.Result) (key.Destination, error) {
+	if len(result.Rows) == 0 {
+		return key.DestinationNone{}, nil
+	}
+	ksids := make([][]byte, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		rowBytes, err := row[0].ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		ksids = append(ksids, rowBytes)
+	}
+	return key.DestinationKeyspaceIDs(ksids), nil
+}
+
+func fullKeyRanges(count int) []key.Destination {
+	out := make([]key.Destination, 0, count)
+	for i := 0; i < count; i++ {
+		out = append(out, key.DestinationKeyRange{KeyRange: &topodatapb.KeyRange{}})
+	}
+	return out
+}
+
+func allTrue(count int) []bool {
+	out := make([]bool, count)
+	for i := range out {
+		out[i] = true
+	}
+	return out
+}
+
+func hasNull(colValues []sqltypes.Value) bool {
+	for _, colValue := range colValues {
+		if colValue.IsNull() {
+			return true
+		}
+	}
+	return false
+}
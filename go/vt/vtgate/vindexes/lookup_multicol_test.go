@@ -0,0 +1,218 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vindexes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/test/utils"
+	"vitess.io/vitess/go/vt/key"
+
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+)
+
+func TestLookupMultiColInfo(t *testing.T) {
+	lm := createLookupMultiCol(t, "lookup_multicol", false /* writeOnly */)
+	assert.Equal(t, 20, lm.Cost())
+	assert.Equal(t, "lookup_multicol", lm.String())
+	assert.False(t, lm.IsUnique())
+	assert.True(t, lm.NeedsVCursor())
+	assert.True(t, lm.PartialVindex())
+
+	lu := createLookupMultiCol(t, "lookup_multicol_unique", false /* writeOnly */)
+	assert.Equal(t, 10, lu.Cost())
+	assert.True(t, lu.IsUnique())
+	assert.True(t, lu.PartialVindex())
+}
+
+func TestLookupMultiColMap(t *testing.T) {
+	lm := createLookupMultiCol(t, "lookup_multicol", false /* writeOnly */)
+	vc := &vcursor{result: sqltypes.MakeTestResult(sqltypes.MakeTestFields("toc|fromc1|fromc2", "varbinary|int64|varchar"),
+		"1|1|A",
+		"2|1|a",
+		"3|1|b",
+		"4|2|a",
+	)}
+
+	got, err := lm.Map(context.Background(), vc, [][]sqltypes.Value{
+		{sqltypes.NewInt64(1), sqltypes.NewVarChar("a")},
+		{sqltypes.NewInt64(2)},
+		{sqltypes.NewInt64(3), sqltypes.NewVarChar("a")},
+	})
+	require.NoError(t, err)
+	want := []key.Destination{
+		key.DestinationKeyspaceIDs([][]byte{[]byte("1"), []byte("2")}),
+		key.DestinationKeyspaceIDs([][]byte{[]byte("4")}),
+		key.DestinationNone{},
+	}
+	utils.MustMatch(t, want, got)
+
+	wantqueries := []*querypb.BoundQuery{{
+		Sql: "select toc, fromc1 from t where fromc1 in (:fromc1_0)",
+		BindVariables: map[string]*querypb.BindVariable{
+			"fromc1_0": sqltypes.Int64BindVariable(2),
+		},
+	}, {
+		Sql: "select toc, fromc1, fromc2 from t where (fromc1, fromc2) in ((:fromc1_0, :fromc2_0), (:fromc1_1, :fromc2_1))",
+		BindVariables: map[string]*querypb.BindVariable{
+			"fromc1_0": sqltypes.Int64BindVariable(1),
+			"fromc2_0": sqltypes.StringBindVariable("a"),
+			"fromc1_1": sqltypes.Int64BindVariable(3),
+			"fromc2_1": sqltypes.StringBindVariable("a"),
+		},
+	}}
+	utils.MustMatch(t, wantqueries, vc.queries)
+
+	// Test too many values.
+	_, err = lm.Map(context.Background(), vc, [][]sqltypes.Value{{sqltypes.NewInt64(1), sqltypes.NewInt64(2), sqltypes.NewInt64(3)}})
+	require.EqualError(t, err, "[BUG] wrong number of column values were passed: maximum allowed 2, got 3")
+
+	// Test query fail.
+	vc.mustFail = true
+	_, err = lm.Map(context.Background(), vc, [][]sqltypes.Value{{sqltypes.NewInt64(1)}})
+	require.EqualError(t, err, "lookup.Map: execute failed")
+}
+
+func TestLookupMultiColMapWriteOnly(t *testing.T) {
+	lm := createLookupMultiCol(t, "lookup_multicol", true /* writeOnly */)
+	vc := &vcursor{numRows: 2}
+
+	got, err := lm.Map(context.Background(), vc, [][]sqltypes.Value{{sqltypes.NewInt64(1), sqltypes.NewVarChar("a")}})
+	require.NoError(t, err)
+	want := []key.Destination{key.DestinationKeyRange{KeyRange: &topodatapb.KeyRange{}}}
+	utils.MustMatch(t, want, got)
+	assert.Empty(t, vc.queries)
+}
+
+func TestLookupMultiColUniqueMap(t *testing.T) {
+	lu := createLookupMultiCol(t, "lookup_multicol_unique", false /* writeOnly */)
+	fields := sqltypes.MakeTestFields("toc|fromc1|fromc2", "varbinary|int64|varchar")
+	vc := &vcursor{result: sqltypes.MakeTestResult(fields, "1|1|a", "2|2|b")}
+
+	got, err := lu.Map(context.Background(), vc, [][]sqltypes.Value{
+		{sqltypes.NewInt64(1), sqltypes.NewVarChar("a")},
+		{sqltypes.NewInt64(2)},
+		{sqltypes.NewInt64(3), sqltypes.NewVarChar("c")},
+	})
+	require.NoError(t, err)
+	want := []key.Destination{
+		key.DestinationKeyspaceID([]byte("1")),
+		key.DestinationKeyspaceIDs([][]byte{[]byte("2")}),
+		key.DestinationNone{},
+	}
+	utils.MustMatch(t, want, got)
+
+	vc.result = sqltypes.MakeTestResult(fields, "1|1|a", "2|1|a")
+	_, err = lu.Map(context.Background(), vc, [][]sqltypes.Value{{sqltypes.NewInt64(1), sqltypes.NewVarChar("a")}})
+	require.EqualError(t, err, "Lookup.Map: unexpected multiple results from vindex t: [INT64(1) VARCHAR(\"a\")]")
+}
+
+func TestLookupMultiColVerify(t *testing.T) {
+	lu := createLookupMultiCol(t, "lookup_multicol_unique", false /* writeOnly */)
+	vc := &vcursor{result: sqltypes.MakeTestResult(sqltypes.MakeTestFields("fromc1|fromc2|toc", "int64|varchar|varbinary"), "1|A|test1")}
+
+	got, err := lu.Verify(context.Background(), vc, [][]sqltypes.Value{
+		{sqltypes.NewInt64(1), sqltypes.NewVarChar("a")},
+		{sqltypes.NewInt64(1), sqltypes.NewVarChar("a")},
+		{sqltypes.NewInt64(2), sqltypes.NewVarChar("b")},
+	}, [][]byte{[]byte("test1"), []byte("test2"), []byte("test1")})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false, false}, got)
+
+	wantqueries := []*querypb.BoundQuery{{
+		Sql: "select fromc1, fromc2, toc from t where (fromc1, fromc2, toc) in ((:fromc1_0, :fromc2_0, :toc_0), (:fromc1_1, :fromc2_1, :toc_1), (:fromc1_2, :fromc2_2, :toc_2))",
+		BindVariables: map[string]*querypb.BindVariable{
+			"fromc1_0": sqltypes.Int64BindVariable(1),
+			"fromc2_0": sqltypes.StringBindVariable("a"),
+			"toc_0":    sqltypes.BytesBindVariable([]byte("test1")),
+			"fromc1_1": sqltypes.Int64BindVariable(1),
+			"fromc2_1": sqltypes.StringBindVariable("a"),
+			"toc_1":    sqltypes.BytesBindVariable([]byte("test2")),
+			"fromc1_2": sqltypes.Int64BindVariable(2),
+			"fromc2_2": sqltypes.StringBindVariable("b"),
+			"toc_2":    sqltypes.BytesBindVariable([]byte("test1")),
+		},
+	}}
+	utils.MustMatch(t, wantqueries, vc.queries)
+
+	// Test column count fail.
+	_, err = lu.Verify(context.Background(), vc, [][]sqltypes.Value{{sqltypes.NewInt64(1)}}, [][]byte{[]byte("test1")})
+	require.EqualError(t, err, "lookup.Verify: column vindex count does not match the columns in the lookup: 1 vs [fromc1 fromc2]")
+}
+
+func TestLookupMultiColCreateUpdateDelete(t *testing.T) {
+	lu := createLookupMultiCol(t, "lookup_multicol_unique", false /* writeOnly */)
+	vc := &vcursor{}
+
+	err := lu.(Lookup).Create(context.Background(), vc, [][]sqltypes.Value{{sqltypes.NewInt64(1), sqltypes.NewVarChar("a")}}, [][]byte{[]byte("test1")}, false /* ignoreMode */)
+	require.NoError(t, err)
+	err = lu.(Lookup).Update(context.Background(), vc, []sqltypes.Value{sqltypes.NewInt64(1), sqltypes.NewVarChar("a")}, []byte("test1"), []sqltypes.Value{sqltypes.NewInt64(1), sqltypes.NewVarChar("b")})
+	require.NoError(t, err)
+	err = lu.(Lookup).Delete(context.Background(), vc, [][]sqltypes.Value{{sqltypes.NewInt64(1), sqltypes.NewVarChar("b")}}, []byte("test1"))
+	require.NoError(t, err)
+
+	deleteQuery := func(fromc2 string) *querypb.BoundQuery {
+		return &querypb.BoundQuery{
+			Sql: "delete from t where fromc1 = :fromc1 and fromc2 = :fromc2 and toc = :toc",
+			BindVariables: map[string]*querypb.BindVariable{
+				"fromc1": sqltypes.Int64BindVariable(1),
+				"fromc2": sqltypes.StringBindVariable(fromc2),
+				"toc":    sqltypes.BytesBindVariable([]byte("test1")),
+			},
+		}
+	}
+	insertQuery := func(fromc2 string) *querypb.BoundQuery {
+		return &querypb.BoundQuery{
+			Sql: "insert into t(fromc1, fromc2, toc) values(:fromc1_0, :fromc2_0, :toc_0)",
+			BindVariables: map[string]*querypb.BindVariable{
+				"fromc1_0": sqltypes.Int64BindVariable(1),
+				"fromc2_0": sqltypes.StringBindVariable(fromc2),
+				"toc_0":    sqltypes.BytesBindVariable([]byte("test1")),
+			},
+		}
+	}
+	wantqueries := []*querypb.BoundQuery{
+		insertQuery("a"),
+		deleteQuery("a"),
+		insertQuery("b"),
+		deleteQuery("b"),
+	}
+	utils.MustMatch(t, wantqueries, vc.queries)
+}
+
+func createLookupMultiCol(t *testing.T, name string, writeOnly bool) MultiColumn {
+	t.Helper()
+	write := "false"
+	if writeOnly {
+		write = "true"
+	}
+	l, err := CreateVindex(name, name, map[string]string{
+		"table":      "t",
+		"from":       "fromc1,fromc2",
+		"to":         "toc",
+		"write_only": write,
+	})
+	require.NoError(t, err)
+	require.Empty(t, l.(ParamValidating).UnknownParams())
+	return l.(MultiColumn)
+}
@@ -21,20 +21,25 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"math"
 
 	"vitess.io/vitess/go/sqltypes"
 	"vitess.io/vitess/go/vt/key"
+
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
 )
 
 var (
 	_ SingleColumn    = (*Numeric)(nil)
 	_ Reversible      = (*Numeric)(nil)
 	_ Hashing         = (*Numeric)(nil)
+	_ RangeMapper     = (*Numeric)(nil)
 	_ ParamValidating = (*Numeric)(nil)
 )
 
 // Numeric defines a bit-pattern mapping of a uint64 to the KeyspaceId.
-// It's Unique and Reversible.
+// It's Unique and Reversible, and it can map ranges of ids to the key
+// range of their keyspace ids since it preserves their order.
 type Numeric struct {
 	name          string
 	unknownParams []string
@@ -95,6 +100,16 @@ func (vind *Numeric) Map(ctx context.Context, vcursor VCursor, ids []sqltypes.Va
 	return out, nil
 }
 
+// MapRange maps the ids from start to end to the key range of their
+// keyspace ids.
+func (*Numeric) MapRange(ctx context.Context, vcursor VCursor, start, end sqltypes.Value) (key.Destination, error) {
+	keyRange, ok := numericKeyRange(start, end)
+	if !ok {
+		return key.DestinationNone{}, nil
+	}
+	return key.DestinationKeyRange{KeyRange: keyRange}, nil
+}
+
 // ReverseMap returns the associated ids for the ksids.
 func (*Numeric) ReverseMap(_ VCursor, ksids [][]byte) ([]sqltypes.Value, error) {
 	var reverseIds = make([]sqltypes.Value, len(ksids))
@@ -123,6 +138,38 @@ func (*Numeric) Hash(id sqltypes.Value) ([]byte, error) {
 	return keybytes[:], nil
 }
 
+// numericKeyRange returns the key range of the numeric keyspace ids of the
+// ids from start to end, or false if there are none. A bound that isn't a
+// uint64, like a negative or a decimal number, leaves the range unbounded on
+// that side, which can only widen it.
+func numericKeyRange(start, end sqltypes.Value) (*topodatapb.KeyRange, bool) {
+	keyRange := &topodatapb.KeyRange{}
+	from := uint64(0)
+	if !start.IsNull() {
+		if num, err := start.ToCastUint64(); err == nil {
+			from = num
+			keyRange.Start = numericKeyspaceID(num)
+		}
+	}
+	if !end.IsNull() {
+		if num, err := end.ToCastUint64(); err == nil {
+			if num < from {
+				return nil, false
+			}
+			if num < math.MaxUint64 {
+				keyRange.End = numericKeyspaceID(num + 1)
+			}
+		}
+	}
+	return keyRange, true
+}
+
+func numericKeyspaceID(num uint64) []byte {
+	var keybytes [8]byte
+	binary.BigEndian.PutUint64(keybytes[:], num)
+	return keybytes[:]
+}
+
 func init() {
 	Register("numeric", newNumeric)
 }
@@ -21,13 +21,17 @@ import (
 	"context"
 	"encoding/binary"
 	"encoding/json"
+	"math"
 	"os"
+	"sort"
 	"strconv"
 
 	"vitess.io/vitess/go/sqltypes"
 	"vitess.io/vitess/go/vt/key"
 	"vitess.io/vitess/go/vt/proto/vtrpc"
 	"vitess.io/vitess/go/vt/vterrors"
+
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
 )
 
 const (
@@ -39,6 +43,7 @@ const (
 var (
 	_ SingleColumn    = (*NumericStaticMap)(nil)
 	_ Hashing         = (*NumericStaticMap)(nil)
+	_ RangeMapper     = (*NumericStaticMap)(nil)
 	_ ParamValidating = (*NumericStaticMap)(nil)
 
 	numericStaticMapParams = []string{
@@ -159,6 +164,38 @@ func (vind *NumericStaticMap) Map(ctx context.Context, vcursor VCursor, ids []sq
 	return out, nil
 }
 
+// MapRange maps the ids from start to end to the key range of the ids
+// that aren't in the lookup table, and the keyspace ids of the ones that
+// are. All the keyspace ids are returned if the ids that aren't in the
+// lookup table are hashed by a fallback vindex, since their keyspace ids
+// aren't in order.
+func (vind *NumericStaticMap) MapRange(ctx context.Context, vcursor VCursor, start, end sqltypes.Value) (key.Destination, error) {
+	if vind.hashVdx != nil {
+		return key.DestinationAllShards{}, nil
+	}
+	keyRange, ok := numericKeyRange(start, end)
+	if !ok {
+		return key.DestinationNone{}, nil
+	}
+	var lookupNums []uint64
+	for num, lookupNum := range vind.lookup {
+		if key.KeyRangeContains(keyRange, numericKeyspaceID(num)) {
+			lookupNums = append(lookupNums, lookupNum)
+		}
+	}
+	sort.Slice(lookupNums, func(i, j int) bool { return lookupNums[i] < lookupNums[j] })
+
+	keyRanges := key.DestinationKeyRanges{keyRange}
+	for _, lookupNum := range lookupNums {
+		lookupKeyRange := &topodatapb.KeyRange{Start: numericKeyspaceID(lookupNum)}
+		if lookupNum < math.MaxUint64 {
+			lookupKeyRange.End = numericKeyspaceID(lookupNum + 1)
+		}
+		keyRanges = append(keyRanges, lookupKeyRange)
+	}
+	return keyRanges, nil
+}
+
 func (vind *NumericStaticMap) Hash(id sqltypes.Value) ([]byte, error) {
 	num, err := id.ToCastUint64()
 	if err != nil {
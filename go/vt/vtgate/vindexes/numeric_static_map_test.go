@@ -290,6 +290,42 @@ func TestNumericStaticMapWithFallback(t *testing.T) {
 	}
 }
 
+func TestNumericStaticMapMapRange(t *testing.T) {
+	vindex, err := CreateVindex("numeric_static_map", t.Name(), map[string]string{
+		"json": "{\"1\":2,\"3\":100,\"4\":5,\"10\":18446744073709551615}",
+	})
+	require.NoError(t, err)
+	got, err := vindex.(RangeMapper).MapRange(context.Background(), nil, sqltypes.NewInt64(3), sqltypes.NewInt64(10))
+	require.NoError(t, err)
+	want := key.DestinationKeyRanges{{
+		Start: []byte("\x00\x00\x00\x00\x00\x00\x00\x03"),
+		End:   []byte("\x00\x00\x00\x00\x00\x00\x00\x0b"),
+	}, {
+		Start: []byte("\x00\x00\x00\x00\x00\x00\x00\x05"),
+		End:   []byte("\x00\x00\x00\x00\x00\x00\x00\x06"),
+	}, {
+		Start: []byte("\x00\x00\x00\x00\x00\x00\x00\x64"),
+		End:   []byte("\x00\x00\x00\x00\x00\x00\x00\x65"),
+	}, {
+		Start: []byte("\xff\xff\xff\xff\xff\xff\xff\xff"),
+	}}
+	assert.Equal(t, want, got)
+
+	got, err = vindex.(RangeMapper).MapRange(context.Background(), nil, sqltypes.NewInt64(10), sqltypes.NewInt64(3))
+	require.NoError(t, err)
+	assert.Equal(t, key.DestinationNone{}, got)
+
+	// The keyspace ids of the ids hashed by the fallback vindex aren't in order.
+	vindex, err = CreateVindex("numeric_static_map", t.Name(), map[string]string{
+		"json":          "{\"1\":2}",
+		"fallback_type": "xxhash",
+	})
+	require.NoError(t, err)
+	got, err = vindex.(RangeMapper).MapRange(context.Background(), nil, sqltypes.NewInt64(3), sqltypes.NewInt64(10))
+	require.NoError(t, err)
+	assert.Equal(t, key.DestinationAllShards{}, got)
+}
+
 func TestNumericStaticMapWithFallbackVerify(t *testing.T) {
 	mapWithFallbackVdx, err := CreateVindex(
 		"numeric_static_map",
@@ -18,13 +18,17 @@ package vindexes
 
 import (
 	"context"
+	"math"
 	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"vitess.io/vitess/go/sqltypes"
 	"vitess.io/vitess/go/vt/key"
+
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
 )
 
 var numeric SingleColumn
@@ -117,6 +121,69 @@ func TestNumericMap(t *testing.T) {
 	}
 }
 
+func TestNumericMapRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end sqltypes.Value
+		want       key.Destination
+	}{{
+		name:  "bounded",
+		start: sqltypes.NewInt64(2),
+		end:   sqltypes.NewInt64(5),
+		want: key.DestinationKeyRange{KeyRange: &topodatapb.KeyRange{
+			Start: []byte("\x00\x00\x00\x00\x00\x00\x00\x02"),
+			End:   []byte("\x00\x00\x00\x00\x00\x00\x00\x06"),
+		}},
+	}, {
+		name:  "unbounded start",
+		start: sqltypes.NULL,
+		end:   sqltypes.NewInt64(5),
+		want: key.DestinationKeyRange{KeyRange: &topodatapb.KeyRange{
+			End: []byte("\x00\x00\x00\x00\x00\x00\x00\x06"),
+		}},
+	}, {
+		name:  "unbounded end",
+		start: sqltypes.NewInt64(2),
+		end:   sqltypes.NULL,
+		want: key.DestinationKeyRange{KeyRange: &topodatapb.KeyRange{
+			Start: []byte("\x00\x00\x00\x00\x00\x00\x00\x02"),
+		}},
+	}, {
+		name:  "largest end",
+		start: sqltypes.NewInt64(2),
+		end:   sqltypes.NewUint64(math.MaxUint64),
+		want: key.DestinationKeyRange{KeyRange: &topodatapb.KeyRange{
+			Start: []byte("\x00\x00\x00\x00\x00\x00\x00\x02"),
+		}},
+	}, {
+		name:  "negative start",
+		start: sqltypes.NewInt64(-2),
+		end:   sqltypes.NewInt64(5),
+		want: key.DestinationKeyRange{KeyRange: &topodatapb.KeyRange{
+			End: []byte("\x00\x00\x00\x00\x00\x00\x00\x06"),
+		}},
+	}, {
+		name:  "decimal end",
+		start: sqltypes.NewInt64(2),
+		end:   sqltypes.NewFloat64(5.5),
+		want: key.DestinationKeyRange{KeyRange: &topodatapb.KeyRange{
+			Start: []byte("\x00\x00\x00\x00\x00\x00\x00\x02"),
+		}},
+	}, {
+		name:  "empty",
+		start: sqltypes.NewInt64(5),
+		end:   sqltypes.NewInt64(2),
+		want:  key.DestinationNone{},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := numeric.(RangeMapper).MapRange(context.Background(), nil, tt.start, tt.end)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestNumericVerify(t *testing.T) {
 	got, err := numeric.Verify(context.Background(), nil, []sqltypes.Value{sqltypes.NewInt64(1), sqltypes.NewInt64(2)}, [][]byte{[]byte("\x00\x00\x00\x00\x00\x00\x00\x01"), []byte("\x00\x00\x00\x00\x00\x00\x00\x01")})
 	require.NoError(t, err)
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vindexes
+
+import (
+	"time"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vterrors"
+
+	vschemapb "vitess.io/vitess/go/vt/proto/vschema"
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+)
+
+// The keys that identify the tenant of a query for a QueryQuota.
+const (
+	// QueryQuotaKeyCallerID identifies the tenant by the effective caller ID of the query.
+	QueryQuotaKeyCallerID = "caller_id"
+	// QueryQuotaKeyTag identifies the tenant by the QUOTA_TAG comment directive of the query.
+	QueryQuotaKeyTag = "tag"
+	// QueryQuotaKeyColumn identifies the tenant by the value the query gives to a column.
+	QueryQuotaKeyColumn = "column"
+)
+
+// QueryQuota limits the queries that each tenant of a keyspace sends through vtgate.
+type QueryQuota struct {
+	Name   string                 `json:"name"`
+	Key    string                 `json:"key"`
+	Column sqlparser.IdentifierCI `json:"column"`
+	// MaxQPS is the maximum number of queries per second of a tenant, or 0 for no limit.
+	MaxQPS int64 `json:"max_qps,omitempty"`
+	// MaxConcurrency is the maximum number of queries a tenant runs at the same time, or 0 for no limit.
+	MaxConcurrency int64 `json:"max_concurrency,omitempty"`
+	// QueueTimeout is how long a query over the quota waits for it before it is rejected.
+	QueueTimeout time.Duration `json:"queue_timeout,omitempty"`
+}
+
+// buildQueryQuotas validates the query quotas of the keyspace.
+func buildQueryQuotas(ks *vschemapb.Keyspace) ([]*QueryQuota, error) {
+	var quotas []*QueryQuota
+	names := make(map[string]bool, len(ks.QueryQuotas))
+	for _, quota := range ks.QueryQuotas {
+		if quota.Name == "" {
+			return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "query quota has no name")
+		}
+		if names[quota.Name] {
+			return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "duplicate query quota: %s", quota.Name)
+		}
+		names[quota.Name] = true
+		switch quota.Key {
+		case QueryQuotaKeyCallerID, QueryQuotaKeyTag:
+			if quota.Column != "" {
+				return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "column %s can only be given to query quota %s if its key is %s", quota.Column, quota.Name, QueryQuotaKeyColumn)
+			}
+		case QueryQuotaKeyColumn:
+			if quota.Column == "" {
+				return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "missing column for query quota: %s", quota.Name)
+			}
+		default:
+			return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "unknown key %q for query quota: %s", quota.Key, quota.Name)
+		}
+		if quota.MaxQps < 0 || quota.MaxConcurrency < 0 {
+			return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "negative limit for query quota: %s", quota.Name)
+		}
+		if quota.MaxQps == 0 && quota.MaxConcurrency == 0 {
+			return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "query quota %s needs max_qps or max_concurrency", quota.Name)
+		}
+		q := &QueryQuota{
+			Name:           quota.Name,
+			Key:            quota.Key,
+			Column:         sqlparser.NewIdentifierCI(quota.Column),
+			MaxQPS:         quota.MaxQps,
+			MaxConcurrency: quota.MaxConcurrency,
+		}
+		if quota.QueueTimeout != "" {
+			timeout, err := time.ParseDuration(quota.QueueTimeout)
+			if err != nil || timeout < 0 {
+				return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "invalid queue_timeout %q for query quota: %s", quota.QueueTimeout, quota.Name)
+			}
+			q.QueueTimeout = timeout
+		}
+		quotas = append(quotas, q)
+	}
+	return quotas, nil
+}
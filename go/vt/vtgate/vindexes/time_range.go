@@ -0,0 +1,293 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vindexes
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sort"
+	"strings"
+	"time"
+
+	"vitess.io/vitess/go/mysql/datetime"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/key"
+	"vitess.io/vitess/go/vt/proto/vtrpc"
+	"vitess.io/vitess/go/vt/vterrors"
+
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+)
+
+const (
+	timeRangeParamBucket = "bucket"
+	timeRangeParamRanges = "ranges"
+)
+
+var (
+	_ SingleColumn    = (*TimeRange)(nil)
+	_ Hashing         = (*TimeRange)(nil)
+	_ RangeMapper     = (*TimeRange)(nil)
+	_ ParamValidating = (*TimeRange)(nil)
+
+	timeRangeParams = []string{
+		timeRangeParamBucket,
+		timeRangeParamRanges,
+	}
+
+	// timeRangeBucketLayouts are the layouts of the first bucket of
+	// the ranges, by bucket.
+	timeRangeBucketLayouts = map[string]string{
+		"day":   "2006-01-02",
+		"month": "2006-01",
+	}
+)
+
+// timeRange is a range of time mapped to a key range.
+type timeRange struct {
+	// start is the start of the first bucket of the range, in unix
+	// seconds. The range ends where the next one starts.
+	start    int64
+	keyRange *topodatapb.KeyRange
+}
+
+// TimeRange maps dates and times to keyspace ids by ranges of time. The
+// ranges param maps the first day or month bucket of each range to a key
+// range, e.g. "2023-01:-80,2023-07:80-" with monthly buckets. The keyspace
+// id of a time is the start of the key range of its range, followed by
+// the time as a big-endian unix timestamp, in seconds, so that keyspace
+// ids are in time order within a range. Times before the first range
+// can't be mapped.
+// It's Unique, and it can map ranges of times to the key ranges of their
+// keyspace ids.
+type TimeRange struct {
+	name          string
+	ranges        []timeRange
+	unknownParams []string
+}
+
+func init() {
+	Register("time_range", newTimeRange)
+}
+
+// newTimeRange creates a TimeRange vindex.
+func newTimeRange(name string, params map[string]string) (Vindex, error) {
+	bucket := params[timeRangeParamBucket]
+	layout, ok := timeRangeBucketLayouts[bucket]
+	if !ok {
+		return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: `bucket` must be day or month, got: %q", bucket)
+	}
+	rangesParam := params[timeRangeParamRanges]
+	if rangesParam == "" {
+		return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: Could not find `ranges` param in vschema")
+	}
+
+	var ranges []timeRange
+	for _, rangeParam := range strings.Split(rangesParam, ",") {
+		firstBucket, spec, ok := strings.Cut(strings.TrimSpace(rangeParam), ":")
+		if !ok {
+			return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: invalid range %q, must be <first %s>:<key range>", rangeParam, bucket)
+		}
+		start, err := time.Parse(layout, firstBucket)
+		if err != nil {
+			return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: invalid %s %q in range %q", bucket, firstBucket, rangeParam)
+		}
+		keyRanges, err := key.ParseShardingSpec(spec)
+		if err != nil || len(keyRanges) != 1 {
+			return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: invalid key range %q in range %q", spec, rangeParam)
+		}
+		if len(ranges) > 0 && start.Unix() <= ranges[len(ranges)-1].start {
+			return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: ranges must be in time order: %q", rangesParam)
+		}
+		ranges = append(ranges, timeRange{start: start.Unix(), keyRange: keyRanges[0]})
+	}
+
+	return &TimeRange{
+		name:          name,
+		ranges:        ranges,
+		unknownParams: FindUnknownParams(params, timeRangeParams),
+	}, nil
+}
+
+// String returns the name of the vindex.
+func (vind *TimeRange) String() string {
+	return vind.name
+}
+
+// Cost returns the cost of this vindex as 1.
+func (*TimeRange) Cost() int {
+	return 1
+}
+
+// IsUnique returns true since the Vindex is unique.
+func (*TimeRange) IsUnique() bool {
+	return true
+}
+
+// NeedsVCursor satisfies the Vindex interface.
+func (*TimeRange) NeedsVCursor() bool {
+	return false
+}
+
+// Verify returns true if ids and ksids match.
+func (vind *TimeRange) Verify(ctx context.Context, vcursor VCursor, ids []sqltypes.Value, ksids [][]byte) ([]bool, error) {
+	out := make([]bool, 0, len(ids))
+	for i, id := range ids {
+		ksid, err := vind.Hash(id)
+		if err != nil {
+			out = append(out, false)
+			continue
+		}
+		out = append(out, bytes.Equal(ksid, ksids[i]))
+	}
+	return out, nil
+}
+
+// Map can map ids to key.Destination objects. NULLs and the times before
+// the first range map to no keyspace id, as no row can have them, and the
+// ids that are not dates or times are an error.
+func (vind *TimeRange) Map(ctx context.Context, vcursor VCursor, ids []sqltypes.Value) ([]key.Destination, error) {
+	out := make([]key.Destination, 0, len(ids))
+	for _, id := range ids {
+		if id.IsNull() {
+			out = append(out, key.DestinationNone{})
+			continue
+		}
+		t, err := timeRangeUnix(id)
+		if err != nil {
+			return nil, err
+		}
+		i := vind.rangeIndex(t)
+		if i < 0 {
+			out = append(out, key.DestinationNone{})
+			continue
+		}
+		out = append(out, key.DestinationKeyspaceID(timeRangeKeyspaceID(vind.ranges[i], t)))
+	}
+	return out, nil
+}
+
+// MapRange maps the times from start to end to the key ranges of the ranges
+// they span, narrowed to the keyspace ids of the times. A bound that is NULL,
+// or that is not a date or time, leaves its side of the range unbounded.
+func (vind *TimeRange) MapRange(ctx context.Context, vcursor VCursor, start, end sqltypes.Value) (key.Destination, error) {
+	from := vind.ranges[0].start
+	if !start.IsNull() {
+		if t, err := timeRangeUnix(start); err == nil {
+			from = max(from, t)
+		}
+	}
+	var to int64
+	bounded := false
+	if !end.IsNull() {
+		if t, err := timeRangeUnix(end); err == nil {
+			if t < from {
+				return key.DestinationNone{}, nil
+			}
+			to, bounded = t, true
+		}
+	}
+
+	var keyRanges key.DestinationKeyRanges
+	for i := vind.rangeIndex(from); i < len(vind.ranges); i++ {
+		r := vind.ranges[i]
+		if bounded && r.start > to {
+			break
+		}
+		keyRange := &topodatapb.KeyRange{
+			Start: timeRangeKeyspaceID(r, max(from, r.start)),
+			End:   r.keyRange.End,
+		}
+		if bounded && (i == len(vind.ranges)-1 || to < vind.ranges[i+1].start) {
+			keyRange.End = timeRangeKeyspaceID(r, to+1)
+		}
+		keyRanges = append(keyRanges, keyRange)
+	}
+	return keyRanges, nil
+}
+
+// UnknownParams implements the ParamValidating interface.
+func (vind *TimeRange) UnknownParams() []string {
+	return vind.unknownParams
+}
+
+// Hash returns the keyspace id of the time.
+func (vind *TimeRange) Hash(id sqltypes.Value) ([]byte, error) {
+	t, err := timeRangeUnix(id)
+	if err != nil {
+		return nil, err
+	}
+	i := vind.rangeIndex(t)
+	if i < 0 {
+		return nil, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: %v is before the first range", id)
+	}
+	return timeRangeKeyspaceID(vind.ranges[i], t), nil
+}
+
+// rangeIndex returns the index of the range of the time, or -1 if the time
+// is before the first range.
+func (vind *TimeRange) rangeIndex(t int64) int {
+	return sort.Search(len(vind.ranges), func(i int) bool {
+		return vind.ranges[i].start > t
+	}) - 1
+}
+
+func timeRangeKeyspaceID(r timeRange, t int64) []byte {
+	ksid := make([]byte, len(r.keyRange.Start), len(r.keyRange.Start)+8)
+	copy(ksid, r.keyRange.Start)
+	return binary.BigEndian.AppendUint64(ksid, uint64(t))
+}
+
+// timeRangeUnix returns the unix time of a date or time, in seconds. Dates
+// and times are UTC, and integers are dates or times in the YYYYMMDD or
+// YYYYMMDDhhmmss form, as MySQL compares them to dates and times.
+func timeRangeUnix(id sqltypes.Value) (int64, error) {
+	var t int64
+	switch {
+	case id.IsIntegral():
+		i, err := id.ToCastInt64()
+		if err != nil {
+			return 0, err
+		}
+		dt, ok := datetime.ParseDateTimeInt64(i)
+		if !ok {
+			d, ok := datetime.ParseDateInt64(i)
+			if !ok {
+				return 0, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: invalid date or time: %d", i)
+			}
+			dt = datetime.DateTime{Date: d}
+		}
+		t = dt.ToStdTime(time.UTC).Unix()
+	case id.IsQuoted():
+		s := id.ToString()
+		dt, _, ok := datetime.ParseDateTime(s, -1)
+		if !ok {
+			d, ok := datetime.ParseDate(s)
+			if !ok {
+				return 0, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: invalid date or time: %q", s)
+			}
+			dt = datetime.DateTime{Date: d}
+		}
+		t = dt.ToStdTime(time.UTC).Unix()
+	default:
+		return 0, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: unsupported type %v", id.Type())
+	}
+	if t < 0 {
+		return 0, vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: %v is before 1970", id)
+	}
+	return t, nil
+}
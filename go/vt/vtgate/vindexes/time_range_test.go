@@ -0,0 +1,263 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vindexes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/key"
+	"vitess.io/vitess/go/vt/proto/vtrpc"
+	"vitess.io/vitess/go/vt/vterrors"
+)
+
+func timeRangeCreateVindexTestCase(
+	testName string,
+	vindexParams map[string]string,
+	expectErr error,
+	expectUnknownParams []string,
+) createVindexTestCase {
+	return createVindexTestCase{
+		testName: testName,
+
+		vindexType:   "time_range",
+		vindexName:   "time_range",
+		vindexParams: vindexParams,
+
+		expectCost:          1,
+		expectErr:           expectErr,
+		expectIsUnique:      true,
+		expectNeedsVCursor:  false,
+		expectString:        "time_range",
+		expectUnknownParams: expectUnknownParams,
+	}
+}
+
+func TestTimeRangeCreateVindex(t *testing.T) {
+	cases := []createVindexTestCase{
+		timeRangeCreateVindexTestCase(
+			"no params",
+			nil,
+			vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: `bucket` must be day or month, got: \"\""),
+			nil,
+		),
+		timeRangeCreateVindexTestCase(
+			"invalid bucket",
+			map[string]string{"bucket": "week", "ranges": "2023-01:-"},
+			vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: `bucket` must be day or month, got: \"week\""),
+			nil,
+		),
+		timeRangeCreateVindexTestCase(
+			"no ranges",
+			map[string]string{"bucket": "month"},
+			vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: Could not find `ranges` param in vschema"),
+			nil,
+		),
+		timeRangeCreateVindexTestCase(
+			"range without key range",
+			map[string]string{"bucket": "month", "ranges": "2023-01"},
+			vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: invalid range \"2023-01\", must be <first month>:<key range>"),
+			nil,
+		),
+		timeRangeCreateVindexTestCase(
+			"invalid bucket in range",
+			map[string]string{"bucket": "day", "ranges": "2023-01:-"},
+			vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: invalid day \"2023-01\" in range \"2023-01:-\""),
+			nil,
+		),
+		timeRangeCreateVindexTestCase(
+			"invalid key range",
+			map[string]string{"bucket": "month", "ranges": "2023-01:-40-80"},
+			vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: invalid key range \"-40-80\" in range \"2023-01:-40-80\""),
+			nil,
+		),
+		timeRangeCreateVindexTestCase(
+			"ranges out of order",
+			map[string]string{"bucket": "month", "ranges": "2023-07:-80,2023-01:80-"},
+			vterrors.Errorf(vtrpc.Code_INVALID_ARGUMENT, "TimeRange: ranges must be in time order: \"2023-07:-80,2023-01:80-\""),
+			nil,
+		),
+		timeRangeCreateVindexTestCase(
+			"daily ranges",
+			map[string]string{"bucket": "day", "ranges": "2023-01-01:-80, 2023-01-15:80-"},
+			nil,
+			nil,
+		),
+		timeRangeCreateVindexTestCase(
+			"unknown params",
+			map[string]string{"bucket": "month", "ranges": "2023-01:-", "hello": "world"},
+			nil,
+			[]string{"hello"},
+		),
+	}
+
+	testCreateVindexes(t, cases)
+}
+
+func createTimeRange(t *testing.T) *TimeRange {
+	vindex, err := CreateVindex("time_range", "time_range", map[string]string{
+		"bucket": "month",
+		"ranges": "2023-01:-80,2023-07:80-",
+	})
+	require.NoError(t, err)
+	return vindex.(*TimeRange)
+}
+
+func TestTimeRangeMap(t *testing.T) {
+	timeRange := createTimeRange(t)
+	got, err := timeRange.Map(context.Background(), nil, []sqltypes.Value{
+		sqltypes.NewDatetime("2023-03-01 10:00:00"),
+		sqltypes.NewVarChar("2023-03-01"),
+		sqltypes.NewInt64(20230301100000),
+		sqltypes.NewUint64(20230301),
+		sqltypes.NewTimestamp("2023-07-01 00:00:00"),
+		sqltypes.NewDate("2023-08-15"),
+		sqltypes.NewVarChar("2022-12-31 23:59:59"),
+		sqltypes.NULL,
+	})
+	require.NoError(t, err)
+	want := []key.Destination{
+		key.DestinationKeyspaceID("\x00\x00\x00\x00\x63\xff\x22\x20"),
+		key.DestinationKeyspaceID("\x00\x00\x00\x00\x63\xfe\x95\x80"),
+		key.DestinationKeyspaceID("\x00\x00\x00\x00\x63\xff\x22\x20"),
+		key.DestinationKeyspaceID("\x00\x00\x00\x00\x63\xfe\x95\x80"),
+		key.DestinationKeyspaceID("\x80\x00\x00\x00\x00\x64\x9f\x6c\x80"),
+		key.DestinationKeyspaceID("\x80\x00\x00\x00\x00\x64\xda\xc0\x00"),
+		key.DestinationNone{},
+		key.DestinationNone{},
+	}
+	assert.Equal(t, want, got)
+
+	// The ids that are not dates or times are an error, not a destination
+	// without any row.
+	for _, id := range []sqltypes.Value{
+		sqltypes.NewVarChar("not a date"),
+		sqltypes.NewInt64(1677664800),
+		sqltypes.NewInt64(-1),
+		sqltypes.NewFloat64(1.5),
+	} {
+		_, err = timeRange.Map(context.Background(), nil, []sqltypes.Value{id})
+		assert.Error(t, err, id.String())
+	}
+}
+
+func TestTimeRangeVerify(t *testing.T) {
+	timeRange := createTimeRange(t)
+	got, err := timeRange.Verify(context.Background(), nil,
+		[]sqltypes.Value{sqltypes.NewDatetime("2023-03-01 10:00:00"), sqltypes.NewDatetime("2023-08-15 00:00:00"), sqltypes.NewVarChar("2022-01-01")},
+		[][]byte{[]byte("\x00\x00\x00\x00\x63\xff\x22\x20"), []byte("\x00\x00\x00\x00\x64\xda\xc0\x00"), nil},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false, false}, got)
+}
+
+func TestTimeRangeMapRange(t *testing.T) {
+	timeRange := createTimeRange(t)
+	tests := []struct {
+		name       string
+		start, end sqltypes.Value
+		want       key.Destination
+	}{{
+		name:  "within a range",
+		start: sqltypes.NewVarChar("2023-03-01"),
+		end:   sqltypes.NewVarChar("2023-05-01"),
+		want: key.DestinationKeyRanges{{
+			Start: []byte("\x00\x00\x00\x00\x63\xfe\x95\x80"),
+			End:   []byte("\x00\x00\x00\x00\x64\x4f\x01\x01"),
+		}},
+	}, {
+		name:  "across ranges",
+		start: sqltypes.NewVarChar("2023-03-01"),
+		end:   sqltypes.NewVarChar("2023-09-30"),
+		want: key.DestinationKeyRanges{{
+			Start: []byte("\x00\x00\x00\x00\x63\xfe\x95\x80"),
+			End:   []byte("\x80"),
+		}, {
+			Start: []byte("\x80\x00\x00\x00\x00\x64\x9f\x6c\x80"),
+			End:   []byte("\x80\x00\x00\x00\x00\x65\x17\x65\x01"),
+		}},
+	}, {
+		name:  "unbounded end",
+		start: sqltypes.NewVarChar("2023-08-15"),
+		end:   sqltypes.NULL,
+		want: key.DestinationKeyRanges{{
+			Start: []byte("\x80\x00\x00\x00\x00\x64\xda\xc0\x00"),
+		}},
+	}, {
+		name:  "unbounded start",
+		start: sqltypes.NULL,
+		end:   sqltypes.NewVarChar("2023-03-01"),
+		want: key.DestinationKeyRanges{{
+			Start: []byte("\x00\x00\x00\x00\x63\xb0\xcd\x00"),
+			End:   []byte("\x00\x00\x00\x00\x63\xfe\x95\x81"),
+		}},
+	}, {
+		name:  "unbounded",
+		start: sqltypes.NULL,
+		end:   sqltypes.NULL,
+		want: key.DestinationKeyRanges{{
+			Start: []byte("\x00\x00\x00\x00\x63\xb0\xcd\x00"),
+			End:   []byte("\x80"),
+		}, {
+			Start: []byte("\x80\x00\x00\x00\x00\x64\x9f\x6c\x80"),
+		}},
+	}, {
+		name:  "integer bounds",
+		start: sqltypes.NewInt64(20230301),
+		end:   sqltypes.NewInt64(20230501000000),
+		want: key.DestinationKeyRanges{{
+			Start: []byte("\x00\x00\x00\x00\x63\xfe\x95\x80"),
+			End:   []byte("\x00\x00\x00\x00\x64\x4f\x01\x01"),
+		}},
+	}, {
+		name:  "invalid start",
+		start: sqltypes.NewVarChar("not a date"),
+		end:   sqltypes.NewVarChar("2023-03-01"),
+		want: key.DestinationKeyRanges{{
+			Start: []byte("\x00\x00\x00\x00\x63\xb0\xcd\x00"),
+			End:   []byte("\x00\x00\x00\x00\x63\xfe\x95\x81"),
+		}},
+	}, {
+		name:  "invalid end",
+		start: sqltypes.NewVarChar("2023-08-15"),
+		end:   sqltypes.NewInt64(1693785600),
+		want: key.DestinationKeyRanges{{
+			Start: []byte("\x80\x00\x00\x00\x00\x64\xda\xc0\x00"),
+		}},
+	}, {
+		name:  "before the first range",
+		start: sqltypes.NewVarChar("2022-01-01"),
+		end:   sqltypes.NewVarChar("2022-12-31"),
+		want:  key.DestinationNone{},
+	}, {
+		name:  "empty range",
+		start: sqltypes.NewVarChar("2023-05-01"),
+		end:   sqltypes.NewVarChar("2023-03-01"),
+		want:  key.DestinationNone{},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := timeRange.MapRange(context.Background(), nil, tt.start, tt.end)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
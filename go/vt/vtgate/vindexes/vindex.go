@@ -136,6 +136,17 @@ type (
 		PrefixVindex() SingleColumn
 	}
 
+	// A RangeMapper vindex is one that maps a range of ids to the key ranges
+	// of their keyspace ids. It's being used to reduce the fan out for
+	// 'BETWEEN' expressions.
+	RangeMapper interface {
+		SingleColumn
+		// MapRange maps the ids from start to end, both included, to a
+		// key.Destination. A NULL start or end leaves the range unbounded
+		// on that side.
+		MapRange(ctx context.Context, vcursor VCursor, start, end sqltypes.Value) (key.Destination, error)
+	}
+
 	// A Lookup vindex is one that needs to lookup
 	// a previously stored map to compute the keyspace
 	// id from an id. This means that the creation of
@@ -118,6 +118,10 @@ type Table struct {
 
 	ChildForeignKeys  []ChildFKInfo  `json:"child_foreign_keys,omitempty"`
 	ParentForeignKeys []ParentFKInfo `json:"parent_foreign_keys,omitempty"`
+
+	// ResultCacheTTL is the longest time vtgate caches the result of
+	// the read-only queries on this table. They aren't cached if it is 0.
+	ResultCacheTTL time.Duration `json:"result_cache_ttl,omitempty"`
 }
 
 // GetTableName gets the sqlparser.TableName for the vindex Table.
@@ -198,6 +202,7 @@ type KeyspaceSchema struct {
 	Tables         map[string]*Table
 	Vindexes       map[string]Vindex
 	Views          map[string]sqlparser.SelectStatement
+	QueryQuotas    []*QueryQuota
 	Error          error
 }
 
@@ -207,6 +212,7 @@ type ksJSON struct {
 	Tables         map[string]*Table `json:"tables,omitempty"`
 	Vindexes       map[string]Vindex `json:"vindexes,omitempty"`
 	Views          map[string]string `json:"views,omitempty"`
+	QueryQuotas    []*QueryQuota     `json:"query_quotas,omitempty"`
 	Error          string            `json:"error,omitempty"`
 }
 
@@ -240,6 +246,7 @@ func (ks *KeyspaceSchema) MarshalJSON() ([]byte, error) {
 		Tables:         ks.Tables,
 		ForeignKeyMode: ks.ForeignKeyMode.String(),
 		Vindexes:       ks.Vindexes,
+		QueryQuotas:    ks.QueryQuotas,
 	}
 	if ks.Error != nil {
 		ksJ.Error = ks.Error.Error()
@@ -332,6 +339,9 @@ func buildKeyspaces(source *vschemapb.SrvVSchema, vschema *VSchema) {
 		}
 		vschema.Keyspaces[ksname] = ksvschema
 		ksvschema.Error = buildTables(ks, vschema, ksvschema)
+		if ksvschema.Error == nil {
+			ksvschema.QueryQuotas, ksvschema.Error = buildQueryQuotas(ks)
+		}
 	}
 }
 
@@ -576,6 +586,18 @@ func buildTables(ks *vschemapb.Keyspace, vschema *VSchema, ksvschema *KeyspaceSc
 				table.Type,
 			)
 		}
+		if table.ResultCacheTtl != "" {
+			ttl, err := time.ParseDuration(table.ResultCacheTtl)
+			if err != nil || ttl < 0 {
+				return vterrors.Errorf(
+					vtrpcpb.Code_INVALID_ARGUMENT,
+					"invalid result_cache_ttl %q for table: %s",
+					table.ResultCacheTtl,
+					tname,
+				)
+			}
+			t.ResultCacheTTL = ttl
+		}
 		if table.Pinned != "" {
 			decoded, err := hex.DecodeString(table.Pinned)
 			if err != nil {
@@ -700,7 +722,8 @@ func buildTables(ks *vschemapb.Keyspace, vschema *VSchema, ksvschema *KeyspaceSc
 			if !isMultiColumn {
 				continue
 			}
-			if i != 0 {
+			if _, isLookup := vindex.(Lookup); i != 0 && !isLookup {
+				// Only lookup vindexes can be secondary multi-column vindexes.
 				return vterrors.Errorf(
 					vtrpcpb.Code_UNIMPLEMENTED,
 					"multi-column vindex %s should be a primary vindex for table %s",
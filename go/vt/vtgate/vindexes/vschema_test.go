@@ -24,6 +24,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -493,6 +494,108 @@ func TestVSchemaColumnsFail(t *testing.T) {
 	require.EqualError(t, got.Keyspaces["unsharded"].Error, "duplicate column name 'c1' for table: t1")
 }
 
+func TestVSchemaResultCacheTTL(t *testing.T) {
+	good := vschemapb.SrvVSchema{
+		Keyspaces: map[string]*vschemapb.Keyspace{
+			"unsharded": {
+				Tables: map[string]*vschemapb.Table{
+					"t1": {ResultCacheTtl: "1m30s"},
+					"t2": {}}}}}
+
+	got := BuildVSchema(&good)
+	require.NoError(t, got.Keyspaces["unsharded"].Error)
+	t1, err := got.FindTable("unsharded", "t1")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, t1.ResultCacheTTL)
+	t2, err := got.FindTable("unsharded", "t2")
+	require.NoError(t, err)
+	assert.Zero(t, t2.ResultCacheTTL)
+
+	bad := vschemapb.SrvVSchema{
+		Keyspaces: map[string]*vschemapb.Keyspace{
+			"unsharded": {
+				Tables: map[string]*vschemapb.Table{
+					"t1": {ResultCacheTtl: "soon"}}}}}
+
+	got = BuildVSchema(&bad)
+	require.EqualError(t, got.Keyspaces["unsharded"].Error, "invalid result_cache_ttl \"soon\" for table: t1")
+}
+
+func TestVSchemaQueryQuotas(t *testing.T) {
+	good := vschemapb.SrvVSchema{
+		Keyspaces: map[string]*vschemapb.Keyspace{
+			"sharded": {
+				Sharded: true,
+				QueryQuotas: []*vschemapb.QueryQuota{{
+					Name:         "tenant",
+					Key:          "column",
+					Column:       "Tenant_ID",
+					MaxQps:       100,
+					QueueTimeout: "500ms",
+				}, {
+					Name:           "caller",
+					Key:            "caller_id",
+					MaxConcurrency: 10,
+				}}}}}
+
+	got := BuildVSchema(&good)
+	require.NoError(t, got.Keyspaces["sharded"].Error)
+	want := []*QueryQuota{{
+		Name:         "tenant",
+		Key:          QueryQuotaKeyColumn,
+		Column:       sqlparser.NewIdentifierCI("Tenant_ID"),
+		MaxQPS:       100,
+		QueueTimeout: 500 * time.Millisecond,
+	}, {
+		Name:           "caller",
+		Key:            QueryQuotaKeyCallerID,
+		MaxConcurrency: 10,
+	}}
+	utils.MustMatch(t, want, got.Keyspaces["sharded"].QueryQuotas)
+
+	testcases := []struct {
+		quota   *vschemapb.QueryQuota
+		wantErr string
+	}{{
+		quota:   &vschemapb.QueryQuota{Key: "tag", MaxQps: 1},
+		wantErr: "query quota has no name",
+	}, {
+		quota:   &vschemapb.QueryQuota{Name: "q", Key: "user", MaxQps: 1},
+		wantErr: "unknown key \"user\" for query quota: q",
+	}, {
+		quota:   &vschemapb.QueryQuota{Name: "q", Key: "column", MaxQps: 1},
+		wantErr: "missing column for query quota: q",
+	}, {
+		quota:   &vschemapb.QueryQuota{Name: "q", Key: "tag", Column: "c", MaxQps: 1},
+		wantErr: "column c can only be given to query quota q if its key is column",
+	}, {
+		quota:   &vschemapb.QueryQuota{Name: "q", Key: "tag"},
+		wantErr: "query quota q needs max_qps or max_concurrency",
+	}, {
+		quota:   &vschemapb.QueryQuota{Name: "q", Key: "tag", MaxConcurrency: -1},
+		wantErr: "negative limit for query quota: q",
+	}, {
+		quota:   &vschemapb.QueryQuota{Name: "q", Key: "tag", MaxQps: 1, QueueTimeout: "soon"},
+		wantErr: "invalid queue_timeout \"soon\" for query quota: q",
+	}}
+	for _, tc := range testcases {
+		bad := vschemapb.SrvVSchema{
+			Keyspaces: map[string]*vschemapb.Keyspace{
+				"sharded": {QueryQuotas: []*vschemapb.QueryQuota{tc.quota}}}}
+		got := BuildVSchema(&bad)
+		assert.EqualError(t, got.Keyspaces["sharded"].Error, tc.wantErr)
+	}
+
+	duplicate := vschemapb.SrvVSchema{
+		Keyspaces: map[string]*vschemapb.Keyspace{
+			"sharded": {QueryQuotas: []*vschemapb.QueryQuota{
+				{Name: "q", Key: "tag", MaxQps: 1},
+				{Name: "q", Key: "caller_id", MaxQps: 1},
+			}}}}
+	got = BuildVSchema(&duplicate)
+	require.EqualError(t, got.Keyspaces["sharded"].Error, "duplicate query quota: q")
+}
+
 func TestVSchemaPinned(t *testing.T) {
 	good := vschemapb.SrvVSchema{
 		Keyspaces: map[string]*vschemapb.Keyspace{
@@ -71,6 +71,8 @@ var (
 	maxPayloadSize  int
 	warnPayloadSize int
 
+	cteMaxRecursionDepth = 1000
+
 	noScatter          bool
 	enableShardRouting bool
 
@@ -122,6 +124,7 @@ func registerFlags(fs *pflag.FlagSet) {
 	fs.Int64Var(&queryPlanCacheMemory, "gate_query_cache_memory", queryPlanCacheMemory, "gate server query cache size in bytes, maximum amount of memory to be cached. vtgate analyzes every incoming query and generate a query plan, these plans are being cached in a lru cache. This config controls the capacity of the lru cache.")
 	fs.IntVar(&maxMemoryRows, "max_memory_rows", maxMemoryRows, "Maximum number of rows that will be held in memory for intermediate results as well as the final result.")
 	fs.IntVar(&warnMemoryRows, "warn_memory_rows", warnMemoryRows, "Warning threshold for in-memory results. A row count higher than this amount will cause the VtGateWarnings.ResultsExceeded counter to be incremented.")
+	fs.IntVar(&cteMaxRecursionDepth, "cte-max-recursion-depth", cteMaxRecursionDepth, "Maximum number of iterations vtgate runs when evaluating a recursive common table expression, similar to MySQL's cte_max_recursion_depth.")
 	fs.StringVar(&defaultDDLStrategy, "ddl_strategy", defaultDDLStrategy, "Set default strategy for DDL statements. Override with @@ddl_strategy session variable")
 	fs.StringVar(&dbDDLPlugin, "dbddl_plugin", dbDDLPlugin, "controls how to handle CREATE/DROP DATABASE. use it if you are using your own database provisioning service")
 	fs.BoolVar(&noScatter, "no_scatter", noScatter, "when set to true, the planner will fail instead of producing a plan that includes scatter queries")
@@ -330,6 +333,18 @@ func Init(
 		st.RegisterSignalReceiver(executor.vm.Rebuild)
 	}
 
+	// invalidate the cached results of the tables whose schema or rows change
+	if executor.resultCache != nil {
+		if enableSchemaChangeSignal {
+			st.RegisterTablesChangedReceiver(func(keyspace string, tables []string) {
+				executor.resultCache.invalidateTables("SchemaTracker", keyspace, tables)
+			})
+		}
+		for _, keyspace := range resultCacheInvalidationKeyspaces {
+			go executor.resultCache.watchRowEvents(ctx, vsm, keyspace)
+		}
+	}
+
 	// TODO: call serv.WatchSrvVSchema here
 
 	vtgateInst := newVTGate(executor, resolver, vsm, tc, gw)
@@ -446,6 +461,7 @@ func (vtg *VTGate) Execute(ctx context.Context, mysqlCtx vtgateservice.MySQLConn
 		err = vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "%v", bvErr)
 	} else {
 		safeSession := NewSafeSession(session)
+		safeSession.SetQueryAttributes(queryAttributesFromContext(ctx))
 		qr, err = vtg.executor.Execute(ctx, mysqlCtx, "Execute", safeSession, sql, bindVariables)
 		safeSession.RemoveInternalSavepoint()
 	}
@@ -502,6 +518,7 @@ func (vtg *VTGate) StreamExecute(ctx context.Context, mysqlCtx vtgateservice.MyS
 	defer vtg.timings.Record(statsKey, time.Now())
 
 	safeSession := NewSafeSession(session)
+	safeSession.SetQueryAttributes(queryAttributesFromContext(ctx))
 	var err error
 	if bvErr := sqltypes.ValidateBindVariables(bindVariables); bvErr != nil {
 		err = vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "%v", bvErr)
@@ -585,6 +602,11 @@ func (vtg *VTGate) VSchemaStats() *VSchemaStats {
 	return vtg.executor.VSchemaStats()
 }
 
+// QueryQuotasStatus returns the status of the query quotas for display on the status page.
+func (vtg *VTGate) QueryQuotasStatus() []*QueryQuotaStatus {
+	return vtg.executor.QueryQuotasStatus()
+}
+
 func truncateErrorStrings(data map[string]any) map[string]any {
 	ret := map[string]any{}
 	if terseErrors {
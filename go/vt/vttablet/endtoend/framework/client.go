@@ -274,7 +274,7 @@ func (client *QueryClient) Stream(query string, bindvars map[string]*querypb.Bin
 
 // MessageStream streams messages from the message table.
 func (client *QueryClient) MessageStream(name string, callback func(*sqltypes.Result) error) (err error) {
-	return client.server.MessageStream(client.ctx, client.target, name, callback)
+	return client.server.MessageStream(client.ctx, client.target, name, "", callback)
 }
 
 // MessageAck acks messages
@@ -286,7 +286,7 @@ func (client *QueryClient) MessageAck(name string, ids []string) (int64, error)
 			Value: []byte(id),
 		})
 	}
-	return client.server.MessageAck(client.ctx, client.target, name, bids)
+	return client.server.MessageAck(client.ctx, client.target, name, "", bids)
 }
 
 // ReserveExecute performs a ReserveExecute.
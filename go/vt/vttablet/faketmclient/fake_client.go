@@ -343,6 +343,17 @@ func (client *FakeTabletManagerClient) RestoreFromBackup(ctx context.Context, ta
 	return &eofEventStream{}, nil
 }
 
+type eofVerifyBackupStream struct{}
+
+func (e *eofVerifyBackupStream) Recv() (*tabletmanagerdatapb.VerifyBackupResponse, error) {
+	return nil, io.EOF
+}
+
+// VerifyBackup is part of the tmclient.TabletManagerClient interface.
+func (client *FakeTabletManagerClient) VerifyBackup(ctx context.Context, tablet *topodatapb.Tablet, req *tabletmanagerdatapb.VerifyBackupRequest) (tmclient.VerifyBackupStream, error) {
+	return &eofVerifyBackupStream{}, nil
+}
+
 // Throttler related methods
 
 func (client *FakeTabletManagerClient) CheckThrottler(ctx context.Context, tablet *topodatapb.Tablet, request *tabletmanagerdatapb.CheckThrottlerRequest) (*tabletmanagerdatapb.CheckThrottlerResponse, error) {
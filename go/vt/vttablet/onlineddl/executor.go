@@ -2196,6 +2196,7 @@ func (e *Executor) scheduleNextMigration(ctx context.Context) error {
 		postponeCompletion := row.AsBool("postpone_completion", false)
 		readyToComplete := row.AsBool("ready_to_complete", false)
 		isImmediateOperation := row.AsBool("is_immediate_operation", false)
+		strategySetting := schema.NewDDLStrategySetting(schema.DDLStrategy(row["strategy"].ToString()), row["options"].ToString())
 
 		if postponeLaunch {
 			// We don't even look into this migration until its postpone_launch flag is cleared
@@ -2213,6 +2214,16 @@ func (e *Executor) scheduleNextMigration(ctx context.Context) error {
 			}
 		}
 
+		if isImmediateOperation {
+			// An immediate operation completes as soon as it runs, so it runs only within its cut-over window
+			inWindow, err := e.isInCutOverWindow(ctx, uuid, strategySetting)
+			if err != nil {
+				return err
+			}
+			if !inWindow {
+				continue
+			}
+		}
 		if !(isImmediateOperation && postponeCompletion) {
 			// Any non-postponed migration can be scheduled
 			// postponed ALTER can be scheduled (because gh-ost or vreplication will postpone the cut-over)
@@ -2375,6 +2386,14 @@ func (e *Executor) reviewQueuedMigrations(ctx context.Context) error {
 				e.failMigration(ctx, onlineDDL, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "--allow-zero-in-date not supported in 'mysql' strategy"))
 			}
 		}
+		switch onlineDDL.Strategy {
+		case schema.DDLStrategyMySQL, schema.DDLStrategyGhost, schema.DDLStrategyPTOSC:
+			// These strategies cut over on their own terms. Only immediate operations, which vitess runs
+			// by itself, can wait for a cut-over window.
+			if window, _ := onlineDDL.StrategySetting().CutOverWindow(); window != nil && !isImmediate {
+				e.failMigration(ctx, onlineDDL, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "--cut-over-window not supported in '%s' strategy", onlineDDL.Strategy))
+			}
+		}
 
 		// The review is complete. We've backfilled details on the migration row. We mark
 		// the migration as having been reviewed. The function scheduleNextMigration() will then
@@ -3530,6 +3549,17 @@ func (e *Executor) reviewRunningMigrations(ctx context.Context) (countRunnning i
 						// override. Even if migration is ready, we do not complete it.
 						isReady = false
 					}
+					if isReady {
+						// The cut-over only takes place within the cut-over window. A failed cut-over is
+						// retried on the next review: within the window, or else on the next window.
+						inWindow, err := e.isInCutOverWindow(ctx, uuid, onlineDDL.StrategySetting())
+						if err != nil {
+							return countRunnning, cancellable, err
+						}
+						if !inWindow {
+							isReady = false
+						}
+					}
 					if isReady && onlineDDL.StrategySetting().IsInOrderCompletion() {
 						if len(pendingMigrationsUUIDs) > 0 && pendingMigrationsUUIDs[0] != onlineDDL.UUID {
 							// wait for earlier pending migrations to complete
@@ -4191,6 +4221,31 @@ func (e *Executor) updateMigrationLastThrottled(ctx context.Context, uuid string
 	return err
 }
 
+// isInCutOverWindow returns true when the migration may cut over now, i.e. when it has no cut-over window,
+// or when now is within its window. It records the start of the window the migration is eligible to cut over in.
+func (e *Executor) isInCutOverWindow(ctx context.Context, uuid string, strategySetting *schema.DDLStrategySetting) (bool, error) {
+	window, err := strategySetting.CutOverWindow()
+	if err != nil {
+		return false, err
+	}
+	if window == nil {
+		return true, nil
+	}
+	now := time.Now()
+	nextStart := window.NextStart(now)
+	query, err := sqlparser.ParseAndBind(sqlUpdateNextCutOverWindow,
+		sqltypes.Int64BindVariable(nextStart.Unix()),
+		sqltypes.StringBindVariable(uuid),
+	)
+	if err != nil {
+		return false, err
+	}
+	if _, err := e.execQuery(ctx, query); err != nil {
+		return false, err
+	}
+	return window.Contains(now), nil
+}
+
 func (e *Executor) updateMigrationTableRows(ctx context.Context, uuid string, tableRows int64) error {
 	query, err := sqlparser.ParseAndBind(sqlUpdateMigrationTableRows,
 		sqltypes.Int64BindVariable(tableRows),
@@ -46,6 +46,8 @@ const (
 			ddl_action,
 			is_view,
 			is_immediate_operation,
+			strategy,
+			options,
 			postpone_launch,
 			postpone_completion,
 			ready_to_complete
@@ -163,6 +165,11 @@ const (
 		WHERE
 			migration_uuid=%a
 	`
+	sqlUpdateNextCutOverWindow = `UPDATE _vt.schema_migrations
+			SET next_cutover_window_timestamp=FROM_UNIXTIME(%a)
+		WHERE
+			migration_uuid=%a
+	`
 	sqlUpdateReadyForCleanup = `UPDATE _vt.schema_migrations
 			SET retain_artifacts_seconds=-1
 		WHERE
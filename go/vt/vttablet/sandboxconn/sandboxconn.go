@@ -101,6 +101,8 @@ type SandboxConn struct {
 	ReadTransactionResults []*querypb.TransactionMetadata
 
 	MessageIDs []*querypb.Value
+	// MessageConsumerGroup is the consumer group of the last message stream or ack.
+	MessageConsumerGroup string
 
 	// vstream expectations.
 	StartPos      string
@@ -397,21 +399,26 @@ func (sbc *SandboxConn) BeginStreamExecute(ctx context.Context, target *querypb.
 }
 
 // MessageStream is part of the QueryService interface.
-func (sbc *SandboxConn) MessageStream(ctx context.Context, target *querypb.Target, name string, callback func(*sqltypes.Result) error) (err error) {
+// It streams all the results that were set, if any.
+func (sbc *SandboxConn) MessageStream(ctx context.Context, target *querypb.Target, name, consumerGroup string, callback func(*sqltypes.Result) error) (err error) {
+	sbc.MessageConsumerGroup = consumerGroup
 	if err := sbc.getError(); err != nil {
 		return err
 	}
-	r := sbc.getNextResult(nil)
-	if r == nil {
-		return nil
+	for {
+		if err := callback(sbc.getNextResult(nil)); err != nil {
+			return err
+		}
+		if len(sbc.results) == 0 {
+			return nil
+		}
 	}
-	callback(r)
-	return nil
 }
 
 // MessageAck is part of the QueryService interface.
-func (sbc *SandboxConn) MessageAck(ctx context.Context, target *querypb.Target, name string, ids []*querypb.Value) (count int64, err error) {
+func (sbc *SandboxConn) MessageAck(ctx context.Context, target *querypb.Target, name, consumerGroup string, ids []*querypb.Value) (count int64, err error) {
 	sbc.MessageIDs = ids
+	sbc.MessageConsumerGroup = consumerGroup
 	return int64(len(ids)), nil
 }
 
@@ -588,6 +588,9 @@ var (
 	// MessageName is a test message name.
 	MessageName = "vitess_message"
 
+	// MessageConsumerGroup is a test consumer group.
+	MessageConsumerGroup = "vitess_consumer_group"
+
 	// MessageStreamResult is a test stream result.
 	MessageStreamResult = &sqltypes.Result{
 		Fields: []*querypb.Field{{
@@ -614,7 +617,7 @@ var (
 )
 
 // MessageStream is part of the queryservice.QueryService interface
-func (f *FakeQueryService) MessageStream(ctx context.Context, target *querypb.Target, name string, callback func(*sqltypes.Result) error) (err error) {
+func (f *FakeQueryService) MessageStream(ctx context.Context, target *querypb.Target, name, consumerGroup string, callback func(*sqltypes.Result) error) (err error) {
 	if f.HasError {
 		return f.TabletError
 	}
@@ -624,6 +627,9 @@ func (f *FakeQueryService) MessageStream(ctx context.Context, target *querypb.Ta
 	if name != MessageName {
 		f.t.Errorf("name: %s, want %s", name, MessageName)
 	}
+	if consumerGroup != MessageConsumerGroup {
+		f.t.Errorf("consumerGroup: %s, want %s", consumerGroup, MessageConsumerGroup)
+	}
 	if err := callback(MessageStreamResult); err != nil {
 		f.t.Logf("MessageStream callback failed: %v", err)
 	}
@@ -631,7 +637,7 @@ func (f *FakeQueryService) MessageStream(ctx context.Context, target *querypb.Ta
 }
 
 // MessageAck is part of the queryservice.QueryService interface
-func (f *FakeQueryService) MessageAck(ctx context.Context, target *querypb.Target, name string, ids []*querypb.Value) (count int64, err error) {
+func (f *FakeQueryService) MessageAck(ctx context.Context, target *querypb.Target, name, consumerGroup string, ids []*querypb.Value) (count int64, err error) {
 	if f.HasError {
 		return 0, f.TabletError
 	}
@@ -641,6 +647,9 @@ func (f *FakeQueryService) MessageAck(ctx context.Context, target *querypb.Targe
 	if name != MessageName {
 		f.t.Errorf("name: %s, want %s", name, MessageName)
 	}
+	if consumerGroup != MessageConsumerGroup {
+		f.t.Errorf("consumerGroup: %s, want %s", consumerGroup, MessageConsumerGroup)
+	}
 	if !sqltypes.Proto3ValuesEqual(ids, MessageIDs) {
 		f.t.Errorf("ids: %v, want %v", ids, MessageIDs)
 	}
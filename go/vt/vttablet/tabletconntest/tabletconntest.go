@@ -812,7 +812,7 @@ func testMessageStream(t *testing.T, conn queryservice.QueryService, f *FakeQuer
 	ctx := context.Background()
 	ctx = callerid.NewContext(ctx, TestCallerID, TestVTGateCallerID)
 	var got *sqltypes.Result
-	err := conn.MessageStream(ctx, TestTarget, MessageName, func(qr *sqltypes.Result) error {
+	err := conn.MessageStream(ctx, TestTarget, MessageName, MessageConsumerGroup, func(qr *sqltypes.Result) error {
 		got = qr
 		return nil
 	})
@@ -829,7 +829,7 @@ func testMessageStreamError(t *testing.T, conn queryservice.QueryService, f *Fak
 	f.HasError = true
 	testErrorHelper(t, f, "MessageStream", func(ctx context.Context) error {
 		ctx = callerid.NewContext(ctx, TestCallerID, TestVTGateCallerID)
-		return conn.MessageStream(ctx, TestTarget, MessageName, func(qr *sqltypes.Result) error { return nil })
+		return conn.MessageStream(ctx, TestTarget, MessageName, MessageConsumerGroup, func(qr *sqltypes.Result) error { return nil })
 	})
 	f.HasError = false
 }
@@ -837,7 +837,7 @@ func testMessageStreamError(t *testing.T, conn queryservice.QueryService, f *Fak
 func testMessageStreamPanics(t *testing.T, conn queryservice.QueryService, f *FakeQueryService) {
 	t.Log("testMessageStreamPanics")
 	testPanicHelper(t, f, "MessageStream", func(ctx context.Context) error {
-		err := conn.MessageStream(ctx, TestTarget, MessageName, func(qr *sqltypes.Result) error { return nil })
+		err := conn.MessageStream(ctx, TestTarget, MessageName, MessageConsumerGroup, func(qr *sqltypes.Result) error { return nil })
 		return err
 	})
 }
@@ -846,7 +846,7 @@ func testMessageAck(t *testing.T, conn queryservice.QueryService, f *FakeQuerySe
 	t.Log("testMessageAck")
 	ctx := context.Background()
 	ctx = callerid.NewContext(ctx, TestCallerID, TestVTGateCallerID)
-	count, err := conn.MessageAck(ctx, TestTarget, MessageName, MessageIDs)
+	count, err := conn.MessageAck(ctx, TestTarget, MessageName, MessageConsumerGroup, MessageIDs)
 	if err != nil {
 		t.Fatalf("MessageAck failed: %v", err)
 	}
@@ -860,7 +860,7 @@ func testMessageAckError(t *testing.T, conn queryservice.QueryService, f *FakeQu
 	f.HasError = true
 	testErrorHelper(t, f, "MessageAck", func(ctx context.Context) error {
 		ctx = callerid.NewContext(ctx, TestCallerID, TestVTGateCallerID)
-		_, err := conn.MessageAck(ctx, TestTarget, MessageName, MessageIDs)
+		_, err := conn.MessageAck(ctx, TestTarget, MessageName, MessageConsumerGroup, MessageIDs)
 		return err
 	})
 	f.HasError = false
@@ -869,7 +869,7 @@ func testMessageAckError(t *testing.T, conn queryservice.QueryService, f *FakeQu
 func testMessageAckPanics(t *testing.T, conn queryservice.QueryService, f *FakeQueryService) {
 	t.Log("testMessageAckPanics")
 	testPanicHelper(t, f, "MessageAck", func(ctx context.Context) error {
-		_, err := conn.MessageAck(ctx, TestTarget, MessageName, MessageIDs)
+		_, err := conn.MessageAck(ctx, TestTarget, MessageName, MessageConsumerGroup, MessageIDs)
 		return err
 	})
 }
@@ -283,12 +283,12 @@ func (ftc *fakeTabletConn) BeginStreamExecute(ctx context.Context, target *query
 }
 
 // fakeTabletConn implements the QueryService interface.
-func (ftc *fakeTabletConn) MessageStream(ctx context.Context, target *querypb.Target, name string, callback func(*sqltypes.Result) error) error {
+func (ftc *fakeTabletConn) MessageStream(ctx context.Context, target *querypb.Target, name, consumerGroup string, callback func(*sqltypes.Result) error) error {
 	return nil
 }
 
 // fakeTabletConn implements the QueryService interface.
-func (ftc *fakeTabletConn) MessageAck(ctx context.Context, target *querypb.Target, name string, ids []*querypb.Value) (count int64, err error) {
+func (ftc *fakeTabletConn) MessageAck(ctx context.Context, target *querypb.Target, name, consumerGroup string, ids []*querypb.Value) (count int64, err error) {
 	return 0, nil
 }
 
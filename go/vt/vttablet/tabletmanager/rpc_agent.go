@@ -141,6 +141,8 @@ type RPCTM interface {
 
 	RestoreFromBackup(ctx context.Context, logger logutil.Logger, request *tabletmanagerdatapb.RestoreFromBackupRequest) error
 
+	VerifyBackup(ctx context.Context, logger logutil.Logger, request *tabletmanagerdatapb.VerifyBackupRequest) (*tabletmanagerdatapb.BackupVerification, error)
+
 	// HandleRPCPanic is to be called in a defer statement in each
 	// RPC input point.
 	HandleRPCPanic(ctx context.Context, name string, args, reply any, verbose bool, err *error)
@@ -19,8 +19,10 @@ package tabletmanager
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
+	"vitess.io/vitess/go/mysql/replication"
 	"vitess.io/vitess/go/vt/topotools"
 	"vitess.io/vitess/go/vt/vtctl/reparentutil"
 
@@ -32,6 +34,7 @@ import (
 
 	tabletmanagerdatapb "vitess.io/vitess/go/vt/proto/tabletmanagerdata"
 	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
 )
 
 const (
@@ -197,6 +200,60 @@ func (tm *TabletManager) RestoreFromBackup(ctx context.Context, logger logutil.L
 	return err
 }
 
+// VerifyBackup restores a backup into a scratch mysqld, started next to the
+// one of the tablet, and checks the restored data. The data of the tablet
+// isn't touched.
+func (tm *TabletManager) VerifyBackup(ctx context.Context, logger logutil.Logger, req *tabletmanagerdatapb.VerifyBackupRequest) (*tabletmanagerdatapb.BackupVerification, error) {
+	if tm.Cnf == nil {
+		return nil, fmt.Errorf("cannot verify a backup without my.cnf, please restart vttablet with a my.cnf file specified")
+	}
+	if req.BackupName == "" {
+		return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "a backup name is required")
+	}
+	concurrency := int(req.Concurrency)
+	if concurrency <= 0 {
+		concurrency = restoreConcurrency
+	}
+	tablet := tm.Tablet()
+
+	// Create the logger: tee to console and source.
+	l := logutil.NewTeeLogger(logutil.NewConsoleLogger(), logger)
+
+	mysqld, cnf, err := mysqlctl.CreateScratchMysqldAndMycnf(tm.Cnf, tm.DBConfigs)
+	if err != nil {
+		return nil, vterrors.Wrap(err, "failed to create the scratch mysqld")
+	}
+	defer func() {
+		if err := mysqld.Shutdown(context.Background(), cnf, true); err != nil {
+			l.Errorf("Failed to shut down the scratch mysqld: %v", err)
+		}
+		mysqld.Close()
+		if err := os.RemoveAll(cnf.TabletDir()); err != nil {
+			l.Errorf("Failed to remove the scratch mysqld directory %v: %v", cnf.TabletDir(), err)
+		}
+	}()
+
+	verification, err := mysqlctl.VerifyBackup(ctx, mysqlctl.VerifyBackupParams{
+		Cnf:          cnf,
+		Mysqld:       mysqld,
+		Logger:       l,
+		Concurrency:  concurrency,
+		HookExtraEnv: tm.hookExtraEnv(),
+		Keyspace:     tablet.Keyspace,
+		Shard:        tablet.Shard,
+		BackupName:   req.BackupName,
+		Stats:        backupstats.RestoreStats(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tabletmanagerdatapb.BackupVerification{
+		Passed:   verification.Passed,
+		Position: replication.EncodePosition(verification.Position),
+		Failures: verification.Failures,
+	}, nil
+}
+
 func (tm *TabletManager) beginBackup(backupMode string) error {
 	tm.mutex.Lock()
 	defer tm.mutex.Unlock()
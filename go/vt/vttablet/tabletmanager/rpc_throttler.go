@@ -23,6 +23,7 @@ import (
 	"vitess.io/vitess/go/vt/proto/vtrpc"
 	"vitess.io/vitess/go/vt/vterrors"
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/throttle"
+	"vitess.io/vitess/go/vt/vttablet/tabletserver/throttle/base"
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/throttle/throttlerapp"
 )
 
@@ -35,6 +36,9 @@ func (tm *TabletManager) CheckThrottler(ctx context.Context, req *tabletmanagerd
 		LowPriority:           false,
 		SkipRequestHeartbeats: true,
 	}
+	if req.MetricName != "" {
+		flags.MetricNames = []base.MetricName{base.MetricName(req.MetricName)}
+	}
 	checkResult := tm.QueryServiceControl.CheckThrottler(ctx, req.AppName, flags)
 	if checkResult == nil {
 		return nil, vterrors.Errorf(vtrpc.Code_INTERNAL, "nil checkResult")
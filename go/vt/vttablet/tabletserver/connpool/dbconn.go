@@ -57,6 +57,9 @@ type DBConn struct {
 	setting      string
 	resetSetting string
 
+	// slot is the workload class slot the conn was admitted with by its pool.
+	slot *workloadSlot
+
 	// err will be set if a query is killed through a Kill.
 	errmu sync.Mutex
 	err   error
@@ -398,9 +401,14 @@ func (dbc *DBConn) Recycle() {
 	case dbc.pool == nil:
 		dbc.Close()
 	case dbc.conn.IsClosed():
-		dbc.pool.Put(nil)
+		pool, slot := dbc.pool, dbc.takeSlot()
+		pool.Put(nil)
+		pool.admission.release(slot)
 	default:
-		dbc.pool.Put(dbc)
+		// Once it is put back, the conn belongs to the next get.
+		pool, slot := dbc.pool, dbc.takeSlot()
+		pool.Put(dbc)
+		pool.admission.release(slot)
 	}
 }
 
@@ -409,8 +417,17 @@ func (dbc *DBConn) Taint() {
 	if dbc.pool == nil {
 		return
 	}
-	dbc.pool.Put(nil)
+	pool, slot := dbc.pool, dbc.takeSlot()
 	dbc.pool = nil
+	pool.Put(nil)
+	pool.admission.release(slot)
+}
+
+// takeSlot clears and returns the workload class slot of the conn.
+func (dbc *DBConn) takeSlot() *workloadSlot {
+	slot := dbc.slot
+	dbc.slot = nil
+	return slot
 }
 
 // Kill kills the currently executing query both on MySQL side
@@ -70,6 +70,7 @@ type Pool struct {
 	dbaPool            *dbconnpool.ConnectionPool
 	appDebugParams     dbconfigs.Connector
 	getConnTime        *servenv.TimingsWrapper
+	admission          *workloadAdmission
 }
 
 // NewPool creates a new Pool. The name is used
@@ -112,6 +113,22 @@ func NewPool(env tabletenv.Env, name string, cfg tabletenv.ConnPoolConfig) *Pool
 	return cp
 }
 
+// AdmitWorkloadClasses makes the pool admit its gets by workload class:
+// each class can use the slots reserved for it and the shared slots, and the
+// gets wait for a free slot in order of the priority of their class. It must
+// be called before Open, and does nothing if there are no classes.
+func (cp *Pool) AdmitWorkloadClasses(classes, reservedSlots tabletenv.WorkloadClassMap) {
+	if len(classes) == 0 {
+		return
+	}
+	cp.admission = newWorkloadAdmission(cp.capacity, classes, reservedSlots)
+	if cp.name == "" {
+		return
+	}
+	cp.env.Exporter().NewGaugesFuncWithMultiLabels(cp.name+"WorkloadClassInUse", "Tablet server conn pool slots in use by workload class", []string{"WorkloadClass"}, cp.admission.inUse)
+	cp.env.Exporter().NewGaugesFuncWithMultiLabels(cp.name+"WorkloadClassWaiting", "Tablet server conn pool gets waiting for a slot by workload class", []string{"WorkloadClass"}, cp.admission.waiting)
+}
+
 func (cp *Pool) pool() (p pools.IResourcePool) {
 	cp.mu.Lock()
 	p = cp.connections
@@ -212,8 +229,16 @@ func (cp *Pool) Get(ctx context.Context, setting *pools.Setting) (*DBConn, error
 	}
 
 	start := time.Now()
+	var slot *workloadSlot
+	if cp.admission != nil {
+		var err error
+		if slot, err = cp.admission.admit(ctx, workloadClassFromContext(ctx)); err != nil {
+			return nil, err
+		}
+	}
 	r, err := p.Get(ctx, setting)
 	if err != nil {
+		cp.admission.release(slot)
 		return nil, err
 	}
 	if cp.getConnTime != nil {
@@ -223,7 +248,9 @@ func (cp *Pool) Get(ctx context.Context, setting *pools.Setting) (*DBConn, error
 			cp.getConnTime.Record(getWithS, start)
 		}
 	}
-	return r.(*DBConn), nil
+	conn := r.(*DBConn)
+	conn.slot = slot
+	return conn, nil
 }
 
 // Put puts a connection into the pool.
@@ -249,6 +276,9 @@ func (cp *Pool) SetCapacity(capacity int) (err error) {
 			return err
 		}
 	}
+	if cp.admission != nil {
+		cp.admission.setCapacity(capacity)
+	}
 	cp.capacity = capacity
 	return nil
 }
@@ -113,6 +113,42 @@ func TestConnPoolMaxWaiters(t *testing.T) {
 	wg.Wait()
 }
 
+func TestConnPoolWorkloadClasses(t *testing.T) {
+	db := fakesqldb.New(t)
+	defer db.Close()
+	cfg := tabletenv.ConnPoolConfig{
+		Size: 2,
+	}
+	_ = cfg.TimeoutSeconds.Set("100ms")
+	connPool := NewPool(tabletenv.NewEnv(nil, "PoolTest"), "TestPool", cfg)
+	connPool.AdmitWorkloadClasses(tabletenv.WorkloadClassMap{"oltp": 0, "batch": 50}, tabletenv.WorkloadClassMap{"oltp": 1})
+	connPool.Open(db.ConnParams(), db.ConnParams(), db.ConnParams())
+	defer connPool.Close()
+
+	oltpCtx := NewWorkloadClassContext(context.Background(), "oltp")
+	batchCtx := NewWorkloadClassContext(context.Background(), "batch")
+
+	// A surge of batch queries only gets the shared slot.
+	batchConn, err := connPool.Get(batchCtx, nil)
+	require.NoError(t, err)
+	_, err = connPool.Get(batchCtx, nil)
+	assert.EqualError(t, err, "resource pool timed out")
+
+	oltpConn, err := connPool.Get(oltpCtx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"oltp": 1, "batch": 1, unclassified: 0}, connPool.admission.inUse())
+
+	// Tainted and recycled conns give their slots back.
+	oltpConn.Taint()
+	oltpConn.Close()
+	batchConn.Recycle()
+	assert.Equal(t, map[string]int64{"oltp": 0, "batch": 0, unclassified: 0}, connPool.admission.inUse())
+
+	batchConn, err = connPool.Get(batchCtx, nil)
+	require.NoError(t, err)
+	batchConn.Recycle()
+}
+
 func TestConnPoolGetEmptyDebugConfig(t *testing.T) {
 	db := fakesqldb.New(t)
 	debugConn := db.ConnParamsWithUname("")
@@ -0,0 +1,234 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connpool
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"vitess.io/vitess/go/pools"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vttablet/tabletserver/tabletenv"
+)
+
+// unclassified is the class of the gets without a workload class, or with
+// a workload class the tablet doesn't know.
+const unclassified = "unclassified"
+
+type workloadClassKey struct{}
+
+// NewWorkloadClassContext returns a context whose connection gets are
+// admitted to the pools as the given workload class.
+func NewWorkloadClassContext(ctx context.Context, class string) context.Context {
+	if class == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, workloadClassKey{}, strings.ToLower(class))
+}
+
+func workloadClassFromContext(ctx context.Context) string {
+	class, _ := ctx.Value(workloadClassKey{}).(string)
+	return class
+}
+
+// workloadAdmission admits the gets of a Pool by workload class. Each class
+// can use the slots reserved for it, and all the classes share the slots of
+// the pool capacity that are not reserved. When a get finds no free slot,
+// it waits behind the gets of classes with a higher priority, and then in
+// arrival order.
+type workloadAdmission struct {
+	mu           sync.Mutex
+	capacity     int
+	reserved     int
+	shared       int
+	classes      map[string]*workloadClass
+	unclassified *workloadClass
+	waiters      []*workloadWaiter
+}
+
+type workloadClass struct {
+	name     string
+	priority int
+	reserved int
+	// inUse counts the reserved slots in use, shared the shared ones.
+	inUse   int
+	shared  int
+	waiting int
+}
+
+// workloadSlot is a pool slot admitted to a get.
+type workloadSlot struct {
+	class    *workloadClass
+	reserved bool
+}
+
+type workloadWaiter struct {
+	class *workloadClass
+	ready chan *workloadSlot
+}
+
+func newWorkloadAdmission(capacity int, classes, reservedSlots tabletenv.WorkloadClassMap) *workloadAdmission {
+	wa := &workloadAdmission{
+		capacity: capacity,
+		classes:  make(map[string]*workloadClass, len(classes)),
+		unclassified: &workloadClass{
+			name:     unclassified,
+			priority: sqlparser.MaxPriorityValue,
+		},
+	}
+	for name, priority := range classes {
+		class := &workloadClass{
+			name:     name,
+			priority: priority,
+			reserved: reservedSlots[name],
+		}
+		wa.classes[name] = class
+		wa.reserved += class.reserved
+	}
+	return wa
+}
+
+func (wa *workloadAdmission) class(name string) *workloadClass {
+	if class, ok := wa.classes[name]; ok {
+		return class
+	}
+	return wa.unclassified
+}
+
+// admit returns a slot of the pool for the given workload class, waiting
+// for one until the context is done.
+func (wa *workloadAdmission) admit(ctx context.Context, name string) (*workloadSlot, error) {
+	wa.mu.Lock()
+	class := wa.class(name)
+	if slot := wa.tryAdmit(class); slot != nil {
+		wa.mu.Unlock()
+		return slot, nil
+	}
+	w := &workloadWaiter{
+		class: class,
+		ready: make(chan *workloadSlot, 1),
+	}
+	// Waiters of the same priority are admitted in arrival order.
+	i := sort.Search(len(wa.waiters), func(i int) bool {
+		return wa.waiters[i].class.priority > class.priority
+	})
+	wa.waiters = append(wa.waiters, nil)
+	copy(wa.waiters[i+1:], wa.waiters[i:])
+	wa.waiters[i] = w
+	class.waiting++
+	wa.mu.Unlock()
+
+	select {
+	case slot := <-w.ready:
+		return slot, nil
+	case <-ctx.Done():
+	}
+
+	wa.mu.Lock()
+	for i, waiter := range wa.waiters {
+		if waiter == w {
+			wa.waiters = append(wa.waiters[:i], wa.waiters[i+1:]...)
+			class.waiting--
+			wa.mu.Unlock()
+			return nil, pools.ErrTimeout
+		}
+	}
+	wa.mu.Unlock()
+	// The slot was admitted while the context was done.
+	wa.release(<-w.ready)
+	return nil, pools.ErrTimeout
+}
+
+// tryAdmit returns a free slot for the class, preferring the slots reserved
+// for it, or nil if there are none. It must be called with mu held.
+func (wa *workloadAdmission) tryAdmit(class *workloadClass) *workloadSlot {
+	if class.inUse < class.reserved {
+		class.inUse++
+		return &workloadSlot{class: class, reserved: true}
+	}
+	if wa.shared < wa.capacity-wa.reserved {
+		wa.shared++
+		class.shared++
+		return &workloadSlot{class: class}
+	}
+	return nil
+}
+
+// release returns the slot, and admits the waiters that can use it.
+func (wa *workloadAdmission) release(slot *workloadSlot) {
+	if wa == nil || slot == nil {
+		return
+	}
+	wa.mu.Lock()
+	defer wa.mu.Unlock()
+	if slot.reserved {
+		slot.class.inUse--
+	} else {
+		slot.class.shared--
+		wa.shared--
+	}
+	wa.admitWaiters()
+}
+
+// setCapacity changes the number of slots of the pool.
+func (wa *workloadAdmission) setCapacity(capacity int) {
+	wa.mu.Lock()
+	defer wa.mu.Unlock()
+	wa.capacity = capacity
+	wa.admitWaiters()
+}
+
+// admitWaiters admits the waiters, in order, that have a free slot. It must
+// be called with mu held.
+func (wa *workloadAdmission) admitWaiters() {
+	waiters := wa.waiters[:0]
+	for _, w := range wa.waiters {
+		if slot := wa.tryAdmit(w.class); slot != nil {
+			w.class.waiting--
+			w.ready <- slot
+			continue
+		}
+		waiters = append(waiters, w)
+	}
+	for i := len(waiters); i < len(wa.waiters); i++ {
+		wa.waiters[i] = nil
+	}
+	wa.waiters = waiters
+}
+
+// inUse returns the number of slots in use by each workload class.
+func (wa *workloadAdmission) inUse() map[string]int64 {
+	return wa.stats(func(class *workloadClass) int { return class.inUse + class.shared })
+}
+
+// waiting returns the number of gets waiting for a slot by workload class.
+func (wa *workloadAdmission) waiting() map[string]int64 {
+	return wa.stats(func(class *workloadClass) int { return class.waiting })
+}
+
+func (wa *workloadAdmission) stats(f func(*workloadClass) int) map[string]int64 {
+	wa.mu.Lock()
+	defer wa.mu.Unlock()
+	stats := make(map[string]int64, len(wa.classes)+1)
+	for name, class := range wa.classes {
+		stats[name] = int64(f(class))
+	}
+	stats[unclassified] = int64(f(wa.unclassified))
+	return stats
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/pools"
+	"vitess.io/vitess/go/vt/vttablet/tabletserver/tabletenv"
+)
+
+func TestWorkloadClassContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, ctx, NewWorkloadClassContext(ctx, ""))
+	assert.Equal(t, "", workloadClassFromContext(ctx))
+	assert.Equal(t, "batch", workloadClassFromContext(NewWorkloadClassContext(ctx, "Batch")))
+}
+
+func TestWorkloadAdmissionReservedSlots(t *testing.T) {
+	ctx := context.Background()
+	wa := newWorkloadAdmission(3, tabletenv.WorkloadClassMap{"oltp": 0, "batch": 50}, tabletenv.WorkloadClassMap{"oltp": 2})
+
+	// batch can only use the shared slot.
+	batch, err := wa.admit(ctx, "batch")
+	require.NoError(t, err)
+	assert.False(t, batch.reserved)
+	_, err = admitWithTimeout(wa, "batch")
+	assert.Equal(t, pools.ErrTimeout, err)
+
+	// oltp still has its reserved slots.
+	oltp1, err := wa.admit(ctx, "oltp")
+	require.NoError(t, err)
+	assert.True(t, oltp1.reserved)
+	oltp2, err := wa.admit(ctx, "oltp")
+	require.NoError(t, err)
+	assert.True(t, oltp2.reserved)
+	_, err = admitWithTimeout(wa, "oltp")
+	assert.Equal(t, pools.ErrTimeout, err)
+
+	assert.Equal(t, map[string]int64{"oltp": 2, "batch": 1, unclassified: 0}, wa.inUse())
+	assert.Equal(t, map[string]int64{"oltp": 0, "batch": 0, unclassified: 0}, wa.waiting())
+
+	// A released reserved slot is not shared.
+	wa.release(oltp1)
+	_, err = admitWithTimeout(wa, "batch")
+	assert.Equal(t, pools.ErrTimeout, err)
+	_, err = admitWithTimeout(wa, "")
+	assert.Equal(t, pools.ErrTimeout, err)
+
+	wa.release(oltp2)
+	wa.release(batch)
+	assert.Equal(t, map[string]int64{"oltp": 0, "batch": 0, unclassified: 0}, wa.inUse())
+}
+
+func TestWorkloadAdmissionPriority(t *testing.T) {
+	ctx := context.Background()
+	wa := newWorkloadAdmission(1, tabletenv.WorkloadClassMap{"oltp": 0, "batch": 50}, nil)
+
+	slot, err := wa.admit(ctx, "batch")
+	require.NoError(t, err)
+
+	// Waiters queue up in order of priority, unclassified last.
+	admitted := make(chan string, 4)
+	waitFor := func(class string, waiters int) {
+		go func() {
+			slot, err := wa.admit(ctx, class)
+			if !assert.NoError(t, err) {
+				return
+			}
+			admitted <- class
+			wa.release(slot)
+		}()
+		assert.Eventually(t, func() bool {
+			wa.mu.Lock()
+			defer wa.mu.Unlock()
+			return len(wa.waiters) == waiters
+		}, 5*time.Second, time.Millisecond)
+	}
+	waitFor("", 1)
+	waitFor("batch", 2)
+	waitFor("unknown", 3)
+	waitFor("oltp", 4)
+	assert.Equal(t, map[string]int64{"oltp": 1, "batch": 1, unclassified: 2}, wa.waiting())
+
+	wa.release(slot)
+	var order []string
+	for i := 0; i < 4; i++ {
+		order = append(order, <-admitted)
+	}
+	assert.Equal(t, []string{"oltp", "batch", "", "unknown"}, order)
+}
+
+func TestWorkloadAdmissionSetCapacity(t *testing.T) {
+	ctx := context.Background()
+	wa := newWorkloadAdmission(1, tabletenv.WorkloadClassMap{"batch": 50}, nil)
+
+	slot, err := wa.admit(ctx, "batch")
+	require.NoError(t, err)
+
+	admitted := make(chan *workloadSlot)
+	go func() {
+		slot, err := wa.admit(ctx, "batch")
+		assert.NoError(t, err)
+		admitted <- slot
+	}()
+	assert.Eventually(t, func() bool {
+		return wa.waiting()["batch"] == 1
+	}, 5*time.Second, time.Millisecond)
+
+	wa.setCapacity(2)
+	wa.release(<-admitted)
+	wa.release(slot)
+	assert.Equal(t, int64(0), wa.inUse()["batch"])
+}
+
+func admitWithTimeout(wa *workloadAdmission, class string) (*workloadSlot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	return wa.admit(ctx, class)
+}
@@ -18,6 +18,7 @@ package messager
 
 import (
 	"container/heap"
+	"slices"
 	"sync"
 
 	"vitess.io/vitess/go/vt/log"
@@ -36,11 +37,20 @@ type MessageRow struct {
 	TimeAcked int64
 	Row       []sqltypes.Value
 
+	// AckedConsumerGroups are the consumer groups that
+	// already acked the message, as far as we know.
+	AckedConsumerGroups []string
+
 	// defunct is set if the row was asked to be removed
 	// from cache.
 	defunct bool
 }
 
+// ackedBy returns true if consumerGroup acked the message.
+func (mr *MessageRow) ackedBy(consumerGroup string) bool {
+	return slices.Contains(mr.AckedConsumerGroups, consumerGroup)
+}
+
 type messageHeap []*MessageRow
 
 func (mh messageHeap) Len() int {
@@ -39,7 +39,9 @@ import (
 type TabletService interface {
 	tabletenv.Env
 	PostponeMessages(ctx context.Context, target *querypb.Target, querygen QueryGenerator, ids []string) (count int64, err error)
+	HoldMessages(ctx context.Context, target *querypb.Target, querygen QueryGenerator, ids []string) (count int64, err error)
 	PurgeMessages(ctx context.Context, target *querypb.Target, querygen QueryGenerator, timeCutoff int64) (count int64, err error)
+	DeadLetterMessages(ctx context.Context, target *querypb.Target, querygen QueryGenerator, ids []string) (count int64, err error)
 }
 
 // VStreamer defines  the functions of VStreamer
@@ -118,14 +120,15 @@ func (me *Engine) GetGenerator(name string) (QueryGenerator, error) {
 	return mm, nil
 }
 
-// Subscribe subscribes to messages from the requested table.
+// Subscribe subscribes to messages from the requested table,
+// as a member of consumerGroup if the table has consumer groups.
 // The function returns a done channel that will be closed when
 // the subscription ends, which can be initiated by the send function
 // returning io.EOF. The engine can also end a subscription which is
 // usually triggered by Close. It's the responsibility of the send
 // function to promptly return if the done channel is closed. Otherwise,
 // the engine's Close function will hang indefinitely.
-func (me *Engine) Subscribe(ctx context.Context, name string, send func(*sqltypes.Result) error) (done <-chan struct{}, err error) {
+func (me *Engine) Subscribe(ctx context.Context, name, consumerGroup string, send func(*sqltypes.Result) error) (done <-chan struct{}, err error) {
 	me.mu.Lock()
 	defer me.mu.Unlock()
 	if !me.isOpen {
@@ -135,7 +138,10 @@ func (me *Engine) Subscribe(ctx context.Context, name string, send func(*sqltype
 	if mm == nil {
 		return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "message table %s not found", name)
 	}
-	return mm.Subscribe(ctx, send), nil
+	if _, err := mm.getConsumerGroup(consumerGroup); err != nil {
+		return nil, err
+	}
+	return mm.Subscribe(ctx, consumerGroup, send), nil
 }
 
 func (me *Engine) schemaChanged(tables map[string]*schema.Table, created, altered, dropped []*schema.Table) {
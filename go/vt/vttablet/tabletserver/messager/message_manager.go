@@ -22,11 +22,13 @@ import (
 	"fmt"
 	"io"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/sync/semaphore"
 
+	"vitess.io/vitess/go/constants/sidecar"
 	"vitess.io/vitess/go/mysql/replication"
 
 	"vitess.io/vitess/go/sqltypes"
@@ -35,7 +37,9 @@ import (
 	"vitess.io/vitess/go/vt/log"
 	binlogdatapb "vitess.io/vitess/go/vt/proto/binlogdata"
 	querypb "vitess.io/vitess/go/vt/proto/query"
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
 	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vterrors"
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/schema"
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/tabletenv"
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/throttle/throttlerapp"
@@ -51,8 +55,11 @@ var (
 
 type QueryGenerator interface {
 	GenerateAckQuery(ids []string) (string, map[string]*querypb.BindVariable)
+	GenerateConsumerGroupAckQueries(consumerGroup string, ids []string) ([]*querypb.BoundQuery, error)
 	GeneratePostponeQuery(ids []string) (string, map[string]*querypb.BindVariable)
+	GenerateHoldQuery(ids []string) (string, map[string]*querypb.BindVariable)
 	GeneratePurgeQuery(timeCutoff int64) (string, map[string]*querypb.BindVariable)
+	GenerateDeadLetterQueries(ids []string) []*querypb.BoundQuery
 }
 
 type messageReceiver struct {
@@ -96,8 +103,18 @@ func (rcv *messageReceiver) Send(qr *sqltypes.Result) error {
 // that the busy flag is controlled by the messageManager
 // mutex.
 type receiverWithStatus struct {
-	receiver *messageReceiver
-	busy     bool
+	receiver      *messageReceiver
+	consumerGroup *consumerGroup
+	busy          bool
+}
+
+// consumerGroup is the set of receivers that share the messages
+// of a consumer group: each message is sent to one of them.
+// Message tables without consumer groups have a single unnamed one.
+type consumerGroup struct {
+	name        string
+	receivers   []*receiverWithStatus
+	curReceiver int
 }
 
 // messageManager manages messages for a message table.
@@ -163,6 +180,23 @@ type receiverWithStatus struct {
 // If, for some reason, a client is closed, the load balancer resets
 // by starting with the first non-busy client.
 //
+// Consumer groups
+// If the table has consumer groups, every client is a member of one of
+// them, and each message is sent to one client of every group that has
+// clients, with the load balancing above happening within each group.
+// A group acks messages by recording them in the message_acks sidecar
+// table, and a message is acked once every group acked it. A resent
+// message is only sent to the groups that haven't acked it yet. A message
+// is only postponed, which counts as an attempt, if it was sent to a group.
+// A message that none of the groups with clients need any more is held
+// for the ack wait time instead, until the groups that haven't acked it
+// get clients.
+//
+// Dead-lettering
+// If the table has a max number of attempts, messages that have already
+// been sent that many times are moved to the dead-letter table instead of
+// being sent again.
+//
 // The Purge thread
 // This thread is mostly independent. It wakes up periodically
 // to delete old rows that were successfully acked.
@@ -177,6 +211,7 @@ type messageManager struct {
 	minBackoff   time.Duration
 	maxBackoff   time.Duration
 	batchSize    int
+	maxAttempts  int
 	pollerTicks  *timer.Timer
 	purgeTicks   *timer.Timer
 	postponeSema *semaphore.Weighted
@@ -188,7 +223,7 @@ type messageManager struct {
 	cond            sync.Cond
 	cache           *cache
 	receivers       []*receiverWithStatus
-	curReceiver     int
+	consumerGroups  []*consumerGroup
 	messagesPending bool
 	// streamCancel is set when a vstream is running, and is reset
 	// to nil after a cancel. This allows for startVStream and stopVStream
@@ -235,7 +270,18 @@ type messageManager struct {
 	readByPriorityAndTimeNext *sqlparser.ParsedQuery
 	ackQuery                  *sqlparser.ParsedQuery
 	postponeQuery             *sqlparser.ParsedQuery
+	holdQuery                 *sqlparser.ParsedQuery
 	purgeQuery                *sqlparser.ParsedQuery
+
+	// The queries of the tables with consumer groups.
+	consumerGroupAckQuery      *sqlparser.ParsedQuery
+	consumerGroupsAckedQuery   *sqlparser.ParsedQuery
+	consumerGroupAckPurgeQuery *sqlparser.ParsedQuery
+
+	// The queries of the tables with a dead-letter table.
+	deadLetterQuery         *sqlparser.ParsedQuery
+	deadLetterAckPurgeQuery *sqlparser.ParsedQuery
+	deadLetterDeleteQuery   *sqlparser.ParsedQuery
 }
 
 // newMessageManager creates a new message manager.
@@ -254,6 +300,7 @@ func newMessageManager(tsv TabletService, vs VStreamer, table *schema.Table, pos
 		minBackoff:      table.MessageInfo.MinBackoff,
 		maxBackoff:      table.MessageInfo.MaxBackoff,
 		batchSize:       table.MessageInfo.BatchSize,
+		maxAttempts:     table.MessageInfo.MaxAttempts,
 		cache:           newCache(table.MessageInfo.CacheSize),
 		pollerTicks:     timer.NewTimer(table.MessageInfo.PollInterval),
 		purgeTicks:      timer.NewTimer(table.MessageInfo.PollInterval),
@@ -261,6 +308,12 @@ func newMessageManager(tsv TabletService, vs VStreamer, table *schema.Table, pos
 		messagesPending: true,
 	}
 	mm.cond.L = &mm.mu
+	if len(table.MessageInfo.ConsumerGroups) == 0 {
+		mm.consumerGroups = []*consumerGroup{{}}
+	}
+	for _, name := range table.MessageInfo.ConsumerGroups {
+		mm.consumerGroups = append(mm.consumerGroups, &consumerGroup{name: name})
+	}
 
 	columnList := buildSelectColumnList(table)
 	vsQuery := fmt.Sprintf("select priority, time_next, epoch, time_acked, %s from %v", columnList, mm.name)
@@ -270,11 +323,19 @@ func newMessageManager(tsv TabletService, vs VStreamer, table *schema.Table, pos
 			Filter: vsQuery,
 		}},
 	}
-	mm.readByPriorityAndTimeNext = sqlparser.BuildParsedQuery(
-		// There should be a poller_idx defined on (time_acked, priority, time_next desc)
-		// for this to be as effecient as possible
-		"select priority, time_next, epoch, time_acked, %s from %v where time_acked is null and time_next < %a order by priority, time_next desc limit %a",
-		columnList, mm.name, ":time_next", ":max")
+	ackTable := sidecar.GetIdentifier() + ".message_acks"
+	if mm.hasConsumerGroups() {
+		// The groups that acked a message are appended to its columns.
+		mm.readByPriorityAndTimeNext = sqlparser.BuildParsedQuery(
+			"select priority, time_next, epoch, time_acked, %s, (select group_concat(consumer_group) from %s where table_name = %a and id = cast(%v.id as binary)) from %v where time_acked is null and time_next < %a order by priority, time_next desc limit %a",
+			columnList, ackTable, ":table_name", mm.name, mm.name, ":time_next", ":max")
+	} else {
+		mm.readByPriorityAndTimeNext = sqlparser.BuildParsedQuery(
+			// There should be a poller_idx defined on (time_acked, priority, time_next desc)
+			// for this to be as effecient as possible
+			"select priority, time_next, epoch, time_acked, %s from %v where time_acked is null and time_next < %a order by priority, time_next desc limit %a",
+			columnList, mm.name, ":time_next", ":max")
+	}
 	mm.ackQuery = sqlparser.BuildParsedQuery(
 		"update %v set time_acked = %a, time_next = null where id in %a and time_acked is null",
 		mm.name, ":time_acked", "::ids")
@@ -282,6 +343,35 @@ func newMessageManager(tsv TabletService, vs VStreamer, table *schema.Table, pos
 		"delete from %v where time_acked < %a limit 500", mm.name, ":time_acked")
 
 	mm.postponeQuery = buildPostponeQuery(mm.name, mm.minBackoff, mm.maxBackoff)
+	mm.holdQuery = sqlparser.BuildParsedQuery(
+		"update %v set time_next = %a + %a where id in %a and time_acked is null",
+		mm.name, ":time_now", ":wait_time", "::ids")
+
+	if mm.hasConsumerGroups() {
+		mm.consumerGroupAckQuery = sqlparser.BuildParsedQuery(
+			"insert ignore into %s(table_name, id, consumer_group, time_acked) select %a, cast(id as binary), %a, %a from %v where id in %a and time_acked is null",
+			ackTable, ":table_name", ":consumer_group", ":time_acked", mm.name, "::ids")
+		// A message is acked once all the groups acked it, at which point its
+		// group acks aren't needed any more.
+		mm.consumerGroupsAckedQuery = sqlparser.BuildParsedQuery(
+			"update %v set time_acked = %a, time_next = null where id in %a and time_acked is null and (select count(*) from %s where table_name = %a and id = cast(%v.id as binary) and consumer_group in %a) = %a",
+			mm.name, ":time_acked", "::ids", ackTable, ":table_name", mm.name, "::consumer_groups", ":consumer_group_count")
+		mm.consumerGroupAckPurgeQuery = sqlparser.BuildParsedQuery(
+			"delete from %s where table_name = %a and id in (select cast(id as binary) from %v where id in %a and time_acked is not null)",
+			ackTable, ":table_name", mm.name, "::ids")
+	}
+
+	if table.MessageInfo.DeadLetterTable != "" {
+		allColumnList := buildColumnList(table.Fields)
+		mm.deadLetterQuery = sqlparser.BuildParsedQuery(
+			"insert into %v(%s) select %s from %v where id in %a and time_acked is null",
+			sqlparser.NewIdentifierCS(table.MessageInfo.DeadLetterTable), allColumnList, allColumnList, mm.name, "::ids")
+		mm.deadLetterAckPurgeQuery = sqlparser.BuildParsedQuery(
+			"delete from %s where table_name = %a and id in (select cast(id as binary) from %v where id in %a)",
+			ackTable, ":table_name", mm.name, "::ids")
+		mm.deadLetterDeleteQuery = sqlparser.BuildParsedQuery(
+			"delete from %v where id in %a and time_acked is null", mm.name, "::ids")
+	}
 
 	return mm
 }
@@ -335,8 +425,13 @@ func buildPostponeQuery(name sqlparser.IdentifierCS, minBackoff, maxBackoff time
 // buildSelectColumnList is a convenience function that
 // builds a 'select' list for the user-defined columns.
 func buildSelectColumnList(t *schema.Table) string {
+	return buildColumnList(t.MessageInfo.Fields)
+}
+
+// buildColumnList builds a column list for fields.
+func buildColumnList(fields []*querypb.Field) string {
 	buf := sqlparser.NewTrackedBuffer(nil)
-	for i, c := range t.MessageInfo.Fields {
+	for i, c := range fields {
 		// Column names may have to be escaped.
 		if i == 0 {
 			buf.Myprintf("%v", sqlparser.NewIdentifierCI(c.Name))
@@ -355,7 +450,9 @@ func (mm *messageManager) Open() {
 		return
 	}
 	mm.isOpen = true
-	mm.curReceiver = -1
+	for _, cg := range mm.consumerGroups {
+		cg.curReceiver = -1
+	}
 
 	mm.wg.Add(1)
 	go mm.runSend() // calls the offsetting mm.wg.Done()
@@ -384,6 +481,10 @@ func (mm *messageManager) Close() {
 		rcvr.receiver.cancel()
 	}
 	mm.receivers = nil
+	for _, cg := range mm.consumerGroups {
+		cg.receivers = nil
+		cg.curReceiver = -1
+	}
 	MessageStats.Set([]string{mm.name.String(), "ClientCount"}, 0)
 	log.Infof("messageManager - clearing cache")
 	mm.cache.Clear()
@@ -399,11 +500,37 @@ func (mm *messageManager) Close() {
 	log.Infof("messageManager - closed")
 }
 
+// hasConsumerGroups returns true if the table has consumer groups.
+func (mm *messageManager) hasConsumerGroups() bool {
+	return mm.consumerGroups[0].name != ""
+}
+
+// getConsumerGroup returns the consumer group subscribers and acks
+// refer to by name, which must be empty if the table has no consumer
+// groups.
+func (mm *messageManager) getConsumerGroup(name string) (*consumerGroup, error) {
+	if !mm.hasConsumerGroups() {
+		if name != "" {
+			return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "message table %s has no consumer groups", mm.name)
+		}
+		return mm.consumerGroups[0], nil
+	}
+	if name == "" {
+		return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "message table %s has consumer groups: a consumer group must be specified", mm.name)
+	}
+	for _, cg := range mm.consumerGroups {
+		if cg.name == name {
+			return cg, nil
+		}
+	}
+	return nil, vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "consumer group %s not found for message table %s", name, mm.name)
+}
+
 // Subscribe registers the send function as a receiver of messages
-// and returns a 'done' channel that will be closed when the subscription
-// ends. There are many reasons for a subscription to end: a grpc context
-// cancel or timeout, or tabletserver shutdown, etc.
-func (mm *messageManager) Subscribe(ctx context.Context, send func(*sqltypes.Result) error) <-chan struct{} {
+// for consumerGroup and returns a 'done' channel that will be closed
+// when the subscription ends. There are many reasons for a subscription
+// to end: a grpc context cancel or timeout, or tabletserver shutdown, etc.
+func (mm *messageManager) Subscribe(ctx context.Context, consumerGroup string, send func(*sqltypes.Result) error) <-chan struct{} {
 	receiver, done := newMessageReceiver(ctx, send)
 
 	mm.mu.Lock()
@@ -412,6 +539,12 @@ func (mm *messageManager) Subscribe(ctx context.Context, send func(*sqltypes.Res
 		receiver.cancel()
 		return done
 	}
+	cg, err := mm.getConsumerGroup(consumerGroup)
+	if err != nil {
+		log.Errorf("Terminating connection: %v", err)
+		receiver.cancel()
+		return done
+	}
 
 	if err := receiver.Send(mm.fieldResult); err != nil {
 		log.Errorf("Terminating connection due to error sending field info: %v", err)
@@ -420,15 +553,17 @@ func (mm *messageManager) Subscribe(ctx context.Context, send func(*sqltypes.Res
 	}
 
 	withStatus := &receiverWithStatus{
-		receiver: receiver,
+		receiver:      receiver,
+		consumerGroup: cg,
 	}
 	if len(mm.receivers) == 0 {
 		mm.startVStream()
 	}
 	mm.receivers = append(mm.receivers, withStatus)
+	cg.receivers = append(cg.receivers, withStatus)
 	MessageStats.Set([]string{mm.name.String(), "ClientCount"}, int64(len(mm.receivers)))
-	if mm.curReceiver == -1 {
-		mm.rescanReceivers(-1)
+	if cg.curReceiver == -1 {
+		mm.rescanReceivers(cg, -1)
 	}
 
 	// Track the context and unsubscribe if it gets cancelled.
@@ -447,14 +582,19 @@ func (mm *messageManager) unsubscribe(receiver *messageReceiver) {
 			continue
 		}
 		// Delete the item at current position.
-		n := len(mm.receivers)
-		copy(mm.receivers[i:n-1], mm.receivers[i+1:n])
-		mm.receivers = mm.receivers[0 : n-1]
+		mm.receivers = deleteReceiver(mm.receivers, i)
 		MessageStats.Set([]string{mm.name.String(), "ClientCount"}, int64(len(mm.receivers)))
+		cg := rcv.consumerGroup
+		for j, cgrcv := range cg.receivers {
+			if cgrcv == rcv {
+				cg.receivers = deleteReceiver(cg.receivers, j)
+				break
+			}
+		}
+		// curReceiver is obsolete. Recompute.
+		mm.rescanReceivers(cg, -1)
 		break
 	}
-	// curReceiver is obsolete. Recompute.
-	mm.rescanReceivers(-1)
 	// If there are no receivers. Shut down the cache.
 	if len(mm.receivers) == 0 {
 		mm.stopVStream()
@@ -462,26 +602,60 @@ func (mm *messageManager) unsubscribe(receiver *messageReceiver) {
 	}
 }
 
-// rescanReceivers finds the next available receiver
-// using start as the starting point. If one was found,
-// it sets curReceiver to that index. If curReceiver
-// was previously -1, it broadcasts. If none was found,
-// curReceiver is set to -1. If there's no starting point,
-// it must be specified as -1.
-func (mm *messageManager) rescanReceivers(start int) {
+func deleteReceiver(receivers []*receiverWithStatus, i int) []*receiverWithStatus {
+	n := len(receivers)
+	copy(receivers[i:n-1], receivers[i+1:n])
+	return receivers[0 : n-1]
+}
+
+// rescanReceivers finds the next available receiver of
+// the consumer group using start as the starting point.
+// If one was found, it sets curReceiver to that index.
+// If curReceiver was previously -1, it broadcasts. If
+// none was found, curReceiver is set to -1. If there's
+// no starting point, it must be specified as -1.
+func (mm *messageManager) rescanReceivers(cg *consumerGroup, start int) {
 	cur := start
-	for range mm.receivers {
-		cur = (cur + 1) % len(mm.receivers)
-		if !mm.receivers[cur].busy {
-			if mm.curReceiver == -1 {
+	for range cg.receivers {
+		cur = (cur + 1) % len(cg.receivers)
+		if !cg.receivers[cur].busy {
+			if cg.curReceiver == -1 {
 				mm.cond.Broadcast()
 			}
-			mm.curReceiver = cur
+			cg.curReceiver = cur
 			return
 		}
 	}
 	// Nothing was found.
-	mm.curReceiver = -1
+	cg.curReceiver = -1
+}
+
+// wanted returns true if one of the consumer groups that have
+// receivers hasn't acked the message yet.
+func (mm *messageManager) wanted(mr *MessageRow) bool {
+	for _, cg := range mm.consumerGroups {
+		if len(cg.receivers) != 0 && !mr.ackedBy(cg.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// canSend returns true if there's a receiver available in
+// every consumer group that has receivers, and there's at
+// least one such group.
+func (mm *messageManager) canSend() bool {
+	subscribed := false
+	for _, cg := range mm.consumerGroups {
+		if len(cg.receivers) == 0 {
+			continue
+		}
+		if cg.curReceiver == -1 {
+			return false
+		}
+		subscribed = true
+	}
+	return subscribed
 }
 
 // Add adds the message to the cache. It returns true
@@ -523,7 +697,7 @@ func (mm *messageManager) runSend() {
 		mm.mu.Unlock()
 		mm.mu.Lock()
 
-		var rows [][]sqltypes.Value
+		var mrs []*MessageRow
 		for {
 			if !mm.isOpen {
 				return
@@ -542,41 +716,57 @@ func (mm *messageManager) runSend() {
 			}
 
 			// If there are no receivers or cache is empty, we wait.
-			if mm.curReceiver == -1 || mm.cache.IsEmpty() {
+			if !mm.canSend() || mm.cache.IsEmpty() {
 				mm.cond.Wait()
 				continue
 			}
 
 			// Fetch rows from cache.
 			lateCount := int64(0)
+			var deadLetterIDs []string
 			for i := 0; i < mm.batchSize; i++ {
 				mr := mm.cache.Pop()
 				if mr == nil {
 					break
 				}
+				if mm.maxAttempts > 0 && mr.Epoch >= int64(mm.maxAttempts) && mm.wanted(mr) {
+					deadLetterIDs = append(deadLetterIDs, mr.Row[0].ToString())
+					continue
+				}
 				if mr.Epoch >= 1 {
 					lateCount++
 				}
-				rows = append(rows, mr.Row)
+				mrs = append(mrs, mr)
 			}
 			MessageStats.Add([]string{mm.name.String(), "Delayed"}, lateCount)
+			if deadLetterIDs != nil {
+				mm.wg.Add(1)
+				go mm.deadLetter(deadLetterIDs) // calls the offsetting mm.wg.Done()
+			}
 
 			// If we have rows to send, break out of this loop.
-			if rows != nil {
+			if mrs != nil {
 				break
 			}
 		}
-		MessageStats.Add([]string{mm.name.String(), "Sent"}, int64(len(rows)))
-		// If we're here, there is a current receiver, and messages
-		// to send. Reserve the receiver and find the next one.
-		receiver := mm.receivers[mm.curReceiver]
-		receiver.busy = true
-		mm.rescanReceivers(mm.curReceiver)
+		// If we're here, there is a current receiver in every subscribed
+		// consumer group, and messages to send. Reserve the receivers and
+		// find the next ones.
+		var receivers []*receiverWithStatus
+		for _, cg := range mm.consumerGroups {
+			if len(cg.receivers) == 0 {
+				continue
+			}
+			receiver := cg.receivers[cg.curReceiver]
+			receiver.busy = true
+			mm.rescanReceivers(cg, cg.curReceiver)
+			receivers = append(receivers, receiver)
+		}
 
 		// Send the message asynchronously.
 		mm.wg.Add(1)
 		go func() {
-			err := mm.send(context.Background(), receiver, &sqltypes.Result{Rows: rows}) // calls the offsetting mm.wg.Done()
+			err := mm.send(context.Background(), receivers, mrs) // calls the offsetting mm.wg.Done()
 			if err != nil {
 				log.Errorf("messageManager - send failed: %v", err)
 			}
@@ -584,15 +774,18 @@ func (mm *messageManager) runSend() {
 	}
 }
 
-func (mm *messageManager) send(ctx context.Context, receiver *receiverWithStatus, qr *sqltypes.Result) error {
+// send sends the messages to one receiver of each consumer group,
+// skipping the groups that already acked them. It postpones the messages
+// it sent, and holds the ones none of the groups needed.
+func (mm *messageManager) send(ctx context.Context, receivers []*receiverWithStatus, mrs []*MessageRow) error {
 	defer func() {
 		mm.tsv.LogError()
 		mm.wg.Done()
 	}()
 
-	ids := make([]string, len(qr.Rows))
-	for i, row := range qr.Rows {
-		ids[i] = row[0].ToString()
+	ids := make([]string, len(mrs))
+	for i, mr := range mrs {
+		ids[i] = mr.Row[0].ToString()
 	}
 
 	defer func() {
@@ -609,21 +802,93 @@ func (mm *messageManager) send(ctx context.Context, receiver *receiverWithStatus
 		mm.mu.Lock()
 		defer mm.mu.Unlock()
 
-		receiver.busy = false
-		// Rescan if there were no previously available receivers
-		// because the current receiver became non-busy.
-		if mm.curReceiver == -1 {
-			mm.rescanReceivers(-1)
+		for _, receiver := range receivers {
+			receiver.busy = false
+			// Rescan if there were no previously available receivers
+			// because the current receiver became non-busy.
+			if receiver.consumerGroup.curReceiver == -1 {
+				mm.rescanReceivers(receiver.consumerGroup, -1)
+			}
 		}
 	}()
 
-	if err := receiver.receiver.Send(qr); err != nil {
-		// Log the error, but we still want to postpone the message.
-		// Otherwise, if this is a chronic failure like "message too
-		// big", we'll end up spamming non-stop.
-		log.Errorf("Error sending messages: %v: %v", qr, err)
+	sent := make([]bool, len(mrs))
+	var wg sync.WaitGroup
+	for _, receiver := range receivers {
+		qr := &sqltypes.Result{}
+		for i, mr := range mrs {
+			if !mr.ackedBy(receiver.consumerGroup.name) {
+				qr.Rows = append(qr.Rows, mr.Row)
+				sent[i] = true
+			}
+		}
+		if len(qr.Rows) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(receiver *receiverWithStatus) {
+			defer wg.Done()
+			if err := receiver.receiver.Send(qr); err != nil {
+				// Log the error, but we still want to postpone the message.
+				// Otherwise, if this is a chronic failure like "message too
+				// big", we'll end up spamming non-stop.
+				log.Errorf("Error sending messages: %v: %v", qr, err)
+			}
+		}(receiver)
+	}
+	wg.Wait()
+
+	var sentIDs, heldIDs []string
+	for i, id := range ids {
+		if sent[i] {
+			sentIDs = append(sentIDs, id)
+		} else {
+			heldIDs = append(heldIDs, id)
+		}
 	}
-	return mm.postpone(ctx, mm.tsv, mm.ackWaitTime, ids)
+	MessageStats.Add([]string{mm.name.String(), "Sent"}, int64(len(sentIDs)))
+	if heldIDs != nil {
+		if err := mm.hold(ctx, heldIDs); err != nil {
+			return err
+		}
+	}
+	if sentIDs == nil {
+		return nil
+	}
+	return mm.postpone(ctx, mm.tsv, mm.ackWaitTime, sentIDs)
+}
+
+// deadLetter moves the messages to the dead-letter table.
+func (mm *messageManager) deadLetter(ids []string) {
+	defer func() {
+		mm.tsv.LogError()
+		mm.wg.Done()
+	}()
+
+	defer func() {
+		// Same as for send: the messages must not be requeued
+		// by a poller that read them before they were moved.
+		// Messages that couldn't be moved will be read again
+		// by the poller.
+		mm.cacheManagementMu.Lock()
+		defer mm.cacheManagementMu.Unlock()
+		mm.cache.Discard(ids)
+	}()
+
+	// Moving messages uses a transaction, just like postponing them.
+	if err := mm.postponeSema.Acquire(context.Background(), 1); err != nil {
+		return
+	}
+	defer mm.postponeSema.Release(1)
+	ctx, cancel := context.WithTimeout(tabletenv.LocalContext(), mm.ackWaitTime)
+	defer cancel()
+	count, err := mm.tsv.DeadLetterMessages(ctx, nil, mm, ids)
+	if err != nil {
+		MessageStats.Add([]string{mm.name.String(), "DeadLetterFailed"}, 1)
+		log.Errorf("Unable to move messages to the dead-letter table: %v", err)
+		return
+	}
+	MessageStats.Add([]string{mm.name.String(), "DeadLettered"}, count)
 }
 
 func (mm *messageManager) postpone(ctx context.Context, tsv TabletService, ackWaitTime time.Duration, ids []string) error {
@@ -642,6 +907,21 @@ func (mm *messageManager) postpone(ctx context.Context, tsv TabletService, ackWa
 	return nil
 }
 
+// hold delays the messages by the ack wait time, without counting it as
+// an attempt to send them.
+func (mm *messageManager) hold(ctx context.Context, ids []string) error {
+	if err := mm.postponeSema.Acquire(ctx, 1); err != nil {
+		return err
+	}
+	defer mm.postponeSema.Release(1)
+	ctx, cancel := context.WithTimeout(tabletenv.LocalContext(), mm.ackWaitTime)
+	defer cancel()
+	if _, err := mm.tsv.HoldMessages(ctx, nil, mm, ids); err != nil {
+		MessageStats.Add([]string{mm.name.String(), "HoldFailed"}, 1)
+	}
+	return nil
+}
+
 func (mm *messageManager) startVStream() {
 	if mm.streamCancel != nil {
 		return
@@ -792,8 +1072,9 @@ func (mm *messageManager) runPoller() {
 
 	size := mm.cache.Size()
 	bindVars := map[string]*querypb.BindVariable{
-		"time_next": sqltypes.Int64BindVariable(time.Now().UnixNano()),
-		"max":       sqltypes.Int64BindVariable(int64(size)),
+		"time_next":  sqltypes.Int64BindVariable(time.Now().UnixNano()),
+		"max":        sqltypes.Int64BindVariable(int64(size)),
+		"table_name": sqltypes.StringBindVariable(mm.name.String()),
 	}
 
 	qr, err := mm.readPending(ctx, bindVars)
@@ -812,7 +1093,7 @@ func (mm *messageManager) runPoller() {
 		defer mm.cond.Broadcast()
 	}
 	for _, row := range qr.Rows {
-		mr, err := BuildMessageRow(row)
+		mr, err := mm.buildPolledMessageRow(row)
 		if err != nil {
 			mm.tsv.Stats().InternalErrors.Add("Messages", 1)
 			log.Errorf("Error reading message row: %v", err)
@@ -850,24 +1131,56 @@ func (mm *messageManager) runPurge() {
 
 // GenerateAckQuery returns the query and bind vars for acking a message.
 func (mm *messageManager) GenerateAckQuery(ids []string) (string, map[string]*querypb.BindVariable) {
-	idbvs := &querypb.BindVariable{
-		Type:   querypb.Type_TUPLE,
-		Values: make([]*querypb.Value, 0, len(ids)),
-	}
-	for _, id := range ids {
-		idbvs.Values = append(idbvs.Values, &querypb.Value{
-			Type:  querypb.Type_VARBINARY,
-			Value: []byte(id),
-		})
-	}
 	return mm.ackQuery.Query, map[string]*querypb.BindVariable{
 		"time_acked": sqltypes.Int64BindVariable(time.Now().UnixNano()),
-		"ids":        idbvs,
+		"ids":        buildIDsBindVariable(ids),
 	}
 }
 
-// GeneratePostponeQuery returns the query and bind vars for postponing a message.
-func (mm *messageManager) GeneratePostponeQuery(ids []string) (string, map[string]*querypb.BindVariable) {
+// GenerateConsumerGroupAckQueries returns the queries and bind vars for acking
+// messages for a consumer group, and acking the messages every group acked.
+func (mm *messageManager) GenerateConsumerGroupAckQueries(consumerGroup string, ids []string) ([]*querypb.BoundQuery, error) {
+	if _, err := mm.getConsumerGroup(consumerGroup); err != nil {
+		return nil, err
+	}
+	consumerGroups := make([]string, 0, len(mm.consumerGroups))
+	for _, cg := range mm.consumerGroups {
+		consumerGroups = append(consumerGroups, cg.name)
+	}
+	consumerGroupsBv, err := sqltypes.BuildBindVariable(consumerGroups)
+	if err != nil {
+		return nil, err
+	}
+	bvs := map[string]*querypb.BindVariable{
+		"table_name":           sqltypes.StringBindVariable(mm.name.String()),
+		"consumer_group":       sqltypes.StringBindVariable(consumerGroup),
+		"consumer_groups":      consumerGroupsBv,
+		"consumer_group_count": sqltypes.Int64BindVariable(int64(len(mm.consumerGroups))),
+		"time_acked":           sqltypes.Int64BindVariable(time.Now().UnixNano()),
+		"ids":                  buildIDsBindVariable(ids),
+	}
+	return []*querypb.BoundQuery{
+		{Sql: mm.consumerGroupAckQuery.Query, BindVariables: bvs},
+		{Sql: mm.consumerGroupsAckedQuery.Query, BindVariables: bvs},
+		{Sql: mm.consumerGroupAckPurgeQuery.Query, BindVariables: bvs},
+	}, nil
+}
+
+// GenerateDeadLetterQueries returns the queries and bind vars for moving
+// messages to the dead-letter table.
+func (mm *messageManager) GenerateDeadLetterQueries(ids []string) []*querypb.BoundQuery {
+	bvs := map[string]*querypb.BindVariable{
+		"table_name": sqltypes.StringBindVariable(mm.name.String()),
+		"ids":        buildIDsBindVariable(ids),
+	}
+	queries := []*querypb.BoundQuery{{Sql: mm.deadLetterQuery.Query, BindVariables: bvs}}
+	if mm.hasConsumerGroups() {
+		queries = append(queries, &querypb.BoundQuery{Sql: mm.deadLetterAckPurgeQuery.Query, BindVariables: bvs})
+	}
+	return append(queries, &querypb.BoundQuery{Sql: mm.deadLetterDeleteQuery.Query, BindVariables: bvs})
+}
+
+func buildIDsBindVariable(ids []string) *querypb.BindVariable {
 	idbvs := &querypb.BindVariable{
 		Type:   querypb.Type_TUPLE,
 		Values: make([]*querypb.Value, 0, len(ids)),
@@ -878,13 +1191,17 @@ func (mm *messageManager) GeneratePostponeQuery(ids []string) (string, map[strin
 			Value: []byte(id),
 		})
 	}
+	return idbvs
+}
 
+// GeneratePostponeQuery returns the query and bind vars for postponing a message.
+func (mm *messageManager) GeneratePostponeQuery(ids []string) (string, map[string]*querypb.BindVariable) {
 	bvs := map[string]*querypb.BindVariable{
 		"time_now":    sqltypes.Int64BindVariable(time.Now().UnixNano()),
 		"wait_time":   sqltypes.Int64BindVariable(int64(mm.ackWaitTime)),
 		"min_backoff": sqltypes.Int64BindVariable(int64(mm.minBackoff)),
 		"jitter":      sqltypes.Float64BindVariable(.666666 + rand.Float64()*.666666),
-		"ids":         idbvs,
+		"ids":         buildIDsBindVariable(ids),
 	}
 
 	if mm.maxBackoff > 0 {
@@ -894,6 +1211,16 @@ func (mm *messageManager) GeneratePostponeQuery(ids []string) (string, map[strin
 	return mm.postponeQuery.Query, bvs
 }
 
+// GenerateHoldQuery returns the query and bind vars for holding messages
+// that were not sent, which delays them without changing their epoch.
+func (mm *messageManager) GenerateHoldQuery(ids []string) (string, map[string]*querypb.BindVariable) {
+	return mm.holdQuery.Query, map[string]*querypb.BindVariable{
+		"time_now":  sqltypes.Int64BindVariable(time.Now().UnixNano()),
+		"wait_time": sqltypes.Int64BindVariable(int64(mm.ackWaitTime)),
+		"ids":       buildIDsBindVariable(ids),
+	}
+}
+
 // GeneratePurgeQuery returns the query and bind vars for purging messages.
 func (mm *messageManager) GeneratePurgeQuery(timeCutoff int64) (string, map[string]*querypb.BindVariable) {
 	return mm.purgeQuery.Query, map[string]*querypb.BindVariable{
@@ -935,6 +1262,24 @@ func BuildMessageRow(row []sqltypes.Value) (*MessageRow, error) {
 	return mr, nil
 }
 
+// buildPolledMessageRow builds a MessageRow from a row read by the poller,
+// which ends with the consumer groups that acked the message if the table
+// has consumer groups.
+func (mm *messageManager) buildPolledMessageRow(row []sqltypes.Value) (*MessageRow, error) {
+	if !mm.hasConsumerGroups() {
+		return BuildMessageRow(row)
+	}
+	last := len(row) - 1
+	mr, err := BuildMessageRow(row[:last])
+	if err != nil {
+		return nil, err
+	}
+	if !row[last].IsNull() {
+		mr.AckedConsumerGroups = strings.Split(row[last].ToString(), ",")
+	}
+	return mr, nil
+}
+
 func (mm *messageManager) readPending(ctx context.Context, bindVars map[string]*querypb.BindVariable) (*sqltypes.Result, error) {
 	query, err := mm.readByPriorityAndTimeNext.GenerateQuery(bindVars, nil)
 	if err != nil {
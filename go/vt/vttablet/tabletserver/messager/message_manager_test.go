@@ -94,6 +94,22 @@ func newMMTableWithBackoff() *schema.Table {
 	}
 }
 
+func newMMTableWithConsumerGroups() *schema.Table {
+	table := newMMTable()
+	table.Fields = []*querypb.Field{
+		{Name: "id", Type: sqltypes.VarBinary},
+		{Name: "priority", Type: sqltypes.Int64},
+		{Name: "time_next", Type: sqltypes.Int64},
+		{Name: "epoch", Type: sqltypes.Int64},
+		{Name: "time_acked", Type: sqltypes.Int64},
+		{Name: "message", Type: sqltypes.VarBinary},
+	}
+	table.MessageInfo.ConsumerGroups = []string{"billing", "shipping"}
+	table.MessageInfo.MaxAttempts = 3
+	table.MessageInfo.DeadLetterTable = "foo_dlq"
+	return table
+}
+
 func newMMRow(id int64) *querypb.Row {
 	return sqltypes.RowToProto3([]sqltypes.Value{
 		sqltypes.NewInt64(1),
@@ -141,7 +157,7 @@ func TestReceiverCancel(t *testing.T) {
 	r1 := newTestReceiver(0)
 	ctx, cancel := context.WithCancel(context.Background())
 	go cancel()
-	_ = mm.Subscribe(ctx, r1.rcv)
+	_ = mm.Subscribe(ctx, "", r1.rcv)
 
 	// r1 should eventually be unsubscribed.
 	for i := 0; i < 10; i++ {
@@ -187,7 +203,7 @@ func TestMessageManagerAdd(t *testing.T) {
 
 	r1 := newTestReceiver(0)
 	go func() { <-r1.ch }()
-	mm.Subscribe(context.Background(), r1.rcv)
+	mm.Subscribe(context.Background(), "", r1.rcv)
 
 	if !mm.Add(row1) {
 		t.Error("Add(1 receiver): false, want true")
@@ -210,7 +226,7 @@ func TestMessageManagerSend(t *testing.T) {
 	defer mm.Close()
 
 	r1 := newTestReceiver(1)
-	mm.Subscribe(context.Background(), r1.rcv)
+	mm.Subscribe(context.Background(), "", r1.rcv)
 
 	want := &sqltypes.Result{
 		Fields: testFields,
@@ -265,7 +281,7 @@ func TestMessageManagerSend(t *testing.T) {
 	// Test that mm stops sending to a canceled receiver.
 	r2 := newTestReceiver(1)
 	ctx, cancel := context.WithCancel(context.Background())
-	mm.Subscribe(ctx, r2.rcv)
+	mm.Subscribe(ctx, "", r2.rcv)
 	<-r2.ch
 
 	mm.Add(&MessageRow{Row: []sqltypes.Value{sqltypes.NewVarBinary("2")}})
@@ -297,6 +313,96 @@ func TestMessageManagerSend(t *testing.T) {
 	<-r1.ch
 }
 
+func TestMessageManagerConsumerGroups(t *testing.T) {
+	tsv := newFakeTabletServer()
+	mm := newMessageManager(tsv, newFakeVStreamer(), newMMTableWithConsumerGroups(), semaphore.NewWeighted(1))
+	mm.Open()
+	defer mm.Close()
+
+	billing := newTestReceiver(1)
+	mm.Subscribe(context.Background(), "billing", billing.rcv)
+	<-billing.ch
+	shipping := newTestReceiver(1)
+	mm.Subscribe(context.Background(), "shipping", shipping.rcv)
+	<-shipping.ch
+
+	// A subscription to an unknown group is terminated.
+	unknown := newTestReceiver(1)
+	done := mm.Subscribe(context.Background(), "unknown", unknown.rcv)
+	<-done
+	assert.EqualValues(t, 0, unknown.count.Load())
+
+	// Every group gets the message.
+	mm.Add(&MessageRow{Row: []sqltypes.Value{sqltypes.NewVarBinary("1"), sqltypes.NULL}})
+	utils.MustMatch(t, newMMResult("1"), <-billing.ch)
+	utils.MustMatch(t, newMMResult("1"), <-shipping.ch)
+
+	// A resent message is only sent to the groups that didn't ack it.
+	mm.Add(&MessageRow{
+		TimeNext:            1,
+		Epoch:               1,
+		Row:                 []sqltypes.Value{sqltypes.NewVarBinary("2"), sqltypes.NULL},
+		AckedConsumerGroups: []string{"billing"},
+	})
+	mm.Add(&MessageRow{Row: []sqltypes.Value{sqltypes.NewVarBinary("3"), sqltypes.NULL}})
+	utils.MustMatch(t, newMMResult("2"), <-shipping.ch)
+	utils.MustMatch(t, newMMResult("3"), <-billing.ch)
+	utils.MustMatch(t, newMMResult("3"), <-shipping.ch)
+}
+
+func newMMResult(id string) *sqltypes.Result {
+	return &sqltypes.Result{Rows: [][]sqltypes.Value{{sqltypes.NewVarBinary(id), sqltypes.NULL}}}
+}
+
+func TestMessageManagerDeadLetter(t *testing.T) {
+	tsv := newFakeTabletServer()
+	mm := newMessageManager(tsv, newFakeVStreamer(), newMMTableWithConsumerGroups(), semaphore.NewWeighted(1))
+	mm.Open()
+	defer mm.Close()
+
+	r1 := newTestReceiver(1)
+	mm.Subscribe(context.Background(), "billing", r1.rcv)
+	<-r1.ch
+
+	ch := make(chan string, 20)
+	tsv.SetChannel(ch)
+	// The message was already sent as many times as allowed.
+	mm.Add(&MessageRow{Epoch: 3, Row: []sqltypes.Value{sqltypes.NewVarBinary("1"), sqltypes.NULL}})
+	mm.Add(&MessageRow{Epoch: 2, Row: []sqltypes.Value{sqltypes.NewVarBinary("2"), sqltypes.NULL}})
+	utils.MustMatch(t, newMMResult("2"), <-r1.ch)
+	assert.ElementsMatch(t, []string{"deadletter", "postpone"}, []string{<-ch, <-ch})
+	assert.EqualValues(t, 1, tsv.deadLetterCount.Load())
+}
+
+func TestMessageManagerOfflineConsumerGroup(t *testing.T) {
+	tsv := newFakeTabletServer()
+	mm := newMessageManager(tsv, newFakeVStreamer(), newMMTableWithConsumerGroups(), semaphore.NewWeighted(1))
+	mm.Open()
+	defer mm.Close()
+
+	// The shipping group has no clients.
+	r1 := newTestReceiver(1)
+	mm.Subscribe(context.Background(), "billing", r1.rcv)
+	<-r1.ch
+
+	ch := make(chan string, 20)
+	tsv.SetChannel(ch)
+	// A message only shipping needs is held, and not dead-lettered
+	// even if it was sent as many times as allowed.
+	mm.Add(&MessageRow{
+		Epoch:               3,
+		Row:                 []sqltypes.Value{sqltypes.NewVarBinary("1"), sqltypes.NULL},
+		AckedConsumerGroups: []string{"billing"},
+	})
+	assert.Equal(t, "hold", <-ch)
+	mm.Add(&MessageRow{Row: []sqltypes.Value{sqltypes.NewVarBinary("2"), sqltypes.NULL}})
+	utils.MustMatch(t, newMMResult("2"), <-r1.ch)
+	assert.Equal(t, "postpone", <-ch)
+	assert.EqualValues(t, 0, tsv.deadLetterCount.Load())
+	assert.EqualValues(t, 1, tsv.holdCount.Load())
+	assert.EqualValues(t, 1, tsv.postponeCount.Load())
+}
+
 func TestMessageManagerPostponeThrottle(t *testing.T) {
 	tsv := newFakeTabletServer()
 	mm := newMessageManager(tsv, newFakeVStreamer(), newMMTable(), semaphore.NewWeighted(1))
@@ -304,7 +410,7 @@ func TestMessageManagerPostponeThrottle(t *testing.T) {
 	defer mm.Close()
 
 	r1 := newTestReceiver(1)
-	mm.Subscribe(context.Background(), r1.rcv)
+	mm.Subscribe(context.Background(), "", r1.rcv)
 	<-r1.ch
 
 	// Set the channel to verify call to Postpone.
@@ -319,7 +425,7 @@ func TestMessageManagerPostponeThrottle(t *testing.T) {
 
 	// Set up a second subsriber, add a message.
 	r2 := newTestReceiver(1)
-	mm.Subscribe(context.Background(), r2.rcv)
+	mm.Subscribe(context.Background(), "", r2.rcv)
 	<-r2.ch
 
 	// Wait.
@@ -355,7 +461,7 @@ func TestMessageManagerSendError(t *testing.T) {
 	ch := make(chan *sqltypes.Result)
 	go func() { <-ch }()
 	fieldSent := false
-	mm.Subscribe(ctx, func(qr *sqltypes.Result) error {
+	mm.Subscribe(ctx, "", func(qr *sqltypes.Result) error {
 		ch <- qr
 		if !fieldSent {
 			fieldSent = true
@@ -383,7 +489,7 @@ func TestMessageManagerFieldSendError(t *testing.T) {
 
 	ch := make(chan *sqltypes.Result)
 	go func() { <-ch }()
-	done := mm.Subscribe(ctx, func(qr *sqltypes.Result) error {
+	done := mm.Subscribe(ctx, "", func(qr *sqltypes.Result) error {
 		ch <- qr
 		return errors.New("non-eof")
 	})
@@ -401,7 +507,7 @@ func TestMessageManagerBatchSend(t *testing.T) {
 	defer mm.Close()
 
 	r1 := newTestReceiver(1)
-	mm.Subscribe(context.Background(), r1.rcv)
+	mm.Subscribe(context.Background(), "", r1.rcv)
 	<-r1.ch
 
 	row1 := &MessageRow{
@@ -471,7 +577,7 @@ func TestMessageManagerStreamerSimple(t *testing.T) {
 	defer mm.Close()
 
 	r1 := newTestReceiver(1)
-	mm.Subscribe(context.Background(), r1.rcv)
+	mm.Subscribe(context.Background(), "", r1.rcv)
 	<-r1.ch
 
 	want := &sqltypes.Result{
@@ -496,7 +602,7 @@ func TestMessageManagerStreamerAndPoller(t *testing.T) {
 	defer mm.Close()
 
 	r1 := newTestReceiver(1)
-	mm.Subscribe(context.Background(), r1.rcv)
+	mm.Subscribe(context.Background(), "", r1.rcv)
 	<-r1.ch
 
 	for {
@@ -594,7 +700,7 @@ func TestMessageManagerPoller(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	r1 := newTestReceiver(1)
-	mm.Subscribe(ctx, r1.rcv)
+	mm.Subscribe(ctx, "", r1.rcv)
 	<-r1.ch
 
 	want := [][]sqltypes.Value{{
@@ -650,7 +756,7 @@ func TestMessagesPending1(t *testing.T) {
 
 	r1 := newTestReceiver(0)
 	go func() { <-r1.ch }()
-	mm.Subscribe(context.Background(), r1.rcv)
+	mm.Subscribe(context.Background(), "", r1.rcv)
 
 	mm.Add(&MessageRow{Row: []sqltypes.Value{sqltypes.NewVarBinary("1")}})
 	// Make sure the first message is enqueued.
@@ -699,7 +805,7 @@ func TestMessagesPending2(t *testing.T) {
 
 	r1 := newTestReceiver(0)
 	go func() { <-r1.ch }()
-	mm.Subscribe(context.Background(), r1.rcv)
+	mm.Subscribe(context.Background(), "", r1.rcv)
 
 	// Now, let's pull more than 1 item. It should
 	// trigger the poller every time cache gets empty.
@@ -821,10 +927,65 @@ func TestMMGenerateWithBackoff(t *testing.T) {
 	}
 }
 
+func TestMMGenerateConsumerGroups(t *testing.T) {
+	mm := newMessageManager(newFakeTabletServer(), newFakeVStreamer(), newMMTableWithConsumerGroups(), semaphore.NewWeighted(1))
+	mm.Open()
+	defer mm.Close()
+
+	wantQuery := "select priority, time_next, epoch, time_acked, id, message, (select group_concat(consumer_group) from _vt.message_acks where table_name = :table_name and id = cast(foo.id as binary)) from foo where time_acked is null and time_next < :time_next order by priority, time_next desc limit :max"
+	assert.Equal(t, wantQuery, mm.readByPriorityAndTimeNext.Query)
+
+	query, holdBV := mm.GenerateHoldQuery([]string{"1", "2"})
+	assert.Equal(t, "update foo set time_next = :time_now + :wait_time where id in ::ids and time_acked is null", query)
+	assert.Len(t, holdBV, 3)
+
+	_, err := mm.GenerateConsumerGroupAckQueries("", []string{"1"})
+	assert.EqualError(t, err, "message table foo has consumer groups: a consumer group must be specified")
+	_, err = mm.GenerateConsumerGroupAckQueries("unknown", []string{"1"})
+	assert.EqualError(t, err, "consumer group unknown not found for message table foo")
+
+	queries, err := mm.GenerateConsumerGroupAckQueries("billing", []string{"1", "2"})
+	assert.NoError(t, err)
+	var got []string
+	for _, query := range queries {
+		got = append(got, query.Sql)
+	}
+	want := []string{
+		"insert ignore into _vt.message_acks(table_name, id, consumer_group, time_acked) select :table_name, cast(id as binary), :consumer_group, :time_acked from foo where id in ::ids and time_acked is null",
+		"update foo set time_acked = :time_acked, time_next = null where id in ::ids and time_acked is null and (select count(*) from _vt.message_acks where table_name = :table_name and id = cast(foo.id as binary) and consumer_group in ::consumer_groups) = :consumer_group_count",
+		"delete from _vt.message_acks where table_name = :table_name and id in (select cast(id as binary) from foo where id in ::ids and time_acked is not null)",
+	}
+	assert.Equal(t, want, got)
+	bv := queries[0].BindVariables
+	delete(bv, "time_acked")
+	wantbv := map[string]*querypb.BindVariable{
+		"table_name":           sqltypes.StringBindVariable("foo"),
+		"consumer_group":       sqltypes.StringBindVariable("billing"),
+		"consumer_groups":      sqltypes.TestBindVariable([]any{"billing", "shipping"}),
+		"consumer_group_count": sqltypes.Int64BindVariable(2),
+		"ids":                  sqltypes.TestBindVariable([]any{[]byte{'1'}, []byte{'2'}}),
+	}
+	utils.MustMatch(t, wantbv, bv, "did not match")
+
+	queries = mm.GenerateDeadLetterQueries([]string{"1"})
+	got = nil
+	for _, query := range queries {
+		got = append(got, query.Sql)
+	}
+	want = []string{
+		"insert into foo_dlq(id, priority, time_next, epoch, time_acked, message) select id, priority, time_next, epoch, time_acked, message from foo where id in ::ids and time_acked is null",
+		"delete from _vt.message_acks where table_name = :table_name and id in (select cast(id as binary) from foo where id in ::ids)",
+		"delete from foo where id in ::ids and time_acked is null",
+	}
+	assert.Equal(t, want, got)
+}
+
 type fakeTabletServer struct {
 	tabletenv.Env
-	postponeCount atomic.Int64
-	purgeCount    atomic.Int64
+	postponeCount   atomic.Int64
+	purgeCount      atomic.Int64
+	deadLetterCount atomic.Int64
+	holdCount       atomic.Int64
 
 	mu sync.Mutex
 	ch chan string
@@ -856,6 +1017,17 @@ func (fts *fakeTabletServer) PostponeMessages(ctx context.Context, target *query
 	return 0, nil
 }
 
+func (fts *fakeTabletServer) HoldMessages(ctx context.Context, target *querypb.Target, gen QueryGenerator, ids []string) (count int64, err error) {
+	fts.holdCount.Add(1)
+	fts.mu.Lock()
+	ch := fts.ch
+	fts.mu.Unlock()
+	if ch != nil {
+		ch <- "hold"
+	}
+	return 0, nil
+}
+
 func (fts *fakeTabletServer) PurgeMessages(ctx context.Context, target *querypb.Target, gen QueryGenerator, timeCutoff int64) (count int64, err error) {
 	fts.purgeCount.Add(1)
 	fts.mu.Lock()
@@ -867,6 +1039,17 @@ func (fts *fakeTabletServer) PurgeMessages(ctx context.Context, target *querypb.
 	return 0, nil
 }
 
+func (fts *fakeTabletServer) DeadLetterMessages(ctx context.Context, target *querypb.Target, gen QueryGenerator, ids []string) (count int64, err error) {
+	fts.deadLetterCount.Add(1)
+	fts.mu.Lock()
+	ch := fts.ch
+	fts.mu.Unlock()
+	if ch != nil {
+		ch <- "deadletter"
+	}
+	return int64(len(ids)), nil
+}
+
 type fakeVStreamer struct {
 	streamInvocations atomic.Int64
 	mu                sync.Mutex
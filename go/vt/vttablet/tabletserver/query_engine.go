@@ -226,6 +226,8 @@ func NewQueryEngine(env tabletenv.Env, se *schema.Engine) *QueryEngine {
 
 	qe.conns = connpool.NewPool(env, "ConnPool", config.OltpReadPool)
 	qe.streamConns = connpool.NewPool(env, "StreamConnPool", config.OlapReadPool)
+	qe.conns.AdmitWorkloadClasses(config.WorkloadClasses, config.OltpReadPool.ReservedSlots)
+	qe.streamConns.AdmitWorkloadClasses(config.WorkloadClasses, config.OlapReadPool.ReservedSlots)
 	qe.consolidatorMode.Store(config.Consolidator)
 	qe.consolidator = sync2.NewConsolidator()
 	if config.ConsolidatorStreamTotalSize > 0 && config.ConsolidatorStreamQuerySize > 0 {
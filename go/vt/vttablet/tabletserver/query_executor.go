@@ -397,7 +397,7 @@ func (qre *QueryExecutor) Stream(callback StreamCallback) error {
 }
 
 // MessageStream streams messages from a message table.
-func (qre *QueryExecutor) MessageStream(callback StreamCallback) error {
+func (qre *QueryExecutor) MessageStream(consumerGroup string, callback StreamCallback) error {
 	qre.logStats.OriginalSQL = qre.query
 	qre.logStats.PlanType = qre.plan.PlanID.String()
 
@@ -411,7 +411,7 @@ func (qre *QueryExecutor) MessageStream(callback StreamCallback) error {
 		return err
 	}
 
-	done, err := qre.tsv.messager.Subscribe(qre.ctx, qre.plan.TableName().String(), func(r *sqltypes.Result) error {
+	done, err := qre.tsv.messager.Subscribe(qre.ctx, qre.plan.TableName().String(), consumerGroup, func(r *sqltypes.Result) error {
 		select {
 		case <-qre.ctx.Done():
 			return io.EOF
@@ -771,6 +771,7 @@ func (qre *QueryExecutor) execOther() (*sqltypes.Result, error) {
 func (qre *QueryExecutor) getConn() (*connpool.DBConn, error) {
 	span, ctx := trace.NewSpan(qre.ctx, "QueryExecutor.getConn")
 	defer span.Finish()
+	ctx = connpool.NewWorkloadClassContext(ctx, qre.options.GetWorkloadClass())
 
 	start := time.Now()
 	conn, err := qre.tsv.qe.conns.Get(ctx, qre.setting)
@@ -788,6 +789,7 @@ func (qre *QueryExecutor) getConn() (*connpool.DBConn, error) {
 func (qre *QueryExecutor) getStreamConn() (*connpool.DBConn, error) {
 	span, ctx := trace.NewSpan(qre.ctx, "QueryExecutor.getStreamConn")
 	defer span.Finish()
+	ctx = connpool.NewWorkloadClassContext(ctx, qre.options.GetWorkloadClass())
 
 	start := time.Now()
 	conn, err := qre.tsv.qe.streamConns.Get(ctx, qre.setting)
@@ -830,7 +830,7 @@ func TestQueryExecutorMessageStreamACL(t *testing.T) {
 	}
 
 	// Should not fail because u1 has permission.
-	err = qre.MessageStream(func(qr *sqltypes.Result) error {
+	err = qre.MessageStream("", func(qr *sqltypes.Result) error {
 		return io.EOF
 	})
 	if err != nil {
@@ -843,7 +843,7 @@ func TestQueryExecutorMessageStreamACL(t *testing.T) {
 	}
 	qre.ctx = callerid.NewContext(context.Background(), nil, callerID)
 	// Should fail because u2 does not have permission.
-	err = qre.MessageStream(func(qr *sqltypes.Result) error {
+	err = qre.MessageStream("", func(qr *sqltypes.Result) error {
 		return io.EOF
 	})
 
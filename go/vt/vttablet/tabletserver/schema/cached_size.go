@@ -25,7 +25,7 @@ func (cached *MessageInfo) CachedSize(alloc bool) int64 {
 	}
 	size := int64(0)
 	if alloc {
-		size += int64(80)
+		size += int64(128)
 	}
 	// field Fields []*vitess.io/vitess/go/vt/proto/query.Field
 	{
@@ -34,6 +34,15 @@ func (cached *MessageInfo) CachedSize(alloc bool) int64 {
 			size += elem.CachedSize(true)
 		}
 	}
+	// field ConsumerGroups []string
+	{
+		size += hack.RuntimeAllocSize(int64(cap(cached.ConsumerGroups)) * int64(16))
+		for _, elem := range cached.ConsumerGroups {
+			size += hack.RuntimeAllocSize(int64(len(elem)))
+		}
+	}
+	// field DeadLetterTable string
+	size += hack.RuntimeAllocSize(int64(len(cached.DeadLetterTable)))
 	return size
 }
 func (cached *Table) CachedSize(alloc bool) int64 {
@@ -107,6 +107,17 @@ func loadMessageInfo(ta *Table, comment string) error {
 
 	ta.MessageInfo.MaxBackoff, _ = getDuration(keyvals, "vt_max_backoff")
 
+	ta.MessageInfo.ConsumerGroups = parseMessageList(keyvals, "vt_consumer_groups")
+	if keyvals["vt_max_attempts"] != "" {
+		if ta.MessageInfo.MaxAttempts, err = getNum(keyvals, "vt_max_attempts"); err != nil {
+			return err
+		}
+		// Poison messages are never dropped: they must have somewhere to go.
+		if ta.MessageInfo.DeadLetterTable = keyvals["vt_dead_letter_table"]; ta.MessageInfo.DeadLetterTable == "" {
+			return fmt.Errorf("vt_max_attempts requires vt_dead_letter_table for message table: %s", ta.Name.String())
+		}
+	}
+
 	// these columns are required for message manager to function properly, but only
 	// id is required to be streamed to subscribers
 	requiredCols := []string{
@@ -135,7 +146,7 @@ func loadMessageInfo(ta *Table, comment string) error {
 	}
 
 	// check to see if the user has specified columns to stream to subscribers
-	specifiedCols := parseMessageCols(keyvals, "vt_message_cols")
+	specifiedCols := parseMessageList(keyvals, "vt_message_cols")
 
 	if len(specifiedCols) > 0 {
 		// make sure that all the specified columns exist in the table schema
@@ -184,10 +195,10 @@ func getNum(in map[string]string, key string) (int, error) {
 	return v, nil
 }
 
-// parseMessageCols parses the vt_message_cols attribute. It doesn't error out if the attribute is not specified
-// because the default behavior is to stream all columns to subscribers, and if done incorrectly, later checks
-// to see if the columns exist in the table schema will fail.
-func parseMessageCols(in map[string]string, key string) []string {
+// parseMessageList parses a | separated list attribute like vt_message_cols. It doesn't error out if the attribute
+// is not specified because the default behavior is to stream all columns to subscribers, and if done incorrectly,
+// later checks to see if the columns exist in the table schema will fail.
+func parseMessageList(in map[string]string, key string) []string {
 	sv := in[key]
 	cols := strings.Split(sv, "|")
 	if len(cols) == 1 && strings.TrimSpace(cols[0]) == "" {
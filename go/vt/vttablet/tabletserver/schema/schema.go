@@ -125,6 +125,20 @@ type MessageInfo struct {
 	// MaxBackoff specifies the longest duration message manager
 	// should wait before rescheduling a message
 	MaxBackoff time.Duration
+
+	// ConsumerGroups are the consumer groups of the table, if any.
+	// Every message is sent to one subscriber of each group,
+	// and it's acked once every group acked it.
+	ConsumerGroups []string
+
+	// MaxAttempts specifies how many times a message is sent
+	// before it's moved to DeadLetterTable. Messages are resent
+	// until they're acked if it's 0.
+	MaxAttempts int
+
+	// DeadLetterTable is the table messages that were sent
+	// MaxAttempts times without being acked are moved to.
+	DeadLetterTable string
 }
 
 // NewTable creates a new Table.
@@ -68,6 +68,8 @@ func NewStatefulConnPool(env tabletenv.Env) *StatefulConnectionPool {
 		foundRowsPool: connpool.NewPool(env, "FoundRowsPool", config.TxPool),
 		active:        pools.NewNumbered(),
 	}
+	scp.conns.AdmitWorkloadClasses(config.WorkloadClasses, config.TxPool.ReservedSlots)
+	scp.foundRowsPool.AdmitWorkloadClasses(config.WorkloadClasses, config.TxPool.ReservedSlots)
 	scp.lastID.Store(time.Now().UnixNano())
 	return scp
 }
@@ -174,6 +176,7 @@ func (sf *StatefulConnectionPool) NewConn(ctx context.Context, options *querypb.
 	var conn *connpool.DBConn
 	var err error
 
+	ctx = connpool.NewWorkloadClassContext(ctx, options.GetWorkloadClass())
 	if options.GetClientFoundRows() {
 		conn, err = sf.foundRowsPool.Get(ctx, setting)
 	} else {
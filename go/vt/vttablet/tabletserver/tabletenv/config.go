@@ -170,6 +170,11 @@ func registerTabletEnvFlags(fs *pflag.FlagSet) {
 	fs.IntVar(&currentConfig.OltpReadPool.MaxWaiters, "queryserver-config-query-pool-waiter-cap", defaultConfig.OltpReadPool.MaxWaiters, "query server query pool waiter limit, this is the maximum number of queries that can be queued waiting to get a connection")
 	fs.IntVar(&currentConfig.OlapReadPool.MaxWaiters, "queryserver-config-stream-pool-waiter-cap", defaultConfig.OlapReadPool.MaxWaiters, "query server stream pool waiter limit, this is the maximum number of streaming queries that can be queued waiting to get a connection")
 	fs.IntVar(&currentConfig.TxPool.MaxWaiters, "queryserver-config-txpool-waiter-cap", defaultConfig.TxPool.MaxWaiters, "query server transaction pool waiter limit, this is the maximum number of transactions that can be queued waiting to get a connection")
+	// workload classes related configurations.
+	fs.Var(&currentConfig.WorkloadClasses, "workload-classes", "comma-separated list of class:priority pairs naming the workload classes queries can select with the WORKLOAD_CLASS directive, e.g. oltp:0,batch:50,analytics:90. When the query, stream and transaction pools are full, queries wait for a connection in order of the priority of their class, where 0 is the highest priority and 100 the lowest. Queries without a known class have the lowest priority.")
+	fs.Var(&currentConfig.OltpReadPool.ReservedSlots, "queryserver-config-pool-reserved-slots", "comma-separated list of class:slots pairs, the query pool slots only queries of a workload class can use, e.g. oltp:8. Other slots are shared by all the queries.")
+	fs.Var(&currentConfig.OlapReadPool.ReservedSlots, "queryserver-config-stream-pool-reserved-slots", "comma-separated list of class:slots pairs, the stream pool slots only queries of a workload class can use. Other slots are shared by all the queries.")
+	fs.Var(&currentConfig.TxPool.ReservedSlots, "queryserver-config-transaction-cap-reserved-slots", "comma-separated list of class:slots pairs, the transaction pool slots only transactions of a workload class can use. Other slots are shared by all the transactions.")
 	// tableacl related configurations.
 	fs.BoolVar(&currentConfig.StrictTableACL, "queryserver-config-strict-table-acl", defaultConfig.StrictTableACL, "only allow queries that pass table acl checks")
 	fs.BoolVar(&currentConfig.EnableTableACLDryRun, "queryserver-config-enable-table-acl-dry-run", defaultConfig.EnableTableACLDryRun, "If this flag is enabled, tabletserver will emit monitoring metrics and let the request pass regardless of table acl check results")
@@ -385,6 +390,10 @@ type TabletConfig struct {
 	EnableViews bool `json:"-"`
 
 	EnablePerWorkloadTableMetrics bool `json:"-"`
+
+	// WorkloadClasses maps the workload classes to their priority in the
+	// query, stream and transaction pools.
+	WorkloadClasses WorkloadClassMap `json:"workloadClasses,omitempty"`
 }
 
 func (cfg *TabletConfig) MarshalJSON() ([]byte, error) {
@@ -418,6 +427,7 @@ type ConnPoolConfig struct {
 	MaxLifetimeSeconds flagutil.DeprecatedFloat64Seconds `json:"maxLifetimeSeconds,omitempty"`
 	PrefillParallelism int                               `json:"prefillParallelism,omitempty"`
 	MaxWaiters         int                               `json:"maxWaiters,omitempty"`
+	ReservedSlots      WorkloadClassMap                  `json:"reservedSlots,omitempty"`
 }
 
 func (cfg *ConnPoolConfig) MarshalJSON() ([]byte, error) {
@@ -672,6 +682,9 @@ func (c *TabletConfig) Verify() error {
 	if err := c.verifyTxThrottlerConfig(); err != nil {
 		return err
 	}
+	if err := c.verifyWorkloadClassConfig(); err != nil {
+		return err
+	}
 	if v := c.HotRowProtection.MaxQueueSize; v <= 0 {
 		return fmt.Errorf("--hot_row_protection_max_queue_size must be > 0 (specified value: %v)", v)
 	}
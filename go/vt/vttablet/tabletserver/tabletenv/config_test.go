@@ -455,3 +455,70 @@ func TestVerifyTxThrottlerConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkloadClassMap(t *testing.T) {
+	var m WorkloadClassMap
+	require.NoError(t, m.Set("OLTP:0, batch:50,analytics:90"))
+	assert.Equal(t, WorkloadClassMap{"oltp": 0, "batch": 50, "analytics": 90}, m)
+	assert.Equal(t, "analytics:90,batch:50,oltp:0", m.String())
+
+	assert.Error(t, m.Set("oltp"))
+	assert.Error(t, m.Set(":1"))
+	assert.Error(t, m.Set("oltp:high"))
+}
+
+func TestVerifyWorkloadClassConfig(t *testing.T) {
+	tests := []struct {
+		name            string
+		workloadClasses WorkloadClassMap
+		reservedSlots   WorkloadClassMap
+		wantErr         string
+	}{{
+		name: "default",
+	}, {
+		name:            "classes without reserved slots",
+		workloadClasses: WorkloadClassMap{"oltp": 0, "batch": 50},
+	}, {
+		name:            "reserved slots",
+		workloadClasses: WorkloadClassMap{"oltp": 0, "batch": 50},
+		reservedSlots:   WorkloadClassMap{"oltp": 10, "batch": 5},
+	}, {
+		name:            "priority out of range",
+		workloadClasses: WorkloadClassMap{"oltp": 101},
+		wantErr:         "--workload-classes priority of oltp must be between 0 and 100 (specified value: 101)",
+	}, {
+		name:            "unknown class",
+		workloadClasses: WorkloadClassMap{"oltp": 0},
+		reservedSlots:   WorkloadClassMap{"batch": 1},
+		wantErr:         "--queryserver-config-pool-reserved-slots reserves slots for batch, which is not in --workload-classes",
+	}, {
+		name:            "negative slots",
+		workloadClasses: WorkloadClassMap{"oltp": 0},
+		reservedSlots:   WorkloadClassMap{"oltp": -1},
+		wantErr:         "--queryserver-config-pool-reserved-slots of oltp must be >= 0 (specified value: -1)",
+	}, {
+		name:            "all the slots of the pool",
+		workloadClasses: WorkloadClassMap{"oltp": 0, "batch": 50},
+		reservedSlots:   WorkloadClassMap{"oltp": 10, "batch": 6},
+		wantErr:         "--queryserver-config-pool-reserved-slots reserves 16 slots, which leaves no shared slot in the pool size of 16",
+	}, {
+		name:            "more slots than the pool",
+		workloadClasses: WorkloadClassMap{"oltp": 0, "batch": 50},
+		reservedSlots:   WorkloadClassMap{"oltp": 10, "batch": 7},
+		wantErr:         "--queryserver-config-pool-reserved-slots reserves 17 slots, which leaves no shared slot in the pool size of 16",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := defaultConfig
+			config.WorkloadClasses = test.workloadClasses
+			config.OltpReadPool.ReservedSlots = test.reservedSlots
+
+			err := config.verifyWorkloadClassConfig()
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.wantErr)
+			}
+		})
+	}
+}
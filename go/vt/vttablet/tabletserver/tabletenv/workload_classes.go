@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tabletenv
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// WorkloadClassMap maps workload class names to a number: the priority of the
+// class in WorkloadClasses, and the slots reserved for the class in the
+// ReservedSlots of a pool. As a flag, it accepts a comma-separated list of
+// class:number pairs. Class names are not case sensitive.
+type WorkloadClassMap map[string]int
+
+// Set sets the value of this flag from parsing the given string.
+func (m *WorkloadClassMap) Set(v string) error {
+	classes := make(WorkloadClassMap)
+	for _, pair := range strings.Split(v, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		class, number, ok := strings.Cut(pair, ":")
+		if !ok || class == "" {
+			return fmt.Errorf("invalid workload class %q, expected class:number", pair)
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return fmt.Errorf("invalid number for workload class %q: %v", class, err)
+		}
+		classes[strings.ToLower(class)] = n
+	}
+	*m = classes
+	return nil
+}
+
+// String returns the string representation of this flag.
+func (m WorkloadClassMap) String() string {
+	parts := make([]string, 0, len(m))
+	for class, n := range m {
+		parts = append(parts, fmt.Sprintf("%s:%d", class, n))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// Type is part of the pflag.Value interface.
+func (m WorkloadClassMap) Type() string { return "string" }
+
+// verifyWorkloadClassConfig checks WorkloadClasses and the ReservedSlots of the
+// pools for sanity.
+func (c *TabletConfig) verifyWorkloadClassConfig() error {
+	for class, priority := range c.WorkloadClasses {
+		if priority < 0 || priority > sqlparser.MaxPriorityValue {
+			return fmt.Errorf("--workload-classes priority of %s must be between 0 and %d (specified value: %d)", class, sqlparser.MaxPriorityValue, priority)
+		}
+	}
+	pools := []struct {
+		flag string
+		cfg  ConnPoolConfig
+	}{
+		{"--queryserver-config-pool-reserved-slots", c.OltpReadPool},
+		{"--queryserver-config-stream-pool-reserved-slots", c.OlapReadPool},
+		{"--queryserver-config-transaction-cap-reserved-slots", c.TxPool},
+	}
+	for _, pool := range pools {
+		reserved := 0
+		for class, slots := range pool.cfg.ReservedSlots {
+			if _, ok := c.WorkloadClasses[class]; !ok {
+				return fmt.Errorf("%s reserves slots for %s, which is not in --workload-classes", pool.flag, class)
+			}
+			if slots < 0 {
+				return fmt.Errorf("%s of %s must be >= 0 (specified value: %d)", pool.flag, class, slots)
+			}
+			reserved += slots
+		}
+		// At least one slot is shared, for the queries without a class.
+		if reserved > 0 && reserved >= pool.cfg.Size {
+			return fmt.Errorf("%s reserves %d slots, which leaves no shared slot in the pool size of %d", pool.flag, reserved, pool.cfg.Size)
+		}
+	}
+	return nil
+}
@@ -63,6 +63,7 @@ import (
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/schema"
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/tabletenv"
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/throttle"
+	"vitess.io/vitess/go/vt/vttablet/tabletserver/throttle/base"
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/throttle/throttlerapp"
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/txserializer"
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/txthrottler"
@@ -1041,8 +1042,9 @@ func (tsv *TabletServer) computeTxSerializerKey(ctx context.Context, logStats *t
 	return key, tableName.String()
 }
 
-// MessageStream streams messages from the requested table.
-func (tsv *TabletServer) MessageStream(ctx context.Context, target *querypb.Target, name string, callback func(*sqltypes.Result) error) (err error) {
+// MessageStream streams messages from the requested table, as a member
+// of consumerGroup if the table has consumer groups.
+func (tsv *TabletServer) MessageStream(ctx context.Context, target *querypb.Target, name, consumerGroup string, callback func(*sqltypes.Result) error) (err error) {
 	return tsv.execRequest(
 		ctx, 0,
 		"MessageStream", "stream", nil,
@@ -1059,14 +1061,16 @@ func (tsv *TabletServer) MessageStream(ctx context.Context, target *querypb.Targ
 				logStats: logStats,
 				tsv:      tsv,
 			}
-			return qre.MessageStream(callback)
+			return qre.MessageStream(consumerGroup, callback)
 		},
 	)
 }
 
 // MessageAck acks the list of messages for a given message table.
+// If consumerGroup is set, the messages are only acked for it, and
+// they're acked for good once every consumer group acked them.
 // It returns the number of messages successfully acked.
-func (tsv *TabletServer) MessageAck(ctx context.Context, target *querypb.Target, name string, ids []*querypb.Value) (count int64, err error) {
+func (tsv *TabletServer) MessageAck(ctx context.Context, target *querypb.Target, name, consumerGroup string, ids []*querypb.Value) (count int64, err error) {
 	sids := make([]string, 0, len(ids))
 	for _, val := range ids {
 		sids = append(sids, sqltypes.ProtoToValue(val).ToString())
@@ -1075,9 +1079,12 @@ func (tsv *TabletServer) MessageAck(ctx context.Context, target *querypb.Target,
 	if err != nil {
 		return 0, err
 	}
-	count, err = tsv.execDML(ctx, target, func() (string, map[string]*querypb.BindVariable, error) {
+	count, err = tsv.execDML(ctx, target, func() ([]*querypb.BoundQuery, error) {
+		if consumerGroup != "" {
+			return querygen.GenerateConsumerGroupAckQueries(consumerGroup, sids)
+		}
 		query, bv := querygen.GenerateAckQuery(sids)
-		return query, bv, nil
+		return []*querypb.BoundQuery{{Sql: query, BindVariables: bv}}, nil
 	})
 	if err != nil {
 		return 0, err
@@ -1089,29 +1096,49 @@ func (tsv *TabletServer) MessageAck(ctx context.Context, target *querypb.Target,
 // PostponeMessages postpones the list of messages for a given message table.
 // It returns the number of messages successfully postponed.
 func (tsv *TabletServer) PostponeMessages(ctx context.Context, target *querypb.Target, querygen messager.QueryGenerator, ids []string) (count int64, err error) {
-	return tsv.execDML(ctx, target, func() (string, map[string]*querypb.BindVariable, error) {
+	return tsv.execDML(ctx, target, func() ([]*querypb.BoundQuery, error) {
 		query, bv := querygen.GeneratePostponeQuery(ids)
-		return query, bv, nil
+		return []*querypb.BoundQuery{{Sql: query, BindVariables: bv}}, nil
+	})
+}
+
+// HoldMessages delays the list of messages for a given message table,
+// without counting it as an attempt to send them.
+// It returns the number of messages successfully held.
+func (tsv *TabletServer) HoldMessages(ctx context.Context, target *querypb.Target, querygen messager.QueryGenerator, ids []string) (count int64, err error) {
+	return tsv.execDML(ctx, target, func() ([]*querypb.BoundQuery, error) {
+		query, bv := querygen.GenerateHoldQuery(ids)
+		return []*querypb.BoundQuery{{Sql: query, BindVariables: bv}}, nil
 	})
 }
 
 // PurgeMessages purges messages older than specified time in Unix Nanoseconds.
 // It purges at most 500 messages. It returns the number of messages successfully purged.
 func (tsv *TabletServer) PurgeMessages(ctx context.Context, target *querypb.Target, querygen messager.QueryGenerator, timeCutoff int64) (count int64, err error) {
-	return tsv.execDML(ctx, target, func() (string, map[string]*querypb.BindVariable, error) {
+	return tsv.execDML(ctx, target, func() ([]*querypb.BoundQuery, error) {
 		query, bv := querygen.GeneratePurgeQuery(timeCutoff)
-		return query, bv, nil
+		return []*querypb.BoundQuery{{Sql: query, BindVariables: bv}}, nil
+	})
+}
+
+// DeadLetterMessages moves the list of messages for a given message table
+// to its dead-letter table. It returns the number of messages successfully moved.
+func (tsv *TabletServer) DeadLetterMessages(ctx context.Context, target *querypb.Target, querygen messager.QueryGenerator, ids []string) (count int64, err error) {
+	return tsv.execDML(ctx, target, func() ([]*querypb.BoundQuery, error) {
+		return querygen.GenerateDeadLetterQueries(ids), nil
 	})
 }
 
-func (tsv *TabletServer) execDML(ctx context.Context, target *querypb.Target, queryGenerator func() (string, map[string]*querypb.BindVariable, error)) (count int64, err error) {
+// execDML executes the queries in a transaction, and returns the number
+// of rows affected by the first one.
+func (tsv *TabletServer) execDML(ctx context.Context, target *querypb.Target, queryGenerator func() ([]*querypb.BoundQuery, error)) (count int64, err error) {
 	if err = tsv.sm.StartRequest(ctx, target, false /* allowOnShutdown */); err != nil {
 		return 0, err
 	}
 	defer tsv.sm.EndRequest()
 	defer tsv.handlePanicAndSendLogStats("ack", nil, nil)
 
-	query, bv, err := queryGenerator()
+	queries, err := queryGenerator()
 	if err != nil {
 		return 0, err
 	}
@@ -1127,16 +1154,21 @@ func (tsv *TabletServer) execDML(ctx context.Context, target *querypb.Target, qu
 			tsv.Rollback(ctx, target, state.TransactionID)
 		}
 	}()
-	qr, err := tsv.Execute(ctx, target, query, bv, state.TransactionID, 0, nil)
-	if err != nil {
-		return 0, err
+	for i, query := range queries {
+		qr, err := tsv.Execute(ctx, target, query.Sql, query.BindVariables, state.TransactionID, 0, nil)
+		if err != nil {
+			return 0, err
+		}
+		if i == 0 {
+			count = int64(qr.RowsAffected)
+		}
 	}
 	if _, err = tsv.Commit(ctx, target, state.TransactionID); err != nil {
 		state.TransactionID = 0
 		return 0, err
 	}
 	state.TransactionID = 0
-	return int64(qr.RowsAffected), nil
+	return count, nil
 }
 
 // VStream streams VReplication events.
@@ -1467,6 +1499,7 @@ func (tsv *TabletServer) execRequest(
 	if options != nil {
 		span.Annotate("isolation-level", options.TransactionIsolation)
 		span.Annotate("workload_name", options.WorkloadName)
+		span.Annotate("workload_class", options.WorkloadClass)
 	}
 	trace.AnnotateSQL(span, sqlparser.Preview(sql))
 	if target != nil {
@@ -1825,6 +1858,7 @@ func (tsv *TabletServer) registerThrottlerCheckHandlers() {
 			flags := &throttle.CheckFlags{
 				LowPriority:           (r.URL.Query().Get("p") == "low"),
 				SkipRequestHeartbeats: (r.URL.Query().Get("s") == "true"),
+				MetricNames:           base.ParseMetricNames(r.URL.Query().Get("metric")),
 			}
 			checkResult := tsv.lagThrottler.CheckByType(ctx, appName, remoteAddr, flags, checkType)
 			if checkResult.StatusCode == http.StatusNotFound && flags.OKIfNotExists {
@@ -1348,7 +1348,7 @@ func TestMessageStream(t *testing.T) {
 	defer tsv.StopService()
 	target := querypb.Target{TabletType: topodatapb.TabletType_PRIMARY}
 
-	err := tsv.MessageStream(ctx, &target, "nomsg", func(qr *sqltypes.Result) error {
+	err := tsv.MessageStream(ctx, &target, "nomsg", "", func(qr *sqltypes.Result) error {
 		return nil
 	})
 	wantErr := "table nomsg not found in schema"
@@ -1358,7 +1358,7 @@ func TestMessageStream(t *testing.T) {
 
 	// Check that the streaming mechanism works.
 	called := false
-	err = tsv.MessageStream(ctx, &target, "msg", func(qr *sqltypes.Result) error {
+	err = tsv.MessageStream(ctx, &target, "msg", "", func(qr *sqltypes.Result) error {
 		called = true
 		return io.EOF
 	})
@@ -1413,18 +1413,18 @@ func TestMessageAck(t *testing.T) {
 		Type:  sqltypes.VarChar,
 		Value: []byte("2"),
 	}}
-	_, err := tsv.MessageAck(ctx, &target, "nonmsg", ids)
+	_, err := tsv.MessageAck(ctx, &target, "nonmsg", "", ids)
 	want := "message table nonmsg not found in schema"
 	require.Error(t, err)
 	require.Contains(t, err.Error(), want)
 
-	_, err = tsv.MessageAck(ctx, &target, "msg", ids)
+	_, err = tsv.MessageAck(ctx, &target, "msg", "", ids)
 	want = "query: 'update msg set time_acked"
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), want)
 
 	db.AddQueryPattern("update msg set time_acked = .*", &sqltypes.Result{RowsAffected: 1})
-	count, err := tsv.MessageAck(ctx, &target, "msg", ids)
+	count, err := tsv.MessageAck(ctx, &target, "msg", "", ids)
 	require.NoError(t, err)
 	require.EqualValues(t, 1, count)
 }
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MetricName is the name of a metric collected by the throttler
+type MetricName string
+
+const (
+	// DefaultMetricName is the metric checked by apps that are not mapped to any metric: replication lag,
+	// or the custom query of the throttler config
+	DefaultMetricName MetricName = "default"
+	// LagMetricName is the replication lag, as measured by the heartbeat table
+	LagMetricName MetricName = "lag"
+	// ThreadsRunningMetricName is the number of running threads, as reported by MySQL's global status
+	ThreadsRunningMetricName MetricName = "threads_running"
+	// HistoryListLengthMetricName is the length of the InnoDB history list
+	HistoryListLengthMetricName MetricName = "history_list_length"
+)
+
+// wellKnownMetricNames are the metrics the throttler knows how to read without being given a query
+var wellKnownMetricNames = []MetricName{
+	LagMetricName,
+	ThreadsRunningMetricName,
+	HistoryListLengthMetricName,
+}
+
+var metricNameRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+func (metric MetricName) String() string {
+	return string(metric)
+}
+
+// IsWellKnown returns true when the metric has a default query
+func (metric MetricName) IsWellKnown() bool {
+	for _, name := range wellKnownMetricNames {
+		if name == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateMetricName returns an error when the given name is not a valid name for a named metric:
+// names are made of lowercase letters, digits and underscores, and "default" is reserved
+func ValidateMetricName(name string) error {
+	if name == DefaultMetricName.String() {
+		return fmt.Errorf("metric name %q is reserved", name)
+	}
+	if !metricNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid metric name %q: expecting lowercase letters, digits and underscores", name)
+	}
+	return nil
+}
+
+// ParseMetricNames parses a comma separated list of metric names, e.g. "lag,threads_running"
+func ParseMetricNames(names string) (metricNames []MetricName) {
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			metricNames = append(metricNames, MetricName(name))
+		}
+	}
+	return metricNames
+}
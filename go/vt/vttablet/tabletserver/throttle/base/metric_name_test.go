@@ -0,0 +1,57 @@
+/*
+Copyright 2023 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMetricName(t *testing.T) {
+	tcases := map[string]bool{
+		"lag":             true,
+		"threads_running": true,
+		"queue_size_2":    true,
+		"default":         false,
+		"":                false,
+		"Lag":             false,
+		"self.lag":        false,
+		"mysql/lag":       false,
+		"lag,queue":       false,
+	}
+	for name, expectValid := range tcases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateMetricName(name)
+			assert.Equal(t, expectValid, err == nil)
+		})
+	}
+}
+
+func TestIsWellKnown(t *testing.T) {
+	assert.True(t, LagMetricName.IsWellKnown())
+	assert.True(t, ThreadsRunningMetricName.IsWellKnown())
+	assert.True(t, HistoryListLengthMetricName.IsWellKnown())
+	assert.False(t, DefaultMetricName.IsWellKnown())
+	assert.False(t, MetricName("queue_size").IsWellKnown())
+}
+
+func TestParseMetricNames(t *testing.T) {
+	assert.Empty(t, ParseMetricNames(""))
+	assert.Equal(t, []MetricName{LagMetricName}, ParseMetricNames("lag"))
+	assert.Equal(t, []MetricName{LagMetricName, ThreadsRunningMetricName}, ParseMetricNames("lag, threads_running,"))
+}
@@ -31,6 +31,7 @@ type CheckFlags struct {
 	LowPriority           bool
 	OKIfNotExists         bool
 	SkipRequestHeartbeats bool
+	MetricNames           []base.MetricName // metrics to check instead of those that the app checks
 }
 
 // StandardCheckFlags have no special hints
@@ -118,11 +119,11 @@ func (check *ThrottlerCheck) Check(ctx context.Context, appName string, storeTyp
 
 	go func(statusCode int) {
 		stats.GetOrNewCounter("ThrottlerCheckAnyTotal", "total number of checks").Add(1)
-		stats.GetOrNewCounter(fmt.Sprintf("ThrottlerCheckAny%s%sTotal", textutil.SingleWordCamel(storeType), textutil.SingleWordCamel(storeName)), "").Add(1)
+		stats.GetOrNewCounter(fmt.Sprintf("ThrottlerCheckAny%s%sTotal", textutil.SingleWordCamel(storeType), camelStoreName(storeName)), "").Add(1)
 
 		if statusCode != http.StatusOK {
 			stats.GetOrNewCounter("ThrottlerCheckAnyError", "total number of failed checks").Add(1)
-			stats.GetOrNewCounter(fmt.Sprintf("ThrottlerCheckAny%s%sError", textutil.SingleWordCamel(storeType), textutil.SingleWordCamel(storeName)), "").Add(1)
+			stats.GetOrNewCounter(fmt.Sprintf("ThrottlerCheckAny%s%sError", textutil.SingleWordCamel(storeType), camelStoreName(storeName)), "").Add(1)
 		}
 
 		check.throttler.markRecentApp(appName, remoteAddr)
@@ -131,6 +132,16 @@ func (check *ThrottlerCheck) Check(ctx context.Context, appName string, storeTyp
 	return checkResult
 }
 
+// camelStoreName returns the given store name in camel case, for use in stats names, e.g. "SelfThreadsRunning"
+// for the "self.threads_running" store
+func camelStoreName(storeName string) string {
+	var b strings.Builder
+	for _, token := range strings.FieldsFunc(storeName, func(r rune) bool { return r == '.' || r == '_' }) {
+		b.WriteString(textutil.SingleWordCamel(token))
+	}
+	return b.String()
+}
+
 func (check *ThrottlerCheck) splitMetricTokens(metricName string) (storeType string, storeName string, err error) {
 	metricTokens := strings.Split(metricName, "/")
 	if len(metricTokens) != 2 {
@@ -154,7 +165,7 @@ func (check *ThrottlerCheck) localCheck(ctx context.Context, metricName string)
 		check.throttler.markMetricHealthy(metricName)
 	}
 	if timeSinceHealthy, found := check.throttler.timeSinceMetricHealthy(metricName); found {
-		stats.GetOrNewGauge(fmt.Sprintf("ThrottlerCheck%s%sSecondsSinceHealthy", textutil.SingleWordCamel(storeType), textutil.SingleWordCamel(storeName)), fmt.Sprintf("seconds since last healthy cehck for %s.%s", storeType, storeName)).Set(int64(timeSinceHealthy.Seconds()))
+		stats.GetOrNewGauge(fmt.Sprintf("ThrottlerCheck%s%sSecondsSinceHealthy", textutil.SingleWordCamel(storeType), camelStoreName(storeName)), fmt.Sprintf("seconds since last healthy cehck for %s.%s", storeType, storeName)).Set(int64(timeSinceHealthy.Seconds()))
 	}
 
 	return checkResult
@@ -166,7 +177,7 @@ func (check *ThrottlerCheck) reportAggregated(metricName string, metricResult ba
 		return
 	}
 	if value, err := metricResult.Get(); err == nil {
-		stats.GetOrNewGaugeFloat64(fmt.Sprintf("ThrottlerAggregated%s%s", textutil.SingleWordCamel(storeType), textutil.SingleWordCamel(storeName)), fmt.Sprintf("aggregated value for %s.%s", storeType, storeName)).Set(value)
+		stats.GetOrNewGaugeFloat64(fmt.Sprintf("ThrottlerAggregated%s%s", textutil.SingleWordCamel(storeType), camelStoreName(storeName)), fmt.Sprintf("aggregated value for %s.%s", storeType, storeName)).Set(value)
 	}
 }
 
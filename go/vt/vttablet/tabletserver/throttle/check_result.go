@@ -20,6 +20,9 @@ type CheckResult struct {
 	Error           error   `json:"-"`
 	Message         string  `json:"Message"`
 	RecentlyChecked bool    `json:"RecentlyChecked"`
+
+	// Metrics are the results of the individual metrics, when more than one metric is checked
+	Metrics map[string]*CheckResult `json:"Metrics,omitempty"`
 }
 
 // NewCheckResult returns a CheckResult
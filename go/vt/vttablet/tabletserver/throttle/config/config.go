@@ -6,12 +6,24 @@
 
 package config
 
-// Instance is the one configuration for the throttler
-var Instance = &ConfigurationSettings{}
+import "sync/atomic"
+
+// instance is the one configuration for the throttler. It is replaced, not changed, so that it can be read
+// while it's being replaced.
+var instance atomic.Pointer[ConfigurationSettings]
+
+func init() {
+	instance.Store(&ConfigurationSettings{})
+}
 
 // Settings returns the settings of the global instance of Configuration
 func Settings() *ConfigurationSettings {
-	return Instance
+	return instance.Load()
+}
+
+// SetSettings replaces the settings of the global instance of Configuration
+func SetSettings(settings *ConfigurationSettings) {
+	instance.Store(settings)
 }
 
 // ConfigurationSettings models a set of configurable values, that can be
@@ -17,6 +17,7 @@ import (
 // MySQLClusterConfigurationSettings has the settings for a specific MySQL cluster. It derives its information
 // from MySQLConfigurationSettings
 type MySQLClusterConfigurationSettings struct {
+	MetricName           string         // name of the metric the cluster collects, or empty for the default metric
 	MetricQuery          string         // override MySQLConfigurationSettings's, or leave empty to inherit those settings
 	CacheMillis          int            // override MySQLConfigurationSettings's, or leave empty to inherit those settings
 	ThrottleThreshold    *atomic.Uint64 // override MySQLConfigurationSettings's, or leave empty to inherit those settings
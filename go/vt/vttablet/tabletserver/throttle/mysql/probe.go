@@ -15,6 +15,7 @@ import (
 // Probe is the minimal configuration required to connect to a MySQL server
 type Probe struct {
 	Key             InstanceKey
+	MetricName      string
 	MetricQuery     string
 	Tablet          *topodatapb.Tablet
 	TabletHost      string
@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"math"
 	"math/rand"
 	"net/http"
@@ -23,6 +24,7 @@ import (
 
 	"github.com/patrickmn/go-cache"
 	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/proto"
 
 	"vitess.io/vitess/go/constants/sidecar"
 	"vitess.io/vitess/go/protoutil"
@@ -67,6 +69,8 @@ const (
 	selfStoreName  = "self"
 
 	defaultReplicationLagQuery = "select unix_timestamp(now(6))-max(ts/1000000000) as replication_lag from %s.heartbeat"
+	threadsRunningQuery        = "show global status like 'threads_running'"
+	historyListLengthQuery     = "select count as history_list_length from information_schema.innodb_metrics where name = 'trx_rseg_history_len'"
 )
 
 var (
@@ -156,6 +160,11 @@ type Throttler struct {
 	MetricsThreshold atomic.Uint64
 	checkAsCheckSelf atomic.Bool
 
+	// namedMetrics are the metrics collected in addition to the default metric, and appCheckedMetrics
+	// are the metrics that apps check instead of the default metric. Both are replaced, not changed.
+	namedMetrics      map[base.MetricName]*topodatapb.ThrottlerMetric
+	appCheckedMetrics map[string][]base.MetricName
+
 	mysqlClusterThresholds *cache.Cache
 	aggregatedMetrics      *cache.Cache
 	throttledApps          *cache.Cache
@@ -169,6 +178,7 @@ type Throttler struct {
 	cancelOpenContext    context.CancelFunc
 	cancelEnableContext  context.CancelFunc
 	throttledAppsMutex   sync.Mutex
+	namedMetricsMutex    sync.RWMutex
 	watchSrvKeyspaceOnce sync.Once
 
 	nonLowPriorityAppRequestsThrottled *cache.Cache
@@ -188,6 +198,9 @@ type ThrottlerStatus struct {
 	Query     string
 	Threshold float64
 
+	Metrics           map[base.MetricName]*topodatapb.ThrottlerMetric
+	AppCheckedMetrics map[string][]base.MetricName
+
 	AggregatedMetrics map[string]base.MetricResult
 	MetricsHealth     base.MetricHealthMap
 }
@@ -267,7 +280,7 @@ func (throttler *Throttler) GetMetricsThreshold() float64 {
 func (throttler *Throttler) initConfig() {
 	log.Infof("Throttler: initializing config")
 
-	config.Instance = &config.ConfigurationSettings{
+	settings := &config.ConfigurationSettings{
 		Stores: config.StoresSettings{
 			MySQL: config.MySQLConfigurationSettings{
 				IgnoreDialTCPErrors: true,
@@ -275,16 +288,125 @@ func (throttler *Throttler) initConfig() {
 			},
 		},
 	}
-	config.Instance.Stores.MySQL.Clusters[selfStoreName] = &config.MySQLClusterConfigurationSettings{
+	settings.Stores.MySQL.Clusters[selfStoreName] = &config.MySQLClusterConfigurationSettings{
 		MetricQuery:       throttler.GetMetricsQuery(),
 		ThrottleThreshold: &throttler.MetricsThreshold,
 		IgnoreHostsCount:  0,
 	}
-	config.Instance.Stores.MySQL.Clusters[shardStoreName] = &config.MySQLClusterConfigurationSettings{
+	settings.Stores.MySQL.Clusters[shardStoreName] = &config.MySQLClusterConfigurationSettings{
 		MetricQuery:       throttler.GetMetricsQuery(),
 		ThrottleThreshold: &throttler.MetricsThreshold,
 		IgnoreHostsCount:  0,
 	}
+	// Each named metric is collected in its own self and shard clusters, with its own query and threshold
+	namedMetrics, _ := throttler.namedMetricsSnapshot()
+	for metricName, metric := range namedMetrics {
+		threshold := &atomic.Uint64{}
+		threshold.Store(math.Float64bits(metric.Threshold))
+		for _, storeName := range []string{selfStoreName, shardStoreName} {
+			settings.Stores.MySQL.Clusters[metricStoreName(storeName, metricName)] = &config.MySQLClusterConfigurationSettings{
+				MetricName:        metricName.String(),
+				MetricQuery:       metric.Query,
+				ThrottleThreshold: threshold,
+				IgnoreHostsCount:  0,
+			}
+		}
+	}
+	// The settings are read by the goroutines that collect the metrics, so they're replaced, not changed
+	config.SetSettings(settings)
+}
+
+// metricStoreName returns the name of the store, and of the MySQL cluster, that collects the given metric
+// for the given store: the store itself for the default metric, e.g. "self", or the store followed by the
+// metric name for named metrics, e.g. "self.threads_running"
+func metricStoreName(storeName string, metricName base.MetricName) string {
+	if metricName == "" || metricName == base.DefaultMetricName {
+		return storeName
+	}
+	return fmt.Sprintf("%s.%s", storeName, metricName)
+}
+
+// isSelfStoreName returns true when the given store collects a metric of this tablet's own MySQL server
+func isSelfStoreName(storeName string) bool {
+	return storeName == selfStoreName || strings.HasPrefix(storeName, selfStoreName+".")
+}
+
+// wellKnownMetricQuery returns the default query of a well known metric, or an empty string for other metrics
+func wellKnownMetricQuery(metricName base.MetricName) string {
+	switch metricName {
+	case base.LagMetricName:
+		return sqlparser.BuildParsedQuery(defaultReplicationLagQuery, sidecar.GetIdentifier()).Query
+	case base.ThreadsRunningMetricName:
+		return threadsRunningQuery
+	case base.HistoryListLengthMetricName:
+		return historyListLengthQuery
+	}
+	return ""
+}
+
+// storeNamedMetrics stores the named metrics of a throttler config, resolving the query of well known
+// metrics that have no query, along with the metrics that each app checks. It returns true if the named
+// metrics changed.
+func (throttler *Throttler) storeNamedMetrics(metrics map[string]*topodatapb.ThrottlerMetric, appCheckedMetrics map[string]*topodatapb.ThrottlerMetricNames) bool {
+	namedMetrics := make(map[base.MetricName]*topodatapb.ThrottlerMetric, len(metrics))
+	for name, metric := range metrics {
+		if err := base.ValidateMetricName(name); err != nil {
+			log.Errorf("Throttler: ignoring metric: %v", err)
+			continue
+		}
+		metricName := base.MetricName(name)
+		query := metric.GetQuery()
+		if query == "" {
+			query = wellKnownMetricQuery(metricName)
+		}
+		if query == "" {
+			log.Errorf("Throttler: ignoring metric %s, which has no query", metricName)
+			continue
+		}
+		namedMetrics[metricName] = &topodatapb.ThrottlerMetric{Query: query, Threshold: metric.GetThreshold()}
+	}
+	appMetrics := make(map[string][]base.MetricName, len(appCheckedMetrics))
+	for appName, metricNames := range appCheckedMetrics {
+		for _, name := range metricNames.GetNames() {
+			appMetrics[appName] = append(appMetrics[appName], base.MetricName(name))
+		}
+	}
+
+	throttler.namedMetricsMutex.Lock()
+	defer throttler.namedMetricsMutex.Unlock()
+	changed := !maps.EqualFunc(throttler.namedMetrics, namedMetrics, func(m1, m2 *topodatapb.ThrottlerMetric) bool {
+		return proto.Equal(m1, m2)
+	})
+	throttler.namedMetrics = namedMetrics
+	throttler.appCheckedMetrics = appMetrics
+	return changed
+}
+
+// namedMetricsSnapshot returns the named metrics and the metrics checked by apps, which the caller must not change
+func (throttler *Throttler) namedMetricsSnapshot() (map[base.MetricName]*topodatapb.ThrottlerMetric, map[string][]base.MetricName) {
+	throttler.namedMetricsMutex.RLock()
+	defer throttler.namedMetricsMutex.RUnlock()
+	return throttler.namedMetrics, throttler.appCheckedMetrics
+}
+
+// getAppCheckedMetrics returns the metrics checked by the given app. The app name may be a colon separated
+// list of names, e.g. "vcopier:vreplication:online-ddl", in which case the first name that is mapped to
+// metrics applies. Apps that are not mapped check the default metric, and so do the throttler's own checks.
+func (throttler *Throttler) getAppCheckedMetrics(appName string) []base.MetricName {
+	defaultMetricNames := []base.MetricName{base.DefaultMetricName}
+	if throttlerapp.VitessName.Equals(appName) {
+		return defaultMetricNames
+	}
+	_, appCheckedMetrics := throttler.namedMetricsSnapshot()
+	if metricNames, ok := appCheckedMetrics[appName]; ok {
+		return metricNames
+	}
+	for _, singleAppName := range strings.Split(appName, ":") {
+		if metricNames, ok := appCheckedMetrics[singleAppName]; ok {
+			return metricNames
+		}
+	}
+	return defaultMetricNames
 }
 
 // readThrottlerConfig proactively reads the throttler's config from SrvKeyspace in local topo
@@ -349,6 +471,9 @@ func (throttler *Throttler) applyThrottlerConfig(ctx context.Context, throttlerC
 		throttler.metricsQuery.Store(throttlerConfig.CustomQuery)
 	}
 	throttler.StoreMetricsThreshold(throttlerConfig.Threshold)
+	if throttler.storeNamedMetrics(throttlerConfig.Metrics, throttlerConfig.AppCheckedMetrics) {
+		throttler.initConfig() // named metrics were added, removed or changed
+	}
 	throttler.checkAsCheckSelf.Store(throttlerConfig.CheckAsCheckSelf)
 	for _, appRule := range throttlerConfig.ThrottledApps {
 		throttler.ThrottleApp(appRule.Name, protoutil.TimeFromProto(appRule.ExpiresAt).UTC(), appRule.Ratio, appRule.Exempt)
@@ -522,17 +647,17 @@ func (throttler *Throttler) Close() {
 	log.Infof("Throttler: finished execution of Close")
 }
 
-func (throttler *Throttler) generateSelfMySQLThrottleMetricFunc(ctx context.Context, probe *mysql.Probe) func() *mysql.MySQLThrottleMetric {
+func (throttler *Throttler) generateSelfMySQLThrottleMetricFunc(ctx context.Context, clusterName string, probe *mysql.Probe) func() *mysql.MySQLThrottleMetric {
 	f := func() *mysql.MySQLThrottleMetric {
-		return throttler.readSelfMySQLThrottleMetric(ctx, probe)
+		return throttler.readSelfMySQLThrottleMetric(ctx, clusterName, probe)
 	}
 	return f
 }
 
 // readSelfMySQLThrottleMetric reads the mysql metric from thi very tablet's backend mysql.
-func (throttler *Throttler) readSelfMySQLThrottleMetric(ctx context.Context, probe *mysql.Probe) *mysql.MySQLThrottleMetric {
+func (throttler *Throttler) readSelfMySQLThrottleMetric(ctx context.Context, clusterName string, probe *mysql.Probe) *mysql.MySQLThrottleMetric {
 	metric := &mysql.MySQLThrottleMetric{
-		ClusterName: selfStoreName,
+		ClusterName: clusterName,
 		Key:         *mysql.SelfInstanceKey,
 		Value:       0,
 		Err:         nil,
@@ -555,7 +680,7 @@ func (throttler *Throttler) readSelfMySQLThrottleMetric(ctx context.Context, pro
 		return metric
 	}
 
-	metricsQueryType := mysql.GetMetricsQueryType(throttler.GetMetricsQuery())
+	metricsQueryType := mysql.GetMetricsQueryType(probe.MetricQuery)
 	switch metricsQueryType {
 	case mysql.MetricsQueryTypeSelect:
 		// We expect a single row, single column result.
@@ -566,7 +691,7 @@ func (throttler *Throttler) readSelfMySQLThrottleMetric(ctx context.Context, pro
 	case mysql.MetricsQueryTypeShowGlobal:
 		metric.Value, metric.Err = strconv.ParseFloat(row["Value"].ToString(), 64)
 	default:
-		metric.Err = fmt.Errorf("Unsupported metrics query type for query: %s", throttler.GetMetricsQuery())
+		metric.Err = fmt.Errorf("Unsupported metrics query type for query: %s", probe.MetricQuery)
 	}
 
 	return metric
@@ -682,6 +807,7 @@ func (throttler *Throttler) Operate(ctx context.Context) {
 				{
 					// sparse
 					if throttler.IsOpen() {
+						throttler.pruneMySQLClusterProbes()
 						go throttler.refreshMySQLInventory(ctx)
 					}
 				}
@@ -704,6 +830,8 @@ func (throttler *Throttler) Operate(ctx context.Context) {
 				}
 			case throttlerConfig := <-throttler.throttlerConfigChan:
 				throttler.applyThrottlerConfig(ctx, throttlerConfig)
+				// named metrics may have changed, let's speed up the next 'refresh' tick
+				go mysqlRefreshTicker.TickNow()
 			case <-recentCheckTicker.C:
 				// Increment recentCheckTickerValue by one.
 				atomic.AddInt64(&throttler.recentCheckTickerValue, 1)
@@ -720,7 +848,9 @@ func (throttler *Throttler) generateTabletHTTPProbeFunction(ctx context.Context,
 		mySQLThrottleMetric.Key = probe.Key
 
 		{
-			req := &tabletmanagerdatapb.CheckThrottlerRequest{} // We leave AppName empty; it will default to VitessName anyway, and we can save some proto space
+			// We leave AppName empty; it will default to VitessName anyway, and we can save some proto space.
+			// MetricName is likewise empty for the default metric.
+			req := &tabletmanagerdatapb.CheckThrottlerRequest{MetricName: probe.MetricName}
 			if resp, gRPCErr := tmClient.CheckThrottler(ctx, probe.Tablet, req); gRPCErr == nil {
 				mySQLThrottleMetric.Value = resp.Value
 				if resp.StatusCode == http.StatusInternalServerError {
@@ -744,6 +874,9 @@ func (throttler *Throttler) generateTabletHTTPProbeFunction(ctx context.Context,
 		}
 		// Backwards compatibility to v17: if the underlying tablets do not support CheckThrottler gRPC, attempt a HTTP cehck:
 		tabletCheckSelfURL := fmt.Sprintf("http://%s:%d/throttler/check-self?app=%s", probe.TabletHost, probe.TabletPort, throttlerapp.VitessName)
+		if probe.MetricName != "" {
+			tabletCheckSelfURL = fmt.Sprintf("%s&metric=%s", tabletCheckSelfURL, probe.MetricName)
+		}
 		resp, err := throttler.httpClient.Get(tabletCheckSelfURL)
 		if err != nil {
 			mySQLThrottleMetric.Err = err
@@ -793,9 +926,9 @@ func (throttler *Throttler) collectMySQLMetrics(ctx context.Context, tmClient tm
 					defer atomic.StoreInt64(&probe.QueryInProgress, 0)
 
 					var throttleMetricFunc func() *mysql.MySQLThrottleMetric
-					if clusterName == selfStoreName {
+					if isSelfStoreName(clusterName) {
 						// Throttler is probing its own tablet's metrics:
-						throttleMetricFunc = throttler.generateSelfMySQLThrottleMetricFunc(ctx, probe)
+						throttleMetricFunc = throttler.generateSelfMySQLThrottleMetricFunc(ctx, clusterName, probe)
 					} else {
 						// Throttler probing other tablets:
 						throttleMetricFunc = throttler.generateTabletHTTPProbeFunction(ctx, tmClient, clusterName, probe)
@@ -831,6 +964,7 @@ func (throttler *Throttler) refreshMySQLInventory(ctx context.Context) error {
 			Tablet:      tablet,
 			TabletHost:  tabletHost,
 			TabletPort:  tabletPort,
+			MetricName:  clusterSettings.MetricName,
 			MetricQuery: clusterSettings.MetricQuery,
 			CacheMillis: clusterSettings.CacheMillis,
 		}
@@ -840,8 +974,11 @@ func (throttler *Throttler) refreshMySQLInventory(ctx context.Context) error {
 	for clusterName, clusterSettings := range config.Settings().Stores.MySQL.Clusters {
 		clusterName := clusterName
 		clusterSettings := clusterSettings
-		clusterSettings.MetricQuery = metricsQuery
-		clusterSettings.ThrottleThreshold.Store(metricsThreshold)
+		if clusterSettings.MetricName == "" {
+			// default metric. Named metrics have their own query and threshold.
+			clusterSettings.MetricQuery = metricsQuery
+			clusterSettings.ThrottleThreshold.Store(metricsThreshold)
+		}
 		// config may dynamically change, but internal structure (config.Settings().Stores.MySQL.Clusters in our case)
 		// is immutable and can only be _replaced_. Hence, it's safe to read in a goroutine:
 		go func() {
@@ -852,7 +989,7 @@ func (throttler *Throttler) refreshMySQLInventory(ctx context.Context) error {
 				InstanceProbes:   mysql.NewProbes(),
 			}
 
-			if clusterName == selfStoreName {
+			if isSelfStoreName(clusterName) {
 				// special case: just looking at this tablet's MySQL server.
 				// We will probe this "cluster" (of one server) is a special way.
 				addInstanceKey(nil, "", 0, mysql.SelfInstanceKey, clusterName, clusterSettings, clusterProbes.InstanceProbes)
@@ -897,6 +1034,22 @@ func (throttler *Throttler) refreshMySQLInventory(ctx context.Context) error {
 	return nil
 }
 
+// synchronous removal of the probes of clusters that are no longer configured, e.g. those of a named metric
+// that was removed from the throttler config
+func (throttler *Throttler) pruneMySQLClusterProbes() {
+	clusters := config.Settings().Stores.MySQL.Clusters
+	for clusterName := range throttler.mysqlInventory.ClustersProbes {
+		if _, ok := clusters[clusterName]; ok {
+			continue
+		}
+		delete(throttler.mysqlInventory.ClustersProbes, clusterName)
+		delete(throttler.mysqlInventory.IgnoreHostsCount, clusterName)
+		delete(throttler.mysqlInventory.IgnoreHostsThreshold, clusterName)
+		throttler.mysqlClusterThresholds.Delete(clusterName)
+		throttler.aggregatedMetrics.Delete(fmt.Sprintf("mysql/%s", clusterName))
+	}
+}
+
 // synchronous update of inventory
 func (throttler *Throttler) updateMySQLClusterProbes(ctx context.Context, clusterProbes *mysql.ClusterProbes) error {
 	throttler.mysqlInventory.ClustersProbes[clusterProbes.ClusterName] = clusterProbes.InstanceProbes
@@ -1142,7 +1295,11 @@ func (throttler *Throttler) checkStore(ctx context.Context, appName string, stor
 		// to the PRIMARY so that it knows it must renew the heartbeat lease.
 		atomic.StoreInt64(&throttler.recentCheckValue, 1+atomic.LoadInt64(&throttler.recentCheckTickerValue))
 	}
-	checkResult = throttler.check.Check(ctx, appName, "mysql", storeName, remoteAddr, flags)
+	metricNames := flags.MetricNames
+	if len(metricNames) == 0 {
+		metricNames = throttler.getAppCheckedMetrics(appName)
+	}
+	checkResult = throttler.checkStoreMetrics(ctx, appName, storeName, remoteAddr, flags, metricNames)
 
 	if atomic.LoadInt64(&throttler.recentCheckValue) >= atomic.LoadInt64(&throttler.recentCheckTickerValue) {
 		// This indicates someone, who is not "vitess" ie not internal to the throttling logic, did a _recent_ `check`.
@@ -1155,6 +1312,27 @@ func (throttler *Throttler) checkStore(ctx context.Context, appName string, stor
 	return checkResult
 }
 
+// checkStoreMetrics checks the given metrics of the given MySQL store. When checking more than one metric, the
+// result is that of the first metric that fails its check, or else that of the first metric, and it lists the
+// result of each of the metrics.
+func (throttler *Throttler) checkStoreMetrics(ctx context.Context, appName string, storeName string, remoteAddr string, flags *CheckFlags, metricNames []base.MetricName) (checkResult *CheckResult) {
+	if len(metricNames) == 1 {
+		return throttler.check.Check(ctx, appName, "mysql", metricStoreName(storeName, metricNames[0]), remoteAddr, flags)
+	}
+	metricsCheckResults := make(map[string]*CheckResult, len(metricNames))
+	for _, metricName := range metricNames {
+		metricCheckResult := throttler.check.Check(ctx, appName, "mysql", metricStoreName(storeName, metricName), remoteAddr, flags)
+		metricsCheckResults[metricName.String()] = metricCheckResult
+		if checkResult == nil || (checkResult.StatusCode == http.StatusOK && metricCheckResult.StatusCode != http.StatusOK) {
+			checkResult = metricCheckResult
+		}
+	}
+	// Check() may return shared results, so we return a copy
+	result := *checkResult
+	result.Metrics = metricsCheckResults
+	return &result
+}
+
 // checkShard checks the health of the shard, and runs on the primary tablet only
 func (throttler *Throttler) checkShard(ctx context.Context, appName string, remoteAddr string, flags *CheckFlags) (checkResult *CheckResult) {
 	return throttler.checkStore(ctx, appName, shardStoreName, remoteAddr, flags)
@@ -1182,6 +1360,7 @@ func (throttler *Throttler) CheckByType(ctx context.Context, appName string, rem
 
 // Status exports a status breakdown
 func (throttler *Throttler) Status() *ThrottlerStatus {
+	namedMetrics, appCheckedMetrics := throttler.namedMetricsSnapshot()
 	return &ThrottlerStatus{
 		Keyspace: throttler.keyspace,
 		Shard:    throttler.shard,
@@ -1194,6 +1373,9 @@ func (throttler *Throttler) Status() *ThrottlerStatus {
 		Query:     throttler.GetMetricsQuery(),
 		Threshold: throttler.GetMetricsThreshold(),
 
+		Metrics:           namedMetrics,
+		AppCheckedMetrics: appCheckedMetrics,
+
 		AggregatedMetrics: throttler.aggregatedMetricsSnapshot(),
 		MetricsHealth:     throttler.metricsHealthSnapshot(),
 	}
@@ -9,6 +9,7 @@ package throttle
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -18,6 +19,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"vitess.io/vitess/go/vt/topo"
+	"vitess.io/vitess/go/vt/vttablet/tabletserver/throttle/base"
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/throttle/config"
 	"vitess.io/vitess/go/vt/vttablet/tabletserver/throttle/mysql"
 
@@ -121,6 +123,126 @@ func TestIsAppExempted(t *testing.T) {
 	assert.True(t, throttler.IsAppExempted("schema-tracker"))
 }
 
+func TestGetAppCheckedMetrics(t *testing.T) {
+	throttler := &Throttler{}
+	changed := throttler.storeNamedMetrics(
+		map[string]*topodatapb.ThrottlerMetric{
+			"threads_running": {Threshold: 100},
+			"queue_size":      {Query: "select count(*) from queue", Threshold: 10},
+			"default":         {Query: "select 1", Threshold: 1}, // reserved name, ignored
+			"unknown":         {Threshold: 1},                    // no query, ignored
+		},
+		map[string]*topodatapb.ThrottlerMetricNames{
+			"online-ddl":   {Names: []string{"default", "threads_running"}},
+			"vreplication": {Names: []string{"queue_size"}},
+			"vitess":       {Names: []string{"queue_size"}},
+		},
+	)
+	assert.True(t, changed)
+	namedMetrics, _ := throttler.namedMetricsSnapshot()
+	assert.Equal(t, map[base.MetricName]*topodatapb.ThrottlerMetric{
+		base.ThreadsRunningMetricName: {Query: threadsRunningQuery, Threshold: 100},
+		"queue_size":                  {Query: "select count(*) from queue", Threshold: 10},
+	}, namedMetrics)
+
+	tcases := map[string][]base.MetricName{
+		"online-ddl":                      {base.DefaultMetricName, base.ThreadsRunningMetricName},
+		"vreplication":                    {"queue_size"},
+		"vcopier:vreplication:online-ddl": {"queue_size"},
+		"vdiff":                           {base.DefaultMetricName},
+		"vitess":                          {base.DefaultMetricName}, // the throttler's own checks are not mapped
+	}
+	for appName, expectMetricNames := range tcases {
+		t.Run(appName, func(t *testing.T) {
+			assert.Equal(t, expectMetricNames, throttler.getAppCheckedMetrics(appName))
+		})
+	}
+}
+
+func TestInitConfigNamedMetrics(t *testing.T) {
+	throttler := &Throttler{}
+	throttler.metricsQuery.Store("select 1")
+	metrics := map[string]*topodatapb.ThrottlerMetric{
+		"threads_running": {Threshold: 100},
+	}
+	assert.True(t, throttler.storeNamedMetrics(metrics, nil))
+	throttler.initConfig()
+	clusters := config.Settings().Stores.MySQL.Clusters
+	assert.Len(t, clusters, 4)
+	require.Contains(t, clusters, "self.threads_running")
+	assert.Equal(t, threadsRunningQuery, clusters["self.threads_running"].MetricQuery)
+	assert.Equal(t, "select 1", clusters["shard"].MetricQuery)
+
+	// the same metrics, e.g. on a SrvKeyspace update of another setting, don't change the config
+	assert.False(t, throttler.storeNamedMetrics(metrics, map[string]*topodatapb.ThrottlerMetricNames{
+		"online-ddl": {Names: []string{"threads_running"}},
+	}))
+	assert.True(t, throttler.storeNamedMetrics(map[string]*topodatapb.ThrottlerMetric{
+		"threads_running": {Threshold: 50},
+	}, nil))
+	assert.True(t, throttler.storeNamedMetrics(nil, nil))
+	throttler.initConfig()
+	assert.Len(t, config.Settings().Stores.MySQL.Clusters, 2)
+}
+
+func TestMetricStoreName(t *testing.T) {
+	assert.Equal(t, "self", metricStoreName(selfStoreName, base.DefaultMetricName))
+	assert.Equal(t, "shard", metricStoreName(shardStoreName, ""))
+	assert.Equal(t, "self.threads_running", metricStoreName(selfStoreName, base.ThreadsRunningMetricName))
+	assert.True(t, isSelfStoreName("self"))
+	assert.True(t, isSelfStoreName("self.threads_running"))
+	assert.False(t, isSelfStoreName("shard.threads_running"))
+	assert.Equal(t, "SelfThreadsRunning", camelStoreName("self.threads_running"))
+	assert.Equal(t, "Shard", camelStoreName("shard"))
+}
+
+func TestCheckStoreMetrics(t *testing.T) {
+	throttler := &Throttler{
+		throttledApps:                      cache.New(cache.NoExpiration, 0),
+		mysqlClusterThresholds:             cache.New(cache.NoExpiration, 0),
+		aggregatedMetrics:                  cache.New(cache.NoExpiration, 0),
+		recentApps:                         cache.New(cache.NoExpiration, 0),
+		nonLowPriorityAppRequestsThrottled: cache.New(cache.NoExpiration, 0),
+		heartbeatWriter:                    FakeHeartbeatWriter{},
+	}
+	throttler.check = NewThrottlerCheck(throttler)
+	setMetric := func(clusterName string, value float64, threshold float64) {
+		throttler.mysqlClusterThresholds.Set(clusterName, threshold, cache.DefaultExpiration)
+		throttler.aggregatedMetrics.Set(fmt.Sprintf("mysql/%s", clusterName), &mysql.MySQLThrottleMetric{Value: value}, cache.DefaultExpiration)
+	}
+	setMetric("self", 1, 5)
+	setMetric("self.threads_running", 200, 100)
+	setMetric("self.history_list_length", 10, 1000)
+
+	ctx := context.Background()
+	t.Run("single metric", func(t *testing.T) {
+		checkResult := throttler.checkStoreMetrics(ctx, "app", selfStoreName, "", StandardCheckFlags, []base.MetricName{base.DefaultMetricName})
+		assert.Equal(t, http.StatusOK, checkResult.StatusCode)
+		assert.Equal(t, float64(1), checkResult.Value)
+		assert.Empty(t, checkResult.Metrics)
+	})
+	t.Run("all metrics pass", func(t *testing.T) {
+		checkResult := throttler.checkStoreMetrics(ctx, "app", selfStoreName, "", StandardCheckFlags, []base.MetricName{base.DefaultMetricName, base.HistoryListLengthMetricName})
+		assert.Equal(t, http.StatusOK, checkResult.StatusCode)
+		assert.Equal(t, float64(1), checkResult.Value)
+		require.Len(t, checkResult.Metrics, 2)
+		assert.Equal(t, float64(10), checkResult.Metrics["history_list_length"].Value)
+	})
+	t.Run("a metric fails", func(t *testing.T) {
+		checkResult := throttler.checkStoreMetrics(ctx, "app", selfStoreName, "", StandardCheckFlags, []base.MetricName{base.DefaultMetricName, base.ThreadsRunningMetricName, base.HistoryListLengthMetricName})
+		assert.Equal(t, http.StatusTooManyRequests, checkResult.StatusCode)
+		assert.Equal(t, float64(200), checkResult.Value)
+		assert.Equal(t, float64(100), checkResult.Threshold)
+		require.Len(t, checkResult.Metrics, 3)
+		assert.Equal(t, http.StatusOK, checkResult.Metrics["default"].StatusCode)
+		assert.Equal(t, http.StatusTooManyRequests, checkResult.Metrics["threads_running"].StatusCode)
+	})
+	t.Run("unknown metric", func(t *testing.T) {
+		checkResult := throttler.checkStoreMetrics(ctx, "app", selfStoreName, "", StandardCheckFlags, []base.MetricName{base.DefaultMetricName, "queue_size"})
+		assert.Equal(t, http.StatusNotFound, checkResult.StatusCode)
+	})
+}
+
 // TestRefreshMySQLInventory tests the behavior of the throttler's RefreshMySQLInventory() function, which
 // is called periodically in actual throttler. For a given cluster name, it generates a list of probes
 // the throttler will use to check metrics.
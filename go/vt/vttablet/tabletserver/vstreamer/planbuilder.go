@@ -18,6 +18,7 @@ package vstreamer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -59,6 +60,10 @@ type Plan struct {
 	// Filters is the list of filters to be applied to the columns
 	// of the table.
 	Filters []Filter
+
+	// hasExpressions is set if any of the Filters or ColExprs
+	// must be evaluated with the evalengine.
+	hasExpressions bool
 }
 
 // Opcode enumerates the operators supported in a where clause
@@ -81,6 +86,9 @@ const (
 	NotEqual
 	// IsNotNull is used to filter a column if it is NULL
 	IsNotNull
+	// Expression is used to filter a row if an arbitrary scalar
+	// expression, like an OR, IN, LIKE or BETWEEN, is not true
+	Expression
 )
 
 // Filter contains opcodes for filtering.
@@ -97,6 +105,10 @@ type Filter struct {
 	Vindex        vindexes.Vindex
 	VindexColumns []int
 	KeyRange      *topodatapb.KeyRange
+
+	// Expr is the expression evaluated against the row of the
+	// table for the Expression opcode.
+	Expr evalengine.Expr
 }
 
 // ColExpr represents a column expression.
@@ -114,6 +126,10 @@ type ColExpr struct {
 	Field *querypb.Field
 
 	FixedValue sqltypes.Value
+
+	// Expr, if set, is evaluated against the row of the table
+	// to compute the value. If so, ColNum is -1.
+	Expr evalengine.Expr
 }
 
 // Table contains the metadata for a table.
@@ -216,6 +232,11 @@ func (plan *Plan) filter(values, result []sqltypes.Value, charsets []collations.
 	if len(result) != len(plan.ColExprs) {
 		return false, fmt.Errorf("expected %d values in result slice", len(plan.ColExprs))
 	}
+	var env *evalengine.ExpressionEnv
+	if plan.hasExpressions {
+		env = evalengine.EmptyExpressionEnv()
+		env.Row = values
+	}
 	for _, filter := range plan.Filters {
 		switch filter.Opcode {
 		case VindexMatch:
@@ -230,6 +251,14 @@ func (plan *Plan) filter(values, result []sqltypes.Value, charsets []collations.
 			if values[filter.ColNum].IsNull() {
 				return false, nil
 			}
+		case Expression:
+			res, err := env.Evaluate(filter.Expr)
+			if err != nil {
+				return false, err
+			}
+			if !res.ToBoolean() {
+				return false, nil
+			}
 		default:
 			match, err := compare(filter.Opcode, values[filter.ColNum], filter.Value, charsets[filter.ColNum])
 			if err != nil {
@@ -241,6 +270,14 @@ func (plan *Plan) filter(values, result []sqltypes.Value, charsets []collations.
 		}
 	}
 	for i, colExpr := range plan.ColExprs {
+		if colExpr.Expr != nil {
+			res, err := env.Evaluate(colExpr.Expr)
+			if err != nil {
+				return false, err
+			}
+			result[i] = res.Value(collations.ID(colExpr.Field.Charset))
+			continue
+		}
 		if colExpr.ColNum == -1 {
 			result[i] = colExpr.FixedValue
 			continue
@@ -512,13 +549,21 @@ func (plan *Plan) analyzeWhere(vschema *localVSchema, where *sqlparser.Where) er
 	for _, expr := range exprs {
 		switch expr := expr.(type) {
 		case *sqlparser.ComparisonExpr:
+			// Only a column compared to an int or string literal has
+			// a dedicated opcode, anything else is an Expression.
 			opcode, err := getOpcode(expr)
 			if err != nil {
-				return err
+				if err := plan.analyzeExpression(expr); err != nil {
+					return err
+				}
+				continue
 			}
 			qualifiedName, ok := expr.Left.(*sqlparser.ColName)
 			if !ok {
-				return fmt.Errorf("unexpected: %v", sqlparser.String(expr))
+				if err := plan.analyzeExpression(expr); err != nil {
+					return err
+				}
+				continue
 			}
 			if !qualifiedName.Qualifier.IsEmpty() {
 				return fmt.Errorf("unsupported qualifier for column: %v", sqlparser.String(qualifiedName))
@@ -528,12 +573,12 @@ func (plan *Plan) analyzeWhere(vschema *localVSchema, where *sqlparser.Where) er
 				return err
 			}
 			val, ok := expr.Right.(*sqlparser.Literal)
-			if !ok {
-				return fmt.Errorf("unexpected: %v", sqlparser.String(expr))
-			}
 			//StrVal is varbinary, we do not support varchar since we would have to implement all collation types
-			if val.Type != sqlparser.IntVal && val.Type != sqlparser.StrVal {
-				return fmt.Errorf("unexpected: %v", sqlparser.String(expr))
+			if !ok || (val.Type != sqlparser.IntVal && val.Type != sqlparser.StrVal) {
+				if err := plan.analyzeExpression(expr); err != nil {
+					return err
+				}
+				continue
 			}
 			pv, err := evalengine.Translate(val, nil)
 			if err != nil {
@@ -551,18 +596,21 @@ func (plan *Plan) analyzeWhere(vschema *localVSchema, where *sqlparser.Where) er
 			})
 		case *sqlparser.FuncExpr:
 			if !expr.Name.EqualString("in_keyrange") {
-				return fmt.Errorf("unsupported constraint: %v", sqlparser.String(expr))
+				if err := plan.analyzeExpression(expr); err != nil {
+					return err
+				}
+				continue
 			}
 			if err := plan.analyzeInKeyRange(vschema, expr.Exprs); err != nil {
 				return err
 			}
 		case *sqlparser.IsExpr: // Needed for CreateLookupVindex with ignore_nulls
-			if expr.Right != sqlparser.IsNotNullOp {
-				return fmt.Errorf("unsupported constraint: %v", sqlparser.String(expr))
-			}
 			qualifiedName, ok := expr.Left.(*sqlparser.ColName)
-			if !ok {
-				return fmt.Errorf("unexpected: %v", sqlparser.String(expr))
+			if expr.Right != sqlparser.IsNotNullOp || !ok {
+				if err := plan.analyzeExpression(expr); err != nil {
+					return err
+				}
+				continue
 			}
 			if !qualifiedName.Qualifier.IsEmpty() {
 				return fmt.Errorf("unsupported qualifier for column: %v", sqlparser.String(qualifiedName))
@@ -576,12 +624,75 @@ func (plan *Plan) analyzeWhere(vschema *localVSchema, where *sqlparser.Where) er
 				ColNum: colnum,
 			})
 		default:
-			return fmt.Errorf("unsupported constraint: %v", sqlparser.String(expr))
+			if err := plan.analyzeExpression(expr); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+// analyzeExpression adds a filter for a constraint that is evaluated
+// with the evalengine, like an OR, IN, LIKE or BETWEEN.
+func (plan *Plan) analyzeExpression(expr sqlparser.Expr) error {
+	evalExpr, err := plan.translateExpr(expr)
+	if err == errUnsupportedExpr {
+		return fmt.Errorf("unsupported constraint: %v", sqlparser.String(expr))
+	}
+	if err != nil {
+		return err
+	}
+	plan.Filters = append(plan.Filters, Filter{
+		Opcode: Expression,
+		Expr:   evalExpr,
+	})
+	plan.hasExpressions = true
+	return nil
+}
+
+var errUnsupportedExpr = errors.New("unsupported expression")
+
+// translateExpr translates an expression on the columns of the table
+// for the evalengine. It returns errUnsupportedExpr if the evalengine
+// cannot evaluate the expression.
+func (plan *Plan) translateExpr(expr sqlparser.Expr) (evalengine.Expr, error) {
+	var colErr error
+	evalExpr, err := evalengine.Translate(expr, &evalengine.Config{
+		ResolveColumn: func(col *sqlparser.ColName) (int, error) {
+			if !col.Qualifier.IsEmpty() {
+				colErr = fmt.Errorf("unsupported qualifier for column: %v", sqlparser.String(col))
+				return 0, colErr
+			}
+			colnum, err := findColumn(plan.Table, col.Name)
+			if err != nil {
+				colErr = err
+			}
+			return colnum, err
+		},
+		ResolveType: func(expr sqlparser.Expr) (sqltypes.Type, collations.ID, bool) {
+			col, ok := expr.(*sqlparser.ColName)
+			if !ok {
+				return sqltypes.Unknown, collations.Unknown, false
+			}
+			colnum, err := findColumn(plan.Table, col.Name)
+			if err != nil {
+				return sqltypes.Unknown, collations.Unknown, false
+			}
+			field := plan.Table.Fields[colnum]
+			return field.Type, collations.ID(field.Charset), true
+		},
+		Collation: collations.Default(),
+	})
+	if colErr != nil {
+		return nil, colErr
+	}
+	if err != nil {
+		log.Infof("Unsupported expression %v: %v", sqlparser.String(expr), err)
+		return nil, errUnsupportedExpr
+	}
+	return evalExpr, nil
+}
+
 // splitAndExpression breaks up the Expr into AND-separated conditions
 // and appends them to filters, which can be shuffled and recombined
 // as needed.
@@ -702,7 +813,11 @@ func (plan *Plan) analyzeExpr(vschema *localVSchema, selExpr sqlparser.SelectExp
 				Field:  field,
 			}, nil
 		default:
-			return ColExpr{}, fmt.Errorf("unsupported function: %v", sqlparser.String(inner))
+			cExpr, err := plan.analyzeComputedExpr(aliased)
+			if err == errUnsupportedExpr {
+				return ColExpr{}, fmt.Errorf("unsupported function: %v", sqlparser.String(inner))
+			}
+			return cExpr, err
 		}
 	case *sqlparser.Literal:
 		//allow only intval 1
@@ -738,9 +853,48 @@ func (plan *Plan) analyzeExpr(vschema *localVSchema, selExpr sqlparser.SelectExp
 			Field:  field,
 		}, nil
 	default:
-		log.Infof("Unsupported expression: %v", inner)
-		return ColExpr{}, fmt.Errorf("unsupported: %v", sqlparser.String(aliased.Expr))
+		cExpr, err := plan.analyzeComputedExpr(aliased)
+		if err == errUnsupportedExpr {
+			return ColExpr{}, fmt.Errorf("unsupported: %v", sqlparser.String(aliased.Expr))
+		}
+		return cExpr, err
+	}
+}
+
+// analyzeComputedExpr builds a column computed with the evalengine
+// from the columns of the table, like "concat(a, b) as ab".
+func (plan *Plan) analyzeComputedExpr(aliased *sqlparser.AliasedExpr) (ColExpr, error) {
+	evalExpr, err := plan.translateExpr(aliased.Expr)
+	if err != nil {
+		return ColExpr{}, err
+	}
+	typ, _, err := evalengine.EmptyExpressionEnv().TypeOf(evalExpr, plan.Table.Fields)
+	if err != nil {
+		return ColExpr{}, fmt.Errorf("unsupported: %v: %v", sqlparser.String(aliased.Expr), err)
 	}
+	name := aliased.As.String()
+	if name == "" {
+		name = sqlparser.String(aliased.Expr)
+	}
+	field := &querypb.Field{
+		Name:    name,
+		Type:    typ,
+		Charset: collations.CollationBinaryID,
+	}
+	switch {
+	case sqltypes.IsText(typ):
+		field.Charset = uint32(collations.Default())
+	case sqltypes.IsNumber(typ):
+		field.Flags = uint32(querypb.MySqlFlag_NUM_FLAG)
+	case sqltypes.IsBinary(typ):
+		field.Flags = uint32(querypb.MySqlFlag_BINARY_FLAG)
+	}
+	plan.hasExpressions = true
+	return ColExpr{
+		ColNum: -1,
+		Field:  field,
+		Expr:   evalExpr,
+	}, nil
 }
 
 // analyzeInKeyRange allows the following constructs: "in_keyrange('-80')",
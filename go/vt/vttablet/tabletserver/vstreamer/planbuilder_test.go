@@ -593,8 +593,16 @@ func TestPlanBuilder(t *testing.T) {
 		outErr:  `unsupported function: max(val)`,
 	}, {
 		inTable: t1,
-		inRule:  &binlogdatapb.Rule{Match: "t1", Filter: "select id+1, val from t1"},
-		outErr:  `unsupported: id + 1`,
+		inRule:  &binlogdatapb.Rule{Match: "t1", Filter: "select id, val from t1 where id in (select id from t2)"},
+		outErr:  `unsupported constraint: id in (select id from t2)`,
+	}, {
+		inTable: t1,
+		inRule:  &binlogdatapb.Rule{Match: "t1", Filter: "select id, val from t1 where none = 1 or id = 2"},
+		outErr:  "column `none` not found in table t1",
+	}, {
+		inTable: t1,
+		inRule:  &binlogdatapb.Rule{Match: "t1", Filter: "select id, (select 1 from dual) from t1"},
+		outErr:  `unsupported: (select 1 from dual)`,
 	}, {
 		inTable: t1,
 		inRule:  &binlogdatapb.Rule{Match: "t1", Filter: "select t1.id, val from t1"},
@@ -723,6 +731,86 @@ func TestPlanBuilderFilterComparison(t *testing.T) {
 	}
 }
 
+func TestPlanFilterExpressions(t *testing.T) {
+	t1 := &Table{
+		Name: "t1",
+		Fields: []*querypb.Field{{
+			Name:    "id",
+			Type:    sqltypes.Int64,
+			Charset: collations.CollationBinaryID,
+			Flags:   uint32(querypb.MySqlFlag_NUM_FLAG),
+		}, {
+			Name:    "val",
+			Type:    sqltypes.VarChar,
+			Charset: uint32(collations.Default()),
+		}},
+	}
+	rows := [][]sqltypes.Value{
+		{sqltypes.NewInt64(1), sqltypes.NewVarChar("aaa")},
+		{sqltypes.NewInt64(2), sqltypes.NewVarChar("bbb")},
+		{sqltypes.NewInt64(3), sqltypes.NewVarChar("abc")},
+		{sqltypes.NewInt64(4), sqltypes.NULL},
+	}
+	testcases := []struct {
+		filter string
+		fields []string
+		out    [][]sqltypes.Value
+	}{{
+		filter: "select id from t1 where id = 1 or val = 'bbb'",
+		fields: []string{"id"},
+		out:    [][]sqltypes.Value{{sqltypes.NewInt64(1)}, {sqltypes.NewInt64(2)}},
+	}, {
+		filter: "select id from t1 where id in (2, 3)",
+		fields: []string{"id"},
+		out:    [][]sqltypes.Value{{sqltypes.NewInt64(2)}, {sqltypes.NewInt64(3)}},
+	}, {
+		filter: "select id from t1 where val like 'a%' and id > 1",
+		fields: []string{"id"},
+		out:    [][]sqltypes.Value{{sqltypes.NewInt64(3)}},
+	}, {
+		filter: "select id from t1 where id between 2 and 4 and val is not null",
+		fields: []string{"id"},
+		out:    [][]sqltypes.Value{{sqltypes.NewInt64(2)}, {sqltypes.NewInt64(3)}},
+	}, {
+		filter: "select id from t1 where val is null",
+		fields: []string{"id"},
+		out:    [][]sqltypes.Value{{sqltypes.NewInt64(4)}},
+	}, {
+		filter: "select id, id * 10 as tens, concat(val, '!') from t1 where not (id < 3)",
+		fields: []string{"id", "tens", "concat(val, '!')"},
+		out: [][]sqltypes.Value{
+			{sqltypes.NewInt64(3), sqltypes.NewInt64(30), sqltypes.NewVarChar("abc!")},
+			{sqltypes.NewInt64(4), sqltypes.NewInt64(40), sqltypes.NULL},
+		},
+	}}
+	for _, tcase := range testcases {
+		t.Run(tcase.filter, func(t *testing.T) {
+			plan, err := buildPlan(t1, testLocalVSchema, &binlogdatapb.Filter{
+				Rules: []*binlogdatapb.Rule{{Match: "t1", Filter: tcase.filter}},
+			})
+			require.NoError(t, err)
+
+			var fields []string
+			for _, field := range plan.fields() {
+				fields = append(fields, field.Name)
+			}
+			assert.Equal(t, tcase.fields, fields)
+
+			charsets := []collations.ID{collations.CollationBinaryID, collations.Default()}
+			var out [][]sqltypes.Value
+			for _, row := range rows {
+				result := make([]sqltypes.Value, len(plan.ColExprs))
+				ok, err := plan.filter(row, result, charsets)
+				require.NoError(t, err)
+				if ok {
+					out = append(out, result)
+				}
+			}
+			assert.Equal(t, tcase.out, out)
+		})
+	}
+}
+
 func TestCompare(t *testing.T) {
 	type testcase struct {
 		opcode                   Opcode
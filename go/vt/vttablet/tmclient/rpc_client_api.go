@@ -246,6 +246,10 @@ type TabletManagerClient interface {
 	// RestoreFromBackup deletes local data and restores database from backup
 	RestoreFromBackup(ctx context.Context, tablet *topodatapb.Tablet, req *tabletmanagerdatapb.RestoreFromBackupRequest) (logutil.EventStream, error)
 
+	// VerifyBackup restores a backup into a scratch mysqld and checks it.
+	// The stream returns the log events, then the verdict of the verification.
+	VerifyBackup(ctx context.Context, tablet *topodatapb.Tablet, req *tabletmanagerdatapb.VerifyBackupRequest) (VerifyBackupStream, error)
+
 	// Throttler
 	CheckThrottler(ctx context.Context, tablet *topodatapb.Tablet, request *tabletmanagerdatapb.CheckThrottlerRequest) (*tabletmanagerdatapb.CheckThrottlerResponse, error)
 
@@ -258,6 +262,13 @@ type TabletManagerClient interface {
 	Close()
 }
 
+// VerifyBackupStream is the stream returned by VerifyBackup.
+type VerifyBackupStream interface {
+	// Recv returns the next response of the stream. The verdict of the
+	// verification is in the last response, followed by io.EOF.
+	Recv() (*tabletmanagerdatapb.VerifyBackupResponse, error)
+}
+
 // TabletManagerClientFactory is the factory method to create
 // TabletManagerClient objects.
 type TabletManagerClientFactory func() TabletManagerClient
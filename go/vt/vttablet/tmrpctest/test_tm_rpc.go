@@ -1287,6 +1287,22 @@ func (fra *fakeRPCTM) RestoreFromBackup(ctx context.Context, logger logutil.Logg
 	return nil
 }
 
+var testVerifyBackupRequest = &tabletmanagerdatapb.VerifyBackupRequest{BackupName: "2023-08-01.120000.zone1-0000000100", Concurrency: 8}
+var testBackupVerification = &tabletmanagerdatapb.BackupVerification{
+	Passed:   false,
+	Position: testReplicationPosition,
+	Failures: []string{"table vt_test_keyspace.t1 is missing"},
+}
+
+func (fra *fakeRPCTM) VerifyBackup(ctx context.Context, logger logutil.Logger, request *tabletmanagerdatapb.VerifyBackupRequest) (*tabletmanagerdatapb.BackupVerification, error) {
+	if fra.panics {
+		panic(fmt.Errorf("test-triggered panic"))
+	}
+	compare(fra.t, "VerifyBackup args", request, testVerifyBackupRequest)
+	logStuff(logger, 10)
+	return testBackupVerification, nil
+}
+
 func (fra *fakeRPCTM) CheckThrottler(ctx context.Context, req *tabletmanagerdatapb.CheckThrottlerRequest) (*tabletmanagerdatapb.CheckThrottlerResponse, error) {
 	if fra.panics {
 		panic(fmt.Errorf("test-triggered panic"))
@@ -1317,6 +1333,46 @@ func tmRPCTestRestoreFromBackupPanic(ctx context.Context, t *testing.T, client t
 	expectHandleRPCPanic(t, "RestoreFromBackup", true /*verbose*/, err)
 }
 
+func tmRPCTestVerifyBackup(ctx context.Context, t *testing.T, client tmclient.TabletManagerClient, tablet *topodatapb.Tablet) {
+	stream, err := client.VerifyBackup(ctx, tablet, testVerifyBackupRequest)
+	if err != nil {
+		t.Fatalf("VerifyBackup failed: %v", err)
+	}
+	var verification *tabletmanagerdatapb.BackupVerification
+	events := 0
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("VerifyBackup failed: %v", err)
+		}
+		if resp.Verification != nil {
+			verification = resp.Verification
+			continue
+		}
+		if resp.Event.Value != testLogString {
+			t.Errorf("Unexpected log response for VerifyBackup: got %v expected %v", resp.Event.Value, testLogString)
+		}
+		events++
+	}
+	compare(t, "VerifyBackup logged events", events, 10)
+	compare(t, "VerifyBackup result", verification, testBackupVerification)
+}
+
+func tmRPCTestVerifyBackupPanic(ctx context.Context, t *testing.T, client tmclient.TabletManagerClient, tablet *topodatapb.Tablet) {
+	stream, err := client.VerifyBackup(ctx, tablet, testVerifyBackupRequest)
+	if err != nil {
+		t.Fatalf("VerifyBackup failed: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err == nil {
+		t.Fatalf("Unexpected VerifyBackup response: %v", resp)
+	}
+	expectHandleRPCPanic(t, "VerifyBackup", true /*verbose*/, err)
+}
+
 func tmRPCTestCheckThrottler(ctx context.Context, t *testing.T, client tmclient.TabletManagerClient, tablet *topodatapb.Tablet, req *tabletmanagerdatapb.CheckThrottlerRequest) {
 	_, err := client.CheckThrottler(ctx, tablet, req)
 	expectHandleRPCPanic(t, "CheckThrottler", false /*verbose*/, err)
@@ -1405,6 +1461,7 @@ func Run(t *testing.T, client tmclient.TabletManagerClient, tablet *topodatapb.T
 	// Backup / restore related methods
 	tmRPCTestBackup(ctx, t, client, tablet)
 	tmRPCTestRestoreFromBackup(ctx, t, client, tablet, restoreFromBackupRequest)
+	tmRPCTestVerifyBackup(ctx, t, client, tablet)
 
 	// Throttler related methods
 	tmRPCTestCheckThrottler(ctx, t, client, tablet, checkThrottlerRequest)
@@ -1460,6 +1517,7 @@ func Run(t *testing.T, client tmclient.TabletManagerClient, tablet *topodatapb.T
 	// Backup / restore related methods
 	tmRPCTestBackupPanic(ctx, t, client, tablet)
 	tmRPCTestRestoreFromBackupPanic(ctx, t, client, tablet, restoreFromBackupRequest)
+	tmRPCTestVerifyBackupPanic(ctx, t, client, tablet)
 
 	client.Close()
 }